@@ -0,0 +1,94 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package plugin
+
+import (
+	"context"
+	"time"
+
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/types"
+)
+
+// maxActivityRecords bounds the records kept per day, the oldest are dropped first.
+const maxActivityRecords = 5000
+
+// itemResultKeys are the result lists whose length counts as the items a call processed.
+var itemResultKeys = []string{"articles", "items", "entries", "files", "contacts", "activities"}
+
+// WithActivityLog records every call with its duration and outcome into Request.Store,
+// one list per UTC day, for the journal plugin. Calls without a store are not recorded.
+func WithActivityLog() Option {
+	return func(m *manager) {
+		m.activityLog = true
+	}
+}
+
+func (m *manager) recordActivity(ctx context.Context, store api.PersistentStore, ps types.PluginCall, started time.Time, resp *api.Response, err error) {
+	record := types.ActivityRecord{
+		Workflow:   ps.Workflow,
+		JobID:      ps.JobID,
+		Plugin:     ps.PluginName,
+		StartedAt:  started.UTC().Format(time.RFC3339),
+		DurationMs: time.Since(started).Milliseconds(),
+	}
+	switch {
+	case err != nil:
+		record.Message = err.Error()
+	case resp == nil:
+		record.Message = "no response"
+	case !resp.IsSucceed:
+		record.Message = resp.Message
+	default:
+		record.Succeed = true
+		record.Items = countItems(resp.Results)
+	}
+
+	day := started.UTC().Format(types.ActivityDayLayout)
+	m.activityMux.Lock()
+	defer m.activityMux.Unlock()
+	var activity types.ActivityDay
+	_ = store.Load(ctx, types.ActivitySource, types.ActivityGroup, day, &activity)
+	activity.Records = append(activity.Records, record)
+	if n := len(activity.Records); n > maxActivityRecords {
+		activity.Records = activity.Records[n-maxActivityRecords:]
+	}
+	if saveErr := store.Save(ctx, types.ActivitySource, types.ActivityGroup, day, &activity); saveErr != nil {
+		m.logger.Warnw("record plugin activity failed", "plugin", ps.PluginName, "error", saveErr)
+	}
+}
+
+// countItems uses the total result when the plugin reports one, else the length of its result list.
+func countItems(results map[string]any) int {
+	switch total := results["total"].(type) {
+	case int:
+		return total
+	case float64:
+		return int(total)
+	}
+	for _, key := range itemResultKeys {
+		switch list := results[key].(type) {
+		case []any:
+			return len(list)
+		case []map[string]any:
+			return len(list)
+		case []string:
+			return len(list)
+		}
+	}
+	return 0
+}
@@ -0,0 +1,120 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/types"
+)
+
+type activityStore struct {
+	data map[string][]byte
+}
+
+func (s *activityStore) Load(ctx context.Context, source, group, key string, data any) error {
+	raw, ok := s.data[source+"/"+group+"/"+key]
+	if !ok {
+		return errors.New("no record")
+	}
+	return json.Unmarshal(raw, data)
+}
+
+func (s *activityStore) Save(ctx context.Context, source, group, key string, data any) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	s.data[source+"/"+group+"/"+key] = raw
+	return nil
+}
+
+type resultPlugin struct {
+	resp *api.Response
+}
+
+func (r *resultPlugin) Name() string           { return "result" }
+func (r *resultPlugin) Type() types.PluginType { return types.TypeProcess }
+func (r *resultPlugin) Version() string        { return "1.0" }
+
+func (r *resultPlugin) Run(ctx context.Context, request *api.Request) (*api.Response, error) {
+	return r.resp, nil
+}
+
+func TestManager_Call_ActivityLog(t *testing.T) {
+	responses := []*api.Response{
+		api.NewResponseWithResult(map[string]any{"articles": []map[string]any{{}, {}}}),
+		api.NewResponseWithResult(map[string]any{"total": 4}),
+		api.NewFailedResponse("feed unavailable"),
+	}
+	var next int
+	m := New(WithActivityLog())
+	m.Register(types.PluginSpec{Name: "result", Version: "1.0", Type: types.TypeProcess}, func(ps types.PluginCall) types.Plugin {
+		p := &resultPlugin{resp: responses[next]}
+		next++
+		return p
+	})
+
+	store := &activityStore{data: map[string][]byte{}}
+	for range responses {
+		if _, err := m.Call(context.Background(), types.PluginCall{PluginName: "result", Workflow: "news", JobID: "job-1"}, &api.Request{Store: store}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(store.data) != 1 {
+		t.Fatalf("expected one day of activity, got %d", len(store.data))
+	}
+	var activity types.ActivityDay
+	for _, raw := range store.data {
+		if err := json.Unmarshal(raw, &activity); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(activity.Records) != 3 {
+		t.Fatalf("expected 3 records, got %d", len(activity.Records))
+	}
+	wantItems := []int{2, 4, 0}
+	for i, r := range activity.Records {
+		if r.Plugin != "result" || r.Workflow != "news" || r.JobID != "job-1" || r.StartedAt == "" {
+			t.Errorf("record %d: unexpected %+v", i, r)
+		}
+		if r.Items != wantItems[i] {
+			t.Errorf("record %d: expected %d items, got %d", i, wantItems[i], r.Items)
+		}
+	}
+	if last := activity.Records[2]; last.Succeed || last.Message != "feed unavailable" {
+		t.Errorf("expected failed call recorded, got %+v", last)
+	}
+}
+
+func TestManager_Call_NoActivityLogByDefault(t *testing.T) {
+	m := New()
+	m.Register(types.PluginSpec{Name: "result", Version: "1.0", Type: types.TypeProcess}, func(ps types.PluginCall) types.Plugin {
+		return &resultPlugin{resp: api.NewResponse()}
+	})
+	store := &activityStore{data: map[string][]byte{}}
+	if _, err := m.Call(context.Background(), types.PluginCall{PluginName: "result"}, &api.Request{Store: store}); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.data) != 0 {
+		t.Errorf("expected no activity recorded, got %d", len(store.data))
+	}
+}
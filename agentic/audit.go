@@ -0,0 +1,163 @@
+package agentic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	fridaytools "github.com/basenana/friday/core/tools"
+	"github.com/basenana/plugin/utils"
+	"go.uber.org/zap"
+)
+
+const (
+	// ToolAuditPath is the JSONL file, relative to the working path, that every tool call of react and research is appended to.
+	ToolAuditPath = ".friday/tool_audit.jsonl"
+
+	maxAuditValue = 2000
+)
+
+// ToolAuditEntry is one line of the audit file.
+type ToolAuditEntry struct {
+	Time       string         `json:"time"`
+	JobID      string         `json:"job_id"`
+	Plugin     string         `json:"plugin"`
+	Tool       string         `json:"tool"`
+	Arguments  map[string]any `json:"arguments,omitempty"`
+	Result     string         `json:"result,omitempty"`
+	DurationMs int64          `json:"duration_ms"`
+	Error      string         `json:"error,omitempty"`
+}
+
+// ToolAudit records the tool calls of one agent step.
+type ToolAudit struct {
+	fileAccess *utils.FileAccess
+	jobID      string
+	plugin     string
+	logger     *zap.SugaredLogger
+
+	mu    sync.Mutex
+	calls int
+}
+
+func NewToolAudit(workingPath, jobID, plugin string, logger *zap.SugaredLogger) *ToolAudit {
+	return &ToolAudit{fileAccess: utils.NewFileAccess(workingPath), jobID: jobID, plugin: plugin, logger: logger}
+}
+
+// Wrap returns copies of the tools whose calls are recorded.
+func (a *ToolAudit) Wrap(tools []*fridaytools.Tool) []*fridaytools.Tool {
+	wrapped := make([]*fridaytools.Tool, 0, len(tools))
+	for _, tool := range tools {
+		audited := *tool
+		handler := tool.Handler
+		audited.Handler = func(ctx context.Context, request *fridaytools.Request) (*fridaytools.Result, error) {
+			startAt := time.Now()
+			result, err := handler(ctx, request)
+			entry := ToolAuditEntry{
+				Time:       startAt.UTC().Format(time.RFC3339Nano),
+				JobID:      a.jobID,
+				Plugin:     a.plugin,
+				Tool:       audited.Name,
+				Arguments:  auditArguments(request.Arguments),
+				DurationMs: time.Since(startAt).Milliseconds(),
+			}
+			if result != nil {
+				entry.Result = truncateAuditValue(toolResultText(result))
+				if result.IsError {
+					entry.Error = "tool returned error"
+				}
+			}
+			if err != nil {
+				entry.Error = err.Error()
+			}
+			a.record(entry)
+			return result, err
+		}
+		wrapped = append(wrapped, &audited)
+	}
+	return wrapped
+}
+
+// Result describes the audit file for the step results, it returns nil when no tool was called.
+func (a *ToolAudit) Result() map[string]any {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.calls == 0 {
+		return nil
+	}
+	return map[string]any{"path": ToolAuditPath, "calls": a.calls}
+}
+
+// record never fails the tool call, a lost audit line is only logged.
+func (a *ToolAudit) record(entry ToolAuditEntry) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.calls++
+
+	if err := a.append(entry); err != nil {
+		a.logger.Warnw("write tool audit failed", "tool", entry.Tool, "error", err)
+	}
+}
+
+func (a *ToolAudit) append(entry ToolAuditEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err = a.fileAccess.MkdirAll(path.Dir(ToolAuditPath), 0755); err != nil {
+		return err
+	}
+	absPath, err := a.fileAccess.GetAbsPath(ToolAuditPath)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(absPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(append(line, '\n')); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// auditArguments truncates long string arguments, e.g. the content of file_write.
+func auditArguments(args map[string]any) map[string]any {
+	if len(args) == 0 {
+		return nil
+	}
+	result := make(map[string]any, len(args))
+	for k, v := range args {
+		if s, ok := v.(string); ok {
+			v = truncateAuditValue(s)
+		}
+		result[k] = v
+	}
+	return result
+}
+
+func toolResultText(result *fridaytools.Result) string {
+	var parts []string
+	for _, content := range result.Content {
+		if text, ok := content.(fridaytools.TextContent); ok {
+			parts = append(parts, text.Text)
+		} else {
+			parts = append(parts, fridaytools.Res2Str(content))
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+func truncateAuditValue(value string) string {
+	runes := []rune(value)
+	if len(runes) <= maxAuditValue {
+		return value
+	}
+	return string(runes[:maxAuditValue]) + fmt.Sprintf("... [truncated: %d of %d characters]", maxAuditValue, len(runes))
+}
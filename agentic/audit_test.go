@@ -0,0 +1,119 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package agentic
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	fridaytools "github.com/basenana/friday/core/tools"
+	"go.uber.org/zap"
+)
+
+func readAuditEntries(t *testing.T, workdir string) []ToolAuditEntry {
+	f, err := os.Open(filepath.Join(workdir, ToolAuditPath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var entries []ToolAuditEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry ToolAuditEntry
+		if err = json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("invalid audit line %q: %v", scanner.Text(), err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestToolAudit_Wrap(t *testing.T) {
+	var (
+		ctx     = context.Background()
+		workdir = t.TempDir()
+		audit   = NewToolAudit(workdir, "job-1", "react", zap.NewNop().Sugar())
+	)
+	if audit.Result() != nil {
+		t.Errorf("expected no audit result before any call")
+	}
+
+	tools := []*fridaytools.Tool{
+		{Name: "file_write", Handler: func(ctx context.Context, request *fridaytools.Request) (*fridaytools.Result, error) {
+			return fridaytools.NewToolResultText("written"), nil
+		}},
+		{Name: "file_read", Handler: func(ctx context.Context, request *fridaytools.Request) (*fridaytools.Result, error) {
+			return fridaytools.NewToolResultError("file not found"), nil
+		}},
+		{Name: "web_fetch", Handler: func(ctx context.Context, request *fridaytools.Request) (*fridaytools.Result, error) {
+			return nil, errors.New("connection refused")
+		}},
+	}
+	wrapped := audit.Wrap(tools)
+	if len(wrapped) != len(tools) || wrapped[0].Name != "file_write" {
+		t.Fatalf("expected the tools kept, got %v", wrapped)
+	}
+
+	content := strings.Repeat("x", maxAuditValue+100)
+	result, err := wrapped[0].Handler(ctx, &fridaytools.Request{Arguments: map[string]any{"path": "report.md", "content": content}})
+	if err != nil || getResultText(result) != "written" {
+		t.Fatalf("expected the tool result passed through, got %v %v", result, err)
+	}
+	if result, _ = wrapped[1].Handler(ctx, &fridaytools.Request{Arguments: map[string]any{"path": "missing.md"}}); !result.IsError {
+		t.Errorf("expected the tool error passed through")
+	}
+	if _, err = wrapped[2].Handler(ctx, &fridaytools.Request{}); err == nil {
+		t.Errorf("expected the handler error passed through")
+	}
+
+	entries := readAuditEntries(t, workdir)
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 audit entries, got %d", len(entries))
+	}
+	write := entries[0]
+	if write.Tool != "file_write" || write.JobID != "job-1" || write.Plugin != "react" || write.Result != "written" || write.Error != "" || write.Time == "" {
+		t.Errorf("unexpected file_write entry %+v", write)
+	}
+	if write.Arguments["path"] != "report.md" || !strings.Contains(write.Arguments["content"].(string), "[truncated") {
+		t.Errorf("expected the arguments with long values truncated, got %v", write.Arguments)
+	}
+	if entries[1].Error == "" || entries[1].Result != "file not found" {
+		t.Errorf("expected the tool error recorded, got %+v", entries[1])
+	}
+	if entries[2].Error != "connection refused" {
+		t.Errorf("expected the handler error recorded, got %+v", entries[2])
+	}
+
+	if got := audit.Result(); got["path"] != ToolAuditPath || got["calls"] != 3 {
+		t.Errorf("unexpected audit result %v", got)
+	}
+
+	// another step of the job appends to the same file
+	next := NewToolAudit(workdir, "job-1", "research", zap.NewNop().Sugar())
+	_, _ = next.Wrap(tools[:1])[0].Handler(ctx, &fridaytools.Request{})
+	if entries = readAuditEntries(t, workdir); len(entries) != 4 || entries[3].Plugin != "research" {
+		t.Errorf("expected the entry of the next step appended, got %+v", entries)
+	}
+}
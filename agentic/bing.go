@@ -0,0 +1,89 @@
+package agentic
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// bingSearchEndpoint is replaced in tests.
+var bingSearchEndpoint = "https://api.bing.microsoft.com/v7.0/search"
+
+type bingSearchResponse struct {
+	WebPages struct {
+		Value []struct {
+			Name       string `json:"name"`
+			URL        string `json:"url"`
+			DisplayURL string `json:"displayUrl"`
+			Snippet    string `json:"snippet"`
+		} `json:"value"`
+	} `json:"webPages"`
+	Error *struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// bingFreshness maps a time range to the freshness parameter, Bing has no keyword for a year
+// so it is sent as a date range.
+func bingFreshness(timeRange string, now time.Time) string {
+	switch timeRange {
+	case "day":
+		return "Day"
+	case "week":
+		return "Week"
+	case "month":
+		return "Month"
+	case "year":
+		return now.AddDate(-1, 0, 0).Format("2006-01-02") + ".." + now.Format("2006-01-02")
+	default:
+		return "" // anytime
+	}
+}
+
+func bingSearch(apiKey string) webSearchFunc {
+	return func(ctx context.Context, query, timeRange string) ([]WebSearchItem, error) {
+		params := url.Values{}
+		params.Set("q", query)
+		params.Set("count", "10")
+		params.Set("responseFilter", "Webpages")
+		if freshness := bingFreshness(timeRange, time.Now()); freshness != "" {
+			params.Set("freshness", freshness)
+		}
+		return bingQuery(ctx, apiKey, params)
+	}
+}
+
+func bingQuery(ctx context.Context, apiKey string, params url.Values) ([]WebSearchItem, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, bingSearchEndpoint+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Ocp-Apim-Subscription-Key", apiKey)
+
+	var result bingSearchResponse
+	err = doSearchRequest(req, &result)
+	if result.Error != nil {
+		return nil, fmt.Errorf("bing search failed: %s %s", result.Error.Code, result.Error.Message)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("bing search failed: %w", err)
+	}
+
+	var items []WebSearchItem
+	for _, page := range result.WebPages.Value {
+		site := page.DisplayURL
+		if u, err := url.Parse(page.URL); err == nil && u.Host != "" {
+			site = u.Host
+		}
+		items = append(items, WebSearchItem{
+			Title:   page.Name,
+			Content: page.Snippet,
+			Site:    site,
+			URL:     page.URL,
+		})
+	}
+	return items, nil
+}
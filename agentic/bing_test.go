@@ -0,0 +1,97 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package agentic
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	fridaytools "github.com/basenana/friday/core/tools"
+	"github.com/basenana/plugin/logger"
+)
+
+func newBingServer(t *testing.T, handler http.HandlerFunc) {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	old := bingSearchEndpoint
+	bingSearchEndpoint = server.URL
+	t.Cleanup(func() { bingSearchEndpoint = old })
+}
+
+func TestBingWebSearch(t *testing.T) {
+	var got *http.Request
+	newBingServer(t, func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		_, _ = w.Write([]byte(`{"webPages":{"value":[{"name":"Go","url":"https://go.dev/doc/","displayUrl":"go.dev/doc","snippet":"Documentation"}]}}`))
+	})
+
+	tools := NewBingWebSearchTool("key", newWebCitations(t.TempDir()), logger.NewLogger("test"))
+	search := getToolByName(tools, "web_search")
+	if search == nil || getToolByName(tools, "crawl_webpages") == nil {
+		t.Fatal("expected web_search and crawl_webpages tools")
+	}
+
+	result, err := search.Handler(t.Context(), &fridaytools.Request{Arguments: map[string]any{"query": "golang", "time_range": "week"}})
+	if err != nil || result.IsError {
+		t.Fatalf("search failed: %v %s", err, getResultText(result))
+	}
+	if got.Header.Get("Ocp-Apim-Subscription-Key") != "key" {
+		t.Errorf("expected subscription key header, got %q", got.Header.Get("Ocp-Apim-Subscription-Key"))
+	}
+	if q := got.URL.Query(); q.Get("q") != "golang" || q.Get("freshness") != "Week" {
+		t.Errorf("unexpected query %s", got.URL.RawQuery)
+	}
+
+	var items []WebSearchItem
+	if err = json.Unmarshal([]byte(getResultText(result)), &items); err != nil {
+		t.Fatalf("decode result failed: %v", err)
+	}
+	want := WebSearchItem{Title: "Go", Content: "Documentation", Site: "go.dev", URL: "https://go.dev/doc/"}
+	if len(items) != 1 || items[0] != want {
+		t.Errorf("expected %+v, got %+v", want, items)
+	}
+}
+
+func TestBingWebSearchError(t *testing.T) {
+	newBingServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"error":{"code":"401","message":"Access denied due to invalid subscription key."}}`))
+	})
+
+	search := getToolByName(NewBingWebSearchTool("bad", newWebCitations(t.TempDir()), logger.NewLogger("test")), "web_search")
+	result, err := search.Handler(t.Context(), &fridaytools.Request{Arguments: map[string]any{"query": "golang"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.IsError || !strings.Contains(getResultText(result), "invalid subscription key") {
+		t.Errorf("expected tool error, got %s", getResultText(result))
+	}
+}
+
+func TestBingFreshness(t *testing.T) {
+	now := time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)
+	cases := map[string]string{"day": "Day", "week": "Week", "month": "Month", "year": "2023-03-15..2024-03-15", "anytime": ""}
+	for timeRange, want := range cases {
+		if got := bingFreshness(timeRange, now); got != want {
+			t.Errorf("bingFreshness(%s) = %q, want %q", timeRange, got, want)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package agentic
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// braveSearchEndpoint is replaced in tests.
+var braveSearchEndpoint = "https://api.search.brave.com/res/v1/web/search"
+
+var braveFreshness = map[string]string{
+	"day":   "pd",
+	"week":  "pw",
+	"month": "pm",
+	"year":  "py",
+}
+
+type braveSearchResponse struct {
+	Web struct {
+		Results []struct {
+			Title       string `json:"title"`
+			URL         string `json:"url"`
+			Description string `json:"description"`
+			MetaURL     struct {
+				Hostname string `json:"hostname"`
+			} `json:"meta_url"`
+		} `json:"results"`
+	} `json:"web"`
+	Error *struct {
+		Code   string `json:"code"`
+		Detail string `json:"detail"`
+	} `json:"error"`
+}
+
+func braveSearch(apiKey string) webSearchFunc {
+	return func(ctx context.Context, query, timeRange string) ([]WebSearchItem, error) {
+		params := url.Values{}
+		params.Set("q", query)
+		params.Set("count", "10")
+		params.Set("result_filter", "web")
+		if freshness := braveFreshness[timeRange]; freshness != "" {
+			params.Set("freshness", freshness)
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, braveSearchEndpoint+"?"+params.Encode(), nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "application/json")
+		req.Header.Set("X-Subscription-Token", apiKey)
+
+		var result braveSearchResponse
+		err = doSearchRequest(req, &result)
+		if result.Error != nil {
+			return nil, fmt.Errorf("brave search failed: %s %s", result.Error.Code, result.Error.Detail)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("brave search failed: %w", err)
+		}
+
+		var items []WebSearchItem
+		for _, page := range result.Web.Results {
+			items = append(items, WebSearchItem{
+				Title:   page.Title,
+				Content: page.Description,
+				Site:    page.MetaURL.Hostname,
+				URL:     page.URL,
+			})
+		}
+		return items, nil
+	}
+}
@@ -0,0 +1,81 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package agentic
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	fridaytools "github.com/basenana/friday/core/tools"
+	"github.com/basenana/plugin/logger"
+)
+
+func newBraveServer(t *testing.T, handler http.HandlerFunc) {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	old := braveSearchEndpoint
+	braveSearchEndpoint = server.URL
+	t.Cleanup(func() { braveSearchEndpoint = old })
+}
+
+func TestBraveWebSearch(t *testing.T) {
+	var got *http.Request
+	newBraveServer(t, func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		_, _ = w.Write([]byte(`{"type":"search","web":{"results":[{"title":"Go","url":"https://go.dev/doc/","description":"Documentation","meta_url":{"hostname":"go.dev"}}]}}`))
+	})
+
+	search := getToolByName(NewBraveWebSearchTool("token", newWebCitations(t.TempDir()), logger.NewLogger("test")), "web_search")
+	result, err := search.Handler(t.Context(), &fridaytools.Request{Arguments: map[string]any{"query": "golang", "time_range": "year"}})
+	if err != nil || result.IsError {
+		t.Fatalf("search failed: %v %s", err, getResultText(result))
+	}
+	if got.Header.Get("X-Subscription-Token") != "token" {
+		t.Errorf("expected subscription token header, got %q", got.Header.Get("X-Subscription-Token"))
+	}
+	if q := got.URL.Query(); q.Get("q") != "golang" || q.Get("freshness") != "py" {
+		t.Errorf("unexpected query %s", got.URL.RawQuery)
+	}
+
+	var items []WebSearchItem
+	if err = json.Unmarshal([]byte(getResultText(result)), &items); err != nil {
+		t.Fatalf("decode result failed: %v", err)
+	}
+	want := WebSearchItem{Title: "Go", Content: "Documentation", Site: "go.dev", URL: "https://go.dev/doc/"}
+	if len(items) != 1 || items[0] != want {
+		t.Errorf("expected %+v, got %+v", want, items)
+	}
+}
+
+func TestBraveWebSearchError(t *testing.T) {
+	newBraveServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		_, _ = w.Write([]byte(`{"type":"ErrorResponse","error":{"code":"SUBSCRIPTION_TOKEN_INVALID","detail":"The provided subscription token is invalid."}}`))
+	})
+
+	search := getToolByName(NewBraveWebSearchTool("bad", newWebCitations(t.TempDir()), logger.NewLogger("test")), "web_search")
+	result, err := search.Handler(t.Context(), &fridaytools.Request{Arguments: map[string]any{"query": "golang"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.IsError || !strings.Contains(getResultText(result), "SUBSCRIPTION_TOKEN_INVALID") {
+		t.Errorf("expected tool error, got %s", getResultText(result))
+	}
+}
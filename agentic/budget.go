@@ -0,0 +1,266 @@
+package agentic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/basenana/friday/core/providers/openai"
+	"github.com/basenana/friday/core/types"
+	"github.com/basenana/plugin/api"
+)
+
+const (
+	ConfigMaxCalls  = "friday_llm_max_calls"
+	ConfigMaxTokens = "friday_llm_max_tokens"
+
+	budgetSource = "agentic"
+	budgetGroup  = "llm_budget"
+)
+
+var ErrBudgetExceeded = errors.New("llm budget exceeded")
+
+// LLMUsage is the spend of a job so far, shared by every plugin step of the job.
+type LLMUsage struct {
+	Calls  int64 `json:"calls"`
+	Tokens int64 `json:"tokens"`
+}
+
+// LLMBudget caps the LLM calls and tokens of one job. The usage is kept in the persistent
+// store under the job ID, or in process memory when the step has no store.
+type LLMBudget struct {
+	jobID     string
+	maxCalls  int64
+	maxTokens int64
+	store     api.PersistentStore
+}
+
+var (
+	budgetMu    sync.Mutex
+	localUsages = map[string]LLMUsage{}
+)
+
+// NewLLMBudget reads friday_llm_max_calls and friday_llm_max_tokens, it returns nil when neither is set.
+func NewLLMBudget(jobID string, config map[string]string, store api.PersistentStore) (*LLMBudget, error) {
+	maxCalls, err := parseBudgetLimit(config, ConfigMaxCalls)
+	if err != nil {
+		return nil, err
+	}
+	maxTokens, err := parseBudgetLimit(config, ConfigMaxTokens)
+	if err != nil {
+		return nil, err
+	}
+	if maxCalls == 0 && maxTokens == 0 {
+		return nil, nil
+	}
+	return &LLMBudget{jobID: jobID, maxCalls: maxCalls, maxTokens: maxTokens, store: store}, nil
+}
+
+func parseBudgetLimit(config map[string]string, key string) (int64, error) {
+	raw := config[key]
+	if raw == "" {
+		return 0, nil
+	}
+	n, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid %s [%s]: expect positive integer", key, raw)
+	}
+	return n, nil
+}
+
+// Usage returns the spend of the job so far.
+func (b *LLMBudget) Usage(ctx context.Context) LLMUsage {
+	budgetMu.Lock()
+	defer budgetMu.Unlock()
+	return b.load(ctx)
+}
+
+// Result describes the budget and its usage for a plugin response.
+func (b *LLMBudget) Result(ctx context.Context) map[string]any {
+	usage := b.Usage(ctx)
+	result := map[string]any{"calls": usage.Calls, "tokens": usage.Tokens}
+	if b.maxCalls > 0 {
+		result["max_calls"] = b.maxCalls
+	}
+	if b.maxTokens > 0 {
+		result["max_tokens"] = b.maxTokens
+	}
+	return result
+}
+
+// reserve counts a call before it is made, so steps running at the same time can not overrun the call limit.
+func (b *LLMBudget) reserve(ctx context.Context) error {
+	budgetMu.Lock()
+	defer budgetMu.Unlock()
+	usage := b.load(ctx)
+	if b.maxCalls > 0 && usage.Calls >= b.maxCalls {
+		return fmt.Errorf("%w: %d of %d calls used", ErrBudgetExceeded, usage.Calls, b.maxCalls)
+	}
+	if b.maxTokens > 0 && usage.Tokens >= b.maxTokens {
+		return fmt.Errorf("%w: %d of %d tokens used", ErrBudgetExceeded, usage.Tokens, b.maxTokens)
+	}
+	usage.Calls++
+	return b.save(ctx, usage)
+}
+
+func (b *LLMBudget) record(ctx context.Context, tokens int64) {
+	budgetMu.Lock()
+	defer budgetMu.Unlock()
+	usage := b.load(ctx)
+	usage.Tokens += tokens
+	_ = b.save(ctx, usage)
+}
+
+func (b *LLMBudget) load(ctx context.Context) LLMUsage {
+	if b.store == nil {
+		return localUsages[b.jobID]
+	}
+	var usage LLMUsage
+	// a job without usage yet has no record
+	_ = b.store.Load(ctx, budgetSource, budgetGroup, b.jobID, &usage)
+	return usage
+}
+
+func (b *LLMBudget) save(ctx context.Context, usage LLMUsage) error {
+	if b.store == nil {
+		localUsages[b.jobID] = usage
+		return nil
+	}
+	if err := b.store.Save(ctx, budgetSource, budgetGroup, b.jobID, &usage); err != nil {
+		return fmt.Errorf("save llm usage failed: %w", err)
+	}
+	return nil
+}
+
+type llmBudgetKey struct{}
+
+// WithLLMBudget makes the clients of NewLLMClient charge their calls to the budget.
+func WithLLMBudget(ctx context.Context, budget *LLMBudget) context.Context {
+	if budget == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, llmBudgetKey{}, budget)
+}
+
+func budgetFromContext(ctx context.Context) *LLMBudget {
+	budget, _ := ctx.Value(llmBudgetKey{}).(*LLMBudget)
+	return budget
+}
+
+// budgetClient charges the calls of the wrapped client to the budget and the run limits of the
+// request context. Tokens come from the reported usage, or are estimated from the text when the
+// API reports none.
+type budgetClient struct {
+	openai.Client
+}
+
+func (c budgetClient) Completion(ctx context.Context, request openai.Request) openai.Response {
+	budget, limits := budgetFromContext(ctx), runLimitsFromContext(ctx)
+	if budget == nil && limits == nil {
+		return c.Client.Completion(ctx, request)
+	}
+	resp := &budgetResponse{stream: make(chan openai.Delta, 5), err: make(chan error, 1)}
+	if err := reserveCall(ctx, budget, limits); err != nil {
+		resp.err <- err
+		close(resp.stream)
+		close(resp.err)
+		return resp
+	}
+
+	upstream := c.Client.Completion(ctx, request)
+	go func() {
+		var output types.Message
+		for delta := range upstream.Message() {
+			output.AssistantMessage += delta.Content + delta.Reasoning
+			for _, tool := range delta.ToolUse {
+				output.ToolArguments += tool.Arguments
+			}
+			resp.stream <- delta
+		}
+		for err := range upstream.Error() {
+			if err != nil {
+				resp.err <- err
+				break
+			}
+		}
+		resp.tokens = upstream.Tokens()
+		recordTokens(ctx, budget, limits, usedTokens(resp.tokens, request, output))
+		close(resp.stream)
+		close(resp.err)
+	}()
+	return resp
+}
+
+func (c budgetClient) CompletionNonStreaming(ctx context.Context, request openai.Request) (string, error) {
+	budget, limits := budgetFromContext(ctx), runLimitsFromContext(ctx)
+	if budget == nil && limits == nil {
+		return c.Client.CompletionNonStreaming(ctx, request)
+	}
+	if err := reserveCall(ctx, budget, limits); err != nil {
+		return "", err
+	}
+	reply, err := c.Client.CompletionNonStreaming(ctx, request)
+	recordTokens(ctx, budget, limits, usedTokens(openai.Tokens{}, request, types.Message{AssistantMessage: reply}))
+	return reply, err
+}
+
+func (c budgetClient) StructuredPredict(ctx context.Context, request openai.Request, model any) error {
+	budget, limits := budgetFromContext(ctx), runLimitsFromContext(ctx)
+	if budget == nil && limits == nil {
+		return c.Client.StructuredPredict(ctx, request, model)
+	}
+	if err := reserveCall(ctx, budget, limits); err != nil {
+		return err
+	}
+	err := c.Client.StructuredPredict(ctx, request, model)
+	recordTokens(ctx, budget, limits, usedTokens(openai.Tokens{}, request, types.Message{}))
+	return err
+}
+
+func reserveCall(ctx context.Context, budget *LLMBudget, limits *RunLimits) error {
+	if limits != nil {
+		if err := limits.check(); err != nil {
+			return err
+		}
+	}
+	if budget != nil {
+		return budget.reserve(ctx)
+	}
+	return nil
+}
+
+func recordTokens(ctx context.Context, budget *LLMBudget, limits *RunLimits, tokens int64) {
+	if budget != nil {
+		budget.record(ctx, tokens)
+	}
+	if limits != nil {
+		limits.record(tokens)
+	}
+}
+
+func usedTokens(reported openai.Tokens, request openai.Request, output types.Message) int64 {
+	if reported.TotalTokens > 0 {
+		return reported.TotalTokens
+	}
+	total := output.FuzzyTokens()
+	for _, msg := range request.History() {
+		if msg.ImageURL != "" {
+			total += imageTokens
+			msg.ImageURL = ""
+		}
+		total += msg.FuzzyTokens()
+	}
+	return total
+}
+
+type budgetResponse struct {
+	stream chan openai.Delta
+	err    chan error
+	tokens openai.Tokens
+}
+
+func (r *budgetResponse) Message() <-chan openai.Delta { return r.stream }
+func (r *budgetResponse) Error() <-chan error          { return r.err }
+func (r *budgetResponse) Tokens() openai.Tokens        { return r.tokens }
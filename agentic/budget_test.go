@@ -0,0 +1,203 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package agentic
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/basenana/friday/core/providers/openai"
+	fridaytypes "github.com/basenana/friday/core/types"
+)
+
+type memStore struct {
+	mux  sync.Mutex
+	data map[string][]byte
+}
+
+func newMemStore() *memStore {
+	return &memStore{data: map[string][]byte{}}
+}
+
+func (m *memStore) Load(ctx context.Context, source, group, key string, data any) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	raw, ok := m.data[source+"/"+group+"/"+key]
+	if !ok {
+		return fmt.Errorf("no record")
+	}
+	return json.Unmarshal(raw, data)
+}
+
+func (m *memStore) Save(ctx context.Context, source, group, key string, data any) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	m.data[source+"/"+group+"/"+key] = raw
+	return nil
+}
+
+// fakeLLM answers every call with reply and reports tokens for streaming calls.
+type fakeLLM struct {
+	reply  string
+	tokens int64
+	calls  int
+}
+
+func (f *fakeLLM) Completion(ctx context.Context, request openai.Request) openai.Response {
+	f.calls++
+	resp := &budgetResponse{stream: make(chan openai.Delta, 1), err: make(chan error), tokens: openai.Tokens{TotalTokens: f.tokens}}
+	resp.stream <- openai.Delta{Content: f.reply}
+	close(resp.stream)
+	close(resp.err)
+	return resp
+}
+
+func (f *fakeLLM) CompletionNonStreaming(ctx context.Context, request openai.Request) (string, error) {
+	f.calls++
+	return f.reply, nil
+}
+
+func (f *fakeLLM) StructuredPredict(ctx context.Context, request openai.Request, model any) error {
+	f.calls++
+	return nil
+}
+
+func readCompletion(resp openai.Response) (string, error) {
+	var content string
+	for delta := range resp.Message() {
+		content += delta.Content
+	}
+	for err := range resp.Error() {
+		if err != nil {
+			return content, err
+		}
+	}
+	return content, nil
+}
+
+func TestNewLLMBudget(t *testing.T) {
+	budget, err := NewLLMBudget("job", map[string]string{}, nil)
+	if err != nil || budget != nil {
+		t.Fatalf("expect no budget without config, got %v, %v", budget, err)
+	}
+	for _, config := range []map[string]string{
+		{ConfigMaxCalls: "0"},
+		{ConfigMaxTokens: "many"},
+	} {
+		if _, err = NewLLMBudget("job", config, nil); err == nil {
+			t.Errorf("expect error for %v", config)
+		}
+	}
+}
+
+func TestLLMBudget_CallsSharedByJob(t *testing.T) {
+	var (
+		store  = newMemStore()
+		config = map[string]string{ConfigMaxCalls: "3"}
+		fake   = &fakeLLM{reply: "ok"}
+		llm    = budgetClient{Client: fake}
+		req    = openai.NewSimpleRequest("system", fridaytypes.Message{UserMessage: "hello"})
+	)
+
+	// two plugin steps of the same job share the budget through the store
+	first, _ := NewLLMBudget("job-1", config, store)
+	ctx := WithLLMBudget(context.Background(), first)
+	for i := 0; i < 2; i++ {
+		if _, err := llm.CompletionNonStreaming(ctx, req); err != nil {
+			t.Fatalf("call %d failed: %v", i, err)
+		}
+	}
+	second, _ := NewLLMBudget("job-1", config, store)
+	ctx = WithLLMBudget(context.Background(), second)
+	if _, err := readCompletion(llm.Completion(ctx, req)); err != nil {
+		t.Fatalf("third call failed: %v", err)
+	}
+	if _, err := readCompletion(llm.Completion(ctx, req)); !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("expect ErrBudgetExceeded, got %v", err)
+	}
+	if err := llm.StructuredPredict(ctx, req, &struct{}{}); !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("expect ErrBudgetExceeded, got %v", err)
+	}
+	if fake.calls != 3 {
+		t.Errorf("expect 3 upstream calls, got %d", fake.calls)
+	}
+	if usage := second.Usage(ctx); usage.Calls != 3 {
+		t.Errorf("unexpected usage %+v", usage)
+	}
+
+	other, _ := NewLLMBudget("job-2", config, store)
+	if _, err := llm.CompletionNonStreaming(WithLLMBudget(context.Background(), other), req); err != nil {
+		t.Errorf("another job should have its own budget: %v", err)
+	}
+}
+
+func TestLLMBudget_Tokens(t *testing.T) {
+	var (
+		fake = &fakeLLM{reply: "ok", tokens: 60}
+		llm  = budgetClient{Client: fake}
+		req  = openai.NewSimpleRequest("system", fridaytypes.Message{UserMessage: "hello"})
+	)
+	budget, _ := NewLLMBudget("job-tokens", map[string]string{ConfigMaxTokens: "100"}, nil)
+	ctx := WithLLMBudget(context.Background(), budget)
+
+	resp := llm.Completion(ctx, req)
+	if _, err := readCompletion(resp); err != nil {
+		t.Fatalf("first call failed: %v", err)
+	}
+	if resp.Tokens().TotalTokens != 60 {
+		t.Errorf("reported tokens not passed through: %+v", resp.Tokens())
+	}
+	if _, err := readCompletion(llm.Completion(ctx, req)); err != nil {
+		t.Fatalf("second call failed: %v", err)
+	}
+	if _, err := llm.CompletionNonStreaming(ctx, req); !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("expect ErrBudgetExceeded after 120 of 100 tokens, got %v", err)
+	}
+	result := budget.Result(ctx)
+	if result["calls"] != int64(2) || result["tokens"] != int64(120) || result["max_tokens"] != int64(100) {
+		t.Errorf("unexpected result %v", result)
+	}
+}
+
+func TestLLMBudget_EstimatedTokens(t *testing.T) {
+	fake := &fakeLLM{reply: "0123456789"}
+	budget, _ := NewLLMBudget("job-estimate", map[string]string{ConfigMaxTokens: "1000"}, newMemStore())
+	ctx := WithLLMBudget(context.Background(), budget)
+	req := openai.NewSimpleRequest("system", fridaytypes.Message{UserMessage: "hello"})
+	if _, err := (budgetClient{Client: fake}).CompletionNonStreaming(ctx, req); err != nil {
+		t.Fatalf("call failed: %v", err)
+	}
+	if usage := budget.Usage(ctx); usage.Tokens == 0 {
+		t.Errorf("tokens should be estimated when the API reports none: %+v", usage)
+	}
+}
+
+func TestBudgetClient_NoBudget(t *testing.T) {
+	fake := &fakeLLM{reply: "ok"}
+	reply, err := (budgetClient{Client: fake}).CompletionNonStreaming(context.Background(), openai.NewSimpleRequest("system"))
+	if err != nil || reply != "ok" || fake.calls != 1 {
+		t.Errorf("expect passthrough without budget, got %q, %v", reply, err)
+	}
+}
@@ -0,0 +1,346 @@
+package agentic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/basenana/friday/core/providers/openai"
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/logger"
+	"github.com/basenana/plugin/types"
+	"github.com/basenana/plugin/utils"
+	"go.uber.org/zap"
+)
+
+const (
+	categorizePluginName    = "categorize"
+	categorizePluginVersion = "1.0.0"
+
+	defaultCategorizeLabels = 5
+	maxCategorizeLabels     = 20
+	maxKeywordLength        = 50
+)
+
+var CategorizePluginSpec = types.PluginSpec{
+	Name:    categorizePluginName,
+	Version: categorizePluginVersion,
+	Type:    types.TypeProcess,
+	Dependencies: []types.Dependency{
+		{Kind: types.DependencyCapability, Name: types.CapabilityNetwork},
+		{Kind: types.DependencyCapability, Name: types.CapabilityFS, Optional: true},
+	},
+	RequiredConfig: LLMRequiredConfig(),
+	InitParameters: []types.ParameterSpec{
+		{
+			Name:        "system_prompt",
+			Required:    false,
+			Description: "Instructions added to the categorization prompt",
+		},
+	},
+	Parameters: []types.ParameterSpec{
+		{
+			Name:        "message",
+			Required:    false,
+			Description: "Text to categorize, exclusive with file_path",
+		},
+		{
+			Name:        "file_path",
+			Required:    false,
+			Description: "Path to a document to categorize, exclusive with message",
+		},
+		{
+			Name:        "taxonomy",
+			Required:    false,
+			Description: "JSON array of labels, or an object of label to description; keywords are generated without taxonomy",
+		},
+		{
+			Name:        "taxonomy_path",
+			Required:    false,
+			Description: "JSON taxonomy file in the working path, added to taxonomy",
+		},
+		{
+			Name:        "max_labels",
+			Required:    false,
+			Default:     strconv.Itoa(defaultCategorizeLabels),
+			Description: "Most labels assigned to the document",
+		},
+		{
+			Name:        "max_retries",
+			Required:    false,
+			Default:     strconv.Itoa(defaultExtractRetries),
+			Description: "Times to ask the model again when its reply is not a valid label list",
+		},
+		{
+			Name:        "entry_uri",
+			Required:    false,
+			Description: "NanaFS entry whose current keywords are kept in the returned properties",
+		},
+	},
+}
+
+const categorizeTaxonomyPrompt = `You categorize the document the user sends.
+Assign at most %d labels from this taxonomy that describe what the document is about, most relevant first:
+%s
+Assign no label rather than one that does not fit. Use the labels exactly as written.
+Reply with a single JSON value that conforms to this JSON Schema, and nothing else:
+%s`
+
+const categorizeKeywordPrompt = `You tag the document the user sends.
+Generate at most %d short keywords that describe what the document is about, most relevant first.
+Prefer common topic names over phrases copied from the text, and write them in the language of the document.
+Reply with a single JSON value that conforms to this JSON Schema, and nothing else:
+%s`
+
+// Label is a taxonomy entry; the description tells the model when the label applies.
+type Label struct {
+	Name        string
+	Description string
+}
+
+type CategorizePlugin struct {
+	logger     *zap.SugaredLogger
+	fileAccess *utils.FileAccess
+	jobID      string
+	config     map[string]string
+	newLLM     func(config map[string]string) (openai.Client, error)
+}
+
+func (p *CategorizePlugin) Name() string           { return categorizePluginName }
+func (p *CategorizePlugin) Type() types.PluginType { return types.TypeProcess }
+func (p *CategorizePlugin) Version() string        { return categorizePluginVersion }
+
+func (p *CategorizePlugin) Run(ctx context.Context, request *api.Request) (*api.Response, error) {
+	var (
+		message  = api.GetStringParameter("message", request, "")
+		filePath = api.GetStringParameter("file_path", request, "")
+		entryURI = api.GetStringParameter("entry_uri", request, "")
+	)
+	if (message == "") == (filePath == "") {
+		p.logger.Warnw("either message or file_path is required")
+		return api.NewFailedResponse("either message or file_path is required"), nil
+	}
+	if entryURI != "" && request.FS == nil {
+		return api.NewFailedResponse("file system is not available"), nil
+	}
+
+	taxonomy, err := p.loadTaxonomy(request)
+	if err != nil {
+		p.logger.Warnw("invalid taxonomy", "error", err)
+		return api.NewFailedResponse(err.Error()), nil
+	}
+	maxLabels, err := rangeParameter(request, "max_labels", defaultCategorizeLabels, 1, maxCategorizeLabels)
+	if err != nil {
+		return api.NewFailedResponse(err.Error()), nil
+	}
+	retries, err := rangeParameter(request, "max_retries", defaultExtractRetries, 0, maxExtractRetries)
+	if err != nil {
+		return api.NewFailedResponse(err.Error()), nil
+	}
+
+	if filePath != "" {
+		if message, err = loadDocument(ctx, p.fileAccess, p.logger, filePath); err != nil {
+			p.logger.Warnw("load file content failed", "path", filePath, "error", err)
+			return api.NewFailedResponse(err.Error()), nil
+		}
+	}
+	if len([]rune(message)) > maxExtractInput {
+		message = string([]rune(message)[:maxExtractInput])
+	}
+
+	systemPrompt := api.GetStringParameter("system_prompt", request, "")
+	p.logger.Infow("categorize plugin started", "message_len", len(message), "labels", len(taxonomy), "max_labels", maxLabels)
+
+	budget, err := NewLLMBudget(p.jobID, p.config, request.Store)
+	if err != nil {
+		p.logger.Warnw("parse LLM budget failed", "error", err)
+		return api.NewFailedResponse(err.Error()), nil
+	}
+	ctx = WithLLMBudget(ctx, budget)
+	ctx, llmRetries := WithLLMRetries(ctx)
+
+	llm, err := p.newLLM(p.config)
+	if err != nil {
+		p.logger.Warnw("create LLM client failed", "error", err)
+		return api.NewFailedResponse(err.Error()), nil
+	}
+
+	schema := labelSchema(taxonomy, maxLabels)
+	system := categorizePrompt(taxonomy, maxLabels, schema)
+	if systemPrompt != "" {
+		system += "\n\n" + systemPrompt
+	}
+	reply, attempts, err := extractJSON(ctx, llm, p.logger, system, message, schema, retries)
+	if err != nil {
+		p.logger.Warnw("categorize failed", "attempts", attempts, "error", err)
+		return api.NewFailedResponse(err.Error()), nil
+	}
+
+	labels := []string{}
+	for _, label := range reply.(map[string]any)["labels"].([]any) {
+		labels = mergeLabels(labels, []string{label.(string)})
+	}
+
+	keywords := labels
+	if entryURI != "" {
+		props, err := request.FS.GetEntryProperties(ctx, entryURI)
+		if err != nil {
+			return api.NewFailedResponse(fmt.Sprintf("get entry %s properties failed: %s", entryURI, err)), nil
+		}
+		if props != nil {
+			keywords = mergeLabels(append([]string{}, props.Keywords...), labels)
+		}
+	}
+
+	p.logger.Infow("categorize plugin completed", "labels", labels, "attempts", attempts)
+	results := map[string]any{
+		"labels":     labels,
+		"properties": map[string]any{"keywords": keywords},
+		"attempts":   attempts,
+	}
+	if filePath != "" {
+		results["file_path"] = filePath
+	}
+	if entryURI != "" {
+		results["entry_uri"] = entryURI
+	}
+	if budget != nil {
+		results["llm_budget"] = budget.Result(ctx)
+	}
+	if n := llmRetries.Count(); n > 0 {
+		results["llm_retries"] = n
+	}
+	return api.NewResponseWithResult(results), nil
+}
+
+// loadTaxonomy returns the inline taxonomy followed by the one in taxonomy_path, or nil to generate keywords.
+func (p *CategorizePlugin) loadTaxonomy(request *api.Request) ([]Label, error) {
+	var labels []Label
+	if raw := api.GetStringParameter("taxonomy", request, ""); raw != "" {
+		inline, err := parseLabels([]byte(raw))
+		if err != nil {
+			return nil, err
+		}
+		labels = append(labels, inline...)
+	}
+	if taxonomyPath := api.GetStringParameter("taxonomy_path", request, ""); taxonomyPath != "" {
+		data, err := p.fileAccess.Read(taxonomyPath)
+		if err != nil {
+			return nil, fmt.Errorf("read taxonomy file failed: %s", err)
+		}
+		fileLabels, err := parseLabels(data)
+		if err != nil {
+			return nil, err
+		}
+		labels = append(labels, fileLabels...)
+	}
+	return labels, nil
+}
+
+// parseLabels accepts ["label", ...] or {"label": "description", ...}.
+func parseLabels(data []byte) ([]Label, error) {
+	var (
+		names        []string
+		descriptions map[string]string
+		labels       []Label
+	)
+	if err := json.Unmarshal(data, &names); err == nil {
+		for _, name := range names {
+			labels = append(labels, Label{Name: strings.TrimSpace(name)})
+		}
+	} else if err = json.Unmarshal(data, &descriptions); err == nil {
+		for name, description := range descriptions {
+			labels = append(labels, Label{Name: strings.TrimSpace(name), Description: strings.TrimSpace(description)})
+		}
+		sort.Slice(labels, func(i, j int) bool { return labels[i].Name < labels[j].Name })
+	} else {
+		return nil, fmt.Errorf("parse taxonomy failed: expect an array of labels or an object of label to description")
+	}
+	for _, label := range labels {
+		if label.Name == "" {
+			return nil, fmt.Errorf("parse taxonomy failed: empty label")
+		}
+	}
+	return labels, nil
+}
+
+// labelSchema limits the reply to the taxonomy, or to short keywords without taxonomy.
+func labelSchema(taxonomy []Label, maxLabels int) map[string]any {
+	item := map[string]any{"type": "string", "minLength": float64(1), "maxLength": float64(maxKeywordLength)}
+	if len(taxonomy) > 0 {
+		names := make([]any, 0, len(taxonomy))
+		for _, label := range taxonomy {
+			names = append(names, label.Name)
+		}
+		item = map[string]any{"type": "string", "enum": names}
+	}
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"labels": map[string]any{"type": "array", "items": item, "maxItems": float64(maxLabels)},
+		},
+		"required":             []any{"labels"},
+		"additionalProperties": false,
+	}
+}
+
+func categorizePrompt(taxonomy []Label, maxLabels int, schema map[string]any) string {
+	if len(taxonomy) == 0 {
+		return fmt.Sprintf(categorizeKeywordPrompt, maxLabels, compactJSON(schema))
+	}
+	var list strings.Builder
+	for _, label := range taxonomy {
+		list.WriteString("- " + label.Name)
+		if label.Description != "" {
+			list.WriteString(": " + label.Description)
+		}
+		list.WriteString("\n")
+	}
+	return fmt.Sprintf(categorizeTaxonomyPrompt, maxLabels, strings.TrimSuffix(list.String(), "\n"), compactJSON(schema))
+}
+
+// mergeLabels appends the new labels missing from the list, ignoring case.
+func mergeLabels(labels, add []string) []string {
+	for _, label := range add {
+		label = strings.TrimSpace(label)
+		if label == "" {
+			continue
+		}
+		found := false
+		for _, l := range labels {
+			if strings.EqualFold(l, label) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			labels = append(labels, label)
+		}
+	}
+	return labels
+}
+
+func rangeParameter(request *api.Request, name string, defaultVal, minVal, maxVal int) (int, error) {
+	raw := api.GetStringParameter(name, request, "")
+	if raw == "" {
+		return defaultVal, nil
+	}
+	v, err := strconv.Atoi(raw)
+	if err != nil || v < minVal || v > maxVal {
+		return 0, fmt.Errorf("invalid %s [%s]: expect %d to %d", name, raw, minVal, maxVal)
+	}
+	return v, nil
+}
+
+func NewCategorizePlugin(ps types.PluginCall) types.Plugin {
+	return &CategorizePlugin{
+		logger:     logger.NewPluginLogger(categorizePluginName, ps.JobID),
+		fileAccess: utils.NewFileAccess(ps.WorkingPath, ps.JobWorkingPath),
+		jobID:      ps.JobID,
+		config:     PluginLLMConfig(ps.Config, categorizePluginName),
+		newLLM:     NewLLMClient,
+	}
+}
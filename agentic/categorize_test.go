@@ -0,0 +1,165 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package agentic
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/basenana/friday/core/providers/openai"
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/types"
+	"github.com/basenana/plugin/utils"
+)
+
+type propertiesFS struct {
+	api.NanaFS
+	props map[string]*types.Properties
+}
+
+func (f *propertiesFS) GetEntryProperties(ctx context.Context, entryURI string) (*types.Properties, error) {
+	props, ok := f.props[entryURI]
+	if !ok {
+		return nil, errors.New("entry not found")
+	}
+	return props, nil
+}
+
+func newCategorizePlugin(t *testing.T, llm openai.Client) *CategorizePlugin {
+	p := NewCategorizePlugin(types.PluginCall{JobID: t.Name(), WorkingPath: t.TempDir()}).(*CategorizePlugin)
+	p.newLLM = func(map[string]string) (openai.Client, error) { return llm, nil }
+	return p
+}
+
+func TestCategorizePlugin_Taxonomy(t *testing.T) {
+	llm := &scriptedLLM{replies: []string{`{"labels": ["finance", "travel", "finance"]}`}}
+	resp, err := newCategorizePlugin(t, llm).Run(context.Background(), &api.Request{Parameter: map[string]any{
+		"message":  "Hotel invoice for the Berlin trip, 320 EUR",
+		"taxonomy": `{"travel": "trips, hotels and flights", "finance": "invoices, receipts and bank statements", "health": ""}`,
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.IsSucceed {
+		t.Fatalf("expected success, got failure: %s", resp.Message)
+	}
+	if labels := resp.Results["labels"].([]string); strings.Join(labels, ",") != "finance,travel" {
+		t.Errorf("unexpected labels %v", labels)
+	}
+
+	system := llm.requests[0].History()[0].SystemMessage
+	for _, want := range []string{"- finance: invoices, receipts and bank statements\n- health\n- travel: trips", `"enum":["finance","health","travel"]`} {
+		if !strings.Contains(system, want) {
+			t.Errorf("expected %q in the prompt, got %q", want, system)
+		}
+	}
+
+	var props types.Properties
+	utils.UnmarshalMap(resp.Results["properties"].(map[string]any), &props)
+	if strings.Join(props.Keywords, ",") != "finance,travel" {
+		t.Errorf("expected the labels as properties keywords, got %v", props.Keywords)
+	}
+}
+
+func TestCategorizePlugin_RetryOutsideTaxonomy(t *testing.T) {
+	llm := &scriptedLLM{replies: []string{
+		`{"labels": ["hotels"]}`,
+		`{"labels": ["travel"]}`,
+	}}
+	resp, err := newCategorizePlugin(t, llm).Run(context.Background(), &api.Request{Parameter: map[string]any{
+		"message":  "Hotel booking confirmation",
+		"taxonomy": `["travel", "finance"]`,
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.IsSucceed || resp.Results["attempts"] != 2 {
+		t.Fatalf("expected success on the second attempt, got %+v %s", resp.Results, resp.Message)
+	}
+	history := llm.requests[1].History()
+	if feedback := history[len(history)-1].UserMessage; !strings.Contains(feedback, "$.labels[0]") {
+		t.Errorf("expected the invalid label in the retry, got %q", feedback)
+	}
+}
+
+func TestCategorizePlugin_Keywords(t *testing.T) {
+	llm := &scriptedLLM{replies: []string{`{"labels": ["kubernetes", "observability", "tracing"]}`}}
+	resp, err := newCategorizePlugin(t, llm).Run(context.Background(), &api.Request{Parameter: map[string]any{
+		"message":    "Tracing requests across Kubernetes services",
+		"max_labels": "2",
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.IsSucceed || !strings.Contains(resp.Message, "no valid result after 3 attempts") {
+		t.Errorf("expected too many keywords to fail, got %+v %s", resp.Results, resp.Message)
+	}
+	system := llm.requests[0].History()[0].SystemMessage
+	if !strings.Contains(system, "at most 2 short keywords") || !strings.Contains(system, `"maxItems":2`) {
+		t.Errorf("expected the keyword prompt, got %q", system)
+	}
+}
+
+func TestCategorizePlugin_KeepEntryKeywords(t *testing.T) {
+	llm := &scriptedLLM{replies: []string{`{"labels": ["travel"]}`}}
+	fs := &propertiesFS{props: map[string]*types.Properties{"/inbox/booking.pdf": {Keywords: []string{"starred", "Travel"}}}}
+	resp, err := newCategorizePlugin(t, llm).Run(context.Background(), &api.Request{
+		FS: fs,
+		Parameter: map[string]any{
+			"message":   "Hotel booking confirmation",
+			"taxonomy":  `["travel", "finance"]`,
+			"entry_uri": "/inbox/booking.pdf",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.IsSucceed {
+		t.Fatalf("expected success, got failure: %s", resp.Message)
+	}
+	keywords := resp.Results["properties"].(map[string]any)["keywords"].([]string)
+	if strings.Join(keywords, ",") != "starred,Travel" || resp.Results["entry_uri"] != "/inbox/booking.pdf" {
+		t.Errorf("unexpected results %+v", resp.Results)
+	}
+}
+
+func TestCategorizePlugin_InvalidParameters(t *testing.T) {
+	tests := []struct {
+		name      string
+		parameter map[string]any
+		want      string
+	}{
+		{"no input", map[string]any{"taxonomy": `["travel"]`}, "either message or file_path is required"},
+		{"bad taxonomy", map[string]any{"message": "x", "taxonomy": `"travel"`}, "parse taxonomy failed"},
+		{"empty label", map[string]any{"message": "x", "taxonomy": `["travel", " "]`}, "empty label"},
+		{"max labels", map[string]any{"message": "x", "max_labels": "0"}, "invalid max_labels [0]: expect 1 to 20"},
+		{"no fs", map[string]any{"message": "x", "entry_uri": "/a"}, "file system is not available"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := newCategorizePlugin(t, &scriptedLLM{}).Run(context.Background(), &api.Request{Parameter: tt.parameter})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.IsSucceed || !strings.Contains(resp.Message, tt.want) {
+				t.Errorf("expected failure %q, got %q", tt.want, resp.Message)
+			}
+		})
+	}
+}
@@ -0,0 +1,258 @@
+package agentic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	fridaytools "github.com/basenana/friday/core/tools"
+	"github.com/basenana/plugin/types"
+	"go.uber.org/zap"
+)
+
+const (
+	ConfigAllowedCommands = "friday_allowed_commands"
+	ConfigCommandTimeout  = "friday_command_timeout"
+
+	defaultCommandTimeout = 2 * time.Minute
+	maxCommandOutput      = 64 << 10
+)
+
+// AgentCommands are the binaries run_command can be allowed to run. They are declared as
+// optional dependencies of the agents, so the host sandbox limits apply to them. Binaries with
+// a shell escape, e.g. sqlite3 .shell or magick |command file names, are left out.
+var AgentCommands = []string{"ffmpeg", "ffprobe", "pandoc", "pdftotext", "jq"}
+
+// pandocScriptOptions run filters, defaults files or PDF engines through pandoc. pandoc accepts
+// any unambiguous prefix of a long option, so the prefixes are rejected too.
+var pandocScriptOptions = []string{"--filter", "--lua-filter", "--defaults", "--pdf-engine", "--pdf-engine-opt"}
+
+func agentCommandDependencies() []types.Dependency {
+	deps := make([]types.Dependency, 0, len(AgentCommands))
+	for _, name := range AgentCommands {
+		deps = append(deps, types.Dependency{Kind: types.DependencyBinary, Name: name, Optional: true})
+	}
+	return deps
+}
+
+// CommandToolOption enables run_command for the Allowed binaries.
+type CommandToolOption struct {
+	Allowed []string
+	Timeout time.Duration
+}
+
+// ParseCommandToolOption reads friday_allowed_commands and friday_command_timeout, it returns
+// nil when no command is allowed.
+func ParseCommandToolOption(config map[string]string) (*CommandToolOption, error) {
+	var allowed []string
+	for _, name := range strings.Split(config[ConfigAllowedCommands], ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !contains(AgentCommands, name) {
+			return nil, fmt.Errorf("invalid %s: %s is not one of %s", ConfigAllowedCommands, name, strings.Join(AgentCommands, ", "))
+		}
+		allowed = append(allowed, name)
+	}
+	if len(allowed) == 0 {
+		return nil, nil
+	}
+
+	opt := &CommandToolOption{Allowed: allowed, Timeout: defaultCommandTimeout}
+	if raw := config[ConfigCommandTimeout]; raw != "" {
+		timeout, err := time.ParseDuration(raw)
+		if err != nil || timeout <= 0 {
+			return nil, fmt.Errorf("invalid %s [%s]: expect positive duration like 30s", ConfigCommandTimeout, raw)
+		}
+		opt.Timeout = timeout
+	}
+	return opt, nil
+}
+
+// commandProtocols are the URL schemes and ffmpeg protocols that read other files or the
+// network, e.g. file:/etc/passwd or subfile,,start,0,end,0,,:secret.
+var commandProtocols = []string{
+	"file", "http", "https", "ftp", "sftp", "smb", "data", "pipe", "fd", "unix", "tcp", "udp", "tls",
+	"rtmp", "rtmps", "rtp", "rtsp", "srt", "srtp", "hls", "concat", "concatf", "subfile", "crypto",
+	"cache", "async", "tee", "gopher", "gophers", "icecast", "mmsh", "mmst", "ipfs", "ipns", "md5",
+}
+
+var commandProtocolPattern = regexp.MustCompile(`^([A-Za-z][A-Za-z0-9+.-]*)[:,]`)
+
+// checkCommandArgs rejects arguments naming paths outside the working directory, URLs and
+// protocol inputs. Flags such as -o/tmp/out can still pass, the host sandbox is what confines
+// the process.
+func checkCommandArgs(args []string) error {
+	for _, arg := range args {
+		value := arg
+		if i := strings.IndexByte(arg, '='); i >= 0 && strings.HasPrefix(arg, "-") {
+			value = arg[i+1:]
+		}
+		if m := commandProtocolPattern.FindStringSubmatch(value); strings.Contains(value, "://") ||
+			(m != nil && contains(commandProtocols, strings.ToLower(m[1]))) {
+			return fmt.Errorf("argument %s: URLs and protocol inputs are not allowed, use files in the working directory", arg)
+		}
+		if filepath.IsAbs(value) || strings.HasPrefix(value, "~") {
+			return fmt.Errorf("argument %s: absolute paths are not allowed, use paths relative to the working directory", arg)
+		}
+		for _, part := range strings.Split(filepath.ToSlash(value), "/") {
+			if part == ".." {
+				return fmt.Errorf("argument %s: path traversal is not allowed", arg)
+			}
+		}
+	}
+	return nil
+}
+
+// checkScriptArgs rejects the arguments making a command run other programs or scripts: the
+// filters, defaults files, PDF engine options and Lua readers and writers of pandoc.
+func checkScriptArgs(name string, args []string) error {
+	if name != "pandoc" {
+		return nil
+	}
+	for _, arg := range args {
+		switch {
+		case strings.Contains(strings.ToLower(arg), ".lua"):
+			return fmt.Errorf("argument %s: Lua filters, readers and writers are not allowed", arg)
+		case strings.HasPrefix(arg, "--"):
+			option, _, _ := strings.Cut(arg, "=")
+			for _, script := range pandocScriptOptions {
+				if len(option) > 2 && strings.HasPrefix(script, option) {
+					return fmt.Errorf("argument %s: %s is not allowed, it runs other programs", arg, script)
+				}
+			}
+		case strings.HasPrefix(arg, "-") && strings.ContainsAny(arg[1:], "FLd"):
+			// short options may be bundled with their values, e.g. -sFfilter
+			return fmt.Errorf("argument %s: -F, -L and -d are not allowed, pass the values of other short options as separate arguments", arg)
+		}
+	}
+	return nil
+}
+
+type commandResult struct {
+	ExitCode  int    `json:"exit_code"`
+	Stdout    string `json:"stdout"`
+	Stderr    string `json:"stderr"`
+	Truncated bool   `json:"truncated,omitempty"`
+}
+
+// cappedBuffer keeps the first max bytes written and drops the rest.
+type cappedBuffer struct {
+	buf       bytes.Buffer
+	max       int
+	truncated bool
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if room := b.max - b.buf.Len(); room < len(p) {
+		b.truncated = true
+		b.buf.Write(p[:max(room, 0)])
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
+
+// sandboxedRunner is implemented by the sandbox runner, run_command requires both.
+type sandboxedRunner interface {
+	Limited() bool
+	Isolated() bool
+}
+
+// CommandTools returns run_command when the config allows commands. The runner must bound the
+// wall time, CPU and memory of the commands and isolate them from the host files and network,
+// i.e. the manager has a sandbox config with limits and bubblewrap.
+func CommandTools(config map[string]string, runner types.CommandRunner, toolLogger *zap.SugaredLogger) ([]*fridaytools.Tool, error) {
+	opt, err := ParseCommandToolOption(config)
+	if err != nil || opt == nil {
+		return nil, err
+	}
+	if sandboxed, ok := runner.(sandboxedRunner); !ok || !sandboxed.Limited() || !sandboxed.Isolated() {
+		return nil, fmt.Errorf("%s requires a host sandbox with timeout, CPU and memory limits and bubblewrap", ConfigAllowedCommands)
+	}
+	toolLogger.Infow("run_command tool added", "commands", opt.Allowed, "timeout", opt.Timeout)
+	return []*fridaytools.Tool{NewRunCommandTool(runner, *opt, toolLogger)}, nil
+}
+
+func NewRunCommandTool(runner types.CommandRunner, opt CommandToolOption, toolLogger *zap.SugaredLogger) *fridaytools.Tool {
+	return fridaytools.NewTool(
+		"run_command",
+		fridaytools.WithDescription(fmt.Sprintf("Run a command in working directory without a shell and return its exit code, stdout and stderr. Allowed commands: %s. Use paths relative to working directory.", strings.Join(opt.Allowed, ", "))),
+		fridaytools.WithString("command",
+			fridaytools.Required(),
+			fridaytools.Enum(opt.Allowed...),
+			fridaytools.Description("Name of the command to run"),
+		),
+		fridaytools.WithArray("args",
+			fridaytools.Items(map[string]interface{}{"type": "string"}),
+			fridaytools.Description("Arguments of the command, each one a separate item; no shell quoting, pipes or redirections"),
+		),
+		fridaytools.WithToolHandler(func(ctx context.Context, request *fridaytools.Request) (*fridaytools.Result, error) {
+			name, ok := request.Arguments["command"].(string)
+			if !ok || name == "" {
+				toolLogger.Warnw("missing required parameter: command")
+				return fridaytools.NewToolResultError("missing required parameter: command"), nil
+			}
+			if !contains(opt.Allowed, name) {
+				toolLogger.Warnw("command not allowed", "command", name)
+				return fridaytools.NewToolResultError(fmt.Sprintf("command %s is not allowed, use one of: %s", name, strings.Join(opt.Allowed, ", "))), nil
+			}
+
+			var args []string
+			rawArgs, _ := request.Arguments["args"].([]any)
+			for _, a := range rawArgs {
+				s, ok := a.(string)
+				if !ok {
+					return fridaytools.NewToolResultError("args must be strings"), nil
+				}
+				args = append(args, s)
+			}
+			err := checkCommandArgs(args)
+			if err == nil {
+				err = checkScriptArgs(name, args)
+			}
+			if err != nil {
+				toolLogger.Warnw("invalid command arguments", "command", name, "error", err)
+				return fridaytools.NewToolResultError(err.Error()), nil
+			}
+
+			toolLogger.Infow("run_command started", "command", name, "args", args)
+
+			stdout := &cappedBuffer{max: maxCommandOutput}
+			stderr := &cappedBuffer{max: maxCommandOutput}
+			err = runner.Run(ctx, types.Command{Name: name, Args: args, Stdout: stdout, Stderr: stderr, Timeout: opt.Timeout})
+
+			result := commandResult{Stdout: stdout.buf.String(), Stderr: stderr.buf.String(), Truncated: stdout.truncated || stderr.truncated}
+			var exitErr *exec.ExitError
+			switch {
+			case err == nil:
+			case errors.As(err, &exitErr) && exitErr.Exited():
+				// a failing command is a result the agent can read stderr of
+				result.ExitCode = exitErr.ExitCode()
+			default:
+				toolLogger.Warnw("run_command failed", "command", name, "error", err)
+				return fridaytools.NewToolResultError(err.Error()), nil
+			}
+
+			toolLogger.Infow("run_command completed", "command", name, "exit_code", result.ExitCode)
+			data, _ := json.Marshal(result)
+			return fridaytools.NewToolResultText(string(data)), nil
+		}),
+	)
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,202 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package agentic
+
+import (
+	"context"
+	"encoding/json"
+	"os/exec"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	fridaytools "github.com/basenana/friday/core/tools"
+	"github.com/basenana/plugin/logger"
+	"github.com/basenana/plugin/sandbox"
+	"github.com/basenana/plugin/types"
+)
+
+func runCommandTool(t *testing.T, timeout time.Duration, args map[string]any) *fridaytools.Result {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not installed")
+	}
+	runner := sandbox.New(nil, t.TempDir(), []string{"sh"})
+	tool := NewRunCommandTool(runner, CommandToolOption{Allowed: []string{"sh"}, Timeout: timeout}, logger.NewLogger("test"))
+	result, err := tool.Handler(context.Background(), &fridaytools.Request{Arguments: args})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func TestRunCommandTool_Output(t *testing.T) {
+	result := runCommandTool(t, time.Minute, map[string]any{
+		"command": "sh",
+		"args":    []any{"-c", "echo hello; echo oops >&2; exit 3"},
+	})
+	if result.IsError {
+		t.Fatalf("expected result, got error: %s", getResultText(result))
+	}
+	var got commandResult
+	if err := json.Unmarshal([]byte(getResultText(result)), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := commandResult{ExitCode: 3, Stdout: "hello\n", Stderr: "oops\n"}
+	if got != want {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+}
+
+func TestRunCommandTool_Truncated(t *testing.T) {
+	result := runCommandTool(t, time.Minute, map[string]any{
+		"command": "sh",
+		"args":    []any{"-c", "head -c 100000 /dev/zero | tr '\\0' a"},
+	})
+	var got commandResult
+	if err := json.Unmarshal([]byte(getResultText(result)), &got); err != nil {
+		t.Fatal(err)
+	}
+	if !got.Truncated || len(got.Stdout) != maxCommandOutput {
+		t.Errorf("expected stdout cut at %d bytes, got %d truncated=%v", maxCommandOutput, len(got.Stdout), got.Truncated)
+	}
+}
+
+func TestRunCommandTool_Rejected(t *testing.T) {
+	cases := []struct {
+		args map[string]any
+		want string
+	}{
+		{args: map[string]any{"command": "rm", "args": []any{"-rf", "data"}}, want: "command rm is not allowed"},
+		{args: map[string]any{"command": "sh", "args": []any{"/etc/passwd"}}, want: "absolute paths are not allowed"},
+		{args: map[string]any{"command": "sh", "args": []any{"--input=../secret"}}, want: "path traversal is not allowed"},
+		{args: map[string]any{"command": "sh", "args": []any{"-i", "file:secret"}}, want: "protocol inputs are not allowed"},
+		{args: map[string]any{"command": "sh", "args": []any{"-i", "http://169.254.169.254/latest"}}, want: "protocol inputs are not allowed"},
+		{args: map[string]any{"command": "sh", "args": []any{"concat:a.mp4|b.mp4"}}, want: "protocol inputs are not allowed"},
+		{args: map[string]any{"command": "sh", "args": []any{"subfile,,start,0,end,0,,:secret"}}, want: "protocol inputs are not allowed"},
+		{args: map[string]any{"command": "sh", "args": []any{"--input=HTTPS://example.com"}}, want: "protocol inputs are not allowed"},
+		{args: map[string]any{}, want: "missing required parameter: command"},
+	}
+	for _, c := range cases {
+		result := runCommandTool(t, time.Minute, c.args)
+		if !result.IsError || !strings.Contains(getResultText(result), c.want) {
+			t.Errorf("%v: expected error %q, got %q", c.args, c.want, getResultText(result))
+		}
+	}
+}
+
+func TestRunCommandTool_Timeout(t *testing.T) {
+	result := runCommandTool(t, 100*time.Millisecond, map[string]any{"command": "sh", "args": []any{"-c", "sleep 5"}})
+	if !result.IsError || !strings.Contains(getResultText(result), "timed out") {
+		t.Errorf("expected timeout error, got %q", getResultText(result))
+	}
+}
+
+func TestParseCommandToolOption(t *testing.T) {
+	opt, err := ParseCommandToolOption(map[string]string{})
+	if err != nil || opt != nil {
+		t.Errorf("expected disabled without config, got %+v %v", opt, err)
+	}
+
+	opt, err = ParseCommandToolOption(map[string]string{ConfigAllowedCommands: "pandoc, ffmpeg", ConfigCommandTimeout: "30s"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(opt.Allowed, ",") != "pandoc,ffmpeg" || opt.Timeout != 30*time.Second {
+		t.Errorf("unexpected option %+v", opt)
+	}
+
+	for _, config := range []map[string]string{
+		{ConfigAllowedCommands: "bash"},
+		{ConfigAllowedCommands: "sqlite3"},
+		{ConfigAllowedCommands: "jq", ConfigCommandTimeout: "soon"},
+	} {
+		if _, err = ParseCommandToolOption(config); err == nil {
+			t.Errorf("expected error for %v", config)
+		}
+	}
+}
+
+func TestCheckCommandArgs_Values(t *testing.T) {
+	args := []string{"-i", "in.mp4", "-vf", "scale=640:480", "-ss", "00:01:00", "-map", "0:a", "-c:v", "libx264", "-M", "title:Notes", "--metadata=author:me", "out.mp4"}
+	if err := checkCommandArgs(args); err != nil {
+		t.Errorf("expected plain values to pass, got %v", err)
+	}
+}
+
+func TestCheckScriptArgs(t *testing.T) {
+	for _, args := range [][]string{
+		{"--filter", "pandoc-citeproc"},
+		{"--lua-f=filter.txt"},
+		{"-t", "writer.lua", "in.md"},
+		{"--defaults=book.yaml"},
+		{"--pdf-engine-opt=-shell-escape"},
+		{"-sFfilter"},
+		{"-d", "book.yaml"},
+	} {
+		if err := checkScriptArgs("pandoc", args); err == nil {
+			t.Errorf("expected %v to be rejected", args)
+		}
+	}
+	for _, args := range [][]string{
+		{"-s", "in.md", "-o", "out.docx"},
+		{"--from=markdown", "--to", "html", "--data-dir", "pandoc", "--toc"},
+	} {
+		if err := checkScriptArgs("pandoc", args); err != nil {
+			t.Errorf("expected %v to pass, got %v", args, err)
+		}
+	}
+	if err := checkScriptArgs("ffmpeg", []string{"-i", "in.mp4", "-f", "mp3", "out.mp3"}); err != nil {
+		t.Errorf("expected ffmpeg args to pass, got %v", err)
+	}
+}
+
+func TestCommandTools_ForCallWithoutSandbox(t *testing.T) {
+	runner := sandbox.ForCall(types.PluginCall{WorkingPath: t.TempDir()})
+	tools, err := CommandTools(map[string]string{ConfigAllowedCommands: "ffmpeg,pandoc"}, runner, logger.NewLogger("test"))
+	if err == nil || len(tools) != 0 {
+		t.Errorf("expected no command tools without a host sandbox, got %d tools, err %v", len(tools), err)
+	}
+}
+
+func TestCommandTools_RequiresLimits(t *testing.T) {
+	config := map[string]string{ConfigAllowedCommands: "pandoc"}
+	limits := sandbox.Config{Timeout: time.Minute, CgroupRoot: "/sys/fs/cgroup/test", CPUs: 1, MemoryBytes: 1 << 30, Bubblewrap: "bwrap"}
+	noBubblewrap := limits
+	noBubblewrap.Bubblewrap = ""
+	for name, runner := range map[string]types.CommandRunner{
+		"no config":     sandbox.New(nil, t.TempDir(), AgentCommands),
+		"no memory":     sandbox.New(&sandbox.Config{Timeout: time.Minute, CgroupRoot: "/sys/fs/cgroup/test", CPUs: 1, Bubblewrap: "bwrap"}, t.TempDir(), AgentCommands),
+		"no timeout":    sandbox.New(&sandbox.Config{CgroupRoot: "/sys/fs/cgroup/test", CPUs: 1, MemoryBytes: 1 << 30, Bubblewrap: "bwrap"}, t.TempDir(), AgentCommands),
+		"no bubblewrap": sandbox.New(&noBubblewrap, t.TempDir(), AgentCommands),
+	} {
+		if tools, err := CommandTools(config, runner, logger.NewLogger("test")); err == nil || len(tools) != 0 {
+			t.Errorf("%s: expected run_command to be refused, got %d tools", name, len(tools))
+		}
+	}
+
+	tools, err := CommandTools(config, sandbox.New(&limits, t.TempDir(), AgentCommands), logger.NewLogger("test"))
+	if runtime.GOOS != "linux" {
+		if err == nil {
+			t.Error("expected run_command to be refused without cgroup v2")
+		}
+		return
+	}
+	if err != nil || len(tools) != 1 || tools[0].Name != "run_command" {
+		t.Errorf("expected run_command with a limited sandbox, got %d tools %v", len(tools), err)
+	}
+}
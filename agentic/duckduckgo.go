@@ -0,0 +1,168 @@
+package agentic
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// duckduckgo endpoints are replaced in tests.
+var (
+	duckduckgoSearchEndpoint = "https://html.duckduckgo.com/html/"
+	duckduckgoAnswerEndpoint = "https://api.duckduckgo.com/"
+)
+
+var duckduckgoDateFilter = map[string]string{
+	"day":   "d",
+	"week":  "w",
+	"month": "m",
+	"year":  "y",
+}
+
+type duckduckgoTopic struct {
+	Text     string            `json:"Text"`
+	FirstURL string            `json:"FirstURL"`
+	Topics   []duckduckgoTopic `json:"Topics"`
+}
+
+type duckduckgoAnswer struct {
+	Heading       string            `json:"Heading"`
+	AbstractText  string            `json:"AbstractText"`
+	AbstractURL   string            `json:"AbstractURL"`
+	RelatedTopics []duckduckgoTopic `json:"RelatedTopics"`
+}
+
+// duckduckgoSearch needs no key: it reads the results of the HTML search page, and the Instant
+// Answer API when the page yields none, e.g. when DuckDuckGo answers with a bot check.
+func duckduckgoSearch() webSearchFunc {
+	return func(ctx context.Context, query, timeRange string) ([]WebSearchItem, error) {
+		items, searchErr := duckduckgoHTMLSearch(ctx, query, timeRange)
+		if len(items) > 0 {
+			return items, nil
+		}
+		items, err := duckduckgoInstantAnswer(ctx, query)
+		if err != nil {
+			if searchErr != nil {
+				return nil, fmt.Errorf("duckduckgo search failed: %w", searchErr)
+			}
+			return nil, fmt.Errorf("duckduckgo search failed: %w", err)
+		}
+		return items, nil
+	}
+}
+
+func duckduckgoHTMLSearch(ctx context.Context, query, timeRange string) ([]WebSearchItem, error) {
+	params := url.Values{}
+	params.Set("q", query)
+	if df := duckduckgoDateFilter[timeRange]; df != "" {
+		params.Set("df", df)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, duckduckgoSearchEndpoint+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", browserUserAgent)
+
+	cli := &http.Client{Timeout: time.Minute}
+	resp, err := cli.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	doc, err := goquery.NewDocumentFromReader(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []WebSearchItem
+	doc.Find(".result").EachWithBreak(func(_ int, result *goquery.Selection) bool {
+		// ads link through the ad click endpoint
+		if result.HasClass("result--ad") {
+			return true
+		}
+		link := result.Find("a.result__a").First()
+		target := duckduckgoTarget(link.AttrOr("href", ""))
+		if target == "" {
+			return true
+		}
+		var site string
+		if u, err := url.Parse(target); err == nil {
+			site = u.Host
+		}
+		items = append(items, WebSearchItem{
+			Title:   strings.TrimSpace(link.Text()),
+			Content: strings.TrimSpace(result.Find(".result__snippet").First().Text()),
+			Site:    site,
+			URL:     target,
+		})
+		return len(items) < 10
+	})
+	return items, nil
+}
+
+// duckduckgoTarget unwraps the redirect links of the result page.
+func duckduckgoTarget(href string) string {
+	u, err := url.Parse(href)
+	if err != nil {
+		return ""
+	}
+	if target := u.Query().Get("uddg"); target != "" {
+		return target
+	}
+	if u.Scheme == "http" || u.Scheme == "https" {
+		return href
+	}
+	return ""
+}
+
+func duckduckgoInstantAnswer(ctx context.Context, query string) ([]WebSearchItem, error) {
+	params := url.Values{}
+	params.Set("q", query)
+	params.Set("format", "json")
+	params.Set("no_html", "1")
+	params.Set("skip_disambig", "1")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, duckduckgoAnswerEndpoint+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var answer duckduckgoAnswer
+	if err = doSearchRequest(req, &answer); err != nil {
+		return nil, err
+	}
+
+	var items []WebSearchItem
+	if answer.AbstractURL != "" {
+		items = append(items, duckduckgoItem(answer.Heading, answer.AbstractText, answer.AbstractURL))
+	}
+	var addTopics func(topics []duckduckgoTopic)
+	addTopics = func(topics []duckduckgoTopic) {
+		for _, topic := range topics {
+			if len(items) == 10 {
+				return
+			}
+			if topic.FirstURL != "" {
+				items = append(items, duckduckgoItem(topic.Text, topic.Text, topic.FirstURL))
+			}
+			addTopics(topic.Topics)
+		}
+	}
+	addTopics(answer.RelatedTopics)
+	return items, nil
+}
+
+func duckduckgoItem(title, content, rawURL string) WebSearchItem {
+	item := WebSearchItem{Title: title, Content: content, URL: rawURL}
+	if u, err := url.Parse(rawURL); err == nil {
+		item.Site = u.Host
+	}
+	return item
+}
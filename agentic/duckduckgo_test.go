@@ -0,0 +1,123 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package agentic
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	fridaytools "github.com/basenana/friday/core/tools"
+	"github.com/basenana/plugin/logger"
+)
+
+const duckduckgoResultPage = `<html><body>
+<div class="result results_links result--ad"><a class="result__a" href="https://duckduckgo.com/y.js?ad_domain=ads.example">Ad</a></div>
+<div class="result results_links">
+  <h2><a class="result__a" href="//duckduckgo.com/l/?uddg=https%3A%2F%2Fgo.dev%2Fdoc%2F&amp;rut=abc">The Go Programming Language</a></h2>
+  <a class="result__snippet" href="//duckduckgo.com/l/?uddg=https%3A%2F%2Fgo.dev%2Fdoc%2F">Documentation for Go</a>
+</div>
+</body></html>`
+
+func newDuckDuckGoServer(t *testing.T, page string, answer string) *http.Request {
+	var got http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/html/":
+			got = *r
+			_, _ = w.Write([]byte(page))
+		default:
+			_, _ = w.Write([]byte(answer))
+		}
+	}))
+	t.Cleanup(server.Close)
+	oldSearch, oldAnswer := duckduckgoSearchEndpoint, duckduckgoAnswerEndpoint
+	duckduckgoSearchEndpoint, duckduckgoAnswerEndpoint = server.URL+"/html/", server.URL+"/"
+	t.Cleanup(func() { duckduckgoSearchEndpoint, duckduckgoAnswerEndpoint = oldSearch, oldAnswer })
+	return &got
+}
+
+func duckduckgoSearchItems(t *testing.T, args map[string]any) []WebSearchItem {
+	search := getToolByName(NewDuckDuckGoWebSearchTool(newWebCitations(t.TempDir()), logger.NewLogger("test")), "web_search")
+	result, err := search.Handler(t.Context(), &fridaytools.Request{Arguments: args})
+	if err != nil || result.IsError {
+		t.Fatalf("search failed: %v %s", err, getResultText(result))
+	}
+	var items []WebSearchItem
+	if err = json.Unmarshal([]byte(getResultText(result)), &items); err != nil {
+		t.Fatalf("decode result failed: %v", err)
+	}
+	return items
+}
+
+func TestDuckDuckGoWebSearch(t *testing.T) {
+	got := newDuckDuckGoServer(t, duckduckgoResultPage, `{}`)
+	items := duckduckgoSearchItems(t, map[string]any{"query": "golang", "time_range": "week"})
+
+	if q := got.URL.Query(); q.Get("q") != "golang" || q.Get("df") != "w" {
+		t.Errorf("unexpected query %s", got.URL.RawQuery)
+	}
+	if got.Header.Get("User-Agent") == "" {
+		t.Error("expected a User-Agent")
+	}
+	want := WebSearchItem{Title: "The Go Programming Language", Content: "Documentation for Go", Site: "go.dev", URL: "https://go.dev/doc/"}
+	if len(items) != 1 || items[0] != want {
+		t.Errorf("expected %+v, got %+v", want, items)
+	}
+}
+
+func TestDuckDuckGoInstantAnswerFallback(t *testing.T) {
+	newDuckDuckGoServer(t, `<html><body>bot check</body></html>`, `{
+		"Heading": "Go (programming language)",
+		"AbstractText": "Go is a programming language.",
+		"AbstractURL": "https://en.wikipedia.org/wiki/Go_(programming_language)",
+		"RelatedTopics": [
+			{"Text": "Gopher - mascot", "FirstURL": "https://duckduckgo.com/Gopher"},
+			{"Name": "Tools", "Topics": [{"Text": "gofmt - formatter", "FirstURL": "https://duckduckgo.com/Gofmt"}]}
+		]}`)
+	items := duckduckgoSearchItems(t, map[string]any{"query": "golang"})
+
+	if len(items) != 3 {
+		t.Fatalf("expected 3 items, got %+v", items)
+	}
+	if items[0].Title != "Go (programming language)" || items[0].Site != "en.wikipedia.org" {
+		t.Errorf("unexpected abstract item %+v", items[0])
+	}
+	if items[2].URL != "https://duckduckgo.com/Gofmt" {
+		t.Errorf("expected nested topic, got %+v", items[2])
+	}
+}
+
+func TestResearchWebSearchFallback(t *testing.T) {
+	cases := []struct {
+		config map[string]string
+		tools  bool
+	}{
+		{config: map[string]string{}, tools: true},
+		{config: map[string]string{"friday_websearch_type": "bing"}, tools: true},
+		{config: map[string]string{"friday_websearch_type": "duckduckgo"}, tools: true},
+		{config: map[string]string{"friday_websearch_type": "none"}, tools: false},
+	}
+	for _, c := range cases {
+		p := &ResearchPlugin{config: c.config, webCitations: newWebCitations(t.TempDir()), logger: logger.NewLogger("test")}
+		got := getToolByName(p.webSearchTools(), "web_search") != nil
+		if got != c.tools {
+			t.Errorf("config %v: expected web_search %v, got %v", c.config, c.tools, got)
+		}
+	}
+}
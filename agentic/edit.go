@@ -0,0 +1,175 @@
+package agentic
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// textEdit replaces OldString with NewString, which must occur exactly once unless ReplaceAll is set.
+type textEdit struct {
+	OldString  string
+	NewString  string
+	ReplaceAll bool
+}
+
+func parseTextEdits(raw []any) ([]textEdit, error) {
+	edits := make([]textEdit, 0, len(raw))
+	for i, item := range raw {
+		m, ok := item.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("edit %d is not an object", i+1)
+		}
+		var edit textEdit
+		edit.OldString, _ = m["old_string"].(string)
+		edit.NewString, _ = m["new_string"].(string)
+		edit.ReplaceAll, _ = m["replace_all"].(bool)
+		if edit.OldString == "" {
+			return nil, fmt.Errorf("edit %d has no old_string", i+1)
+		}
+		if edit.OldString == edit.NewString {
+			return nil, fmt.Errorf("edit %d has the same old_string and new_string", i+1)
+		}
+		edits = append(edits, edit)
+	}
+	return edits, nil
+}
+
+// applyTextEdits applies the edits in order, each one to the result of the previous.
+func applyTextEdits(content string, edits []textEdit) (string, error) {
+	for i, edit := range edits {
+		count := strings.Count(content, edit.OldString)
+		switch {
+		case count == 0:
+			return "", fmt.Errorf("edit %d: old_string not found", i+1)
+		case count > 1 && !edit.ReplaceAll:
+			return "", fmt.Errorf("edit %d: old_string found %d times, add surrounding lines to make it unique or set replace_all", i+1, count)
+		}
+		content = strings.ReplaceAll(content, edit.OldString, edit.NewString)
+	}
+	return content, nil
+}
+
+var hunkHeader = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+type diffHunk struct {
+	oldStart int
+	oldLines []string
+	newLines []string
+}
+
+func parseUnifiedDiff(patch string) ([]diffHunk, error) {
+	var (
+		hunks   []diffHunk
+		current *diffHunk
+	)
+	for _, line := range strings.Split(strings.TrimRight(patch, "\n"), "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if m := hunkHeader.FindStringSubmatch(line); m != nil {
+			start, _ := strconv.Atoi(m[1])
+			hunks = append(hunks, diffHunk{oldStart: start})
+			current = &hunks[len(hunks)-1]
+			continue
+		}
+		if current == nil {
+			// file headers and other text before the first hunk
+			continue
+		}
+		switch {
+		case strings.HasPrefix(line, `\`):
+			// "\ No newline at end of file", the file keeps its final newline as it is
+		case strings.HasPrefix(line, "---"), strings.HasPrefix(line, "+++"):
+			if len(current.oldLines) > 0 || len(current.newLines) > 0 {
+				return nil, fmt.Errorf("patch changes more than one file")
+			}
+		case strings.HasPrefix(line, "-"):
+			current.oldLines = append(current.oldLines, line[1:])
+		case strings.HasPrefix(line, "+"):
+			current.newLines = append(current.newLines, line[1:])
+		case strings.HasPrefix(line, " "):
+			current.oldLines = append(current.oldLines, line[1:])
+			current.newLines = append(current.newLines, line[1:])
+		case line == "":
+			// an empty context line whose leading space was stripped
+			current.oldLines = append(current.oldLines, "")
+			current.newLines = append(current.newLines, "")
+		default:
+			return nil, fmt.Errorf("invalid patch line %q", line)
+		}
+	}
+	if len(hunks) == 0 {
+		return nil, fmt.Errorf("patch has no hunks")
+	}
+	return hunks, nil
+}
+
+// applyUnifiedDiff applies the hunks in order. A hunk is placed where its context and removed
+// lines match, the nearest to the line number of its header; trailing whitespace is ignored,
+// so line numbers may be off but the text must be right.
+func applyUnifiedDiff(content, patch string) (string, int, error) {
+	hunks, err := parseUnifiedDiff(patch)
+	if err != nil {
+		return "", 0, err
+	}
+
+	trailingNewline := strings.HasSuffix(content, "\n")
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	if content == "" {
+		lines = nil
+	}
+
+	var offset, from int
+	for i, hunk := range hunks {
+		expected := max(hunk.oldStart-1, 0) + offset
+		pos := findHunk(lines, hunk.oldLines, from, expected)
+		if pos < 0 {
+			return "", 0, fmt.Errorf("hunk %d (@@ -%d) does not match the file", i+1, hunk.oldStart)
+		}
+		updated := make([]string, 0, len(lines)-len(hunk.oldLines)+len(hunk.newLines))
+		updated = append(updated, lines[:pos]...)
+		updated = append(updated, hunk.newLines...)
+		updated = append(updated, lines[pos+len(hunk.oldLines):]...)
+		lines = updated
+		from = pos + len(hunk.newLines)
+		offset += len(hunk.newLines) - len(hunk.oldLines)
+	}
+
+	result := strings.Join(lines, "\n")
+	if trailingNewline || (content == "" && len(lines) > 0) {
+		result += "\n"
+	}
+	return result, len(hunks), nil
+}
+
+func findHunk(lines, old []string, from, expected int) int {
+	if len(old) == 0 {
+		return min(max(expected, from), len(lines))
+	}
+	best := -1
+	for pos := from; pos+len(old) <= len(lines); pos++ {
+		if !matchLines(lines[pos:pos+len(old)], old) {
+			continue
+		}
+		if best < 0 || abs(pos-expected) < abs(best-expected) {
+			best = pos
+		}
+	}
+	return best
+}
+
+func matchLines(lines, want []string) bool {
+	for i := range want {
+		if strings.TrimRight(lines[i], " \t\r") != strings.TrimRight(want[i], " \t\r") {
+			return false
+		}
+	}
+	return true
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,286 @@
+package agentic
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/basenana/friday/core/providers/openai"
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/logger"
+	"github.com/basenana/plugin/types"
+	"github.com/basenana/plugin/utils"
+	"go.uber.org/zap"
+)
+
+const (
+	enrichPluginName    = "enrich"
+	enrichPluginVersion = "1.0.0"
+
+	defaultEnrichKeywords = 5
+	maxEnrichAbstract     = 2000
+)
+
+var EnrichPluginSpec = types.PluginSpec{
+	Name:    enrichPluginName,
+	Version: enrichPluginVersion,
+	Type:    types.TypeProcess,
+	Dependencies: []types.Dependency{
+		{Kind: types.DependencyCapability, Name: types.CapabilityNetwork},
+		{Kind: types.DependencyCapability, Name: types.CapabilityFS, Optional: true},
+	},
+	RequiredConfig: LLMRequiredConfig(),
+	InitParameters: []types.ParameterSpec{
+		{
+			Name:        "system_prompt",
+			Required:    false,
+			Description: "Instructions added to the enrichment prompt",
+		},
+	},
+	Parameters: []types.ParameterSpec{
+		{
+			Name:        "file_path",
+			Required:    true,
+			Description: "Path to the document to describe",
+		},
+		{
+			Name:        "language",
+			Required:    false,
+			Description: "Language of the abstract and keywords, e.g. en; the language of the document when empty",
+		},
+		{
+			Name:        "max_keywords",
+			Required:    false,
+			Default:     strconv.Itoa(defaultEnrichKeywords),
+			Description: "Most keywords generated for the document",
+		},
+		{
+			Name:        "max_retries",
+			Required:    false,
+			Default:     strconv.Itoa(defaultExtractRetries),
+			Description: "Times to ask the model again when its reply does not conform",
+		},
+		{
+			Name:        "entry_uri",
+			Required:    false,
+			Description: "NanaFS entry whose current keywords are kept in the returned properties",
+		},
+	},
+}
+
+const enrichPrompt = `You catalog the document the user sends for a personal archive.
+Describe it with:
+- title: the title of the document, "" when the document has none
+- abstract: what the document is about in 2 to 5 sentences, %s
+- keywords: at most %d short keywords, most relevant first, %s; prefer common topic names over phrases copied from the text
+- author: the author or organization that wrote it, "" when the document does not tell
+- publish_date: when it was published, as YYYY-MM-DD, YYYY-MM or YYYY, "" when the document does not tell
+- language: the language code of the abstract and keywords, e.g. en, de, zh-cn
+Do not guess an author or a date that the document does not support.%s
+Reply with a single JSON value that conforms to this JSON Schema, and nothing else:
+%s`
+
+type EnrichPlugin struct {
+	logger     *zap.SugaredLogger
+	fileAccess *utils.FileAccess
+	jobID      string
+	config     map[string]string
+	newLLM     func(config map[string]string) (openai.Client, error)
+}
+
+func (p *EnrichPlugin) Name() string           { return enrichPluginName }
+func (p *EnrichPlugin) Type() types.PluginType { return types.TypeProcess }
+func (p *EnrichPlugin) Version() string        { return enrichPluginVersion }
+
+func (p *EnrichPlugin) Run(ctx context.Context, request *api.Request) (*api.Response, error) {
+	var (
+		filePath = api.GetStringParameter("file_path", request, "")
+		entryURI = api.GetStringParameter("entry_uri", request, "")
+		language = types.NormalizeLanguage(api.GetStringParameter("language", request, ""))
+	)
+	if filePath == "" {
+		p.logger.Warnw("file_path parameter is required")
+		return api.NewFailedResponse("file_path parameter is required"), nil
+	}
+	if entryURI != "" && request.FS == nil {
+		return api.NewFailedResponse("file system is not available"), nil
+	}
+	maxKeywords, err := rangeParameter(request, "max_keywords", defaultEnrichKeywords, 1, maxCategorizeLabels)
+	if err != nil {
+		return api.NewFailedResponse(err.Error()), nil
+	}
+	retries, err := rangeParameter(request, "max_retries", defaultExtractRetries, 0, maxExtractRetries)
+	if err != nil {
+		return api.NewFailedResponse(err.Error()), nil
+	}
+
+	doc, err := loadParsedDocument(ctx, p.fileAccess, p.logger, filePath)
+	if err == nil && strings.TrimSpace(doc.Content) == "" {
+		err = fmt.Errorf("no text found in %s", filePath)
+	}
+	if err != nil {
+		p.logger.Warnw("load file content failed", "path", filePath, "error", err)
+		return api.NewFailedResponse(err.Error()), nil
+	}
+	message := doc.Content
+	if len([]rune(message)) > maxExtractInput {
+		message = string([]rune(message)[:maxExtractInput])
+	}
+
+	systemPrompt := api.GetStringParameter("system_prompt", request, "")
+	p.logger.Infow("enrich plugin started", "message_len", len(message), "max_keywords", maxKeywords, "language", language)
+
+	budget, err := NewLLMBudget(p.jobID, p.config, request.Store)
+	if err != nil {
+		p.logger.Warnw("parse LLM budget failed", "error", err)
+		return api.NewFailedResponse(err.Error()), nil
+	}
+	ctx = WithLLMBudget(ctx, budget)
+	ctx, llmRetries := WithLLMRetries(ctx)
+
+	llm, err := p.newLLM(p.config)
+	if err != nil {
+		p.logger.Warnw("create LLM client failed", "error", err)
+		return api.NewFailedResponse(err.Error()), nil
+	}
+
+	schema := enrichSchema(maxKeywords)
+	system := enrichSystemPrompt(doc.Properties, language, maxKeywords, schema)
+	if systemPrompt != "" {
+		system += "\n\n" + systemPrompt
+	}
+	reply, attempts, err := extractJSON(ctx, llm, p.logger, system, message, schema, retries)
+	if err != nil {
+		p.logger.Warnw("enrich failed", "attempts", attempts, "error", err)
+		return api.NewFailedResponse(err.Error()), nil
+	}
+
+	var current *types.Properties
+	if entryURI != "" {
+		if current, err = request.FS.GetEntryProperties(ctx, entryURI); err != nil {
+			return api.NewFailedResponse(fmt.Sprintf("get entry %s properties failed: %s", entryURI, err)), nil
+		}
+	}
+	properties := enrichProperties(reply.(map[string]any), doc.Properties, current, language)
+
+	p.logger.Infow("enrich plugin completed", "keywords", properties["keywords"], "attempts", attempts)
+	results := map[string]any{
+		"file_path":  filePath,
+		"properties": properties,
+		"attempts":   attempts,
+	}
+	if entryURI != "" {
+		results["entry_uri"] = entryURI
+	}
+	if budget != nil {
+		results["llm_budget"] = budget.Result(ctx)
+	}
+	if n := llmRetries.Count(); n > 0 {
+		results["llm_retries"] = n
+	}
+	return api.NewResponseWithResult(results), nil
+}
+
+func enrichSchema(maxKeywords int) map[string]any {
+	text := func(maxLength int) map[string]any {
+		return map[string]any{"type": "string", "maxLength": float64(maxLength)}
+	}
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"title":    text(300),
+			"abstract": map[string]any{"type": "string", "minLength": float64(1), "maxLength": float64(maxEnrichAbstract)},
+			"keywords": map[string]any{
+				"type":     "array",
+				"items":    map[string]any{"type": "string", "minLength": float64(1), "maxLength": float64(maxKeywordLength)},
+				"maxItems": float64(maxKeywords),
+			},
+			"author":       text(200),
+			"publish_date": map[string]any{"type": "string", "pattern": `^(\d{4}(-\d{2}(-\d{2})?)?)?$`},
+			"language":     text(20),
+		},
+		"required":             []any{"title", "abstract", "keywords", "author", "publish_date", "language"},
+		"additionalProperties": false,
+	}
+}
+
+// enrichSystemPrompt gives the metadata of the file to the model as hints, e.g. the author of a PDF.
+func enrichSystemPrompt(known types.Properties, language string, maxKeywords int, schema map[string]any) string {
+	inLanguage := "in the language of the document"
+	if language != "" {
+		inLanguage = "in the language " + language
+	}
+	var hints []string
+	for name, value := range map[string]string{"title": known.Title, "author": known.Author, "year": known.Year} {
+		if value != "" {
+			hints = append(hints, fmt.Sprintf("%s %q", name, value))
+		}
+	}
+	hint := ""
+	if len(hints) > 0 {
+		// map order is random, keep the prompt stable
+		sort.Strings(hints)
+		hint = "\nThe file metadata, possibly guessed from the file name, gives " + strings.Join(hints, ", ") + "."
+	}
+	return fmt.Sprintf(enrichPrompt, inLanguage, maxKeywords, inLanguage, hint, compactJSON(schema))
+}
+
+// enrichProperties returns the properties for the update plugin. The metadata of the file,
+// partly guessed from the file name, only fills what the model did not find; its publish time
+// is not used, as the parsers fall back to the modification time. The keywords of the entry are
+// kept before the new ones.
+func enrichProperties(reply map[string]any, known types.Properties, current *types.Properties, language string) map[string]any {
+	text := func(key, fallback string) string {
+		if s, _ := reply[key].(string); strings.TrimSpace(s) != "" {
+			return strings.TrimSpace(s)
+		}
+		return strings.TrimSpace(fallback)
+	}
+
+	keywords := []string{}
+	if current != nil {
+		keywords = mergeLabels(keywords, current.Keywords)
+	}
+	for _, keyword := range reply["keywords"].([]any) {
+		keywords = mergeLabels(keywords, []string{keyword.(string)})
+	}
+	properties := map[string]any{
+		"abstract": text("abstract", ""),
+		"keywords": keywords,
+	}
+	if title := text("title", known.Title); title != "" {
+		properties["title"] = title
+	}
+	if author := text("author", known.Author); author != "" {
+		properties["author"] = author
+	}
+
+	date := text("publish_date", "")
+	if year := text("publish_date", known.Year); year != "" {
+		properties["year"] = year[:4]
+	}
+	if t, err := time.Parse("2006-01-02", date); err == nil {
+		properties["publish_at"] = t.Unix()
+	}
+
+	if language == "" {
+		language = types.NormalizeLanguage(text("language", ""))
+	}
+	if language != "" {
+		properties["language"] = language
+	}
+	return properties
+}
+
+func NewEnrichPlugin(ps types.PluginCall) types.Plugin {
+	return &EnrichPlugin{
+		logger:     logger.NewPluginLogger(enrichPluginName, ps.JobID),
+		fileAccess: utils.NewFileAccess(ps.WorkingPath, ps.JobWorkingPath),
+		jobID:      ps.JobID,
+		config:     PluginLLMConfig(ps.Config, enrichPluginName),
+		newLLM:     NewLLMClient,
+	}
+}
@@ -0,0 +1,142 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package agentic
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/basenana/friday/core/providers/openai"
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/types"
+	"github.com/basenana/plugin/utils"
+)
+
+func newEnrichPlugin(t *testing.T, llm openai.Client, files map[string]string) *EnrichPlugin {
+	workdir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(workdir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	p := NewEnrichPlugin(types.PluginCall{JobID: t.Name(), WorkingPath: workdir}).(*EnrichPlugin)
+	p.newLLM = func(map[string]string) (openai.Client, error) { return llm, nil }
+	return p
+}
+
+func TestEnrichPlugin(t *testing.T) {
+	llm := &scriptedLLM{replies: []string{`{"title": "Pricing and churn", "abstract": "Compares the churn of three pricing plans.", "keywords": ["Pricing", "churn"], "author": "", "publish_date": "2024-06-01", "language": "EN"}`}}
+	p := newEnrichPlugin(t, llm, map[string]string{"ACME_Report_2023.md": "# Pricing and churn\n\nChurn fell to 3 percent after the pricing change."})
+	fs := &propertiesFS{props: map[string]*types.Properties{"/inbox/report.md": {Keywords: []string{"starred", "pricing"}}}}
+	resp, err := p.Run(context.Background(), &api.Request{FS: fs, Parameter: map[string]any{
+		"file_path": "ACME_Report_2023.md",
+		"entry_uri": "/inbox/report.md",
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.IsSucceed {
+		t.Fatalf("expected success, got failure: %s", resp.Message)
+	}
+
+	system := llm.requests[0].History()[0].SystemMessage
+	for _, want := range []string{`author "ACME"`, `year "2023"`, "at most 5 short keywords", `"maxItems":5`} {
+		if !strings.Contains(system, want) {
+			t.Errorf("expected %q in the prompt, got %q", want, system)
+		}
+	}
+
+	var props types.Properties
+	utils.UnmarshalMap(resp.Results["properties"].(map[string]any), &props)
+	if props.Abstract != "Compares the churn of three pricing plans." || props.Title != "Pricing and churn" {
+		t.Errorf("unexpected properties %+v", props)
+	}
+	if strings.Join(props.Keywords, ",") != "starred,pricing,churn" {
+		t.Errorf("expected the new keywords after the current ones, got %v", props.Keywords)
+	}
+	// the model found no author, the one guessed from the file name fills it
+	if props.Author != "ACME" || props.Year != "2024" || props.Language != "en" {
+		t.Errorf("unexpected author, year or language %+v", props)
+	}
+	if want := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC).Unix(); props.PublishAt != want {
+		t.Errorf("expected publish_at %d, got %d", want, props.PublishAt)
+	}
+	if resp.Results["entry_uri"] != "/inbox/report.md" || resp.Results["attempts"] != 1 {
+		t.Errorf("unexpected results %+v", resp.Results)
+	}
+}
+
+func TestEnrichPlugin_OnlyFoundFields(t *testing.T) {
+	llm := &scriptedLLM{replies: []string{
+		`{"title": "", "abstract": "Notes on a trip.", "keywords": ["travel"], "author": "", "publish_date": "May 2024", "language": "de"}`,
+		`{"title": "", "abstract": "Notizen zu einer Reise.", "keywords": ["Reise"], "author": "", "publish_date": "2024-05", "language": "de"}`,
+	}}
+	p := newEnrichPlugin(t, llm, map[string]string{"notes.txt": "Eine Reise nach Berlin im Mai."})
+	resp, err := p.Run(context.Background(), &api.Request{Parameter: map[string]any{
+		"file_path": "notes.txt",
+		"language":  "de",
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.IsSucceed {
+		t.Fatalf("expected success, got failure: %s", resp.Message)
+	}
+	if resp.Results["attempts"] != 2 {
+		t.Errorf("expected a retry after the invalid date, got %v attempts", resp.Results["attempts"])
+	}
+	if system := llm.requests[0].History()[0].SystemMessage; !strings.Contains(system, "in the language de") || strings.Contains(system, `author "`) {
+		t.Errorf("unexpected prompt %q", system)
+	}
+
+	properties := resp.Results["properties"].(map[string]any)
+	// the text parser takes the first line as title
+	if properties["title"] != "Eine Reise nach Berlin im Mai." {
+		t.Errorf("expected the title of the file metadata, got %v", properties["title"])
+	}
+	for _, key := range []string{"author", "publish_at"} {
+		if _, ok := properties[key]; ok {
+			t.Errorf("expected no %s, got %v", key, properties[key])
+		}
+	}
+	if properties["year"] != "2024" || properties["language"] != "de" {
+		t.Errorf("unexpected properties %+v", properties)
+	}
+}
+
+func TestEnrichPlugin_Invalid(t *testing.T) {
+	p := newEnrichPlugin(t, &scriptedLLM{}, map[string]string{"empty.md": "  \n"})
+	for name, params := range map[string]map[string]any{
+		"no file":      {},
+		"no text":      {"file_path": "empty.md"},
+		"unsupported":  {"file_path": "image.png"},
+		"max_keywords": {"file_path": "empty.md", "max_keywords": "50"},
+		"entry no fs":  {"file_path": "empty.md", "entry_uri": "/inbox/empty.md"},
+	} {
+		resp, err := p.Run(context.Background(), &api.Request{Parameter: params})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.IsSucceed {
+			t.Errorf("%s: expected failure", name)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package agentic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+
+	fridaytools "github.com/basenana/friday/core/tools"
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/types"
+	"github.com/basenana/plugin/utils"
+	"go.uber.org/zap"
+)
+
+// EntryTools lets an agent save files of its working directory into NanaFS and update entries,
+// so a step can persist its report without a save step after it.
+type EntryTools struct {
+	fs         api.NanaFS
+	fileAccess *utils.FileAccess
+	logger     *zap.SugaredLogger
+
+	mu      sync.Mutex
+	entries []map[string]any
+}
+
+// NewEntryTools returns nil when the step has no file system.
+func NewEntryTools(fs api.NanaFS, workingPath string, toolLogger *zap.SugaredLogger) *EntryTools {
+	if fs == nil {
+		return nil
+	}
+	return &EntryTools{fs: fs, fileAccess: utils.NewFileAccess(workingPath), logger: toolLogger}
+}
+
+// Tools returns entry_save and entry_update, none without a file system.
+func (e *EntryTools) Tools() []*fridaytools.Tool {
+	if e == nil {
+		return nil
+	}
+	return []*fridaytools.Tool{e.saveTool(), e.updateTool()}
+}
+
+// Result lists the entries the agent saved or updated, it returns nil when there are none.
+func (e *EntryTools) Result() []map[string]any {
+	if e == nil {
+		return nil
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if len(e.entries) == 0 {
+		return nil
+	}
+	return append([]map[string]any(nil), e.entries...)
+}
+
+func (e *EntryTools) record(action, entryURI string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.entries = append(e.entries, map[string]any{"action": action, "entry_uri": entryURI})
+}
+
+func (e *EntryTools) saveTool() *fridaytools.Tool {
+	return fridaytools.NewTool(
+		"entry_save",
+		fridaytools.WithDescription("Save a file of working directory as a new NanaFS entry, e.g. the final report, and return its entry URI."),
+		fridaytools.WithString("file_path",
+			fridaytools.Required(),
+			fridaytools.Description("Relative path to the file within working directory"),
+		),
+		fridaytools.WithString("parent_uri",
+			fridaytools.Required(),
+			fridaytools.Description("URI of the NanaFS group to save the entry in, e.g. /reports"),
+		),
+		fridaytools.WithString("name",
+			fridaytools.Description("Entry name, defaults to the file name"),
+		),
+		fridaytools.WithString("properties",
+			fridaytools.Description(`Entry properties as a JSON object, e.g. {"title":"Market report","abstract":"...","keywords":["market"]}`),
+		),
+		fridaytools.WithToolHandler(func(ctx context.Context, request *fridaytools.Request) (*fridaytools.Result, error) {
+			filePath, _ := request.Arguments["file_path"].(string)
+			if filePath == "" {
+				e.logger.Warnw("missing required parameter: file_path")
+				return fridaytools.NewToolResultError("missing required parameter: file_path"), nil
+			}
+			parentURI, _ := request.Arguments["parent_uri"].(string)
+			if parentURI == "" {
+				e.logger.Warnw("missing required parameter: parent_uri")
+				return fridaytools.NewToolResultError("missing required parameter: parent_uri"), nil
+			}
+			properties, err := parseEntryProperties(request.Arguments["properties"])
+			if err != nil {
+				e.logger.Warnw("entry_save invalid properties", "error", err)
+				return fridaytools.NewToolResultError(err.Error()), nil
+			}
+			properties.PromoteTranslation()
+
+			file, err := e.fileAccess.Open(filePath)
+			if err != nil {
+				e.logger.Warnw("entry_save open file failed", "path", filePath, "error", err)
+				return fridaytools.NewToolResultError(err.Error()), nil
+			}
+			name, _ := request.Arguments["name"].(string)
+			if name == "" {
+				name = path.Base(filePath)
+			}
+			if strings.Contains(name, "/") {
+				_ = file.Close()
+				return fridaytools.NewToolResultError(fmt.Sprintf("invalid name [%s]: must not contain /", name)), nil
+			}
+
+			e.logger.Infow("entry_save started", "path", filePath, "parent_uri", parentURI, "name", name)
+			if err = e.fs.SaveEntry(ctx, parentURI, name, properties, file); err != nil {
+				e.logger.Warnw("entry_save failed", "path", filePath, "error", err)
+				return fridaytools.NewToolResultError(fmt.Sprintf("save entry failed: %s", err)), nil
+			}
+
+			entryURI := path.Join(parentURI, name)
+			e.record("save", entryURI)
+			e.logger.Infow("entry_save completed", "entry_uri", entryURI)
+			return fridaytools.NewToolResultText(fmt.Sprintf("entry saved: %s", entryURI)), nil
+		}),
+	)
+}
+
+func (e *EntryTools) updateTool() *fridaytools.Tool {
+	return fridaytools.NewTool(
+		"entry_update",
+		fridaytools.WithDescription("Update the content or the properties of an existing NanaFS entry, e.g. add the abstract and keywords of a report saved with entry_save."),
+		fridaytools.WithString("entry_uri",
+			fridaytools.Required(),
+			fridaytools.Description("URI of the entry to update"),
+		),
+		fridaytools.WithString("content",
+			fridaytools.Description("New content of the entry"),
+		),
+		fridaytools.WithString("properties",
+			fridaytools.Description(`Entry properties to update as a JSON object, e.g. {"abstract":"...","keywords":["market"]}`),
+		),
+		fridaytools.WithToolHandler(func(ctx context.Context, request *fridaytools.Request) (*fridaytools.Result, error) {
+			entryURI, _ := request.Arguments["entry_uri"].(string)
+			if entryURI == "" {
+				e.logger.Warnw("missing required parameter: entry_uri")
+				return fridaytools.NewToolResultError("missing required parameter: entry_uri"), nil
+			}
+			content, _ := request.Arguments["content"].(string)
+			rawProperties, _ := request.Arguments["properties"].(string)
+			if content == "" && strings.TrimSpace(rawProperties) == "" {
+				e.logger.Warnw("entry_update requires content or properties")
+				return fridaytools.NewToolResultError("either content or properties is required"), nil
+			}
+			properties, err := parseEntryProperties(rawProperties)
+			if err != nil {
+				e.logger.Warnw("entry_update invalid properties", "error", err)
+				return fridaytools.NewToolResultError(err.Error()), nil
+			}
+			// keep the languages this update does not touch, as the update plugin does
+			if len(properties.Translations) > 0 {
+				if current, err := e.fs.GetEntryProperties(ctx, entryURI); err == nil && current != nil {
+					if properties.Language == "" {
+						properties.Language = current.Language
+						properties.NormalizeTranslations()
+					}
+					properties.MergeTranslations(current.Translations)
+				}
+			}
+
+			e.logger.Infow("entry_update started", "entry_uri", entryURI, "content_len", len(content))
+			if err = e.fs.UpdateEntry(ctx, entryURI, content, properties); err != nil {
+				e.logger.Warnw("entry_update failed", "entry_uri", entryURI, "error", err)
+				return fridaytools.NewToolResultError(fmt.Sprintf("update entry failed: %s", err)), nil
+			}
+
+			e.record("update", entryURI)
+			e.logger.Infow("entry_update completed", "entry_uri", entryURI)
+			return fridaytools.NewToolResultText(fmt.Sprintf("entry updated: %s", entryURI)), nil
+		}),
+	)
+}
+
+// parseEntryProperties reads the properties argument, a JSON object given as a string.
+func parseEntryProperties(raw any) (types.Properties, error) {
+	properties := types.Properties{}
+	text, _ := raw.(string)
+	if strings.TrimSpace(text) == "" {
+		return properties, nil
+	}
+	if err := json.Unmarshal([]byte(text), &properties); err != nil {
+		return properties, fmt.Errorf("invalid properties, expect a JSON object: %w", err)
+	}
+	properties.NormalizeTranslations()
+	return properties, nil
+}
@@ -0,0 +1,206 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package agentic
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	fridaytools "github.com/basenana/friday/core/tools"
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/logger"
+	"github.com/basenana/plugin/types"
+	"github.com/basenana/plugin/utils"
+)
+
+type savedEntry struct {
+	parentURI  string
+	name       string
+	content    string
+	properties types.Properties
+}
+
+// entryFS records the saved and updated entries.
+type entryFS struct {
+	api.NanaFS
+	saved   []savedEntry
+	updated map[string]savedEntry
+	current map[string]*types.Properties
+	err     error
+}
+
+func newEntryFS() *entryFS {
+	return &entryFS{updated: map[string]savedEntry{}, current: map[string]*types.Properties{}}
+}
+
+func (f *entryFS) SaveEntry(ctx context.Context, parentURI, name string, properties types.Properties, reader io.ReadCloser) error {
+	defer reader.Close()
+	if f.err != nil {
+		return f.err
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	f.saved = append(f.saved, savedEntry{parentURI: parentURI, name: name, content: string(data), properties: properties})
+	return nil
+}
+
+func (f *entryFS) UpdateEntry(ctx context.Context, entryURI, content string, properties types.Properties) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.updated[entryURI] = savedEntry{content: content, properties: properties}
+	return nil
+}
+
+func (f *entryFS) GetEntryProperties(ctx context.Context, entryURI string) (*types.Properties, error) {
+	props, ok := f.current[entryURI]
+	if !ok {
+		return nil, errors.New("entry not found")
+	}
+	return props, nil
+}
+
+func newEntryTools(t *testing.T, fs api.NanaFS) (*utils.FileAccess, *EntryTools) {
+	workdir := t.TempDir()
+	return utils.NewFileAccess(workdir), NewEntryTools(fs, workdir, logger.NewLogger("test"))
+}
+
+func TestEntryTools_NoFS(t *testing.T) {
+	_, tools := newEntryTools(t, nil)
+	if tools != nil || tools.Tools() != nil || tools.Result() != nil {
+		t.Error("expected no entry tools without a file system")
+	}
+}
+
+func TestEntrySaveTool(t *testing.T) {
+	fs := newEntryFS()
+	fileAccess, tools := newEntryTools(t, fs)
+	if err := fileAccess.Write("report.md", []byte("# Report"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := getToolByName(tools.Tools(), "entry_save").Handler(context.Background(), &fridaytools.Request{Arguments: map[string]any{
+		"file_path":  "report.md",
+		"parent_uri": "/reports",
+		"properties": `{"title": "Market report", "keywords": ["market"]}`,
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.IsError {
+		t.Fatalf("unexpected error: %s", getResultText(result))
+	}
+	if text := getResultText(result); text != "entry saved: /reports/report.md" {
+		t.Errorf("unexpected result %q", text)
+	}
+	if len(fs.saved) != 1 {
+		t.Fatalf("expected one saved entry, got %d", len(fs.saved))
+	}
+	saved := fs.saved[0]
+	if saved.parentURI != "/reports" || saved.name != "report.md" || saved.content != "# Report" {
+		t.Errorf("unexpected saved entry %+v", saved)
+	}
+	if saved.properties.Title != "Market report" || strings.Join(saved.properties.Keywords, ",") != "market" {
+		t.Errorf("unexpected properties %+v", saved.properties)
+	}
+	entries := tools.Result()
+	if len(entries) != 1 || entries[0]["action"] != "save" || entries[0]["entry_uri"] != "/reports/report.md" {
+		t.Errorf("unexpected entries %v", entries)
+	}
+}
+
+func TestEntrySaveTool_Errors(t *testing.T) {
+	fs := newEntryFS()
+	fileAccess, tools := newEntryTools(t, fs)
+	if err := fileAccess.Write("report.md", []byte("# Report"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	save := getToolByName(tools.Tools(), "entry_save")
+
+	for name, args := range map[string]map[string]any{
+		"missing parent":     {"file_path": "report.md"},
+		"missing file":       {"file_path": "missing.md", "parent_uri": "/reports"},
+		"outside workdir":    {"file_path": "../report.md", "parent_uri": "/reports"},
+		"invalid name":       {"file_path": "report.md", "parent_uri": "/reports", "name": "a/b.md"},
+		"invalid properties": {"file_path": "report.md", "parent_uri": "/reports", "properties": "title"},
+	} {
+		result, err := save.Handler(context.Background(), &fridaytools.Request{Arguments: args})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !result.IsError {
+			t.Errorf("%s: expected error result", name)
+		}
+	}
+
+	fs.err = errors.New("quota exceeded")
+	result, _ := save.Handler(context.Background(), &fridaytools.Request{Arguments: map[string]any{"file_path": "report.md", "parent_uri": "/reports"}})
+	if !result.IsError || !strings.Contains(getResultText(result), "quota exceeded") {
+		t.Errorf("expected the save error, got %q", getResultText(result))
+	}
+	if len(fs.saved) != 0 || tools.Result() != nil {
+		t.Error("expected nothing saved")
+	}
+}
+
+func TestEntryUpdateTool(t *testing.T) {
+	fs := newEntryFS()
+	fs.current["/reports/report.md"] = &types.Properties{
+		Title:        "Market report",
+		Language:     "en",
+		Translations: map[string]types.TranslatedProperties{"de": {Title: "Marktbericht"}},
+	}
+	_, tools := newEntryTools(t, fs)
+	update := getToolByName(tools.Tools(), "entry_update")
+
+	result, err := update.Handler(context.Background(), &fridaytools.Request{Arguments: map[string]any{"entry_uri": "/reports/report.md"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.IsError {
+		t.Error("expected an error without content and properties")
+	}
+
+	result, err = update.Handler(context.Background(), &fridaytools.Request{Arguments: map[string]any{
+		"entry_uri":  "/reports/report.md",
+		"properties": `{"abstract": "Prices rose", "translations": {"fr": {"title": "Rapport"}}}`,
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.IsError {
+		t.Fatalf("unexpected error: %s", getResultText(result))
+	}
+	updated, ok := fs.updated["/reports/report.md"]
+	if !ok {
+		t.Fatal("expected the entry to be updated")
+	}
+	if updated.properties.Abstract != "Prices rose" || updated.properties.Language != "en" {
+		t.Errorf("unexpected properties %+v", updated.properties)
+	}
+	if len(updated.properties.Translations) != 2 {
+		t.Errorf("expected the current translations to be kept, got %v", updated.properties.Translations)
+	}
+	if entries := tools.Result(); len(entries) != 1 || entries[0]["action"] != "update" {
+		t.Errorf("unexpected entries %v", entries)
+	}
+}
@@ -0,0 +1,246 @@
+package agentic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/basenana/friday/core/providers/openai"
+	fridaytypes "github.com/basenana/friday/core/types"
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/logger"
+	"github.com/basenana/plugin/types"
+	"github.com/basenana/plugin/utils"
+	"go.uber.org/zap"
+)
+
+const (
+	extractPluginName    = "extract"
+	extractPluginVersion = "1.0.0"
+
+	defaultExtractRetries = 2
+	maxExtractRetries     = 5
+	maxExtractInput       = 100000
+)
+
+var ExtractPluginSpec = types.PluginSpec{
+	Name:           extractPluginName,
+	Version:        extractPluginVersion,
+	Type:           types.TypeProcess,
+	Dependencies:   []types.Dependency{{Kind: types.DependencyCapability, Name: types.CapabilityNetwork}},
+	RequiredConfig: LLMRequiredConfig(),
+	InitParameters: []types.ParameterSpec{
+		{
+			Name:        "system_prompt",
+			Required:    false,
+			Description: "Instructions added to the extraction prompt",
+		},
+	},
+	Parameters: []types.ParameterSpec{
+		{
+			Name:        "message",
+			Required:    false,
+			Description: "Text to extract from, exclusive with file_path",
+		},
+		{
+			Name:        "file_path",
+			Required:    false,
+			Description: "Path to a document to extract from, exclusive with message",
+		},
+		{
+			Name:        "schema",
+			Required:    true,
+			Description: "JSON Schema the result must conform to",
+		},
+		{
+			Name:        "max_retries",
+			Required:    false,
+			Default:     strconv.Itoa(defaultExtractRetries),
+			Description: "Times to ask the model again when its reply does not conform to the schema",
+		},
+	},
+}
+
+const extractPrompt = `You extract structured data from the text the user sends.
+Reply with a single JSON value that conforms to this JSON Schema, and nothing else:
+%s
+Only use information present in the text. Omit optional properties that the text does not provide.`
+
+type ExtractPlugin struct {
+	logger     *zap.SugaredLogger
+	fileAccess *utils.FileAccess
+	jobID      string
+	config     map[string]string
+	newLLM     func(config map[string]string) (openai.Client, error)
+}
+
+func (p *ExtractPlugin) Name() string           { return extractPluginName }
+func (p *ExtractPlugin) Type() types.PluginType { return types.TypeProcess }
+func (p *ExtractPlugin) Version() string        { return extractPluginVersion }
+
+func (p *ExtractPlugin) Run(ctx context.Context, request *api.Request) (*api.Response, error) {
+	var (
+		message  = api.GetStringParameter("message", request, "")
+		filePath = api.GetStringParameter("file_path", request, "")
+	)
+	if (message == "") == (filePath == "") {
+		p.logger.Warnw("either message or file_path is required")
+		return api.NewFailedResponse("either message or file_path is required"), nil
+	}
+
+	schema, err := parseSchema(api.GetStringParameter("schema", request, ""))
+	if err != nil {
+		p.logger.Warnw("invalid schema", "error", err)
+		return api.NewFailedResponse(err.Error()), nil
+	}
+
+	retries, err := rangeParameter(request, "max_retries", defaultExtractRetries, 0, maxExtractRetries)
+	if err != nil {
+		return api.NewFailedResponse(err.Error()), nil
+	}
+
+	if filePath != "" {
+		if message, err = loadDocument(ctx, p.fileAccess, p.logger, filePath); err != nil {
+			p.logger.Warnw("load file content failed", "path", filePath, "error", err)
+			return api.NewFailedResponse(err.Error()), nil
+		}
+	}
+	if len([]rune(message)) > maxExtractInput {
+		message = string([]rune(message)[:maxExtractInput])
+	}
+
+	systemPrompt := api.GetStringParameter("system_prompt", request, "")
+	p.logger.Infow("extract plugin started", "message_len", len(message), "max_retries", retries, "has_system_prompt", systemPrompt != "")
+
+	budget, err := NewLLMBudget(p.jobID, p.config, request.Store)
+	if err != nil {
+		p.logger.Warnw("parse LLM budget failed", "error", err)
+		return api.NewFailedResponse(err.Error()), nil
+	}
+	ctx = WithLLMBudget(ctx, budget)
+	ctx, llmRetries := WithLLMRetries(ctx)
+
+	llm, err := p.newLLM(p.config)
+	if err != nil {
+		p.logger.Warnw("create LLM client failed", "error", err)
+		return api.NewFailedResponse(err.Error()), nil
+	}
+
+	system := fmt.Sprintf(extractPrompt, compactJSON(schema))
+	if systemPrompt != "" {
+		system += "\n\n" + systemPrompt
+	}
+	result, attempts, err := extractJSON(ctx, llm, p.logger, system, message, schema, retries)
+	if err != nil {
+		p.logger.Warnw("extract failed", "attempts", attempts, "error", err)
+		return api.NewFailedResponse(err.Error()), nil
+	}
+
+	p.logger.Infow("extract plugin completed", "attempts", attempts)
+	results := map[string]any{
+		"result":   result,
+		"attempts": attempts,
+	}
+	if filePath != "" {
+		results["file_path"] = filePath
+	}
+	if budget != nil {
+		results["llm_budget"] = budget.Result(ctx)
+	}
+	if n := llmRetries.Count(); n > 0 {
+		results["llm_retries"] = n
+	}
+	return api.NewResponseWithResult(results), nil
+}
+
+// extractJSON asks for the JSON value and, while the reply does not conform to the schema, asks
+// again with the violations of the previous reply.
+func extractJSON(ctx context.Context, llm openai.Client, log *zap.SugaredLogger, system, message string, schema map[string]any, retries int) (any, int, error) {
+	history := []fridaytypes.Message{{UserMessage: message}}
+
+	var problem string
+	for attempt := 1; attempt <= retries+1; attempt++ {
+		reply, err := llm.CompletionNonStreaming(ctx, openai.NewSimpleRequest(system, history...))
+		if err != nil {
+			return nil, attempt, fmt.Errorf("llm call failed: %w", err)
+		}
+
+		value, err := parseJSONReply(reply)
+		if err == nil {
+			errs := validateSchema(schema, value, "$")
+			if len(errs) == 0 {
+				return value, attempt, nil
+			}
+			problem = strings.Join(errs, "; ")
+		} else {
+			problem = err.Error()
+		}
+		log.Infow("reply does not conform to schema", "attempt", attempt, "problem", problem)
+		history = append(history,
+			fridaytypes.Message{AssistantMessage: reply},
+			fridaytypes.Message{UserMessage: "Your reply does not conform to the schema: " + problem + "\nReply again with only the corrected JSON."},
+		)
+	}
+	return nil, retries + 1, fmt.Errorf("no valid result after %d attempts: %s", retries+1, problem)
+}
+
+// parseJSONReply decodes the JSON value of a reply, tolerating markdown fences and text around it.
+func parseJSONReply(reply string) (any, error) {
+	reply = strings.TrimSpace(reply)
+	var value any
+	if err := json.Unmarshal([]byte(reply), &value); err == nil {
+		return value, nil
+	}
+	start := strings.IndexAny(reply, "{[")
+	if start < 0 {
+		return nil, fmt.Errorf("no JSON value in reply")
+	}
+	closing := "}"
+	if reply[start] == '[' {
+		closing = "]"
+	}
+	end := strings.LastIndex(reply, closing)
+	if end < start {
+		return nil, fmt.Errorf("no JSON value in reply")
+	}
+	if err := json.Unmarshal([]byte(reply[start:end+1]), &value); err != nil {
+		return nil, fmt.Errorf("reply is not valid JSON: %s", err)
+	}
+	return value, nil
+}
+
+// loadDocument returns the text of a document in the working path.
+func loadDocument(ctx context.Context, fileAccess *utils.FileAccess, log *zap.SugaredLogger, filePath string) (string, error) {
+	doc, err := loadParsedDocument(ctx, fileAccess, log, filePath)
+	return doc.Content, err
+}
+
+// loadParsedDocument also returns the metadata the parser read from the file, e.g. the author of a PDF.
+func loadParsedDocument(ctx context.Context, fileAccess *utils.FileAccess, log *zap.SugaredLogger, filePath string) (types.Document, error) {
+	absPath, err := fileAccess.GetAbsPath(filePath)
+	if err != nil {
+		return types.Document{}, fmt.Errorf("invalid file_path: %s", err)
+	}
+	parser := newParser(absPath)
+	if parser == nil {
+		return types.Document{}, fmt.Errorf("unsupported file format: %s", filepath.Ext(filePath))
+	}
+	doc, err := parser.Load(logger.IntoContext(ctx, log))
+	if err != nil {
+		return types.Document{}, fmt.Errorf("load file content failed: %s", filePath)
+	}
+	return doc, nil
+}
+
+func NewExtractPlugin(ps types.PluginCall) types.Plugin {
+	return &ExtractPlugin{
+		logger:     logger.NewPluginLogger(extractPluginName, ps.JobID),
+		fileAccess: utils.NewFileAccess(ps.WorkingPath, ps.JobWorkingPath),
+		jobID:      ps.JobID,
+		config:     PluginLLMConfig(ps.Config, extractPluginName),
+		newLLM:     NewLLMClient,
+	}
+}
@@ -0,0 +1,183 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package agentic
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/basenana/friday/core/providers/openai"
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/types"
+)
+
+const contactSchema = `{
+	"type": "object",
+	"properties": {
+		"name": {"type": "string", "minLength": 1},
+		"email": {"type": "string", "format": "email"},
+		"age": {"type": "integer", "minimum": 0},
+		"tags": {"type": "array", "items": {"type": "string", "enum": ["friend", "work"]}}
+	},
+	"required": ["name", "email"],
+	"additionalProperties": false
+}`
+
+// scriptedLLM answers the non-streaming calls with replies in order and keeps the requests.
+type scriptedLLM struct {
+	fakeLLM
+	replies  []string
+	requests []openai.Request
+}
+
+func (s *scriptedLLM) CompletionNonStreaming(ctx context.Context, request openai.Request) (string, error) {
+	s.requests = append(s.requests, request)
+	reply := s.replies[min(len(s.requests), len(s.replies))-1]
+	return reply, nil
+}
+
+func newExtractPlugin(t *testing.T, llm openai.Client) *ExtractPlugin {
+	p := NewExtractPlugin(types.PluginCall{JobID: t.Name(), WorkingPath: t.TempDir()}).(*ExtractPlugin)
+	p.newLLM = func(map[string]string) (openai.Client, error) { return llm, nil }
+	return p
+}
+
+func TestExtractPlugin(t *testing.T) {
+	llm := &scriptedLLM{replies: []string{"```json\n{\"name\": \"Ada\", \"email\": \"ada@example.com\", \"age\": 36, \"tags\": [\"work\"]}\n```"}}
+	resp, err := newExtractPlugin(t, llm).Run(context.Background(), &api.Request{Parameter: map[string]any{
+		"message": "Ada Lovelace, 36, ada@example.com, a colleague",
+		"schema":  contactSchema,
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.IsSucceed {
+		t.Fatalf("expected success, got failure: %s", resp.Message)
+	}
+	result := resp.Results["result"].(map[string]any)
+	if result["name"] != "Ada" || result["age"] != float64(36) || resp.Results["attempts"] != 1 {
+		t.Errorf("unexpected results %+v", resp.Results)
+	}
+	if system := llm.requests[0].History()[0].SystemMessage; !strings.Contains(system, `"required":["name","email"]`) {
+		t.Errorf("expected the schema in the prompt, got %q", system)
+	}
+}
+
+func TestExtractPlugin_RetryOnInvalid(t *testing.T) {
+	llm := &scriptedLLM{replies: []string{
+		`{"name": "Ada", "age": 36.5}`,
+		`{"name": "Ada", "email": "ada@example.com", "age": 36}`,
+	}}
+	resp, err := newExtractPlugin(t, llm).Run(context.Background(), &api.Request{Parameter: map[string]any{
+		"message": "Ada Lovelace, 36, ada@example.com",
+		"schema":  contactSchema,
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.IsSucceed || resp.Results["attempts"] != 2 {
+		t.Fatalf("expected success on the second attempt, got %+v %s", resp.Results, resp.Message)
+	}
+
+	history := llm.requests[1].History()
+	feedback := history[len(history)-1].UserMessage
+	if !strings.Contains(feedback, "$: missing required property email") || !strings.Contains(feedback, "$.age: expect type integer") {
+		t.Errorf("expected the violations in the retry, got %q", feedback)
+	}
+}
+
+func TestExtractPlugin_GiveUp(t *testing.T) {
+	llm := &scriptedLLM{replies: []string{"I could not find any contact."}}
+	resp, err := newExtractPlugin(t, llm).Run(context.Background(), &api.Request{Parameter: map[string]any{
+		"message":     "nothing here",
+		"schema":      contactSchema,
+		"max_retries": "1",
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.IsSucceed || !strings.Contains(resp.Message, "no valid result after 2 attempts: no JSON value in reply") {
+		t.Errorf("expected failure after 2 attempts, got %q", resp.Message)
+	}
+	if len(llm.requests) != 2 {
+		t.Errorf("expected 2 calls, got %d", len(llm.requests))
+	}
+}
+
+func TestExtractPlugin_InvalidRequest(t *testing.T) {
+	cases := map[string]map[string]any{
+		"either message or file_path":      {"schema": contactSchema},
+		"expect a JSON Schema object":      {"message": "x", "schema": "[1]"},
+		"unsupported schema keyword oneOf": {"message": "x", "schema": `{"oneOf": [{"type": "string"}]}`},
+		"invalid max_retries":              {"message": "x", "schema": contactSchema, "max_retries": "9"},
+		"unsupported file format":          {"file_path": "data.bin", "schema": contactSchema},
+	}
+	for want, params := range cases {
+		llm := &scriptedLLM{replies: []string{"{}"}}
+		resp, err := newExtractPlugin(t, llm).Run(context.Background(), &api.Request{Parameter: params})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.IsSucceed || !strings.Contains(resp.Message, want) {
+			t.Errorf("expected failure %q, got %q", want, resp.Message)
+		}
+		if len(llm.requests) != 0 {
+			t.Errorf("%s: expected no LLM call", want)
+		}
+	}
+}
+
+func TestValidateSchema(t *testing.T) {
+	schema, err := parseSchema(`{
+		"type": "array",
+		"minItems": 1,
+		"items": {
+			"type": "object",
+			"properties": {
+				"date": {"type": "string", "format": "date"},
+				"amount": {"type": ["number", "null"], "exclusiveMinimum": 0},
+				"code": {"type": "string", "pattern": "^[A-Z]{3}$"},
+				"note": {"anyOf": [{"type": "string", "maxLength": 5}, {"type": "boolean"}]}
+			}
+		}
+	}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	valid := []any{map[string]any{"date": "2024-03-01", "amount": 12.5, "code": "EUR", "note": true}, map[string]any{"amount": nil}}
+	if errs := validateSchema(schema, valid, "$"); len(errs) != 0 {
+		t.Errorf("expected valid, got %v", errs)
+	}
+
+	invalid := []any{map[string]any{"date": "03/01/2024", "amount": 0.0, "code": "eur", "note": "too long"}}
+	errs := validateSchema(schema, invalid, "$")
+	want := []string{
+		"$[0].amount: expect greater than 0",
+		"$[0].code: does not match pattern ^[A-Z]{3}$",
+		"$[0].date: expect format date",
+		"$[0].note: does not match any schema of anyOf",
+	}
+	if strings.Join(errs, "\n") != strings.Join(want, "\n") {
+		t.Errorf("expected %v, got %v", want, errs)
+	}
+
+	if errs = validateSchema(schema, []any{}, "$"); len(errs) != 1 || errs[0] != "$: expect at least 1 items, got 0" {
+		t.Errorf("expected minItems violation, got %v", errs)
+	}
+}
@@ -0,0 +1,176 @@
+package agentic
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	defaultSearchMatches = 50
+	maxSearchMatches     = 500
+	maxSearchContext     = 5
+	maxSearchFileSize    = 10 << 20
+	maxSnippetLength     = 300
+)
+
+type fileSearchOption struct {
+	Pattern    *regexp.Regexp
+	Glob       string
+	Context    int
+	MaxMatches int
+}
+
+type searchLine struct {
+	Line int    `json:"line"`
+	Text string `json:"text"`
+	// Match is false for the context lines around a match
+	Match bool `json:"match"`
+}
+
+type fileMatches struct {
+	File  string       `json:"file"`
+	Lines []searchLine `json:"lines"`
+}
+
+type fileSearchResult struct {
+	Files     []fileMatches `json:"files"`
+	Matches   int           `json:"matches"`
+	Truncated bool          `json:"truncated,omitempty"`
+}
+
+func compileSearchPattern(pattern string, ignoreCase, literal bool) (*regexp.Regexp, error) {
+	if literal {
+		pattern = regexp.QuoteMeta(pattern)
+	}
+	if ignoreCase {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern: %w", err)
+	}
+	return re, nil
+}
+
+// matchGlob matches a glob without a slash against the file name, and one with a slash
+// against the path relative to the search root.
+func matchGlob(glob, relPath string) bool {
+	if glob == "" {
+		return true
+	}
+	name := relPath
+	if !strings.Contains(glob, "/") {
+		name = path.Base(relPath)
+	}
+	ok, _ := path.Match(glob, name)
+	return ok
+}
+
+// searchFiles greps the files under root. Binary files, files over 10MB and hidden
+// directories are skipped.
+func searchFiles(root string, opt fileSearchOption) (*fileSearchResult, error) {
+	if opt.Glob != "" {
+		if _, err := path.Match(opt.Glob, ""); err != nil {
+			return nil, fmt.Errorf("invalid glob %s", opt.Glob)
+		}
+	}
+	result := &fileSearchResult{Files: []fileMatches{}}
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, _ := filepath.Rel(root, p)
+		relPath = filepath.ToSlash(relPath)
+		if d.IsDir() {
+			if relPath != "." && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if relPath == "." {
+			// the root is a single file
+			relPath = d.Name()
+		}
+		if !d.Type().IsRegular() || !matchGlob(opt.Glob, relPath) {
+			return nil
+		}
+		if result.Truncated {
+			return filepath.SkipAll
+		}
+		info, err := d.Info()
+		if err != nil || info.Size() > maxSearchFileSize {
+			return nil
+		}
+		matches, err := searchFile(p, relPath, opt, result)
+		if err != nil {
+			return err
+		}
+		if matches != nil {
+			result.Files = append(result.Files, *matches)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func searchFile(filePath, relPath string, opt fileSearchOption, result *fileSearchResult) (*fileMatches, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.IndexByte(data[:min(len(data), 512)], 0) >= 0 {
+		return nil, nil
+	}
+
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(data)+1)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+
+	var (
+		matches *fileMatches
+		shown   = -1 // the last line index already in the snippets
+	)
+	for i, line := range lines {
+		if !opt.Pattern.MatchString(line) {
+			continue
+		}
+		if result.Matches >= opt.MaxMatches {
+			result.Truncated = true
+			break
+		}
+		result.Matches++
+		if matches == nil {
+			matches = &fileMatches{File: relPath}
+		}
+		for j := max(i-opt.Context, shown+1); j <= min(i+opt.Context, len(lines)-1); j++ {
+			if j > i && opt.Pattern.MatchString(lines[j]) {
+				// the next match adds its own line and context
+				break
+			}
+			matches.Lines = append(matches.Lines, searchLine{Line: j + 1, Text: snippet(lines[j]), Match: j == i})
+			shown = j
+		}
+	}
+	return matches, nil
+}
+
+func snippet(line string) string {
+	if utf8.RuneCountInString(line) <= maxSnippetLength {
+		return line
+	}
+	return string([]rune(line)[:maxSnippetLength]) + "..."
+}
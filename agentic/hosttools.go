@@ -0,0 +1,108 @@
+package agentic
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	fridaytools "github.com/basenana/friday/core/tools"
+	"go.uber.org/zap"
+)
+
+// ConfigHostTools selects the registered tool sets research can call, comma-separated; all of
+// them when empty, none with "none".
+const ConfigHostTools = "friday_host_tools"
+
+var (
+	hostToolSetPattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+	hostTools = struct {
+		sync.RWMutex
+		sets map[string][]*fridaytools.Tool
+	}{sets: map[string][]*fridaytools.Tool{}}
+)
+
+// RegisterTools adds tools of the embedding application, e.g. clients of its internal APIs or
+// databases, to research as the set name. Registering a name again replaces its tools, and
+// registering no tools removes it. Like http.Handle it panics on an invalid name or tool, as
+// they are programming errors; call it before the plugins run, e.g. from an init function.
+func RegisterTools(name string, tools []*fridaytools.Tool) {
+	if !hostToolSetPattern.MatchString(name) {
+		panic(fmt.Sprintf("agentic: invalid tool set name [%s]: expect 1 to 64 letters, digits, _ or -", name))
+	}
+	seen := map[string]bool{}
+	for i, tool := range tools {
+		if tool == nil || tool.Name == "" || tool.Handler == nil {
+			panic(fmt.Sprintf("agentic: tool %d of set %s requires a name and a handler", i, name))
+		}
+		if seen[tool.Name] {
+			panic(fmt.Sprintf("agentic: duplicate tool %s in set %s", tool.Name, name))
+		}
+		seen[tool.Name] = true
+	}
+
+	hostTools.Lock()
+	defer hostTools.Unlock()
+	if len(tools) == 0 {
+		delete(hostTools.sets, name)
+		return
+	}
+	hostTools.sets[name] = append([]*fridaytools.Tool(nil), tools...)
+}
+
+// RegisteredToolSets returns the names of the registered tool sets, sorted.
+func RegisteredToolSets() []string {
+	hostTools.RLock()
+	defer hostTools.RUnlock()
+	names := make([]string, 0, len(hostTools.sets))
+	for name := range hostTools.sets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// HostTools returns the registered tools selected by friday_host_tools. A tool named like one
+// of builtin, or of an earlier set, is skipped with a warning so it can not shadow it.
+func HostTools(config map[string]string, builtin []*fridaytools.Tool, toolLogger *zap.SugaredLogger) ([]*fridaytools.Tool, error) {
+	registered := RegisteredToolSets()
+	selected := registered
+	switch raw := strings.TrimSpace(config[ConfigHostTools]); raw {
+	case "":
+	case "none":
+		return nil, nil
+	default:
+		selected = nil
+		for _, name := range strings.Split(raw, ",") {
+			name = strings.TrimSpace(name)
+			if name == "" || contains(selected, name) {
+				continue
+			}
+			if !contains(registered, name) {
+				return nil, fmt.Errorf("invalid %s: %s is not a registered tool set", ConfigHostTools, name)
+			}
+			selected = append(selected, name)
+		}
+	}
+
+	names := map[string]bool{}
+	for _, tool := range builtin {
+		names[tool.Name] = true
+	}
+	hostTools.RLock()
+	defer hostTools.RUnlock()
+	var tools []*fridaytools.Tool
+	for _, set := range selected {
+		for _, tool := range hostTools.sets[set] {
+			if names[tool.Name] {
+				toolLogger.Warnw("host tool is named like another tool, skip it", "set", set, "tool", tool.Name)
+				continue
+			}
+			names[tool.Name] = true
+			tools = append(tools, tool)
+		}
+	}
+	return tools, nil
+}
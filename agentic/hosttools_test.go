@@ -0,0 +1,100 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package agentic
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	fridaytools "github.com/basenana/friday/core/tools"
+	"go.uber.org/zap"
+)
+
+func newHostTool(name, reply string) *fridaytools.Tool {
+	return fridaytools.NewTool(name,
+		fridaytools.WithDescription("host tool "+name),
+		fridaytools.WithToolHandler(func(ctx context.Context, request *fridaytools.Request) (*fridaytools.Result, error) {
+			return fridaytools.NewToolResultText(reply), nil
+		}),
+	)
+}
+
+func registerTestTools(t *testing.T, name string, tools ...*fridaytools.Tool) {
+	RegisterTools(name, tools)
+	t.Cleanup(func() { RegisterTools(name, nil) })
+}
+
+func TestHostTools(t *testing.T) {
+	registerTestTools(t, "crm", newHostTool("crm_lookup", "ACME is a customer since 2019"))
+	registerTestTools(t, "billing", newHostTool("billing_invoices", "2 open invoices"), newHostTool("file_read", "shadowed"))
+	builtin := []*fridaytools.Tool{newHostTool("file_read", "builtin")}
+
+	tools, err := HostTools(map[string]string{}, builtin, zap.NewNop().Sugar())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := strings.Join(toolNames(tools), ","); names != "billing_invoices,crm_lookup" {
+		t.Errorf("expected the tools of all sets without the shadowing one, got %s", names)
+	}
+	result, err := getToolByName(tools, "crm_lookup").Handler(context.Background(), &fridaytools.Request{})
+	if err != nil || getResultText(result) != "ACME is a customer since 2019" {
+		t.Errorf("unexpected result %v %v", result, err)
+	}
+
+	tools, err = HostTools(map[string]string{ConfigHostTools: "crm"}, builtin, zap.NewNop().Sugar())
+	if err != nil || strings.Join(toolNames(tools), ",") != "crm_lookup" {
+		t.Errorf("expected the selected set only, got %s %v", strings.Join(toolNames(tools), ","), err)
+	}
+	if tools, err = HostTools(map[string]string{ConfigHostTools: "none"}, builtin, zap.NewNop().Sugar()); err != nil || len(tools) != 0 {
+		t.Errorf("expected no tools, got %s %v", strings.Join(toolNames(tools), ","), err)
+	}
+	if _, err = HostTools(map[string]string{ConfigHostTools: "crm,erp"}, builtin, zap.NewNop().Sugar()); err == nil {
+		t.Error("expected an error for a set that is not registered")
+	}
+}
+
+func TestRegisterTools(t *testing.T) {
+	registerTestTools(t, "crm", newHostTool("crm_lookup", "v1"))
+	RegisterTools("crm", []*fridaytools.Tool{newHostTool("crm_search", "v2")})
+	tools, _ := HostTools(map[string]string{}, nil, zap.NewNop().Sugar())
+	if names := strings.Join(toolNames(tools), ","); names != "crm_search" {
+		t.Errorf("expected the tools to be replaced, got %s", names)
+	}
+	RegisterTools("crm", nil)
+	if sets := RegisteredToolSets(); len(sets) != 0 {
+		t.Errorf("expected the set to be removed, got %v", sets)
+	}
+
+	for name, register := range map[string]func(){
+		"invalid name":   func() { RegisterTools("crm tools", []*fridaytools.Tool{newHostTool("crm_lookup", "")}) },
+		"no handler":     func() { RegisterTools("crm", []*fridaytools.Tool{{Name: "crm_lookup"}}) },
+		"duplicate tool": func() { RegisterTools("crm", []*fridaytools.Tool{newHostTool("a", ""), newHostTool("a", "")}) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: expected a panic", name)
+				}
+			}()
+			register()
+		}()
+	}
+	if sets := RegisteredToolSets(); len(sets) != 0 {
+		t.Errorf("expected no set after the invalid registrations, got %v", sets)
+	}
+}
@@ -0,0 +1,208 @@
+package agentic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/basenana/friday/core/memory"
+	fridaytools "github.com/basenana/friday/core/tools"
+	"github.com/basenana/friday/core/types"
+	"github.com/basenana/plugin/api"
+)
+
+const (
+	LimitToolCalls = "max_tool_calls"
+	LimitTokens    = "max_tokens"
+	LimitDuration  = "max_duration"
+)
+
+var ErrRunLimitExceeded = errors.New("run limit exceeded")
+
+// RunLimits caps the tool calls, tokens and wall time of one agent run. Unlike the LLM budget,
+// which is shared by the job, the limits come from the step parameters; the run is stopped when
+// one is exceeded, and the step returns what the agent produced so far.
+type RunLimits struct {
+	maxToolCalls int64
+	maxTokens    int64
+	maxDuration  time.Duration
+
+	mu        sync.Mutex
+	startAt   time.Time
+	toolCalls int64
+	tokens    int64
+	exceeded  string
+	cancel    context.CancelFunc
+}
+
+// NewRunLimits reads max_tool_calls, max_tokens and max_duration, it returns nil when none is set.
+func NewRunLimits(request *api.Request) (*RunLimits, error) {
+	maxToolCalls, err := parseRunLimit(request, LimitToolCalls)
+	if err != nil {
+		return nil, err
+	}
+	maxTokens, err := parseRunLimit(request, LimitTokens)
+	if err != nil {
+		return nil, err
+	}
+	var maxDuration time.Duration
+	if raw := strings.TrimSpace(api.GetStringParameter(LimitDuration, request, "")); raw != "" {
+		if maxDuration, err = time.ParseDuration(raw); err != nil || maxDuration <= 0 {
+			return nil, fmt.Errorf("invalid %s [%s]: expect positive duration like 10m", LimitDuration, raw)
+		}
+	}
+	if maxToolCalls == 0 && maxTokens == 0 && maxDuration == 0 {
+		return nil, nil
+	}
+	return &RunLimits{maxToolCalls: maxToolCalls, maxTokens: maxTokens, maxDuration: maxDuration}, nil
+}
+
+func parseRunLimit(request *api.Request, name string) (int64, error) {
+	raw := strings.TrimSpace(api.GetStringParameter(name, request, ""))
+	if raw == "" {
+		return 0, nil
+	}
+	n, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid %s [%s]: expect positive integer", name, raw)
+	}
+	return n, nil
+}
+
+type runLimitsKey struct{}
+
+// Start returns the context of the run, it is canceled when a limit is exceeded.
+func (l *RunLimits) Start(ctx context.Context) (context.Context, context.CancelFunc) {
+	if l == nil {
+		return ctx, func() {}
+	}
+	var cancel context.CancelFunc
+	if l.maxDuration > 0 {
+		ctx, cancel = context.WithTimeout(ctx, l.maxDuration)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	l.mu.Lock()
+	l.startAt = time.Now()
+	l.cancel = cancel
+	l.mu.Unlock()
+	return context.WithValue(ctx, runLimitsKey{}, l), cancel
+}
+
+func runLimitsFromContext(ctx context.Context) *RunLimits {
+	limits, _ := ctx.Value(runLimitsKey{}).(*RunLimits)
+	return limits
+}
+
+// Wrap returns copies of the tools whose calls count against max_tool_calls.
+func (l *RunLimits) Wrap(tools []*fridaytools.Tool) []*fridaytools.Tool {
+	if l == nil || l.maxToolCalls == 0 {
+		return tools
+	}
+	wrapped := make([]*fridaytools.Tool, 0, len(tools))
+	for _, tool := range tools {
+		limited := *tool
+		handler := tool.Handler
+		limited.Handler = func(ctx context.Context, request *fridaytools.Request) (*fridaytools.Result, error) {
+			l.mu.Lock()
+			if l.toolCalls >= l.maxToolCalls {
+				l.stop(LimitToolCalls)
+				l.mu.Unlock()
+				return fridaytools.NewToolResultError(fmt.Sprintf("%s: %d tool calls used", ErrRunLimitExceeded, l.maxToolCalls)), nil
+			}
+			l.toolCalls++
+			l.mu.Unlock()
+			return handler(ctx, request)
+		}
+		wrapped = append(wrapped, &limited)
+	}
+	return wrapped
+}
+
+// check refuses LLM calls once the run is stopped.
+func (l *RunLimits) check() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.exceeded != "" {
+		return fmt.Errorf("%w: %s", ErrRunLimitExceeded, l.exceeded)
+	}
+	return nil
+}
+
+func (l *RunLimits) record(tokens int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.tokens += tokens
+	if l.maxTokens > 0 && l.tokens >= l.maxTokens {
+		l.stop(LimitTokens)
+	}
+}
+
+// stop must be called with the lock held.
+func (l *RunLimits) stop(reason string) {
+	if l.exceeded != "" {
+		return
+	}
+	l.exceeded = reason
+	if l.cancel != nil {
+		l.cancel()
+	}
+}
+
+// Exceeded returns the limit that stopped the run, or "" when it completed within the limits.
+func (l *RunLimits) Exceeded() string {
+	if l == nil {
+		return ""
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.exceeded == "" && l.maxDuration > 0 && !l.startAt.IsZero() && time.Since(l.startAt) >= l.maxDuration {
+		l.exceeded = LimitDuration
+	}
+	return l.exceeded
+}
+
+// Result describes the limits and the usage of the run for a plugin response.
+func (l *RunLimits) Result() map[string]any {
+	exceeded := l.Exceeded()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	result := map[string]any{
+		"tool_calls":  l.toolCalls,
+		"tokens":      l.tokens,
+		"duration_ms": time.Since(l.startAt).Milliseconds(),
+	}
+	if l.maxToolCalls > 0 {
+		result[LimitToolCalls] = l.maxToolCalls
+	}
+	if l.maxTokens > 0 {
+		result[LimitTokens] = l.maxTokens
+	}
+	if l.maxDuration > 0 {
+		result[LimitDuration] = l.maxDuration.String()
+	}
+	if exceeded != "" {
+		result["exceeded"] = exceeded
+	}
+	return result
+}
+
+// partialAnswer joins the answers the agent added to the memory, for a run stopped before its
+// final answer. The memory drops its oldest messages, so the prior history is matched by content.
+func partialAnswer(mem *memory.Memory, prior []types.Message) string {
+	seen := make(map[string]bool, len(prior))
+	for _, msg := range prior {
+		seen[msg.AssistantMessage] = true
+	}
+	var parts []string
+	for _, msg := range mem.History() {
+		if answer := strings.TrimSpace(msg.AssistantMessage); answer != "" && !seen[msg.AssistantMessage] {
+			parts = append(parts, answer)
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
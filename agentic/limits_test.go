@@ -0,0 +1,155 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package agentic
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/basenana/friday/core/memory"
+	"github.com/basenana/friday/core/providers/openai"
+	fridaytools "github.com/basenana/friday/core/tools"
+	fridaytypes "github.com/basenana/friday/core/types"
+	"github.com/basenana/plugin/api"
+)
+
+func TestNewRunLimits(t *testing.T) {
+	limits, err := NewRunLimits(&api.Request{Parameter: map[string]any{}})
+	if err != nil || limits != nil {
+		t.Fatalf("expect no limits without parameters, got %v, %v", limits, err)
+	}
+	ctx, cancel := limits.Start(context.Background())
+	defer cancel()
+	if ctx != context.Background() || limits.Exceeded() != "" || len(limits.Wrap([]*fridaytools.Tool{{Name: "t"}})) != 1 {
+		t.Errorf("expect nil limits to change nothing")
+	}
+
+	for _, params := range []map[string]any{
+		{LimitToolCalls: "0"},
+		{LimitTokens: "many"},
+		{LimitDuration: "10"},
+		{LimitDuration: "-1m"},
+	} {
+		if _, err = NewRunLimits(&api.Request{Parameter: params}); err == nil {
+			t.Errorf("expect error for %v", params)
+		}
+	}
+}
+
+func TestRunLimits_ToolCalls(t *testing.T) {
+	limits, err := NewRunLimits(&api.Request{Parameter: map[string]any{LimitToolCalls: "2"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := limits.Start(context.Background())
+	defer cancel()
+
+	var handled int
+	tools := limits.Wrap([]*fridaytools.Tool{{Name: "web_search", Handler: func(ctx context.Context, request *fridaytools.Request) (*fridaytools.Result, error) {
+		handled++
+		return fridaytools.NewToolResultText("ok"), nil
+	}}})
+	for i := 0; i < 2; i++ {
+		if result, _ := tools[0].Handler(ctx, &fridaytools.Request{}); result.IsError {
+			t.Fatalf("call %d refused", i)
+		}
+	}
+	if ctx.Err() != nil {
+		t.Fatalf("expect the run to continue within the limit")
+	}
+	if result, _ := tools[0].Handler(ctx, &fridaytools.Request{}); !result.IsError {
+		t.Errorf("expect the third call refused")
+	}
+	if handled != 2 || ctx.Err() == nil || limits.Exceeded() != LimitToolCalls {
+		t.Errorf("expect the run stopped by %s, handled %d, exceeded %q", LimitToolCalls, handled, limits.Exceeded())
+	}
+	if result := limits.Result(); result["tool_calls"] != int64(2) || result[LimitToolCalls] != int64(2) || result["exceeded"] != LimitToolCalls {
+		t.Errorf("unexpected result %v", result)
+	}
+}
+
+func TestRunLimits_Tokens(t *testing.T) {
+	var (
+		fake = &fakeLLM{reply: "ok", tokens: 60}
+		llm  = budgetClient{Client: fake}
+		req  = openai.NewSimpleRequest("system", fridaytypes.Message{UserMessage: "hello"})
+	)
+	limits, err := NewRunLimits(&api.Request{Parameter: map[string]any{LimitTokens: "100"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the job budget is charged too
+	budget, _ := NewLLMBudget("job-run-limits", map[string]string{ConfigMaxCalls: "10"}, newMemStore())
+	ctx, cancel := limits.Start(WithLLMBudget(context.Background(), budget))
+	defer cancel()
+
+	if _, err = readCompletion(llm.Completion(ctx, req)); err != nil {
+		t.Fatalf("first call failed: %v", err)
+	}
+	if _, err = readCompletion(llm.Completion(ctx, req)); err != nil {
+		t.Fatalf("second call failed: %v", err)
+	}
+	if ctx.Err() == nil || limits.Exceeded() != LimitTokens {
+		t.Fatalf("expect the run stopped after 120 of 100 tokens, exceeded %q", limits.Exceeded())
+	}
+	if _, err = llm.CompletionNonStreaming(ctx, req); !errors.Is(err, ErrRunLimitExceeded) {
+		t.Errorf("expect ErrRunLimitExceeded, got %v", err)
+	}
+	if fake.calls != 2 {
+		t.Errorf("expect 2 upstream calls, got %d", fake.calls)
+	}
+	if usage := budget.Usage(ctx); usage.Calls != 2 || usage.Tokens != 120 {
+		t.Errorf("unexpected job usage %+v", usage)
+	}
+}
+
+func TestRunLimits_Duration(t *testing.T) {
+	limits, err := NewRunLimits(&api.Request{Parameter: map[string]any{LimitDuration: "20ms"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := limits.Start(context.Background())
+	defer cancel()
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("expect the run stopped after max_duration")
+	}
+	if limits.Exceeded() != LimitDuration {
+		t.Errorf("expect exceeded %s, got %q", LimitDuration, limits.Exceeded())
+	}
+	if result := limits.Result(); result[LimitDuration] != "20ms" {
+		t.Errorf("unexpected result %v", result)
+	}
+}
+
+func TestPartialAnswer(t *testing.T) {
+	prior := []fridaytypes.Message{{UserMessage: "earlier task"}, {AssistantMessage: "earlier answer"}}
+	mem := memory.NewEmpty("job-1", memory.WithHistory(prior...))
+	mem.AppendMessages(
+		fridaytypes.Message{UserMessage: "research quantum computing"},
+		fridaytypes.Message{AssistantMessage: "task 1: qubits"},
+		fridaytypes.Message{ToolName: "web_search", ToolContent: "results"},
+		fridaytypes.Message{AssistantMessage: "task 2: error correction"},
+	)
+	if got := partialAnswer(mem, prior); got != "task 1: qubits\n\ntask 2: error correction" {
+		t.Errorf("unexpected partial answer %q", got)
+	}
+}
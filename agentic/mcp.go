@@ -0,0 +1,222 @@
+package agentic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	fridaytools "github.com/basenana/friday/core/tools"
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+	"go.uber.org/zap"
+)
+
+const (
+	// ConfigMCPServers is a JSON array of MCPServerConfig whose tools research can call.
+	ConfigMCPServers = "friday_mcp_servers"
+
+	mcpTransportHTTP = "http"
+	mcpTransportSSE  = "sse"
+
+	mcpConnectTimeout = 30 * time.Second
+	mcpCallTimeout    = 2 * time.Minute
+	maxMCPResult      = 50000
+	maxToolNameLength = 64
+)
+
+// MCPServerConfig is a remote MCP server, reached over the streamable HTTP or the SSE transport.
+type MCPServerConfig struct {
+	Name      string            `json:"name"`
+	URL       string            `json:"url"`
+	Transport string            `json:"transport,omitempty"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	// Tools limits the tools exposed to the agent, all tools of the server when empty.
+	Tools []string `json:"tools,omitempty"`
+}
+
+var mcpNamePattern = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+func ParseMCPServers(raw string) ([]MCPServerConfig, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+	var servers []MCPServerConfig
+	if err := json.Unmarshal([]byte(raw), &servers); err != nil {
+		return nil, fmt.Errorf("parse %s failed: %s", ConfigMCPServers, err)
+	}
+	names := map[string]bool{}
+	for i, s := range servers {
+		if s.Name == "" || s.URL == "" {
+			return nil, fmt.Errorf("mcp server %d requires name and url", i)
+		}
+		if names[s.Name] {
+			return nil, fmt.Errorf("duplicate mcp server %s", s.Name)
+		}
+		names[s.Name] = true
+		switch s.Transport {
+		case "", mcpTransportHTTP, mcpTransportSSE:
+		default:
+			return nil, fmt.Errorf("mcp server %s has unknown transport %s: expect http or sse", s.Name, s.Transport)
+		}
+	}
+	return servers, nil
+}
+
+// MCPTools connects to the servers of friday_mcp_servers and returns their tools, named
+// mcp_<server>_<tool>. A server that can not be reached is skipped with a warning. The
+// returned func closes the connections, it must be called once the agent is done.
+func MCPTools(ctx context.Context, config map[string]string, toolLogger *zap.SugaredLogger) ([]*fridaytools.Tool, func(), error) {
+	servers, err := ParseMCPServers(config[ConfigMCPServers])
+	if err != nil {
+		return nil, func() {}, err
+	}
+
+	var (
+		tools   []*fridaytools.Tool
+		clients []*client.Client
+	)
+	for _, server := range servers {
+		cli, serverTools, err := connectMCPServer(ctx, server, toolLogger)
+		if err != nil {
+			toolLogger.Warnw("connect mcp server failed, skip it", "server", server.Name, "url", server.URL, "error", err)
+			continue
+		}
+		clients = append(clients, cli)
+		tools = append(tools, serverTools...)
+		toolLogger.Infow("mcp server connected", "server", server.Name, "tools", len(serverTools))
+	}
+	return tools, func() {
+		for _, cli := range clients {
+			_ = cli.Close()
+		}
+	}, nil
+}
+
+func connectMCPServer(ctx context.Context, server MCPServerConfig, toolLogger *zap.SugaredLogger) (*client.Client, []*fridaytools.Tool, error) {
+	var (
+		cli *client.Client
+		err error
+	)
+	if server.Transport == mcpTransportSSE {
+		cli, err = client.NewSSEMCPClient(server.URL, transport.WithHeaders(server.Headers))
+	} else {
+		cli, err = client.NewStreamableHttpClient(server.URL, transport.WithHTTPHeaders(server.Headers))
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	connectCtx, cancel := context.WithTimeout(ctx, mcpConnectTimeout)
+	defer cancel()
+	// the SSE stream lives as long as the context passed to Start
+	if err = cli.Start(ctx); err != nil {
+		return nil, nil, err
+	}
+	initRequest := mcp.InitializeRequest{}
+	initRequest.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+	initRequest.Params.ClientInfo = mcp.Implementation{Name: "basenana-plugin", Version: researchPluginVersion}
+	if _, err = cli.Initialize(connectCtx, initRequest); err != nil {
+		_ = cli.Close()
+		return nil, nil, fmt.Errorf("initialize failed: %w", err)
+	}
+	result, err := cli.ListTools(connectCtx, mcp.ListToolsRequest{})
+	if err != nil {
+		_ = cli.Close()
+		return nil, nil, fmt.Errorf("list tools failed: %w", err)
+	}
+
+	var tools []*fridaytools.Tool
+	for _, tool := range result.Tools {
+		if len(server.Tools) > 0 && !contains(server.Tools, tool.Name) {
+			continue
+		}
+		tools = append(tools, newMCPTool(cli, server.Name, tool, toolLogger))
+	}
+	return cli, tools, nil
+}
+
+func newMCPTool(cli *client.Client, serverName string, tool mcp.Tool, toolLogger *zap.SugaredLogger) *fridaytools.Tool {
+	name := mcpToolName(serverName, tool.Name)
+	schema := fridaytools.ToolInputSchema{Type: "object", Properties: tool.InputSchema.Properties, Required: tool.InputSchema.Required}
+	if len(tool.RawInputSchema) > 0 {
+		var raw mcp.ToolInputSchema
+		if err := json.Unmarshal(tool.RawInputSchema, &raw); err == nil {
+			schema.Properties, schema.Required = raw.Properties, raw.Required
+		}
+	}
+	if schema.Properties == nil {
+		schema.Properties = map[string]any{}
+	}
+
+	return &fridaytools.Tool{
+		Name:        name,
+		Description: fmt.Sprintf("[MCP server %s] %s", serverName, tool.Description),
+		InputSchema: schema,
+		Handler: func(ctx context.Context, request *fridaytools.Request) (*fridaytools.Result, error) {
+			toolLogger.Infow("mcp tool started", "tool", name)
+			callCtx, cancel := context.WithTimeout(ctx, mcpCallTimeout)
+			defer cancel()
+
+			callRequest := mcp.CallToolRequest{}
+			callRequest.Params.Name = tool.Name
+			callRequest.Params.Arguments = request.Arguments
+			result, err := cli.CallTool(callCtx, callRequest)
+			if err != nil {
+				toolLogger.Warnw("mcp tool failed", "tool", name, "error", err)
+				return fridaytools.NewToolResultError(fmt.Sprintf("call %s failed: %s", name, err)), nil
+			}
+
+			text := mcpResultText(result)
+			if length := len([]rune(text)); length > maxMCPResult {
+				text = string([]rune(text)[:maxMCPResult]) + fmt.Sprintf("\n\n[truncated: %d of %d characters]", maxMCPResult, length)
+			}
+			if result.IsError {
+				toolLogger.Warnw("mcp tool returned error", "tool", name)
+				return fridaytools.NewToolResultError(text), nil
+			}
+			toolLogger.Infow("mcp tool completed", "tool", name, "length", len(text))
+			return fridaytools.NewToolResultText(text), nil
+		},
+	}
+}
+
+// mcpToolName keeps the name within the characters and length the LLM APIs accept for tools.
+func mcpToolName(server, tool string) string {
+	name := mcpNamePattern.ReplaceAllString("mcp_"+server+"_"+tool, "_")
+	if len(name) > maxToolNameLength {
+		name = name[:maxToolNameLength]
+	}
+	return name
+}
+
+// mcpResultText joins the text parts of the result; images and binary resources are only named,
+// the agent can not read them.
+func mcpResultText(result *mcp.CallToolResult) string {
+	var parts []string
+	for _, content := range result.Content {
+		switch c := content.(type) {
+		case mcp.TextContent:
+			parts = append(parts, c.Text)
+		case mcp.ImageContent:
+			parts = append(parts, fmt.Sprintf("[image %s]", c.MIMEType))
+		case mcp.AudioContent:
+			parts = append(parts, fmt.Sprintf("[audio %s]", c.MIMEType))
+		case mcp.EmbeddedResource:
+			if r, ok := c.Resource.(mcp.TextResourceContents); ok {
+				parts = append(parts, r.Text)
+			} else {
+				parts = append(parts, fmt.Sprintf("[resource %s]", fridaytools.Res2Str(c.Resource)))
+			}
+		case mcp.ResourceLink:
+			parts = append(parts, fmt.Sprintf("[resource %s %s]", c.Name, c.URI))
+		}
+	}
+	if len(parts) == 0 && result.StructuredContent != nil {
+		return fridaytools.Res2Str(result.StructuredContent)
+	}
+	return strings.Join(parts, "\n\n")
+}
@@ -0,0 +1,151 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package agentic
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	fridaytools "github.com/basenana/friday/core/tools"
+	"github.com/basenana/plugin/logger"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+func newTestMCPServer() *server.MCPServer {
+	s := server.NewMCPServer("notes", "1.0.0")
+	s.AddTool(mcp.NewTool("lookup",
+		mcp.WithDescription("Look up a note"),
+		mcp.WithString("title", mcp.Required()),
+	), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		title, err := request.RequireString("title")
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		if title == "missing" {
+			return mcp.NewToolResultError("note missing not found"), nil
+		}
+		return &mcp.CallToolResult{Content: []mcp.Content{
+			mcp.NewTextContent("note " + title),
+			mcp.NewImageContent("aGk=", "image/png"),
+		}}, nil
+	})
+	s.AddTool(mcp.NewTool("delete.all", mcp.WithDescription("Delete all notes")),
+		func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return mcp.NewToolResultText("deleted"), nil
+		})
+	return s
+}
+
+func mcpServersConfig(t *testing.T, servers ...MCPServerConfig) map[string]string {
+	raw, err := json.Marshal(servers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return map[string]string{ConfigMCPServers: string(raw)}
+}
+
+func callMCPTool(t *testing.T, tools []*fridaytools.Tool, name string, args map[string]any) *fridaytools.Result {
+	tool := getToolByName(tools, name)
+	if tool == nil {
+		t.Fatalf("tool %s not found", name)
+	}
+	result, err := tool.Handler(context.Background(), &fridaytools.Request{Arguments: args})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func TestMCPTools_StreamableHTTP(t *testing.T) {
+	httpServer := server.NewTestStreamableHTTPServer(newTestMCPServer())
+	defer httpServer.Close()
+
+	tools, closeMCP, err := MCPTools(context.Background(), mcpServersConfig(t,
+		MCPServerConfig{Name: "notes", URL: httpServer.URL + "/mcp"},
+		MCPServerConfig{Name: "down", URL: "http://127.0.0.1:1/mcp"},
+	), logger.NewLogger("test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeMCP()
+
+	if len(tools) != 2 {
+		t.Fatalf("expected the tools of the reachable server, got %d", len(tools))
+	}
+	lookup := getToolByName(tools, "mcp_notes_lookup")
+	if lookup == nil || lookup.Description != "[MCP server notes] Look up a note" || lookup.InputSchema.Required[0] != "title" {
+		t.Fatalf("unexpected lookup tool %+v", lookup)
+	}
+	if getToolByName(tools, "mcp_notes_delete_all") == nil {
+		t.Errorf("expected the dot replaced in the tool name")
+	}
+
+	result := callMCPTool(t, tools, "mcp_notes_lookup", map[string]any{"title": "groceries"})
+	if result.IsError || getResultText(result) != "note groceries\n\n[image image/png]" {
+		t.Errorf("unexpected result %+v", result)
+	}
+	result = callMCPTool(t, tools, "mcp_notes_lookup", map[string]any{"title": "missing"})
+	if !result.IsError || getResultText(result) != "note missing not found" {
+		t.Errorf("expected the tool error, got %+v", result)
+	}
+}
+
+func TestMCPTools_SSEAllowlist(t *testing.T) {
+	sseServer := server.NewTestServer(newTestMCPServer())
+	defer sseServer.Close()
+
+	tools, closeMCP, err := MCPTools(context.Background(), mcpServersConfig(t,
+		MCPServerConfig{Name: "notes", URL: sseServer.URL + "/sse", Transport: "sse", Tools: []string{"lookup"}},
+	), logger.NewLogger("test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeMCP()
+
+	if len(tools) != 1 || tools[0].Name != "mcp_notes_lookup" {
+		t.Fatalf("expected only the allowed tool, got %d tools", len(tools))
+	}
+	if result := callMCPTool(t, tools, "mcp_notes_lookup", map[string]any{"title": "a"}); result.IsError {
+		t.Errorf("unexpected error %s", getResultText(result))
+	}
+}
+
+func TestParseMCPServers(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{`{"name": "a"}`, "parse friday_mcp_servers failed"},
+		{`[{"name": "a"}]`, "mcp server 0 requires name and url"},
+		{`[{"name": "a", "url": "http://a"}, {"name": "a", "url": "http://b"}]`, "duplicate mcp server a"},
+		{`[{"name": "a", "url": "http://a", "transport": "stdio"}]`, "unknown transport stdio"},
+	}
+	for _, tt := range tests {
+		if _, err := ParseMCPServers(tt.raw); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected error %q, got %v", tt.raw, tt.want, err)
+		}
+	}
+	if servers, err := ParseMCPServers(""); err != nil || servers != nil {
+		t.Errorf("expected no servers, got %v %v", servers, err)
+	}
+	if name := mcpToolName("my server", strings.Repeat("x", 80)); len(name) != maxToolNameLength || !strings.HasPrefix(name, "mcp_my_server_x") {
+		t.Errorf("unexpected tool name %s", name)
+	}
+}
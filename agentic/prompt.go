@@ -0,0 +1,141 @@
+package agentic
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/utils"
+)
+
+const (
+	// PromptTemplateDir holds the named templates of prompt_template, relative to the working path.
+	PromptTemplateDir = "prompts"
+
+	maxPromptFileSize = 1 << 20
+)
+
+var (
+	promptTemplateNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+	promptPlaceholderPattern  = regexp.MustCompile(`\{\{\s*([a-zA-Z0-9_]+)\s*\}\}`)
+	promptTemplateExtensions  = []string{".md", ".txt"}
+)
+
+// LoadSystemPrompt returns the system prompt of the step, given inline by system_prompt, as a
+// file of the working path by system_prompt_file, or by name with prompt_template. The
+// {{name}} placeholders of the prompt are replaced by the values of prompt_vars.
+func LoadSystemPrompt(request *api.Request, fileAccess *utils.FileAccess) (string, error) {
+	var (
+		prompt     = api.GetStringParameter("system_prompt", request, "")
+		promptFile = api.GetStringParameter("system_prompt_file", request, "")
+		template   = api.GetStringParameter("prompt_template", request, "")
+		given      int
+	)
+	for _, v := range []string{prompt, promptFile, template} {
+		if v != "" {
+			given++
+		}
+	}
+	if given > 1 {
+		return "", errors.New("system_prompt, system_prompt_file and prompt_template are exclusive")
+	}
+
+	var err error
+	switch {
+	case promptFile != "":
+		if prompt, err = readPromptFile(fileAccess, promptFile); err != nil {
+			return "", fmt.Errorf("read system_prompt_file failed: %w", err)
+		}
+	case template != "":
+		if prompt, err = readPromptTemplate(fileAccess, template); err != nil {
+			return "", err
+		}
+	}
+	if prompt == "" {
+		return "", nil
+	}
+
+	vars, err := parsePromptVars(api.GetStringParameter("prompt_vars", request, ""))
+	if err != nil {
+		return "", err
+	}
+	// an inline prompt is kept as is unless it is given values
+	if promptFile == "" && template == "" && vars == nil {
+		return prompt, nil
+	}
+	return renderPrompt(prompt, vars)
+}
+
+func readPromptFile(fileAccess *utils.FileAccess, filePath string) (string, error) {
+	data, err := fileAccess.Read(filePath)
+	if err != nil {
+		return "", err
+	}
+	if len(data) > maxPromptFileSize {
+		return "", fmt.Errorf("%s is larger than %d bytes", filePath, maxPromptFileSize)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// readPromptTemplate reads prompts/<name>.md, or prompts/<name>.txt.
+func readPromptTemplate(fileAccess *utils.FileAccess, name string) (string, error) {
+	if !promptTemplateNamePattern.MatchString(name) {
+		return "", fmt.Errorf("invalid prompt_template [%s]: expect 1 to 64 letters, digits, _ or -", name)
+	}
+	for _, ext := range promptTemplateExtensions {
+		prompt, err := readPromptFile(fileAccess, path.Join(PromptTemplateDir, name+ext))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("read prompt template %s failed: %w", name, err)
+		}
+		return prompt, nil
+	}
+	return "", fmt.Errorf("prompt template %s not found in %s", name, PromptTemplateDir)
+}
+
+// parsePromptVars reads prompt_vars, a JSON object; values other than strings are inserted as JSON.
+func parsePromptVars(raw string) (map[string]string, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+	var values map[string]any
+	if err := json.Unmarshal([]byte(raw), &values); err != nil {
+		return nil, fmt.Errorf("invalid prompt_vars, expect a JSON object: %w", err)
+	}
+	vars := make(map[string]string, len(values))
+	for k, v := range values {
+		if s, ok := v.(string); ok {
+			vars[k] = s
+			continue
+		}
+		vars[k] = compactJSON(v)
+	}
+	return vars, nil
+}
+
+// renderPrompt fails on a placeholder without a value, so a typo does not reach the model.
+func renderPrompt(prompt string, vars map[string]string) (string, error) {
+	var missing []string
+	rendered := promptPlaceholderPattern.ReplaceAllStringFunc(prompt, func(placeholder string) string {
+		name := promptPlaceholderPattern.FindStringSubmatch(placeholder)[1]
+		value, ok := vars[name]
+		if !ok {
+			if !contains(missing, name) {
+				missing = append(missing, name)
+			}
+			return placeholder
+		}
+		return value
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("prompt_vars has no value for %s", strings.Join(missing, ", "))
+	}
+	return rendered, nil
+}
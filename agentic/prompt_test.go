@@ -0,0 +1,93 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package agentic
+
+import (
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/utils"
+)
+
+func newPromptFiles(t *testing.T, files map[string]string) *utils.FileAccess {
+	fileAccess := utils.NewFileAccess(t.TempDir())
+	for name, content := range files {
+		if err := fileAccess.MkdirAll(path.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := fileAccess.Write(name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return fileAccess
+}
+
+func TestLoadSystemPrompt(t *testing.T) {
+	fileAccess := newPromptFiles(t, map[string]string{
+		"prompt.md":          "You review {{ product }} contracts.\n",
+		"prompts/analyst.md": "You are an analyst of {{market}}, answer in {{language}}. Focus: {{focus}}",
+		"prompts/plain.txt":  "Answer briefly.",
+	})
+
+	for name, tc := range map[string]struct {
+		params map[string]any
+		want   string
+	}{
+		"none":               {params: map[string]any{}, want: ""},
+		"inline":             {params: map[string]any{"system_prompt": "Keep {{this}}"}, want: "Keep {{this}}"},
+		"inline with vars":   {params: map[string]any{"system_prompt": "About {{topic}}", "prompt_vars": `{"topic": "solar"}`}, want: "About solar"},
+		"file":               {params: map[string]any{"system_prompt_file": "prompt.md", "prompt_vars": map[string]any{"product": "SaaS"}}, want: "You review SaaS contracts."},
+		"template":           {params: map[string]any{"prompt_template": "analyst", "prompt_vars": `{"market": "energy", "language": "German", "focus": ["price", "supply"]}`}, want: `You are an analyst of energy, answer in German. Focus: ["price","supply"]`},
+		"template from .txt": {params: map[string]any{"prompt_template": "plain"}, want: "Answer briefly."},
+	} {
+		got, err := LoadSystemPrompt(&api.Request{Parameter: tc.params}, fileAccess)
+		if err != nil {
+			t.Errorf("%s: unexpected error %s", name, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("%s: expected %q, got %q", name, tc.want, got)
+		}
+	}
+}
+
+func TestLoadSystemPrompt_Errors(t *testing.T) {
+	fileAccess := newPromptFiles(t, map[string]string{
+		"prompts/analyst.md": "You are an analyst of {{market}}, answer in {{language}}.",
+	})
+
+	for name, tc := range map[string]struct {
+		params map[string]any
+		want   string
+	}{
+		"exclusive":        {params: map[string]any{"system_prompt": "a", "prompt_template": "analyst"}, want: "exclusive"},
+		"missing file":     {params: map[string]any{"system_prompt_file": "missing.md"}, want: "read system_prompt_file failed"},
+		"outside workdir":  {params: map[string]any{"system_prompt_file": "../prompt.md"}, want: "read system_prompt_file failed"},
+		"invalid template": {params: map[string]any{"prompt_template": "../analyst"}, want: "invalid prompt_template"},
+		"missing template": {params: map[string]any{"prompt_template": "writer"}, want: "not found"},
+		"invalid vars":     {params: map[string]any{"prompt_template": "analyst", "prompt_vars": "market=energy"}, want: "invalid prompt_vars"},
+		"missing vars":     {params: map[string]any{"prompt_template": "analyst", "prompt_vars": `{"market": "energy"}`}, want: "no value for language"},
+		"no vars":          {params: map[string]any{"prompt_template": "analyst"}, want: "no value for market, language"},
+	} {
+		_, err := LoadSystemPrompt(&api.Request{Parameter: tc.params}, fileAccess)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: expected error with %q, got %v", name, tc.want, err)
+		}
+	}
+}
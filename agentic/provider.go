@@ -0,0 +1,118 @@
+package agentic
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Providers of friday_llm_provider. All of them are called through their OpenAI compatible
+// chat completions API, friday_llm_host replaces the default base URL of the provider.
+const (
+	ProviderOpenAI    = "openai"
+	ProviderAnthropic = "anthropic"
+	ProviderGemini    = "gemini"
+	ProviderAzure     = "azure"
+	ProviderOllama    = "ollama"
+)
+
+const (
+	ConfigProvider        = "friday_llm_provider"
+	ConfigAnthropicAPIKey = "friday_anthropic_api_key"
+	ConfigGeminiAPIKey    = "friday_gemini_api_key"
+	// ConfigAzureEndpoint is the resource endpoint, e.g. https://my-resource.openai.azure.com;
+	// friday_llm_model is the deployment name.
+	ConfigAzureEndpoint = "friday_azure_endpoint"
+	ConfigAzureAPIKey   = "friday_azure_api_key"
+	ConfigOllamaURL     = "friday_ollama_url"
+
+	anthropicBaseURL = "https://api.anthropic.com/v1/"
+	geminiBaseURL    = "https://generativelanguage.googleapis.com/v1beta/openai/"
+	ollamaURL        = "http://localhost:11434"
+	// ollamaAPIKey is sent because the client requires a key, Ollama ignores it.
+	ollamaAPIKey = "ollama"
+)
+
+// llmEndpoint returns the base URL and API key of the provider selected by the config.
+func llmEndpoint(config map[string]string) (host, apiKey string, err error) {
+	host = config[ConfigHost]
+	provider := strings.ToLower(strings.TrimSpace(config[ConfigProvider]))
+	switch provider {
+	case "", ProviderOpenAI:
+		if host == "" {
+			return "", "", fmt.Errorf("friday_llm_host is required")
+		}
+		if apiKey = config[ConfigAPIKey]; apiKey == "" {
+			return "", "", fmt.Errorf("friday_llm_api_key is required")
+		}
+	case ProviderAnthropic:
+		host = firstConfig(host, anthropicBaseURL)
+		if apiKey = firstConfig(config[ConfigAnthropicAPIKey], config[ConfigAPIKey]); apiKey == "" {
+			return "", "", fmt.Errorf("friday_anthropic_api_key is required for provider anthropic")
+		}
+	case ProviderGemini:
+		host = firstConfig(host, geminiBaseURL)
+		if apiKey = firstConfig(config[ConfigGeminiAPIKey], config[ConfigAPIKey]); apiKey == "" {
+			return "", "", fmt.Errorf("friday_gemini_api_key is required for provider gemini")
+		}
+	case ProviderAzure:
+		if host == "" {
+			endpoint := config[ConfigAzureEndpoint]
+			if endpoint == "" {
+				return "", "", fmt.Errorf("friday_azure_endpoint is required for provider azure")
+			}
+			host = strings.TrimSuffix(endpoint, "/") + "/openai/v1/"
+		}
+		if apiKey = firstConfig(config[ConfigAzureAPIKey], config[ConfigAPIKey]); apiKey == "" {
+			return "", "", fmt.Errorf("friday_azure_api_key is required for provider azure")
+		}
+	case ProviderOllama:
+		if host == "" {
+			host = strings.TrimSuffix(firstConfig(config[ConfigOllamaURL], ollamaURL), "/") + "/v1/"
+		}
+		apiKey = firstConfig(config[ConfigAPIKey], ollamaAPIKey)
+	default:
+		return "", "", fmt.Errorf("unknown friday_llm_provider [%s]: expect openai, anthropic, gemini, azure or ollama", provider)
+	}
+	return host, apiKey, nil
+}
+
+// LLMConfigured reports whether the config selects a model and a reachable provider.
+func LLMConfigured(config map[string]string) bool {
+	if config[ConfigModel] == "" {
+		return false
+	}
+	_, _, err := llmEndpoint(config)
+	return err == nil
+}
+
+// PluginLLMConfig applies the keys prefixed with the plugin name, e.g. summary.friday_llm_provider,
+// so that one host can give each plugin its own provider and model.
+func PluginLLMConfig(config map[string]string, plugin string) map[string]string {
+	prefix := plugin + "."
+	var merged map[string]string
+	for k, v := range config {
+		if !strings.HasPrefix(k, prefix) {
+			continue
+		}
+		if merged == nil {
+			merged = make(map[string]string, len(config))
+			for key, value := range config {
+				merged[key] = value
+			}
+		}
+		merged[strings.TrimPrefix(k, prefix)] = v
+	}
+	if merged == nil {
+		return config
+	}
+	return merged
+}
+
+func firstConfig(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,209 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package agentic
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/basenana/friday/core/providers/openai"
+	fridaytypes "github.com/basenana/friday/core/types"
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/types"
+)
+
+func TestLLMEndpoint(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   map[string]string
+		wantHost string
+		wantKey  string
+		wantErr  string
+	}{
+		{
+			name:     "openai by default",
+			config:   map[string]string{ConfigHost: "https://api.openai.com/v1", ConfigAPIKey: "sk"},
+			wantHost: "https://api.openai.com/v1",
+			wantKey:  "sk",
+		},
+		{
+			name:    "openai without host",
+			config:  map[string]string{ConfigProvider: "openai", ConfigAPIKey: "sk"},
+			wantErr: "friday_llm_host is required",
+		},
+		{
+			name:     "anthropic key",
+			config:   map[string]string{ConfigProvider: "Anthropic", ConfigAnthropicAPIKey: "ant", ConfigAPIKey: "sk"},
+			wantHost: anthropicBaseURL,
+			wantKey:  "ant",
+		},
+		{
+			name:    "anthropic without key",
+			config:  map[string]string{ConfigProvider: "anthropic"},
+			wantErr: "friday_anthropic_api_key is required",
+		},
+		{
+			name:     "gemini falls back to the llm key",
+			config:   map[string]string{ConfigProvider: "gemini", ConfigAPIKey: "g"},
+			wantHost: geminiBaseURL,
+			wantKey:  "g",
+		},
+		{
+			name:     "azure endpoint",
+			config:   map[string]string{ConfigProvider: "azure", ConfigAzureEndpoint: "https://res.openai.azure.com/", ConfigAzureAPIKey: "az"},
+			wantHost: "https://res.openai.azure.com/openai/v1/",
+			wantKey:  "az",
+		},
+		{
+			name:    "azure without endpoint",
+			config:  map[string]string{ConfigProvider: "azure", ConfigAzureAPIKey: "az"},
+			wantErr: "friday_azure_endpoint is required",
+		},
+		{
+			name:     "ollama defaults",
+			config:   map[string]string{ConfigProvider: "ollama"},
+			wantHost: "http://localhost:11434/v1/",
+			wantKey:  ollamaAPIKey,
+		},
+		{
+			name:     "ollama url",
+			config:   map[string]string{ConfigProvider: "ollama", ConfigOllamaURL: "http://ollama:11434"},
+			wantHost: "http://ollama:11434/v1/",
+			wantKey:  ollamaAPIKey,
+		},
+		{
+			name:     "host overrides the provider default",
+			config:   map[string]string{ConfigProvider: "gemini", ConfigHost: "https://proxy.example.com/v1", ConfigGeminiAPIKey: "g"},
+			wantHost: "https://proxy.example.com/v1",
+			wantKey:  "g",
+		},
+		{
+			name:    "unknown provider",
+			config:  map[string]string{ConfigProvider: "mistral"},
+			wantErr: "unknown friday_llm_provider [mistral]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			host, key, err := llmEndpoint(tt.config)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if host != tt.wantHost || key != tt.wantKey {
+				t.Errorf("expected %s %s, got %s %s", tt.wantHost, tt.wantKey, host, key)
+			}
+		})
+	}
+}
+
+func TestLLMConfigured(t *testing.T) {
+	if LLMConfigured(map[string]string{ConfigProvider: "ollama"}) {
+		t.Error("expected a config without model to be unconfigured")
+	}
+	if !LLMConfigured(map[string]string{ConfigProvider: "ollama", ConfigModel: "llama3.1"}) {
+		t.Error("expected ollama with a model to be configured")
+	}
+	if _, err := NewLLMClient(map[string]string{ConfigProvider: "gemini", ConfigModel: "gemini-2.0-flash"}); err == nil {
+		t.Error("expected an error for gemini without key")
+	}
+}
+
+func TestPluginLLMConfig(t *testing.T) {
+	config := map[string]string{
+		ConfigHost:                   "https://api.openai.com/v1",
+		ConfigModel:                  "gpt-4o",
+		"summary." + ConfigProvider:  "ollama",
+		"summary." + ConfigModel:     "llama3.1",
+		"research." + ConfigMaxCalls: "10",
+		"summaryx." + ConfigModel:    "other",
+	}
+
+	summary := PluginLLMConfig(config, "summary")
+	if summary[ConfigProvider] != "ollama" || summary[ConfigModel] != "llama3.1" || summary[ConfigHost] != "https://api.openai.com/v1" {
+		t.Errorf("unexpected summary config %v", summary)
+	}
+	if config[ConfigModel] != "gpt-4o" {
+		t.Errorf("expected the host config unchanged, got %v", config)
+	}
+	if react := PluginLLMConfig(config, "react"); react[ConfigModel] != "gpt-4o" || react[ConfigProvider] != "" {
+		t.Errorf("unexpected react config %v", react)
+	}
+}
+
+func TestCallLLMConfig(t *testing.T) {
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices": [{"message": {"role": "assistant", "content": "ok"}}]}`))
+	}))
+	defer server.Close()
+
+	config := map[string]string{ConfigHost: server.URL, ConfigAPIKey: "sk", ConfigModel: "gpt-4o", ConfigTemperature: "0.7"}
+	callConfig, err := CallLLMConfig(config, &api.Request{Parameter: map[string]any{"model": "gpt-4o-mini", "temperature": "0.2"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config[ConfigModel] != "gpt-4o" {
+		t.Errorf("expected the plugin config unchanged, got %v", config)
+	}
+
+	llm, err := NewLLMClient(callConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = llm.CompletionNonStreaming(context.Background(), openai.NewSimpleRequest("system", fridaytypes.Message{UserMessage: "hi"})); err != nil {
+		t.Fatal(err)
+	}
+	if body["model"] != "gpt-4o-mini" || body["temperature"] != 0.2 {
+		t.Errorf("expected the call model and temperature, got %v %v", body["model"], body["temperature"])
+	}
+
+	if same, _ := CallLLMConfig(config, &api.Request{Parameter: map[string]any{}}); same[ConfigModel] != "gpt-4o" {
+		t.Errorf("expected the plugin config without parameters, got %v", same)
+	}
+}
+
+func TestCallLLMConfig_InvalidTemperature(t *testing.T) {
+	workdir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(workdir, "a.txt"), []byte("text"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	p := NewSummaryPlugin(types.PluginCall{JobID: t.Name(), WorkingPath: workdir}).(*SummaryPlugin)
+	resp, err := p.Run(context.Background(), &api.Request{Parameter: map[string]any{"file_path": "a.txt", "temperature": "3"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.IsSucceed || resp.Message != "invalid temperature [3]: expect 0 to 2" {
+		t.Errorf("expected invalid temperature, got %q", resp.Message)
+	}
+	if _, err = NewLLMClient(map[string]string{ConfigHost: "http://127.0.0.1", ConfigAPIKey: "sk", ConfigModel: "m", ConfigTemperature: "hot"}); err == nil {
+		t.Error("expected an error for an invalid friday_llm_temperature")
+	}
+}
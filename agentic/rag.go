@@ -0,0 +1,567 @@
+package agentic
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/basenana/friday/core/providers/openai"
+	fridaytypes "github.com/basenana/friday/core/types"
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/logger"
+	"github.com/basenana/plugin/types"
+	"github.com/basenana/plugin/utils"
+	"go.uber.org/zap"
+)
+
+const (
+	ragPluginName    = "rag"
+	ragPluginVersion = "1.0.0"
+
+	defaultRAGEntries = 20
+	maxRAGEntries     = 100
+	defaultRAGChunks  = 8
+	maxRAGChunks      = 30
+	maxRAGWalk        = 1000
+	maxRAGEntrySize   = 10 << 20
+	ragChunkSize      = 1500
+)
+
+var RAGPluginSpec = types.PluginSpec{
+	Name:    ragPluginName,
+	Version: ragPluginVersion,
+	Type:    types.TypeProcess,
+	Dependencies: []types.Dependency{
+		{Kind: types.DependencyCapability, Name: types.CapabilityNetwork},
+		{Kind: types.DependencyCapability, Name: types.CapabilityLister},
+		{Kind: types.DependencyCapability, Name: types.CapabilityFS, Optional: true},
+	},
+	RequiredConfig: LLMRequiredConfig(),
+	InitParameters: []types.ParameterSpec{
+		{
+			Name:        "system_prompt",
+			Required:    false,
+			Description: "Instructions added to the answer prompt",
+		},
+	},
+	Parameters: []types.ParameterSpec{
+		{
+			Name:        "question",
+			Required:    true,
+			Description: "Question to answer from the NanaFS entries",
+		},
+		{
+			Name:        "parent_uri",
+			Required:    false,
+			Description: "Only use entries under this parent URI, walked recursively",
+		},
+		{
+			Name:        "keywords",
+			Required:    false,
+			Description: "Comma-separated keywords that entries must have",
+		},
+		{
+			Name:        "max_entries",
+			Required:    false,
+			Default:     strconv.Itoa(defaultRAGEntries),
+			Description: "Most entries read to answer the question",
+		},
+		{
+			Name:        "top_k",
+			Required:    false,
+			Default:     strconv.Itoa(defaultRAGChunks),
+			Description: "Most relevant chunks given to the model",
+		},
+	},
+}
+
+const ragPrompt = `You answer the question of the user from the numbered sources the user sends, taken from the user's archive.
+Cite the sources of each statement with their numbers in brackets, e.g. [1] or [2][3].
+If the sources do not answer the question, say so. Do not use knowledge outside the sources.`
+
+// ragNoAnswer is the result when no entry matches the question, the model is not called then.
+const ragNoAnswer = "No entries relevant to the question were found."
+
+type RAGPlugin struct {
+	logger     *zap.SugaredLogger
+	fileAccess *utils.FileAccess
+	jobID      string
+	config     map[string]string
+	newLLM     func(config map[string]string) (openai.Client, error)
+}
+
+func (p *RAGPlugin) Name() string           { return ragPluginName }
+func (p *RAGPlugin) Type() types.PluginType { return types.TypeProcess }
+func (p *RAGPlugin) Version() string        { return ragPluginVersion }
+
+// ragChunk is a part of an entry text, scored against the question.
+type ragChunk struct {
+	entry int
+	text  string
+	score float64
+}
+
+func (p *RAGPlugin) Run(ctx context.Context, request *api.Request) (*api.Response, error) {
+	var (
+		question  = strings.TrimSpace(api.GetStringParameter("question", request, ""))
+		parentURI = api.GetStringParameter("parent_uri", request, "")
+	)
+	if question == "" {
+		return api.NewFailedResponse("question is required"), nil
+	}
+	if request.Lister == nil {
+		return api.NewFailedResponse("entry lister is not available"), nil
+	}
+	if request.FS == nil && parentURI == "" {
+		return api.NewFailedResponse("parent_uri is required when the file system search is not available"), nil
+	}
+	maxEntries, err := rangeParameter(request, "max_entries", defaultRAGEntries, 1, maxRAGEntries)
+	if err != nil {
+		return api.NewFailedResponse(err.Error()), nil
+	}
+	topK, err := rangeParameter(request, "top_k", defaultRAGChunks, 1, maxRAGChunks)
+	if err != nil {
+		return api.NewFailedResponse(err.Error()), nil
+	}
+	var keywords []string
+	for _, k := range strings.Split(api.GetStringParameter("keywords", request, ""), ",") {
+		if k = strings.TrimSpace(k); k != "" {
+			keywords = append(keywords, k)
+		}
+	}
+
+	p.logger.Infow("rag plugin started", "question", question, "parent_uri", parentURI, "max_entries", maxEntries)
+
+	entries, err := p.candidates(ctx, request, question, parentURI, keywords, maxEntries)
+	if err != nil {
+		p.logger.Warnw("find entries failed", "error", err)
+		return api.NewFailedResponse(fmt.Sprintf("find entries failed: %s", err)), nil
+	}
+
+	var chunks []ragChunk
+	for i, en := range entries {
+		text, err := p.entryText(ctx, request.Lister, en)
+		if err != nil {
+			p.logger.Warnw("read entry failed, skip it", "entry_uri", en.URI, "error", err)
+			continue
+		}
+		for _, part := range chunkText(text, ragChunkSize) {
+			chunks = append(chunks, ragChunk{entry: i, text: part})
+		}
+	}
+	chunks = rankChunks(question, entries, chunks, topK)
+	if len(chunks) == 0 {
+		p.logger.Infow("no relevant entries", "entries", len(entries))
+		return api.NewResponseWithResult(map[string]any{
+			"result":    ragNoAnswer,
+			"citations": []map[string]any{},
+			"sources":   []map[string]any{},
+		}), nil
+	}
+
+	budget, err := NewLLMBudget(p.jobID, p.config, request.Store)
+	if err != nil {
+		p.logger.Warnw("parse LLM budget failed", "error", err)
+		return api.NewFailedResponse(err.Error()), nil
+	}
+	ctx = WithLLMBudget(ctx, budget)
+	ctx, llmRetries := WithLLMRetries(ctx)
+
+	llm, err := p.newLLM(p.config)
+	if err != nil {
+		p.logger.Warnw("create LLM client failed", "error", err)
+		return api.NewFailedResponse(err.Error()), nil
+	}
+
+	sources, message := ragSources(entries, chunks, question)
+	system := ragPrompt
+	if systemPrompt := api.GetStringParameter("system_prompt", request, ""); systemPrompt != "" {
+		system += "\n\n" + systemPrompt
+	}
+	answer, err := llm.CompletionNonStreaming(ctx, openai.NewSimpleRequest(system, fridaytypes.Message{UserMessage: message}))
+	if err != nil {
+		p.logger.Warnw("answer question failed", "error", err)
+		return api.NewFailedResponse(fmt.Sprintf("llm call failed: %s", err)), nil
+	}
+
+	citations := make([]map[string]any, 0)
+	for _, index := range citedSources(answer, len(sources)) {
+		citations = append(citations, sources[index-1])
+	}
+
+	p.logger.Infow("rag plugin completed", "entries", len(entries), "sources", len(sources), "citations", len(citations))
+	results := map[string]any{
+		"result":    answer,
+		"citations": citations,
+		"sources":   sources,
+	}
+	if budget != nil {
+		results["llm_budget"] = budget.Result(ctx)
+	}
+	if n := llmRetries.Count(); n > 0 {
+		results["llm_retries"] = n
+	}
+	return api.NewResponseWithResult(results), nil
+}
+
+// candidates returns the entries of the file system search for the question, then the
+// entries under parentURI whose name and properties best match it.
+func (p *RAGPlugin) candidates(ctx context.Context, request *api.Request, question, parentURI string, keywords []string, maxEntries int) ([]types.Entry, error) {
+	var (
+		entries []types.Entry
+		seen    = map[string]bool{}
+	)
+	add := func(en types.Entry) {
+		if len(entries) < maxEntries && !en.IsGroup && !seen[en.URI] {
+			seen[en.URI] = true
+			entries = append(entries, en)
+		}
+	}
+
+	if request.FS != nil {
+		found, err := request.FS.Search(ctx, question, types.SearchFilter{ParentURI: parentURI, Keywords: keywords, Limit: maxEntries})
+		if err != nil {
+			return nil, err
+		}
+		for _, en := range found {
+			add(en)
+		}
+	}
+	if parentURI == "" || len(entries) >= maxEntries {
+		return entries, nil
+	}
+
+	var walked []types.Entry
+	if err := walkEntries(ctx, request.Lister, parentURI, func(en types.Entry) bool {
+		if hasKeywords(en.Properties.Keywords, keywords) {
+			walked = append(walked, en)
+		}
+		return len(walked) < maxRAGWalk
+	}); err != nil {
+		return nil, err
+	}
+
+	docs := make([][]string, len(walked))
+	for i, en := range walked {
+		docs[i] = ragTokens(entryMeta(en))
+	}
+	scores := bm25(ragTokens(question), docs)
+	order := make([]int, len(walked))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return scores[order[i]] > scores[order[j]] })
+	for _, i := range order {
+		add(walked[i])
+	}
+	return entries, nil
+}
+
+// walkEntries visits the entries under parentURI depth first until fn returns false.
+func walkEntries(ctx context.Context, lister api.Lister, parentURI string, fn func(types.Entry) bool) error {
+	var walk func(uri string) (bool, error)
+	walk = func(uri string) (bool, error) {
+		entries, err := lister.ListEntries(ctx, uri)
+		if err != nil {
+			return false, err
+		}
+		for _, en := range entries {
+			if err = ctx.Err(); err != nil {
+				return false, err
+			}
+			next := true
+			if en.IsGroup {
+				next, err = walk(en.URI)
+				if err != nil {
+					return false, err
+				}
+			} else {
+				next = fn(en)
+			}
+			if !next {
+				return false, nil
+			}
+		}
+		return true, nil
+	}
+	_, err := walk(parentURI)
+	return err
+}
+
+func hasKeywords(entryKeywords, keywords []string) bool {
+	for _, k := range keywords {
+		found := false
+		for _, ek := range entryKeywords {
+			if strings.EqualFold(ek, k) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func entryMeta(en types.Entry) string {
+	props := en.Properties
+	return strings.Join(append([]string{en.Name, props.Title, props.Abstract}, props.Keywords...), " ")
+}
+
+// entryText opens the entry and parses it by the extension of its name, or reads it as UTF-8 text.
+func (p *RAGPlugin) entryText(ctx context.Context, lister api.Lister, en types.Entry) (string, error) {
+	reader, err := lister.OpenEntry(ctx, en.URI)
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+	data, err := io.ReadAll(io.LimitReader(reader, maxRAGEntrySize+1))
+	if err != nil {
+		return "", err
+	}
+	if len(data) > maxRAGEntrySize {
+		return "", fmt.Errorf("entry is larger than %s", formatSize(maxRAGEntrySize))
+	}
+
+	ext := strings.ToLower(filepath.Ext(en.Name))
+	if newParser("entry"+ext) == nil {
+		if !utf8.Valid(data) {
+			return "", fmt.Errorf("unsupported binary entry")
+		}
+		return string(data), nil
+	}
+
+	sum := sha256.Sum256([]byte(en.URI))
+	tmpName := ".rag_" + hex.EncodeToString(sum[:8]) + ext
+	if err = p.fileAccess.Write(tmpName, data, 0644); err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = p.fileAccess.Remove(tmpName)
+	}()
+	return loadDocument(ctx, p.fileAccess, p.logger, tmpName)
+}
+
+// chunkText packs the paragraphs of text into chunks of about size characters, splitting longer paragraphs.
+func chunkText(text string, size int) []string {
+	var (
+		chunks  []string
+		current strings.Builder
+	)
+	flush := func() {
+		if s := strings.TrimSpace(current.String()); s != "" {
+			chunks = append(chunks, s)
+		}
+		current.Reset()
+	}
+	for _, para := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n\n") {
+		para = strings.TrimSpace(para)
+		if para == "" {
+			continue
+		}
+		if utf8.RuneCountInString(current.String())+utf8.RuneCountInString(para) > size {
+			flush()
+		}
+		for runes := []rune(para); len(runes) > size; runes = []rune(para) {
+			chunks = append(chunks, string(runes[:size]))
+			para = string(runes[size:])
+		}
+		if current.Len() > 0 {
+			current.WriteString("\n\n")
+		}
+		current.WriteString(para)
+	}
+	flush()
+	return chunks
+}
+
+// rankChunks keeps the topK chunks matching the question best, together with the metadata of their entry.
+func rankChunks(question string, entries []types.Entry, chunks []ragChunk, topK int) []ragChunk {
+	docs := make([][]string, len(chunks))
+	for i, c := range chunks {
+		docs[i] = ragTokens(entryMeta(entries[c.entry]) + " " + c.text)
+	}
+	scores := bm25(ragTokens(question), docs)
+
+	var ranked []ragChunk
+	for i, c := range chunks {
+		if scores[i] > 0 {
+			c.score = scores[i]
+			ranked = append(ranked, c)
+		}
+	}
+	sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].score > ranked[j].score })
+	if len(ranked) > topK {
+		ranked = ranked[:topK]
+	}
+	return ranked
+}
+
+// bm25 scores each document against the query terms.
+func bm25(query []string, docs [][]string) []float64 {
+	const k1, b = 1.2, 0.75
+	scores := make([]float64, len(docs))
+	if len(docs) == 0 || len(query) == 0 {
+		return scores
+	}
+
+	var (
+		df    = map[string]int{}
+		total int
+	)
+	for _, doc := range docs {
+		total += len(doc)
+		seen := map[string]bool{}
+		for _, term := range doc {
+			if !seen[term] {
+				seen[term] = true
+				df[term]++
+			}
+		}
+	}
+	avgLen := math.Max(float64(total)/float64(len(docs)), 1)
+
+	terms := map[string]bool{}
+	for _, term := range query {
+		terms[term] = true
+	}
+	for i, doc := range docs {
+		tf := map[string]int{}
+		for _, term := range doc {
+			if terms[term] {
+				tf[term]++
+			}
+		}
+		for term, f := range tf {
+			idf := math.Log(1 + (float64(len(docs)-df[term])+0.5)/(float64(df[term])+0.5))
+			scores[i] += idf * float64(f) * (k1 + 1) / (float64(f) + k1*(1-b+b*float64(len(doc))/avgLen))
+		}
+	}
+	return scores
+}
+
+// ragStopWords are left out of the query, they match nearly every chunk.
+var ragStopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true, "by": true,
+	"did": true, "do": true, "does": true, "for": true, "from": true, "how": true, "in": true, "is": true,
+	"it": true, "of": true, "on": true, "or": true, "that": true, "the": true, "this": true, "to": true,
+	"was": true, "were": true, "what": true, "when": true, "where": true, "which": true, "who": true,
+	"why": true, "with": true,
+}
+
+// ragTokens lowercases the words of text; CJK text, which has no spaces, becomes character bigrams.
+func ragTokens(text string) []string {
+	var (
+		tokens []string
+		word   []rune
+		cjk    []rune
+	)
+	flush := func() {
+		if w := string(word); len(word) > 0 && !ragStopWords[w] {
+			tokens = append(tokens, w)
+		}
+		word = word[:0]
+		if len(cjk) == 1 {
+			tokens = append(tokens, string(cjk))
+		}
+		for i := 0; i+1 < len(cjk); i++ {
+			tokens = append(tokens, string(cjk[i:i+2]))
+		}
+		cjk = cjk[:0]
+	}
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul):
+			if len(word) > 0 {
+				flush()
+			}
+			cjk = append(cjk, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if len(cjk) > 0 {
+				flush()
+			}
+			word = append(word, r)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return tokens
+}
+
+// ragSources numbers the entries of the chunks in rank order and builds the user message.
+func ragSources(entries []types.Entry, chunks []ragChunk, question string) ([]map[string]any, string) {
+	var (
+		sources []map[string]any
+		index   = map[int]int{}
+		texts   [][]string
+	)
+	for _, c := range chunks {
+		n, ok := index[c.entry]
+		if !ok {
+			en := entries[c.entry]
+			title := en.Properties.Title
+			if title == "" {
+				title = en.Name
+			}
+			source := map[string]any{"index": len(sources) + 1, "entry_uri": en.URI, "title": title}
+			if en.Properties.URL != "" {
+				source["url"] = en.Properties.URL
+			}
+			sources = append(sources, source)
+			texts = append(texts, nil)
+			n = len(sources)
+			index[c.entry] = n
+		}
+		texts[n-1] = append(texts[n-1], c.text)
+	}
+
+	var msg strings.Builder
+	msg.WriteString("Sources:\n\n")
+	for i, source := range sources {
+		fmt.Fprintf(&msg, "[%d] %s (%s)\n%s\n\n", i+1, source["title"], source["entry_uri"], strings.Join(texts[i], "\n...\n"))
+	}
+	msg.WriteString("Question: " + question)
+	return sources, msg.String()
+}
+
+var citationPattern = regexp.MustCompile(`\[(\d+(?:\s*,\s*\d+)*)\]`)
+
+// citedSources returns the source numbers referenced in the answer, in ascending order.
+func citedSources(answer string, count int) []int {
+	cited := map[int]bool{}
+	for _, match := range citationPattern.FindAllStringSubmatch(answer, -1) {
+		for _, part := range strings.Split(match[1], ",") {
+			if n, err := strconv.Atoi(strings.TrimSpace(part)); err == nil && n >= 1 && n <= count {
+				cited[n] = true
+			}
+		}
+	}
+	indexes := make([]int, 0, len(cited))
+	for n := range cited {
+		indexes = append(indexes, n)
+	}
+	sort.Ints(indexes)
+	return indexes
+}
+
+func NewRAGPlugin(ps types.PluginCall) types.Plugin {
+	return &RAGPlugin{
+		logger:     logger.NewPluginLogger(ragPluginName, ps.JobID),
+		fileAccess: utils.NewFileAccess(ps.WorkingPath, ps.JobWorkingPath),
+		jobID:      ps.JobID,
+		config:     PluginLLMConfig(ps.Config, ragPluginName),
+		newLLM:     NewLLMClient,
+	}
+}
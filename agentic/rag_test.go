@@ -0,0 +1,216 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package agentic
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/basenana/friday/core/providers/openai"
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/types"
+)
+
+// archiveFS serves an in-memory tree to the lister methods and answers searches with found.
+type archiveFS struct {
+	api.NanaFS
+	api.Lister
+	entries  map[string]types.Entry
+	contents map[string]string
+	found    []string
+	queries  []string
+}
+
+func newArchiveFS() *archiveFS {
+	return &archiveFS{entries: map[string]types.Entry{}, contents: map[string]string{}}
+}
+
+func (a *archiveFS) add(parent string, en types.Entry, content string) {
+	en.URI = path.Join(parent, en.Name)
+	a.entries[en.URI] = en
+	a.contents[en.URI] = content
+}
+
+func (a *archiveFS) ListEntries(ctx context.Context, parentURI string) ([]types.Entry, error) {
+	var result []types.Entry
+	for uri, en := range a.entries {
+		if path.Dir(uri) == parentURI {
+			result = append(result, en)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result, nil
+}
+
+func (a *archiveFS) OpenEntry(ctx context.Context, entryURI string) (io.ReadCloser, error) {
+	content, ok := a.contents[entryURI]
+	if !ok {
+		return nil, fmt.Errorf("open %s failed", entryURI)
+	}
+	return io.NopCloser(strings.NewReader(content)), nil
+}
+
+func (a *archiveFS) Search(ctx context.Context, query string, filter types.SearchFilter) ([]types.Entry, error) {
+	a.queries = append(a.queries, query)
+	var result []types.Entry
+	for _, uri := range a.found {
+		result = append(result, a.entries[uri])
+	}
+	return result, nil
+}
+
+func newRAGPlugin(t *testing.T, llm openai.Client) *RAGPlugin {
+	p := NewRAGPlugin(types.PluginCall{JobID: t.Name(), WorkingPath: t.TempDir()}).(*RAGPlugin)
+	p.newLLM = func(map[string]string) (openai.Client, error) { return llm, nil }
+	return p
+}
+
+func testArchive() *archiveFS {
+	archive := newArchiveFS()
+	archive.add("/notes", types.Entry{Name: "reports", IsGroup: true}, "")
+	archive.add("/notes/reports", types.Entry{Name: "q3.md", Properties: types.Properties{Title: "Q3 Report", Keywords: []string{"finance"}}},
+		"# Q3\n\nRevenue grew 12 percent.\n\nCustomer churn fell to 3 percent after the pricing change.")
+	archive.add("/notes", types.Entry{Name: "recipes.txt", Properties: types.Properties{URL: "https://example.com/pasta"}},
+		"Boil the pasta for nine minutes.")
+	archive.add("/notes", types.Entry{Name: "photo.bin"}, "\xff\xfe\x00binary")
+	return archive
+}
+
+func TestRAGPlugin_WalkParent(t *testing.T) {
+	llm := &scriptedLLM{replies: []string{"Churn fell to 3 percent [1]."}}
+	resp, err := newRAGPlugin(t, llm).Run(context.Background(), &api.Request{
+		Lister:    testArchive(),
+		Parameter: map[string]any{"question": "What happened to customer churn?", "parent_uri": "/notes"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.IsSucceed {
+		t.Fatalf("expected success, got failure: %s", resp.Message)
+	}
+
+	citations := resp.Results["citations"].([]map[string]any)
+	if len(citations) != 1 || citations[0]["entry_uri"] != "/notes/reports/q3.md" || citations[0]["title"] != "Q3 Report" {
+		t.Errorf("unexpected citations %+v", citations)
+	}
+	if sources := resp.Results["sources"].([]map[string]any); len(sources) != 1 {
+		t.Errorf("expected only the report as source, got %+v", sources)
+	}
+	message := llm.requests[0].History()[1].UserMessage
+	if !strings.Contains(message, "[1] Q3 Report (/notes/reports/q3.md)\n") || !strings.Contains(message, "churn fell to 3 percent") ||
+		strings.Contains(message, "pasta") || !strings.HasSuffix(message, "Question: What happened to customer churn?") {
+		t.Errorf("unexpected message %q", message)
+	}
+}
+
+func TestRAGPlugin_SearchFirst(t *testing.T) {
+	archive := testArchive()
+	archive.found = []string{"/notes/recipes.txt"}
+	llm := &scriptedLLM{replies: []string{"Boil it for nine minutes [1], churn is unrelated [2, 7]."}}
+	resp, err := newRAGPlugin(t, llm).Run(context.Background(), &api.Request{
+		FS:        archive,
+		Lister:    archive,
+		Parameter: map[string]any{"question": "How long to boil pasta?"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.IsSucceed {
+		t.Fatalf("expected success, got failure: %s", resp.Message)
+	}
+	if len(archive.queries) != 1 || archive.queries[0] != "How long to boil pasta?" {
+		t.Errorf("expected the question as search query, got %v", archive.queries)
+	}
+	citations := resp.Results["citations"].([]map[string]any)
+	if len(citations) != 1 || citations[0]["url"] != "https://example.com/pasta" {
+		t.Errorf("expected only the existing source cited, got %+v", citations)
+	}
+}
+
+func TestRAGPlugin_NoRelevantEntries(t *testing.T) {
+	llm := &scriptedLLM{}
+	resp, err := newRAGPlugin(t, llm).Run(context.Background(), &api.Request{
+		Lister:    testArchive(),
+		Parameter: map[string]any{"question": "Who won the marathon?", "parent_uri": "/notes"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.IsSucceed || resp.Results["result"] != ragNoAnswer || len(llm.requests) != 0 {
+		t.Errorf("expected no answer without calling the model, got %+v", resp.Results)
+	}
+}
+
+func TestRAGPlugin_Keywords(t *testing.T) {
+	llm := &scriptedLLM{replies: []string{"Nothing about pasta."}}
+	resp, err := newRAGPlugin(t, llm).Run(context.Background(), &api.Request{
+		Lister:    testArchive(),
+		Parameter: map[string]any{"question": "pasta revenue", "parent_uri": "/notes", "keywords": "Finance"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sources := resp.Results["sources"].([]map[string]any)
+	if len(sources) != 1 || sources[0]["entry_uri"] != "/notes/reports/q3.md" {
+		t.Errorf("expected only the finance entry, got %+v", sources)
+	}
+}
+
+func TestRAGPlugin_InvalidParameters(t *testing.T) {
+	tests := []struct {
+		name    string
+		request *api.Request
+		want    string
+	}{
+		{"no question", &api.Request{Lister: newArchiveFS(), Parameter: map[string]any{"parent_uri": "/"}}, "question is required"},
+		{"no lister", &api.Request{Parameter: map[string]any{"question": "q", "parent_uri": "/"}}, "entry lister is not available"},
+		{"no scope", &api.Request{Lister: newArchiveFS(), Parameter: map[string]any{"question": "q"}}, "parent_uri is required"},
+		{"top k", &api.Request{Lister: newArchiveFS(), Parameter: map[string]any{"question": "q", "parent_uri": "/", "top_k": "31"}}, "invalid top_k [31]: expect 1 to 30"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := newRAGPlugin(t, &scriptedLLM{}).Run(context.Background(), tt.request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.IsSucceed || !strings.Contains(resp.Message, tt.want) {
+				t.Errorf("expected failure %q, got %q", tt.want, resp.Message)
+			}
+		})
+	}
+}
+
+func TestChunkText(t *testing.T) {
+	chunks := chunkText("one two\n\nthree\n\n"+strings.Repeat("x", 25)+"\n\nfour", 10)
+	want := []string{"one two", "three", "xxxxxxxxxx", "xxxxxxxxxx", "xxxxx\n\nfour"}
+	if strings.Join(chunks, "|") != strings.Join(want, "|") {
+		t.Errorf("expected %q, got %q", want, chunks)
+	}
+}
+
+func TestRAGTokens(t *testing.T) {
+	got := ragTokens("What is the Q3 churn? 客户流失")
+	want := []string{"q3", "churn", "客户", "户流", "流失"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
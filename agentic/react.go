@@ -6,10 +6,11 @@ import (
 
 	"github.com/basenana/friday/core/agents/react"
 	fridayapi "github.com/basenana/friday/core/api"
-	"github.com/basenana/friday/core/memory"
 	"github.com/basenana/plugin/api"
 	"github.com/basenana/plugin/logger"
+	"github.com/basenana/plugin/sandbox"
 	"github.com/basenana/plugin/types"
+	"github.com/basenana/plugin/utils"
 	"go.uber.org/zap"
 )
 
@@ -22,6 +23,7 @@ var PluginSpec = types.PluginSpec{
 	Name:           pluginName,
 	Version:        pluginVersion,
 	Type:           types.TypeProcess,
+	Dependencies:   append([]types.Dependency{{Kind: types.DependencyCapability, Name: types.CapabilityNetwork}, {Kind: types.DependencyCapability, Name: types.CapabilityFS, Optional: true}}, agentCommandDependencies()...),
 	RequiredConfig: LLMRequiredConfig(),
 	InitParameters: []types.ParameterSpec{
 		{
@@ -36,14 +38,21 @@ var PluginSpec = types.PluginSpec{
 			Required:    true,
 			Description: "User message for the agent",
 		},
+		{
+			Name:        "session",
+			Required:    false,
+			Description: "Session name, steps of the job with the same session continue its conversation",
+		},
 	},
 }
 
 type ReactPlugin struct {
 	logger      *zap.SugaredLogger
 	workingPath string
+	jobPath     string
 	jobID       string
 	config      map[string]string
+	runner      types.CommandRunner
 }
 
 func (p *ReactPlugin) Name() string           { return pluginName }
@@ -61,39 +70,107 @@ func (p *ReactPlugin) Run(ctx context.Context, request *api.Request) (*api.Respo
 
 	p.logger.Infow("react plugin started", "message_len", len(message), "has_system_prompt", systemPrompt != "")
 
+	session, err := OpenSession(ctx, request, NewSessionStore(p.jobID, request.Store, p.jobPath))
+	if err != nil {
+		p.logger.Warnw("open session failed", "error", err)
+		return api.NewFailedResponse(err.Error()), nil
+	}
+
+	budget, err := NewLLMBudget(p.jobID, p.config, request.Store)
+	if err != nil {
+		p.logger.Warnw("parse LLM budget failed", "error", err)
+		return api.NewFailedResponse(err.Error()), nil
+	}
+	ctx = WithLLMBudget(ctx, budget)
+	ctx, llmRetries := WithLLMRetries(ctx)
+
 	llm, err := NewLLMClient(p.config)
 	if err != nil {
 		p.logger.Warnw("create LLM client failed", "error", err)
 		return api.NewFailedResponse(err.Error()), nil
 	}
 
-	tools := FileAccessTools(p.workingPath, p.logger)
+	commandTools, err := CommandTools(p.config, p.runner, p.logger)
+	if err != nil {
+		p.logger.Warnw("parse command tool config failed", "error", err)
+		return api.NewFailedResponse(err.Error()), nil
+	}
+
+	visionLLM, err := NewVisionLLMClient(p.config)
+	if err != nil {
+		p.logger.Warnw("create vision LLM client failed", "error", err)
+		return api.NewFailedResponse(err.Error()), nil
+	}
+
+	fileAccess := utils.NewFileAccess(p.workingPath, p.jobPath)
+	tools := append(FileAccessTools(fileAccess, p.logger), commandTools...)
+	tools = append(tools, NewImageDescribeTool(fileAccess, visionLLM, p.logger))
+	entryTools := NewEntryTools(request.FS, p.workingPath, p.logger)
+	tools = append(tools, entryTools.Tools()...)
+	toolAudit := NewToolAudit(p.workingPath, p.jobID, pluginName, p.logger)
 	agent := react.New("react", "ReAct Agent with file access", llm, react.Option{
 		SystemPrompt: systemPrompt,
-		Tools:        tools,
+		Tools:        toolAudit.Wrap(tools),
 	})
 
 	resp := agent.Chat(ctx, &fridayapi.Request{
 		Session:     NewSession(p.jobID),
-		Memory:      memory.NewEmpty(p.jobID),
+		Memory:      session.Memory(p.jobID),
 		UserMessage: message,
 	})
 
 	content, err := fridayapi.ReadAllContent(ctx, resp)
 	if err != nil {
 		p.logger.Warnw("collect response failed", "error", err)
+		failed := api.NewFailedResponse(err.Error())
+		if audit := toolAudit.Result(); audit != nil {
+			failed.Results = map[string]any{"tool_audit": audit}
+		}
+		return failed, nil
+	}
+
+	result := strings.TrimSpace(content)
+	if err = session.Record(ctx, message, result); err != nil {
+		p.logger.Warnw("record session failed", "error", err)
 		return api.NewFailedResponse(err.Error()), nil
 	}
 
 	p.logger.Infow("react plugin completed", "result_len", len(content))
-	return api.NewResponseWithResult(map[string]any{"result": strings.TrimSpace(content)}), nil
+	results := map[string]any{"result": result}
+	if session != nil {
+		results["session"] = session.Name()
+	}
+	if audit := toolAudit.Result(); audit != nil {
+		results["tool_audit"] = audit
+	}
+	if entries := entryTools.Result(); entries != nil {
+		results["entries"] = entries
+	}
+	if budget != nil {
+		results["llm_budget"] = budget.Result(ctx)
+	}
+	if n := llmRetries.Count(); n > 0 {
+		results["llm_retries"] = n
+	}
+	return api.NewResponseWithResult(results), nil
 }
 
 func NewReactPlugin(ps types.PluginCall) types.Plugin {
 	return &ReactPlugin{
 		logger:      logger.NewPluginLogger(pluginName, ps.JobID),
 		workingPath: ps.WorkingPath,
+		jobPath:     jobWorkingPath(ps),
 		jobID:       ps.JobID,
-		config:      ps.Config,
+		config:      PluginLLMConfig(ps.Config, pluginName),
+		runner:      sandbox.ForCall(ps),
+	}
+}
+
+// jobWorkingPath is where a call keeps state for later steps of its job, the job working
+// path when the call runs in an isolated directory.
+func jobWorkingPath(ps types.PluginCall) string {
+	if ps.JobWorkingPath != "" {
+		return ps.JobWorkingPath
 	}
+	return ps.WorkingPath
 }
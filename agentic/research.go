@@ -6,9 +6,10 @@ import (
 
 	"github.com/basenana/friday/core/agents/research"
 	fridayapi "github.com/basenana/friday/core/api"
-	"github.com/basenana/friday/core/memory"
+	fridaytools "github.com/basenana/friday/core/tools"
 	"github.com/basenana/plugin/api"
 	"github.com/basenana/plugin/logger"
+	"github.com/basenana/plugin/sandbox"
 	"github.com/basenana/plugin/types"
 	"github.com/basenana/plugin/utils"
 	"go.uber.org/zap"
@@ -20,13 +21,24 @@ const (
 )
 
 var ResearchPluginSpec = types.PluginSpec{
-	Name:    researchPluginName,
-	Version: researchPluginVersion,
-	Type:    types.TypeProcess,
+	Name:         researchPluginName,
+	Version:      researchPluginVersion,
+	Type:         types.TypeProcess,
+	Dependencies: append([]types.Dependency{{Kind: types.DependencyCapability, Name: types.CapabilityNetwork}, {Kind: types.DependencyCapability, Name: types.CapabilityFS, Optional: true}}, agentCommandDependencies()...),
 	RequiredConfig: append(LLMRequiredConfig(),
-		"friday_websearch_type", // WebSearch type: pse (Google Programmable Search Engine)
+		"friday_websearch_type", // WebSearch type: pse (Google Programmable Search Engine), bing (Bing Web Search), brave (Brave Search), searxng (self-hosted SearxNG), duckduckgo or none
 		"friday_pse_engine_id",  // Google PSE Engine ID (required when websearch_type=pse)
 		"friday_pse_api_key",    // Google PSE API Key (required when websearch_type=pse)
+		"friday_bing_api_key",   // Bing Web Search API Key (required when websearch_type=bing)
+		"friday_brave_api_key",  // Brave Search API Key (required when websearch_type=brave)
+		"friday_searxng_url",    // SearxNG base URL (required when websearch_type=searxng)
+		ConfigMCPServers,        // MCP servers whose tools research can call, JSON array of {name, url, transport, headers, tools}
+		ConfigAgentTools,        // Agents research can call as tools, comma-separated: summarize, extract, react
+		ConfigAgentMaxDepth,     // How many agents can be nested below research (default 1, at most 3)
+		ConfigHostTools,         // Tool sets registered by the embedding application research can call, comma-separated (default all, none for none)
+		ConfigWebOutputFilter,   // Sanitize the web tool outputs before the agent reads them: on (default) or off
+		ConfigWebOutputLength,   // Most characters of a web tool output (default 50000)
+		ConfigWebOutputPatterns, // More prompt injection patterns to filter, JSON array of regular expressions
 	),
 	InitParameters: []types.ParameterSpec{
 		{
@@ -41,14 +53,61 @@ var ResearchPluginSpec = types.PluginSpec{
 			Required:    true,
 			Description: "Research topic or question",
 		},
+		{
+			Name:        "session",
+			Required:    false,
+			Description: "Session name, steps of the job with the same session continue its conversation",
+		},
+		{
+			Name:        "system_prompt_file",
+			Required:    false,
+			Description: "File in working directory holding the system prompt, exclusive with system_prompt and prompt_template",
+		},
+		{
+			Name:        "prompt_template",
+			Required:    false,
+			Description: "Name of a system prompt template in prompts/ of working directory, read from <name>.md or <name>.txt",
+		},
+		{
+			Name:        "prompt_vars",
+			Required:    false,
+			Description: "JSON object of the values of the {{name}} placeholders in the system prompt",
+		},
+		{
+			Name:        "model",
+			Required:    false,
+			Description: "Model of this call, overrides friday_llm_model",
+		},
+		{
+			Name:        "temperature",
+			Required:    false,
+			Description: "Sampling temperature of this call, 0 to 2, overrides friday_llm_temperature",
+		},
+		{
+			Name:        "max_tool_calls",
+			Required:    false,
+			Description: "Most tool calls of this run, the run stops with a partial result when exceeded",
+		},
+		{
+			Name:        "max_tokens",
+			Required:    false,
+			Description: "Most LLM tokens of this run, the run stops with a partial result when exceeded",
+		},
+		{
+			Name:        "max_duration",
+			Required:    false,
+			Description: "Most wall time of this run, e.g. 10m, the run stops with a partial result when exceeded",
+		},
 	},
 }
 
 type ResearchPlugin struct {
 	workingPath  string
+	jobPath      string
 	jobID        string
 	config       map[string]string
 	webCitations *WebCitations
+	runner       types.CommandRunner
 	logger       *zap.SugaredLogger
 }
 
@@ -63,44 +122,126 @@ func (p *ResearchPlugin) Run(ctx context.Context, request *api.Request) (*api.Re
 		return api.NewFailedResponse("message parameter is required"), nil
 	}
 
-	systemPrompt := api.GetStringParameter("system_prompt", request, "")
+	systemPrompt, err := LoadSystemPrompt(request, utils.NewFileAccess(p.workingPath, p.jobPath))
+	if err != nil {
+		p.logger.Warnw("load system prompt failed", "error", err)
+		return api.NewFailedResponse(err.Error()), nil
+	}
 
 	p.logger.Infow("research plugin started", "message_len", len(message), "has_system_prompt", systemPrompt != "")
 
-	llm, err := NewLLMClient(p.config)
+	config, err := CallLLMConfig(p.config, request)
+	if err != nil {
+		p.logger.Warnw("invalid llm parameters", "error", err)
+		return api.NewFailedResponse(err.Error()), nil
+	}
+
+	session, err := OpenSession(ctx, request, NewSessionStore(p.jobID, request.Store, p.jobPath))
+	if err != nil {
+		p.logger.Warnw("open session failed", "error", err)
+		return api.NewFailedResponse(err.Error()), nil
+	}
+
+	limits, err := NewRunLimits(request)
+	if err != nil {
+		p.logger.Warnw("invalid run limits", "error", err)
+		return api.NewFailedResponse(err.Error()), nil
+	}
+
+	budget, err := NewLLMBudget(p.jobID, config, request.Store)
+	if err != nil {
+		p.logger.Warnw("parse LLM budget failed", "error", err)
+		return api.NewFailedResponse(err.Error()), nil
+	}
+	ctx = WithLLMBudget(ctx, budget)
+	ctx, llmRetries := WithLLMRetries(ctx)
+
+	llm, err := NewLLMClient(config)
 	if err != nil {
 		p.logger.Warnw("create LLM client failed", "error", err)
 		return api.NewFailedResponse(err.Error()), nil
 	}
 
-	rsTools := FileAccessTools(p.workingPath, p.logger)
+	commandTools, err := CommandTools(config, p.runner, p.logger)
+	if err != nil {
+		p.logger.Warnw("parse command tool config failed", "error", err)
+		return api.NewFailedResponse(err.Error()), nil
+	}
 
-	// Check for websearch_type config and add corresponding tools
-	switch p.config["friday_websearch_type"] {
-	case "pse":
-		engineID := p.config["friday_pse_engine_id"]
-		apiKey := p.config["friday_pse_api_key"]
-		if engineID != "" && apiKey != "" {
-			rsTools = append(rsTools, NewPSEWebSearchTool(engineID, apiKey, p.webCitations, p.logger)...)
-			p.logger.Infow("PSE web search tool added", "engine_id", engineID)
-		}
+	webOutputFilter, err := NewWebOutputFilter(config)
+	if err != nil {
+		p.logger.Warnw("parse web output filter config failed", "error", err)
+		return api.NewFailedResponse(err.Error()), nil
+	}
+
+	fileAccess := utils.NewFileAccess(p.workingPath, p.jobPath)
+	rsTools := append(FileAccessTools(fileAccess, p.logger), webOutputFilter.Wrap(p.webSearchTools())...)
+	rsTools = append(rsTools, commandTools...)
+
+	visionLLM, err := NewVisionLLMClient(config)
+	if err != nil {
+		p.logger.Warnw("create vision LLM client failed", "error", err)
+		return api.NewFailedResponse(err.Error()), nil
+	}
+	rsTools = append(rsTools, NewImageDescribeTool(fileAccess, visionLLM, p.logger))
+	entryTools := NewEntryTools(request.FS, p.workingPath, p.logger)
+	rsTools = append(rsTools, entryTools.Tools()...)
+
+	mcpTools, closeMCP, err := MCPTools(ctx, config, p.logger)
+	if err != nil {
+		p.logger.Warnw("parse mcp server config failed", "error", err)
+		return api.NewFailedResponse(err.Error()), nil
 	}
+	defer closeMCP()
+	rsTools = append(rsTools, mcpTools...)
+
+	toolAudit := NewToolAudit(p.workingPath, p.jobID, researchPluginName, p.logger)
+	agentTools, err := AgentTools(config, p.jobID, p.workingPath, llm, func(tools []*fridaytools.Tool) []*fridaytools.Tool {
+		return toolAudit.Wrap(limits.Wrap(tools))
+	}, p.logger)
+	if err != nil {
+		p.logger.Warnw("parse agent tool config failed", "error", err)
+		return api.NewFailedResponse(err.Error()), nil
+	}
+	rsTools = append(rsTools, agentTools...)
+
+	hostTools, err := HostTools(config, rsTools, p.logger)
+	if err != nil {
+		p.logger.Warnw("parse host tool config failed", "error", err)
+		return api.NewFailedResponse(err.Error()), nil
+	}
+	rsTools = append(rsTools, hostTools...)
 
 	agent := research.New("research", "Research Agent", llm, research.Option{
 		SystemPrompt: systemPrompt,
-		Tools:        rsTools,
+		Tools:        toolAudit.Wrap(limits.Wrap(rsTools)),
 	})
 
-	resp := agent.Chat(ctx, &fridayapi.Request{
+	runCtx, cancelRun := limits.Start(ctx)
+	defer cancelRun()
+	mem := session.Memory(p.jobID)
+	prior := mem.History()
+	resp := agent.Chat(runCtx, &fridayapi.Request{
 		Session:     NewSession(p.jobID),
-		Memory:      memory.NewEmpty(p.jobID),
+		Memory:      mem,
 		UserMessage: message,
 	})
 
-	content, err := fridayapi.ReadAllContent(ctx, resp)
-	if err != nil {
+	content, err := fridayapi.ReadAllContent(runCtx, resp)
+	exceeded := limits.Exceeded()
+	if err != nil && exceeded == "" {
 		p.logger.Warnw("collect response failed", "error", err)
-		return api.NewFailedResponse(err.Error()), nil
+		failed := api.NewFailedResponse(err.Error())
+		if audit := toolAudit.Result(); audit != nil {
+			failed.Results = map[string]any{"tool_audit": audit}
+		}
+		return failed, nil
+	}
+	if exceeded != "" {
+		p.logger.Warnw("research stopped by run limit", "limit", exceeded, "error", err)
+		if strings.TrimSpace(content) == "" {
+			content = partialAnswer(mem, prior)
+		}
 	}
 
 	var citations = make([]any, 0, len(p.webCitations.files))
@@ -108,19 +249,91 @@ func (p *ResearchPlugin) Run(ctx context.Context, request *api.Request) (*api.Re
 		citations = append(citations, utils.MarshalMap(c))
 	}
 
+	result := strings.TrimSpace(content)
+	if err = session.Record(ctx, message, result); err != nil {
+		p.logger.Warnw("record session failed", "error", err)
+		return api.NewFailedResponse(err.Error()), nil
+	}
+
 	p.logger.Infow("research plugin completed", "result_len", len(content))
-	return api.NewResponseWithResult(map[string]any{
-		"result":    strings.TrimSpace(content),
+	results := map[string]any{
+		"result":    result,
 		"citations": citations,
-	}), nil
+		"sources":   p.webCitations.Sources(result),
+	}
+	if session != nil {
+		results["session"] = session.Name()
+	}
+	if audit := toolAudit.Result(); audit != nil {
+		results["tool_audit"] = audit
+	}
+	if entries := entryTools.Result(); entries != nil {
+		results["entries"] = entries
+	}
+	if filtered := webOutputFilter.Result(); filtered != nil {
+		results["web_output_filter"] = filtered
+	}
+	if limits != nil {
+		results["budget_exceeded"] = exceeded != ""
+		results["run_limits"] = limits.Result()
+	}
+	if budget != nil {
+		results["llm_budget"] = budget.Result(ctx)
+	}
+	if n := llmRetries.Count(); n > 0 {
+		results["llm_retries"] = n
+	}
+	return api.NewResponseWithResult(results), nil
+}
+
+// webSearchTools returns the tools of the configured search provider, and falls back to the
+// keyless DuckDuckGo search when no provider is configured, unless websearch_type is none.
+func (p *ResearchPlugin) webSearchTools() []*fridaytools.Tool {
+	switch p.config["friday_websearch_type"] {
+	case "pse":
+		engineID := p.config["friday_pse_engine_id"]
+		apiKey := p.config["friday_pse_api_key"]
+		if engineID != "" && apiKey != "" {
+			p.logger.Infow("PSE web search tool added", "engine_id", engineID)
+			return NewPSEWebSearchTool(engineID, apiKey, p.webCitations, p.logger)
+		}
+	case "bing":
+		if apiKey := p.config["friday_bing_api_key"]; apiKey != "" {
+			p.logger.Infow("Bing web search tool added")
+			return NewBingWebSearchTool(apiKey, p.webCitations, p.logger)
+		}
+	case "brave":
+		if apiKey := p.config["friday_brave_api_key"]; apiKey != "" {
+			p.logger.Infow("Brave web search tool added")
+			return NewBraveWebSearchTool(apiKey, p.webCitations, p.logger)
+		}
+	case "searxng":
+		if baseURL := p.config["friday_searxng_url"]; baseURL != "" {
+			p.logger.Infow("SearxNG web search tool added", "url", baseURL)
+			return NewSearxNGWebSearchTool(baseURL, p.webCitations, p.logger)
+		}
+	case "duckduckgo":
+		return p.duckduckgoTools()
+	case "none":
+		return nil
+	}
+	p.logger.Warnw("no web search provider configured, fall back to DuckDuckGo", "websearch_type", p.config["friday_websearch_type"])
+	return p.duckduckgoTools()
+}
+
+func (p *ResearchPlugin) duckduckgoTools() []*fridaytools.Tool {
+	p.logger.Warnw("DuckDuckGo web search needs no key but reads the HTML result page, results may be fewer, less relevant or refused by bot checks; configure pse, bing, brave or searxng for better research")
+	return NewDuckDuckGoWebSearchTool(p.webCitations, p.logger)
 }
 
 func NewResearchPlugin(ps types.PluginCall) types.Plugin {
 	return &ResearchPlugin{
 		logger:       logger.NewPluginLogger(researchPluginName, ps.JobID),
 		workingPath:  ps.WorkingPath,
+		jobPath:      jobWorkingPath(ps),
 		jobID:        ps.JobID,
-		config:       ps.Config,
+		config:       PluginLLMConfig(ps.Config, researchPluginName),
 		webCitations: newWebCitations(ps.WorkingPath),
+		runner:       sandbox.ForCall(ps),
 	}
 }
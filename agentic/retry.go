@@ -0,0 +1,199 @@
+package agentic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	fridaylogger "github.com/basenana/friday/core/logger"
+	"github.com/basenana/friday/core/providers/openai"
+	openaisdk "github.com/openai/openai-go"
+)
+
+const (
+	ConfigMaxRetries   = "friday_llm_max_retries"
+	ConfigRetryBackoff = "friday_llm_retry_backoff"
+
+	defaultMaxRetries   = 3
+	maxRetriesLimit     = 10
+	defaultRetryBackoff = 2 * time.Second
+	maxRetryBackoff     = time.Minute
+	// maxRetryAfter bounds the wait a Retry-After header can ask for.
+	maxRetryAfter = 5 * time.Minute
+)
+
+type retryPolicy struct {
+	maxRetries int
+	backoff    time.Duration
+}
+
+func parseRetryPolicy(config map[string]string) (retryPolicy, error) {
+	policy := retryPolicy{maxRetries: defaultMaxRetries, backoff: defaultRetryBackoff}
+	if raw := config[ConfigMaxRetries]; raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 || n > maxRetriesLimit {
+			return policy, fmt.Errorf("invalid %s [%s]: expect 0 to %d", ConfigMaxRetries, raw, maxRetriesLimit)
+		}
+		policy.maxRetries = n
+	}
+	if raw := config[ConfigRetryBackoff]; raw != "" {
+		d, err := time.ParseDuration(raw)
+		if err != nil || d <= 0 {
+			return policy, fmt.Errorf("invalid %s [%s]: expect positive duration like 2s", ConfigRetryBackoff, raw)
+		}
+		policy.backoff = d
+	}
+	return policy, nil
+}
+
+// wait returns the delay before the given retry, starting at 1: the backoff doubles on each
+// retry, and a longer Retry-After of the API wins.
+func (p retryPolicy) wait(retry int, err error) time.Duration {
+	wait := p.backoff << (retry - 1)
+	if wait > maxRetryBackoff || wait <= 0 {
+		wait = maxRetryBackoff
+	}
+	if after := retryAfter(err); after > wait {
+		wait = min(after, maxRetryAfter)
+	}
+	return wait
+}
+
+// LLMRetries counts the retried LLM calls of a plugin step.
+type LLMRetries struct {
+	count atomic.Int64
+}
+
+func (r *LLMRetries) Count() int64 {
+	if r == nil {
+		return 0
+	}
+	return r.count.Load()
+}
+
+type llmRetriesKey struct{}
+
+// WithLLMRetries makes the clients of NewLLMClient count their retries into the returned counter.
+func WithLLMRetries(ctx context.Context) (context.Context, *LLMRetries) {
+	retries := &LLMRetries{}
+	return context.WithValue(ctx, llmRetriesKey{}, retries), retries
+}
+
+// retryClient retries the calls of the wrapped client that fail with a rate limit, a server
+// error or a network error. A streaming call is only retried before its first delta.
+type retryClient struct {
+	openai.Client
+	policy retryPolicy
+}
+
+func (c retryClient) Completion(ctx context.Context, request openai.Request) openai.Response {
+	if c.policy.maxRetries == 0 {
+		return c.Client.Completion(ctx, request)
+	}
+	resp := &budgetResponse{stream: make(chan openai.Delta, 5), err: make(chan error, 1)}
+	go func() {
+		defer close(resp.err)
+		defer close(resp.stream)
+		for retry := 0; ; retry++ {
+			var (
+				upstream = c.Client.Completion(ctx, request)
+				streamed bool
+				err      error
+			)
+			for delta := range upstream.Message() {
+				streamed = true
+				resp.stream <- delta
+			}
+			for e := range upstream.Error() {
+				if e != nil && err == nil {
+					err = e
+				}
+			}
+			resp.tokens = upstream.Tokens()
+			if err == nil || streamed || !c.retry(ctx, retry+1, err) {
+				if err != nil {
+					resp.err <- err
+				}
+				return
+			}
+		}
+	}()
+	return resp
+}
+
+func (c retryClient) CompletionNonStreaming(ctx context.Context, request openai.Request) (string, error) {
+	for retry := 0; ; retry++ {
+		reply, err := c.Client.CompletionNonStreaming(ctx, request)
+		if err == nil || !c.retry(ctx, retry+1, err) {
+			return reply, err
+		}
+	}
+}
+
+func (c retryClient) StructuredPredict(ctx context.Context, request openai.Request, model any) error {
+	for retry := 0; ; retry++ {
+		err := c.Client.StructuredPredict(ctx, request, model)
+		if err == nil || !c.retry(ctx, retry+1, err) {
+			return err
+		}
+	}
+}
+
+// retry waits before the given retry and reports whether the call should be made again.
+func (c retryClient) retry(ctx context.Context, retry int, err error) bool {
+	if retry > c.policy.maxRetries || !retryableLLMError(ctx, err) {
+		return false
+	}
+	wait := c.policy.wait(retry, err)
+	fridaylogger.Default().Warnw("llm call failed, retry", "retry", retry, "max_retries", c.policy.maxRetries, "wait", wait.String(), "error", err)
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+	}
+	if retries, ok := ctx.Value(llmRetriesKey{}).(*LLMRetries); ok {
+		retries.count.Add(1)
+	}
+	return true
+}
+
+// retryableLLMError reports rate limits, server errors, timeouts and dropped connections; the
+// errors of the request itself, the budget and a canceled step are final.
+func retryableLLMError(ctx context.Context, err error) bool {
+	if ctx.Err() != nil || errors.Is(err, ErrBudgetExceeded) || errors.Is(err, ErrRunLimitExceeded) {
+		return false
+	}
+	var apiErr *openaisdk.Error
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode == http.StatusRequestTimeout || apiErr.StatusCode == http.StatusTooManyRequests || apiErr.StatusCode >= 500
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF)
+}
+
+// retryAfter reads the Retry-After header of an API error, in seconds or as an HTTP date.
+func retryAfter(err error) time.Duration {
+	var apiErr *openaisdk.Error
+	if !errors.As(err, &apiErr) || apiErr.Response == nil {
+		return 0
+	}
+	if ms, err := strconv.ParseFloat(apiErr.Response.Header.Get("Retry-After-Ms"), 64); err == nil && ms > 0 {
+		return time.Duration(ms * float64(time.Millisecond))
+	}
+	raw := apiErr.Response.Header.Get("Retry-After")
+	if seconds, err := strconv.ParseFloat(raw, 64); err == nil && seconds > 0 {
+		return time.Duration(seconds * float64(time.Second))
+	}
+	if at, err := http.ParseTime(raw); err == nil {
+		return time.Until(at)
+	}
+	return 0
+}
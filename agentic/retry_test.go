@@ -0,0 +1,203 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package agentic
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/basenana/friday/core/providers/openai"
+	fridaytypes "github.com/basenana/friday/core/types"
+	openaisdk "github.com/openai/openai-go"
+)
+
+const (
+	completionReply = `{"choices": [{"message": {"role": "assistant", "content": "ok"}}]}`
+	streamReply     = "data: {\"id\":\"1\",\"object\":\"chat.completion.chunk\",\"choices\":[{\"index\":0,\"delta\":{\"content\":\"ok\"}}]}\n\ndata: [DONE]\n\n"
+)
+
+// flakyLLMServer fails the first calls with status, the SDK is told not to retry them itself.
+func flakyLLMServer(t *testing.T, failures int32, status int) (*httptest.Server, *atomic.Int32) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hits.Add(1) <= failures {
+			w.Header().Set("x-should-retry", "false")
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(status)
+			_, _ = w.Write([]byte(`{"error": {"message": "unavailable"}}`))
+			return
+		}
+		var body struct {
+			Stream bool `json:"stream"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if body.Stream {
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = w.Write([]byte(streamReply))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(completionReply))
+	}))
+	t.Cleanup(server.Close)
+	return server, &hits
+}
+
+func retryConfig(host, maxRetries string) map[string]string {
+	return map[string]string{ConfigHost: host, ConfigAPIKey: "sk", ConfigModel: "gpt-4o", ConfigMaxRetries: maxRetries, ConfigRetryBackoff: "1ms"}
+}
+
+func TestRetryClient_ServerErrors(t *testing.T) {
+	server, hits := flakyLLMServer(t, 2, http.StatusServiceUnavailable)
+	llm, err := NewLLMClient(retryConfig(server.URL, "3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, retries := WithLLMRetries(context.Background())
+	reply, err := llm.CompletionNonStreaming(ctx, openai.NewSimpleRequest("system", fridaytypes.Message{UserMessage: "hi"}))
+	if err != nil || reply != "ok" {
+		t.Fatalf("expect the call to succeed after retries, got %q, %v", reply, err)
+	}
+	if hits.Load() != 3 || retries.Count() != 2 {
+		t.Errorf("expect 3 requests and 2 retries, got %d and %d", hits.Load(), retries.Count())
+	}
+}
+
+func TestRetryClient_Streaming(t *testing.T) {
+	server, hits := flakyLLMServer(t, 1, http.StatusBadGateway)
+	llm, err := NewLLMClient(retryConfig(server.URL, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, retries := WithLLMRetries(context.Background())
+	content, err := readCompletion(llm.Completion(ctx, openai.NewSimpleRequest("system", fridaytypes.Message{UserMessage: "hi"})))
+	if err != nil || content != "ok" {
+		t.Fatalf("expect the stream to succeed after a retry, got %q, %v", content, err)
+	}
+	if hits.Load() != 2 || retries.Count() != 1 {
+		t.Errorf("expect 2 requests and 1 retry, got %d and %d", hits.Load(), retries.Count())
+	}
+}
+
+func TestRetryClient_GiveUp(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		status     int
+		maxRetries string
+		hits       int32
+	}{
+		{name: "retries exhausted", status: http.StatusInternalServerError, maxRetries: "2", hits: 3},
+		{name: "retry disabled", status: http.StatusInternalServerError, maxRetries: "0", hits: 1},
+		{name: "bad request", status: http.StatusBadRequest, maxRetries: "3", hits: 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			server, hits := flakyLLMServer(t, 100, tc.status)
+			llm, err := NewLLMClient(retryConfig(server.URL, tc.maxRetries))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err = llm.CompletionNonStreaming(context.Background(), openai.NewSimpleRequest("system", fridaytypes.Message{UserMessage: "hi"})); err == nil {
+				t.Fatal("expect the call to fail")
+			}
+			if hits.Load() != tc.hits {
+				t.Errorf("expect %d requests, got %d", tc.hits, hits.Load())
+			}
+		})
+	}
+}
+
+func TestParseRetryPolicy(t *testing.T) {
+	policy, err := parseRetryPolicy(map[string]string{})
+	if err != nil || policy.maxRetries != defaultMaxRetries || policy.backoff != defaultRetryBackoff {
+		t.Fatalf("unexpected default policy %+v, %v", policy, err)
+	}
+	for _, config := range []map[string]string{
+		{ConfigMaxRetries: "-1"},
+		{ConfigMaxRetries: "11"},
+		{ConfigRetryBackoff: "2"},
+		{ConfigRetryBackoff: "0s"},
+	} {
+		if _, err = parseRetryPolicy(config); err == nil {
+			t.Errorf("expect error for %v", config)
+		}
+	}
+	if _, err = NewLLMClient(map[string]string{ConfigHost: "http://localhost", ConfigAPIKey: "sk", ConfigModel: "m", ConfigMaxRetries: "many"}); err == nil {
+		t.Errorf("expect NewLLMClient to reject an invalid retry config")
+	}
+}
+
+func TestRetryPolicy_Wait(t *testing.T) {
+	policy := retryPolicy{maxRetries: 5, backoff: time.Second}
+	if policy.wait(1, errors.New("eof")) != time.Second || policy.wait(3, errors.New("eof")) != 4*time.Second {
+		t.Errorf("expect exponential backoff")
+	}
+	if policy.wait(10, errors.New("eof")) != maxRetryBackoff {
+		t.Errorf("expect the backoff capped at %s", maxRetryBackoff)
+	}
+
+	apiErr := func(header, value string) error {
+		resp := &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{}}
+		resp.Header.Set(header, value)
+		return fmt.Errorf("call failed: %w", &openaisdk.Error{StatusCode: http.StatusTooManyRequests, Response: resp})
+	}
+	if wait := policy.wait(1, apiErr("Retry-After", "30")); wait != 30*time.Second {
+		t.Errorf("expect Retry-After honored, got %s", wait)
+	}
+	if wait := policy.wait(1, apiErr("Retry-After-Ms", "1500")); wait != 1500*time.Millisecond {
+		t.Errorf("expect Retry-After-Ms honored, got %s", wait)
+	}
+	if wait := policy.wait(1, apiErr("Retry-After", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))); wait != maxRetryAfter {
+		t.Errorf("expect a long Retry-After capped at %s, got %s", maxRetryAfter, wait)
+	}
+	if wait := policy.wait(2, apiErr("Retry-After", "1")); wait != 2*time.Second {
+		t.Errorf("expect the backoff when longer than Retry-After, got %s", wait)
+	}
+}
+
+func TestRetryableLLMError(t *testing.T) {
+	ctx := context.Background()
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	for _, tc := range []struct {
+		name string
+		ctx  context.Context
+		err  error
+		want bool
+	}{
+		{name: "server error", ctx: ctx, err: &openaisdk.Error{StatusCode: http.StatusServiceUnavailable}, want: true},
+		{name: "rate limit", ctx: ctx, err: &openaisdk.Error{StatusCode: http.StatusTooManyRequests}, want: true},
+		{name: "timeout", ctx: ctx, err: &openaisdk.Error{StatusCode: http.StatusRequestTimeout}, want: true},
+		{name: "bad request", ctx: ctx, err: &openaisdk.Error{StatusCode: http.StatusBadRequest}, want: false},
+		{name: "budget", ctx: ctx, err: fmt.Errorf("%w: 3 of 3 calls used", ErrBudgetExceeded), want: false},
+		{name: "run limit", ctx: ctx, err: fmt.Errorf("%w: max_tokens", ErrRunLimitExceeded), want: false},
+		{name: "canceled step", ctx: canceled, err: &openaisdk.Error{StatusCode: http.StatusServiceUnavailable}, want: false},
+		{name: "other", ctx: ctx, err: errors.New("no completion choices returned"), want: false},
+	} {
+		if got := retryableLLMError(tc.ctx, tc.err); got != tc.want {
+			t.Errorf("%s: expect %v, got %v", tc.name, tc.want, got)
+		}
+	}
+}
@@ -0,0 +1,292 @@
+package agentic
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// schemaKeywords is the JSON Schema subset validateSchema understands; schemas using other
+// keywords are rejected instead of silently passing unchecked.
+var schemaKeywords = map[string]bool{
+	"$schema": true, "$id": true, "title": true, "description": true, "default": true, "examples": true,
+	"type": true, "enum": true, "const": true, "format": true,
+	"properties": true, "required": true, "additionalProperties": true,
+	"items": true, "minItems": true, "maxItems": true,
+	"minLength": true, "maxLength": true, "pattern": true,
+	"minimum": true, "maximum": true, "exclusiveMinimum": true, "exclusiveMaximum": true,
+	"anyOf": true,
+}
+
+// parseSchema decodes a JSON Schema object and checks that every keyword is supported.
+func parseSchema(raw string) (map[string]any, error) {
+	var schema map[string]any
+	if err := json.Unmarshal([]byte(raw), &schema); err != nil {
+		return nil, fmt.Errorf("parse schema failed: expect a JSON Schema object: %s", err)
+	}
+	if err := checkSchema(schema, "schema"); err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+func checkSchema(schema map[string]any, path string) error {
+	for key, value := range schema {
+		if !schemaKeywords[key] {
+			return fmt.Errorf("%s: unsupported schema keyword %s", path, key)
+		}
+		switch key {
+		case "properties":
+			props, ok := value.(map[string]any)
+			if !ok {
+				return fmt.Errorf("%s.properties: expect an object", path)
+			}
+			for name, sub := range props {
+				if err := checkSubSchema(sub, path+".properties."+name); err != nil {
+					return err
+				}
+			}
+		case "items":
+			if err := checkSubSchema(value, path+".items"); err != nil {
+				return err
+			}
+		case "additionalProperties":
+			if _, ok := value.(bool); !ok {
+				if err := checkSubSchema(value, path+".additionalProperties"); err != nil {
+					return err
+				}
+			}
+		case "anyOf":
+			list, ok := value.([]any)
+			if !ok || len(list) == 0 {
+				return fmt.Errorf("%s.anyOf: expect a non-empty array", path)
+			}
+			for i, sub := range list {
+				if err := checkSubSchema(sub, fmt.Sprintf("%s.anyOf[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		case "pattern":
+			pattern, _ := value.(string)
+			if _, err := regexp.Compile(pattern); err != nil {
+				return fmt.Errorf("%s.pattern: %s", path, err)
+			}
+		}
+	}
+	return nil
+}
+
+func checkSubSchema(value any, path string) error {
+	sub, ok := value.(map[string]any)
+	if !ok {
+		return fmt.Errorf("%s: expect a schema object", path)
+	}
+	return checkSchema(sub, path)
+}
+
+// validateSchema returns the violations of value, each prefixed with its JSON path.
+func validateSchema(schema map[string]any, value any, path string) []string {
+	var errs []string
+	fail := func(format string, args ...any) {
+		errs = append(errs, path+": "+fmt.Sprintf(format, args...))
+	}
+
+	if t, ok := schema["type"]; ok && !matchSchemaType(t, value) {
+		fail("expect type %s, got %s", schemaTypeName(t), jsonTypeName(value))
+		return errs
+	}
+	if enum, ok := schema["enum"].([]any); ok && !containsValue(enum, value) {
+		fail("expect one of %s", compactJSON(enum))
+	}
+	if c, ok := schema["const"]; ok && !reflect.DeepEqual(c, value) {
+		fail("expect %s", compactJSON(c))
+	}
+	if anyOf, ok := schema["anyOf"].([]any); ok {
+		matched := false
+		for _, sub := range anyOf {
+			if len(validateSchema(sub.(map[string]any), value, path)) == 0 {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			fail("does not match any schema of anyOf")
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		errs = append(errs, validateObject(schema, v, path)...)
+	case []any:
+		if n, ok := schemaNumber(schema, "minItems"); ok && float64(len(v)) < n {
+			fail("expect at least %v items, got %d", n, len(v))
+		}
+		if n, ok := schemaNumber(schema, "maxItems"); ok && float64(len(v)) > n {
+			fail("expect at most %v items, got %d", n, len(v))
+		}
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range v {
+				errs = append(errs, validateSchema(items, item, fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+	case string:
+		length := float64(utf8.RuneCountInString(v))
+		if n, ok := schemaNumber(schema, "minLength"); ok && length < n {
+			fail("expect at least %v characters", n)
+		}
+		if n, ok := schemaNumber(schema, "maxLength"); ok && length > n {
+			fail("expect at most %v characters", n)
+		}
+		if pattern, ok := schema["pattern"].(string); ok && !regexp.MustCompile(pattern).MatchString(v) {
+			fail("does not match pattern %s", pattern)
+		}
+		if format, ok := schema["format"].(string); ok && !matchFormat(format, v) {
+			fail("expect format %s", format)
+		}
+	case float64:
+		if n, ok := schemaNumber(schema, "minimum"); ok && v < n {
+			fail("expect minimum %v", n)
+		}
+		if n, ok := schemaNumber(schema, "maximum"); ok && v > n {
+			fail("expect maximum %v", n)
+		}
+		if n, ok := schemaNumber(schema, "exclusiveMinimum"); ok && v <= n {
+			fail("expect greater than %v", n)
+		}
+		if n, ok := schemaNumber(schema, "exclusiveMaximum"); ok && v >= n {
+			fail("expect less than %v", n)
+		}
+	}
+	return errs
+}
+
+func validateObject(schema map[string]any, obj map[string]any, path string) []string {
+	var errs []string
+	required, _ := schema["required"].([]any)
+	for _, name := range required {
+		key, _ := name.(string)
+		if _, ok := obj[key]; !ok {
+			errs = append(errs, fmt.Sprintf("%s: missing required property %s", path, key))
+		}
+	}
+
+	props, _ := schema["properties"].(map[string]any)
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if sub, ok := props[key].(map[string]any); ok {
+			errs = append(errs, validateSchema(sub, obj[key], path+"."+key)...)
+			continue
+		}
+		switch extra := schema["additionalProperties"].(type) {
+		case bool:
+			if !extra {
+				errs = append(errs, fmt.Sprintf("%s: property %s is not allowed", path, key))
+			}
+		case map[string]any:
+			errs = append(errs, validateSchema(extra, obj[key], path+"."+key)...)
+		}
+	}
+	return errs
+}
+
+func matchSchemaType(t any, value any) bool {
+	switch t := t.(type) {
+	case string:
+		return matchType(t, value)
+	case []any:
+		for _, item := range t {
+			if name, ok := item.(string); ok && matchType(name, value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func matchType(name string, value any) bool {
+	switch name {
+	case "integer":
+		n, ok := value.(float64)
+		return ok && n == math.Trunc(n)
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	default:
+		return jsonTypeName(value) == name
+	}
+}
+
+func jsonTypeName(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func schemaTypeName(t any) string {
+	if list, ok := t.([]any); ok {
+		names := make([]string, 0, len(list))
+		for _, item := range list {
+			names = append(names, fmt.Sprint(item))
+		}
+		return strings.Join(names, " or ")
+	}
+	return fmt.Sprint(t)
+}
+
+func schemaNumber(schema map[string]any, key string) (float64, bool) {
+	n, ok := schema[key].(float64)
+	return n, ok
+}
+
+func containsValue(list []any, value any) bool {
+	for _, item := range list {
+		if reflect.DeepEqual(item, value) {
+			return true
+		}
+	}
+	return false
+}
+
+var emailPattern = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`)
+
+// matchFormat checks the date, date-time and email formats, other formats are annotations.
+func matchFormat(format, value string) bool {
+	switch format {
+	case "date":
+		_, err := time.Parse("2006-01-02", value)
+		return err == nil
+	case "date-time":
+		_, err := time.Parse(time.RFC3339, value)
+		return err == nil
+	case "email":
+		return emailPattern.MatchString(value)
+	}
+	return true
+}
+
+func compactJSON(value any) string {
+	data, _ := json.Marshal(value)
+	return string(data)
+}
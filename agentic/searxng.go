@@ -0,0 +1,61 @@
+package agentic
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type searxngSearchResponse struct {
+	Results []struct {
+		Title   string `json:"title"`
+		URL     string `json:"url"`
+		Content string `json:"content"`
+	} `json:"results"`
+}
+
+// searxngSearch queries the search endpoint of a SearxNG instance, which must have the json
+// format enabled in its settings.
+func searxngSearch(baseURL string) webSearchFunc {
+	endpoint := strings.TrimSuffix(baseURL, "/") + "/search"
+	return func(ctx context.Context, query, timeRange string) ([]WebSearchItem, error) {
+		params := url.Values{}
+		params.Set("q", query)
+		params.Set("format", "json")
+		switch timeRange {
+		case "day", "week", "month", "year":
+			params.Set("time_range", timeRange)
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+"?"+params.Encode(), nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "application/json")
+
+		var result searxngSearchResponse
+		if err = doSearchRequest(req, &result); err != nil {
+			return nil, fmt.Errorf("searxng search failed: %w", err)
+		}
+
+		var items []WebSearchItem
+		for _, page := range result.Results {
+			if len(items) == 10 {
+				break
+			}
+			var site string
+			if u, err := url.Parse(page.URL); err == nil {
+				site = u.Host
+			}
+			items = append(items, WebSearchItem{
+				Title:   page.Title,
+				Content: page.Content,
+				Site:    site,
+				URL:     page.URL,
+			})
+		}
+		return items, nil
+	}
+}
@@ -0,0 +1,79 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package agentic
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	fridaytools "github.com/basenana/friday/core/tools"
+	"github.com/basenana/plugin/logger"
+)
+
+func TestSearxNGWebSearch(t *testing.T) {
+	var got *http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		var results []string
+		for i := 0; i < 12; i++ {
+			results = append(results, fmt.Sprintf(`{"title":"Go %d","url":"https://go.dev/doc/%d","content":"Documentation","engine":"duckduckgo"}`, i, i))
+		}
+		_, _ = w.Write([]byte(`{"query":"golang","results":[` + strings.Join(results, ",") + `]}`))
+	}))
+	defer server.Close()
+
+	search := getToolByName(NewSearxNGWebSearchTool(server.URL+"/", newWebCitations(t.TempDir()), logger.NewLogger("test")), "web_search")
+	result, err := search.Handler(t.Context(), &fridaytools.Request{Arguments: map[string]any{"query": "golang", "time_range": "month"}})
+	if err != nil || result.IsError {
+		t.Fatalf("search failed: %v %s", err, getResultText(result))
+	}
+	if got.URL.Path != "/search" {
+		t.Errorf("expected /search, got %s", got.URL.Path)
+	}
+	if q := got.URL.Query(); q.Get("q") != "golang" || q.Get("format") != "json" || q.Get("time_range") != "month" {
+		t.Errorf("unexpected query %s", got.URL.RawQuery)
+	}
+
+	var items []WebSearchItem
+	if err = json.Unmarshal([]byte(getResultText(result)), &items); err != nil {
+		t.Fatalf("decode result failed: %v", err)
+	}
+	want := WebSearchItem{Title: "Go 0", Content: "Documentation", Site: "go.dev", URL: "https://go.dev/doc/0"}
+	if len(items) != 10 || items[0] != want {
+		t.Errorf("expected 10 items starting with %+v, got %+v", want, items)
+	}
+}
+
+func TestSearxNGWebSearchFormatDisabled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	search := getToolByName(NewSearxNGWebSearchTool(server.URL, newWebCitations(t.TempDir()), logger.NewLogger("test")), "web_search")
+	result, err := search.Handler(t.Context(), &fridaytools.Request{Arguments: map[string]any{"query": "golang", "time_range": "anytime"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.IsError || !strings.Contains(getResultText(result), "403") {
+		t.Errorf("expected tool error, got %s", getResultText(result))
+	}
+}
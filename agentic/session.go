@@ -0,0 +1,174 @@
+package agentic
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"sync"
+
+	"github.com/basenana/friday/core/memory"
+	"github.com/basenana/friday/core/types"
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/utils"
+)
+
+const (
+	sessionSource = "agentic"
+	sessionGroup  = "session"
+	// sessionDir keeps the sessions under the working path when the step has no persistent store.
+	sessionDir = ".friday/sessions"
+
+	maxSessionMessages      = 40
+	maxSessionMessageLength = 8000
+)
+
+var (
+	sessionNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+	sessionMu          sync.Mutex
+)
+
+// SessionStore keeps the conversation history of named sessions between the steps of a job.
+type SessionStore interface {
+	Load(ctx context.Context, name string) ([]types.Message, error)
+	Save(ctx context.Context, name string, history []types.Message) error
+}
+
+// NewSessionStore returns a store backed by the persistent store of the step, or by files
+// under the working path when the step has none.
+func NewSessionStore(jobID string, store api.PersistentStore, workingPath string) SessionStore {
+	if store != nil {
+		return &persistentSessionStore{jobID: jobID, store: store}
+	}
+	return &fileSessionStore{fileAccess: utils.NewFileAccess(workingPath)}
+}
+
+type sessionRecord struct {
+	History []types.Message `json:"history"`
+}
+
+type persistentSessionStore struct {
+	jobID string
+	store api.PersistentStore
+}
+
+func (s *persistentSessionStore) Load(ctx context.Context, name string) ([]types.Message, error) {
+	var record sessionRecord
+	// a new session has no record yet
+	_ = s.store.Load(ctx, sessionSource, sessionGroup, s.jobID+"/"+name, &record)
+	return record.History, nil
+}
+
+func (s *persistentSessionStore) Save(ctx context.Context, name string, history []types.Message) error {
+	return s.store.Save(ctx, sessionSource, sessionGroup, s.jobID+"/"+name, &sessionRecord{History: history})
+}
+
+type fileSessionStore struct {
+	fileAccess *utils.FileAccess
+}
+
+func (s *fileSessionStore) Load(ctx context.Context, name string) ([]types.Message, error) {
+	data, err := s.fileAccess.Read(path.Join(sessionDir, name+".json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var record sessionRecord
+	if err = json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("parse session %s failed: %w", name, err)
+	}
+	return record.History, nil
+}
+
+func (s *fileSessionStore) Save(ctx context.Context, name string, history []types.Message) error {
+	data, err := json.Marshal(&sessionRecord{History: history})
+	if err != nil {
+		return err
+	}
+	if err = s.fileAccess.MkdirAll(sessionDir, 0755); err != nil {
+		return err
+	}
+	return s.fileAccess.Write(path.Join(sessionDir, name+".json"), data, 0644)
+}
+
+// AgentSession carries the conversation of the "session" parameter across the agentic steps
+// of a job, e.g. research, then summary, then react writing the report. Steps without the
+// parameter start with an empty history, as before.
+type AgentSession struct {
+	name    string
+	store   SessionStore
+	history []types.Message
+}
+
+// OpenSession loads the session named by the "session" parameter, it returns nil when the
+// parameter is not given.
+func OpenSession(ctx context.Context, request *api.Request, store SessionStore) (*AgentSession, error) {
+	name := api.GetStringParameter("session", request, "")
+	if name == "" {
+		return nil, nil
+	}
+	if !sessionNamePattern.MatchString(name) {
+		return nil, fmt.Errorf("invalid session [%s]: expect 1 to 64 letters, digits, _ or -", name)
+	}
+	history, err := store.Load(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("load session %s failed: %w", name, err)
+	}
+	return &AgentSession{name: name, store: store, history: history}, nil
+}
+
+// Memory returns the agent memory, starting with the history of the session.
+func (s *AgentSession) Memory(jobID string) *memory.Memory {
+	if s == nil || len(s.history) == 0 {
+		return memory.NewEmpty(jobID)
+	}
+	history := make([]types.Message, len(s.history))
+	copy(history, s.history)
+	return memory.NewEmpty(jobID, memory.WithHistory(history...))
+}
+
+// Record appends the exchange of the step; only the task and the final answer are kept, not
+// the tool calls, and the session keeps its latest messages.
+func (s *AgentSession) Record(ctx context.Context, userMessage, answer string) error {
+	if s == nil {
+		return nil
+	}
+	sessionMu.Lock()
+	defer sessionMu.Unlock()
+
+	// reload, another step may have recorded since this one started
+	history, err := s.store.Load(ctx, s.name)
+	if err != nil {
+		return fmt.Errorf("load session %s failed: %w", s.name, err)
+	}
+	history = append(history,
+		types.Message{UserMessage: truncateSessionMessage(userMessage)},
+		types.Message{AssistantMessage: truncateSessionMessage(answer)},
+	)
+	if len(history) > maxSessionMessages {
+		history = history[len(history)-maxSessionMessages:]
+	}
+	if err = s.store.Save(ctx, s.name, history); err != nil {
+		return fmt.Errorf("save session %s failed: %w", s.name, err)
+	}
+	s.history = history
+	return nil
+}
+
+// Name returns the session name for the step results.
+func (s *AgentSession) Name() string {
+	return s.name
+}
+
+func truncateSessionMessage(message string) string {
+	runes := []rune(message)
+	if len(runes) <= maxSessionMessageLength {
+		return message
+	}
+	return string(runes[:maxSessionMessageLength]) + "\n\n[truncated]"
+}
@@ -0,0 +1,152 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package agentic
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/basenana/plugin/api"
+)
+
+func sessionRequest(name string) *api.Request {
+	return &api.Request{Parameter: map[string]any{"session": name}}
+}
+
+func TestOpenSession_NotRequested(t *testing.T) {
+	session, err := OpenSession(context.Background(), &api.Request{Parameter: map[string]any{}}, NewSessionStore("job-1", nil, t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if session != nil {
+		t.Fatalf("expected no session, got %v", session)
+	}
+	if history := session.Memory("job-1").History(); len(history) != 0 {
+		t.Errorf("expected empty memory, got %v", history)
+	}
+	if err = session.Record(context.Background(), "task", "answer"); err != nil {
+		t.Errorf("expected recording without session to be a no-op, got %v", err)
+	}
+}
+
+func TestOpenSession_InvalidName(t *testing.T) {
+	for _, name := range []string{"../escape", "a b", strings.Repeat("x", 65)} {
+		if _, err := OpenSession(context.Background(), sessionRequest(name), NewSessionStore("job-1", nil, t.TempDir())); err == nil {
+			t.Errorf("expected invalid session %q to be rejected", name)
+		}
+	}
+}
+
+func TestAgentSession_WorkingPath(t *testing.T) {
+	var (
+		ctx     = context.Background()
+		workdir = t.TempDir()
+	)
+	research, err := OpenSession(ctx, sessionRequest("report"), NewSessionStore("job-1", nil, workdir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = research.Record(ctx, "research quantum computing", "qubits are improving"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(filepath.Join(workdir, sessionDir, "report.json")); err != nil {
+		t.Fatalf("expected the session file under the working path: %v", err)
+	}
+
+	summary, err := OpenSession(ctx, sessionRequest("report"), NewSessionStore("job-1", nil, workdir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	history := summary.Memory("job-1").History()
+	if len(history) != 2 || history[0].UserMessage != "research quantum computing" || history[1].AssistantMessage != "qubits are improving" {
+		t.Fatalf("expected the research exchange in memory, got %+v", history)
+	}
+
+	other, err := OpenSession(ctx, sessionRequest("other"), NewSessionStore("job-1", nil, workdir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if history = other.Memory("job-1").History(); len(history) != 0 {
+		t.Errorf("expected another session to start empty, got %+v", history)
+	}
+}
+
+func TestAgentSession_PersistentStore(t *testing.T) {
+	var (
+		ctx   = context.Background()
+		store = newMemStore()
+	)
+	first, err := OpenSession(ctx, sessionRequest("report"), NewSessionStore("job-1", store, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a step that started before the first one recorded still keeps its exchange
+	second, err := OpenSession(ctx, sessionRequest("report"), NewSessionStore("job-1", store, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = first.Record(ctx, "task 1", "answer 1"); err != nil {
+		t.Fatal(err)
+	}
+	if err = second.Record(ctx, "task 2", "answer 2"); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := OpenSession(ctx, sessionRequest("report"), NewSessionStore("job-1", store, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if history := reopened.Memory("job-1").History(); len(history) != 4 || history[3].AssistantMessage != "answer 2" {
+		t.Fatalf("expected both exchanges, got %+v", history)
+	}
+
+	otherJob, err := OpenSession(ctx, sessionRequest("report"), NewSessionStore("job-2", store, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if history := otherJob.Memory("job-2").History(); len(history) != 0 {
+		t.Errorf("expected sessions to be scoped to the job, got %+v", history)
+	}
+}
+
+func TestAgentSession_Limits(t *testing.T) {
+	ctx := context.Background()
+	session, err := OpenSession(ctx, sessionRequest("long"), NewSessionStore("job-1", nil, t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < maxSessionMessages; i++ {
+		if err = session.Record(ctx, "task", "answer"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = session.Record(ctx, "last task", strings.Repeat("a", maxSessionMessageLength+10)); err != nil {
+		t.Fatal(err)
+	}
+
+	history := session.Memory("job-1").History()
+	if len(history) != maxSessionMessages {
+		t.Fatalf("expected %d messages, got %d", maxSessionMessages, len(history))
+	}
+	last := history[len(history)-1].AssistantMessage
+	if history[len(history)-2].UserMessage != "last task" || !strings.HasSuffix(last, "[truncated]") || len([]rune(last)) > maxSessionMessageLength+20 {
+		t.Errorf("expected the latest exchange truncated, got %q", last[len(last)-20:])
+	}
+}
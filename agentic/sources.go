@@ -0,0 +1,206 @@
+package agentic
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/basenana/plugin/types"
+)
+
+const (
+	maxSourceClaims      = 10
+	maxSourceClaimLength = 500
+	minClaimWords        = 3
+)
+
+var (
+	markdownLinkPattern   = regexp.MustCompile(`\[([^\]]*)\]\((https?://[^\s)]+)(?:\s+"[^"]*")?\)`)
+	bareURLPattern        = regexp.MustCompile(`https?://[^\s<>()\[\]"']+`)
+	referenceDefPattern   = regexp.MustCompile(`^\s*\[([^\]]+)\]:\s*<?(https?://[^\s>]+)>?`)
+	numberedSourcePattern = regexp.MustCompile(`^\s*\[?(\d+)[\].):]+\s*(?:\[[^\]]*\]\((https?://[^\s)]+)\)|<?(https?://[^\s>]+)>?)(.*)$`)
+	referenceUsePattern   = regexp.MustCompile(`\[([^\]]+)\]`)
+	sentenceEndPattern    = regexp.MustCompile(`[.!?。！？]\s+`)
+	listMarkerPattern     = regexp.MustCompile(`^\s*(?:[-*+>]|#{1,6}|\d+[.)])\s+`)
+	claimSpacePattern     = regexp.MustCompile(`\s+`)
+	claimLeftoverPattern  = regexp.MustCompile(`\(\s*\)|\[\s*\]`)
+)
+
+// webSources keeps the pages research found in search results or read, by URL.
+type webSources struct {
+	mu      sync.Mutex
+	order   []string
+	sources map[string]*types.Source
+}
+
+func (s *webSources) get(rawURL string) *types.Source {
+	key := sourceKey(rawURL)
+	if s.sources == nil {
+		s.sources = map[string]*types.Source{}
+	}
+	source, ok := s.sources[key]
+	if !ok {
+		source = &types.Source{URL: rawURL}
+		s.sources[key] = source
+		s.order = append(s.order, key)
+	}
+	return source
+}
+
+// searched records the titles of search results, they become sources when the answer cites them.
+func (wc *WebCitations) searched(items []WebSearchItem) {
+	if wc == nil {
+		return
+	}
+	wc.sources.mu.Lock()
+	defer wc.sources.mu.Unlock()
+	for _, item := range items {
+		if item.URL == "" {
+			continue
+		}
+		if source := wc.sources.get(item.URL); source.Title == "" {
+			source.Title = strings.TrimSpace(item.Title)
+		}
+	}
+}
+
+// accessed records a page that was read, title is empty when the tool does not know it.
+func (wc *WebCitations) accessed(rawURL, title string) {
+	if wc == nil {
+		return
+	}
+	wc.sources.mu.Lock()
+	defer wc.sources.mu.Unlock()
+	source := wc.sources.get(rawURL)
+	if title = strings.TrimSpace(title); title != "" {
+		source.Title = title
+	}
+	source.AccessedAt = time.Now().Unix()
+}
+
+// Sources returns the pages read by research and the search results cited by the answer,
+// each with the sentences of the answer that link to it.
+func (wc *WebCitations) Sources(answer string) []types.Source {
+	claims, cited := answerClaims(answer)
+	wc.sources.mu.Lock()
+	defer wc.sources.mu.Unlock()
+
+	sources := make([]types.Source, 0, len(wc.sources.order))
+	for _, key := range wc.sources.order {
+		source := *wc.sources.sources[key]
+		if source.AccessedAt == 0 && !cited[key] {
+			continue
+		}
+		source.Claims = claims[key]
+		sources = append(sources, source)
+	}
+	return sources
+}
+
+// answerClaims maps the sources linked by the answer, inline or with [n] references, to the
+// sentences that link them. A sentence that is only a link, like an entry of a source list,
+// cites its source without being a claim.
+func answerClaims(answer string) (map[string][]string, map[string]bool) {
+	var (
+		claims     = map[string][]string{}
+		cited      = map[string]bool{}
+		references = map[string]string{}
+		lines      []string
+	)
+	for _, line := range strings.Split(answer, "\n") {
+		if m := referenceDefPattern.FindStringSubmatch(line); m != nil {
+			references[m[1]] = m[2]
+			cited[sourceKey(m[2])] = true
+			continue
+		}
+		// an entry of a numbered source list, like "1. [Title](url)", defines the reference [1]
+		if m := numberedSourcePattern.FindStringSubmatch(line); m != nil && len(strings.Fields(m[4])) < minClaimWords {
+			references[m[1]] = m[2] + m[3]
+			cited[sourceKey(m[2]+m[3])] = true
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	for _, line := range lines {
+		for _, sentence := range splitSentences(line) {
+			var urls []string
+			for _, m := range markdownLinkPattern.FindAllStringSubmatch(sentence, -1) {
+				urls = append(urls, m[2])
+			}
+			withoutLinks := markdownLinkPattern.ReplaceAllString(sentence, "")
+			for _, u := range bareURLPattern.FindAllString(withoutLinks, -1) {
+				urls = append(urls, strings.TrimRight(u, ".,;:!?"))
+			}
+			for _, m := range referenceUsePattern.FindAllStringSubmatch(withoutLinks, -1) {
+				if u, ok := references[m[1]]; ok {
+					urls = append(urls, u)
+				}
+			}
+			if len(urls) == 0 {
+				continue
+			}
+
+			claim := ""
+			if rest := cleanClaim(withoutLinks, references); len(strings.Fields(rest)) >= minClaimWords {
+				claim = cleanClaim(markdownLinkPattern.ReplaceAllString(sentence, "$1"), references)
+				if runes := []rune(claim); len(runes) > maxSourceClaimLength {
+					claim = string(runes[:maxSourceClaimLength]) + "..."
+				}
+			}
+			for _, u := range urls {
+				key := sourceKey(u)
+				cited[key] = true
+				if claim != "" && len(claims[key]) < maxSourceClaims && !contains(claims[key], claim) {
+					claims[key] = append(claims[key], claim)
+				}
+			}
+		}
+	}
+	return claims, cited
+}
+
+func splitSentences(line string) []string {
+	var (
+		sentences []string
+		start     int
+	)
+	for _, loc := range sentenceEndPattern.FindAllStringIndex(line, -1) {
+		sentences = append(sentences, line[start:loc[1]])
+		start = loc[1]
+	}
+	return append(sentences, line[start:])
+}
+
+// cleanClaim drops the URLs, the [n] references and the list markers of a sentence.
+func cleanClaim(sentence string, references map[string]string) string {
+	sentence = bareURLPattern.ReplaceAllStringFunc(sentence, func(u string) string {
+		// keep the punctuation the sentence ends with
+		return u[len(strings.TrimRight(u, ".,;:!?")):]
+	})
+	for label := range references {
+		sentence = strings.ReplaceAll(sentence, "["+label+"]", "")
+	}
+	sentence = claimLeftoverPattern.ReplaceAllString(sentence, "")
+	sentence = listMarkerPattern.ReplaceAllString(sentence, "")
+	sentence = claimSpacePattern.ReplaceAllString(sentence, " ")
+	sentence = strings.ReplaceAll(sentence, " .", ".")
+	sentence = strings.ReplaceAll(sentence, " ,", ",")
+	return strings.Trim(strings.TrimSpace(sentence), "*_")
+}
+
+// sourceKey matches the URLs of one page: the host is lowercased, the fragment and a trailing
+// slash are dropped.
+func sourceKey(rawURL string) string {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || u.Host == "" {
+		return strings.TrimSpace(rawURL)
+	}
+	u.Host = strings.ToLower(u.Host)
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Fragment = ""
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	return u.String()
+}
@@ -0,0 +1,106 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package agentic
+
+import (
+	"strings"
+	"testing"
+
+	fridaytools "github.com/basenana/friday/core/tools"
+	"github.com/basenana/plugin/logger"
+)
+
+func TestWebCitations_Sources(t *testing.T) {
+	wc := newWebCitations(t.TempDir())
+	wc.searched([]WebSearchItem{
+		{Title: "Solar Outlook 2024", URL: "https://iea.example.org/solar/"},
+		{Title: "Module prices", URL: "https://prices.example.com/modules"},
+		{Title: "Never cited", URL: "https://other.example.com/"},
+		{Title: "Grid study", URL: "https://grid.example.net/study"},
+	})
+	wc.accessed("https://iea.example.org/solar", "")
+	wc.accessed("https://blog.example.com/post", "Installer blog")
+
+	answer := strings.Join([]string{
+		"# Solar market",
+		"",
+		"Global installations grew 30% in 2024 ([IEA](https://IEA.example.org/solar#growth)). Module prices fell below $0.15/W [1].",
+		"- Grid connection queues are the main bottleneck, see https://grid.example.net/study.",
+		"",
+		"## Sources",
+		"1. [Module prices](https://prices.example.com/modules)",
+		"- [Solar Outlook 2024](https://iea.example.org/solar)",
+	}, "\n")
+
+	sources := wc.Sources(answer)
+	got := map[string][]string{}
+	var urls []string
+	for _, s := range sources {
+		urls = append(urls, s.URL)
+		got[s.URL] = s.Claims
+	}
+	if strings.Join(urls, ",") != "https://iea.example.org/solar/,https://prices.example.com/modules,https://grid.example.net/study,https://blog.example.com/post" {
+		t.Fatalf("unexpected sources %v", urls)
+	}
+
+	if claims := got["https://iea.example.org/solar/"]; len(claims) != 1 || claims[0] != "Global installations grew 30% in 2024 (IEA)." {
+		t.Errorf("unexpected claims of the IEA source %q", claims)
+	}
+	if claims := got["https://prices.example.com/modules"]; len(claims) != 1 || claims[0] != "Module prices fell below $0.15/W." {
+		t.Errorf("unexpected claims of the referenced source %q", claims)
+	}
+	if claims := got["https://grid.example.net/study"]; len(claims) != 1 || claims[0] != "Grid connection queues are the main bottleneck, see." {
+		t.Errorf("unexpected claims of the bare link %q", claims)
+	}
+	if claims := got["https://blog.example.com/post"]; len(claims) != 0 {
+		t.Errorf("expected no claims for the uncited page, got %q", claims)
+	}
+
+	for _, s := range sources {
+		switch s.URL {
+		case "https://iea.example.org/solar/":
+			if s.Title != "Solar Outlook 2024" || s.AccessedAt == 0 {
+				t.Errorf("expected the search title and the access time, got %+v", s)
+			}
+		case "https://blog.example.com/post":
+			if s.Title != "Installer blog" || s.AccessedAt == 0 {
+				t.Errorf("unexpected read page %+v", s)
+			}
+		case "https://prices.example.com/modules":
+			if s.AccessedAt != 0 {
+				t.Errorf("expected a cited search result without access time, got %+v", s)
+			}
+		}
+	}
+}
+
+func TestWebFetchTool_RecordsSource(t *testing.T) {
+	server := newWebFetchServer(t)
+	orig := webFetchPrivateNet
+	webFetchPrivateNet = true
+	t.Cleanup(func() { webFetchPrivateNet = orig })
+
+	wc := newWebCitations(t.TempDir())
+	result, err := NewWebFetchTool(wc, logger.NewLogger("test")).Handler(t.Context(), &fridaytools.Request{Arguments: map[string]any{"url": server.URL + "/article"}})
+	if err != nil || result.IsError {
+		t.Fatalf("unexpected error %v %q", err, getResultText(result))
+	}
+	sources := wc.Sources("")
+	if len(sources) != 1 || sources[0].URL != server.URL+"/article" || sources[0].Title != "Gophers" || sources[0].AccessedAt == 0 {
+		t.Errorf("unexpected sources %+v", sources)
+	}
+}
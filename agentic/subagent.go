@@ -0,0 +1,245 @@
+package agentic
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/basenana/friday/core/agents/react"
+	"github.com/basenana/friday/core/agents/summarize"
+	fridayapi "github.com/basenana/friday/core/api"
+	"github.com/basenana/friday/core/memory"
+	"github.com/basenana/friday/core/providers/openai"
+	fridaytools "github.com/basenana/friday/core/tools"
+	"github.com/basenana/plugin/utils"
+	"go.uber.org/zap"
+)
+
+const (
+	ConfigAgentTools    = "friday_agent_tools"
+	ConfigAgentMaxDepth = "friday_agent_max_depth"
+
+	AgentToolSummarize = "summarize"
+	AgentToolExtract   = "extract"
+	AgentToolReact     = "react"
+
+	defaultAgentMaxDepth = 1
+	maxAgentMaxDepth     = 3
+	maxAgentToolInput    = 100000
+)
+
+// AgentToolNames are the agents research can call as tools, named agent_<name>.
+var AgentToolNames = []string{AgentToolSummarize, AgentToolExtract, AgentToolReact}
+
+// AgentToolOption enables the Agents as tools. MaxDepth is how many agents can be nested below
+// the agent of the plugin: with 1 the react agent gets no agent tools of its own.
+type AgentToolOption struct {
+	Agents   []string
+	MaxDepth int
+}
+
+// ParseAgentToolOption reads friday_agent_tools and friday_agent_max_depth, it returns nil when
+// no agent is enabled.
+func ParseAgentToolOption(config map[string]string) (*AgentToolOption, error) {
+	var agents []string
+	for _, name := range strings.Split(config[ConfigAgentTools], ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !contains(AgentToolNames, name) {
+			return nil, fmt.Errorf("invalid %s: %s is not one of %s", ConfigAgentTools, name, strings.Join(AgentToolNames, ", "))
+		}
+		agents = append(agents, name)
+	}
+	if len(agents) == 0 {
+		return nil, nil
+	}
+
+	opt := &AgentToolOption{Agents: agents, MaxDepth: defaultAgentMaxDepth}
+	if raw := config[ConfigAgentMaxDepth]; raw != "" {
+		depth, err := strconv.Atoi(raw)
+		if err != nil || depth < 1 || depth > maxAgentMaxDepth {
+			return nil, fmt.Errorf("invalid %s [%s]: expect 1 to %d", ConfigAgentMaxDepth, raw, maxAgentMaxDepth)
+		}
+		opt.MaxDepth = depth
+	}
+	return opt, nil
+}
+
+// subAgents builds the agent tools. The sub-agents use the LLM of the caller, so its budget,
+// run limits and retries apply to them; wrap is applied to the tools of the react agent.
+type subAgents struct {
+	opt        AgentToolOption
+	jobID      string
+	fileAccess *utils.FileAccess
+	llm        openai.Client
+	wrap       func([]*fridaytools.Tool) []*fridaytools.Tool
+	logger     *zap.SugaredLogger
+}
+
+// AgentTools returns the tools of the agents enabled by friday_agent_tools.
+func AgentTools(config map[string]string, jobID, workingPath string, llm openai.Client, wrap func([]*fridaytools.Tool) []*fridaytools.Tool, toolLogger *zap.SugaredLogger) ([]*fridaytools.Tool, error) {
+	opt, err := ParseAgentToolOption(config)
+	if err != nil || opt == nil {
+		return nil, err
+	}
+	if wrap == nil {
+		wrap = func(tools []*fridaytools.Tool) []*fridaytools.Tool { return tools }
+	}
+	toolLogger.Infow("agent tools added", "agents", opt.Agents, "max_depth", opt.MaxDepth)
+	s := &subAgents{opt: *opt, jobID: jobID, fileAccess: utils.NewFileAccess(workingPath), llm: llm, wrap: wrap, logger: toolLogger}
+	return s.tools(0), nil
+}
+
+// tools returns the agent tools of an agent at depth, none once the agents it calls would
+// be nested deeper than MaxDepth.
+func (s *subAgents) tools(depth int) []*fridaytools.Tool {
+	if depth >= s.opt.MaxDepth {
+		return nil
+	}
+	var tools []*fridaytools.Tool
+	for _, name := range s.opt.Agents {
+		switch name {
+		case AgentToolSummarize:
+			tools = append(tools, s.summarizeTool())
+		case AgentToolExtract:
+			tools = append(tools, s.extractTool())
+		case AgentToolReact:
+			tools = append(tools, s.reactTool(depth+1))
+		}
+	}
+	return tools
+}
+
+func (s *subAgents) summarizeTool() *fridaytools.Tool {
+	return fridaytools.NewTool(
+		"agent_summarize",
+		fridaytools.WithDescription("Hand a document in working directory, or a text, to the summary agent and return its summary. Use it to condense long sources or your notes instead of reading them in full."),
+		fridaytools.WithString("file_path",
+			fridaytools.Description("Relative path to a document (PDF, text, Markdown, HTML, EPUB, notebook, LaTeX) within working directory, exclusive with text"),
+		),
+		fridaytools.WithString("text",
+			fridaytools.Description("Text to summarize, exclusive with file_path"),
+		),
+		fridaytools.WithString("instruction",
+			fridaytools.Description("What the summary should focus on, e.g. the figures about revenue"),
+		),
+		fridaytools.WithToolHandler(func(ctx context.Context, request *fridaytools.Request) (*fridaytools.Result, error) {
+			content, errResult := s.agentInput(ctx, "agent_summarize", request)
+			if errResult != nil {
+				return errResult, nil
+			}
+			if instruction, _ := request.Arguments["instruction"].(string); instruction != "" {
+				content = fmt.Sprintf("Focus of the summary: %s\n\n%s", instruction, content)
+			}
+
+			s.logger.Infow("agent_summarize started", "input_len", len(content))
+			agent := summarize.New("summary", "Summary Agent", s.llm, summarize.Option{})
+			summary, err := fridayapi.ReadAllContent(ctx, agent.Chat(ctx, &fridayapi.Request{
+				Session:     NewSession(s.jobID),
+				Memory:      memory.NewEmpty(s.jobID),
+				UserMessage: content,
+			}))
+			if err != nil {
+				s.logger.Warnw("agent_summarize failed", "error", err)
+				return fridaytools.NewToolResultError(fmt.Sprintf("summary agent failed: %s", err)), nil
+			}
+			s.logger.Infow("agent_summarize completed", "result_len", len(summary))
+			return fridaytools.NewToolResultText(strings.TrimSpace(summary)), nil
+		}),
+	)
+}
+
+func (s *subAgents) extractTool() *fridaytools.Tool {
+	return fridaytools.NewTool(
+		"agent_extract",
+		fridaytools.WithDescription("Hand a document in working directory, or a text, to the extraction agent and return the JSON value it extracted, checked against the given JSON Schema."),
+		fridaytools.WithString("schema",
+			fridaytools.Required(),
+			fridaytools.Description(`JSON Schema of the result, e.g. {"type":"object","properties":{"total":{"type":"number"}},"required":["total"]}`),
+		),
+		fridaytools.WithString("file_path",
+			fridaytools.Description("Relative path to a document within working directory, exclusive with text"),
+		),
+		fridaytools.WithString("text",
+			fridaytools.Description("Text to extract from, exclusive with file_path"),
+		),
+		fridaytools.WithToolHandler(func(ctx context.Context, request *fridaytools.Request) (*fridaytools.Result, error) {
+			rawSchema, _ := request.Arguments["schema"].(string)
+			schema, err := parseSchema(rawSchema)
+			if err != nil {
+				s.logger.Warnw("agent_extract invalid schema", "error", err)
+				return fridaytools.NewToolResultError(err.Error()), nil
+			}
+			content, errResult := s.agentInput(ctx, "agent_extract", request)
+			if errResult != nil {
+				return errResult, nil
+			}
+
+			s.logger.Infow("agent_extract started", "input_len", len(content))
+			result, attempts, err := extractJSON(ctx, s.llm, s.logger, fmt.Sprintf(extractPrompt, compactJSON(schema)), content, schema, defaultExtractRetries)
+			if err != nil {
+				s.logger.Warnw("agent_extract failed", "attempts", attempts, "error", err)
+				return fridaytools.NewToolResultError(fmt.Sprintf("extraction agent failed: %s", err)), nil
+			}
+			s.logger.Infow("agent_extract completed", "attempts", attempts)
+			return fridaytools.NewToolResultText(compactJSON(result)), nil
+		}),
+	)
+}
+
+func (s *subAgents) reactTool(depth int) *fridaytools.Tool {
+	return fridaytools.NewTool(
+		"agent_react",
+		fridaytools.WithDescription("Hand a self-contained task to an agent that can read, write and search the files in working directory, and return its answer. Describe the task fully, the agent does not see this conversation."),
+		fridaytools.WithString("task",
+			fridaytools.Required(),
+			fridaytools.Description("The task, with every file name and detail the agent needs"),
+		),
+		fridaytools.WithToolHandler(func(ctx context.Context, request *fridaytools.Request) (*fridaytools.Result, error) {
+			task, _ := request.Arguments["task"].(string)
+			if strings.TrimSpace(task) == "" {
+				s.logger.Warnw("missing required parameter: task")
+				return fridaytools.NewToolResultError("missing required parameter: task"), nil
+			}
+
+			s.logger.Infow("agent_react started", "depth", depth, "task_len", len(task))
+			tools := append(FileAccessTools(s.fileAccess, s.logger), s.tools(depth)...)
+			agent := react.New("react", "ReAct Agent with file access", s.llm, react.Option{Tools: s.wrap(tools)})
+			answer, err := fridayapi.ReadAllContent(ctx, agent.Chat(ctx, &fridayapi.Request{
+				Session:     NewSession(s.jobID),
+				Memory:      memory.NewEmpty(s.jobID),
+				UserMessage: task,
+			}))
+			if err != nil {
+				s.logger.Warnw("agent_react failed", "depth", depth, "error", err)
+				return fridaytools.NewToolResultError(fmt.Sprintf("react agent failed: %s", err)), nil
+			}
+			s.logger.Infow("agent_react completed", "depth", depth, "result_len", len(answer))
+			return fridaytools.NewToolResultText(strings.TrimSpace(answer)), nil
+		}),
+	)
+}
+
+// agentInput returns the document of file_path or the text argument, cut at maxAgentToolInput.
+func (s *subAgents) agentInput(ctx context.Context, tool string, request *fridaytools.Request) (string, *fridaytools.Result) {
+	filePath, _ := request.Arguments["file_path"].(string)
+	text, _ := request.Arguments["text"].(string)
+	if (filePath == "") == (text == "") {
+		s.logger.Warnw(tool + " requires either file_path or text")
+		return "", fridaytools.NewToolResultError("either file_path or text is required")
+	}
+	if filePath != "" {
+		var err error
+		if text, err = loadDocument(ctx, s.fileAccess, s.logger, filePath); err != nil {
+			s.logger.Warnw(tool+" load file failed", "path", filePath, "error", err)
+			return "", fridaytools.NewToolResultError(err.Error())
+		}
+	}
+	if len([]rune(text)) > maxAgentToolInput {
+		text = string([]rune(text)[:maxAgentToolInput])
+	}
+	return text, nil
+}
@@ -0,0 +1,187 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package agentic
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/basenana/friday/core/providers/openai"
+	fridaytools "github.com/basenana/friday/core/tools"
+	"go.uber.org/zap"
+)
+
+// finishingLLM streams reply and ends the topic of the agent, like a model closing its answer.
+type finishingLLM struct {
+	fakeLLM
+	mu       sync.Mutex
+	requests []openai.Request
+}
+
+func (f *finishingLLM) Completion(ctx context.Context, request openai.Request) openai.Response {
+	f.mu.Lock()
+	f.requests = append(f.requests, request)
+	f.mu.Unlock()
+	resp := &budgetResponse{stream: make(chan openai.Delta, 2), err: make(chan error)}
+	resp.stream <- openai.Delta{Content: f.reply}
+	resp.stream <- openai.Delta{ToolUse: []openai.ToolUse{{Name: "topic_finish_close", Arguments: "{}"}}}
+	close(resp.stream)
+	close(resp.err)
+	return resp
+}
+
+func (f *finishingLLM) userMessages() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var messages []string
+	for _, request := range f.requests {
+		for _, msg := range request.History() {
+			messages = append(messages, msg.UserMessage)
+		}
+	}
+	return strings.Join(messages, "\n")
+}
+
+func toolNames(tools []*fridaytools.Tool) []string {
+	names := make([]string, 0, len(tools))
+	for _, tool := range tools {
+		names = append(names, tool.Name)
+	}
+	return names
+}
+
+func TestParseAgentToolOption(t *testing.T) {
+	opt, err := ParseAgentToolOption(map[string]string{})
+	if err != nil || opt != nil {
+		t.Fatalf("expect no agent tools without config, got %v, %v", opt, err)
+	}
+	opt, err = ParseAgentToolOption(map[string]string{ConfigAgentTools: "summarize, react"})
+	if err != nil || strings.Join(opt.Agents, ",") != "summarize,react" || opt.MaxDepth != defaultAgentMaxDepth {
+		t.Fatalf("unexpected option %+v, %v", opt, err)
+	}
+	for _, config := range []map[string]string{
+		{ConfigAgentTools: "summarize,planner"},
+		{ConfigAgentTools: "react", ConfigAgentMaxDepth: "0"},
+		{ConfigAgentTools: "react", ConfigAgentMaxDepth: "4"},
+	} {
+		if _, err = ParseAgentToolOption(config); err == nil {
+			t.Errorf("expect error for %v", config)
+		}
+	}
+}
+
+func TestAgentTools_Depth(t *testing.T) {
+	var (
+		llm     = &finishingLLM{fakeLLM: fakeLLM{reply: "done"}}
+		wrapped [][]string
+		wrap    = func(tools []*fridaytools.Tool) []*fridaytools.Tool {
+			wrapped = append(wrapped, toolNames(tools))
+			return tools
+		}
+	)
+	for _, tc := range []struct {
+		maxDepth   string
+		agentTools bool
+	}{
+		{maxDepth: "1", agentTools: false},
+		{maxDepth: "2", agentTools: true},
+	} {
+		wrapped = nil
+		config := map[string]string{ConfigAgentTools: "summarize,react", ConfigAgentMaxDepth: tc.maxDepth}
+		tools, err := AgentTools(config, "job-1", t.TempDir(), llm, wrap, zap.NewNop().Sugar())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if names := strings.Join(toolNames(tools), ","); names != "agent_summarize,agent_react" {
+			t.Fatalf("unexpected agent tools %s", names)
+		}
+
+		result, err := getToolByName(tools, "agent_react").Handler(context.Background(), &fridaytools.Request{Arguments: map[string]any{"task": "write notes.md"}})
+		if err != nil || result.IsError || getResultText(result) != "done" {
+			t.Fatalf("expect the react agent answer, got %v, %v", result, err)
+		}
+		if len(wrapped) != 1 {
+			t.Fatalf("expect the react agent tools wrapped once, got %v", wrapped)
+		}
+		names := strings.Join(wrapped[0], ",")
+		if !strings.Contains(names, "file_read") || strings.Contains(names, "agent_") != tc.agentTools {
+			t.Errorf("max depth %s: unexpected react agent tools %s", tc.maxDepth, names)
+		}
+	}
+}
+
+func TestAgentTools_Summarize(t *testing.T) {
+	var (
+		workdir = t.TempDir()
+		llm     = &finishingLLM{fakeLLM: fakeLLM{reply: "revenue grew 10%"}}
+	)
+	if err := os.WriteFile(filepath.Join(workdir, "report.md"), []byte("# Q3\nRevenue grew by 10% to 1.1M."), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tools, err := AgentTools(map[string]string{ConfigAgentTools: "summarize"}, "job-1", workdir, llm, nil, zap.NewNop().Sugar())
+	if err != nil {
+		t.Fatal(err)
+	}
+	summarize := getToolByName(tools, "agent_summarize")
+
+	result, err := summarize.Handler(context.Background(), &fridaytools.Request{Arguments: map[string]any{"file_path": "report.md", "instruction": "revenue"}})
+	if err != nil || result.IsError || getResultText(result) != "revenue grew 10%" {
+		t.Fatalf("expect the summary, got %v, %v", result, err)
+	}
+	if messages := llm.userMessages(); !strings.Contains(messages, "Revenue grew by 10%") || !strings.Contains(messages, "Focus of the summary: revenue") {
+		t.Errorf("expect the document and the instruction sent, got %q", messages)
+	}
+
+	for _, args := range []map[string]any{
+		{},
+		{"file_path": "report.md", "text": "both"},
+		{"file_path": "missing.md"},
+	} {
+		if result, _ = summarize.Handler(context.Background(), &fridaytools.Request{Arguments: args}); !result.IsError {
+			t.Errorf("expect error for %v", args)
+		}
+	}
+}
+
+func TestAgentTools_Extract(t *testing.T) {
+	llm := &scriptedLLM{replies: []string{`{"name": "Ada"}`, `{"name": "Ada", "email": "ada@example.com"}`}}
+	tools, err := AgentTools(map[string]string{ConfigAgentTools: "extract"}, "job-1", t.TempDir(), llm, nil, zap.NewNop().Sugar())
+	if err != nil {
+		t.Fatal(err)
+	}
+	extract := getToolByName(tools, "agent_extract")
+
+	schema := `{"type": "object", "properties": {"name": {"type": "string"}, "email": {"type": "string"}}, "required": ["name", "email"]}`
+	result, err := extract.Handler(context.Background(), &fridaytools.Request{Arguments: map[string]any{"schema": schema, "text": "Ada, ada@example.com"}})
+	if err != nil || result.IsError {
+		t.Fatalf("expect the extracted value, got %v, %v", result, err)
+	}
+	if text := getResultText(result); text != `{"email":"ada@example.com","name":"Ada"}` {
+		t.Errorf("unexpected result %s", text)
+	}
+	if len(llm.requests) != 2 {
+		t.Errorf("expect a retry after the reply missing email, got %d calls", len(llm.requests))
+	}
+
+	if result, _ = extract.Handler(context.Background(), &fridaytools.Request{Arguments: map[string]any{"schema": "{", "text": "Ada"}}); !result.IsError {
+		t.Errorf("expect an invalid schema rejected")
+	}
+}
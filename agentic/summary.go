@@ -8,7 +8,6 @@ import (
 
 	"github.com/basenana/friday/core/agents/summarize"
 	fridayapi "github.com/basenana/friday/core/api"
-	"github.com/basenana/friday/core/memory"
 	"github.com/basenana/plugin/api"
 	"github.com/basenana/plugin/logger"
 	"github.com/basenana/plugin/types"
@@ -25,6 +24,7 @@ var SummaryPluginSpec = types.PluginSpec{
 	Name:           summaryPluginName,
 	Version:        summaryPluginVersion,
 	Type:           types.TypeProcess,
+	Dependencies:   []types.Dependency{{Kind: types.DependencyCapability, Name: types.CapabilityNetwork}},
 	RequiredConfig: LLMRequiredConfig(),
 	InitParameters: []types.ParameterSpec{
 		{
@@ -39,12 +39,53 @@ var SummaryPluginSpec = types.PluginSpec{
 			Required:    true,
 			Description: "Path to file to summarize",
 		},
+		{
+			Name:        "session",
+			Required:    false,
+			Description: "Session name, steps of the job with the same session continue its conversation",
+		},
+		{
+			Name:        "system_prompt_file",
+			Required:    false,
+			Description: "File in working directory holding the system prompt, exclusive with system_prompt and prompt_template",
+		},
+		{
+			Name:        "prompt_template",
+			Required:    false,
+			Description: "Name of a system prompt template in prompts/ of working directory, read from <name>.md or <name>.txt",
+		},
+		{
+			Name:        "prompt_vars",
+			Required:    false,
+			Description: "JSON object of the values of the {{name}} placeholders in the system prompt",
+		},
+		{
+			Name:        "model",
+			Required:    false,
+			Description: "Model of this call, overrides friday_llm_model",
+		},
+		{
+			Name:        "temperature",
+			Required:    false,
+			Description: "Sampling temperature of this call, 0 to 2, overrides friday_llm_temperature",
+		},
+		{
+			Name:        "max_tokens",
+			Required:    false,
+			Description: "Most LLM tokens of this run, the run stops with a partial result when exceeded",
+		},
+		{
+			Name:        "max_duration",
+			Required:    false,
+			Description: "Most wall time of this run, e.g. 10m, the run stops with a partial result when exceeded",
+		},
 	},
 }
 
 type SummaryPlugin struct {
 	logger     *zap.SugaredLogger
 	fileAccess *utils.FileAccess
+	jobPath    string
 	jobID      string
 	config     map[string]string
 }
@@ -79,10 +120,40 @@ func (p *SummaryPlugin) Run(ctx context.Context, request *api.Request) (*api.Res
 	}
 
 	message := doc.Content
-	systemPrompt := api.GetStringParameter("system_prompt", request, "")
+	systemPrompt, err := LoadSystemPrompt(request, p.fileAccess)
+	if err != nil {
+		p.logger.Warnw("load system prompt failed", "error", err)
+		return api.NewFailedResponse(err.Error()), nil
+	}
 	p.logger.Infow("summary plugin started", "message_len", len(message), "has_system_prompt", systemPrompt != "")
 
-	llm, err := NewLLMClient(p.config)
+	config, err := CallLLMConfig(p.config, request)
+	if err != nil {
+		p.logger.Warnw("invalid llm parameters", "error", err)
+		return api.NewFailedResponse(err.Error()), nil
+	}
+
+	session, err := OpenSession(ctx, request, NewSessionStore(p.jobID, request.Store, p.jobPath))
+	if err != nil {
+		p.logger.Warnw("open session failed", "error", err)
+		return api.NewFailedResponse(err.Error()), nil
+	}
+
+	limits, err := NewRunLimits(request)
+	if err != nil {
+		p.logger.Warnw("invalid run limits", "error", err)
+		return api.NewFailedResponse(err.Error()), nil
+	}
+
+	budget, err := NewLLMBudget(p.jobID, config, request.Store)
+	if err != nil {
+		p.logger.Warnw("parse LLM budget failed", "error", err)
+		return api.NewFailedResponse(err.Error()), nil
+	}
+	ctx = WithLLMBudget(ctx, budget)
+	ctx, llmRetries := WithLLMRetries(ctx)
+
+	llm, err := NewLLMClient(config)
 	if err != nil {
 		p.logger.Warnw("create LLM client failed", "error", err)
 		return api.NewFailedResponse(err.Error()), nil
@@ -92,30 +163,62 @@ func (p *SummaryPlugin) Run(ctx context.Context, request *api.Request) (*api.Res
 		SystemPrompt: systemPrompt,
 	})
 
-	resp := agent.Chat(ctx, &fridayapi.Request{
+	runCtx, cancelRun := limits.Start(ctx)
+	defer cancelRun()
+	mem := session.Memory(p.jobID)
+	prior := mem.History()
+	resp := agent.Chat(runCtx, &fridayapi.Request{
 		Session:     NewSession(p.jobID),
-		Memory:      memory.NewEmpty(p.jobID),
+		Memory:      mem,
 		UserMessage: message,
 	})
 
-	content, err := fridayapi.ReadAllContent(ctx, resp)
-	if err != nil {
+	content, err := fridayapi.ReadAllContent(runCtx, resp)
+	exceeded := limits.Exceeded()
+	if err != nil && exceeded == "" {
 		p.logger.Warnw("collect response failed", "error", err)
 		return api.NewFailedResponse(err.Error()), nil
 	}
+	if exceeded != "" {
+		p.logger.Warnw("summary stopped by run limit", "limit", exceeded, "error", err)
+		if strings.TrimSpace(content) == "" {
+			content = partialAnswer(mem, prior)
+		}
+	}
+
+	result := strings.TrimSpace(content)
+	if err = session.Record(ctx, "Summarize the file "+filePath, result); err != nil {
+		p.logger.Warnw("record session failed", "error", err)
+		return api.NewFailedResponse(err.Error()), nil
+	}
 
 	p.logger.Infow("summary plugin completed", "result_len", len(content))
-	return api.NewResponseWithResult(map[string]any{
+	results := map[string]any{
 		"file_path": filePath,
-		"result":    strings.TrimSpace(content),
-	}), nil
+		"result":    result,
+	}
+	if session != nil {
+		results["session"] = session.Name()
+	}
+	if limits != nil {
+		results["budget_exceeded"] = exceeded != ""
+		results["run_limits"] = limits.Result()
+	}
+	if budget != nil {
+		results["llm_budget"] = budget.Result(ctx)
+	}
+	if n := llmRetries.Count(); n > 0 {
+		results["llm_retries"] = n
+	}
+	return api.NewResponseWithResult(results), nil
 }
 
 func NewSummaryPlugin(ps types.PluginCall) types.Plugin {
 	return &SummaryPlugin{
 		logger:     logger.NewPluginLogger(summaryPluginName, ps.JobID),
-		fileAccess: utils.NewFileAccess(ps.WorkingPath),
+		fileAccess: utils.NewFileAccess(ps.WorkingPath, ps.JobWorkingPath),
+		jobPath:    jobWorkingPath(ps),
 		jobID:      ps.JobID,
-		config:     ps.Config,
+		config:     PluginLLMConfig(ps.Config, summaryPluginName),
 	}
 }
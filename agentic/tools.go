@@ -13,12 +13,13 @@ import (
 	"go.uber.org/zap"
 )
 
-func FileAccessTools(workdir string, toolLogger *zap.SugaredLogger) []*fridaytools.Tool {
-	fileAccess := utils.NewFileAccess(workdir)
+func FileAccessTools(fileAccess *utils.FileAccess, toolLogger *zap.SugaredLogger) []*fridaytools.Tool {
 	return []*fridaytools.Tool{
 		NewFileReadTool(fileAccess, toolLogger),
 		NewFileWriteTool(fileAccess, toolLogger),
+		NewFileEditTool(fileAccess, toolLogger),
 		NewFileListTool(fileAccess, toolLogger),
+		NewFileSearchTool(fileAccess, toolLogger),
 		NewFileParseTool(fileAccess, toolLogger),
 	}
 }
@@ -91,6 +92,81 @@ func NewFileWriteTool(fileAccess *utils.FileAccess, toolLogger *zap.SugaredLogge
 	)
 }
 
+func NewFileEditTool(fileAccess *utils.FileAccess, toolLogger *zap.SugaredLogger) *fridaytools.Tool {
+	return fridaytools.NewTool(
+		"file_edit",
+		fridaytools.WithDescription("Change part of a file in working directory with search-and-replace edits or a unified diff patch, instead of rewriting the whole file with file_write. Provide either edits or patch."),
+		fridaytools.WithString("path",
+			fridaytools.Required(),
+			fridaytools.Description("Relative path to file within working directory"),
+		),
+		fridaytools.WithArray("edits",
+			fridaytools.Items(map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"old_string":  map[string]interface{}{"type": "string", "description": "Exact text to replace, include enough surrounding text to make it unique"},
+					"new_string":  map[string]interface{}{"type": "string", "description": "Replacement text"},
+					"replace_all": map[string]interface{}{"type": "boolean", "description": "Replace every occurrence instead of requiring a unique match"},
+				},
+				"required": []string{"old_string", "new_string"},
+			}),
+			fridaytools.Description("Search-and-replace edits applied in order"),
+		),
+		fridaytools.WithString("patch",
+			fridaytools.Description("Unified diff of this file with @@ hunk headers; context and removed lines must match the file"),
+		),
+		fridaytools.WithToolHandler(func(ctx context.Context, request *fridaytools.Request) (*fridaytools.Result, error) {
+			path, ok := request.Arguments["path"].(string)
+			if !ok || path == "" {
+				toolLogger.Warnw("missing required parameter: path")
+				return fridaytools.NewToolResultError("missing required parameter: path"), nil
+			}
+
+			rawEdits, _ := request.Arguments["edits"].([]any)
+			patch, _ := request.Arguments["patch"].(string)
+			if (len(rawEdits) == 0) == (patch == "") {
+				toolLogger.Warnw("file_edit needs either edits or patch", "path", path)
+				return fridaytools.NewToolResultError("provide either edits or patch"), nil
+			}
+
+			toolLogger.Infow("file_edit started", "path", path, "edits", len(rawEdits), "patch_len", len(patch))
+
+			data, err := fileAccess.Read(path)
+			if err != nil {
+				toolLogger.Warnw("file_edit failed", "path", path, "error", err)
+				return fridaytools.NewToolResultError(err.Error()), nil
+			}
+
+			var (
+				content string
+				changes int
+			)
+			if patch != "" {
+				content, changes, err = applyUnifiedDiff(string(data), patch)
+			} else {
+				var edits []textEdit
+				if edits, err = parseTextEdits(rawEdits); err == nil {
+					content, err = applyTextEdits(string(data), edits)
+					changes = len(edits)
+				}
+			}
+			if err != nil {
+				// nothing is written when any edit fails
+				toolLogger.Warnw("file_edit failed", "path", path, "error", err)
+				return fridaytools.NewToolResultError(err.Error()), nil
+			}
+
+			if err = fileAccess.Write(path, []byte(content), 0644); err != nil {
+				toolLogger.Warnw("file_edit failed", "path", path, "error", err)
+				return fridaytools.NewToolResultError(err.Error()), nil
+			}
+
+			toolLogger.Infow("file_edit completed", "path", path, "changes", changes)
+			return fridaytools.NewToolResultText(fmt.Sprintf("file edited: %s, %d changes applied", path, changes)), nil
+		}),
+	)
+}
+
 func NewFileListTool(fileAccess *utils.FileAccess, toolLogger *zap.SugaredLogger) *fridaytools.Tool {
 	type fileInfo struct {
 		Name     string `json:"name"`
@@ -148,6 +224,85 @@ func NewFileListTool(fileAccess *utils.FileAccess, toolLogger *zap.SugaredLogger
 	)
 }
 
+func NewFileSearchTool(fileAccess *utils.FileAccess, toolLogger *zap.SugaredLogger) *fridaytools.Tool {
+	return fridaytools.NewTool(
+		"file_search",
+		fridaytools.WithDescription("Search the text files in working directory for a regular expression and return the matching lines with their line numbers. Use it to find a section before reading or editing a file."),
+		fridaytools.WithString("pattern",
+			fridaytools.Required(),
+			fridaytools.Description("Regular expression (RE2 syntax) to search for in each line"),
+		),
+		fridaytools.WithString("path",
+			fridaytools.Description("Relative path to the directory or file to search, default is root"),
+		),
+		fridaytools.WithString("glob",
+			fridaytools.Description("Only search files matching this glob, e.g. *.md; a glob with a slash matches the path relative to the searched directory"),
+		),
+		fridaytools.WithBoolean("ignore_case",
+			fridaytools.Description("Match case-insensitively, default: false"),
+		),
+		fridaytools.WithBoolean("literal",
+			fridaytools.Description("Treat the pattern as plain text instead of a regular expression, default: false"),
+		),
+		fridaytools.WithNumber("context",
+			fridaytools.Description("Lines of context to return around each match, default: 0"),
+			fridaytools.Min(0),
+			fridaytools.Max(maxSearchContext),
+		),
+		fridaytools.WithNumber("max_matches",
+			fridaytools.Description(fmt.Sprintf("Maximum matching lines to return, default: %d", defaultSearchMatches)),
+			fridaytools.Min(1),
+			fridaytools.Max(maxSearchMatches),
+		),
+		fridaytools.WithToolHandler(func(ctx context.Context, request *fridaytools.Request) (*fridaytools.Result, error) {
+			pattern, ok := request.Arguments["pattern"].(string)
+			if !ok || pattern == "" {
+				toolLogger.Warnw("missing required parameter: pattern")
+				return fridaytools.NewToolResultError("missing required parameter: pattern"), nil
+			}
+			path := "."
+			if p, ok := request.Arguments["path"].(string); ok && p != "" {
+				path = p
+			}
+			ignoreCase, _ := request.Arguments["ignore_case"].(bool)
+			literal, _ := request.Arguments["literal"].(bool)
+
+			opt := fileSearchOption{MaxMatches: defaultSearchMatches}
+			opt.Glob, _ = request.Arguments["glob"].(string)
+			if n, ok := request.Arguments["context"].(float64); ok && n > 0 {
+				opt.Context = min(int(n), maxSearchContext)
+			}
+			if n, ok := request.Arguments["max_matches"].(float64); ok && n >= 1 {
+				opt.MaxMatches = min(int(n), maxSearchMatches)
+			}
+
+			toolLogger.Infow("file_search started", "pattern", pattern, "path", path, "glob", opt.Glob)
+
+			var err error
+			if opt.Pattern, err = compileSearchPattern(pattern, ignoreCase, literal); err != nil {
+				toolLogger.Warnw("invalid search pattern", "pattern", pattern, "error", err)
+				return fridaytools.NewToolResultError(err.Error()), nil
+			}
+
+			absPath, err := fileAccess.GetAbsPath(path)
+			if err != nil {
+				toolLogger.Warnw("invalid path", "path", path, "error", err)
+				return fridaytools.NewToolResultError(fmt.Sprintf("invalid path: %s", err.Error())), nil
+			}
+
+			result, err := searchFiles(absPath, opt)
+			if err != nil {
+				toolLogger.Warnw("file_search failed", "path", path, "error", err)
+				return fridaytools.NewToolResultError(err.Error()), nil
+			}
+
+			toolLogger.Infow("file_search completed", "path", path, "files", len(result.Files), "matches", result.Matches)
+			data, _ := json.Marshal(result)
+			return fridaytools.NewToolResultText(string(data)), nil
+		}),
+	)
+}
+
 func NewFileParseTool(fileAccess *utils.FileAccess, toolLogger *zap.SugaredLogger) *fridaytools.Tool {
 	return fridaytools.NewTool(
 		"file_parse",
@@ -19,6 +19,8 @@ package agentic
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -35,7 +37,7 @@ func init() {
 func newTools(t *testing.T) (*utils.FileAccess, []*fridaytools.Tool) {
 	workdir := t.TempDir()
 	fileAccess := utils.NewFileAccess(workdir)
-	tools := FileAccessTools(workdir, logger.NewLogger("test"))
+	tools := FileAccessTools(utils.NewFileAccess(workdir), logger.NewLogger("test"))
 	return fileAccess, tools
 }
 
@@ -211,6 +213,144 @@ func TestFileWriteTool_MissingContent(t *testing.T) {
 	}
 }
 
+// ============ File Edit Tests ============
+
+const editDocument = `# Notes
+
+## Gophers
+Gophers dig tunnels.
+They eat roots.
+
+## Mascot
+The Go mascot is a gopher.
+`
+
+func runFileEdit(t *testing.T, args map[string]any) (*utils.FileAccess, *fridaytools.Result) {
+	fa, tools := newTools(t)
+	tool := getToolByName(tools, "file_edit")
+	if tool == nil {
+		t.Fatal("file_edit tool not found")
+	}
+	if err := fa.Write("notes.md", []byte(editDocument), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	args["path"] = "notes.md"
+	result, err := tool.Handler(context.Background(), &fridaytools.Request{Arguments: args})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fa, result
+}
+
+func readEdited(t *testing.T, fa *utils.FileAccess) string {
+	data, err := fa.Read("notes.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestFileEditTool_SearchReplace(t *testing.T) {
+	fa, result := runFileEdit(t, map[string]any{
+		"edits": []any{
+			map[string]any{"old_string": "They eat roots.", "new_string": "They eat roots and tubers."},
+			map[string]any{"old_string": "gopher", "new_string": "Gopher", "replace_all": true},
+		},
+	})
+	if result.IsError {
+		t.Fatalf("expected success, got error: %s", getResultText(result))
+	}
+
+	expected := strings.NewReplacer("They eat roots.", "They eat roots and tubers.", "gopher", "Gopher").Replace(editDocument)
+	if got := readEdited(t, fa); got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+}
+
+func TestFileEditTool_AmbiguousEditWritesNothing(t *testing.T) {
+	_, result := runFileEdit(t, map[string]any{
+		"edits": []any{
+			map[string]any{"old_string": "# Notes", "new_string": "# Notes"},
+		},
+	})
+	if !result.IsError || !strings.Contains(getResultText(result), "same old_string and new_string") {
+		t.Errorf("expected no-op edit error, got %q", getResultText(result))
+	}
+
+	fa, result := runFileEdit(t, map[string]any{
+		"edits": []any{
+			map[string]any{"old_string": "They eat roots.", "new_string": "They eat bugs."},
+			map[string]any{"old_string": "##", "new_string": "###"},
+		},
+	})
+	if !result.IsError || !strings.Contains(getResultText(result), "edit 2: old_string found 2 times") {
+		t.Errorf("expected ambiguous edit error, got %q", getResultText(result))
+	}
+	if got := readEdited(t, fa); got != editDocument {
+		t.Errorf("expected file unchanged, got %q", got)
+	}
+}
+
+func TestFileEditTool_Patch(t *testing.T) {
+	// the line numbers are off by one, the hunks are placed by their text
+	patch := `--- a/notes.md
++++ b/notes.md
+@@ -3,3 +3,4 @@
+ ## Gophers
+ Gophers dig tunnels.
++Their tunnels have many chambers.
+ They eat roots.
+@@ -8,2 +9,2 @@
+ ## Mascot
+-The Go mascot is a gopher.
++The Go mascot is a gopher drawn by Renee French.
+`
+	fa, result := runFileEdit(t, map[string]any{"patch": patch})
+	if result.IsError {
+		t.Fatalf("expected success, got error: %s", getResultText(result))
+	}
+	if !strings.Contains(getResultText(result), "2 changes applied") {
+		t.Errorf("expected 2 hunks applied, got %q", getResultText(result))
+	}
+
+	expected := `# Notes
+
+## Gophers
+Gophers dig tunnels.
+Their tunnels have many chambers.
+They eat roots.
+
+## Mascot
+The Go mascot is a gopher drawn by Renee French.
+`
+	if got := readEdited(t, fa); got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+}
+
+func TestFileEditTool_PatchMismatch(t *testing.T) {
+	fa, result := runFileEdit(t, map[string]any{"patch": "@@ -4,1 +4,1 @@\n-Gophers fly.\n+Gophers swim.\n"})
+	if !result.IsError || !strings.Contains(getResultText(result), "hunk 1 (@@ -4) does not match") {
+		t.Errorf("expected mismatch error, got %q", getResultText(result))
+	}
+	if got := readEdited(t, fa); got != editDocument {
+		t.Errorf("expected file unchanged, got %q", got)
+	}
+}
+
+func TestFileEditTool_EditsOrPatch(t *testing.T) {
+	for _, args := range []map[string]any{
+		{},
+		{"patch": "@@ -1 +1 @@\n-# Notes\n+# Log\n", "edits": []any{map[string]any{"old_string": "a", "new_string": "b"}}},
+	} {
+		_, result := runFileEdit(t, args)
+		if !result.IsError || !strings.Contains(getResultText(result), "provide either edits or patch") {
+			t.Errorf("expected either edits or patch error, got %q", getResultText(result))
+		}
+	}
+}
+
 // ============ File List Tests ============
 
 func TestFileListTool_EmptyDirectory(t *testing.T) {
@@ -371,6 +511,111 @@ func TestFileListTool_InvalidPath(t *testing.T) {
 	}
 }
 
+// ============ File Search Tests ============
+
+func runFileSearch(t *testing.T, args map[string]any) fileSearchResult {
+	fa, tools := newTools(t)
+	tool := getToolByName(tools, "file_search")
+	if tool == nil {
+		t.Fatal("file_search tool not found")
+	}
+	files := map[string]string{
+		"notes.md":         "# Notes\nGophers dig tunnels.\nThey eat roots.\n\n## Mascot\nThe Go mascot is a gopher.\n",
+		"docs/guide.md":    "Install Go.\nWrite a gopher.\n",
+		"docs/data.txt":    "gopher count: 3\n",
+		"image.bin":        "gopher\x00\x01",
+		".cache/hidden.md": "gopher\n",
+	}
+	for name, content := range files {
+		if err := fa.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := fa.Write(name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	result, err := tool.Handler(context.Background(), &fridaytools.Request{Arguments: args})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.IsError {
+		t.Fatalf("expected success, got error: %s", getResultText(result))
+	}
+	var found fileSearchResult
+	if err = json.Unmarshal([]byte(getResultText(result)), &found); err != nil {
+		t.Fatal(err)
+	}
+	return found
+}
+
+func TestFileSearchTool_Pattern(t *testing.T) {
+	found := runFileSearch(t, map[string]any{"pattern": "gopher", "ignore_case": true})
+	if found.Matches != 4 || len(found.Files) != 3 {
+		t.Fatalf("expected 4 matches in 3 files, got %+v", found)
+	}
+	files := map[string][]searchLine{}
+	for _, f := range found.Files {
+		files[f.File] = f.Lines
+	}
+	want := []searchLine{{Line: 2, Text: "Gophers dig tunnels.", Match: true}, {Line: 6, Text: "The Go mascot is a gopher.", Match: true}}
+	if fmt.Sprint(files["notes.md"]) != fmt.Sprint(want) {
+		t.Errorf("expected %+v, got %+v", want, files["notes.md"])
+	}
+	if _, ok := files[".cache/hidden.md"]; ok {
+		t.Error("expected hidden directories to be skipped")
+	}
+	if _, ok := files["image.bin"]; ok {
+		t.Error("expected binary files to be skipped")
+	}
+}
+
+func TestFileSearchTool_GlobAndContext(t *testing.T) {
+	found := runFileSearch(t, map[string]any{"pattern": "gopher", "glob": "*.md", "context": float64(1)})
+	if found.Matches != 2 || len(found.Files) != 2 {
+		t.Fatalf("expected 2 matches in markdown files, got %+v", found)
+	}
+	for _, f := range found.Files {
+		if f.File == "notes.md" {
+			want := []searchLine{{Line: 5, Text: "## Mascot"}, {Line: 6, Text: "The Go mascot is a gopher.", Match: true}}
+			if fmt.Sprint(f.Lines) != fmt.Sprint(want) {
+				t.Errorf("expected %+v, got %+v", want, f.Lines)
+			}
+		}
+	}
+
+	found = runFileSearch(t, map[string]any{"pattern": "gopher", "path": "docs", "glob": "*.txt"})
+	if found.Matches != 1 || found.Files[0].File != "data.txt" {
+		t.Errorf("expected docs/data.txt only, got %+v", found)
+	}
+}
+
+func TestFileSearchTool_LiteralAndLimit(t *testing.T) {
+	found := runFileSearch(t, map[string]any{"pattern": "count: 3", "literal": true})
+	if found.Matches != 1 {
+		t.Errorf("expected literal match, got %+v", found)
+	}
+
+	found = runFileSearch(t, map[string]any{"pattern": "o", "max_matches": float64(2)})
+	if found.Matches != 2 || !found.Truncated {
+		t.Errorf("expected 2 matches and truncated, got %+v", found)
+	}
+}
+
+func TestFileSearchTool_InvalidPattern(t *testing.T) {
+	_, tools := newTools(t)
+	tool := getToolByName(tools, "file_search")
+	for _, args := range []map[string]any{{}, {"pattern": "("}, {"pattern": "a", "path": "../etc"}} {
+		result, err := tool.Handler(context.Background(), &fridaytools.Request{Arguments: args})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !result.IsError {
+			t.Errorf("expected error for %v, got %s", args, getResultText(result))
+		}
+	}
+}
+
 // ============ File Parse Tests ============
 
 func TestFileParseTool_TextFile(t *testing.T) {
@@ -3,9 +3,12 @@ package agentic
 import (
 	"fmt"
 	"path/filepath"
+	"strconv"
+	"strings"
 
 	"github.com/basenana/friday/core/providers/openai"
 	"github.com/basenana/friday/core/types"
+	"github.com/basenana/plugin/api"
 	"github.com/basenana/plugin/docloader"
 )
 
@@ -13,6 +16,8 @@ const (
 	ConfigHost   = "friday_llm_host"
 	ConfigAPIKey = "friday_llm_api_key"
 	ConfigModel  = "friday_llm_model"
+	// ConfigTemperature is the sampling temperature, 0 to 2; the provider default is used when unset.
+	ConfigTemperature = "friday_llm_temperature"
 )
 
 func NewLLMClient(config map[string]string) (openai.Client, error) {
@@ -20,22 +25,64 @@ func NewLLMClient(config map[string]string) (openai.Client, error) {
 		return nil, fmt.Errorf("config is required")
 	}
 
-	host := config[ConfigHost]
-	if host == "" {
-		return nil, fmt.Errorf("friday_llm_host is required")
+	model := config[ConfigModel]
+	if model == "" {
+		return nil, fmt.Errorf("friday_llm_model is required")
 	}
 
-	apiKey := config[ConfigAPIKey]
-	if apiKey == "" {
-		return nil, fmt.Errorf("friday_llm_api_key is required")
+	host, apiKey, err := llmEndpoint(config)
+	if err != nil {
+		return nil, err
 	}
 
-	model := config[ConfigModel]
-	if model == "" {
-		return nil, fmt.Errorf("friday_llm_model is required")
+	llmModel := openai.Model{Name: model}
+	if raw := config[ConfigTemperature]; raw != "" {
+		temperature, err := parseTemperature(raw)
+		if err != nil {
+			return nil, err
+		}
+		llmModel.Temperature = &temperature
+	}
+	policy, err := parseRetryPolicy(config)
+	if err != nil {
+		return nil, err
 	}
+	return budgetClient{Client: retryClient{Client: openai.New(host, apiKey, llmModel), policy: policy}}, nil
+}
 
-	return openai.New(host, apiKey, openai.Model{Name: model}), nil
+// CallLLMConfig returns the config with the model and temperature of the request parameters, when given.
+func CallLLMConfig(config map[string]string, request *api.Request) (map[string]string, error) {
+	var (
+		model       = strings.TrimSpace(api.GetStringParameter("model", request, ""))
+		temperature = strings.TrimSpace(api.GetStringParameter("temperature", request, ""))
+	)
+	if model == "" && temperature == "" {
+		return config, nil
+	}
+	if temperature != "" {
+		if _, err := parseTemperature(temperature); err != nil {
+			return nil, err
+		}
+	}
+	callConfig := make(map[string]string, len(config)+2)
+	for k, v := range config {
+		callConfig[k] = v
+	}
+	if model != "" {
+		callConfig[ConfigModel] = model
+	}
+	if temperature != "" {
+		callConfig[ConfigTemperature] = temperature
+	}
+	return callConfig, nil
+}
+
+func parseTemperature(raw string) (float64, error) {
+	temperature, err := strconv.ParseFloat(raw, 64)
+	if err != nil || temperature < 0 || temperature > 2 {
+		return 0, fmt.Errorf("invalid temperature [%s]: expect 0 to 2", raw)
+	}
+	return temperature, nil
 }
 
 func NewSession(jobID string) *types.Session {
@@ -45,8 +92,9 @@ func NewSession(jobID string) *types.Session {
 	}
 }
 
+// LLMRequiredConfig lists the config every provider needs, the host and key depend on friday_llm_provider.
 func LLMRequiredConfig() []string {
-	return []string{ConfigHost, ConfigAPIKey, ConfigModel}
+	return []string{ConfigModel}
 }
 
 func newParser(docPath string) docloader.Parser {
@@ -62,6 +110,10 @@ func newParser(docPath string) docloader.Parser {
 		return docloader.NewHTML(docPath, nil)
 	case ".epub":
 		return docloader.NewEPUB(docPath, nil)
+	case ".ipynb":
+		return docloader.NewNotebook(docPath, nil)
+	case ".tex":
+		return docloader.NewLaTeX(docPath, nil)
 	default:
 		return nil
 	}
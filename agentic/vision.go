@@ -0,0 +1,117 @@
+package agentic
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/basenana/friday/core/providers/openai"
+	fridaytools "github.com/basenana/friday/core/tools"
+	fridaytypes "github.com/basenana/friday/core/types"
+	"github.com/basenana/plugin/utils"
+	"go.uber.org/zap"
+)
+
+const (
+	// ConfigVisionModel names a vision-capable model of the same host for image_describe,
+	// when friday_llm_model can not read images.
+	ConfigVisionModel = "friday_vision_model"
+
+	maxImageSize = 20 << 20
+	// imageTokens is charged for an image when the API reports no usage, its data URL is not text.
+	imageTokens = 1000
+
+	imageDescribePrompt = "You describe images for a research assistant that can not see them. Describe what the image shows, transcribe every piece of text in it verbatim, and explain charts, tables and figures including their values. Answer only from what is visible."
+)
+
+var imageMediaTypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// NewVisionLLMClient returns the client of image_describe, the LLM config with the model
+// replaced by friday_vision_model when set.
+func NewVisionLLMClient(config map[string]string) (openai.Client, error) {
+	visionModel := config[ConfigVisionModel]
+	if visionModel == "" {
+		return NewLLMClient(config)
+	}
+	visionConfig := make(map[string]string, len(config))
+	for k, v := range config {
+		visionConfig[k] = v
+	}
+	visionConfig[ConfigModel] = visionModel
+	return NewLLMClient(visionConfig)
+}
+
+func NewImageDescribeTool(fileAccess *utils.FileAccess, llm openai.Client, toolLogger *zap.SugaredLogger) *fridaytools.Tool {
+	return fridaytools.NewTool(
+		"image_describe",
+		fridaytools.WithDescription("Look at an image file (PNG, JPEG, GIF, WebP) in working directory, such as a screenshot, chart or scanned page, and return a description with the text it contains."),
+		fridaytools.WithString("path",
+			fridaytools.Required(),
+			fridaytools.Description("Relative path to image file within working directory"),
+		),
+		fridaytools.WithString("question",
+			fridaytools.Description("What you want to know about the image, default is a full description and transcription"),
+		),
+		fridaytools.WithToolHandler(func(ctx context.Context, request *fridaytools.Request) (*fridaytools.Result, error) {
+			path, ok := request.Arguments["path"].(string)
+			if !ok || path == "" {
+				toolLogger.Warnw("missing required parameter: path")
+				return fridaytools.NewToolResultError("missing required parameter: path"), nil
+			}
+			question, _ := request.Arguments["question"].(string)
+			if strings.TrimSpace(question) == "" {
+				question = "Describe this image and transcribe its text."
+			}
+
+			toolLogger.Infow("image_describe started", "path", path)
+
+			dataURL, err := imageDataURL(fileAccess, path)
+			if err != nil {
+				toolLogger.Warnw("image_describe failed", "path", path, "error", err)
+				return fridaytools.NewToolResultError(err.Error()), nil
+			}
+
+			reply, err := llm.CompletionNonStreaming(ctx, openai.NewSimpleRequest(imageDescribePrompt,
+				fridaytypes.Message{UserMessage: question},
+				fridaytypes.Message{ImageURL: dataURL},
+			))
+			if err != nil {
+				toolLogger.Warnw("image_describe failed", "path", path, "error", err)
+				return fridaytools.NewToolResultError(fmt.Sprintf("describe image failed, the model may not support images (see %s): %s", ConfigVisionModel, err)), nil
+			}
+
+			toolLogger.Infow("image_describe completed", "path", path, "result_len", len(reply))
+			return fridaytools.NewToolResultText(strings.TrimSpace(reply)), nil
+		}),
+	)
+}
+
+// imageDataURL reads a workdir image into a base64 data URL, the media type is sniffed from the content.
+func imageDataURL(fileAccess *utils.FileAccess, path string) (string, error) {
+	info, err := fileAccess.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if info.IsDir() {
+		return "", fmt.Errorf("%s is a directory", path)
+	}
+	if info.Size() > maxImageSize {
+		return "", fmt.Errorf("image %s is %s, larger than %s", path, formatSize(info.Size()), formatSize(maxImageSize))
+	}
+	data, err := fileAccess.Read(path)
+	if err != nil {
+		return "", err
+	}
+	mediaType := http.DetectContentType(data)
+	if !imageMediaTypes[mediaType] {
+		return "", fmt.Errorf("unsupported image type %s, expect PNG, JPEG, GIF or WebP", mediaType)
+	}
+	return "data:" + mediaType + ";base64," + base64.StdEncoding.EncodeToString(data), nil
+}
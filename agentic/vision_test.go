@@ -0,0 +1,121 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package agentic
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/basenana/friday/core/providers/openai"
+	fridaytools "github.com/basenana/friday/core/tools"
+	fridaytypes "github.com/basenana/friday/core/types"
+	"github.com/basenana/plugin/logger"
+	"github.com/basenana/plugin/utils"
+)
+
+// recordingLLM keeps the last non-streaming request.
+type recordingLLM struct {
+	fakeLLM
+	request openai.Request
+}
+
+func (r *recordingLLM) CompletionNonStreaming(ctx context.Context, request openai.Request) (string, error) {
+	r.request = request
+	return r.fakeLLM.CompletionNonStreaming(ctx, request)
+}
+
+func writePNG(t *testing.T, fa *utils.FileAccess, name string) {
+	buf := &bytes.Buffer{}
+	if err := png.Encode(buf, image.NewRGBA(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatal(err)
+	}
+	if err := fa.Write(name, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestImageDescribeTool(t *testing.T) {
+	fa := utils.NewFileAccess(t.TempDir())
+	writePNG(t, fa, "chart.png")
+	llm := &recordingLLM{fakeLLM: fakeLLM{reply: " A bar chart titled Sales. \n"}}
+
+	tool := NewImageDescribeTool(fa, llm, logger.NewLogger("test"))
+	result, err := tool.Handler(context.Background(), &fridaytools.Request{Arguments: map[string]any{"path": "chart.png", "question": "What is the title?"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.IsError || getResultText(result) != "A bar chart titled Sales." {
+		t.Fatalf("unexpected result %q", getResultText(result))
+	}
+
+	history := llm.request.History()
+	if len(history) != 3 || history[1].UserMessage != "What is the title?" {
+		t.Fatalf("unexpected request %+v", history)
+	}
+	if !strings.HasPrefix(history[2].ImageURL, "data:image/png;base64,") {
+		t.Errorf("expected png data url, got %.40s", history[2].ImageURL)
+	}
+}
+
+func TestImageDescribeTool_Rejected(t *testing.T) {
+	fa := utils.NewFileAccess(t.TempDir())
+	if err := fa.Write("notes.png", []byte("not an image"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	llm := &recordingLLM{}
+	tool := NewImageDescribeTool(fa, llm, logger.NewLogger("test"))
+
+	cases := map[string]string{
+		"notes.png":   "unsupported image type text/plain",
+		"missing.png": "no such file",
+		"../x.png":    "path traversal",
+	}
+	for path, want := range cases {
+		result, err := tool.Handler(context.Background(), &fridaytools.Request{Arguments: map[string]any{"path": path}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !result.IsError || !strings.Contains(getResultText(result), want) {
+			t.Errorf("%s: expected error %q, got %q", path, want, getResultText(result))
+		}
+	}
+	if llm.calls != 0 {
+		t.Errorf("expected no LLM call for rejected images, got %d", llm.calls)
+	}
+}
+
+func TestNewVisionLLMClient(t *testing.T) {
+	config := map[string]string{ConfigHost: "http://localhost", ConfigAPIKey: "key", ConfigModel: "text-model", ConfigVisionModel: "vision-model"}
+	if _, err := NewVisionLLMClient(config); err != nil {
+		t.Fatal(err)
+	}
+	if config[ConfigModel] != "text-model" {
+		t.Errorf("the plugin config should not change, got model %s", config[ConfigModel])
+	}
+}
+
+func TestUsedTokens_Image(t *testing.T) {
+	dataURL := "data:image/png;base64," + strings.Repeat("A", 100000)
+	req := openai.NewSimpleRequest("system", fridaytypes.Message{ImageURL: dataURL})
+	if tokens := usedTokens(openai.Tokens{}, req, fridaytypes.Message{}); tokens > imageTokens+100 {
+		t.Errorf("an image should be estimated as %d tokens, got %d", imageTokens, tokens)
+	}
+}
@@ -0,0 +1,153 @@
+package agentic
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"code.dny.dev/ssrf"
+	htmltomarkdown "github.com/JohannesKaufmann/html-to-markdown/v2"
+	"github.com/JohannesKaufmann/html-to-markdown/v2/converter"
+	"github.com/basenana/friday/core/tools"
+	"github.com/basenana/plugin/utils"
+	"github.com/basenana/plugin/web"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultWebFetchLength = 20000
+	maxWebFetchLength     = 100000
+	maxWebFetchBodySize   = 5 << 20
+)
+
+// webFetchPrivateNet follows the WebPackerEnablePrivateNet env of the web package.
+var webFetchPrivateNet = os.Getenv("WebPackerEnablePrivateNet") == "true"
+
+// NewWebFetchTool records the pages it reads as sources of wc, which may be nil.
+func NewWebFetchTool(wc *WebCitations, toolLogger *zap.SugaredLogger) *tools.Tool {
+	return tools.NewTool(
+		"web_fetch",
+		tools.WithDescription("Download a webpage and return its main content as clean text, without saving a file. Use it to read search results."),
+		tools.WithString("url",
+			tools.Required(),
+			tools.Description("The exact url address you want to read, Do not make up addresses."),
+		),
+		tools.WithNumber("max_length",
+			tools.Description(fmt.Sprintf("Maximum characters of text to return, default: %d", defaultWebFetchLength)),
+			tools.Min(1),
+			tools.Max(maxWebFetchLength),
+		),
+		tools.WithToolHandler(webFetchHandler(wc, toolLogger)),
+	)
+}
+
+func webFetchHandler(wc *WebCitations, toolLogger *zap.SugaredLogger) func(ctx context.Context, request *tools.Request) (*tools.Result, error) {
+	return func(ctx context.Context, request *tools.Request) (*tools.Result, error) {
+		rawURL, ok := request.Arguments["url"].(string)
+		if !ok || rawURL == "" {
+			toolLogger.Warnw("missing required parameter: url")
+			return tools.NewToolResultError("missing required parameter: url"), nil
+		}
+		maxLength := defaultWebFetchLength
+		if n, ok := request.Arguments["max_length"].(float64); ok && n >= 1 {
+			maxLength = min(int(n), maxWebFetchLength)
+		}
+
+		toolLogger.Infow("web_fetch started", "url", rawURL, "max_length", maxLength)
+
+		text, title, err := fetchReadableText(ctx, rawURL)
+		if err != nil {
+			toolLogger.Warnw("web_fetch failed", "url", rawURL, "error", err)
+			return tools.NewToolResultError(err.Error()), nil
+		}
+		wc.accessed(rawURL, title)
+
+		length := utf8.RuneCountInString(text)
+		if length > maxLength {
+			text = string([]rune(text)[:maxLength]) + fmt.Sprintf("\n\n[truncated: %d of %d characters]", maxLength, length)
+		}
+
+		toolLogger.Infow("web_fetch completed", "url", rawURL, "length", length)
+		return tools.NewToolResultText(text), nil
+	}
+}
+
+// fetchReadableText downloads a page and returns its readable article as Markdown with its
+// title, plain text documents are returned as they are.
+func fetchReadableText(ctx context.Context, rawURL string) (string, string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return "", "", fmt.Errorf("invalid url %s: expect http or https", rawURL)
+	}
+	if err = web.WaitFetch(ctx, rawURL); err != nil {
+		return "", "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return "", "", err
+	}
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,text/plain;q=0.9,*/*;q=0.8")
+	req.Header.Set("User-Agent", browserUserAgent)
+
+	dialer := &net.Dialer{Timeout: 30 * time.Second}
+	if !webFetchPrivateNet {
+		dialer.Control = ssrf.New().Safe
+	}
+	cli := &http.Client{
+		Timeout:   time.Minute,
+		Transport: &http.Transport{Proxy: http.ProxyFromEnvironment, DialContext: dialer.DialContext},
+	}
+	resp, err := cli.Do(req)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", "", fmt.Errorf("fetch %s failed: %s", rawURL, resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxWebFetchBodySize+1))
+	if err != nil {
+		return "", "", err
+	}
+	if len(data) > maxWebFetchBodySize {
+		return "", "", fmt.Errorf("page %s is larger than %s", rawURL, formatSize(maxWebFetchBodySize))
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType == "" {
+		mediaType, _, _ = mime.ParseMediaType(http.DetectContentType(data))
+	}
+	switch {
+	case mediaType == "text/html" || mediaType == "application/xhtml+xml":
+	case strings.HasPrefix(mediaType, "text/") || mediaType == "application/json":
+		return strings.TrimSpace(string(data)), "", nil
+	default:
+		return "", "", fmt.Errorf("unsupported content type %s, use crawl_webpages to save the page and file_parse to read it", mediaType)
+	}
+
+	// the final url resolves the relative links of a redirected page
+	article, err := utils.ExtractArticle(bytes.NewReader(data), resp.Request.URL)
+	if err != nil {
+		return "", "", fmt.Errorf("extract article failed: %w", err)
+	}
+	body, err := htmltomarkdown.ConvertString(article.Content, converter.WithDomain(resp.Request.URL.String()))
+	if err != nil {
+		return "", "", fmt.Errorf("convert article failed: %w", err)
+	}
+	body = strings.TrimSpace(body)
+	if article.Title != "" {
+		body = "# " + article.Title + "\n\n" + body
+	}
+	return body, article.Title, nil
+}
@@ -0,0 +1,127 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package agentic
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	fridaytools "github.com/basenana/friday/core/tools"
+	"github.com/basenana/plugin/logger"
+)
+
+const webFetchArticle = `<html><head><title>Gophers</title></head><body>
+<nav><a href="/">Home</a> <a href="/about">About</a></nav>
+<article>
+<h1>Gophers</h1>
+<p>Gophers are small burrowing rodents that live in North and Central America. They spend most of their lives underground in tunnel systems.</p>
+<p>The Go mascot is a gopher drawn by Renee French, it appears in the documentation and on the <a href="/blog">blog</a> of the project.</p>
+<p>Gophers eat roots, tubers and other plant parts they find while digging, and they store food in their burrows for the winter.</p>
+</article>
+<footer>Copyright example.com</footer>
+</body></html>`
+
+func webFetch(t *testing.T, args map[string]any) *fridaytools.Result {
+	orig := webFetchPrivateNet
+	webFetchPrivateNet = true
+	t.Cleanup(func() { webFetchPrivateNet = orig })
+
+	result, err := NewWebFetchTool(nil, logger.NewLogger("test")).Handler(t.Context(), &fridaytools.Request{Arguments: args})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return result
+}
+
+func newWebFetchServer(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/article":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			_, _ = w.Write([]byte(webFetchArticle))
+		case "/notes.txt":
+			w.Header().Set("Content-Type", "text/plain")
+			_, _ = w.Write([]byte("  plain notes\n"))
+		case "/image.png":
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = w.Write([]byte{0x89, 'P', 'N', 'G'})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestWebFetchReadable(t *testing.T) {
+	server := newWebFetchServer(t)
+	result := webFetch(t, map[string]any{"url": server.URL + "/article"})
+	text := getResultText(result)
+	if result.IsError {
+		t.Fatalf("web_fetch failed: %s", text)
+	}
+	if !strings.HasPrefix(text, "# Gophers") || !strings.Contains(text, "burrowing rodents") {
+		t.Errorf("expected article text, got %q", text)
+	}
+	if strings.Contains(text, "Copyright") || strings.Contains(text, "<p>") {
+		t.Errorf("expected clean article without page chrome, got %q", text)
+	}
+	if !strings.Contains(text, "("+server.URL+"/blog)") {
+		t.Errorf("expected absolute links, got %q", text)
+	}
+}
+
+func TestWebFetchMaxLength(t *testing.T) {
+	server := newWebFetchServer(t)
+	text := getResultText(webFetch(t, map[string]any{"url": server.URL + "/article", "max_length": float64(20)}))
+	if !strings.Contains(text, "[truncated: 20 of ") || len([]rune(strings.SplitN(text, "\n\n[truncated", 2)[0])) != 20 {
+		t.Errorf("expected text cut at 20 characters, got %q", text)
+	}
+}
+
+func TestWebFetchContentTypes(t *testing.T) {
+	server := newWebFetchServer(t)
+	if text := getResultText(webFetch(t, map[string]any{"url": server.URL + "/notes.txt"})); text != "plain notes" {
+		t.Errorf("expected plain text, got %q", text)
+	}
+	cases := map[string]string{
+		server.URL + "/image.png": "unsupported content type image/png",
+		server.URL + "/missing":   "404",
+		"file:///etc/passwd":      "expect http or https",
+		"":                        "missing required parameter: url",
+	}
+	for rawURL, want := range cases {
+		result := webFetch(t, map[string]any{"url": rawURL})
+		if !result.IsError || !strings.Contains(getResultText(result), want) {
+			t.Errorf("%s: expected error %q, got %q", rawURL, want, getResultText(result))
+		}
+	}
+}
+
+func TestWebFetchPrivateNetBlocked(t *testing.T) {
+	server := newWebFetchServer(t)
+	orig := webFetchPrivateNet
+	webFetchPrivateNet = false
+	defer func() { webFetchPrivateNet = orig }()
+
+	result, _ := NewWebFetchTool(nil, logger.NewLogger("test")).Handler(t.Context(), &fridaytools.Request{Arguments: map[string]any{"url": server.URL + "/article"}})
+	if !result.IsError {
+		t.Errorf("expected loopback fetch to be refused, got %q", getResultText(result))
+	}
+}
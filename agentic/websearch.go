@@ -3,6 +3,9 @@ package agentic
 import (
 	"context"
 	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 	"os"
@@ -19,8 +22,35 @@ import (
 	"google.golang.org/api/option"
 )
 
+// browserUserAgent is sent by the tools that read pages meant for browsers.
+const browserUserAgent = "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.6 Safari/605.1.15"
+
 // NewPSEWebSearchTool https://programmablesearchengine.google.com/
 func NewPSEWebSearchTool(engineID, apiKey string, wc *WebCitations, toolLogger *zap.SugaredLogger) []*tools.Tool {
+	return webSearchTools(pseSearchHandler(toolLogger, wc, engineID, apiKey), wc, toolLogger)
+}
+
+// NewBingWebSearchTool https://learn.microsoft.com/en-us/bing/search-apis/bing-web-search/
+func NewBingWebSearchTool(apiKey string, wc *WebCitations, toolLogger *zap.SugaredLogger) []*tools.Tool {
+	return webSearchTools(searchHandler(toolLogger, wc, bingSearch(apiKey)), wc, toolLogger)
+}
+
+// NewBraveWebSearchTool https://brave.com/search/api/
+func NewBraveWebSearchTool(apiKey string, wc *WebCitations, toolLogger *zap.SugaredLogger) []*tools.Tool {
+	return webSearchTools(searchHandler(toolLogger, wc, braveSearch(apiKey)), wc, toolLogger)
+}
+
+// NewSearxNGWebSearchTool https://docs.searxng.org/dev/search_api.html
+func NewSearxNGWebSearchTool(baseURL string, wc *WebCitations, toolLogger *zap.SugaredLogger) []*tools.Tool {
+	return webSearchTools(searchHandler(toolLogger, wc, searxngSearch(baseURL)), wc, toolLogger)
+}
+
+// NewDuckDuckGoWebSearchTool https://duckduckgo.com/
+func NewDuckDuckGoWebSearchTool(wc *WebCitations, toolLogger *zap.SugaredLogger) []*tools.Tool {
+	return webSearchTools(searchHandler(toolLogger, wc, duckduckgoSearch()), wc, toolLogger)
+}
+
+func webSearchTools(searchHandler tools.ToolHandlerFunc, wc *WebCitations, toolLogger *zap.SugaredLogger) []*tools.Tool {
 	return []*tools.Tool{
 		tools.NewTool(
 			"crawl_webpages",
@@ -44,12 +74,13 @@ func NewPSEWebSearchTool(engineID, apiKey string, wc *WebCitations, toolLogger *
 				tools.Enum("day", "week", "month", "year", "anytime"),
 				tools.Description("The time range you want to search, (this) day/week/month/year, default: anytime"),
 			),
-			tools.WithToolHandler(pseSearchHandler(toolLogger, engineID, apiKey)),
+			tools.WithToolHandler(searchHandler),
 		),
+		NewWebFetchTool(wc, toolLogger),
 	}
 }
 
-func pseSearchHandler(toolLogger *zap.SugaredLogger, engineID, apiKey string) func(ctx context.Context, request *tools.Request) (*tools.Result, error) {
+func pseSearchHandler(toolLogger *zap.SugaredLogger, wc *WebCitations, engineID, apiKey string) func(ctx context.Context, request *tools.Request) (*tools.Result, error) {
 	return func(ctx context.Context, request *tools.Request) (*tools.Result, error) {
 		query, ok := request.Arguments["query"].(string)
 		if !ok || query == "" {
@@ -120,12 +151,39 @@ func pseSearchHandler(toolLogger *zap.SugaredLogger, engineID, apiKey string) fu
 				URL:     item.Link,
 			})
 		}
+		wc.searched(results)
 
 		toolLogger.Infow("web_search completed", "results_count", len(results))
 		return tools.NewToolResultText(tools.Res2Str(results)), nil
 	}
 }
 
+// webSearchFunc queries a search API, timeRange is one of the web_search time ranges.
+type webSearchFunc func(ctx context.Context, query, timeRange string) ([]WebSearchItem, error)
+
+func searchHandler(toolLogger *zap.SugaredLogger, wc *WebCitations, search webSearchFunc) func(ctx context.Context, request *tools.Request) (*tools.Result, error) {
+	return func(ctx context.Context, request *tools.Request) (*tools.Result, error) {
+		query, ok := request.Arguments["query"].(string)
+		if !ok || query == "" {
+			toolLogger.Warnw("missing required parameter: query")
+			return tools.NewToolResultError("missing required parameter: query"), nil
+		}
+
+		timeRange, _ := request.Arguments["time_range"].(string)
+		toolLogger.Infow("web_search started", "query", query, "time_range", timeRange)
+
+		results, err := search(ctx, query, timeRange)
+		if err != nil {
+			toolLogger.Warnw("search query failed", "error", err)
+			return tools.NewToolResultError(err.Error()), nil
+		}
+
+		wc.searched(results)
+		toolLogger.Infow("web_search completed", "results_count", len(results))
+		return tools.NewToolResultText(tools.Res2Str(results)), nil
+	}
+}
+
 func crawlWebpagesHandler(wc *WebCitations, toolLogger *zap.SugaredLogger) func(ctx context.Context, request *tools.Request) (*tools.Result, error) {
 	return func(ctx context.Context, request *tools.Request) (*tools.Result, error) {
 		urlList, ok := request.Arguments["url_list"].([]any)
@@ -187,6 +245,7 @@ func crawlWebpagesHandler(wc *WebCitations, toolLogger *zap.SugaredLogger) func(
 					Filepath: content.FilePath,
 					URL:      content.URL,
 				})
+				wc.accessed(content.URL, "")
 				content.FilePath = path.Base(content.FilePath) // remove workdir
 				successCount++
 			}
@@ -214,6 +273,7 @@ type WebSearchItem struct {
 type WebCitations struct {
 	workdir string
 	files   []WebFile
+	sources webSources
 }
 
 func newWebCitations(workdir string) *WebCitations {
@@ -224,3 +284,27 @@ type WebFile struct {
 	Filepath string `json:"file_path"`
 	URL      string `json:"url"`
 }
+
+// doSearchRequest decodes the JSON reply of a search API into result. A reply with an error
+// status is still decoded when possible, so the provider error message can be reported.
+func doSearchRequest(req *http.Request, result any) error {
+	cli := &http.Client{Timeout: time.Minute}
+	resp, err := cli.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	decodeErr := json.Unmarshal(body, result)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	if decodeErr != nil {
+		return fmt.Errorf("decode response failed: %w", decodeErr)
+	}
+	return nil
+}
@@ -33,4 +33,19 @@ type NanaFS interface {
 	SaveEntry(ctx context.Context, parentURI, name string, properties types.Properties, reader io.ReadCloser) error
 	UpdateEntry(ctx context.Context, entryURI, content string, properties types.Properties) error
 	GetEntryProperties(ctx context.Context, entryURI string) (properties *types.Properties, err error)
+	Search(ctx context.Context, query string, filter types.SearchFilter) ([]types.Entry, error)
+}
+
+// Approver delivers approval requests to users and reports their decisions.
+type Approver interface {
+	RequestApproval(ctx context.Context, approval types.Approval) error
+	GetApproval(ctx context.Context, id string) (*types.Approval, error)
+}
+
+// Lister walks and manages existing NanaFS entries.
+type Lister interface {
+	ListEntries(ctx context.Context, parentURI string) ([]types.Entry, error)
+	OpenEntry(ctx context.Context, entryURI string) (io.ReadCloser, error)
+	MoveEntry(ctx context.Context, entryURI, newParentURI string) error
+	DeleteEntry(ctx context.Context, entryURI string) error
 }
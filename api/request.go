@@ -22,6 +22,8 @@ type Request struct {
 	Parameter map[string]any
 	Store     PersistentStore
 	FS        NanaFS
+	Approver  Approver
+	Lister    Lister
 }
 
 func GetStringParameter(key string, r *Request, defaultVal string) string {
@@ -0,0 +1,236 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package approval
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/logger"
+	"github.com/basenana/plugin/types"
+	"github.com/basenana/plugin/utils"
+	"go.uber.org/zap"
+)
+
+const (
+	pluginName    = "approval"
+	pluginVersion = "1.0"
+
+	defaultInterval = 5 * time.Second
+	defaultTimeout  = 24 * time.Hour
+
+	actionApprove = "approve"
+	actionReject  = "reject"
+)
+
+var PluginSpec = types.PluginSpec{
+	Name:    pluginName,
+	Version: pluginVersion,
+	Type:    types.TypeProcess,
+	Parameters: []types.ParameterSpec{
+		{
+			Name:        "title",
+			Required:    true,
+			Description: "Short description of what needs approval",
+		},
+		{
+			Name:        "message",
+			Required:    false,
+			Description: "Details shown to the reviewer",
+		},
+		{
+			Name:        "approval_id",
+			Required:    false,
+			Description: "Approval record ID, defaults to the job ID and a hash of the title",
+		},
+		{
+			Name:        "interval",
+			Required:    false,
+			Default:     defaultInterval.String(),
+			Description: "Polling interval for the decision",
+		},
+		{
+			Name:        "timeout",
+			Required:    false,
+			Default:     defaultTimeout.String(),
+			Description: "Maximum time to wait for a decision",
+		},
+		{
+			Name:        "default_action",
+			Required:    false,
+			Default:     actionReject,
+			Description: "Decision applied when the timeout expires",
+			Options:     []string{actionApprove, actionReject},
+		},
+	},
+}
+
+type ApprovalPlugin struct {
+	logger   *zap.SugaredLogger
+	fileRoot *utils.FileAccess
+	jobID    string
+	workflow string
+}
+
+// NewApprovalPlugin keeps file records in the job working path, so a decision
+// survives the cleanup of an isolated call directory.
+func NewApprovalPlugin(ps types.PluginCall) types.Plugin {
+	root := ps.JobWorkingPath
+	if root == "" {
+		root = ps.WorkingPath
+	}
+	return &ApprovalPlugin{
+		logger:   logger.NewPluginLogger(pluginName, ps.JobID),
+		fileRoot: utils.NewFileAccess(root),
+		jobID:    ps.JobID,
+		workflow: ps.Workflow,
+	}
+}
+
+func (p *ApprovalPlugin) Name() string {
+	return pluginName
+}
+
+func (p *ApprovalPlugin) Type() types.PluginType {
+	return types.TypeProcess
+}
+
+func (p *ApprovalPlugin) Version() string {
+	return pluginVersion
+}
+
+func (p *ApprovalPlugin) Run(ctx context.Context, request *api.Request) (*api.Response, error) {
+	var (
+		title         = api.GetStringParameter("title", request, "")
+		message       = api.GetStringParameter("message", request, "")
+		id            = api.GetStringParameter("approval_id", request, "")
+		defaultAction = api.GetStringParameter("default_action", request, actionReject)
+		interval      = defaultInterval
+		timeout       = defaultTimeout
+		err           error
+	)
+
+	if title == "" {
+		return api.NewFailedResponse("title is required"), nil
+	}
+	if defaultAction != actionApprove && defaultAction != actionReject {
+		return api.NewFailedResponse(fmt.Sprintf("unknown default_action: %s", defaultAction)), nil
+	}
+	if s := api.GetStringParameter("interval", request, ""); s != "" {
+		if interval, err = time.ParseDuration(s); err != nil || interval <= 0 {
+			return api.NewFailedResponse(fmt.Sprintf("invalid interval: %s", s)), nil
+		}
+	}
+	if s := api.GetStringParameter("timeout", request, ""); s != "" {
+		if timeout, err = time.ParseDuration(s); err != nil || timeout <= 0 {
+			return api.NewFailedResponse(fmt.Sprintf("invalid timeout: %s", s)), nil
+		}
+	}
+	if id == "" && p.jobID != "" {
+		id = defaultApprovalID(p.jobID, title)
+	}
+	if id == "" {
+		if id, err = randomID(); err != nil {
+			return nil, err
+		}
+	}
+
+	approver := request.Approver
+	if approver == nil {
+		approver = newFileApprover(p.fileRoot)
+	}
+
+	err = approver.RequestApproval(ctx, types.Approval{
+		ID:        id,
+		Title:     title,
+		Message:   message,
+		Workflow:  p.workflow,
+		JobID:     p.jobID,
+		Status:    types.ApprovalPending,
+		CreatedAt: time.Now().UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		p.logger.Warnw("request approval failed", "approval_id", id, "error", err)
+		return api.NewFailedResponse(fmt.Sprintf("request approval failed: %s", err)), nil
+	}
+	p.logger.Infow("approval requested", "approval_id", id, "title", title, "timeout", timeout)
+
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		approval, err := approver.GetApproval(ctx, id)
+		if err != nil {
+			p.logger.Warnw("get approval failed", "approval_id", id, "error", err)
+		} else if approval.Status != types.ApprovalPending {
+			return p.decided(approval, false), nil
+		}
+
+		select {
+		case <-ticker.C:
+		case <-deadline.C:
+			p.logger.Warnw("approval timeout", "approval_id", id, "default_action", defaultAction)
+			status := types.ApprovalRejected
+			if defaultAction == actionApprove {
+				status = types.ApprovalApproved
+			}
+			return p.decided(&types.Approval{ID: id, Status: status}, true), nil
+		case <-ctx.Done():
+			return api.NewFailedResponse(ctx.Err().Error()), nil
+		}
+	}
+}
+
+func (p *ApprovalPlugin) decided(approval *types.Approval, timedOut bool) *api.Response {
+	results := map[string]any{
+		"approval_id": approval.ID,
+		"status":      string(approval.Status),
+		"decided_by":  approval.DecidedBy,
+		"comment":     approval.Comment,
+		"timed_out":   timedOut,
+	}
+	p.logger.Infow("approval decided", "approval_id", approval.ID, "status", approval.Status, "timed_out", timedOut)
+
+	if approval.Status != types.ApprovalApproved {
+		resp := api.NewFailedResponse(fmt.Sprintf("approval %s rejected", approval.ID))
+		resp.Results = results
+		return resp
+	}
+	return api.NewResponseWithResult(results)
+}
+
+// defaultApprovalID gives every gate of a job its own record, the title keeps
+// the ID stable so a restarted job still finds the decision of the same gate.
+func defaultApprovalID(jobID, title string) string {
+	sum := sha256.Sum256([]byte(title))
+	return jobID + "-" + hex.EncodeToString(sum[:4])
+}
+
+func randomID() (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generate approval id failed: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
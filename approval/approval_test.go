@@ -0,0 +1,268 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package approval
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/logger"
+	"github.com/basenana/plugin/types"
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) {
+	logger.SetLogger(zap.NewNop().Sugar())
+	os.Exit(m.Run())
+}
+
+func newApprovalPlugin(workdir string) *ApprovalPlugin {
+	return NewApprovalPlugin(types.PluginCall{
+		JobID:       "job-1",
+		Workflow:    "test-workflow",
+		WorkingPath: workdir,
+	}).(*ApprovalPlugin)
+}
+
+type mockApprover struct {
+	mux       sync.Mutex
+	requested []types.Approval
+	decision  *types.Approval
+	polls     int
+}
+
+func (m *mockApprover) RequestApproval(ctx context.Context, approval types.Approval) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	m.requested = append(m.requested, approval)
+	return nil
+}
+
+func (m *mockApprover) GetApproval(ctx context.Context, id string) (*types.Approval, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	m.polls++
+	if m.polls < 3 || m.decision == nil {
+		return &types.Approval{ID: id, Status: types.ApprovalPending}, nil
+	}
+	return m.decision, nil
+}
+
+func TestApprovalPlugin_Approver(t *testing.T) {
+	approver := &mockApprover{decision: &types.Approval{ID: "publish", Status: types.ApprovalApproved, DecidedBy: "alice", Comment: "lgtm"}}
+	resp, err := newApprovalPlugin(t.TempDir()).Run(context.Background(), &api.Request{
+		Parameter: map[string]any{"title": "Publish post", "approval_id": "publish", "interval": "10ms"},
+		Approver:  approver,
+	})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if !resp.IsSucceed {
+		t.Fatalf("expected approval to succeed: %s", resp.Message)
+	}
+	if resp.Results["status"] != "approved" || resp.Results["decided_by"] != "alice" || resp.Results["timed_out"] != false {
+		t.Errorf("unexpected results %v", resp.Results)
+	}
+	if len(approver.requested) != 1 || approver.requested[0].Title != "Publish post" || approver.requested[0].JobID != "job-1" {
+		t.Errorf("unexpected approval request %v", approver.requested)
+	}
+}
+
+func TestApprovalPlugin_Rejected(t *testing.T) {
+	approver := &mockApprover{decision: &types.Approval{ID: "job-1", Status: types.ApprovalRejected, Comment: "not yet"}}
+	resp, err := newApprovalPlugin(t.TempDir()).Run(context.Background(), &api.Request{
+		Parameter: map[string]any{"title": "Delete files", "interval": "10ms"},
+		Approver:  approver,
+	})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if resp.IsSucceed {
+		t.Fatal("expected rejection to fail the step")
+	}
+	if resp.Results["status"] != "rejected" || resp.Results["comment"] != "not yet" {
+		t.Errorf("unexpected results %v", resp.Results)
+	}
+}
+
+func TestApprovalPlugin_FileApprover(t *testing.T) {
+	workdir := t.TempDir()
+	recordPath := filepath.Join(workdir, approvalDir, "gate-1.json")
+
+	go func() {
+		for i := 0; i < 100; i++ {
+			time.Sleep(10 * time.Millisecond)
+			data, err := os.ReadFile(recordPath)
+			if err != nil {
+				continue
+			}
+			var record types.Approval
+			if json.Unmarshal(data, &record) != nil {
+				continue
+			}
+			record.Status = types.ApprovalApproved
+			record.DecidedBy = "bob"
+			data, _ = json.Marshal(record)
+			_ = os.WriteFile(recordPath, data, 0644)
+			return
+		}
+	}()
+
+	resp, err := newApprovalPlugin(workdir).Run(context.Background(), &api.Request{
+		Parameter: map[string]any{"title": "Publish", "message": "3 posts", "approval_id": "gate-1", "interval": "10ms", "timeout": "5s"},
+	})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if !resp.IsSucceed || resp.Results["decided_by"] != "bob" {
+		t.Errorf("expected file approval by bob, got %v %v", resp.Message, resp.Results)
+	}
+}
+
+func TestApprovalPlugin_Timeout(t *testing.T) {
+	tests := []struct {
+		defaultAction string
+		succeed       bool
+		status        string
+	}{
+		{"", false, "rejected"},
+		{"approve", true, "approved"},
+	}
+	for _, tt := range tests {
+		params := map[string]any{"title": "Publish", "interval": "10ms", "timeout": "50ms"}
+		if tt.defaultAction != "" {
+			params["default_action"] = tt.defaultAction
+		}
+		resp, err := newApprovalPlugin(t.TempDir()).Run(context.Background(), &api.Request{Parameter: params})
+		if err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		if resp.IsSucceed != tt.succeed || resp.Results["status"] != tt.status || resp.Results["timed_out"] != true {
+			t.Errorf("default_action %q: unexpected response %v %v", tt.defaultAction, resp.IsSucceed, resp.Results)
+		}
+	}
+}
+
+func TestApprovalPlugin_ContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	resp, err := newApprovalPlugin(t.TempDir()).Run(ctx, &api.Request{
+		Parameter: map[string]any{"title": "Publish", "interval": "10ms"},
+	})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if resp.IsSucceed {
+		t.Error("expected failure when the context is canceled")
+	}
+}
+
+func TestApprovalPlugin_InvalidParameters(t *testing.T) {
+	for _, params := range []map[string]any{
+		{},
+		{"title": "x", "interval": "fast"},
+		{"title": "x", "timeout": "-1s"},
+		{"title": "x", "default_action": "skip"},
+	} {
+		resp, err := newApprovalPlugin(t.TempDir()).Run(context.Background(), &api.Request{Parameter: params})
+		if err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		if resp.IsSucceed {
+			t.Errorf("expected failure for %v", params)
+		}
+	}
+}
+
+func TestFileApprover_KeepsExistingDecision(t *testing.T) {
+	workdir := t.TempDir()
+	approver := newFileApprover(newApprovalPlugin(workdir).fileRoot)
+	ctx := context.Background()
+
+	if err := approver.RequestApproval(ctx, types.Approval{ID: "a", Title: "first", Status: types.ApprovalApproved}); err != nil {
+		t.Fatal(err)
+	}
+	if err := approver.RequestApproval(ctx, types.Approval{ID: "a", Title: "second", Status: types.ApprovalPending}); err != nil {
+		t.Fatal(err)
+	}
+	approval, err := approver.GetApproval(ctx, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if approval.Status != types.ApprovalApproved || approval.Title != "first" {
+		t.Errorf("expected the earlier decision to be kept, got %v", approval)
+	}
+}
+
+func TestApprovalPlugin_TwoGatesInOneJob(t *testing.T) {
+	workdir := t.TempDir()
+	approver := newFileApprover(newApprovalPlugin(workdir).fileRoot)
+	first := types.Approval{ID: defaultApprovalID("job-1", "Delete files"), Title: "Delete files", Status: types.ApprovalApproved}
+	if err := approver.RequestApproval(context.Background(), first); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := newApprovalPlugin(workdir).Run(context.Background(), &api.Request{
+		Parameter: map[string]any{"title": "Publish post", "interval": "10ms", "timeout": "50ms"},
+	})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if resp.IsSucceed || resp.Results["timed_out"] != true {
+		t.Errorf("expected the second gate to wait for its own decision, got %v %v", resp.Message, resp.Results)
+	}
+	if resp.Results["approval_id"] == first.ID {
+		t.Errorf("expected a separate record for the second gate, got %v", resp.Results["approval_id"])
+	}
+
+	resp, err = newApprovalPlugin(workdir).Run(context.Background(), &api.Request{
+		Parameter: map[string]any{"title": "Delete files", "interval": "10ms", "timeout": "50ms"},
+	})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if !resp.IsSucceed || resp.Results["timed_out"] != false {
+		t.Errorf("expected the first gate to keep its decision, got %v %v", resp.Message, resp.Results)
+	}
+}
+
+func TestApprovalPlugin_RecordInJobWorkingPath(t *testing.T) {
+	jobdir := t.TempDir()
+	p := NewApprovalPlugin(types.PluginCall{
+		JobID:          "job-1",
+		WorkingPath:    filepath.Join(jobdir, ".calls", "approval-1"),
+		JobWorkingPath: jobdir,
+	}).(*ApprovalPlugin)
+	resp, err := p.Run(context.Background(), &api.Request{
+		Parameter: map[string]any{"title": "Publish", "approval_id": "gate-1", "interval": "10ms", "timeout": "20ms"},
+	})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if resp.IsSucceed {
+		t.Error("expected the undecided approval to time out")
+	}
+	if _, err = os.Stat(filepath.Join(jobdir, approvalDir, "gate-1.json")); err != nil {
+		t.Errorf("expected the record in the job working path: %v", err)
+	}
+}
@@ -0,0 +1,79 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package approval
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/types"
+	"github.com/basenana/plugin/utils"
+)
+
+const approvalDir = ".approvals"
+
+// fileApprover keeps approval records as JSON files under the working path,
+// a reviewer decides by setting "status" to approved or rejected.
+type fileApprover struct {
+	fileRoot *utils.FileAccess
+}
+
+var _ api.Approver = &fileApprover{}
+
+func newFileApprover(fileRoot *utils.FileAccess) *fileApprover {
+	return &fileApprover{fileRoot: fileRoot}
+}
+
+func (f *fileApprover) RequestApproval(ctx context.Context, approval types.Approval) error {
+	recordPath := approvalPath(approval.ID)
+	// keep an existing record so a restarted job picks up an earlier decision
+	if f.fileRoot.Exists(recordPath) {
+		return nil
+	}
+	if err := f.fileRoot.MkdirAll(approvalDir, 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(approval, "", "  ")
+	if err != nil {
+		return err
+	}
+	return f.fileRoot.Write(recordPath, data, 0644)
+}
+
+func (f *fileApprover) GetApproval(ctx context.Context, id string) (*types.Approval, error) {
+	data, err := f.fileRoot.Read(approvalPath(id))
+	if err != nil {
+		return nil, err
+	}
+	approval := &types.Approval{}
+	if err = json.Unmarshal(data, approval); err != nil {
+		return nil, fmt.Errorf("parse approval record failed: %w", err)
+	}
+	switch approval.Status {
+	case types.ApprovalPending, types.ApprovalApproved, types.ApprovalRejected:
+	default:
+		return nil, fmt.Errorf("unknown approval status: %s", approval.Status)
+	}
+	return approval, nil
+}
+
+func approvalPath(id string) string {
+	return path.Join(approvalDir, utils.SanitizeFilename(id)+".json")
+}
@@ -91,7 +91,7 @@ type ArchivePlugin struct {
 func NewArchivePlugin(ps types.PluginCall) types.Plugin {
 	return &ArchivePlugin{
 		logger:   logger.NewPluginLogger(pluginName, ps.JobID),
-		fileRoot: utils.NewFileAccess(ps.WorkingPath),
+		fileRoot: utils.NewFileAccess(ps.WorkingPath, ps.JobWorkingPath),
 	}
 }
 
@@ -0,0 +1,326 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package arxiv
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/logger"
+	"github.com/basenana/plugin/types"
+	"github.com/basenana/plugin/utils"
+	"go.uber.org/zap"
+)
+
+const (
+	pluginName    = "arxiv"
+	pluginVersion = "1.0"
+
+	defaultAPIURL          = "https://export.arxiv.org/api/query"
+	defaultOutputDir       = "papers"
+	defaultMaxResults      = 20
+	maxMaxResults          = 100
+	defaultRequestInterval = 3 * time.Second
+	maxPDFSize             = 100 << 20
+)
+
+var PluginSpec = types.PluginSpec{
+	Name:    pluginName,
+	Version: pluginVersion,
+	Type:    types.TypeSource,
+	Dependencies: []types.Dependency{
+		{Kind: types.DependencyCapability, Name: types.CapabilityNetwork},
+		{Kind: types.DependencyCapability, Name: types.CapabilityStore, Optional: true},
+	},
+	Parameters: []types.ParameterSpec{
+		{
+			Name:        "categories",
+			Required:    false,
+			Description: "Comma separated arXiv categories, e.g. cs.CL,cs.IR; papers in any of them match",
+		},
+		{
+			Name:        "keywords",
+			Required:    false,
+			Description: "Comma separated keywords or phrases searched in all fields; papers matching any of them match",
+		},
+		{
+			Name:        "query",
+			Required:    false,
+			Description: "Raw arXiv search_query, replaces categories and keywords",
+		},
+		{
+			Name:        "max_results",
+			Required:    false,
+			Default:     strconv.Itoa(defaultMaxResults),
+			Description: "Newest submissions to check per run, at most 100",
+		},
+		{
+			Name:        "download_pdf",
+			Required:    false,
+			Default:     "true",
+			Description: "Download the paper PDF, otherwise write a Markdown abstract page",
+			Options:     []string{"true", "false"},
+		},
+		{
+			Name:        "output_dir",
+			Required:    false,
+			Default:     defaultOutputDir,
+			Description: "Directory in the working path for the papers",
+		},
+		{
+			Name:        "request_interval",
+			Required:    false,
+			Default:     defaultRequestInterval.String(),
+			Description: "Delay between requests to arXiv, as asked by the API terms of use",
+		},
+		{
+			Name:        "api_url",
+			Required:    false,
+			Default:     defaultAPIURL,
+			Description: "arXiv query API endpoint",
+		},
+		{
+			Name:        "state_file",
+			Required:    false,
+			Default:     defaultStateFile,
+			Description: "File in the working path recording seen papers when no store is provided",
+		},
+	},
+}
+
+type ArxivPlugin struct {
+	logger   *zap.SugaredLogger
+	fileRoot *utils.FileAccess
+	cli      *http.Client
+}
+
+func NewArxivPlugin(ps types.PluginCall) types.Plugin {
+	return &ArxivPlugin{
+		logger:   logger.NewPluginLogger(pluginName, ps.JobID),
+		fileRoot: utils.NewFileAccess(ps.WorkingPath, ps.JobWorkingPath),
+		cli:      &http.Client{Timeout: 2 * time.Minute},
+	}
+}
+
+func (p *ArxivPlugin) Name() string {
+	return pluginName
+}
+
+func (p *ArxivPlugin) Type() types.PluginType {
+	return types.TypeSource
+}
+
+func (p *ArxivPlugin) Version() string {
+	return pluginVersion
+}
+
+func (p *ArxivPlugin) Run(ctx context.Context, request *api.Request) (*api.Response, error) {
+	query, err := buildQuery(api.GetStringParameter("query", request, ""),
+		splitList(api.GetStringParameter("categories", request, "")), splitList(api.GetStringParameter("keywords", request, "")))
+	if err != nil {
+		return api.NewFailedResponse(err.Error()), nil
+	}
+	maxResults := defaultMaxResults
+	if raw := api.GetStringParameter("max_results", request, ""); raw != "" {
+		if maxResults, err = strconv.Atoi(raw); err != nil || maxResults <= 0 || maxResults > maxMaxResults {
+			return api.NewFailedResponse(fmt.Sprintf("parse max_results [%s] failed: expect 1 to %d", raw, maxMaxResults)), nil
+		}
+	}
+	rawPDF := api.GetStringParameter("download_pdf", request, "true")
+	downloadPDF, err := strconv.ParseBool(rawPDF)
+	if err != nil {
+		return api.NewFailedResponse(fmt.Sprintf("parse download_pdf [%s] failed: expect true or false", rawPDF)), nil
+	}
+	rawInterval := api.GetStringParameter("request_interval", request, defaultRequestInterval.String())
+	interval, err := time.ParseDuration(rawInterval)
+	if err != nil || interval < 0 {
+		return api.NewFailedResponse(fmt.Sprintf("parse request_interval [%s] failed: expect duration", rawInterval)), nil
+	}
+	outputDir := strings.Trim(api.GetStringParameter("output_dir", request, defaultOutputDir), "/")
+	if outputDir == "" {
+		outputDir = defaultOutputDir
+	}
+	store := request.Store
+	if store == nil {
+		store = &fileStore{fileRoot: p.fileRoot, path: api.GetStringParameter("state_file", request, defaultStateFile)}
+	}
+
+	data, err := p.get(ctx, queryURL(api.GetStringParameter("api_url", request, defaultAPIURL), query, maxResults), "")
+	if err != nil {
+		p.logger.Warnw("query arxiv failed", "query", query, "err", err)
+		return api.NewFailedResponse(fmt.Sprintf("query arxiv failed: %s", err)), nil
+	}
+	papers, err := parseFeed(data)
+	if err != nil {
+		return api.NewFailedResponse(err.Error()), nil
+	}
+	if err = p.fileRoot.MkdirAll(outputDir, 0755); err != nil {
+		return nil, err
+	}
+
+	var (
+		items  = make([]map[string]any, 0)
+		failed int
+	)
+	// archive oldest first so an interrupted run resumes in submission order
+	for i := len(papers) - 1; i >= 0; i-- {
+		paper := papers[i]
+		var seen time.Time
+		if err = store.Load(ctx, pluginName, "papers", paper.ID, &seen); err == nil {
+			continue
+		}
+
+		fileName := path.Join(outputDir, strings.ReplaceAll(paper.ID, "/", "_"))
+		if downloadPDF {
+			if err = sleepContext(ctx, interval); err != nil {
+				return nil, err
+			}
+			fileName += ".pdf"
+			err = p.savePDF(ctx, paper.PDFURL, fileName)
+		} else {
+			fileName += ".md"
+			err = p.fileRoot.Write(fileName, paper.markdown(), 0644)
+		}
+		if err != nil {
+			p.logger.Warnw("save paper failed, retry next run", "id", paper.ID, "err", err)
+			failed++
+			continue
+		}
+		if err = store.Save(ctx, pluginName, "papers", paper.ID, paper.Published); err != nil {
+			return nil, fmt.Errorf("save paper record failed: %w", err)
+		}
+
+		item := utils.MarshalMap(paper)
+		item["file_path"] = fileName
+		item["properties"] = utils.MarshalMap(paper.properties())
+		items = append(items, item)
+	}
+
+	p.logger.Infow("arxiv papers fetched", "query", query, "checked", len(papers), "new", len(items), "failed", failed)
+	return api.NewResponseWithResult(map[string]any{
+		"query":  query,
+		"items":  items,
+		"total":  len(items),
+		"failed": failed,
+	}), nil
+}
+
+func (p *ArxivPlugin) get(ctx context.Context, rawURL, accept string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "basenana-plugin/"+pluginVersion)
+	resp, err := p.cli.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status code is %d", resp.StatusCode)
+	}
+	if accept != "" && !strings.HasPrefix(resp.Header.Get("Content-Type"), accept) {
+		return nil, fmt.Errorf("unexpected content type %s", resp.Header.Get("Content-Type"))
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxPDFSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxPDFSize {
+		return nil, fmt.Errorf("response larger than %d bytes", maxPDFSize)
+	}
+	return data, nil
+}
+
+func (p *ArxivPlugin) savePDF(ctx context.Context, pdfURL, fileName string) error {
+	data, err := p.get(ctx, pdfURL, "application/pdf")
+	if err != nil {
+		return fmt.Errorf("download %s failed: %w", pdfURL, err)
+	}
+	return p.fileRoot.Write(fileName, data, 0644)
+}
+
+// properties maps the paper onto the entry properties used by the research library.
+func (p Paper) properties() types.Properties {
+	props := types.Properties{
+		Title:    p.Title,
+		Author:   strings.Join(p.Authors, ", "),
+		Source:   "arXiv",
+		Abstract: p.Abstract,
+		Keywords: p.Categories,
+		URL:      p.AbsURL,
+		SiteName: "arXiv",
+		SiteURL:  "https://arxiv.org",
+	}
+	if p.JournalRef != "" {
+		props.Source = p.JournalRef
+	}
+	notes := []string{"arXiv: " + p.ID + p.Version}
+	if p.DOI != "" {
+		notes = append(notes, "DOI: "+p.DOI)
+	}
+	if p.Comment != "" {
+		notes = append(notes, "Comment: "+p.Comment)
+	}
+	props.Notes = strings.Join(notes, "\n")
+	if !p.Published.IsZero() {
+		props.PublishAt = p.Published.Unix()
+		props.Year = strconv.Itoa(p.Published.Year())
+	}
+	return props
+}
+
+func (p Paper) markdown() []byte {
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "# %s\n\n", p.Title)
+	fmt.Fprintf(buf, "- Authors: %s\n", strings.Join(p.Authors, ", "))
+	fmt.Fprintf(buf, "- arXiv: [%s%s](%s)\n", p.ID, p.Version, p.AbsURL)
+	fmt.Fprintf(buf, "- PDF: %s\n", p.PDFURL)
+	if p.DOI != "" {
+		fmt.Fprintf(buf, "- DOI: [%s](https://doi.org/%s)\n", p.DOI, p.DOI)
+	}
+	if len(p.Categories) > 0 {
+		fmt.Fprintf(buf, "- Categories: %s\n", strings.Join(p.Categories, ", "))
+	}
+	if !p.Published.IsZero() {
+		fmt.Fprintf(buf, "- Published: %s\n", p.Published.Format("2006-01-02"))
+	}
+	fmt.Fprintf(buf, "\n## Abstract\n\n%s\n", p.Abstract)
+	return buf.Bytes()
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
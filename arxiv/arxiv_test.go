@@ -0,0 +1,184 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package arxiv
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/logger"
+	"github.com/basenana/plugin/types"
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) {
+	logger.SetLogger(zap.NewNop().Sugar())
+	os.Exit(m.Run())
+}
+
+type fakeArxiv struct {
+	*httptest.Server
+	mu        sync.Mutex
+	queries   []string
+	pdfStatus int
+}
+
+func newFakeArxiv(t *testing.T) *fakeArxiv {
+	f := &fakeArxiv{pdfStatus: http.StatusOK}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		switch {
+		case r.URL.Path == "/api/query":
+			f.queries = append(f.queries, r.URL.Query().Get("search_query"))
+			if r.URL.Query().Get("sortBy") != "submittedDate" || r.URL.Query().Get("max_results") != "5" {
+				t.Errorf("unexpected query %s", r.URL.RawQuery)
+			}
+			w.Header().Set("Content-Type", "application/atom+xml")
+			feed := strings.NewReplacer("http://arxiv.org/abs/", f.URL+"/abs/", "http://arxiv.org/pdf/", f.URL+"/pdf/").Replace(testFeed)
+			_, _ = w.Write([]byte(feed))
+		case strings.HasPrefix(r.URL.Path, "/pdf/"):
+			if f.pdfStatus != http.StatusOK {
+				w.WriteHeader(f.pdfStatus)
+				return
+			}
+			w.Header().Set("Content-Type", "application/pdf")
+			_, _ = w.Write([]byte("%PDF-1.4 " + r.URL.Path))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func runArxiv(t *testing.T, workdir string, params map[string]any) *api.Response {
+	t.Helper()
+	p := NewArxivPlugin(types.PluginCall{JobID: "job-1", WorkingPath: workdir}).(*ArxivPlugin)
+	resp, err := p.Run(context.Background(), &api.Request{Parameter: params})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestArxivPlugin_Run(t *testing.T) {
+	server := newFakeArxiv(t)
+	workdir := t.TempDir()
+	params := map[string]any{
+		"categories":       "cs.CL, cs.IR",
+		"keywords":         "retrieval augmented",
+		"max_results":      "5",
+		"request_interval": "0s",
+		"api_url":          server.URL + "/api/query",
+	}
+
+	resp := runArxiv(t, workdir, params)
+	if !resp.IsSucceed {
+		t.Fatalf("Run not succeed: %s", resp.Message)
+	}
+	if server.queries[0] != `(cat:cs.CL OR cat:cs.IR) AND all:"retrieval augmented"` {
+		t.Errorf("unexpected search query %s", server.queries[0])
+	}
+	items := resp.Results["items"].([]map[string]any)
+	if resp.Results["total"] != 2 || len(items) != 2 {
+		t.Fatalf("unexpected results %v", resp.Results)
+	}
+	if items[0]["id"] != "hep-th/9901001" || items[0]["file_path"] != "papers/hep-th_9901001.pdf" {
+		t.Errorf("expected the oldest paper first, got %v", items[0])
+	}
+
+	paper := items[1]
+	if paper["file_path"] != "papers/2401.00002.pdf" || paper["doi"] != "10.1000/rag.2024" {
+		t.Errorf("unexpected paper %v", paper)
+	}
+	if data, err := os.ReadFile(filepath.Join(workdir, "papers/2401.00002.pdf")); err != nil || !strings.HasPrefix(string(data), "%PDF") {
+		t.Errorf("unexpected pdf %q, %v", data, err)
+	}
+	props := paper["properties"].(map[string]any)
+	if props["title"] != "Retrieval Augmented Generation at Scale" || props["author"] != "Ada Lovelace, Alan Turing" ||
+		props["abstract"] != "We study retrieval augmented generation." || props["source"] != "Proc. RAG 2024" || props["year"] != "2024" {
+		t.Errorf("unexpected properties %v", props)
+	}
+	if notes := props["notes"].(string); !strings.Contains(notes, "DOI: 10.1000/rag.2024") || !strings.Contains(notes, "arXiv: 2401.00002v2") {
+		t.Errorf("unexpected notes %q", notes)
+	}
+
+	resp = runArxiv(t, workdir, params)
+	if resp.Results["total"] != 0 {
+		t.Errorf("expected seen papers to be skipped, got %v", resp.Results["items"])
+	}
+}
+
+func TestArxivPlugin_Run_PDFFailureRetried(t *testing.T) {
+	server := newFakeArxiv(t)
+	server.pdfStatus = http.StatusServiceUnavailable
+	workdir := t.TempDir()
+	params := map[string]any{"query": "cat:cs.CL", "max_results": "5", "request_interval": "0s", "api_url": server.URL + "/api/query"}
+
+	resp := runArxiv(t, workdir, params)
+	if resp.Results["total"] != 0 || resp.Results["failed"] != 2 {
+		t.Fatalf("expected both downloads to fail, got %v", resp.Results)
+	}
+
+	server.mu.Lock()
+	server.pdfStatus = http.StatusOK
+	server.mu.Unlock()
+	if resp = runArxiv(t, workdir, params); resp.Results["total"] != 2 {
+		t.Errorf("expected failed papers to be retried, got %v", resp.Results)
+	}
+}
+
+func TestArxivPlugin_Run_AbstractOnly(t *testing.T) {
+	server := newFakeArxiv(t)
+	workdir := t.TempDir()
+	resp := runArxiv(t, workdir, map[string]any{
+		"categories": "cs.CL", "max_results": "5", "download_pdf": "false", "output_dir": "inbox/arxiv", "api_url": server.URL + "/api/query",
+	})
+	if !resp.IsSucceed || resp.Results["total"] != 2 {
+		t.Fatalf("unexpected response %v %s", resp.Results, resp.Message)
+	}
+	content, err := os.ReadFile(filepath.Join(workdir, "inbox/arxiv/2401.00002.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"# Retrieval Augmented Generation at Scale", "[10.1000/rag.2024](https://doi.org/10.1000/rag.2024)", "## Abstract\n\nWe study"} {
+		if !strings.Contains(string(content), want) {
+			t.Errorf("abstract page missing %q:\n%s", want, content)
+		}
+	}
+}
+
+func TestArxivPlugin_Run_InvalidParameters(t *testing.T) {
+	for _, params := range []map[string]any{
+		{},
+		{"categories": "cs.CL", "max_results": "500"},
+		{"categories": "cs.CL", "download_pdf": "maybe"},
+		{"categories": "cs.CL", "request_interval": "soon"},
+	} {
+		if resp := runArxiv(t, t.TempDir(), params); resp.IsSucceed {
+			t.Errorf("expected %v to fail", params)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package arxiv
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Paper is one arXiv entry. ID is the arXiv identifier without the version suffix.
+type Paper struct {
+	ID              string    `json:"id"`
+	Version         string    `json:"version,omitempty"`
+	Title           string    `json:"title"`
+	Authors         []string  `json:"authors"`
+	Abstract        string    `json:"abstract"`
+	DOI             string    `json:"doi,omitempty"`
+	JournalRef      string    `json:"journal_ref,omitempty"`
+	Comment         string    `json:"comment,omitempty"`
+	PrimaryCategory string    `json:"primary_category,omitempty"`
+	Categories      []string  `json:"categories,omitempty"`
+	AbsURL          string    `json:"abs_url"`
+	PDFURL          string    `json:"pdf_url"`
+	Published       time.Time `json:"published"`
+	Updated         time.Time `json:"updated"`
+}
+
+type atomFeed struct {
+	Entries []atomEntry `xml:"http://www.w3.org/2005/Atom entry"`
+}
+
+type atomEntry struct {
+	ID        string `xml:"http://www.w3.org/2005/Atom id"`
+	Title     string `xml:"http://www.w3.org/2005/Atom title"`
+	Summary   string `xml:"http://www.w3.org/2005/Atom summary"`
+	Published string `xml:"http://www.w3.org/2005/Atom published"`
+	Updated   string `xml:"http://www.w3.org/2005/Atom updated"`
+	Authors   []struct {
+		Name string `xml:"http://www.w3.org/2005/Atom name"`
+	} `xml:"http://www.w3.org/2005/Atom author"`
+	Links []struct {
+		Href  string `xml:"href,attr"`
+		Rel   string `xml:"rel,attr"`
+		Title string `xml:"title,attr"`
+		Type  string `xml:"type,attr"`
+	} `xml:"http://www.w3.org/2005/Atom link"`
+	Categories []struct {
+		Term string `xml:"term,attr"`
+	} `xml:"http://www.w3.org/2005/Atom category"`
+	PrimaryCategory struct {
+		Term string `xml:"term,attr"`
+	} `xml:"http://arxiv.org/schemas/atom primary_category"`
+	DOI        string `xml:"http://arxiv.org/schemas/atom doi"`
+	JournalRef string `xml:"http://arxiv.org/schemas/atom journal_ref"`
+	Comment    string `xml:"http://arxiv.org/schemas/atom comment"`
+}
+
+var (
+	spaces        = regexp.MustCompile(`\s+`)
+	versionSuffix = regexp.MustCompile(`v(\d+)$`)
+	categoryRegex = regexp.MustCompile(`^[a-zA-Z-]+(\.[a-zA-Z-]+)?$`)
+)
+
+// parseFeed reads the Atom response of the arXiv query API.
+func parseFeed(data []byte) ([]Paper, error) {
+	var feed atomFeed
+	if err := xml.Unmarshal(data, &feed); err != nil {
+		return nil, fmt.Errorf("parse arxiv response failed: %s", err)
+	}
+	papers := make([]Paper, 0, len(feed.Entries))
+	for _, e := range feed.Entries {
+		// the API reports errors as a single entry with an /api/errors id
+		if strings.Contains(e.ID, "/api/errors") {
+			return nil, fmt.Errorf("arxiv query failed: %s", collapse(e.Summary))
+		}
+		p := Paper{
+			Title:           collapse(e.Title),
+			Abstract:        collapse(e.Summary),
+			DOI:             strings.TrimSpace(e.DOI),
+			JournalRef:      collapse(e.JournalRef),
+			Comment:         collapse(e.Comment),
+			PrimaryCategory: e.PrimaryCategory.Term,
+		}
+		p.ID, p.Version = splitID(e.ID)
+		if p.ID == "" {
+			continue
+		}
+		for _, a := range e.Authors {
+			if name := collapse(a.Name); name != "" {
+				p.Authors = append(p.Authors, name)
+			}
+		}
+		for _, c := range e.Categories {
+			if c.Term != "" {
+				p.Categories = append(p.Categories, c.Term)
+			}
+		}
+		for _, l := range e.Links {
+			switch {
+			case l.Title == "pdf" || l.Type == "application/pdf":
+				p.PDFURL = l.Href
+			case l.Rel == "alternate":
+				p.AbsURL = l.Href
+			}
+		}
+		if p.AbsURL == "" {
+			p.AbsURL = strings.TrimSpace(e.ID)
+		}
+		if p.PDFURL == "" {
+			p.PDFURL = strings.Replace(p.AbsURL, "/abs/", "/pdf/", 1)
+		}
+		p.Published, _ = time.Parse(time.RFC3339, strings.TrimSpace(e.Published))
+		p.Updated, _ = time.Parse(time.RFC3339, strings.TrimSpace(e.Updated))
+		papers = append(papers, p)
+	}
+	return papers, nil
+}
+
+// splitID turns http://arxiv.org/abs/2401.01234v2 into 2401.01234 and v2,
+// old style ids such as hep-th/9901001v1 keep their archive prefix.
+func splitID(raw string) (id, version string) {
+	raw = strings.TrimSpace(raw)
+	if i := strings.Index(raw, "/abs/"); i >= 0 {
+		raw = raw[i+len("/abs/"):]
+	}
+	if m := versionSuffix.FindStringIndex(raw); m != nil {
+		return raw[:m[0]], raw[m[0]:]
+	}
+	return raw, ""
+}
+
+func collapse(s string) string {
+	return strings.TrimSpace(spaces.ReplaceAllString(s, " "))
+}
+
+// buildQuery combines categories and keywords into an arXiv search_query, categories
+// and keywords are each OR-ed and the two groups AND-ed. A raw query is used as is.
+func buildQuery(raw string, categories, keywords []string) (string, error) {
+	if raw = strings.TrimSpace(raw); raw != "" {
+		return raw, nil
+	}
+	var groups []string
+	if len(categories) > 0 {
+		terms := make([]string, 0, len(categories))
+		for _, c := range categories {
+			if !categoryRegex.MatchString(c) {
+				return "", fmt.Errorf("invalid category %q", c)
+			}
+			terms = append(terms, "cat:"+c)
+		}
+		groups = append(groups, orGroup(terms))
+	}
+	if len(keywords) > 0 {
+		terms := make([]string, 0, len(keywords))
+		for _, k := range keywords {
+			k = strings.ReplaceAll(k, `"`, "")
+			if strings.Contains(k, " ") {
+				k = `"` + k + `"`
+			}
+			terms = append(terms, "all:"+k)
+		}
+		groups = append(groups, orGroup(terms))
+	}
+	if len(groups) == 0 {
+		return "", fmt.Errorf("query, categories or keywords is required")
+	}
+	return strings.Join(groups, " AND "), nil
+}
+
+func orGroup(terms []string) string {
+	if len(terms) == 1 {
+		return terms[0]
+	}
+	return "(" + strings.Join(terms, " OR ") + ")"
+}
+
+func queryURL(apiURL, query string, maxResults int) string {
+	values := url.Values{}
+	values.Set("search_query", query)
+	values.Set("start", "0")
+	values.Set("max_results", fmt.Sprint(maxResults))
+	values.Set("sortBy", "submittedDate")
+	values.Set("sortOrder", "descending")
+	return apiURL + "?" + values.Encode()
+}
+
+func splitList(raw string) []string {
+	var items []string
+	for _, s := range strings.FieldsFunc(raw, func(r rune) bool { return r == ',' || r == '\n' }) {
+		if s = strings.TrimSpace(s); s != "" {
+			items = append(items, s)
+		}
+	}
+	return items
+}
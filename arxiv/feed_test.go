@@ -0,0 +1,121 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package arxiv
+
+import (
+	"strings"
+	"testing"
+)
+
+const testFeed = `<?xml version="1.0" encoding="UTF-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom" xmlns:arxiv="http://arxiv.org/schemas/atom">
+  <title>arXiv Query</title>
+  <entry>
+    <id>http://arxiv.org/abs/2401.00002v2</id>
+    <updated>2024-01-03T10:00:00Z</updated>
+    <published>2024-01-02T09:00:00Z</published>
+    <title>Retrieval Augmented
+      Generation at Scale</title>
+    <summary>  We study retrieval
+  augmented generation.
+</summary>
+    <author><name>Ada Lovelace</name><arxiv:affiliation>Analytical Engines</arxiv:affiliation></author>
+    <author><name>Alan Turing</name></author>
+    <arxiv:doi>10.1000/rag.2024</arxiv:doi>
+    <arxiv:journal_ref>Proc. RAG 2024</arxiv:journal_ref>
+    <arxiv:comment>12 pages</arxiv:comment>
+    <link href="http://arxiv.org/abs/2401.00002v2" rel="alternate" type="text/html"/>
+    <link title="pdf" href="http://arxiv.org/pdf/2401.00002v2" rel="related" type="application/pdf"/>
+    <arxiv:primary_category term="cs.CL" scheme="http://arxiv.org/schemas/atom"/>
+    <category term="cs.CL" scheme="http://arxiv.org/schemas/atom"/>
+    <category term="cs.IR" scheme="http://arxiv.org/schemas/atom"/>
+  </entry>
+  <entry>
+    <id>http://arxiv.org/abs/hep-th/9901001v1</id>
+    <published>1999-01-01T00:00:00Z</published>
+    <title>Old Style Identifier</title>
+    <summary>Strings.</summary>
+    <author><name>Someone</name></author>
+    <category term="hep-th"/>
+  </entry>
+</feed>`
+
+func TestParseFeed(t *testing.T) {
+	papers, err := parseFeed([]byte(testFeed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(papers) != 2 {
+		t.Fatalf("expected 2 papers, got %d", len(papers))
+	}
+	p := papers[0]
+	if p.ID != "2401.00002" || p.Version != "v2" || p.Title != "Retrieval Augmented Generation at Scale" ||
+		p.Abstract != "We study retrieval augmented generation." {
+		t.Errorf("unexpected paper %+v", p)
+	}
+	if strings.Join(p.Authors, ";") != "Ada Lovelace;Alan Turing" || p.DOI != "10.1000/rag.2024" || p.JournalRef != "Proc. RAG 2024" {
+		t.Errorf("unexpected metadata %+v", p)
+	}
+	if p.PrimaryCategory != "cs.CL" || strings.Join(p.Categories, ",") != "cs.CL,cs.IR" {
+		t.Errorf("unexpected categories %+v", p)
+	}
+	if p.AbsURL != "http://arxiv.org/abs/2401.00002v2" || p.PDFURL != "http://arxiv.org/pdf/2401.00002v2" || p.Published.Year() != 2024 {
+		t.Errorf("unexpected links %+v", p)
+	}
+
+	old := papers[1]
+	if old.ID != "hep-th/9901001" || old.PDFURL != "http://arxiv.org/pdf/hep-th/9901001v1" {
+		t.Errorf("unexpected old style paper %+v", old)
+	}
+}
+
+func TestParseFeed_Error(t *testing.T) {
+	_, err := parseFeed([]byte(`<feed xmlns="http://www.w3.org/2005/Atom"><entry><id>http://arxiv.org/api/errors#incorrect_id_format</id>
+<summary>incorrect id format for 1234</summary></entry></feed>`))
+	if err == nil || !strings.Contains(err.Error(), "incorrect id format") {
+		t.Errorf("expected api error, got %v", err)
+	}
+	if _, err = parseFeed([]byte("not xml <")); err == nil {
+		t.Errorf("expected parse error")
+	}
+}
+
+func TestBuildQuery(t *testing.T) {
+	tests := []struct {
+		raw        string
+		categories []string
+		keywords   []string
+		want       string
+	}{
+		{"", []string{"cs.CL"}, nil, "cat:cs.CL"},
+		{"", []string{"cs.CL", "cs.IR"}, []string{"rag", "large language model"}, `(cat:cs.CL OR cat:cs.IR) AND (all:rag OR all:"large language model")`},
+		{"", nil, []string{`"agents"`}, "all:agents"},
+		{"ti:transformer", []string{"cs.CL"}, nil, "ti:transformer"},
+	}
+	for _, tt := range tests {
+		got, err := buildQuery(tt.raw, tt.categories, tt.keywords)
+		if err != nil || got != tt.want {
+			t.Errorf("buildQuery(%q, %v, %v) = %q, %v, want %q", tt.raw, tt.categories, tt.keywords, got, err, tt.want)
+		}
+	}
+	if _, err := buildQuery("", nil, nil); err == nil {
+		t.Errorf("expected error without query")
+	}
+	if _, err := buildQuery("", []string{"cs.CL OR all:x"}, nil); err == nil {
+		t.Errorf("expected invalid category error")
+	}
+}
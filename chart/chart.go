@@ -0,0 +1,253 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package chart
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/logger"
+	"github.com/basenana/plugin/types"
+	"github.com/basenana/plugin/utils"
+	"go.uber.org/zap"
+)
+
+const (
+	pluginName    = "chart"
+	pluginVersion = "1.0"
+
+	formatSVG = "svg"
+	formatPNG = "png"
+
+	defaultWidth  = 800
+	defaultHeight = 480
+	minSize       = 200
+	maxSize       = 4000
+)
+
+var PluginSpec = types.PluginSpec{
+	Name:    pluginName,
+	Version: pluginVersion,
+	Type:    types.TypeProcess,
+	Parameters: []types.ParameterSpec{
+		{
+			Name:        "type",
+			Required:    true,
+			Description: "Chart type",
+			Options:     []string{chartBar, chartLine, chartPie},
+		},
+		{
+			Name:        "data",
+			Required:    false,
+			Description: "JSON array of objects, e.g. the results of a previous step",
+		},
+		{
+			Name:        "csv_path",
+			Required:    false,
+			Description: "CSV file in the working path with a header row",
+		},
+		{
+			Name:        "label_field",
+			Required:    false,
+			Description: "Column used for labels, defaults to the first column",
+		},
+		{
+			Name:        "value_fields",
+			Required:    false,
+			Description: "Comma-separated numeric columns to plot, defaults to every other numeric column",
+		},
+		{
+			Name:        "title",
+			Required:    false,
+			Description: "Chart title",
+		},
+		{
+			Name:        "format",
+			Required:    false,
+			Description: "Output format, defaults to the output_path extension or svg",
+			Options:     []string{formatSVG, formatPNG},
+		},
+		{
+			Name:        "width",
+			Required:    false,
+			Default:     strconv.Itoa(defaultWidth),
+			Description: "Image width in pixels",
+		},
+		{
+			Name:        "height",
+			Required:    false,
+			Default:     strconv.Itoa(defaultHeight),
+			Description: "Image height in pixels",
+		},
+		{
+			Name:        "output_path",
+			Required:    false,
+			Description: "Output file, defaults to chart.<format>",
+		},
+	},
+}
+
+type ChartPlugin struct {
+	logger   *zap.SugaredLogger
+	fileRoot *utils.FileAccess
+}
+
+func NewChartPlugin(ps types.PluginCall) types.Plugin {
+	return &ChartPlugin{
+		logger:   logger.NewPluginLogger(pluginName, ps.JobID),
+		fileRoot: utils.NewFileAccess(ps.WorkingPath, ps.JobWorkingPath),
+	}
+}
+
+func (p *ChartPlugin) Name() string {
+	return pluginName
+}
+
+func (p *ChartPlugin) Type() types.PluginType {
+	return types.TypeProcess
+}
+
+func (p *ChartPlugin) Version() string {
+	return pluginVersion
+}
+
+func (p *ChartPlugin) Run(ctx context.Context, request *api.Request) (*api.Response, error) {
+	opt := chartOption{
+		Type:  api.GetStringParameter("type", request, ""),
+		Title: api.GetStringParameter("title", request, ""),
+	}
+	switch opt.Type {
+	case chartBar, chartLine, chartPie:
+	case "":
+		return api.NewFailedResponse("type is required"), nil
+	default:
+		return api.NewFailedResponse(fmt.Sprintf("unknown chart type: %s", opt.Type)), nil
+	}
+
+	var err error
+	if opt.Width, err = sizeParameter("width", request, defaultWidth); err != nil {
+		return api.NewFailedResponse(err.Error()), nil
+	}
+	if opt.Height, err = sizeParameter("height", request, defaultHeight); err != nil {
+		return api.NewFailedResponse(err.Error()), nil
+	}
+
+	outputPath := api.GetStringParameter("output_path", request, "")
+	format := api.GetStringParameter("format", request, "")
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(outputPath)), ".")
+		if format != formatPNG {
+			format = formatSVG
+		}
+	}
+	if format != formatSVG && format != formatPNG {
+		return api.NewFailedResponse(fmt.Sprintf("unknown format: %s", format)), nil
+	}
+	if outputPath == "" {
+		outputPath = "chart." + format
+	}
+
+	tbl, err := p.loadTable(request)
+	if err != nil {
+		return api.NewFailedResponse(err.Error()), nil
+	}
+	var valueFields []string
+	for _, f := range strings.Split(api.GetStringParameter("value_fields", request, ""), ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			valueFields = append(valueFields, f)
+		}
+	}
+	ds, err := tbl.dataset(api.GetStringParameter("label_field", request, ""), valueFields)
+	if err != nil {
+		return api.NewFailedResponse(err.Error()), nil
+	}
+
+	var c canvas
+	if format == formatPNG {
+		c = newPNGCanvas(opt.Width, opt.Height)
+	} else {
+		c = newSVGCanvas(opt.Width, opt.Height)
+	}
+	if err = render(c, opt, ds); err != nil {
+		return api.NewFailedResponse(err.Error()), nil
+	}
+	buf := &bytes.Buffer{}
+	if err = c.encode(buf); err != nil {
+		return nil, err
+	}
+	if err = p.fileRoot.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+		return api.NewFailedResponse(fmt.Sprintf("create directory for %s failed: %s", outputPath, err)), nil
+	}
+	if err = p.fileRoot.Write(outputPath, buf.Bytes(), 0644); err != nil {
+		return api.NewFailedResponse(fmt.Sprintf("write file %s failed: %s", outputPath, err)), nil
+	}
+
+	p.logger.Infow("chart rendered", "type", opt.Type, "format", format, "output", outputPath,
+		"series", len(ds.Series), "points", len(ds.Labels))
+	return api.NewResponseWithResult(map[string]any{
+		"file_path": outputPath,
+		"format":    format,
+		"type":      opt.Type,
+		"width":     opt.Width,
+		"height":    opt.Height,
+		"series":    len(ds.Series),
+		"points":    len(ds.Labels),
+	}), nil
+}
+
+func (p *ChartPlugin) loadTable(request *api.Request) (*table, error) {
+	data := api.GetStringParameter("data", request, "")
+	csvPath := api.GetStringParameter("csv_path", request, "")
+	switch {
+	case data != "" && csvPath != "":
+		return nil, fmt.Errorf("only one of data or csv_path can be set")
+	case data != "":
+		tbl, err := parseJSONRows([]byte(data))
+		if err != nil {
+			return nil, fmt.Errorf("parse data failed: %s", err)
+		}
+		return tbl, nil
+	case csvPath != "":
+		content, err := p.fileRoot.Read(csvPath)
+		if err != nil {
+			return nil, fmt.Errorf("read csv file failed: %s", err)
+		}
+		tbl, err := parseCSV(content)
+		if err != nil {
+			return nil, fmt.Errorf("parse csv file failed: %s", err)
+		}
+		return tbl, nil
+	}
+	return nil, fmt.Errorf("data or csv_path is required")
+}
+
+func sizeParameter(key string, request *api.Request, defaultVal int) (int, error) {
+	raw := api.GetStringParameter(key, request, "")
+	if raw == "" {
+		return defaultVal, nil
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < minSize || n > maxSize {
+		return 0, fmt.Errorf("%s must be an integer between %d and %d", key, minSize, maxSize)
+	}
+	return n, nil
+}
@@ -0,0 +1,141 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package chart
+
+import (
+	"bytes"
+	"context"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/logger"
+	"github.com/basenana/plugin/types"
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) {
+	logger.SetLogger(zap.NewNop().Sugar())
+	os.Exit(m.Run())
+}
+
+const testRows = `[{"month":"Jan","visits":120,"signups":30},{"month":"Feb","visits":180,"signups":45},{"month":"Mar","visits":90,"signups":"60"}]`
+
+func newChartPlugin(workdir string) *ChartPlugin {
+	return NewChartPlugin(types.PluginCall{JobID: "job-1", WorkingPath: workdir}).(*ChartPlugin)
+}
+
+func runChart(t *testing.T, p *ChartPlugin, params map[string]any) *api.Response {
+	t.Helper()
+	resp, err := p.Run(context.Background(), &api.Request{Parameter: params})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	return resp
+}
+
+func TestChartPlugin_SVG(t *testing.T) {
+	workdir := t.TempDir()
+	p := newChartPlugin(workdir)
+
+	for _, typ := range []string{chartBar, chartLine, chartPie} {
+		resp := runChart(t, p, map[string]any{"type": typ, "data": testRows, "title": "Traffic & signups", "output_path": typ + ".svg"})
+		if !resp.IsSucceed {
+			t.Fatalf("%s: Run not succeed: %s", typ, resp.Message)
+		}
+		if resp.Results["format"] != formatSVG || resp.Results["points"] != 3 {
+			t.Errorf("%s: unexpected results %v", typ, resp.Results)
+		}
+		data, err := os.ReadFile(filepath.Join(workdir, typ+".svg"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		svg := string(data)
+		if !strings.HasPrefix(svg, "<svg") || !strings.Contains(svg, "Traffic &amp; signups") || !strings.Contains(svg, ">Feb<") && typ != chartPie {
+			t.Errorf("%s: unexpected svg %s", typ, svg)
+		}
+	}
+
+	resp := runChart(t, p, map[string]any{"type": chartBar, "data": testRows, "output_path": "bar.svg"})
+	if resp.Results["series"] != 2 {
+		t.Errorf("expected visits and signups series, got %v", resp.Results["series"])
+	}
+	resp = runChart(t, p, map[string]any{"type": chartPie, "data": testRows, "value_fields": "signups"})
+	if !resp.IsSucceed || resp.Results["file_path"] != "chart.svg" {
+		t.Errorf("unexpected pie results %+v", resp)
+	}
+	data, _ := os.ReadFile(filepath.Join(workdir, "chart.svg"))
+	if !strings.Contains(string(data), "Mar 44.4%") {
+		t.Errorf("expected pie legend with percentage, got %s", data)
+	}
+}
+
+func TestChartPlugin_PNGFromCSV(t *testing.T) {
+	workdir := t.TempDir()
+	csvData := "day,requests\nMon,\"1,200\"\nTue,800\nWed,\n"
+	if err := os.WriteFile(filepath.Join(workdir, "stats.csv"), []byte(csvData), 0644); err != nil {
+		t.Fatal(err)
+	}
+	p := newChartPlugin(workdir)
+
+	resp := runChart(t, p, map[string]any{"type": chartLine, "csv_path": "stats.csv", "output_path": "out/requests.png", "width": "400", "height": "300"})
+	if !resp.IsSucceed {
+		t.Fatalf("Run not succeed: %s", resp.Message)
+	}
+	if resp.Results["format"] != formatPNG {
+		t.Errorf("format should follow the output extension, got %v", resp.Results["format"])
+	}
+	data, err := os.ReadFile(filepath.Join(workdir, "out", "requests.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := img.Bounds(); b.Dx() != 400 || b.Dy() != 300 {
+		t.Errorf("unexpected image size %v", b)
+	}
+}
+
+func TestChartPlugin_Errors(t *testing.T) {
+	p := newChartPlugin(t.TempDir())
+	cases := []struct {
+		params map[string]any
+		msg    string
+	}{
+		{map[string]any{"data": testRows}, "type is required"},
+		{map[string]any{"type": "radar", "data": testRows}, "unknown chart type"},
+		{map[string]any{"type": chartBar}, "data or csv_path is required"},
+		{map[string]any{"type": chartBar, "data": testRows, "csv_path": "a.csv"}, "only one of data or csv_path"},
+		{map[string]any{"type": chartBar, "data": `{"a": 1}`}, "parse data failed"},
+		{map[string]any{"type": chartBar, "data": testRows, "value_fields": "month"}, "column month is not numeric"},
+		{map[string]any{"type": chartBar, "data": testRows, "label_field": "day"}, "column day not found"},
+		{map[string]any{"type": chartBar, "data": testRows, "width": "50"}, "width must be an integer"},
+		{map[string]any{"type": chartBar, "data": testRows, "format": "gif"}, "unknown format"},
+		{map[string]any{"type": chartPie, "data": `[{"k":"a","v":-1}]`}, "must not be negative"},
+	}
+	for _, c := range cases {
+		resp := runChart(t, p, c.params)
+		if resp.IsSucceed || !strings.Contains(resp.Message, c.msg) {
+			t.Errorf("Run(%v) expected %q, got %+v", c.params, c.msg, resp)
+		}
+	}
+}
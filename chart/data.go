@@ -0,0 +1,226 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package chart
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// table is tabular input with ordered columns, cells are kept as text.
+type table struct {
+	columns []string
+	rows    [][]string
+}
+
+type series struct {
+	Name   string
+	Values []float64
+}
+
+type dataset struct {
+	Labels []string
+	Series []series
+}
+
+func parseCSV(data []byte) (*table, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) < 2 {
+		return nil, fmt.Errorf("csv needs a header row and at least one data row")
+	}
+	t := &table{columns: records[0]}
+	for _, record := range records[1:] {
+		row := make([]string, len(t.columns))
+		copy(row, record)
+		t.rows = append(t.rows, row)
+	}
+	return t, nil
+}
+
+// parseJSONRows decodes an array of objects, keeping the key order of the
+// first object as the column order.
+func parseJSONRows(data []byte) (*table, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('[') {
+		return nil, fmt.Errorf("expect a JSON array of objects")
+	}
+
+	t := &table{}
+	index := make(map[string]int)
+	for dec.More() {
+		if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+			return nil, fmt.Errorf("expect a JSON array of objects")
+		}
+		cells := make(map[int]string)
+		for dec.More() {
+			tok, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			key := tok.(string)
+			var val any
+			if err = dec.Decode(&val); err != nil {
+				return nil, err
+			}
+			i, ok := index[key]
+			if !ok {
+				i = len(t.columns)
+				index[key] = i
+				t.columns = append(t.columns, key)
+			}
+			cells[i] = cellText(val)
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		row := make([]string, 0, len(t.columns))
+		for i := range t.columns {
+			row = append(row, cells[i])
+		}
+		t.rows = append(t.rows, row)
+	}
+	if _, err := dec.Token(); err != nil && err != io.EOF {
+		return nil, err
+	}
+	if len(t.rows) == 0 {
+		return nil, fmt.Errorf("data has no rows")
+	}
+	for i := range t.rows {
+		for len(t.rows[i]) < len(t.columns) {
+			t.rows[i] = append(t.rows[i], "")
+		}
+	}
+	return t, nil
+}
+
+func cellText(val any) string {
+	switch v := val.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	default:
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+}
+
+// dataset picks the label column and numeric value columns. Without explicit
+// fields the first column is the label and every other numeric column a series.
+func (t *table) dataset(labelField string, valueFields []string) (*dataset, error) {
+	column := func(name string) (int, error) {
+		for i, c := range t.columns {
+			if c == name {
+				return i, nil
+			}
+		}
+		return -1, fmt.Errorf("column %s not found", name)
+	}
+
+	labelIdx := 0
+	if labelField != "" {
+		i, err := column(labelField)
+		if err != nil {
+			return nil, err
+		}
+		labelIdx = i
+	}
+
+	var valueIdx []int
+	if len(valueFields) > 0 {
+		for _, f := range valueFields {
+			i, err := column(f)
+			if err != nil {
+				return nil, err
+			}
+			if !t.numeric(i) {
+				return nil, fmt.Errorf("column %s is not numeric", f)
+			}
+			valueIdx = append(valueIdx, i)
+		}
+	} else {
+		for i := range t.columns {
+			if i != labelIdx && t.numeric(i) {
+				valueIdx = append(valueIdx, i)
+			}
+		}
+	}
+	if len(valueIdx) == 0 {
+		return nil, fmt.Errorf("no numeric column found")
+	}
+
+	ds := &dataset{}
+	for _, row := range t.rows {
+		ds.Labels = append(ds.Labels, row[labelIdx])
+	}
+	for _, i := range valueIdx {
+		s := series{Name: t.columns[i]}
+		for _, row := range t.rows {
+			v, _ := parseNumber(row[i])
+			s.Values = append(s.Values, v)
+		}
+		ds.Series = append(ds.Series, s)
+	}
+	return ds, nil
+}
+
+// numeric reports whether every non-empty cell of the column is a number.
+func (t *table) numeric(col int) bool {
+	var found bool
+	for _, row := range t.rows {
+		if strings.TrimSpace(row[col]) == "" {
+			continue
+		}
+		if _, ok := parseNumber(row[col]); !ok {
+			return false
+		}
+		found = true
+	}
+	return found
+}
+
+// parseNumber accepts plain numbers with optional thousands separators, currency
+// signs and a percent suffix. Empty cells count as zero.
+func parseNumber(s string) (float64, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, true
+	}
+	s = strings.TrimSuffix(s, "%")
+	s = strings.TrimLeft(s, "$€£¥")
+	s = strings.ReplaceAll(s, ",", "")
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, false
+	}
+	return v, true
+}
@@ -0,0 +1,70 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package chart
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseJSONRows(t *testing.T) {
+	tbl, err := parseJSONRows([]byte(`[{"name":"a","score":1.5},{"score":2,"name":"b","extra":true}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(tbl.columns, []string{"name", "score", "extra"}) {
+		t.Errorf("unexpected columns %v", tbl.columns)
+	}
+	if !reflect.DeepEqual(tbl.rows, [][]string{{"a", "1.5", ""}, {"b", "2", "true"}}) {
+		t.Errorf("unexpected rows %v", tbl.rows)
+	}
+
+	ds, err := tbl.dataset("", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ds.Labels, []string{"a", "b"}) || len(ds.Series) != 1 || ds.Series[0].Name != "score" {
+		t.Errorf("unexpected dataset %+v", ds)
+	}
+}
+
+func TestParseNumber(t *testing.T) {
+	for in, want := range map[string]float64{"1,200": 1200, "$3.5": 3.5, "45%": 45, "": 0, "-2": -2} {
+		if got, ok := parseNumber(in); !ok || got != want {
+			t.Errorf("parseNumber(%q) = %v, %v", in, got, ok)
+		}
+	}
+	for _, in := range []string{"abc", "NaN", "Inf"} {
+		if _, ok := parseNumber(in); ok {
+			t.Errorf("parseNumber(%q) should fail", in)
+		}
+	}
+}
+
+func TestNiceTicks(t *testing.T) {
+	ticks, step := niceTicks(0, 47, 5)
+	if step != 10 || !reflect.DeepEqual(ticks, []float64{0, 10, 20, 30, 40, 50}) {
+		t.Errorf("unexpected ticks %v step %v", ticks, step)
+	}
+	ticks, step = niceTicks(-3, 0.8, 5)
+	if step != 1 || ticks[0] != -3 || ticks[len(ticks)-1] != 1 {
+		t.Errorf("unexpected ticks %v step %v", ticks, step)
+	}
+	if got := formatTick(0.25, 0.05); got != "0.25" {
+		t.Errorf("formatTick() = %s", got)
+	}
+}
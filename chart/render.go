@@ -0,0 +1,257 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package chart
+
+import (
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+	"strconv"
+	"unicode/utf8"
+)
+
+const (
+	chartBar  = "bar"
+	chartLine = "line"
+	chartPie  = "pie"
+
+	titleSize = 16
+	labelSize = 11
+
+	maxLabelRunes = 14
+)
+
+const (
+	anchorStart  = "start"
+	anchorMiddle = "middle"
+	anchorEnd    = "end"
+)
+
+var (
+	colorBackground = color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+	colorText       = color.RGBA{R: 0x33, G: 0x33, B: 0x33, A: 0xff}
+	colorAxis       = color.RGBA{R: 0x88, G: 0x88, B: 0x88, A: 0xff}
+	colorGrid       = color.RGBA{R: 0xe5, G: 0xe5, B: 0xe5, A: 0xff}
+
+	palette = []color.RGBA{
+		{R: 0x4e, G: 0x79, B: 0xa7, A: 0xff},
+		{R: 0xf2, G: 0x8e, B: 0x2b, A: 0xff},
+		{R: 0xe1, G: 0x57, B: 0x59, A: 0xff},
+		{R: 0x76, G: 0xb7, B: 0xb2, A: 0xff},
+		{R: 0x59, G: 0xa1, B: 0x4f, A: 0xff},
+		{R: 0xed, G: 0xc9, B: 0x48, A: 0xff},
+		{R: 0xb0, G: 0x7a, B: 0xa1, A: 0xff},
+		{R: 0x9c, G: 0x75, B: 0x5f, A: 0xff},
+	}
+)
+
+type point struct {
+	X, Y float64
+}
+
+// canvas is the drawing surface shared by the SVG and PNG outputs. Angles are
+// in radians clockwise from 12 o'clock, text y is the baseline.
+type canvas interface {
+	rect(x, y, w, h float64, fill color.RGBA)
+	polyline(points []point, stroke color.RGBA, width float64)
+	circle(cx, cy, r float64, fill color.RGBA)
+	wedge(cx, cy, r, start, end float64, fill color.RGBA)
+	text(x, y float64, s string, size float64, anchor string, fill color.RGBA)
+	encode(w io.Writer) error
+}
+
+type chartOption struct {
+	Type   string
+	Title  string
+	Width  int
+	Height int
+}
+
+func render(c canvas, opt chartOption, ds *dataset) error {
+	c.rect(0, 0, float64(opt.Width), float64(opt.Height), colorBackground)
+	top := 20.0
+	if opt.Title != "" {
+		c.text(float64(opt.Width)/2, 28, opt.Title, titleSize, anchorMiddle, colorText)
+		top = 48
+	}
+	switch opt.Type {
+	case chartBar, chartLine:
+		return renderAxes(c, opt, ds, top)
+	case chartPie:
+		return renderPie(c, opt, ds, top)
+	}
+	return fmt.Errorf("unknown chart type: %s", opt.Type)
+}
+
+func renderAxes(c canvas, opt chartOption, ds *dataset, top float64) error {
+	width, height := float64(opt.Width), float64(opt.Height)
+	if len(ds.Series) > 1 {
+		renderLegend(c, ds, 60, top)
+		top += 24
+	}
+	left, right, bottom := 64.0, width-20, height-48
+	if right-left < 50 || bottom-top < 50 {
+		return fmt.Errorf("chart size %dx%d is too small", opt.Width, opt.Height)
+	}
+
+	lo, hi := 0.0, 0.0
+	for _, s := range ds.Series {
+		for _, v := range s.Values {
+			lo, hi = math.Min(lo, v), math.Max(hi, v)
+		}
+	}
+	ticks, step := niceTicks(lo, hi, 5)
+	lo, hi = ticks[0], ticks[len(ticks)-1]
+	y := func(v float64) float64 { return bottom - (v-lo)/(hi-lo)*(bottom-top) }
+
+	for _, t := range ticks {
+		c.polyline([]point{{left, y(t)}, {right, y(t)}}, colorGrid, 1)
+		c.text(left-6, y(t)+4, formatTick(t, step), labelSize, anchorEnd, colorText)
+	}
+	c.polyline([]point{{left, top}, {left, bottom}}, colorAxis, 1)
+	c.polyline([]point{{left, y(0)}, {right, y(0)}}, colorAxis, 1)
+
+	n := len(ds.Labels)
+	slot := (right - left) / float64(n)
+	labelEvery := int(math.Ceil(float64(n) * 7 * maxLabelRunes / 2 / (right - left)))
+	if labelEvery < 1 {
+		labelEvery = 1
+	}
+	for i, label := range ds.Labels {
+		if i%labelEvery == 0 {
+			c.text(left+slot*(float64(i)+0.5), bottom+18, truncateLabel(label), labelSize, anchorMiddle, colorText)
+		}
+	}
+
+	if opt.Type == chartBar {
+		barWidth := slot * 0.8 / float64(len(ds.Series))
+		for si, s := range ds.Series {
+			fill := palette[si%len(palette)]
+			for i, v := range s.Values {
+				x := left + slot*float64(i) + slot*0.1 + barWidth*float64(si)
+				y0, y1 := y(0), y(v)
+				c.rect(x, math.Min(y0, y1), math.Max(barWidth-1, 1), math.Abs(y1-y0), fill)
+			}
+		}
+		return nil
+	}
+
+	for si, s := range ds.Series {
+		stroke := palette[si%len(palette)]
+		points := make([]point, 0, len(s.Values))
+		for i, v := range s.Values {
+			points = append(points, point{left + slot*(float64(i)+0.5), y(v)})
+		}
+		c.polyline(points, stroke, 2)
+		for _, p := range points {
+			c.circle(p.X, p.Y, 3, stroke)
+		}
+	}
+	return nil
+}
+
+func renderPie(c canvas, opt chartOption, ds *dataset, top float64) error {
+	values := ds.Series[0].Values
+	var total float64
+	for _, v := range values {
+		if v < 0 {
+			return fmt.Errorf("pie chart values must not be negative")
+		}
+		total += v
+	}
+	if total == 0 {
+		return fmt.Errorf("pie chart values sum to zero")
+	}
+
+	width, height := float64(opt.Width), float64(opt.Height)
+	legendWidth := 7.0*maxLabelRunes + 80
+	r := math.Min(width-legendWidth-60, height-top-20) / 2
+	if r < 20 {
+		return fmt.Errorf("chart size %dx%d is too small", opt.Width, opt.Height)
+	}
+	cx, cy := 30+r, top+(height-top-20)/2+10
+
+	start := 0.0
+	for i, v := range values {
+		if v == 0 {
+			continue
+		}
+		end := start + v/total*2*math.Pi
+		c.wedge(cx, cy, r, start, end, palette[i%len(palette)])
+		start = end
+	}
+
+	x := cx + r + 30
+	for i, label := range ds.Labels {
+		ly := top + 10 + float64(i)*20
+		if ly > height-10 {
+			break
+		}
+		c.rect(x, ly-10, 12, 12, palette[i%len(palette)])
+		text := fmt.Sprintf("%s %.1f%%", truncateLabel(label), values[i]/total*100)
+		c.text(x+18, ly, text, labelSize, anchorStart, colorText)
+	}
+	return nil
+}
+
+func renderLegend(c canvas, ds *dataset, x, y float64) {
+	for i, s := range ds.Series {
+		c.rect(x, y-10, 12, 12, palette[i%len(palette)])
+		c.text(x+18, y, truncateLabel(s.Name), labelSize, anchorStart, colorText)
+		x += 18 + 7*float64(utf8.RuneCountInString(truncateLabel(s.Name))) + 20
+	}
+}
+
+// niceTicks spreads about n ticks over [lo, hi] on multiples of 1, 2 or 5.
+func niceTicks(lo, hi float64, n int) ([]float64, float64) {
+	if hi == lo {
+		hi = lo + 1
+	}
+	raw := (hi - lo) / float64(n)
+	mag := math.Pow(10, math.Floor(math.Log10(raw)))
+	step := mag * 10
+	for _, m := range []float64{1, 2, 5, 10} {
+		if raw <= m*mag {
+			step = m * mag
+			break
+		}
+	}
+	var ticks []float64
+	for t := math.Floor(lo/step) * step; t < hi+step/2; t += step {
+		ticks = append(ticks, math.Round(t/step)*step)
+	}
+	if ticks[len(ticks)-1] < hi {
+		ticks = append(ticks, ticks[len(ticks)-1]+step)
+	}
+	return ticks, step
+}
+
+func formatTick(v, step float64) string {
+	decimals := 0
+	if step < 1 {
+		decimals = int(math.Ceil(-math.Log10(step)))
+	}
+	return strconv.FormatFloat(v, 'f', decimals, 64)
+}
+
+func truncateLabel(s string) string {
+	if utf8.RuneCountInString(s) <= maxLabelRunes {
+		return s
+	}
+	return string([]rune(s)[:maxLabelRunes-2]) + ".."
+}
@@ -0,0 +1,88 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package chart
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+	"strings"
+)
+
+type svgCanvas struct {
+	width, height int
+	buf           bytes.Buffer
+}
+
+func newSVGCanvas(width, height int) *svgCanvas {
+	return &svgCanvas{width: width, height: height}
+}
+
+func (s *svgCanvas) rect(x, y, w, h float64, fill color.RGBA) {
+	fmt.Fprintf(&s.buf, `<rect x="%s" y="%s" width="%s" height="%s" fill="%s"/>`+"\n", num(x), num(y), num(w), num(h), hexColor(fill))
+}
+
+func (s *svgCanvas) polyline(points []point, stroke color.RGBA, width float64) {
+	coords := make([]string, 0, len(points))
+	for _, p := range points {
+		coords = append(coords, num(p.X)+","+num(p.Y))
+	}
+	fmt.Fprintf(&s.buf, `<polyline points="%s" fill="none" stroke="%s" stroke-width="%s" stroke-linejoin="round"/>`+"\n",
+		strings.Join(coords, " "), hexColor(stroke), num(width))
+}
+
+func (s *svgCanvas) circle(cx, cy, r float64, fill color.RGBA) {
+	fmt.Fprintf(&s.buf, `<circle cx="%s" cy="%s" r="%s" fill="%s"/>`+"\n", num(cx), num(cy), num(r), hexColor(fill))
+}
+
+func (s *svgCanvas) wedge(cx, cy, r, start, end float64, fill color.RGBA) {
+	if end-start >= 2*math.Pi-1e-9 {
+		s.circle(cx, cy, r, fill)
+		return
+	}
+	x0, y0 := cx+r*math.Sin(start), cy-r*math.Cos(start)
+	x1, y1 := cx+r*math.Sin(end), cy-r*math.Cos(end)
+	large := 0
+	if end-start > math.Pi {
+		large = 1
+	}
+	fmt.Fprintf(&s.buf, `<path d="M%s,%s L%s,%s A%s,%s 0 %d 1 %s,%s Z" fill="%s" stroke="#ffffff" stroke-width="1"/>`+"\n",
+		num(cx), num(cy), num(x0), num(y0), num(r), num(r), large, num(x1), num(y1), hexColor(fill))
+}
+
+func (s *svgCanvas) text(x, y float64, text string, size float64, anchor string, fill color.RGBA) {
+	fmt.Fprintf(&s.buf, `<text x="%s" y="%s" font-size="%s" text-anchor="%s" fill="%s">`, num(x), num(y), num(size), anchor, hexColor(fill))
+	_ = xml.EscapeText(&s.buf, []byte(text))
+	s.buf.WriteString("</text>\n")
+}
+
+func (s *svgCanvas) encode(w io.Writer) error {
+	_, err := fmt.Fprintf(w, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif">`+"\n%s</svg>\n",
+		s.width, s.height, s.width, s.height, s.buf.String())
+	return err
+}
+
+func num(v float64) string {
+	return fmt.Sprintf("%.1f", v)
+}
+
+func hexColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
@@ -23,6 +23,8 @@ import (
 	"encoding/hex"
 	"fmt"
 	"io"
+	"io/fs"
+	"path/filepath"
 
 	"github.com/basenana/plugin/api"
 	"github.com/basenana/plugin/logger"
@@ -52,10 +54,24 @@ var PluginSpec = types.PluginSpec{
 	Parameters: []types.ParameterSpec{
 		{
 			Name:        "file_path",
-			Required:    true,
-			Description: "Path to file",
+			Required:    false,
+			Description: "Path to file, required unless dir_path is set",
+		},
+		{
+			Name:        "dir_path",
+			Required:    false,
+			Description: "Hash every regular file below this directory instead of file_path",
+		},
+		{
+			Name:        "index_path",
+			Required:    false,
+			Description: "JSON index of path, size, mtime and hash; unchanged files reuse the cached hash",
 		},
 	},
+	Example: &types.PluginExample{
+		Request: map[string]any{"file_path": "report.pdf"},
+		Results: map[string]any{"hash": "d41d8cd98f00b204e9800998ecf8427e"},
+	},
 }
 
 type ChecksumPlugin struct {
@@ -72,7 +88,7 @@ func NewChecksumPlugin(ps types.PluginCall) types.Plugin {
 	return &ChecksumPlugin{
 		logger:    logger.NewPluginLogger(pluginName, ps.JobID),
 		algorithm: algorithm,
-		fileRoot:  utils.NewFileAccess(ps.WorkingPath),
+		fileRoot:  utils.NewFileAccess(ps.WorkingPath, ps.JobWorkingPath),
 	}
 }
 
@@ -90,26 +106,167 @@ func (p *ChecksumPlugin) Version() string {
 
 func (p *ChecksumPlugin) Run(ctx context.Context, request *api.Request) (*api.Response, error) {
 	filePath := api.GetStringParameter("file_path", request, "")
+	dirPath := api.GetStringParameter("dir_path", request, "")
+	indexPath := api.GetStringParameter("index_path", request, "")
 
-	if filePath == "" {
+	if filePath == "" && dirPath == "" {
 		return api.NewFailedResponse("file_path is required"), nil
 	}
+	if filePath != "" && dirPath != "" {
+		return api.NewFailedResponse("file_path and dir_path are exclusive"), nil
+	}
 
-	p.logger.Infow("checksum started", "file_path", filePath, "algorithm", p.algorithm)
+	var index *hashIndex
+	if indexPath != "" {
+		var err error
+		index, err = loadHashIndex(p.fileRoot, indexPath, p.algorithm)
+		if index == nil {
+			return api.NewFailedResponse(err.Error()), nil
+		}
+		if err != nil {
+			p.logger.Warnw("hash index is corrupt, rebuild it", "index_path", indexPath, "error", err)
+		}
+	}
+
+	var (
+		results map[string]any
+		err     error
+	)
+	if dirPath != "" {
+		p.logger.Infow("checksum started", "dir_path", dirPath, "algorithm", p.algorithm)
+		results, err = p.hashDir(ctx, dirPath, indexPath, index)
+		if err != nil {
+			p.logger.Warnw("compute hashes failed", "dir_path", dirPath, "error", err)
+			return api.NewFailedResponse(err.Error()), nil
+		}
+		p.logger.Infow("checksum completed", "dir_path", dirPath, "total", results["total"], "hashed", results["hashed"], "reused", results["reused"])
+	} else {
+		p.logger.Infow("checksum started", "file_path", filePath, "algorithm", p.algorithm)
+		hash, cached, err := p.hashFile(filePath, index)
+		if err != nil {
+			p.logger.Warnw("compute hash failed", "file_path", filePath, "error", err)
+			return api.NewFailedResponse(err.Error()), nil
+		}
+		p.logger.Infow("checksum completed", "file_path", filePath, "hash", hash, "cached", cached)
+		results = map[string]any{
+			"hash": hash,
+		}
+		if index != nil {
+			results["cached"] = cached
+		}
+	}
+
+	if index != nil {
+		if err = index.save(p.fileRoot, indexPath); err != nil {
+			p.logger.Warnw("save hash index failed", "index_path", indexPath, "error", err)
+			return api.NewFailedResponse(err.Error()), nil
+		}
+	}
+
+	return api.NewResponseWithResult(results), nil
+}
 
+// hashFile returns the hash of the file, from the index when its size and mtime are unchanged.
+func (p *ChecksumPlugin) hashFile(filePath string, index *hashIndex) (string, bool, error) {
+	if index == nil {
+		hash, err := p.computeHash(filePath)
+		return hash, false, err
+	}
+	key, err := p.indexKey(filePath)
+	if err != nil {
+		return "", false, err
+	}
+	info, err := p.fileRoot.Stat(filePath)
+	if err != nil {
+		return "", false, fmt.Errorf("stat file failed: %w", err)
+	}
+	if hash, ok := index.lookup(key, info); ok {
+		return hash, true, nil
+	}
 	hash, err := p.computeHash(filePath)
 	if err != nil {
-		p.logger.Warnw("compute hash failed", "file_path", filePath, "error", err)
-		return api.NewFailedResponse(err.Error()), nil
+		return "", false, err
 	}
+	index.update(key, info, hash)
+	return hash, false, nil
+}
 
-	p.logger.Infow("checksum completed", "file_path", filePath, "hash", hash)
+// hashDir hashes every regular file below dirPath in lexical order, skipping the index itself.
+func (p *ChecksumPlugin) hashDir(ctx context.Context, dirPath, indexPath string, index *hashIndex) (map[string]any, error) {
+	absDir, err := p.fileRoot.GetAbsPath(dirPath)
+	if err != nil {
+		return nil, err
+	}
+	skip := map[string]bool{}
+	if indexPath != "" {
+		absIndex, err := p.fileRoot.GetAbsPath(indexPath)
+		if err != nil {
+			return nil, err
+		}
+		skip[absIndex] = true
+		skip[absIndex+".tmp"] = true
+	}
 
-	results := map[string]any{
-		"hash": hash,
+	var (
+		files  = make([]map[string]any, 0)
+		seen   = map[string]bool{}
+		hashed int
+		reused int
+	)
+	err = filepath.WalkDir(absDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() || skip[path] {
+			return nil
+		}
+		rel, err := p.indexKey(path)
+		if err != nil {
+			return err
+		}
+		hash, cached, err := p.hashFile(rel, index)
+		if err != nil {
+			return fmt.Errorf("hash %s failed: %w", rel, err)
+		}
+		if cached {
+			reused++
+		} else {
+			hashed++
+		}
+		seen[rel] = true
+		files = append(files, map[string]any{"path": rel, "hash": hash, "cached": cached})
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return api.NewResponseWithResult(results), nil
+	if index != nil {
+		dirKey, err := p.indexKey(absDir)
+		if err != nil {
+			return nil, err
+		}
+		index.prune(dirKey, seen)
+	}
+	return map[string]any{
+		"files":  files,
+		"total":  len(files),
+		"hashed": hashed,
+		"reused": reused,
+	}, nil
+}
+
+// indexKey is the path relative to the working path, so the index does not depend on
+// how the path was given.
+func (p *ChecksumPlugin) indexKey(path string) (string, error) {
+	absPath, err := p.fileRoot.GetAbsPath(path)
+	if err != nil {
+		return "", err
+	}
+	return p.fileRoot.RelPath(absPath)
 }
 
 func (p *ChecksumPlugin) computeHash(filePath string) (string, error) {
@@ -319,3 +319,118 @@ func TestChecksumPlugin_WorkdirIsolation(t *testing.T) {
 		t.Error("expected failure when accessing file outside workdir")
 	}
 }
+
+func TestChecksumPlugin_DirWithIndex(t *testing.T) {
+	for _, f := range []struct{ path, content string }{
+		{"tree/a.txt", "alpha"},
+		{"tree/sub/b.txt", "beta"},
+		{"tree/sub/c.txt", "gamma"},
+	} {
+		if err := os.MkdirAll(filepath.Join(testWorkdir, filepath.Dir(f.path)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := testFileAccess.Write(f.path, []byte(f.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	p := newChecksumPlugin(t, "md5")
+	run := func() map[string]any {
+		resp, err := p.Run(context.Background(), &api.Request{Parameter: map[string]any{
+			"dir_path":   "tree",
+			"index_path": "tree/.checksums.json",
+		}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !resp.IsSucceed {
+			t.Fatalf("expected success, got failure: %s", resp.Message)
+		}
+		return resp.Results
+	}
+
+	results := run()
+	if results["total"] != 3 || results["hashed"] != 3 || results["reused"] != 0 {
+		t.Errorf("unexpected first run %v", results)
+	}
+	files := results["files"].([]map[string]any)
+	beta := md5.Sum([]byte("beta"))
+	if files[1]["path"] != filepath.Join("tree", "sub", "b.txt") || files[1]["hash"] != hex.EncodeToString(beta[:]) {
+		t.Errorf("unexpected file entry %v", files[1])
+	}
+
+	// a cached hash is trusted while size and mtime are unchanged
+	index, err := loadHashIndex(testFileAccess, "tree/.checksums.json", "md5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := index.Files[filepath.Join("tree", "a.txt")]
+	entry.Hash = "cached"
+	index.Files[filepath.Join("tree", "a.txt")] = entry
+	if err = index.save(testFileAccess, "tree/.checksums.json"); err != nil {
+		t.Fatal(err)
+	}
+	if err = testFileAccess.Write("tree/sub/b.txt", []byte("beta, changed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err = testFileAccess.Remove("tree/sub/c.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	results = run()
+	if results["total"] != 2 || results["hashed"] != 1 || results["reused"] != 1 {
+		t.Errorf("unexpected second run %v", results)
+	}
+	files = results["files"].([]map[string]any)
+	if files[0]["hash"] != "cached" || files[0]["cached"] != true || files[1]["cached"] != false {
+		t.Errorf("unexpected files %v", files)
+	}
+	index, _ = loadHashIndex(testFileAccess, "tree/.checksums.json", "md5")
+	if _, ok := index.Files[filepath.Join("tree", "sub", "c.txt")]; ok || len(index.Files) != 2 {
+		t.Errorf("removed files should be pruned from the index: %v", index.Files)
+	}
+
+	// another algorithm does not reuse the index
+	resp, err := newChecksumPlugin(t, "sha256").Run(context.Background(), &api.Request{Parameter: map[string]any{
+		"file_path":  "tree/a.txt",
+		"index_path": "tree/.checksums.json",
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Results["cached"] != false || resp.Results["hash"] == "cached" {
+		t.Errorf("unexpected sha256 result %v", resp.Results)
+	}
+}
+
+func TestChecksumPlugin_FileWithIndex(t *testing.T) {
+	if err := testFileAccess.Write("indexed.txt", []byte("indexed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := testFileAccess.Write("broken-index.json", []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	p := newChecksumPlugin(t, "md5")
+	for i, want := range []bool{false, true} {
+		resp, err := p.Run(context.Background(), &api.Request{Parameter: map[string]any{
+			"file_path":  filepath.Join(testWorkdir, "indexed.txt"),
+			"index_path": "broken-index.json",
+		}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !resp.IsSucceed || resp.Results["cached"] != want {
+			t.Errorf("run %d: unexpected result %v %s", i, resp.Results, resp.Message)
+		}
+	}
+
+	resp, err := p.Run(context.Background(), &api.Request{Parameter: map[string]any{
+		"file_path": "indexed.txt",
+		"dir_path":  "tree",
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.IsSucceed {
+		t.Errorf("expected failure when file_path and dir_path are both set")
+	}
+}
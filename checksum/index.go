@@ -0,0 +1,106 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package checksum
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/basenana/plugin/utils"
+)
+
+const indexVersion = 1
+
+// hashIndex caches the hash of files by path. An entry is reused while the size and
+// modification time of the file are unchanged.
+type hashIndex struct {
+	Version   int                   `json:"version"`
+	Algorithm string                `json:"algorithm"`
+	Files     map[string]indexEntry `json:"files"`
+}
+
+type indexEntry struct {
+	Size  int64  `json:"size"`
+	MTime int64  `json:"mtime"`
+	Hash  string `json:"hash"`
+}
+
+func newHashIndex(algorithm string) *hashIndex {
+	return &hashIndex{Version: indexVersion, Algorithm: algorithm, Files: map[string]indexEntry{}}
+}
+
+// loadHashIndex reads the index, starting a new one when the file is missing. An index of
+// another algorithm or version is discarded; a corrupt one is reported so it can be rebuilt.
+func loadHashIndex(fileRoot *utils.FileAccess, indexPath, algorithm string) (*hashIndex, error) {
+	data, err := fileRoot.Read(indexPath)
+	if os.IsNotExist(err) {
+		return newHashIndex(algorithm), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read index failed: %w", err)
+	}
+	index := &hashIndex{}
+	if err = json.Unmarshal(data, index); err != nil {
+		return newHashIndex(algorithm), fmt.Errorf("parse index failed: %w", err)
+	}
+	if index.Version != indexVersion || index.Algorithm != algorithm || index.Files == nil {
+		return newHashIndex(algorithm), nil
+	}
+	return index, nil
+}
+
+func (idx *hashIndex) lookup(path string, info os.FileInfo) (string, bool) {
+	entry, ok := idx.Files[path]
+	if !ok || entry.Size != info.Size() || entry.MTime != info.ModTime().UnixNano() {
+		return "", false
+	}
+	return entry.Hash, true
+}
+
+func (idx *hashIndex) update(path string, info os.FileInfo, hash string) {
+	idx.Files[path] = indexEntry{Size: info.Size(), MTime: info.ModTime().UnixNano(), Hash: hash}
+}
+
+// prune drops the entries below dir that are not in keep.
+func (idx *hashIndex) prune(dir string, keep map[string]bool) {
+	prefix := filepath.Clean(dir) + string(filepath.Separator)
+	for path := range idx.Files {
+		if (dir == "." || strings.HasPrefix(path, prefix)) && !keep[path] {
+			delete(idx.Files, path)
+		}
+	}
+}
+
+// save writes the index through a temporary file so an interrupted run keeps the old one.
+func (idx *hashIndex) save(fileRoot *utils.FileAccess, indexPath string) error {
+	data, err := json.MarshalIndent(idx, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmpPath := indexPath + ".tmp"
+	if err = fileRoot.Write(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("write index failed: %w", err)
+	}
+	if err = fileRoot.Rename(tmpPath, indexPath); err != nil {
+		_ = fileRoot.Remove(tmpPath)
+		return fmt.Errorf("write index failed: %w", err)
+	}
+	return nil
+}
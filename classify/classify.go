@@ -0,0 +1,208 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package classify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/docloader"
+	"github.com/basenana/plugin/logger"
+	"github.com/basenana/plugin/types"
+	"github.com/basenana/plugin/utils"
+	"go.uber.org/zap"
+)
+
+const (
+	pluginName    = "classify"
+	pluginVersion = "1.0"
+
+	tagLicensePrefix     = "license:"
+	tagSensitivityPrefix = "sensitivity:"
+	tagCopyright         = "copyright"
+)
+
+var PluginSpec = types.PluginSpec{
+	Name:    pluginName,
+	Version: pluginVersion,
+	Type:    types.TypeProcess,
+	Dependencies: []types.Dependency{
+		{Kind: types.DependencyCapability, Name: types.CapabilityFS, Optional: true},
+	},
+	Parameters: []types.ParameterSpec{
+		{
+			Name:        "file_path",
+			Required:    true,
+			Description: "Document to classify: pdf, txt, md, html, epub, or any UTF-8 text file such as source code",
+		},
+		{
+			Name:        "markings",
+			Required:    false,
+			Description: "JSON array of extra confidentiality markings: {\"pattern\": regex, \"level\": public|internal|confidential|restricted, \"tag\": keyword}",
+		},
+		{
+			Name:        "default_markings",
+			Required:    false,
+			Default:     "true",
+			Description: "Also detect the built-in markings such as CONFIDENTIAL, internal use only and TLP labels",
+			Options:     []string{"true", "false"},
+		},
+		{
+			Name:        "entry_uri",
+			Required:    false,
+			Description: "Write the tags to the keywords of this NanaFS entry",
+		},
+	},
+}
+
+type ClassifyPlugin struct {
+	logger   *zap.SugaredLogger
+	fileRoot *utils.FileAccess
+}
+
+func NewClassifyPlugin(ps types.PluginCall) types.Plugin {
+	return &ClassifyPlugin{
+		logger:   logger.NewPluginLogger(pluginName, ps.JobID),
+		fileRoot: utils.NewFileAccess(ps.WorkingPath, ps.JobWorkingPath),
+	}
+}
+
+func (p *ClassifyPlugin) Name() string {
+	return pluginName
+}
+
+func (p *ClassifyPlugin) Type() types.PluginType {
+	return types.TypeProcess
+}
+
+func (p *ClassifyPlugin) Version() string {
+	return pluginVersion
+}
+
+func (p *ClassifyPlugin) Run(ctx context.Context, request *api.Request) (*api.Response, error) {
+	filePath := api.GetStringParameter("file_path", request, "")
+	if filePath == "" {
+		return api.NewFailedResponse("file_path is required"), nil
+	}
+	var rules []MarkingRule
+	if raw := api.GetStringParameter("markings", request, ""); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &rules); err != nil {
+			return api.NewFailedResponse(fmt.Sprintf("parse markings failed: %s", err)), nil
+		}
+	}
+	if api.GetBoolParameter("default_markings", request, true) {
+		rules = append(rules, defaultMarkings...)
+	}
+	markings, err := compileMarkings(rules)
+	if err != nil {
+		return api.NewFailedResponse(err.Error()), nil
+	}
+	entryURI := api.GetStringParameter("entry_uri", request, "")
+	if entryURI != "" && request.FS == nil {
+		return api.NewFailedResponse("file system is not available"), nil
+	}
+
+	text, err := p.loadText(ctx, filePath)
+	if err != nil {
+		p.logger.Warnw("load file failed", "file", filePath, "error", err)
+		return api.NewFailedResponse(fmt.Sprintf("load file %s failed: %s", filePath, err)), nil
+	}
+
+	res := Detect(text, markings)
+	tags := res.tags()
+	results := map[string]any{
+		"file_path":   filePath,
+		"licenses":    marshalList(res.Licenses),
+		"copyrights":  marshalList(res.Copyrights),
+		"markings":    marshalList(res.Markings),
+		"sensitivity": res.Sensitivity,
+		"tags":        tags,
+	}
+
+	if entryURI != "" {
+		props, err := request.FS.GetEntryProperties(ctx, entryURI)
+		if err != nil {
+			return api.NewFailedResponse(fmt.Sprintf("get entry %s properties failed: %s", entryURI, err)), nil
+		}
+		if props == nil {
+			props = &types.Properties{}
+		}
+		props.Keywords = mergeTags(props.Keywords, tags)
+		if err = request.FS.UpdateEntry(ctx, entryURI, "", *props); err != nil {
+			p.logger.Warnw("update entry failed", "entry_uri", entryURI, "error", err)
+			return api.NewFailedResponse(fmt.Sprintf("update entry %s failed: %s", entryURI, err)), nil
+		}
+		results["entry_uri"] = entryURI
+		results["keywords"] = props.Keywords
+	}
+
+	p.logger.Infow("file classified", "file", filePath, "licenses", len(res.Licenses),
+		"copyrights", len(res.Copyrights), "markings", len(res.Markings), "sensitivity", res.Sensitivity)
+	return api.NewResponseWithResult(results), nil
+}
+
+// loadText uses docloader for document formats and reads other files as UTF-8 text.
+func (p *ClassifyPlugin) loadText(ctx context.Context, filePath string) (string, error) {
+	absPath, err := p.fileRoot.GetAbsPath(filePath)
+	if err != nil {
+		return "", err
+	}
+	if parser, err := docloader.NewParser(absPath, nil); err == nil {
+		doc, err := parser.Load(logger.IntoContext(ctx, p.logger))
+		if err != nil {
+			return "", err
+		}
+		return doc.Content, nil
+	}
+
+	data, err := p.fileRoot.Read(filePath)
+	if err != nil {
+		return "", err
+	}
+	if !utf8.Valid(data) {
+		return "", fmt.Errorf("unsupported binary file")
+	}
+	return string(data), nil
+}
+
+// mergeTags replaces the tags of a previous classification and keeps the other keywords.
+func mergeTags(keywords, tags []string) []string {
+	classified := make(map[string]bool)
+	for _, t := range tags {
+		classified[t] = true
+	}
+	merged := make([]string, 0, len(keywords)+len(tags))
+	for _, k := range keywords {
+		if k == tagCopyright || strings.HasPrefix(k, tagLicensePrefix) || strings.HasPrefix(k, tagSensitivityPrefix) || classified[k] {
+			continue
+		}
+		merged = append(merged, k)
+	}
+	return append(merged, tags...)
+}
+
+func marshalList[T any](items []T) []map[string]any {
+	list := make([]map[string]any, 0, len(items))
+	for _, item := range items {
+		list = append(list, utils.MarshalMap(item))
+	}
+	return list
+}
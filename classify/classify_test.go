@@ -0,0 +1,150 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package classify
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/logger"
+	"github.com/basenana/plugin/types"
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) {
+	logger.SetLogger(zap.NewNop().Sugar())
+	os.Exit(m.Run())
+}
+
+type mockFS struct {
+	props   map[string]*types.Properties
+	updated map[string]types.Properties
+}
+
+func (m *mockFS) CreateGroupIfNotExists(ctx context.Context, parentURI, group string, properties types.Properties) error {
+	return nil
+}
+
+func (m *mockFS) SaveEntry(ctx context.Context, parentURI, name string, properties types.Properties, reader io.ReadCloser) error {
+	return nil
+}
+
+func (m *mockFS) UpdateEntry(ctx context.Context, entryURI, content string, properties types.Properties) error {
+	m.updated[entryURI] = properties
+	return nil
+}
+
+func (m *mockFS) GetEntryProperties(ctx context.Context, entryURI string) (*types.Properties, error) {
+	props, ok := m.props[entryURI]
+	if !ok {
+		return nil, errors.New("entry not found")
+	}
+	return props, nil
+}
+
+func (m *mockFS) Search(ctx context.Context, query string, filter types.SearchFilter) ([]types.Entry, error) {
+	return nil, nil
+}
+
+func newClassifyPlugin(t *testing.T, files map[string]string) *ClassifyPlugin {
+	workdir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(workdir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return NewClassifyPlugin(types.PluginCall{JobID: "test-job", WorkingPath: workdir}).(*ClassifyPlugin)
+}
+
+func TestClassifyPlugin_Run(t *testing.T) {
+	p := newClassifyPlugin(t, map[string]string{
+		"main.go":  apacheHeader + "\n// INTERNAL USE ONLY\n",
+		"memo.md":  "# Falcon roadmap\n\nCONFIDENTIAL\n",
+		"blob.bin": "\xff\xfe\x00binary",
+	})
+	fs := &mockFS{
+		props:   map[string]*types.Properties{"/docs/main.go": {Title: "main.go", Keywords: []string{"code", "license:MIT"}}},
+		updated: map[string]types.Properties{},
+	}
+
+	resp, err := p.Run(context.Background(), &api.Request{
+		Parameter: map[string]any{"file_path": "main.go", "entry_uri": "/docs/main.go"},
+		FS:        fs,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.IsSucceed {
+		t.Fatalf("Run not succeed: %s", resp.Message)
+	}
+	wantTags := []string{"license:Apache-2.0", "copyright", "sensitivity:internal"}
+	if !reflect.DeepEqual(resp.Results["tags"], wantTags) || resp.Results["sensitivity"] != levelInternal {
+		t.Errorf("unexpected results %v", resp.Results)
+	}
+	copyrights := resp.Results["copyrights"].([]map[string]any)
+	if len(copyrights) != 1 || copyrights[0]["holder"] != "NanaFS Authors" {
+		t.Errorf("unexpected copyrights %v", copyrights)
+	}
+	updated := fs.updated["/docs/main.go"]
+	if want := append([]string{"code"}, wantTags...); !reflect.DeepEqual(updated.Keywords, want) || updated.Title != "main.go" {
+		t.Errorf("unexpected updated properties %+v", updated)
+	}
+
+	resp, err = p.Run(context.Background(), &api.Request{Parameter: map[string]any{
+		"file_path":        "memo.md",
+		"markings":         `[{"pattern": "falcon", "level": "internal", "tag": "project:falcon"}]`,
+		"default_markings": "false",
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(resp.Results["tags"], []string{"sensitivity:internal", "project:falcon"}) {
+		t.Errorf("expected only the custom marking, got %v", resp.Results)
+	}
+	if _, ok := resp.Results["entry_uri"]; ok {
+		t.Errorf("entry should not be updated without entry_uri")
+	}
+
+	for want, params := range map[string]map[string]any{
+		"file_path is required":         {},
+		"parse markings failed":         {"file_path": "memo.md", "markings": "{"},
+		"unknown level":                 {"file_path": "memo.md", "markings": `[{"pattern": "x", "level": "secret"}]`},
+		"unsupported binary file":       {"file_path": "blob.bin"},
+		"get entry /missing properties": {"file_path": "memo.md", "entry_uri": "/missing"},
+		"load file missing.txt failed":  {"file_path": "missing.txt"},
+	} {
+		resp, err = p.Run(context.Background(), &api.Request{Parameter: params, FS: fs})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.IsSucceed || !strings.Contains(resp.Message, want) {
+			t.Errorf("expected failure %q, got %+v", want, resp)
+		}
+	}
+
+	resp, _ = p.Run(context.Background(), &api.Request{Parameter: map[string]any{"file_path": "memo.md", "entry_uri": "/docs/memo.md"}})
+	if resp.IsSucceed || !strings.Contains(resp.Message, "file system is not available") {
+		t.Errorf("expected missing FS to fail, got %+v", resp)
+	}
+}
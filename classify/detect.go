@@ -0,0 +1,320 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package classify
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	levelPublic       = "public"
+	levelInternal     = "internal"
+	levelConfidential = "confidential"
+	levelRestricted   = "restricted"
+
+	// levelUnmarked is reported when no marking was found, it ranks below public.
+	levelUnmarked = "unmarked"
+
+	maxHolderLength = 120
+)
+
+var levelRank = map[string]int{
+	levelUnmarked:     0,
+	levelPublic:       1,
+	levelInternal:     2,
+	levelConfidential: 3,
+	levelRestricted:   4,
+}
+
+type License struct {
+	ID     string `json:"id"`
+	Source string `json:"source"` // spdx or text
+	Line   int    `json:"line,omitempty"`
+}
+
+type Copyright struct {
+	Years  string `json:"years,omitempty"`
+	Holder string `json:"holder,omitempty"`
+	Line   int    `json:"line"`
+}
+
+type Marking struct {
+	Text  string `json:"text"`
+	Level string `json:"level"`
+	Tag   string `json:"tag,omitempty"`
+	Line  int    `json:"line"`
+}
+
+// MarkingRule is a user defined confidentiality marking, Pattern is a case-insensitive regex.
+type MarkingRule struct {
+	Pattern string `json:"pattern"`
+	Level   string `json:"level"`
+	Tag     string `json:"tag,omitempty"`
+}
+
+type markingRule struct {
+	pattern *regexp.Regexp
+	level   string
+	tag     string
+}
+
+type Result struct {
+	Licenses    []License   `json:"licenses"`
+	Copyrights  []Copyright `json:"copyrights"`
+	Markings    []Marking   `json:"markings"`
+	Sensitivity string      `json:"sensitivity"`
+}
+
+var (
+	spdxPattern = regexp.MustCompile(`SPDX-License-Identifier:\s*([A-Za-z0-9.+\-() ]+?)\s*(?:\*/|-->)?\s*$`)
+
+	licenseTexts = []struct {
+		id      string
+		pattern *regexp.Regexp
+	}{
+		{"Apache-2.0", regexp.MustCompile(`(?i)Apache License,? Version 2\.0`)},
+		{"MIT", regexp.MustCompile(`(?i)Permission is hereby granted, free of charge, to any person obtaining a copy`)},
+		{"AGPL-3.0", regexp.MustCompile(`(?i)GNU Affero General Public License`)},
+		{"LGPL-3.0", regexp.MustCompile(`(?i)GNU Lesser General Public License.{0,200}?version 3`)},
+		{"LGPL-2.1", regexp.MustCompile(`(?i)GNU Lesser General Public License.{0,200}?version 2\.1`)},
+		{"GPL-3.0", regexp.MustCompile(`(?i)GNU General Public License.{0,200}?version 3`)},
+		{"GPL-2.0", regexp.MustCompile(`(?i)GNU General Public License.{0,200}?version 2`)},
+		{"MPL-2.0", regexp.MustCompile(`(?i)Mozilla Public License,? v(?:ersion|\.)? ?2\.0`)},
+		{"BSD-3-Clause", regexp.MustCompile(`(?i)Redistribution and use in source and binary forms.{0,1000}?Neither the name of`)},
+		{"ISC", regexp.MustCompile(`(?i)Permission to use, copy, modify, and(?:/or)? distribute this software for any purpose with or without fee`)},
+		{"Unlicense", regexp.MustCompile(`(?i)This is free and unencumbered software released into the public domain`)},
+		{"CC0-1.0", regexp.MustCompile(`(?i)CC0 1\.0 Universal`)},
+	}
+	bsdPattern = regexp.MustCompile(`(?i)Redistribution and use in source and binary forms`)
+	ccPattern  = regexp.MustCompile(`(?i)Creative Commons (Attribution(?:[- ](?:ShareAlike|NonCommercial|NoDerivatives|NoDerivs))*) (\d\.\d)`)
+
+	commentPrefix = regexp.MustCompile(`^\s*(?:/\*+|\*+/?|//+|#+|--|;+|%+|<!--|'|rem\b)?\s*`)
+	commentSuffix = regexp.MustCompile(`\s*(?:\*+/|-->)\s*$`)
+	yearsPattern  = regexp.MustCompile(`^(\d{4}(?:\s*(?:[-–,]|to)\s*\d{4})*)`)
+	rightsPattern = regexp.MustCompile(`(?i)[,.]?\s*all rights reserved\.?`)
+
+	defaultMarkings = []MarkingRule{
+		{Pattern: `\bTLP:\s?RED\b`, Level: levelRestricted},
+		{Pattern: `\bTOP SECRET\b`, Level: levelRestricted},
+		{Pattern: `\bstrictly confidential\b`, Level: levelRestricted},
+		{Pattern: `(?-i:\bRESTRICTED\b)`, Level: levelRestricted},
+		{Pattern: `\bTLP:\s?AMBER(?:\+STRICT)?\b`, Level: levelConfidential},
+		{Pattern: `(?-i:\bCONFIDENTIAL\b)`, Level: levelConfidential},
+		{Pattern: `\b(?:company|proprietary and|business) confidential\b`, Level: levelConfidential},
+		{Pattern: `\bconfidential (?:and|&) proprietary\b`, Level: levelConfidential},
+		{Pattern: `\b(?:do not|don't) (?:distribute|forward|share)\b`, Level: levelConfidential},
+		{Pattern: `\bnot for (?:public )?distribution\b`, Level: levelConfidential},
+		{Pattern: `\bunder NDA\b`, Level: levelConfidential},
+		{Pattern: `\bTLP:\s?GREEN\b`, Level: levelInternal},
+		{Pattern: `\b(?:for )?internal use only\b`, Level: levelInternal},
+		{Pattern: `\binternal only\b`, Level: levelInternal},
+		{Pattern: `\bTLP:\s?(?:CLEAR|WHITE)\b`, Level: levelPublic},
+		{Pattern: `\bapproved for public release\b`, Level: levelPublic},
+	}
+)
+
+func compileMarkings(rules []MarkingRule) ([]markingRule, error) {
+	compiled := make([]markingRule, 0, len(rules))
+	for i, r := range rules {
+		if _, ok := levelRank[r.Level]; !ok || r.Level == levelUnmarked {
+			return nil, fmt.Errorf("marking %d: unknown level %q, expect public, internal, confidential or restricted", i+1, r.Level)
+		}
+		if strings.TrimSpace(r.Pattern) == "" {
+			return nil, fmt.Errorf("marking %d: pattern is required", i+1)
+		}
+		re, err := regexp.Compile("(?i)" + r.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("marking %d: invalid pattern: %s", i+1, err)
+		}
+		compiled = append(compiled, markingRule{pattern: re, level: r.Level, tag: r.Tag})
+	}
+	return compiled, nil
+}
+
+// Detect scans the text for SPDX identifiers and license texts, copyright notices and
+// confidentiality markings. Sensitivity is the highest level of the markings found.
+func Detect(text string, markings []markingRule) Result {
+	res := Result{Licenses: []License{}, Copyrights: []Copyright{}, Markings: []Marking{}, Sensitivity: levelUnmarked}
+
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	stripped := make([]string, len(lines))
+	seenLicense := make(map[string]bool)
+	seenCopyright := make(map[string]bool)
+	for i, line := range lines {
+		stripped[i] = strings.TrimSpace(commentSuffix.ReplaceAllString(commentPrefix.ReplaceAllString(line, ""), ""))
+
+		if m := spdxPattern.FindStringSubmatch(line); m != nil {
+			for _, id := range spdxIDs(m[1]) {
+				if !seenLicense[id] {
+					seenLicense[id] = true
+					res.Licenses = append(res.Licenses, License{ID: id, Source: "spdx", Line: i + 1})
+				}
+			}
+		}
+		if c, ok := parseCopyright(stripped[i]); ok {
+			key := strings.ToLower(c.Years + "|" + c.Holder)
+			if !seenCopyright[key] {
+				seenCopyright[key] = true
+				c.Line = i + 1
+				res.Copyrights = append(res.Copyrights, c)
+			}
+		}
+		var spans [][]int
+		for _, rule := range markings {
+			if loc := rule.pattern.FindStringIndex(line); loc != nil && !overlaps(spans, loc) {
+				spans = append(spans, loc)
+				res.Markings = append(res.Markings, Marking{Text: line[loc[0]:loc[1]], Level: rule.level, Tag: rule.tag, Line: i + 1})
+				if levelRank[rule.level] > levelRank[res.Sensitivity] {
+					res.Sensitivity = rule.level
+				}
+			}
+		}
+	}
+
+	// license texts span lines, match them on the comment-free text joined with single spaces
+	joined := strings.Join(strings.Fields(strings.Join(stripped, " ")), " ")
+	addText := func(id string) {
+		if !seenLicense[id] {
+			seenLicense[id] = true
+			res.Licenses = append(res.Licenses, License{ID: id, Source: "text"})
+		}
+	}
+	for _, lt := range licenseTexts {
+		if lt.pattern.MatchString(joined) {
+			addText(lt.id)
+		}
+	}
+	if bsdPattern.MatchString(joined) && !seenLicense["BSD-3-Clause"] {
+		addText("BSD-2-Clause")
+	}
+	for _, m := range ccPattern.FindAllStringSubmatch(joined, -1) {
+		addText(ccID(m[1], m[2]))
+	}
+	return res
+}
+
+// overlaps keeps "STRICTLY CONFIDENTIAL" from also counting as "CONFIDENTIAL", the rules
+// are ordered from the highest level down.
+func overlaps(spans [][]int, loc []int) bool {
+	for _, s := range spans {
+		if loc[0] < s[1] && s[0] < loc[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// spdxIDs splits an SPDX expression such as "(MIT OR Apache-2.0)" into license ids.
+func spdxIDs(expr string) []string {
+	var ids []string
+	for _, f := range strings.Fields(strings.NewReplacer("(", " ", ")", " ").Replace(expr)) {
+		switch strings.ToUpper(f) {
+		case "AND", "OR", "WITH":
+			continue
+		}
+		ids = append(ids, f)
+	}
+	return ids
+}
+
+func ccID(name, version string) string {
+	parts := []string{"CC", "BY"}
+	for _, w := range strings.FieldsFunc(strings.ToLower(name), func(r rune) bool { return r == '-' || r == ' ' }) {
+		switch w {
+		case "sharealike":
+			parts = append(parts, "SA")
+		case "noncommercial":
+			parts = append(parts, "NC")
+		case "noderivatives", "noderivs":
+			parts = append(parts, "ND")
+		}
+	}
+	return strings.Join(parts, "-") + "-" + version
+}
+
+// parseCopyright reads "Copyright (c) 2020-2024 Holder" style notices. A bare "copyright"
+// counts only when followed by a year or a (c)/© sign, so prose about copyright is ignored.
+func parseCopyright(line string) (Copyright, bool) {
+	lower := strings.ToLower(line)
+	idx, markLen := strings.Index(lower, "copyright"), len("copyright")
+	if idx < 0 {
+		if idx = strings.Index(line, "©"); idx < 0 {
+			return Copyright{}, false
+		}
+		markLen = len("©")
+	}
+
+	rest := strings.TrimSpace(line[idx+markLen:])
+	signed := markLen == len("©")
+	for {
+		switch {
+		case strings.HasPrefix(strings.ToLower(rest), "(c)"):
+			rest, signed = strings.TrimSpace(rest[3:]), true
+			continue
+		case strings.HasPrefix(rest, "©"):
+			rest, signed = strings.TrimSpace(rest[len("©"):]), true
+			continue
+		}
+		break
+	}
+
+	var c Copyright
+	if m := yearsPattern.FindString(rest); m != "" {
+		c.Years = m
+		rest = strings.TrimSpace(rest[len(m):])
+	} else if !signed {
+		return Copyright{}, false
+	}
+
+	rest = strings.TrimPrefix(strings.TrimPrefix(rest, ","), " ")
+	rest = strings.TrimPrefix(rest, "by ")
+	rest = strings.TrimSpace(rightsPattern.ReplaceAllString(rest, ""))
+	rest = strings.TrimRight(rest, " .,;")
+	if utf8.RuneCountInString(rest) > maxHolderLength {
+		rest = string([]rune(rest)[:maxHolderLength])
+	}
+	c.Holder = rest
+	if c.Years == "" && c.Holder == "" {
+		return Copyright{}, false
+	}
+	return c, true
+}
+
+// tags renders the findings as entry keywords.
+func (r Result) tags() []string {
+	var tags []string
+	for _, l := range r.Licenses {
+		tags = append(tags, tagLicensePrefix+l.ID)
+	}
+	if len(r.Copyrights) > 0 {
+		tags = append(tags, tagCopyright)
+	}
+	if r.Sensitivity != levelUnmarked {
+		tags = append(tags, tagSensitivityPrefix+r.Sensitivity)
+	}
+	custom := make(map[string]bool)
+	for _, m := range r.Markings {
+		if m.Tag != "" && !custom[m.Tag] {
+			custom[m.Tag] = true
+			tags = append(tags, m.Tag)
+		}
+	}
+	return tags
+}
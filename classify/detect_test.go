@@ -0,0 +1,146 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package classify
+
+import (
+	"reflect"
+	"testing"
+)
+
+const apacheHeader = `/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+*/
+
+package main
+`
+
+const mitLicense = `MIT License
+
+Copyright (c) 2019-2024 Jane Doe, All rights reserved.
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction.
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+`
+
+func defaultRules(t *testing.T) []markingRule {
+	t.Helper()
+	rules, err := compileMarkings(defaultMarkings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rules
+}
+
+func TestDetect_Licenses(t *testing.T) {
+	cases := map[string][]string{
+		apacheHeader: {"Apache-2.0"},
+		mitLicense:   {"MIT"},
+		"// SPDX-License-Identifier: (MIT OR Apache-2.0)\n":                           {"MIT", "Apache-2.0"},
+		"<!-- SPDX-License-Identifier: GPL-2.0-only WITH Classpath-exception-2.0 -->": {"GPL-2.0-only", "Classpath-exception-2.0"},
+		"# This program is free software; you can redistribute it under the terms of the\n# GNU General Public License as published by the Free Software Foundation, either\n# version 3 of the License, or any later version.": {"GPL-3.0"},
+		"Redistribution and use in source and binary forms, with or without modification,\nare permitted provided that the following conditions are met:":                                                                       {"BSD-2-Clause"},
+		"Redistribution and use in source and binary forms ... 3. Neither the name of the copyright holder":                                                                                                                     {"BSD-3-Clause"},
+		"This work is licensed under a Creative Commons Attribution-NonCommercial-ShareAlike 4.0 International License.":                                                                                                        {"CC-BY-NC-SA-4.0"},
+		"Just some text about licensing in general.": {},
+	}
+	for text, want := range cases {
+		res := Detect(text, nil)
+		got := []string{}
+		for _, l := range res.Licenses {
+			got = append(got, l.ID)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Detect(%q) licenses = %v, want %v", text, got, want)
+		}
+	}
+
+	res := Detect("package x\n// SPDX-License-Identifier: MIT\n", nil)
+	if res.Licenses[0].Source != "spdx" || res.Licenses[0].Line != 2 {
+		t.Errorf("unexpected spdx license %+v", res.Licenses[0])
+	}
+}
+
+func TestDetect_Copyrights(t *testing.T) {
+	res := Detect(apacheHeader+mitLicense+"\n© 2021 Example Corp.\n# Copyright by Nobody", nil)
+	want := []Copyright{
+		{Years: "2023", Holder: "NanaFS Authors", Line: 2},
+		{Years: "2019-2024", Holder: "Jane Doe", Line: 11},
+		{Years: "2021", Holder: "Example Corp", Line: 20},
+	}
+	if !reflect.DeepEqual(res.Copyrights, want) {
+		t.Errorf("copyrights = %+v, want %+v", res.Copyrights, want)
+	}
+}
+
+func TestDetect_Markings(t *testing.T) {
+	text := "Quarterly plan\nSTRICTLY CONFIDENTIAL\nThis is confidential information between us.\nFor internal use only\nTLP:AMBER"
+	res := Detect(text, defaultRules(t))
+	want := []Marking{
+		{Text: "STRICTLY CONFIDENTIAL", Level: levelRestricted, Line: 2},
+		{Text: "For internal use only", Level: levelInternal, Line: 4},
+		{Text: "TLP:AMBER", Level: levelConfidential, Line: 5},
+	}
+	if !reflect.DeepEqual(res.Markings, want) {
+		t.Errorf("markings = %+v, want %+v", res.Markings, want)
+	}
+	if res.Sensitivity != levelRestricted {
+		t.Errorf("expected restricted, got %s", res.Sensitivity)
+	}
+
+	if res = Detect("Nothing to see here", defaultRules(t)); res.Sensitivity != levelUnmarked || len(res.Markings) != 0 {
+		t.Errorf("expected unmarked, got %+v", res)
+	}
+}
+
+func TestCompileMarkings(t *testing.T) {
+	rules, err := compileMarkings([]MarkingRule{{Pattern: `project\s+falcon`, Level: levelConfidential, Tag: "project:falcon"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := Detect("Notes on Project  Falcon", rules)
+	if len(res.Markings) != 1 || res.Markings[0].Tag != "project:falcon" || res.Sensitivity != levelConfidential {
+		t.Errorf("unexpected custom marking result %+v", res)
+	}
+	if tags := res.tags(); !reflect.DeepEqual(tags, []string{"sensitivity:confidential", "project:falcon"}) {
+		t.Errorf("unexpected tags %v", tags)
+	}
+
+	for _, rule := range []MarkingRule{
+		{Pattern: "x", Level: "secret"},
+		{Pattern: "x", Level: levelUnmarked},
+		{Pattern: " ", Level: levelPublic},
+		{Pattern: "(", Level: levelPublic},
+	} {
+		if _, err = compileMarkings([]MarkingRule{rule}); err == nil {
+			t.Errorf("compileMarkings(%+v) expected error", rule)
+		}
+	}
+}
+
+func TestMergeTags(t *testing.T) {
+	got := mergeTags([]string{"work", "license:MIT", "copyright", "sensitivity:internal", "draft"}, []string{"license:Apache-2.0", "draft"})
+	if want := []string{"work", "license:Apache-2.0", "draft"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("mergeTags = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,179 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package plugin
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/basenana/plugin/types"
+)
+
+var (
+	ErrPluginDisabled = errors.New("PluginDisabled")
+
+	lookPath = exec.LookPath
+)
+
+// WithCapabilities declares the capabilities provided by the host. Without it,
+// capability dependencies are not checked by Init.
+func WithCapabilities(capabilities ...string) Option {
+	return func(m *manager) {
+		if m.capabilities == nil {
+			m.capabilities = make(map[string]struct{})
+		}
+		for _, c := range capabilities {
+			m.capabilities[c] = struct{}{}
+		}
+	}
+}
+
+type DependencyError struct {
+	Plugin     string
+	Dependency types.Dependency
+	Reason     string
+}
+
+func (e *DependencyError) Error() string {
+	return fmt.Sprintf("plugin %s depends on %s %s: %s", e.Plugin, e.Dependency.Kind, e.Dependency.Name, e.Reason)
+}
+
+// Init validates the dependency graph of all registered plugins in dependency order.
+// Plugins with a missing required dependency are disabled, and every problem is reported
+// in the returned error instead of at the first call.
+func (m *manager) Init() error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	for _, p := range m.plugins {
+		p.disable = false
+		p.disableReason = nil
+	}
+
+	var (
+		errs  []error
+		order = make([]string, 0, len(m.plugins))
+		state = make(map[string]int) // 0: unvisited, 1: visiting, 2: done
+		stack []string
+		visit func(name string)
+	)
+	visit = func(name string) {
+		p := m.plugins[name]
+		state[name] = 1
+		stack = append(stack, name)
+		for _, dep := range p.spec.Dependencies {
+			if dep.Kind != types.DependencyPlugin {
+				continue
+			}
+			if _, ok := m.plugins[dep.Name]; !ok {
+				continue
+			}
+			switch state[dep.Name] {
+			case 0:
+				visit(dep.Name)
+			case 1:
+				cycle := append(stackFrom(stack, dep.Name), dep.Name)
+				m.disable(p, &DependencyError{Plugin: name, Dependency: dep,
+					Reason: fmt.Sprintf("dependency cycle %s", strings.Join(cycle, " -> "))})
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[name] = 2
+		order = append(order, name)
+	}
+
+	names := make([]string, 0, len(m.plugins))
+	for name := range m.plugins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if state[name] == 0 {
+			visit(name)
+		}
+	}
+
+	for _, name := range order {
+		p := m.plugins[name]
+		for _, dep := range p.spec.Dependencies {
+			reason := m.checkDependency(dep)
+			if reason == "" {
+				continue
+			}
+			depErr := &DependencyError{Plugin: name, Dependency: dep, Reason: reason}
+			if dep.Optional {
+				m.logger.Warnw("optional plugin dependency missing", "plugin", name, "dependency", dep.Name, "reason", reason)
+				continue
+			}
+			m.disable(p, depErr)
+		}
+		if p.disable {
+			errs = append(errs, p.disableReason)
+		}
+	}
+
+	m.order = order
+	return errors.Join(errs...)
+}
+
+func (m *manager) checkDependency(dep types.Dependency) string {
+	switch dep.Kind {
+	case types.DependencyPlugin:
+		p, ok := m.plugins[dep.Name]
+		if !ok {
+			return "plugin is not registered"
+		}
+		if p.disable {
+			return fmt.Sprintf("plugin is disabled (%s)", p.disableReason)
+		}
+	case types.DependencyCapability:
+		if m.capabilities == nil {
+			return ""
+		}
+		if _, ok := m.capabilities[dep.Name]; !ok {
+			return "capability is not provided by the host, declare it with WithCapabilities once it is available"
+		}
+	case types.DependencyBinary:
+		if _, err := lookPath(dep.Name); err != nil {
+			return fmt.Sprintf("executable not found in PATH, install it or add its directory to PATH (%s)", err)
+		}
+	default:
+		return "unknown dependency kind"
+	}
+	return ""
+}
+
+func (m *manager) disable(p *pluginInfo, reason error) {
+	if p.disable {
+		return
+	}
+	p.disable = true
+	p.disableReason = reason
+	m.logger.Warnw("plugin disabled", "plugin", p.spec.Name, "reason", reason)
+}
+
+func stackFrom(stack []string, name string) []string {
+	for i := range stack {
+		if stack[i] == name {
+			return append([]string{}, stack[i:]...)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package plugin
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/types"
+)
+
+func registerDependent(m Manager, name string, deps ...types.Dependency) {
+	m.Register(types.PluginSpec{Name: name, Version: "1.0", Type: types.TypeProcess, Dependencies: deps}, func(ps types.PluginCall) types.Plugin {
+		return &workdirRecorder{ps: ps, succeed: true}
+	})
+}
+
+func TestManager_Init_BuiltinPlugins(t *testing.T) {
+	m := New(WithCapabilities(types.CapabilityFS, types.CapabilityStore, types.CapabilityNetwork, types.CapabilityLister))
+	if err := m.Init(); err != nil {
+		t.Fatalf("builtin plugins should initialize with all capabilities: %v", err)
+	}
+}
+
+func TestManager_Init_MissingCapability(t *testing.T) {
+	m := New(WithCapabilities(types.CapabilityNetwork, types.CapabilityLister))
+	err := m.Init()
+	if err == nil {
+		t.Fatal("expected error for plugins requiring fs")
+	}
+	var depErr *DependencyError
+	if !errors.As(err, &depErr) || depErr.Dependency.Name != types.CapabilityFS {
+		t.Errorf("expected fs dependency error, got %v", err)
+	}
+
+	_, err = m.Call(context.Background(), types.PluginCall{PluginName: "save"}, &api.Request{})
+	if !errors.Is(err, ErrPluginDisabled) {
+		t.Errorf("expected disabled plugin error, got %v", err)
+	}
+	if _, err = m.GetPlugin("webpack"); err != nil {
+		t.Errorf("plugins with satisfied dependencies should stay available: %v", err)
+	}
+}
+
+func TestManager_Init_PluginDependencies(t *testing.T) {
+	m := New()
+	registerDependent(m, "app", types.Dependency{Kind: types.DependencyPlugin, Name: "lib"})
+	registerDependent(m, "lib")
+	registerDependent(m, "orphan", types.Dependency{Kind: types.DependencyPlugin, Name: "missing"})
+	registerDependent(m, "orphan-user", types.Dependency{Kind: types.DependencyPlugin, Name: "orphan"})
+
+	err := m.Init()
+	if err == nil {
+		t.Fatal("expected error for missing plugin dependency")
+	}
+	if !strings.Contains(err.Error(), "plugin orphan depends on plugin missing: plugin is not registered") {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if !strings.Contains(err.Error(), "plugin orphan-user depends on plugin orphan: plugin is disabled") {
+		t.Errorf("expected transitive failure to be reported: %v", err)
+	}
+
+	if _, err = m.Call(context.Background(), types.PluginCall{PluginName: "app", WorkingPath: t.TempDir()}, &api.Request{}); err != nil {
+		t.Errorf("app should be callable: %v", err)
+	}
+
+	var appIdx, libIdx int
+	for i, spec := range m.ListPlugins() {
+		switch spec.Name {
+		case "app":
+			appIdx = i
+		case "lib":
+			libIdx = i
+		}
+	}
+	if libIdx > appIdx {
+		t.Errorf("expected lib to be listed before app")
+	}
+}
+
+func TestManager_Init_Cycle(t *testing.T) {
+	m := New()
+	registerDependent(m, "a", types.Dependency{Kind: types.DependencyPlugin, Name: "b"})
+	registerDependent(m, "b", types.Dependency{Kind: types.DependencyPlugin, Name: "a"})
+
+	err := m.Init()
+	if err == nil || !strings.Contains(err.Error(), "dependency cycle a -> b -> a") {
+		t.Fatalf("expected dependency cycle error, got %v", err)
+	}
+	for _, name := range []string{"a", "b"} {
+		if _, err = m.Call(context.Background(), types.PluginCall{PluginName: name}, &api.Request{}); !errors.Is(err, ErrPluginDisabled) {
+			t.Errorf("expected %s to be disabled, got %v", name, err)
+		}
+	}
+}
+
+func TestManager_Init_Binary(t *testing.T) {
+	defer func(orig func(string) (string, error)) { lookPath = orig }(lookPath)
+	lookPath = func(file string) (string, error) {
+		if file == "pdftotext" {
+			return "/usr/bin/pdftotext", nil
+		}
+		return "", exec.ErrNotFound
+	}
+
+	m := New()
+	registerDependent(m, "pdf", types.Dependency{Kind: types.DependencyBinary, Name: "pdftotext"})
+	registerDependent(m, "ocr", types.Dependency{Kind: types.DependencyBinary, Name: "tesseract", Optional: true})
+	registerDependent(m, "video", types.Dependency{Kind: types.DependencyBinary, Name: "ffmpeg"})
+
+	err := m.Init()
+	if err == nil || !strings.Contains(err.Error(), "plugin video depends on binary ffmpeg") {
+		t.Fatalf("expected missing ffmpeg error, got %v", err)
+	}
+	if strings.Contains(err.Error(), "tesseract") {
+		t.Errorf("optional dependencies should not fail Init: %v", err)
+	}
+	for _, name := range []string{"pdf", "ocr"} {
+		if _, err = m.Call(context.Background(), types.PluginCall{PluginName: name, WorkingPath: t.TempDir()}, &api.Request{}); err != nil {
+			t.Errorf("expected %s to be callable, got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package plugin
+
+import (
+	"slices"
+
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/types"
+)
+
+// PluginDoc describes a registered plugin for help output of hosts and CLIs.
+type PluginDoc struct {
+	Spec            types.PluginSpec   `json:"spec"`
+	Enabled         bool               `json:"enabled"`
+	DisabledReason  string             `json:"disabled_reason,omitempty"`
+	Capabilities    PluginCapabilities `json:"capabilities"`
+	InitSchema      map[string]any     `json:"init_schema"`
+	ParameterSchema map[string]any     `json:"parameter_schema"`
+	ExampleRequest  map[string]any     `json:"example_request"` // Request.Parameter of a sample call
+	ExampleResponse *api.Response      `json:"example_response"`
+}
+
+// PluginCapabilities flags what a plugin needs from the host, from its declared dependencies.
+type PluginCapabilities struct {
+	Source   bool     `json:"source"`
+	FS       bool     `json:"fs"`
+	Store    bool     `json:"store"`
+	Network  bool     `json:"network"`
+	Lister   bool     `json:"lister"`
+	Binaries []string `json:"binaries,omitempty"`
+	Plugins  []string `json:"plugins,omitempty"`
+}
+
+// Describe documents the named plugin from its spec. Without a declared PluginSpec.Example the
+// example request only holds the required parameters and the example response has no results.
+func (m *manager) Describe(name string) (*PluginDoc, error) {
+	m.mux.RLock()
+	info, ok := m.plugins[name]
+	var doc *PluginDoc
+	if ok {
+		doc = &PluginDoc{Spec: info.spec, Enabled: !info.disable}
+		if info.disableReason != nil {
+			doc.DisabledReason = info.disableReason.Error()
+		}
+	}
+	m.mux.RUnlock()
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	doc.Capabilities = describeCapabilities(doc.Spec)
+	doc.InitSchema = parameterSchema(doc.Spec.InitParameters)
+	doc.ParameterSchema = parameterSchema(doc.Spec.Parameters)
+	doc.ExampleRequest, doc.ExampleResponse = describeExample(doc.Spec)
+	return doc, nil
+}
+
+func describeCapabilities(spec types.PluginSpec) PluginCapabilities {
+	c := PluginCapabilities{Source: spec.Type == types.TypeSource}
+	for _, dep := range spec.Dependencies {
+		switch dep.Kind {
+		case types.DependencyCapability:
+			switch dep.Name {
+			case types.CapabilityFS:
+				c.FS = true
+			case types.CapabilityStore:
+				c.Store = true
+			case types.CapabilityNetwork:
+				c.Network = true
+			case types.CapabilityLister:
+				c.Lister = true
+			}
+		case types.DependencyBinary:
+			c.Binaries = append(c.Binaries, dep.Name)
+		case types.DependencyPlugin:
+			c.Plugins = append(c.Plugins, dep.Name)
+		}
+	}
+	return c
+}
+
+// parameterSchema renders the parameters as a JSON Schema object. Values are passed as strings.
+func parameterSchema(params []types.ParameterSpec) map[string]any {
+	var (
+		properties = make(map[string]any, len(params))
+		required   []string
+	)
+	for _, p := range params {
+		property := map[string]any{"type": "string"}
+		if p.Description != "" {
+			property["description"] = p.Description
+		}
+		if p.Default != "" {
+			property["default"] = p.Default
+		}
+		if len(p.Options) > 0 {
+			property["enum"] = slices.Clone(p.Options)
+		}
+		properties[p.Name] = property
+		if p.Required {
+			required = append(required, p.Name)
+		}
+	}
+	schema := map[string]any{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func describeExample(spec types.PluginSpec) (map[string]any, *api.Response) {
+	params := make(map[string]any)
+	for _, p := range spec.Parameters {
+		if !p.Required {
+			continue
+		}
+		switch {
+		case p.Default != "":
+			params[p.Name] = p.Default
+		case len(p.Options) > 0:
+			params[p.Name] = p.Options[0]
+		default:
+			params[p.Name] = "<" + p.Name + ">"
+		}
+	}
+
+	resp := api.NewResponseWithResult(map[string]any{})
+	if spec.Example != nil {
+		for k, v := range spec.Example.Request {
+			params[k] = v
+		}
+		for k, v := range spec.Example.Results {
+			resp.Results[k] = v
+		}
+	}
+	return params, resp
+}
@@ -0,0 +1,109 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package plugin
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/basenana/plugin/types"
+)
+
+func TestManager_Describe(t *testing.T) {
+	m := New()
+	m.Register(types.PluginSpec{
+		Name:    "ocr",
+		Version: "1.0",
+		Type:    types.TypeProcess,
+		Dependencies: []types.Dependency{
+			{Kind: types.DependencyCapability, Name: types.CapabilityFS},
+			{Kind: types.DependencyBinary, Name: "tesseract"},
+			{Kind: types.DependencyPlugin, Name: "docloader"},
+		},
+		InitParameters: []types.ParameterSpec{{Name: "dpi", Default: "300"}},
+		Parameters: []types.ParameterSpec{
+			{Name: "file_path", Required: true, Description: "Image to read"},
+			{Name: "language", Required: true, Options: []string{"eng", "deu"}},
+			{Name: "psm", Default: "3"},
+		},
+	}, func(ps types.PluginCall) types.Plugin { return &workdirRecorder{ps: ps} })
+
+	doc, err := m.Describe("ocr")
+	if err != nil {
+		t.Fatalf("describe failed: %v", err)
+	}
+	if !doc.Enabled || doc.Spec.Name != "ocr" {
+		t.Errorf("unexpected doc %+v", doc)
+	}
+	want := PluginCapabilities{FS: true, Binaries: []string{"tesseract"}, Plugins: []string{"docloader"}}
+	if !reflect.DeepEqual(doc.Capabilities, want) {
+		t.Errorf("capabilities = %+v, want %+v", doc.Capabilities, want)
+	}
+
+	if !reflect.DeepEqual(doc.ParameterSchema["required"], []string{"file_path", "language"}) {
+		t.Errorf("unexpected required %v", doc.ParameterSchema["required"])
+	}
+	properties := doc.ParameterSchema["properties"].(map[string]any)
+	if lang := properties["language"].(map[string]any); !reflect.DeepEqual(lang["enum"], []string{"eng", "deu"}) {
+		t.Errorf("unexpected language schema %v", lang)
+	}
+	if psm := properties["psm"].(map[string]any); psm["default"] != "3" {
+		t.Errorf("unexpected psm schema %v", psm)
+	}
+	if _, ok := doc.InitSchema["required"]; ok {
+		t.Errorf("init schema should have no required parameters: %v", doc.InitSchema)
+	}
+
+	wantRequest := map[string]any{"file_path": "<file_path>", "language": "eng"}
+	if !reflect.DeepEqual(doc.ExampleRequest, wantRequest) {
+		t.Errorf("example request = %v, want %v", doc.ExampleRequest, wantRequest)
+	}
+	if !doc.ExampleResponse.IsSucceed || len(doc.ExampleResponse.Results) != 0 {
+		t.Errorf("unexpected example response %+v", doc.ExampleResponse)
+	}
+
+	if _, err = m.Describe("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected not found, got %v", err)
+	}
+}
+
+func TestManager_Describe_DeclaredExample(t *testing.T) {
+	m := New(WithCapabilities(types.CapabilityFS))
+	_ = m.Init()
+
+	doc, err := m.Describe("webpack")
+	if err != nil {
+		t.Fatalf("describe failed: %v", err)
+	}
+	if doc.Enabled || doc.DisabledReason == "" {
+		t.Errorf("webpack should be disabled without the network capability: %+v", doc)
+	}
+	if !doc.Capabilities.Network || doc.ExampleRequest["url"] != "https://example.com/article" || doc.ExampleResponse.Results["file_path"] == nil {
+		t.Errorf("unexpected webpack doc %+v", doc)
+	}
+	if _, err = json.Marshal(doc); err != nil {
+		t.Errorf("marshal doc failed: %v", err)
+	}
+
+	for _, spec := range m.ListPlugins() {
+		if _, err = m.Describe(spec.Name); err != nil {
+			t.Errorf("describe %s failed: %v", spec.Name, err)
+		}
+	}
+}
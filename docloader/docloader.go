@@ -65,6 +65,31 @@ var PluginSpec = types.PluginSpec{
 			Required:    false,
 			Description: "Site URL",
 		},
+		{
+			Name:        "include_outputs",
+			Required:    false,
+			Default:     "false",
+			Description: "Include code cell outputs when loading Jupyter notebooks",
+			Options:     []string{"true", "false"},
+		},
+		{
+			Name:        "readability",
+			Required:    false,
+			Default:     "false",
+			Description: "Keep only the main article body when loading HTML and webarchive files",
+			Options:     []string{"true", "false"},
+		},
+		{
+			Name:        "pdf_workers",
+			Required:    false,
+			Description: "Number of PDF pages parsed concurrently, defaults to the CPU count capped at 8",
+		},
+		{
+			Name:        "pdf_page_timeout",
+			Required:    false,
+			Default:     "30s",
+			Description: "Time limit for parsing one PDF page, slower pages are skipped",
+		},
 	},
 }
 
@@ -76,7 +101,7 @@ type DocLoader struct {
 func NewDocLoader(ps types.PluginCall) types.Plugin {
 	return &DocLoader{
 		logger:   logger.NewPluginLogger(PluginName, ps.JobID),
-		fileRoot: utils.NewFileAccess(ps.WorkingPath),
+		fileRoot: utils.NewFileAccess(ps.WorkingPath, ps.JobWorkingPath),
 	}
 }
 
@@ -92,7 +117,20 @@ func (d *DocLoader) Run(ctx context.Context, request *api.Request) (*api.Respons
 
 	d.logger.Infow("docloader started", "file_path", filePath)
 
-	doc, err := d.loadDocument(ctx, filePath)
+	parseOption := map[string]string{}
+	if api.GetBoolParameter("include_outputs", request, false) {
+		parseOption["include_outputs"] = "true"
+	}
+	if api.GetBoolParameter("readability", request, false) {
+		parseOption["readability"] = "true"
+	}
+	for _, key := range []string{"pdf_workers", "pdf_page_timeout"} {
+		if val := api.GetStringParameter(key, request, ""); val != "" {
+			parseOption[key] = val
+		}
+	}
+
+	doc, err := d.loadDocument(ctx, filePath, parseOption)
 	if err != nil {
 		d.logger.Warnw("load document failed", "file_path", filePath, "error", err)
 		return api.NewFailedResponse(fmt.Sprintf("load document %s error: %s", filePath, err.Error())), nil
@@ -120,41 +158,42 @@ func (d *DocLoader) Run(ctx context.Context, request *api.Request) (*api.Respons
 		}
 	}
 
+	var sourceSize int64
+	if info, err := d.fileRoot.Stat(filePath); err == nil {
+		sourceSize = info.Size()
+	}
+	warnings := checkDocumentQuality(doc, filepath.Ext(filePath), sourceSize)
+	warningMaps := make([]map[string]any, len(warnings))
+	for i := range warnings {
+		warningMaps[i] = utils.MarshalMap(warnings[i])
+	}
+	if len(warnings) > 0 {
+		d.logger.Infow("document quality warnings", "file_path", filePath, "warnings", warnings)
+	}
+
 	d.logger.Infow("docloader completed", "file_path", filePath, "title", doc.Properties.Title)
 
 	resp := api.NewResponseWithResult(map[string]any{
 		"file_path": filePath,
 		"document":  utils.MarshalMap(doc),
+		"warnings":  warningMaps,
 	})
 	return resp, nil
 }
 
-func (d *DocLoader) loadDocument(ctx context.Context, filePath string) (types.Document, error) {
+func (d *DocLoader) loadDocument(ctx context.Context, filePath string, parseOption map[string]string) (types.Document, error) {
 	entryPath, err := d.fileRoot.GetAbsPath(filePath)
 	if err != nil {
 		return types.Document{}, fmt.Errorf("invalid file path: %w", err)
 	}
 
 	var (
-		baseName    = filepath.Base(filePath)
-		fileExt     = filepath.Ext(baseName)
-		p           Parser
-		parseOption = map[string]string{}
+		baseName = filepath.Base(filePath)
+		fileExt  = filepath.Ext(baseName)
 	)
-
-	switch fileExt {
-	case ".pdf":
-		p = buildInLoaders[pdfParser](entryPath, parseOption)
-	case ".txt", ".md", ".markdown":
-		p = buildInLoaders[textParser](entryPath, parseOption)
-	case ".html", ".htm":
-		p = buildInLoaders[htmlParser](entryPath, parseOption)
-	case ".webarchive":
-		p = buildInLoaders[webArchiveParser](entryPath, parseOption)
-	case ".epub":
-		p = buildInLoaders[epubParser](entryPath, parseOption)
-	default:
-		return types.Document{}, fmt.Errorf("load %s file unsupported", fileExt)
+	p, err := NewParser(entryPath, parseOption)
+	if err != nil {
+		return types.Document{}, err
 	}
 
 	doc, err := p.Load(logger.IntoContext(ctx, d.logger))
@@ -179,6 +218,33 @@ type Parser interface {
 
 type parserBuilder func(docPath string, docOption map[string]string) Parser
 
+// NewParser returns the built-in parser for the file extension of docPath.
+func NewParser(docPath string, option map[string]string) (Parser, error) {
+	if option == nil {
+		option = map[string]string{}
+	}
+
+	fileExt := filepath.Ext(docPath)
+	switch fileExt {
+	case ".pdf":
+		return buildInLoaders[pdfParser](docPath, option), nil
+	case ".txt", ".md", ".markdown":
+		return buildInLoaders[textParser](docPath, option), nil
+	case ".html", ".htm":
+		return buildInLoaders[htmlParser](docPath, option), nil
+	case ".webarchive":
+		return buildInLoaders[webArchiveParser](docPath, option), nil
+	case ".epub":
+		return buildInLoaders[epubParser](docPath, option), nil
+	case ".ipynb":
+		return buildInLoaders[notebookParser](docPath, option), nil
+	case ".tex":
+		return buildInLoaders[latexParser](docPath, option), nil
+	default:
+		return nil, fmt.Errorf("load %s file unsupported", fileExt)
+	}
+}
+
 var (
 	buildInLoaders = map[string]parserBuilder{
 		textParser:       NewText,
@@ -186,5 +252,7 @@ var (
 		htmlParser:       NewHTML,
 		webArchiveParser: NewHTML,
 		epubParser:       NewEPUB,
+		notebookParser:   NewNotebook,
+		latexParser:      NewLaTeX,
 	}
 )
@@ -92,7 +92,7 @@ func TestEPUB_Load(t *testing.T) {
 
 	createTestEPUB(t, "test.epub", "Test Book", "Test Author", "Chapter content here")
 
-	doc, err := loader.loadDocument(context.Background(), "test.epub")
+	doc, err := loader.loadDocument(context.Background(), "test.epub", nil)
 	if err != nil {
 		t.Fatalf("Load failed: %v", err)
 	}
@@ -39,11 +39,12 @@ const (
 var metaContentRegex = regexp.MustCompile(`<meta\s+(?:[^>]*?\s+)?(name|property)=["']([^"']+)["'][^>]*?content=["']([^"']*)["'][^>]*?>`)
 
 type HTML struct {
-	docPath string
+	docPath     string
+	readability bool
 }
 
 func NewHTML(docPath string, option map[string]string) Parser {
-	return HTML{docPath: docPath}
+	return HTML{docPath: docPath, readability: option["readability"] == "true"}
 }
 
 func (h HTML) Load(ctx context.Context) (types.Document, error) {
@@ -52,6 +53,12 @@ func (h HTML) Load(ctx context.Context) (types.Document, error) {
 	if err != nil {
 		return types.Document{}, err
 	}
+	if h.readability {
+		// the page is kept as is when no article can be found
+		if article, err := utils.ExtractArticle(strings.NewReader(content), nil); err == nil && strings.TrimSpace(article.Content) != "" {
+			content = article.Content
+		}
+	}
 
 	if props.Abstract == "" {
 		props.Abstract = utils.GenerateContentAbstract(content)
@@ -205,3 +205,38 @@ func TestStripHTMLTags(t *testing.T) {
 		})
 	}
 }
+
+func TestHTML_Load_Readability(t *testing.T) {
+	htmlContent := `<!DOCTYPE html>
+<html>
+<head><title>Readable Page</title></head>
+<body>
+<header><a href="/">Site Logo</a></header>
+<nav><a href="/a">Navigation One</a><a href="/b">Navigation Two</a></nav>
+<article>
+<h1>Readable Page</h1>
+<p>The first paragraph of the article body describes the topic in detail.</p>
+<p>The second paragraph of the article body continues the discussion.</p>
+</article>
+<footer>Footer Copyright Text</footer>
+</body>
+</html>`
+
+	if err := testFileAccess.Write("readable.html", []byte(htmlContent), 0644); err != nil {
+		t.Fatalf("Failed to create test HTML file: %v", err)
+	}
+	absPath, _ := testFileAccess.GetAbsPath("readable.html")
+
+	doc, err := NewHTML(absPath, map[string]string{"readability": "true"}).Load(context.Background())
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if !strings.Contains(doc.Content, "first paragraph of the article body") {
+		t.Errorf("content should contain article body, got %q", doc.Content)
+	}
+	for _, noise := range []string{"Navigation One", "Footer Copyright Text", "Site Logo"} {
+		if strings.Contains(doc.Content, noise) {
+			t.Errorf("content should not contain %q, got %q", noise, doc.Content)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package docloader
+
+import (
+	"context"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/basenana/plugin/types"
+)
+
+const latexParser = "latex"
+
+var (
+	latexCommentRegex   = regexp.MustCompile(`(?m)(^|[^\\])%.*$`)
+	latexAbstractRegex  = regexp.MustCompile(`(?s)\\begin\{abstract\}(.*?)\\end\{abstract\}`)
+	latexDocumentRegex  = regexp.MustCompile(`(?s)\\begin\{document\}(.*?)(\\end\{document\}|$)`)
+	latexDropEnvRegex   = regexp.MustCompile(`(?s)\\begin\{(figure|table|tikzpicture|thebibliography)\*?\}.*?\\end\{(figure|table|tikzpicture|thebibliography)\*?\}`)
+	latexSectionRegex   = regexp.MustCompile(`\\(chapter|section|subsection|subsubsection|paragraph)\*?\{([^{}]*)\}`)
+	latexDropCmdRegex   = regexp.MustCompile(`\\(cite|citep|citet|label|ref|eqref|includegraphics|bibliography|bibliographystyle|maketitle|tableofcontents)\*?(\[[^\]]*\])*(\{[^{}]*\})?`)
+	latexKeepArgRegex   = regexp.MustCompile(`\\[a-zA-Z]+\*?(\[[^\]]*\])*\{([^{}]*)\}`)
+	latexBareCmdRegex   = regexp.MustCompile(`\\[a-zA-Z]+\*?`)
+	latexEnvMarkerRegex = regexp.MustCompile(`\\(begin|end)\{[^{}]*\}`)
+	latexBlankRegex     = regexp.MustCompile(`\n{3,}`)
+)
+
+type LaTeX struct {
+	docPath string
+}
+
+func NewLaTeX(docPath string, option map[string]string) Parser {
+	return LaTeX{docPath: docPath}
+}
+
+func (l LaTeX) Load(_ context.Context) (types.Document, error) {
+	data, err := os.ReadFile(l.docPath)
+	if err != nil {
+		return types.Document{}, err
+	}
+	source := latexCommentRegex.ReplaceAllString(string(data), "$1")
+
+	props := extractFileNameMetadata(l.docPath)
+	if title := latexCommandArg(source, "title"); title != "" {
+		props.Title = stripLaTeX(title)
+	}
+	if author := latexCommandArg(source, "author"); author != "" {
+		author = strings.ReplaceAll(author, `\and`, ",")
+		props.Author = strings.Join(strings.Fields(stripLaTeX(author)), " ")
+	}
+	if keywords := latexCommandArg(source, "keywords"); keywords != "" {
+		for _, k := range regexp.MustCompile(`[,;]`).Split(stripLaTeX(keywords), -1) {
+			if k = strings.TrimSpace(k); k != "" {
+				props.Keywords = append(props.Keywords, k)
+			}
+		}
+	}
+	if matches := latexAbstractRegex.FindStringSubmatch(source); matches != nil {
+		props.Abstract = strings.Join(strings.Fields(stripLaTeX(matches[1])), " ")
+	}
+
+	body := source
+	if matches := latexDocumentRegex.FindStringSubmatch(source); matches != nil {
+		body = matches[1]
+	}
+	content := stripLaTeX(body)
+
+	if props.Abstract == "" {
+		props = extractTextContentMetadata(content, props)
+	}
+	if props.PublishAt == 0 {
+		if info, err := os.Stat(l.docPath); err == nil {
+			props.PublishAt = info.ModTime().Unix()
+		}
+	}
+
+	return types.Document{
+		Content:    content,
+		Properties: props,
+	}, nil
+}
+
+// latexCommandArg returns the brace-balanced argument of the first \name{...}.
+func latexCommandArg(source, name string) string {
+	idx := strings.Index(source, `\`+name+`{`)
+	if idx < 0 {
+		return ""
+	}
+	start := idx + len(name) + 2
+	depth := 1
+	for i := start; i < len(source); i++ {
+		switch source[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return strings.TrimSpace(source[start:i])
+			}
+		}
+	}
+	return ""
+}
+
+func stripLaTeX(content string) string {
+	content = latexDropEnvRegex.ReplaceAllString(content, "")
+	content = latexSectionRegex.ReplaceAllString(content, "\n$2\n")
+	content = latexDropCmdRegex.ReplaceAllString(content, "")
+	content = latexEnvMarkerRegex.ReplaceAllString(content, "")
+	for i := 0; i < 5; i++ {
+		next := latexKeepArgRegex.ReplaceAllString(content, "$2")
+		if next == content {
+			break
+		}
+		content = next
+	}
+	content = latexBareCmdRegex.ReplaceAllString(content, "")
+	content = strings.NewReplacer(`\\`, "\n", `\%`, "%", `\&`, "&", `\_`, "_", `\#`, "#", `\$`, "$", "~", " ", "{", "", "}", "", "$", "").Replace(content)
+
+	lines := strings.Split(content, "\n")
+	for i := range lines {
+		lines[i] = strings.TrimSpace(lines[i])
+	}
+	content = latexBlankRegex.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")
+	return strings.TrimSpace(content)
+}
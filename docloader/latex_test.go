@@ -0,0 +1,73 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package docloader
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestLaTeX_Load(t *testing.T) {
+	source := `\documentclass{article}
+\usepackage{amsmath}
+\title{A Study of \emph{Things}}
+\author{Alice Smith \and Bob Jones}
+\begin{document}
+\maketitle
+\begin{abstract}
+We study things in \textbf{great} detail.
+\end{abstract}
+\section{Introduction}
+Things are important~\cite{ref1}. % this is a comment
+We save 50\% of time.
+\begin{figure}
+\includegraphics{plot.png}
+\caption{A plot}
+\end{figure}
+\end{document}`
+
+	if err := testFileAccess.Write("paper.tex", []byte(source), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	absPath, _ := testFileAccess.GetAbsPath("paper.tex")
+
+	doc, err := NewLaTeX(absPath, nil).Load(context.Background())
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if doc.Properties.Title != "A Study of Things" {
+		t.Errorf("title = %q", doc.Properties.Title)
+	}
+	if doc.Properties.Author != "Alice Smith , Bob Jones" && doc.Properties.Author != "Alice Smith, Bob Jones" {
+		t.Errorf("author = %q", doc.Properties.Author)
+	}
+	if doc.Properties.Abstract != "We study things in great detail." {
+		t.Errorf("abstract = %q", doc.Properties.Abstract)
+	}
+	for _, want := range []string{"Introduction", "Things are important", "50% of time"} {
+		if !strings.Contains(doc.Content, want) {
+			t.Errorf("content should contain %q, got %q", want, doc.Content)
+		}
+	}
+	for _, unwanted := range []string{`\section`, "this is a comment", "usepackage", "plot.png", `\cite`} {
+		if strings.Contains(doc.Content, unwanted) {
+			t.Errorf("content should not contain %q, got %q", unwanted, doc.Content)
+		}
+	}
+}
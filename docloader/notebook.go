@@ -0,0 +1,193 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package docloader
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/basenana/plugin/types"
+)
+
+const notebookParser = "notebook"
+
+type Notebook struct {
+	docPath        string
+	includeOutputs bool
+}
+
+func NewNotebook(docPath string, option map[string]string) Parser {
+	return Notebook{docPath: docPath, includeOutputs: option["include_outputs"] == "true"}
+}
+
+type notebookFile struct {
+	Metadata struct {
+		Title   string `json:"title"`
+		Authors []struct {
+			Name string `json:"name"`
+		} `json:"authors"`
+		KernelSpec struct {
+			Language string `json:"language"`
+		} `json:"kernelspec"`
+	} `json:"metadata"`
+	Cells []notebookCell `json:"cells"`
+}
+
+type notebookCell struct {
+	CellType string           `json:"cell_type"`
+	Source   notebookText     `json:"source"`
+	Outputs  []notebookOutput `json:"outputs"`
+}
+
+type notebookOutput struct {
+	OutputType string       `json:"output_type"`
+	Text       notebookText `json:"text"`
+	// Data is a MIME bundle, only its text/* entries are strings, e.g. application/json and
+	// widget views hold objects.
+	Data   map[string]json.RawMessage `json:"data"`
+	EName  string                     `json:"ename"`
+	EValue string                     `json:"evalue"`
+}
+
+// plainText decodes the text/plain entry of the MIME bundle.
+func (o notebookOutput) plainText() string {
+	var text notebookText
+	if raw, ok := o.Data["text/plain"]; ok {
+		_ = json.Unmarshal(raw, &text)
+	}
+	return string(text)
+}
+
+// notebookText accepts both the string and the list-of-lines form used by nbformat.
+type notebookText string
+
+func (t *notebookText) UnmarshalJSON(data []byte) error {
+	var str string
+	if err := json.Unmarshal(data, &str); err == nil {
+		*t = notebookText(str)
+		return nil
+	}
+	var lines []string
+	if err := json.Unmarshal(data, &lines); err != nil {
+		return err
+	}
+	*t = notebookText(strings.Join(lines, ""))
+	return nil
+}
+
+func (n Notebook) Load(_ context.Context) (types.Document, error) {
+	data, err := os.ReadFile(n.docPath)
+	if err != nil {
+		return types.Document{}, err
+	}
+
+	var nb notebookFile
+	if err = json.Unmarshal(data, &nb); err != nil {
+		return types.Document{}, fmt.Errorf("parse notebook failed: %w", err)
+	}
+
+	language := nb.Metadata.KernelSpec.Language
+	buf := &strings.Builder{}
+	for _, cell := range nb.Cells {
+		source := strings.TrimSpace(string(cell.Source))
+		switch cell.CellType {
+		case "markdown", "raw":
+			if source == "" {
+				continue
+			}
+			buf.WriteString(source)
+			buf.WriteString("\n\n")
+		case "code":
+			if source != "" {
+				buf.WriteString("```" + language + "\n")
+				buf.WriteString(source)
+				buf.WriteString("\n```\n\n")
+			}
+			if n.includeOutputs {
+				if output := notebookCellOutput(cell.Outputs); output != "" {
+					buf.WriteString("```\n")
+					buf.WriteString(output)
+					buf.WriteString("\n```\n\n")
+				}
+			}
+		}
+	}
+
+	props := extractFileNameMetadata(n.docPath)
+	if nb.Metadata.Title != "" {
+		props.Title = nb.Metadata.Title
+	}
+	if len(nb.Metadata.Authors) > 0 {
+		var authors []string
+		for _, a := range nb.Metadata.Authors {
+			if a.Name != "" {
+				authors = append(authors, a.Name)
+			}
+		}
+		if len(authors) > 0 {
+			props.Author = strings.Join(authors, ", ")
+		}
+	}
+
+	content := strings.TrimSpace(buf.String())
+	props = extractTextContentMetadata(notebookMarkdownOnly(nb.Cells), props)
+
+	if props.PublishAt == 0 {
+		if info, err := os.Stat(n.docPath); err == nil {
+			props.PublishAt = info.ModTime().Unix()
+		}
+	}
+
+	return types.Document{
+		Content:    content,
+		Properties: props,
+	}, nil
+}
+
+func notebookCellOutput(outputs []notebookOutput) string {
+	var parts []string
+	for _, out := range outputs {
+		switch out.OutputType {
+		case "stream":
+			if text := strings.TrimSpace(string(out.Text)); text != "" {
+				parts = append(parts, text)
+			}
+		case "execute_result", "display_data":
+			if text := strings.TrimSpace(out.plainText()); text != "" {
+				parts = append(parts, text)
+			}
+		case "error":
+			parts = append(parts, fmt.Sprintf("%s: %s", out.EName, out.EValue))
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+func notebookMarkdownOnly(cells []notebookCell) string {
+	buf := &strings.Builder{}
+	for _, cell := range cells {
+		if cell.CellType != "markdown" {
+			continue
+		}
+		buf.WriteString(string(cell.Source))
+		buf.WriteString("\n\n")
+	}
+	return buf.String()
+}
@@ -0,0 +1,131 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package docloader
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/basenana/plugin/api"
+)
+
+const testNotebook = `{
+  "metadata": {
+    "title": "Data Exploration",
+    "authors": [{"name": "Alice"}, {"name": "Bob"}],
+    "kernelspec": {"language": "python", "name": "python3"}
+  },
+  "nbformat": 4,
+  "cells": [
+    {"cell_type": "markdown", "source": ["# Exploring data\n", "\n", "This notebook explores the dataset."]},
+    {"cell_type": "code", "source": "print('hello')", "outputs": [
+      {"output_type": "stream", "name": "stdout", "text": ["hello\n"]}
+    ]},
+    {"cell_type": "code", "source": ["1 + 1"], "outputs": [
+      {"output_type": "execute_result", "data": {"text/plain": ["2"]}}
+    ]},
+    {"cell_type": "code", "source": "display(config)", "outputs": [
+      {"output_type": "display_data", "data": {"application/json": {"depth": 3, "tags": ["a"]}, "text/plain": ["{'depth': 3}"]}, "metadata": {}},
+      {"output_type": "display_data", "data": {"application/vnd.jupyter.widget-view+json": {"model_id": "f00", "version_major": 2}}, "metadata": {}}
+    ]}
+  ]
+}`
+
+func TestNotebook_Load(t *testing.T) {
+	if err := testFileAccess.Write("analysis.ipynb", []byte(testNotebook), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	absPath, _ := testFileAccess.GetAbsPath("analysis.ipynb")
+
+	doc, err := NewNotebook(absPath, map[string]string{}).Load(context.Background())
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if doc.Properties.Title != "Data Exploration" {
+		t.Errorf("title = %q, want %q", doc.Properties.Title, "Data Exploration")
+	}
+	if doc.Properties.Author != "Alice, Bob" {
+		t.Errorf("author = %q, want %q", doc.Properties.Author, "Alice, Bob")
+	}
+	if !strings.Contains(doc.Content, "This notebook explores the dataset.") {
+		t.Errorf("content should contain markdown cell, got %q", doc.Content)
+	}
+	if !strings.Contains(doc.Content, "```python\nprint('hello')\n```") {
+		t.Errorf("content should contain code cell, got %q", doc.Content)
+	}
+	if strings.Contains(doc.Content, "hello\n```\n\n```python") || strings.Contains(doc.Content, "```\n2\n```") {
+		t.Errorf("outputs should not be included by default, got %q", doc.Content)
+	}
+	if doc.Properties.Abstract != "This notebook explores the dataset." {
+		t.Errorf("abstract = %q", doc.Properties.Abstract)
+	}
+}
+
+func TestNotebook_Load_IncludeOutputs(t *testing.T) {
+	if err := testFileAccess.Write("outputs.ipynb", []byte(testNotebook), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	absPath, _ := testFileAccess.GetAbsPath("outputs.ipynb")
+
+	doc, err := NewNotebook(absPath, map[string]string{"include_outputs": "true"}).Load(context.Background())
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if !strings.Contains(doc.Content, "```\nhello\n```") {
+		t.Errorf("content should contain stream output, got %q", doc.Content)
+	}
+	if !strings.Contains(doc.Content, "```\n2\n```") {
+		t.Errorf("content should contain execute result, got %q", doc.Content)
+	}
+	if !strings.Contains(doc.Content, "```\n{'depth': 3}\n```") || strings.Contains(doc.Content, "model_id") {
+		t.Errorf("content should contain the text of the JSON output only, got %q", doc.Content)
+	}
+}
+
+func TestNotebook_Load_Invalid(t *testing.T) {
+	if err := testFileAccess.Write("broken.ipynb", []byte("not json"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	absPath, _ := testFileAccess.GetAbsPath("broken.ipynb")
+
+	if _, err := NewNotebook(absPath, nil).Load(context.Background()); err == nil {
+		t.Error("expected error for invalid notebook")
+	}
+}
+
+func TestDocLoader_Run_NotebookFile(t *testing.T) {
+	loader := newDocLoader(t)
+	if err := testFileAccess.Write("run.ipynb", []byte(testNotebook), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	resp, err := loader.Run(context.Background(), &api.Request{
+		Parameter: map[string]any{"file_path": "run.ipynb", "include_outputs": true},
+	})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if !resp.IsSucceed {
+		t.Fatalf("Run failed: %s", resp.Message)
+	}
+	doc := resp.Results["document"].(map[string]any)
+	if !strings.Contains(doc["content"].(string), "```\n2\n```") {
+		t.Errorf("expected outputs in content, got %q", doc["content"])
+	}
+}
@@ -17,33 +17,60 @@
 package docloader
 
 import (
-	"bytes"
 	"context"
+	"errors"
+	"fmt"
 	"os"
 	"regexp"
+	"runtime"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/basenana/plugin/logger"
 	"github.com/basenana/plugin/types"
 	"github.com/ledongthuc/pdf"
 )
 
-const pdfParser = "pdf"
+const (
+	pdfParser = "pdf"
 
-var pdfDateRegex = regexp.MustCompile(`^D:(\d{4})(\d{2})(\d{2})(\d{2})?(\d{2})?(\d{2})?`)
+	defaultPDFPageTimeout = 30 * time.Second
+	maxPDFWorkers         = 8
+)
+
+var (
+	pdfDateRegex = regexp.MustCompile(`^D:(\d{4})(\d{2})(\d{2})(\d{2})?(\d{2})?(\d{2})?`)
+
+	errPageTimeout = errors.New("pdf page timed out")
+)
 
 type PDF struct {
-	docPath  string
-	password string
+	docPath     string
+	password    string
+	workers     int
+	pageTimeout time.Duration
 }
 
 func NewPDF(docPath string, option map[string]string) Parser {
-	return newPDFWithPassword(docPath, option["password"])
+	p := newPDFWithPassword(docPath, option["password"]).(*PDF)
+	if n, err := strconv.Atoi(option["pdf_workers"]); err == nil && n > 0 {
+		p.workers = n
+	}
+	if d, err := time.ParseDuration(option["pdf_page_timeout"]); err == nil && d > 0 {
+		p.pageTimeout = d
+	}
+	return p
 }
 
 func newPDFWithPassword(docPath, pass string) Parser {
-	return &PDF{docPath: docPath, password: pass}
+	return &PDF{
+		docPath:     docPath,
+		password:    pass,
+		workers:     min(runtime.NumCPU(), maxPDFWorkers),
+		pageTimeout: defaultPDFPageTimeout,
+	}
 }
 
 func parsePDFDate(dateStr string) int64 {
@@ -67,7 +94,7 @@ func parsePDFDate(dateStr string) int64 {
 	return time.Date(year, time.Month(month), day, hour, minute, second, 0, time.UTC).Unix()
 }
 
-func (p *PDF) Load(_ context.Context) (types.Document, error) {
+func (p *PDF) Load(ctx context.Context) (types.Document, error) {
 	fInfo, err := os.Stat(p.docPath)
 	if err != nil {
 		return types.Document{}, err
@@ -95,9 +122,7 @@ func (p *PDF) Load(_ context.Context) (types.Document, error) {
 		props.PublishAt = fInfo.ModTime().Unix()
 	}
 
-	fonts := make(map[string]*pdf.Font)
-	buf := &bytes.Buffer{}
-	for i := 1; i <= reader.NumPage(); i++ {
+	texts, err := extractPages(ctx, reader.NumPage(), p.workers, p.pageTimeout, func(fonts map[string]*pdf.Font, i int) (string, error) {
 		page := reader.Page(i)
 		for _, name := range page.Fonts() {
 			if _, ok := fonts[name]; !ok {
@@ -105,19 +130,111 @@ func (p *PDF) Load(_ context.Context) (types.Document, error) {
 				fonts[name] = &font
 			}
 		}
-		text, err := page.GetPlainText(fonts)
-		if err != nil {
-			return types.Document{}, err
-		}
-		buf.WriteString(text)
+		return page.GetPlainText(fonts)
+	})
+	if err != nil {
+		return types.Document{}, err
 	}
 
 	return types.Document{
-		Content:    buf.String(),
+		Content:    strings.Join(texts, ""),
 		Properties: props,
 	}, nil
 }
 
+type pageExtractor func(fonts map[string]*pdf.Font, page int) (string, error)
+
+// extractPages runs extract for pages 1..total on a bounded pool of workers and returns the texts in page order.
+// Every worker keeps its own font cache. A page exceeding timeout is skipped with a warning; its goroutine
+// is abandoned because the pdf library can not be interrupted.
+func extractPages(ctx context.Context, total, workers int, timeout time.Duration, extract pageExtractor) ([]string, error) {
+	var (
+		log      = logger.FromContext(ctx)
+		texts    = make([]string, total)
+		pages    = make(chan int)
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	fail := func(err error) {
+		errOnce.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+
+	workers = max(1, min(workers, total))
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fonts := make(map[string]*pdf.Font)
+			for i := range pages {
+				text, err := extractPage(ctx, fonts, i, timeout, extract)
+				switch {
+				case errors.Is(err, errPageTimeout):
+					log.Warnw("pdf page timed out, skipped", "page", i, "timeout", timeout)
+					// the abandoned extraction may still be writing the font cache
+					fonts = make(map[string]*pdf.Font)
+				case err != nil:
+					fail(err)
+				default:
+					texts[i-1] = text
+				}
+			}
+		}()
+	}
+
+feed:
+	for i := 1; i <= total; i++ {
+		select {
+		case pages <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(pages)
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return texts, nil
+}
+
+func extractPage(ctx context.Context, fonts map[string]*pdf.Font, page int, timeout time.Duration, extract pageExtractor) (string, error) {
+	type result struct {
+		text string
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- result{err: fmt.Errorf("parse pdf page %d failed: %v", page, r)}
+			}
+		}()
+		text, err := extract(fonts, page)
+		done <- result{text: text, err: err}
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case r := <-done:
+		return r.text, r.err
+	case <-timer.C:
+		return "", errPageTimeout
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
 func (p *PDF) getAndCleanPassword() string {
 	pass := p.password
 	if pass != "" {
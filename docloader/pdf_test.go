@@ -17,7 +17,18 @@
 package docloader
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"github.com/ledongthuc/pdf"
 )
 
 func TestParsePDFDate(t *testing.T) {
@@ -52,3 +63,135 @@ func TestExtractPDFMetadata_NilReader(t *testing.T) {
 		t.Errorf("expected empty result for nil reader, got %+v", result)
 	}
 }
+
+func TestExtractPages_OrderAndBound(t *testing.T) {
+	var running, peak int32
+	texts, err := extractPages(context.Background(), 50, 4, time.Second, func(fonts map[string]*pdf.Font, page int) (string, error) {
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(time.Duration(50-page) * 100 * time.Microsecond)
+		return fmt.Sprintf("p%d;", page), nil
+	})
+	if err != nil {
+		t.Fatalf("extract pages failed: %v", err)
+	}
+	var want strings.Builder
+	for i := 1; i <= 50; i++ {
+		want.WriteString(fmt.Sprintf("p%d;", i))
+	}
+	if got := strings.Join(texts, ""); got != want.String() {
+		t.Errorf("pages out of order: %s", got)
+	}
+	if peak > 4 {
+		t.Errorf("expected at most 4 concurrent pages, got %d", peak)
+	}
+}
+
+func TestExtractPages_TimeoutSkipsPage(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	texts, err := extractPages(context.Background(), 3, 2, 50*time.Millisecond, func(fonts map[string]*pdf.Font, page int) (string, error) {
+		if page == 2 {
+			<-release
+		}
+		return fmt.Sprintf("p%d", page), nil
+	})
+	if err != nil {
+		t.Fatalf("extract pages failed: %v", err)
+	}
+	if strings.Join(texts, ",") != "p1,,p3" {
+		t.Errorf("expected the slow page to be skipped, got %v", texts)
+	}
+}
+
+func TestExtractPages_Errors(t *testing.T) {
+	_, err := extractPages(context.Background(), 20, 3, time.Second, func(fonts map[string]*pdf.Font, page int) (string, error) {
+		if page == 5 {
+			return "", errors.New("broken page")
+		}
+		if page == 7 {
+			panic("bad content stream")
+		}
+		return "ok", nil
+	})
+	if err == nil || (err.Error() != "broken page" && !strings.Contains(err.Error(), "page 7")) {
+		t.Errorf("expected page error, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err = extractPages(ctx, 5, 2, time.Second, func(fonts map[string]*pdf.Font, page int) (string, error) {
+		return "ok", nil
+	}); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context canceled, got %v", err)
+	}
+}
+
+func TestNewPDF_Options(t *testing.T) {
+	p := NewPDF("a.pdf", map[string]string{"pdf_workers": "3", "pdf_page_timeout": "5s"}).(*PDF)
+	if p.workers != 3 || p.pageTimeout != 5*time.Second {
+		t.Errorf("unexpected options %d %s", p.workers, p.pageTimeout)
+	}
+	p = NewPDF("a.pdf", map[string]string{"pdf_workers": "-1", "pdf_page_timeout": "soon"}).(*PDF)
+	if p.workers < 1 || p.workers > maxPDFWorkers || p.pageTimeout != defaultPDFPageTimeout {
+		t.Errorf("expected defaults, got %d %s", p.workers, p.pageTimeout)
+	}
+}
+
+// writeTestPDF writes a minimal PDF with one line of text per page.
+func writeTestPDF(t *testing.T, pages []string) string {
+	t.Helper()
+	var (
+		buf     bytes.Buffer
+		offsets []int
+	)
+	obj := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+	buf.WriteString("%PDF-1.4\n")
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 4+2*i)
+	}
+	obj("<< /Type /Catalog /Pages 2 0 R >>")
+	obj(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+	obj("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>")
+	for i, text := range pages {
+		obj(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>", 5+2*i))
+		stream := fmt.Sprintf("BT /F1 12 Tf 72 720 Td (%s) Tj ET", text)
+		obj(fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(stream), stream))
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	path := filepath.Join(t.TempDir(), "doc.pdf")
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestPDF_LoadParallel(t *testing.T) {
+	var pages []string
+	for i := 1; i <= 30; i++ {
+		pages = append(pages, fmt.Sprintf("Page%d.", i))
+	}
+	doc, err := NewPDF(writeTestPDF(t, pages), map[string]string{"pdf_workers": "4"}).Load(context.Background())
+	if err != nil {
+		t.Fatalf("load pdf failed: %v", err)
+	}
+	if doc.Content != "\n"+strings.Join(pages, "\n") {
+		t.Errorf("unexpected content %q", doc.Content)
+	}
+}
@@ -0,0 +1,132 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package docloader
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/basenana/plugin/types"
+)
+
+const (
+	WarningEmptyContent       = "empty_content"
+	WarningEncodingIssue      = "encoding_issue"
+	WarningTruncatedParse     = "truncated_parse"
+	WarningBoilerplateContent = "boilerplate_content"
+
+	qualityMinSourceSize       = 4 * 1024
+	qualityMinTextRatio        = 0.01
+	qualityMaxInvalidCharRatio = 0.005
+	qualityMaxLinkTextRatio    = 0.5
+	qualityMaxDuplicateRatio   = 0.4
+	qualityMinLinesForRatio    = 10
+)
+
+var markdownLinkRegex = regexp.MustCompile(`!?\[[^\]]*\]\([^)]*\)`)
+
+type QualityWarning struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// checkDocumentQuality reports extraction problems that callers may want to route to
+// a fallback path such as OCR, JS rendering or an agentic loader.
+func checkDocumentQuality(doc types.Document, fileExt string, sourceSize int64) []QualityWarning {
+	warnings := make([]QualityWarning, 0)
+	content := strings.TrimSpace(doc.Content)
+
+	if content == "" {
+		return append(warnings, QualityWarning{Code: WarningEmptyContent, Message: "no text content was extracted"})
+	}
+
+	if ratio := invalidCharRatio(content); ratio > qualityMaxInvalidCharRatio {
+		warnings = append(warnings, QualityWarning{
+			Code:    WarningEncodingIssue,
+			Message: fmt.Sprintf("%.1f%% of characters are invalid or replacement characters", ratio*100),
+		})
+	}
+
+	switch fileExt {
+	case ".pdf", ".html", ".htm":
+		if sourceSize >= qualityMinSourceSize {
+			if ratio := float64(len(content)) / float64(sourceSize); ratio < qualityMinTextRatio {
+				warnings = append(warnings, QualityWarning{
+					Code:    WarningTruncatedParse,
+					Message: fmt.Sprintf("extracted %d bytes of text from a %d bytes file", len(content), sourceSize),
+				})
+			}
+		}
+	}
+
+	if reason := boilerplateReason(content); reason != "" {
+		warnings = append(warnings, QualityWarning{Code: WarningBoilerplateContent, Message: reason})
+	}
+	return warnings
+}
+
+func invalidCharRatio(content string) float64 {
+	var total, invalid int
+	for i := 0; i < len(content); {
+		r, size := utf8.DecodeRuneInString(content[i:])
+		if r == utf8.RuneError {
+			invalid++
+		}
+		total++
+		i += size
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(invalid) / float64(total)
+}
+
+func boilerplateReason(content string) string {
+	linkText := 0
+	for _, link := range markdownLinkRegex.FindAllString(content, -1) {
+		linkText += len(link)
+	}
+	if ratio := float64(linkText) / float64(len(content)); ratio > qualityMaxLinkTextRatio {
+		return fmt.Sprintf("%.0f%% of content is links", ratio*100)
+	}
+
+	var (
+		lines = 0
+		dup   = 0
+		seen  = make(map[string]struct{})
+	)
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		lines++
+		if _, ok := seen[line]; ok {
+			dup++
+			continue
+		}
+		seen[line] = struct{}{}
+	}
+	if lines >= qualityMinLinesForRatio {
+		if ratio := float64(dup) / float64(lines); ratio > qualityMaxDuplicateRatio {
+			return fmt.Sprintf("%.0f%% of lines are repeated", ratio*100)
+		}
+	}
+	return ""
+}
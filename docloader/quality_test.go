@@ -0,0 +1,83 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package docloader
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/types"
+)
+
+func warningCodes(warnings []QualityWarning) []string {
+	codes := make([]string, 0, len(warnings))
+	for _, w := range warnings {
+		codes = append(codes, w.Code)
+	}
+	return codes
+}
+
+func TestCheckDocumentQuality(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		ext     string
+		size    int64
+		want    []string
+	}{
+		{"clean text", "A normal paragraph of text.\n\nAnother paragraph.", ".txt", 50, []string{}},
+		{"empty content", "   \n ", ".pdf", 1024, []string{WarningEmptyContent}},
+		{"encoding issue", "caf\xe9 na\xefve r\xe9sum\xe9 text", ".txt", 30, []string{WarningEncodingIssue}},
+		{"truncated pdf", "Page 1", ".pdf", 512 * 1024, []string{WarningTruncatedParse}},
+		{"large text file is not truncated", "short", ".txt", 512 * 1024, []string{}},
+		{"link heavy", "[Home](/) [About](/about) [Blog](/blog) [Contact](/contact) hi", ".html", 100, []string{WarningBoilerplateContent}},
+		{"repeated lines", strings.Repeat("Subscribe now\n", 8) + "a\nb\nc\n", ".html", 200, []string{WarningBoilerplateContent}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := warningCodes(checkDocumentQuality(types.Document{Content: tt.content}, tt.ext, tt.size))
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("warnings = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDocLoader_Run_Warnings(t *testing.T) {
+	loader := newDocLoader(t)
+	if err := testFileAccess.Write("empty.txt", []byte("   "), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	resp, err := loader.Run(context.Background(), &api.Request{
+		Parameter: map[string]any{"file_path": "empty.txt"},
+	})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if !resp.IsSucceed {
+		t.Fatalf("Run failed: %s", resp.Message)
+	}
+
+	warnings, ok := resp.Results["warnings"].([]map[string]any)
+	if !ok || len(warnings) != 1 || warnings[0]["code"] != WarningEmptyContent {
+		t.Errorf("expected empty_content warning, got %v", resp.Results["warnings"])
+	}
+}
@@ -64,7 +64,7 @@ type FileOpPlugin struct {
 func NewFileOpPlugin(ps types.PluginCall) types.Plugin {
 	return &FileOpPlugin{
 		logger:   logger.NewPluginLogger(pluginName, ps.JobID),
-		fileRoot: utils.NewFileAccess(ps.WorkingPath),
+		fileRoot: utils.NewFileAccess(ps.WorkingPath, ps.JobWorkingPath),
 	}
 }
 
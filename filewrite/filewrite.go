@@ -67,7 +67,7 @@ type FileWritePlugin struct {
 func NewFileWritePlugin(ps types.PluginCall) types.Plugin {
 	return &FileWritePlugin{
 		logger:   logger.NewPluginLogger(pluginName, ps.JobID),
-		fileRoot: utils.NewFileAccess(ps.WorkingPath),
+		fileRoot: utils.NewFileAccess(ps.WorkingPath, ps.JobWorkingPath),
 	}
 }
 
@@ -0,0 +1,294 @@
+package fs
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/logger"
+	"github.com/basenana/plugin/types"
+	"github.com/basenana/plugin/utils"
+	"go.uber.org/zap"
+)
+
+const (
+	duplicatePluginName    = "duplicate"
+	duplicatePluginVersion = "1.0"
+
+	defaultTitleThreshold = 0.9
+	defaultDuplicateLimit = 10
+
+	matchReasonURL   = "url"
+	matchReasonTitle = "title"
+
+	// besides the whole title, its longest words are searched so reworded titles are still found
+	titleSearchWords     = 2
+	titleSearchMinLength = 4
+)
+
+var DuplicatePluginSpec = types.PluginSpec{
+	Name:         duplicatePluginName,
+	Version:      duplicatePluginVersion,
+	Type:         types.TypeProcess,
+	Dependencies: []types.Dependency{{Kind: types.DependencyCapability, Name: types.CapabilityFS}},
+	Parameters: []types.ParameterSpec{
+		{
+			Name:        "url",
+			Required:    false,
+			Description: "Source URL of the new article, entries with the same normalized URL are duplicates",
+		},
+		{
+			Name:        "title",
+			Required:    false,
+			Description: "Title of the new article, entries with a near-identical title are duplicates",
+		},
+		{
+			Name:        "parent_uri",
+			Required:    false,
+			Description: "Only look for duplicates under this parent URI",
+		},
+		{
+			Name:        "threshold",
+			Required:    false,
+			Default:     "0.9",
+			Description: "Minimum title similarity between 0 and 1",
+		},
+		{
+			Name:        "exclude_uri",
+			Required:    false,
+			Description: "Entry never reported, e.g. the article itself when it is already saved",
+		},
+		{
+			Name:        "limit",
+			Required:    false,
+			Default:     "10",
+			Description: "Maximum number of matches to return",
+		},
+	},
+}
+
+type DuplicateFinder struct {
+	logger *zap.SugaredLogger
+}
+
+func NewDuplicateFinder(ps types.PluginCall) types.Plugin {
+	return &DuplicateFinder{
+		logger: logger.NewPluginLogger(duplicatePluginName, ps.JobID),
+	}
+}
+
+func (p *DuplicateFinder) Name() string           { return duplicatePluginName }
+func (p *DuplicateFinder) Type() types.PluginType { return types.TypeProcess }
+func (p *DuplicateFinder) Version() string        { return duplicatePluginVersion }
+
+// duplicateMatch is an existing entry that duplicates the new article.
+type duplicateMatch struct {
+	URI        string  `json:"uri"`
+	Name       string  `json:"name"`
+	Title      string  `json:"title,omitempty"`
+	URL        string  `json:"url,omitempty"`
+	Reason     string  `json:"reason"`
+	Similarity float64 `json:"similarity"`
+}
+
+func (p *DuplicateFinder) Run(ctx context.Context, request *api.Request) (*api.Response, error) {
+	rawURL := strings.TrimSpace(api.GetStringParameter("url", request, ""))
+	title := strings.TrimSpace(api.GetStringParameter("title", request, ""))
+	if rawURL == "" && title == "" {
+		return api.NewFailedResponse("one of url or title is required"), nil
+	}
+	threshold, limit, err := parseDuplicateOptions(request)
+	if err != nil {
+		return api.NewFailedResponse(err.Error()), nil
+	}
+	if request.FS == nil {
+		return api.NewFailedResponse("file system is not available"), nil
+	}
+
+	var (
+		parentURI  = api.GetStringParameter("parent_uri", request, "")
+		excludeURI = api.GetStringParameter("exclude_uri", request, "")
+		canonical  = utils.CanonicalURL(rawURL)
+		normTitle  = normalizeTitle(title)
+		matches    = make(map[string]duplicateMatch)
+	)
+	check := func(en types.Entry) {
+		if en.IsGroup || en.URI == excludeURI {
+			return
+		}
+		match := duplicateMatch{URI: en.URI, Name: en.Name, Title: en.Properties.Title, URL: en.Properties.URL}
+		switch {
+		case rawURL != "" && en.Properties.URL != "" && utils.CanonicalURL(en.Properties.URL) == canonical:
+			match.Reason, match.Similarity = matchReasonURL, 1
+		case normTitle != "":
+			similarity := titleSimilarity(normTitle, normalizeTitle(entryTitle(en)))
+			if similarity < threshold {
+				return
+			}
+			match.Reason, match.Similarity = matchReasonTitle, math.Round(similarity*1000)/1000
+		default:
+			return
+		}
+		if old, ok := matches[en.URI]; !ok || rankMatch(match, old) {
+			matches[en.URI] = match
+		}
+	}
+
+	p.logger.Infow("duplicate check started", "url", rawURL, "title", title, "parent_uri", parentURI)
+	for _, u := range uniqueStrings(rawURL, canonical) {
+		if u == "" {
+			continue
+		}
+		entries, err := request.FS.Search(ctx, "", types.SearchFilter{ParentURI: parentURI, URL: u, Limit: limit})
+		if err != nil {
+			p.logger.Warnw("search entries by url failed", "url", u, "error", err)
+			return api.NewFailedResponse("failed to search entries: " + err.Error()), nil
+		}
+		for _, en := range entries {
+			check(en)
+		}
+	}
+	for _, query := range titleQueries(title) {
+		entries, err := request.FS.Search(ctx, query, types.SearchFilter{ParentURI: parentURI, Limit: defaultSearchLimit})
+		if err != nil {
+			p.logger.Warnw("search entries by title failed", "query", query, "error", err)
+			return api.NewFailedResponse("failed to search entries: " + err.Error()), nil
+		}
+		for _, en := range entries {
+			check(en)
+		}
+	}
+
+	found := make([]duplicateMatch, 0, len(matches))
+	for _, m := range matches {
+		found = append(found, m)
+	}
+	sort.Slice(found, func(i, j int) bool {
+		if found[i].Reason != found[j].Reason || found[i].Similarity != found[j].Similarity {
+			return rankMatch(found[i], found[j])
+		}
+		return found[i].URI < found[j].URI
+	})
+	if len(found) > limit {
+		found = found[:limit]
+	}
+
+	results := make([]map[string]any, 0, len(found))
+	for _, m := range found {
+		results = append(results, utils.MarshalMap(m))
+	}
+	p.logger.Infow("duplicate check completed", "url", rawURL, "title", title, "found", len(results))
+	return api.NewResponseWithResult(map[string]any{
+		"duplicate": len(results) > 0,
+		"matches":   results,
+		"total":     len(results),
+	}), nil
+}
+
+func parseDuplicateOptions(request *api.Request) (threshold float64, limit int, err error) {
+	threshold, limit = defaultTitleThreshold, defaultDuplicateLimit
+	if raw := api.GetStringParameter("threshold", request, ""); raw != "" {
+		threshold, err = strconv.ParseFloat(raw, 64)
+		if err != nil || threshold <= 0 || threshold > 1 {
+			return 0, 0, fmt.Errorf("invalid threshold: %s", raw)
+		}
+	}
+	if raw := api.GetStringParameter("limit", request, ""); raw != "" {
+		limit, err = strconv.Atoi(raw)
+		if err != nil || limit <= 0 {
+			return 0, 0, fmt.Errorf("invalid limit: %s", raw)
+		}
+	}
+	return threshold, limit, nil
+}
+
+// rankMatch orders URL matches before title matches, then by similarity.
+func rankMatch(a, b duplicateMatch) bool {
+	if a.Reason != b.Reason {
+		return a.Reason == matchReasonURL
+	}
+	return a.Similarity > b.Similarity
+}
+
+// entryTitle falls back to the entry name without extension for entries without a title.
+func entryTitle(en types.Entry) string {
+	if en.Properties.Title != "" {
+		return en.Properties.Title
+	}
+	return strings.TrimSuffix(en.Name, path.Ext(en.Name))
+}
+
+// normalizeTitle lowercases the title and keeps its words of letters and digits separated by single spaces.
+func normalizeTitle(title string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
+
+// titleQueries returns the title and its longest words as search queries.
+func titleQueries(title string) []string {
+	if title == "" {
+		return nil
+	}
+	words := strings.Fields(normalizeTitle(title))
+	sort.SliceStable(words, func(i, j int) bool {
+		return len([]rune(words[i])) > len([]rune(words[j]))
+	})
+	queries := []string{title}
+	for _, w := range words {
+		if len(queries) > titleSearchWords || len([]rune(w)) < titleSearchMinLength {
+			break
+		}
+		queries = append(queries, w)
+	}
+	return uniqueStrings(queries...)
+}
+
+// titleSimilarity is one minus the edit distance of the normalized titles relative to the longer one.
+func titleSimilarity(a, b string) float64 {
+	if a == "" || b == "" {
+		return 0
+	}
+	ra, rb := []rune(a), []rune(b)
+	longer := max(len(ra), len(rb))
+	return 1 - float64(editDistance(ra, rb))/float64(longer)
+}
+
+func editDistance(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func uniqueStrings(values ...string) []string {
+	seen := make(map[string]bool, len(values))
+	result := make([]string, 0, len(values))
+	for _, v := range values {
+		if seen[v] {
+			continue
+		}
+		seen[v] = true
+		result = append(result, v)
+	}
+	return result
+}
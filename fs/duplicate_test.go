@@ -0,0 +1,183 @@
+package fs
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/types"
+)
+
+func newDuplicateFinder(t *testing.T) *DuplicateFinder {
+	return NewDuplicateFinder(types.PluginCall{
+		JobID:       "test-job",
+		Workflow:    "test-workflow",
+		Namespace:   "test-namespace",
+		WorkingPath: t.TempDir(),
+		Params:      map[string]string{},
+	}).(*DuplicateFinder)
+}
+
+func newDuplicateMockFS(t *testing.T) *MockNanaFS {
+	mockFS := NewMockNanaFS()
+	entries := []struct {
+		parent string
+		name   string
+		props  types.Properties
+	}{
+		{"1", "go-generics.html", types.Properties{Title: "Understanding Generics in Go", URL: "https://example.com/posts/generics"}},
+		{"1", "go-generics-2.html", types.Properties{Title: "Understanding Generics in Go!"}},
+		{"1", "Understanding_Generic_in_Go.md", types.Properties{}},
+		{"2", "generics-java.html", types.Properties{Title: "Understanding Generics in Java"}},
+		{"2", "rust-book.pdf", types.Properties{Title: "The Rust Book", URL: "https://example.com/rust"}},
+	}
+	for _, en := range entries {
+		if err := mockFS.SaveEntry(context.Background(), en.parent, en.name, en.props, io.NopCloser(strings.NewReader(""))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return mockFS
+}
+
+func TestDuplicateFinder_Run_MissingInput(t *testing.T) {
+	resp, err := newDuplicateFinder(t).Run(context.Background(), &api.Request{Parameter: map[string]any{}, FS: NewMockNanaFS()})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.IsSucceed {
+		t.Error("expected failure when neither url nor title is given")
+	}
+}
+
+func TestDuplicateFinder_Run_NoFS(t *testing.T) {
+	resp, err := newDuplicateFinder(t).Run(context.Background(), &api.Request{Parameter: map[string]any{"title": "Go"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.IsSucceed {
+		t.Error("expected failure when file system is not available")
+	}
+}
+
+func TestDuplicateFinder_Run(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  map[string]any
+		want    []string
+		reasons []string
+	}{
+		{
+			name:    "normalized url",
+			params:  map[string]any{"url": "https://example.com/posts/generics?utm_source=rss#comments"},
+			want:    []string{"1/go-generics.html"},
+			reasons: []string{matchReasonURL},
+		},
+		{
+			name:    "url before near-identical titles",
+			params:  map[string]any{"url": "https://example.com/posts/generics", "title": "Understanding Generics in Go"},
+			want:    []string{"1/go-generics.html", "1/go-generics-2.html", "1/Understanding_Generic_in_Go.md"},
+			reasons: []string{matchReasonURL, matchReasonTitle, matchReasonTitle},
+		},
+		{
+			name:    "parent and exclude",
+			params:  map[string]any{"title": "Understanding generics in Go", "parent_uri": "1", "exclude_uri": "1/go-generics.html"},
+			want:    []string{"1/go-generics-2.html", "1/Understanding_Generic_in_Go.md"},
+			reasons: []string{matchReasonTitle, matchReasonTitle},
+		},
+		{
+			name:    "lower threshold",
+			params:  map[string]any{"title": "Understanding Generics in Go", "parent_uri": "2", "threshold": "0.7"},
+			want:    []string{"2/generics-java.html"},
+			reasons: []string{matchReasonTitle},
+		},
+		{
+			name:   "no duplicate",
+			params: map[string]any{"url": "https://example.com/new", "title": "Something Else Entirely"},
+		},
+		{
+			name:    "limit",
+			params:  map[string]any{"title": "Understanding Generics in Go", "limit": 1},
+			want:    []string{"1/go-generics-2.html"},
+			reasons: []string{matchReasonTitle},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := newDuplicateFinder(t).Run(context.Background(), &api.Request{Parameter: tt.params, FS: newDuplicateMockFS(t)})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !resp.IsSucceed {
+				t.Fatalf("expected success, got failure: %s", resp.Message)
+			}
+			if resp.Results["duplicate"] != (len(tt.want) > 0) {
+				t.Errorf("expected duplicate %v, got %v", len(tt.want) > 0, resp.Results["duplicate"])
+			}
+			matches := resp.Results["matches"].([]map[string]any)
+			if len(matches) != len(tt.want) {
+				t.Fatalf("expected %d matches, got %d: %v", len(tt.want), len(matches), matches)
+			}
+			for i, uri := range tt.want {
+				if matches[i]["uri"] != uri || matches[i]["reason"] != tt.reasons[i] {
+					t.Errorf("match %d: expected %s by %s, got %v", i, uri, tt.reasons[i], matches[i])
+				}
+			}
+		})
+	}
+}
+
+func TestDuplicateFinder_Run_InvalidThreshold(t *testing.T) {
+	for _, threshold := range []string{"abc", "0", "1.5"} {
+		resp, err := newDuplicateFinder(t).Run(context.Background(), &api.Request{Parameter: map[string]any{"title": "Go", "threshold": threshold}, FS: NewMockNanaFS()})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if resp.IsSucceed {
+			t.Errorf("expected failure for threshold %s", threshold)
+		}
+	}
+}
+
+func TestDuplicateFinder_Run_SearchError(t *testing.T) {
+	req := &api.Request{Parameter: map[string]any{"title": "Go"}, FS: failingSearchFS{NewMockNanaFS()}}
+	resp, err := newDuplicateFinder(t).Run(context.Background(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.IsSucceed || !strings.Contains(resp.Message, "search backend down") {
+		t.Errorf("expected search failure, got %+v", resp)
+	}
+}
+
+func TestTitleSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b string
+		min  float64
+		max  float64
+	}{
+		{"Understanding Generics in Go", "understanding generics in go!", 1, 1},
+		{"Understanding Generics in Go", "Understanding Generic in Go", 0.95, 0.99},
+		{"Understanding Generics in Go", "The Rust Book", 0, 0.3},
+		{"", "The Rust Book", 0, 0},
+	}
+	for _, tt := range tests {
+		got := titleSimilarity(normalizeTitle(tt.a), normalizeTitle(tt.b))
+		if got < tt.min || got > tt.max {
+			t.Errorf("similarity(%q, %q) = %v, expected between %v and %v", tt.a, tt.b, got, tt.min, tt.max)
+		}
+	}
+}
+
+func TestTitleQueries(t *testing.T) {
+	got := titleQueries("Understanding Generics in Go")
+	want := []string{"Understanding Generics in Go", "understanding", "generics"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if len(titleQueries("")) != 0 {
+		t.Error("expected no queries for empty title")
+	}
+}
@@ -0,0 +1,188 @@
+package fs
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/logger"
+	"github.com/basenana/plugin/types"
+	"github.com/basenana/plugin/utils"
+	"go.uber.org/zap"
+)
+
+const (
+	listPluginName    = "list"
+	listPluginVersion = "1.0"
+
+	defaultListLimit = 100
+
+	listTypeAll   = "all"
+	listTypeFile  = "file"
+	listTypeGroup = "group"
+)
+
+var ListPluginSpec = types.PluginSpec{
+	Name:         listPluginName,
+	Version:      listPluginVersion,
+	Type:         types.TypeProcess,
+	Dependencies: []types.Dependency{{Kind: types.DependencyCapability, Name: types.CapabilityLister}},
+	Parameters: []types.ParameterSpec{
+		{
+			Name:        "parent_uri",
+			Required:    true,
+			Description: "URI of the group whose children are listed",
+		},
+		{
+			Name:        "type",
+			Required:    false,
+			Default:     listTypeAll,
+			Description: "Kind of children to list",
+			Options:     []string{listTypeAll, listTypeFile, listTypeGroup},
+		},
+		{
+			Name:        "keywords",
+			Required:    false,
+			Description: "Comma-separated keywords that entries must have",
+		},
+		{
+			Name:        "url",
+			Required:    false,
+			Description: "Only return entries with this source URL",
+		},
+		{
+			Name:        "unread",
+			Required:    false,
+			Description: "Filter by unread status",
+			Options:     []string{"true", "false"},
+		},
+		{
+			Name:        "marked",
+			Required:    false,
+			Description: "Filter by marked status",
+			Options:     []string{"true", "false"},
+		},
+		{
+			Name:        "offset",
+			Required:    false,
+			Default:     "0",
+			Description: "Number of matching entries to skip, the next_offset of the previous page",
+		},
+		{
+			Name:        "limit",
+			Required:    false,
+			Default:     strconv.Itoa(defaultListLimit),
+			Description: "Maximum number of entries to return",
+		},
+	},
+}
+
+type Lister struct {
+	logger *zap.SugaredLogger
+}
+
+func NewLister(ps types.PluginCall) types.Plugin {
+	return &Lister{
+		logger: logger.NewPluginLogger(listPluginName, ps.JobID),
+	}
+}
+
+func (p *Lister) Name() string           { return listPluginName }
+func (p *Lister) Type() types.PluginType { return types.TypeProcess }
+func (p *Lister) Version() string        { return listPluginVersion }
+
+func (p *Lister) Run(ctx context.Context, request *api.Request) (*api.Response, error) {
+	filter, err := buildSearchFilter(request)
+	if err != nil {
+		return api.NewFailedResponse(err.Error()), nil
+	}
+	if filter.ParentURI == "" {
+		return api.NewFailedResponse("parent_uri is required"), nil
+	}
+	if api.GetStringParameter("limit", request, "") == "" {
+		filter.Limit = defaultListLimit
+	}
+	entryType := api.GetStringParameter("type", request, listTypeAll)
+	switch entryType {
+	case listTypeAll, listTypeFile, listTypeGroup:
+	default:
+		return api.NewFailedResponse(fmt.Sprintf("invalid type: %s", entryType)), nil
+	}
+	offset, err := strconv.Atoi(api.GetStringParameter("offset", request, "0"))
+	if err != nil || offset < 0 {
+		return api.NewFailedResponse(fmt.Sprintf("invalid offset: %s", api.GetStringParameter("offset", request, ""))), nil
+	}
+
+	if request.Lister == nil {
+		return api.NewFailedResponse("entry lister is not available"), nil
+	}
+
+	p.logger.Infow("list started", "parent_uri", filter.ParentURI, "type", entryType, "offset", offset, "limit", filter.Limit)
+	children, err := request.Lister.ListEntries(ctx, filter.ParentURI)
+	if err != nil {
+		p.logger.Warnw("list entries failed", "parent_uri", filter.ParentURI, "error", err)
+		return api.NewFailedResponse("failed to list entries: " + err.Error()), nil
+	}
+
+	var matched []types.Entry
+	for _, en := range children {
+		if (entryType == listTypeFile && en.IsGroup) || (entryType == listTypeGroup && !en.IsGroup) {
+			continue
+		}
+		if matchEntry(en, filter) {
+			matched = append(matched, en)
+		}
+	}
+	// the order of the children is up to the file system, pages need a stable one
+	sort.SliceStable(matched, func(i, j int) bool { return matched[i].Name < matched[j].Name })
+
+	// offset and limit come from the caller, offset+limit may overflow
+	start := min(offset, len(matched))
+	page := matched[start : start+min(filter.Limit, len(matched)-start)]
+	entries := make([]map[string]any, 0, len(page))
+	for _, en := range page {
+		entries = append(entries, utils.MarshalMap(en))
+	}
+
+	results := map[string]any{
+		"entries": entries,
+		"total":   len(matched),
+		"offset":  offset,
+	}
+	if next := offset + len(page); len(page) > 0 && next < len(matched) {
+		results["next_offset"] = next
+	}
+	p.logger.Infow("list completed", "parent_uri", filter.ParentURI, "children", len(children), "matched", len(matched), "returned", len(entries))
+	return api.NewResponseWithResult(results), nil
+}
+
+// matchEntry applies the property filters of filter: keywords match case-insensitively, URLs
+// by their canonical form, and an entry without unread or marked status counts as false.
+func matchEntry(en types.Entry, filter types.SearchFilter) bool {
+	props := en.Properties
+	for _, keyword := range filter.Keywords {
+		found := false
+		for _, k := range props.Keywords {
+			if strings.EqualFold(k, keyword) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if filter.URL != "" && (props.URL == "" || utils.CanonicalURL(props.URL) != utils.CanonicalURL(filter.URL)) {
+		return false
+	}
+	if filter.Unread != nil && (props.Unread != nil && *props.Unread) != *filter.Unread {
+		return false
+	}
+	if filter.Marked != nil && (props.Marked != nil && *props.Marked) != *filter.Marked {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,130 @@
+package fs
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/types"
+)
+
+type childLister struct {
+	api.Lister
+	children map[string][]types.Entry
+}
+
+func (l *childLister) ListEntries(ctx context.Context, parentURI string) ([]types.Entry, error) {
+	children, ok := l.children[parentURI]
+	if !ok {
+		return nil, errors.New("group not found")
+	}
+	return children, nil
+}
+
+func newListTestLister() *childLister {
+	unread, read := true, false
+	return &childLister{children: map[string][]types.Entry{"/inbox": {
+		{URI: "/inbox/c.md", Name: "c.md", Properties: types.Properties{Keywords: []string{"Go"}, Unread: &unread}},
+		{URI: "/inbox/archive", Name: "archive", IsGroup: true},
+		{URI: "/inbox/a.html", Name: "a.html", Properties: types.Properties{Keywords: []string{"go", "web"}, URL: "https://example.com/posts/a?utm_source=rss", Unread: &read}},
+		{URI: "/inbox/b.pdf", Name: "b.pdf"},
+	}}}
+}
+
+func runList(t *testing.T, params map[string]any) *api.Response {
+	plugin := NewLister(types.PluginCall{JobID: "test-job", WorkingPath: t.TempDir()}).(*Lister)
+	resp, err := plugin.Run(context.Background(), &api.Request{Lister: newListTestLister(), Parameter: params})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return resp
+}
+
+func listedNames(resp *api.Response) string {
+	var names []string
+	for _, en := range resp.Results["entries"].([]map[string]any) {
+		names = append(names, en["name"].(string))
+	}
+	return strings.Join(names, ",")
+}
+
+func TestLister_Run(t *testing.T) {
+	resp := runList(t, map[string]any{"parent_uri": "/inbox"})
+	if !resp.IsSucceed {
+		t.Fatalf("expected success, got: %s", resp.Message)
+	}
+	if names := listedNames(resp); names != "a.html,archive,b.pdf,c.md" {
+		t.Errorf("expected the children sorted by name, got %s", names)
+	}
+	if resp.Results["total"] != 4 {
+		t.Errorf("unexpected total %v", resp.Results["total"])
+	}
+	if _, ok := resp.Results["next_offset"]; ok {
+		t.Error("expected no next_offset on the last page")
+	}
+}
+
+func TestLister_Run_Pagination(t *testing.T) {
+	resp := runList(t, map[string]any{"parent_uri": "/inbox", "type": "file", "limit": "2"})
+	if names := listedNames(resp); names != "a.html,b.pdf" || resp.Results["total"] != 3 || resp.Results["next_offset"] != 2 {
+		t.Errorf("unexpected first page %s %+v", names, resp.Results)
+	}
+	resp = runList(t, map[string]any{"parent_uri": "/inbox", "type": "file", "limit": "2", "offset": "2"})
+	if names := listedNames(resp); names != "c.md" || resp.Results["next_offset"] != nil {
+		t.Errorf("unexpected last page %s %+v", names, resp.Results)
+	}
+	for _, offset := range []string{"10", strconv.Itoa(math.MaxInt), strconv.Itoa(math.MaxInt - 1)} {
+		resp = runList(t, map[string]any{"parent_uri": "/inbox", "offset": offset})
+		if !resp.IsSucceed || listedNames(resp) != "" || resp.Results["next_offset"] != nil {
+			t.Errorf("expected an empty page past the end for offset %s, got %s %s", offset, listedNames(resp), resp.Message)
+		}
+	}
+	resp = runList(t, map[string]any{"parent_uri": "/inbox", "type": "file", "offset": "1", "limit": strconv.Itoa(math.MaxInt)})
+	if names := listedNames(resp); names != "b.pdf,c.md" {
+		t.Errorf("expected the rest of the entries with a huge limit, got %s %s", names, resp.Message)
+	}
+}
+
+func TestLister_Run_Filters(t *testing.T) {
+	for params, want := range map[string]string{
+		"type=group":                      "archive",
+		"keywords=go":                     "a.html,c.md",
+		"keywords=GO,web":                 "a.html",
+		"url=https://example.com/posts/a": "a.html",
+		"unread=true":                     "c.md",
+		"unread=false":                    "a.html,archive,b.pdf",
+	} {
+		key, value, _ := strings.Cut(params, "=")
+		resp := runList(t, map[string]any{"parent_uri": "/inbox", key: value})
+		if !resp.IsSucceed {
+			t.Fatalf("%s: expected success, got: %s", params, resp.Message)
+		}
+		if names := listedNames(resp); names != want {
+			t.Errorf("%s: expected %s, got %s", params, want, names)
+		}
+	}
+}
+
+func TestLister_Run_Invalid(t *testing.T) {
+	for name, params := range map[string]map[string]any{
+		"missing parent_uri": {},
+		"invalid type":       {"parent_uri": "/inbox", "type": "link"},
+		"invalid offset":     {"parent_uri": "/inbox", "offset": "-1"},
+		"invalid limit":      {"parent_uri": "/inbox", "limit": "0"},
+		"unknown group":      {"parent_uri": "/missing"},
+	} {
+		if resp := runList(t, params); resp.IsSucceed {
+			t.Errorf("%s: expected failure", name)
+		}
+	}
+
+	plugin := NewLister(types.PluginCall{JobID: "test-job"}).(*Lister)
+	resp, err := plugin.Run(context.Background(), &api.Request{Parameter: map[string]any{"parent_uri": "/inbox"}})
+	if err != nil || resp.IsSucceed {
+		t.Errorf("expected failure without lister, got %v %v", resp, err)
+	}
+}
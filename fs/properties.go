@@ -36,5 +36,6 @@ func buildUpdateParams(request *api.Request) (string, types.Properties) {
 		}
 	}
 
+	properties.NormalizeTranslations()
 	return content, properties
 }
@@ -0,0 +1,138 @@
+package fs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/logger"
+	"github.com/basenana/plugin/types"
+	"github.com/basenana/plugin/utils"
+	"go.uber.org/zap"
+)
+
+const (
+	readPluginName    = "read"
+	readPluginVersion = "1.0"
+)
+
+var ReadPluginSpec = types.PluginSpec{
+	Name:    readPluginName,
+	Version: readPluginVersion,
+	Type:    types.TypeProcess,
+	Dependencies: []types.Dependency{
+		{Kind: types.DependencyCapability, Name: types.CapabilityLister},
+		{Kind: types.DependencyCapability, Name: types.CapabilityFS, Optional: true},
+	},
+	Parameters: []types.ParameterSpec{
+		{
+			Name:        "entry_uri",
+			Required:    true,
+			Description: "URI of the entry to read",
+		},
+		{
+			Name:        "file_path",
+			Required:    false,
+			Description: "Path in the working directory to write the content to (defaults to the entry name)",
+		},
+	},
+}
+
+type Reader struct {
+	fileRoot *utils.FileAccess
+	logger   *zap.SugaredLogger
+}
+
+func NewReader(ps types.PluginCall) types.Plugin {
+	return &Reader{
+		fileRoot: utils.NewFileAccess(ps.WorkingPath, ps.JobWorkingPath),
+		logger:   logger.NewPluginLogger(readPluginName, ps.JobID),
+	}
+}
+
+func (p *Reader) Name() string           { return readPluginName }
+func (p *Reader) Type() types.PluginType { return types.TypeProcess }
+func (p *Reader) Version() string        { return readPluginVersion }
+
+func (p *Reader) Run(ctx context.Context, request *api.Request) (*api.Response, error) {
+	entryURI := api.GetStringParameter("entry_uri", request, "")
+	if entryURI == "" {
+		return api.NewFailedResponse("entry_uri is required"), nil
+	}
+	filePath := api.GetStringParameter("file_path", request, "")
+	if filePath == "" {
+		filePath = path.Base(strings.TrimRight(entryURI, "/"))
+		if filePath == "." || filePath == "/" || filePath == ".." {
+			return api.NewFailedResponse(fmt.Sprintf("entry_uri %s has no name, file_path is required", entryURI)), nil
+		}
+	}
+	if err := p.fileRoot.ValidatePath(filePath); err != nil {
+		return api.NewFailedResponse("invalid file_path: " + err.Error()), nil
+	}
+
+	if request.Lister == nil {
+		return api.NewFailedResponse("entry lister is not available"), nil
+	}
+
+	p.logger.Infow("read started", "entry_uri", entryURI, "file_path", filePath)
+	size, err := p.download(ctx, request.Lister, entryURI, filePath)
+	if err != nil {
+		p.logger.Warnw("read entry failed", "entry_uri", entryURI, "error", err)
+		return api.NewFailedResponse("failed to read entry: " + err.Error()), nil
+	}
+
+	results := map[string]any{
+		"entry_uri": entryURI,
+		"file_path": filePath,
+		"size":      size,
+	}
+	if request.FS != nil {
+		properties, err := request.FS.GetEntryProperties(ctx, entryURI)
+		if err != nil {
+			p.logger.Warnw("get entry properties failed", "entry_uri", entryURI, "error", err)
+			return api.NewFailedResponse("failed to get entry properties: " + err.Error()), nil
+		}
+		if properties != nil {
+			results["properties"] = utils.MarshalMap(properties)
+		}
+	}
+
+	p.logger.Infow("read completed", "entry_uri", entryURI, "file_path", filePath, "size", size)
+	return api.NewResponseWithResult(results), nil
+}
+
+// download writes the content to a temporary file renamed to filePath once complete, so a
+// failed read leaves no partial file behind.
+func (p *Reader) download(ctx context.Context, lister api.Lister, entryURI, filePath string) (int64, error) {
+	reader, err := lister.OpenEntry(ctx, entryURI)
+	if err != nil {
+		return 0, err
+	}
+	defer reader.Close()
+
+	if dir := path.Dir(filePath); dir != "." {
+		if err = p.fileRoot.MkdirAll(dir, 0755); err != nil {
+			return 0, err
+		}
+	}
+	tmpPath := path.Join(path.Dir(filePath), "."+path.Base(filePath)+".reading")
+	file, err := p.fileRoot.Create(tmpPath, 0644)
+	if err != nil {
+		return 0, err
+	}
+	size, err := io.Copy(file, reader)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = p.fileRoot.Rename(tmpPath, filePath)
+	}
+	if err != nil {
+		_ = p.fileRoot.Remove(tmpPath)
+		return 0, err
+	}
+	return size, nil
+}
@@ -0,0 +1,105 @@
+package fs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/types"
+)
+
+type contentLister struct {
+	api.Lister
+	contents map[string]string
+}
+
+func (l *contentLister) OpenEntry(ctx context.Context, entryURI string) (io.ReadCloser, error) {
+	content, ok := l.contents[entryURI]
+	if !ok {
+		return nil, errors.New("entry not found")
+	}
+	return io.NopCloser(strings.NewReader(content)), nil
+}
+
+func newReader(t *testing.T) (*Reader, string) {
+	workdir := t.TempDir()
+	return NewReader(types.PluginCall{JobID: "test-job", WorkingPath: workdir}).(*Reader), workdir
+}
+
+func TestReader_Run(t *testing.T) {
+	plugin, workdir := newReader(t)
+	mockFS := NewMockNanaFS()
+	_ = mockFS.SaveEntry(context.Background(), "/inbox", "go-tips.html", types.Properties{Title: "Go Tips", Keywords: []string{"go"}}, nil)
+	lister := &contentLister{contents: map[string]string{"/inbox/go-tips.html": "<h1>Go Tips</h1>"}}
+
+	resp, err := plugin.Run(context.Background(), &api.Request{FS: mockFS, Lister: lister, Parameter: map[string]any{
+		"entry_uri": "/inbox/go-tips.html",
+	}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !resp.IsSucceed {
+		t.Fatalf("expected success, got: %s", resp.Message)
+	}
+	if resp.Results["file_path"] != "go-tips.html" || resp.Results["size"] != int64(16) {
+		t.Errorf("unexpected results %+v", resp.Results)
+	}
+	data, err := os.ReadFile(filepath.Join(workdir, "go-tips.html"))
+	if err != nil || string(data) != "<h1>Go Tips</h1>" {
+		t.Errorf("unexpected content %q %v", data, err)
+	}
+	properties, ok := resp.Results["properties"].(map[string]any)
+	if !ok || properties["title"] != "Go Tips" {
+		t.Errorf("expected the entry properties, got %+v", resp.Results["properties"])
+	}
+}
+
+func TestReader_Run_FilePathWithoutFS(t *testing.T) {
+	plugin, workdir := newReader(t)
+	lister := &contentLister{contents: map[string]string{"/inbox/note": "remember the milk"}}
+
+	resp, err := plugin.Run(context.Background(), &api.Request{Lister: lister, Parameter: map[string]any{
+		"entry_uri": "/inbox/note",
+		"file_path": "notes/note.md",
+	}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !resp.IsSucceed {
+		t.Fatalf("expected success, got: %s", resp.Message)
+	}
+	if _, ok := resp.Results["properties"]; ok {
+		t.Error("expected no properties without file system")
+	}
+	if data, err := os.ReadFile(filepath.Join(workdir, "notes", "note.md")); err != nil || string(data) != "remember the milk" {
+		t.Errorf("unexpected content %q %v", data, err)
+	}
+}
+
+func TestReader_Run_Invalid(t *testing.T) {
+	lister := &contentLister{contents: map[string]string{"/inbox/note": "remember the milk"}}
+	for name, req := range map[string]*api.Request{
+		"missing entry_uri": {Lister: lister, Parameter: map[string]any{}},
+		"root entry_uri":    {Lister: lister, Parameter: map[string]any{"entry_uri": "/"}},
+		"path traversal":    {Lister: lister, Parameter: map[string]any{"entry_uri": "/inbox/note", "file_path": "../note"}},
+		"no lister":         {Parameter: map[string]any{"entry_uri": "/inbox/note"}},
+		"unknown entry":     {Lister: lister, Parameter: map[string]any{"entry_uri": "/inbox/missing"}},
+	} {
+		plugin, workdir := newReader(t)
+		resp, err := plugin.Run(context.Background(), req)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		if resp.IsSucceed {
+			t.Errorf("%s: expected failure", name)
+		}
+		if files, _ := os.ReadDir(workdir); len(files) != 0 {
+			t.Errorf("%s: expected no file left, got %d", name, len(files))
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package fs
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/basenana/plugin/types"
+	"github.com/basenana/plugin/utils"
+)
+
+// Route files an entry under ParentURI when all of its conditions match. MIME and property
+// values are path.Match patterns, e.g. "image/*"; a route without conditions matches everything.
+type Route struct {
+	MIME       string            `json:"mime,omitempty"`
+	Extensions []string          `json:"extensions,omitempty"`
+	Properties map[string]string `json:"properties,omitempty"`
+	ParentURI  string            `json:"parent_uri"`
+}
+
+// extensionTypes covers archive formats the mime package does not know.
+var extensionTypes = map[string]string{
+	".webarchive": "application/x-webarchive",
+	".mhtml":      "multipart/related",
+	".mht":        "multipart/related",
+	".md":         "text/markdown",
+	".markdown":   "text/markdown",
+	".epub":       "application/epub+zip",
+	".ipynb":      "application/x-ipynb+json",
+}
+
+func parseRoutes(raw string) ([]Route, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	var routes []Route
+	if err := json.Unmarshal([]byte(raw), &routes); err != nil {
+		return nil, fmt.Errorf("parse routes failed: %s", err)
+	}
+	for i, r := range routes {
+		if r.ParentURI == "" {
+			return nil, fmt.Errorf("route %d has no parent_uri", i)
+		}
+		if r.MIME != "" {
+			if _, err := path.Match(r.MIME, ""); err != nil {
+				return nil, fmt.Errorf("route %d has invalid mime pattern %s", i, r.MIME)
+			}
+		}
+		for key, pattern := range r.Properties {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("route %d has invalid pattern %s for property %s", i, pattern, key)
+			}
+		}
+	}
+	return routes, nil
+}
+
+// routeEntry returns the parent of the first matching route and its index, or -1 when none matches.
+func routeEntry(routes []Route, name, mimeType string, properties types.Properties) (string, int) {
+	var (
+		ext   = strings.ToLower(filepath.Ext(name))
+		props = utils.MarshalMap(properties)
+	)
+	for i, r := range routes {
+		if r.MIME != "" {
+			if ok, _ := path.Match(strings.ToLower(r.MIME), mimeType); !ok {
+				continue
+			}
+		}
+		if len(r.Extensions) > 0 && !matchExtension(r.Extensions, ext) {
+			continue
+		}
+		if !matchProperties(r.Properties, props) {
+			continue
+		}
+		return r.ParentURI, i
+	}
+	return "", -1
+}
+
+func matchExtension(extensions []string, ext string) bool {
+	for _, e := range extensions {
+		e = strings.ToLower(e)
+		if !strings.HasPrefix(e, ".") {
+			e = "." + e
+		}
+		if e == ext {
+			return true
+		}
+	}
+	return false
+}
+
+// matchProperties requires every pattern to match its property; list properties such as
+// keywords match when any element does.
+func matchProperties(patterns map[string]string, props map[string]any) bool {
+	for key, pattern := range patterns {
+		var values []string
+		switch v := props[key].(type) {
+		case nil:
+		case []any:
+			for _, item := range v {
+				values = append(values, fmt.Sprint(item))
+			}
+		default:
+			values = append(values, fmt.Sprint(v))
+		}
+		matched := false
+		for _, value := range values {
+			if ok, _ := path.Match(pattern, value); ok {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// detectMIME guesses the media type from the extension and sniffs the content otherwise.
+// The file is rewound for saving.
+func detectMIME(name string, file *os.File) (string, error) {
+	ext := strings.ToLower(filepath.Ext(name))
+	if t, ok := extensionTypes[ext]; ok {
+		return t, nil
+	}
+	if t := mime.TypeByExtension(ext); t != "" {
+		mediaType, _, _ := mime.ParseMediaType(t)
+		return mediaType, nil
+	}
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", err
+	}
+	if _, err = file.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	mediaType, _, _ := mime.ParseMediaType(http.DetectContentType(head[:n]))
+	return mediaType, nil
+}
@@ -0,0 +1,148 @@
+package fs
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/types"
+)
+
+const testRoutes = `[
+	{"properties": {"site_name": "GitHub"}, "parent_uri": "/code"},
+	{"mime": "image/*", "parent_uri": "/images"},
+	{"extensions": ["webarchive", ".mhtml"], "parent_uri": "/web"},
+	{"mime": "application/pdf", "properties": {"keywords": "paper*"}, "parent_uri": "/papers"},
+	{"parent_uri": "/inbox"}
+]`
+
+func TestRouteEntry(t *testing.T) {
+	routes, err := parseRoutes(testRoutes)
+	if err != nil {
+		t.Fatalf("parse routes failed: %v", err)
+	}
+	cases := []struct {
+		name       string
+		mimeType   string
+		properties types.Properties
+		parentURI  string
+		route      int
+	}{
+		{"readme.html", "text/html", types.Properties{SiteName: "GitHub"}, "/code", 0},
+		{"photo.JPG", "image/jpeg", types.Properties{}, "/images", 1},
+		{"article.webarchive", "application/x-webarchive", types.Properties{}, "/web", 2},
+		{"article.MHTML", "multipart/related", types.Properties{}, "/web", 2},
+		{"attention.pdf", "application/pdf", types.Properties{Keywords: []string{"ml", "papers"}}, "/papers", 3},
+		{"invoice.pdf", "application/pdf", types.Properties{Keywords: []string{"finance"}}, "/inbox", 4},
+	}
+	for _, c := range cases {
+		parentURI, route := routeEntry(routes, c.name, c.mimeType, c.properties)
+		if parentURI != c.parentURI || route != c.route {
+			t.Errorf("%s: got %s (%d), want %s (%d)", c.name, parentURI, route, c.parentURI, c.route)
+		}
+	}
+
+	if _, route := routeEntry(routes[:2], "notes.txt", "text/plain", types.Properties{}); route != -1 {
+		t.Errorf("expected no route, got %d", route)
+	}
+}
+
+func TestParseRoutes_Invalid(t *testing.T) {
+	for _, raw := range []string{
+		`{"parent_uri": "/a"}`,
+		`[{"mime": "image/*"}]`,
+		`[{"mime": "image/[", "parent_uri": "/a"}]`,
+		`[{"properties": {"title": "a["}, "parent_uri": "/a"}]`,
+	} {
+		if _, err := parseRoutes(raw); err == nil {
+			t.Errorf("expected error for %s", raw)
+		}
+	}
+}
+
+func TestDetectMIME(t *testing.T) {
+	dir := t.TempDir()
+	cases := map[string][]byte{
+		"doc.pdf":          []byte("%PDF-1.4"),
+		"page.webarchive":  []byte("bplist00"),
+		"scan":             {0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n', 0, 0, 0, 0},
+		"notes.unknownext": []byte("plain words"),
+		"index.html":       []byte("<html></html>"),
+	}
+	want := map[string]string{
+		"doc.pdf":          "application/pdf",
+		"page.webarchive":  "application/x-webarchive",
+		"scan":             "image/png",
+		"notes.unknownext": "text/plain",
+		"index.html":       "text/html",
+	}
+	for name, data := range cases {
+		filePath := filepath.Join(dir, name)
+		if err := os.WriteFile(filePath, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		f, err := os.Open(filePath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := detectMIME(name, f)
+		if err != nil || got != want[name] {
+			t.Errorf("%s: got %s, %v, want %s", name, got, err, want[name])
+		}
+		if pos, _ := f.Seek(0, 1); pos != 0 {
+			t.Errorf("%s: file not rewound, at %d", name, pos)
+		}
+		f.Close()
+	}
+}
+
+func TestSaver_Run_Routes(t *testing.T) {
+	plugin, tw := newSaver(t)
+	if err := tw.Write("scan", []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n'}, 0644); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+
+	mockFS := NewMockNanaFS()
+	resp, err := plugin.Run(context.Background(), &api.Request{
+		Parameter: map[string]interface{}{
+			"file_path": filepath.Join(tw.Workdir(), "scan"),
+			"subgroup":  "2024",
+			"routes":    testRoutes,
+		},
+		FS: mockFS,
+	})
+	if err != nil || !resp.IsSucceed {
+		t.Fatalf("run failed: %v %v", err, resp)
+	}
+	if resp.Results["entry_uri"] != "/images/2024/scan" || resp.Results["route"] != 1 || resp.Results["mime_type"] != "image/png" {
+		t.Errorf("unexpected results %v", resp.Results)
+	}
+	if _, ok := mockFS.entries["/images/2024/scan"]; !ok {
+		t.Errorf("entry not saved under the routed parent")
+	}
+
+	resp, _ = plugin.Run(context.Background(), &api.Request{
+		Parameter: map[string]interface{}{
+			"file_path": filepath.Join(tw.Workdir(), "scan"),
+			"routes":    `[{"mime": "application/pdf", "parent_uri": "/papers"}]`,
+		},
+		FS: mockFS,
+	})
+	if resp.IsSucceed {
+		t.Errorf("expected failure when no route matches")
+	}
+
+	resp, _ = plugin.Run(context.Background(), &api.Request{
+		Parameter: map[string]interface{}{
+			"file_path":  filepath.Join(tw.Workdir(), "scan"),
+			"parent_uri": "/explicit",
+			"routes":     testRoutes,
+		},
+		FS: mockFS,
+	})
+	if !resp.IsSucceed || resp.Results["entry_uri"] != "/explicit/scan" {
+		t.Errorf("parent_uri should take precedence over routes: %v", resp.Results)
+	}
+}
@@ -2,6 +2,7 @@ package fs
 
 import (
 	"context"
+	"fmt"
 	"path"
 
 	"github.com/basenana/plugin/api"
@@ -17,9 +18,10 @@ const (
 )
 
 var SavePluginSpec = types.PluginSpec{
-	Name:    savePluginName,
-	Version: savePluginVersion,
-	Type:    types.TypeProcess,
+	Name:         savePluginName,
+	Version:      savePluginVersion,
+	Type:         types.TypeProcess,
+	Dependencies: []types.Dependency{{Kind: types.DependencyCapability, Name: types.CapabilityFS}},
 	Parameters: []types.ParameterSpec{
 		{
 			Name:        "file_path",
@@ -28,8 +30,13 @@ var SavePluginSpec = types.PluginSpec{
 		},
 		{
 			Name:        "parent_uri",
-			Required:    true,
-			Description: "Parent entry URI",
+			Required:    false,
+			Description: "Parent entry URI, chosen by routes when empty",
+		},
+		{
+			Name:        "routes",
+			Required:    false,
+			Description: "JSON array of routing rules (mime, extensions, properties, parent_uri) used when parent_uri is empty, the first match wins",
 		},
 		{
 			Name:        "name",
@@ -56,7 +63,7 @@ type Saver struct {
 
 func NewSaver(ps types.PluginCall) types.Plugin {
 	return &Saver{
-		fileRoot: utils.NewFileAccess(ps.WorkingPath),
+		fileRoot: utils.NewFileAccess(ps.WorkingPath, ps.JobWorkingPath),
 		logger:   logger.NewPluginLogger(savePluginName, ps.JobID),
 	}
 }
@@ -86,9 +93,28 @@ func (p *Saver) Run(ctx context.Context, request *api.Request) (*api.Response, e
 	parentURI := api.GetStringParameter("parent_uri", request, "")
 	subGroup := api.GetStringParameter("subgroup", request, "")
 	_, properties := buildUpdateParams(request)
+	properties.PromoteTranslation()
 
+	results := map[string]any{}
 	if parentURI == "" {
-		return api.NewFailedResponse("parent_uri is required"), nil
+		routes, err := parseRoutes(api.GetStringParameter("routes", request, ""))
+		if err != nil {
+			return api.NewFailedResponse(err.Error()), nil
+		}
+		if len(routes) == 0 {
+			return api.NewFailedResponse("parent_uri is required"), nil
+		}
+		mimeType, err := detectMIME(fileInfo.Name(), file)
+		if err != nil {
+			return api.NewFailedResponse("failed to detect mime type: " + err.Error()), nil
+		}
+		var route int
+		if parentURI, route = routeEntry(routes, fileInfo.Name(), mimeType, properties); route < 0 {
+			return api.NewFailedResponse(fmt.Sprintf("parent_uri is empty and no route matched %s (%s)", fileInfo.Name(), mimeType)), nil
+		}
+		p.logger.Infow("entry routed", "file_path", filePath, "mime_type", mimeType, "route", route, "parent_uri", parentURI)
+		results["mime_type"] = mimeType
+		results["route"] = route
 	}
 
 	if request.FS == nil {
@@ -111,5 +137,7 @@ func (p *Saver) Run(ctx context.Context, request *api.Request) (*api.Response, e
 	}
 
 	p.logger.Infow("save completed", "file_path", filePath)
-	return api.NewResponseWithResult(map[string]any{"entry_uri": path.Join(parentURI, name)}), nil
+	results["entry_uri"] = path.Join(parentURI, name)
+	results["parent_uri"] = parentURI
+	return api.NewResponseWithResult(results), nil
 }
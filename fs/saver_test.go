@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"io"
 	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
 	"testing"
 
@@ -437,6 +439,39 @@ func TestSaver_Properties_InvalidDocumentType(t *testing.T) {
 	}
 }
 
+func TestSaver_Run_PromoteTranslation(t *testing.T) {
+	plugin, tw := newSaver(t)
+	if err := tw.Write("note.md", []byte("content"), 0644); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+
+	mockFS := NewMockNanaFS()
+	resp, err := plugin.Run(context.Background(), &api.Request{
+		Parameter: map[string]interface{}{
+			"file_path":  "note.md",
+			"parent_uri": "/group",
+			"properties": map[string]interface{}{
+				"translations": map[string]interface{}{
+					"fr": map[string]interface{}{"title": "Bonjour"},
+					"de": map[string]interface{}{"title": "Hallo", "keywords": []interface{}{"gruss"}},
+				},
+			},
+		},
+		FS: mockFS,
+	})
+	if err != nil || !resp.IsSucceed {
+		t.Fatalf("save failed: %v %v", err, resp)
+	}
+
+	props := mockFS.entries["/group/note.md"].props
+	if props.Title != "Hallo" || props.Language != "de" || len(props.Keywords) != 1 {
+		t.Errorf("expected first language promoted to primary, got %+v", props)
+	}
+	if len(props.Translations) != 1 || props.Translations["fr"].Title != "Bonjour" {
+		t.Errorf("unexpected translations %+v", props.Translations)
+	}
+}
+
 // MockNanaFS is a mock implementation of NanaFS interface for testing.
 type MockNanaFS struct {
 	mu           sync.RWMutex
@@ -508,6 +543,63 @@ func (m *MockNanaFS) GetEntryProperties(ctx context.Context, entryURI string) (p
 	return &en.props, nil
 }
 
+func (m *MockNanaFS) Search(ctx context.Context, query string, filter types.SearchFilter) ([]types.Entry, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	uris := make([]string, 0, len(m.entries))
+	for uri := range m.entries {
+		uris = append(uris, uri)
+	}
+	sort.Strings(uris)
+
+	query = strings.ToLower(query)
+	result := make([]types.Entry, 0)
+	for _, uri := range uris {
+		en := m.entries[uri]
+		if filter.ParentURI != "" && en.parentURI != filter.ParentURI {
+			continue
+		}
+		if filter.URL != "" && en.props.URL != filter.URL {
+			continue
+		}
+		if filter.Unread != nil && (en.props.Unread == nil || *en.props.Unread != *filter.Unread) {
+			continue
+		}
+		if filter.Marked != nil && (en.props.Marked == nil || *en.props.Marked != *filter.Marked) {
+			continue
+		}
+		if !mockHasKeywords(en.props.Keywords, filter.Keywords) {
+			continue
+		}
+		text := strings.ToLower(strings.Join([]string{en.name, en.props.Title, en.props.Abstract, strings.Join(en.props.Keywords, " ")}, " "))
+		if query != "" && !strings.Contains(text, query) {
+			continue
+		}
+		result = append(result, types.Entry{URI: uri, Name: en.name, Properties: en.props})
+		if filter.Limit > 0 && len(result) >= filter.Limit {
+			break
+		}
+	}
+	return result, nil
+}
+
+func mockHasKeywords(entryKeywords, required []string) bool {
+	for _, want := range required {
+		found := false
+		for _, k := range entryKeywords {
+			if strings.EqualFold(k, want) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
 // Test helpers
 
 func (m *MockNanaFS) SetSaveError(err error) {
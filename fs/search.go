@@ -0,0 +1,148 @@
+package fs
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/logger"
+	"github.com/basenana/plugin/types"
+	"github.com/basenana/plugin/utils"
+	"go.uber.org/zap"
+)
+
+const (
+	searchPluginName    = "search"
+	searchPluginVersion = "1.0"
+
+	defaultSearchLimit = 20
+)
+
+var SearchPluginSpec = types.PluginSpec{
+	Name:         searchPluginName,
+	Version:      searchPluginVersion,
+	Type:         types.TypeProcess,
+	Dependencies: []types.Dependency{{Kind: types.DependencyCapability, Name: types.CapabilityFS}},
+	Parameters: []types.ParameterSpec{
+		{
+			Name:        "query",
+			Required:    false,
+			Description: "Text matched against entry name, title and content",
+		},
+		{
+			Name:        "parent_uri",
+			Required:    false,
+			Description: "Only search entries under this parent URI",
+		},
+		{
+			Name:        "keywords",
+			Required:    false,
+			Description: "Comma-separated keywords that entries must have",
+		},
+		{
+			Name:        "url",
+			Required:    false,
+			Description: "Only return entries with this source URL",
+		},
+		{
+			Name:        "unread",
+			Required:    false,
+			Description: "Filter by unread status",
+			Options:     []string{"true", "false"},
+		},
+		{
+			Name:        "marked",
+			Required:    false,
+			Description: "Filter by marked status",
+			Options:     []string{"true", "false"},
+		},
+		{
+			Name:        "limit",
+			Required:    false,
+			Default:     "20",
+			Description: "Maximum number of entries to return",
+		},
+	},
+}
+
+type Searcher struct {
+	logger *zap.SugaredLogger
+}
+
+func NewSearcher(ps types.PluginCall) types.Plugin {
+	return &Searcher{
+		logger: logger.NewPluginLogger(searchPluginName, ps.JobID),
+	}
+}
+
+func (p *Searcher) Name() string           { return searchPluginName }
+func (p *Searcher) Type() types.PluginType { return types.TypeProcess }
+func (p *Searcher) Version() string        { return searchPluginVersion }
+
+func (p *Searcher) Run(ctx context.Context, request *api.Request) (*api.Response, error) {
+	query := strings.TrimSpace(api.GetStringParameter("query", request, ""))
+	filter, err := buildSearchFilter(request)
+	if err != nil {
+		return api.NewFailedResponse(err.Error()), nil
+	}
+	if query == "" && filter.URL == "" && len(filter.Keywords) == 0 {
+		return api.NewFailedResponse("one of query, keywords or url is required"), nil
+	}
+
+	if request.FS == nil {
+		return api.NewFailedResponse("file system is not available"), nil
+	}
+
+	p.logger.Infow("search started", "query", query, "parent_uri", filter.ParentURI)
+
+	entries, err := request.FS.Search(ctx, query, filter)
+	if err != nil {
+		p.logger.Warnw("search entries failed", "query", query, "error", err)
+		return api.NewFailedResponse("failed to search entries: " + err.Error()), nil
+	}
+
+	results := make([]map[string]any, 0, len(entries))
+	for _, en := range entries {
+		results = append(results, utils.MarshalMap(en))
+	}
+
+	p.logger.Infow("search completed", "query", query, "found", len(results))
+	return api.NewResponseWithResult(map[string]any{
+		"entries": results,
+		"total":   len(results),
+	}), nil
+}
+
+func buildSearchFilter(request *api.Request) (types.SearchFilter, error) {
+	filter := types.SearchFilter{
+		ParentURI: api.GetStringParameter("parent_uri", request, ""),
+		URL:       api.GetStringParameter("url", request, ""),
+		Limit:     defaultSearchLimit,
+	}
+
+	for _, k := range strings.Split(api.GetStringParameter("keywords", request, ""), ",") {
+		if k = strings.TrimSpace(k); k != "" {
+			filter.Keywords = append(filter.Keywords, k)
+		}
+	}
+
+	if _, ok := request.Parameter["unread"]; ok {
+		unread := api.GetBoolParameter("unread", request, false)
+		filter.Unread = &unread
+	}
+	if _, ok := request.Parameter["marked"]; ok {
+		marked := api.GetBoolParameter("marked", request, false)
+		filter.Marked = &marked
+	}
+
+	if limitStr := api.GetStringParameter("limit", request, ""); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 {
+			return filter, fmt.Errorf("invalid limit: %s", limitStr)
+		}
+		filter.Limit = limit
+	}
+	return filter, nil
+}
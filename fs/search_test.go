@@ -0,0 +1,141 @@
+package fs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/types"
+)
+
+func newSearcher(t *testing.T) *Searcher {
+	return NewSearcher(types.PluginCall{
+		JobID:       "test-job",
+		Workflow:    "test-workflow",
+		Namespace:   "test-namespace",
+		WorkingPath: t.TempDir(),
+		Params:      map[string]string{},
+	}).(*Searcher)
+}
+
+func newSearchMockFS(t *testing.T) *MockNanaFS {
+	mockFS := NewMockNanaFS()
+	marked := true
+	entries := []struct {
+		parent string
+		name   string
+		props  types.Properties
+	}{
+		{"1", "go-tips.html", types.Properties{Title: "Go Tips", Keywords: []string{"go", "programming"}, URL: "https://example.com/go", Marked: &marked}},
+		{"1", "rust-book.pdf", types.Properties{Title: "The Rust Book", Keywords: []string{"rust", "programming"}}},
+		{"2", "go-news.html", types.Properties{Title: "Go Release Notes", Keywords: []string{"go"}}},
+	}
+	for _, en := range entries {
+		if err := mockFS.SaveEntry(context.Background(), en.parent, en.name, en.props, io.NopCloser(strings.NewReader(""))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return mockFS
+}
+
+func TestSearcher_Run_MissingQuery(t *testing.T) {
+	resp, err := newSearcher(t).Run(context.Background(), &api.Request{Parameter: map[string]any{}, FS: NewMockNanaFS()})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.IsSucceed {
+		t.Error("expected failure when no query is given")
+	}
+}
+
+func TestSearcher_Run_NoFS(t *testing.T) {
+	resp, err := newSearcher(t).Run(context.Background(), &api.Request{Parameter: map[string]any{"query": "go"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.IsSucceed {
+		t.Error("expected failure when file system is not available")
+	}
+}
+
+func TestSearcher_Run_ByQuery(t *testing.T) {
+	req := &api.Request{Parameter: map[string]any{"query": "go"}, FS: newSearchMockFS(t)}
+
+	resp, err := newSearcher(t).Run(context.Background(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !resp.IsSucceed {
+		t.Fatalf("expected success, got failure: %s", resp.Message)
+	}
+	if resp.Results["total"] != 2 {
+		t.Errorf("expected 2 entries, got %v", resp.Results["total"])
+	}
+}
+
+func TestSearcher_Run_WithFilters(t *testing.T) {
+	tests := []struct {
+		name   string
+		params map[string]any
+		want   []string
+	}{
+		{"parent", map[string]any{"query": "go", "parent_uri": "2"}, []string{"go-news.html"}},
+		{"keywords", map[string]any{"keywords": "programming, rust"}, []string{"rust-book.pdf"}},
+		{"url", map[string]any{"url": "https://example.com/go"}, []string{"go-tips.html"}},
+		{"marked", map[string]any{"query": "go", "marked": true}, []string{"go-tips.html"}},
+		{"limit", map[string]any{"keywords": "programming", "limit": 1}, []string{"go-tips.html"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := newSearcher(t).Run(context.Background(), &api.Request{Parameter: tt.params, FS: newSearchMockFS(t)})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !resp.IsSucceed {
+				t.Fatalf("expected success, got failure: %s", resp.Message)
+			}
+			entries := resp.Results["entries"].([]map[string]any)
+			if len(entries) != len(tt.want) {
+				t.Fatalf("expected %d entries, got %d: %v", len(tt.want), len(entries), entries)
+			}
+			for i, name := range tt.want {
+				if entries[i]["name"] != name {
+					t.Errorf("entry %d: expected %s, got %v", i, name, entries[i]["name"])
+				}
+			}
+		})
+	}
+}
+
+func TestSearcher_Run_InvalidLimit(t *testing.T) {
+	resp, err := newSearcher(t).Run(context.Background(), &api.Request{Parameter: map[string]any{"query": "go", "limit": "abc"}, FS: NewMockNanaFS()})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.IsSucceed {
+		t.Error("expected failure for invalid limit")
+	}
+}
+
+type failingSearchFS struct {
+	*MockNanaFS
+}
+
+func (f failingSearchFS) Search(ctx context.Context, query string, filter types.SearchFilter) ([]types.Entry, error) {
+	return nil, errors.New("search backend down")
+}
+
+func TestSearcher_Run_SearchError(t *testing.T) {
+	req := &api.Request{Parameter: map[string]any{"query": "go"}, FS: failingSearchFS{NewMockNanaFS()}}
+	resp, err := newSearcher(t).Run(context.Background(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.IsSucceed || !strings.Contains(resp.Message, "search backend down") {
+		t.Errorf("expected search failure, got %+v", resp)
+	}
+}
@@ -16,9 +16,10 @@ const (
 )
 
 var UpdatePluginSpec = types.PluginSpec{
-	Name:    updatePluginName,
-	Version: updatePluginVersion,
-	Type:    types.TypeProcess,
+	Name:         updatePluginName,
+	Version:      updatePluginVersion,
+	Type:         types.TypeProcess,
+	Dependencies: []types.Dependency{{Kind: types.DependencyCapability, Name: types.CapabilityFS}},
 	Parameters: []types.ParameterSpec{
 		{
 			Name:        "entry_uri",
@@ -40,7 +41,7 @@ type Updater struct {
 
 func NewUpdater(ps types.PluginCall) types.Plugin {
 	return &Updater{
-		fileRoot: utils.NewFileAccess(ps.WorkingPath),
+		fileRoot: utils.NewFileAccess(ps.WorkingPath, ps.JobWorkingPath),
 		logger:   logger.NewPluginLogger(updatePluginName, ps.JobID),
 	}
 }
@@ -62,6 +63,17 @@ func (p *Updater) Run(ctx context.Context, request *api.Request) (*api.Response,
 	if request.FS == nil {
 		return api.NewFailedResponse("file system is not available"), nil
 	}
+	// keep the languages this update does not touch
+	if len(props.Translations) > 0 {
+		if current, err := request.FS.GetEntryProperties(ctx, entryURI); err == nil && current != nil {
+			if props.Language == "" {
+				props.Language = current.Language
+				props.NormalizeTranslations()
+			}
+			props.MergeTranslations(current.Translations)
+		}
+	}
+
 	if err := request.FS.UpdateEntry(ctx, entryURI, content, props); err != nil {
 		p.logger.Warnw("update entry failed", "entry_uri", entryURI, "error", err)
 		return api.NewFailedResponse("failed to update entry: " + err.Error()), nil
@@ -435,3 +435,47 @@ func TestUpdater_Run_UpdateNonexistentEntry(t *testing.T) {
 		t.Errorf("expected success (silently ignoring non-existent entry), got failure: %s", resp.Message)
 	}
 }
+
+func TestUpdater_Run_Translations(t *testing.T) {
+	plugin := newUpdater(t)
+	mockFS := NewMockNanaFS()
+	mockFS.entries["/docs/a"] = &mockEntry{parentURI: "/docs", name: "a", props: types.Properties{
+		Title:        "Rolling restarts",
+		Language:     "en",
+		Translations: map[string]types.TranslatedProperties{"ja": {Title: "ローリング再起動"}},
+	}}
+
+	resp, err := plugin.Run(context.Background(), &api.Request{
+		Parameter: map[string]interface{}{
+			"entry_uri": "/docs/a",
+			"properties": map[string]interface{}{
+				"title": "Rolling restarts",
+				"translations": map[string]interface{}{
+					"zh_CN": map[string]interface{}{"title": "滚动重启", "abstract": "如何滚动重启"},
+					"EN":    map[string]interface{}{"abstract": "How to restart"},
+				},
+			},
+		},
+		FS: mockFS,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !resp.IsSucceed {
+		t.Fatalf("expected success, got failure: %s", resp.Message)
+	}
+
+	props := mockFS.entries["/docs/a"].props
+	if props.Language != "en" || props.Abstract != "How to restart" {
+		t.Errorf("expected primary language values in place, got %+v", props)
+	}
+	if props.Translations["zh-cn"].Title != "滚动重启" || props.Translations["ja"].Title != "ローリング再起動" {
+		t.Errorf("expected merged translations, got %+v", props.Translations)
+	}
+	if _, ok := props.Translations["en"]; ok {
+		t.Errorf("primary language should not be kept as translation: %+v", props.Translations)
+	}
+	if localized := props.Localized("zh_CN"); localized.Title != "滚动重启" || localized.Language != "zh-cn" {
+		t.Errorf("unexpected localized properties %+v", localized)
+	}
+}
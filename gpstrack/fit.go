@@ -0,0 +1,240 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package gpstrack
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	fitMesgSession = 18
+	fitMesgRecord  = 20
+
+	fitFieldTimestamp = 253
+
+	fitRecordLat              = 0
+	fitRecordLong             = 1
+	fitRecordAltitude         = 2
+	fitRecordEnhancedAltitude = 78
+
+	fitSessionSport = 5
+)
+
+// fitEpoch is 1989-12-31T00:00:00Z, the origin of FIT timestamps.
+var fitEpoch = time.Date(1989, 12, 31, 0, 0, 0, 0, time.UTC)
+
+var fitSports = map[uint64]string{
+	1:  "running",
+	2:  "cycling",
+	5:  "swimming",
+	10: "training",
+	11: "walking",
+	12: "cross_country_skiing",
+	13: "alpine_skiing",
+	15: "rowing",
+	16: "mountaineering",
+	17: "hiking",
+}
+
+type fitField struct {
+	num  uint8
+	size int
+}
+
+type fitDefinition struct {
+	global    uint16
+	byteOrder binary.ByteOrder
+	fields    []fitField
+	devSize   int
+}
+
+// ParseFIT decodes the record and session messages of a FIT activity file,
+// other messages and developer fields are skipped.
+func ParseFIT(data []byte) (*Activity, error) {
+	if len(data) < 12 {
+		return nil, errors.New("file too short")
+	}
+	headerSize := int(data[0])
+	if headerSize < 12 || len(data) < headerSize || string(data[8:12]) != ".FIT" {
+		return nil, errors.New("missing .FIT signature")
+	}
+	end := headerSize + int(binary.LittleEndian.Uint32(data[4:8]))
+	if end > len(data) {
+		return nil, fmt.Errorf("truncated file: data size %d exceeds file size", end-headerSize)
+	}
+
+	var (
+		a      = &Activity{}
+		r      = bytes.NewReader(data[headerSize:end])
+		defs   = make(map[uint8]*fitDefinition)
+		lastTS uint32
+	)
+	for r.Len() > 0 {
+		hdr, _ := r.ReadByte()
+		switch {
+		case hdr&0x80 != 0:
+			local := (hdr >> 5) & 0x03
+			offset := uint32(hdr & 0x1F)
+			ts := lastTS&^0x1F + offset
+			if offset < lastTS&0x1F {
+				ts += 0x20
+			}
+			lastTS = ts
+			def, ok := defs[local]
+			if !ok {
+				return nil, fmt.Errorf("data message for undefined local type %d", local)
+			}
+			values, err := def.read(r)
+			if err != nil {
+				return nil, err
+			}
+			values[fitFieldTimestamp] = uint64(ts)
+			a.apply(def.global, values)
+
+		case hdr&0x40 != 0:
+			def, err := readFitDefinition(r, hdr&0x20 != 0)
+			if err != nil {
+				return nil, err
+			}
+			defs[hdr&0x0F] = def
+
+		default:
+			def, ok := defs[hdr&0x0F]
+			if !ok {
+				return nil, fmt.Errorf("data message for undefined local type %d", hdr&0x0F)
+			}
+			values, err := def.read(r)
+			if err != nil {
+				return nil, err
+			}
+			if ts, ok := values[fitFieldTimestamp]; ok {
+				lastTS = uint32(ts)
+			}
+			a.apply(def.global, values)
+		}
+	}
+	return a, nil
+}
+
+func readFitDefinition(r *bytes.Reader, developer bool) (*fitDefinition, error) {
+	head := make([]byte, 5)
+	if _, err := readFull(r, head); err != nil {
+		return nil, fmt.Errorf("read definition failed: %s", err)
+	}
+	def := &fitDefinition{byteOrder: binary.LittleEndian}
+	if head[1] == 1 {
+		def.byteOrder = binary.BigEndian
+	}
+	def.global = def.byteOrder.Uint16(head[2:4])
+
+	raw := make([]byte, int(head[4])*3)
+	if _, err := readFull(r, raw); err != nil {
+		return nil, fmt.Errorf("read definition fields failed: %s", err)
+	}
+	for i := 0; i < len(raw); i += 3 {
+		def.fields = append(def.fields, fitField{num: raw[i], size: int(raw[i+1])})
+	}
+	if developer {
+		n, err := r.ReadByte()
+		if err != nil {
+			return nil, fmt.Errorf("read developer fields failed: %s", err)
+		}
+		dev := make([]byte, int(n)*3)
+		if _, err = readFull(r, dev); err != nil {
+			return nil, fmt.Errorf("read developer fields failed: %s", err)
+		}
+		for i := 0; i < len(dev); i += 3 {
+			def.devSize += int(dev[i+1])
+		}
+	}
+	return def, nil
+}
+
+// read returns the fields of one data message, invalid (all bits set) values are left out.
+func (d *fitDefinition) read(r *bytes.Reader) (map[uint8]uint64, error) {
+	values := make(map[uint8]uint64, len(d.fields))
+	for _, f := range d.fields {
+		buf := make([]byte, f.size)
+		if _, err := readFull(r, buf); err != nil {
+			return nil, fmt.Errorf("read message %d failed: %s", d.global, err)
+		}
+		var v, invalid uint64
+		switch f.size {
+		case 1:
+			v, invalid = uint64(buf[0]), 0xFF
+		case 2:
+			v, invalid = uint64(d.byteOrder.Uint16(buf)), 0xFFFF
+		case 4:
+			v, invalid = uint64(d.byteOrder.Uint32(buf)), 0xFFFFFFFF
+		default:
+			continue
+		}
+		// signed 32-bit coordinates mark missing values with 0x7FFFFFFF
+		if v == invalid || (f.size == 4 && (f.num == fitRecordLat || f.num == fitRecordLong) && v == 0x7FFFFFFF) {
+			continue
+		}
+		values[f.num] = v
+	}
+	if d.devSize > 0 {
+		if _, err := readFull(r, make([]byte, d.devSize)); err != nil {
+			return nil, fmt.Errorf("read developer data failed: %s", err)
+		}
+	}
+	return values, nil
+}
+
+func (a *Activity) apply(global uint16, values map[uint8]uint64) {
+	switch global {
+	case fitMesgRecord:
+		lat, okLat := values[fitRecordLat]
+		lon, okLon := values[fitRecordLong]
+		if !okLat || !okLon {
+			return
+		}
+		p := Point{Lat: semicircles(lat), Lon: semicircles(lon)}
+		if ts, ok := values[fitFieldTimestamp]; ok {
+			p.Time = fitEpoch.Add(time.Duration(ts) * time.Second)
+		}
+		if alt, ok := values[fitRecordEnhancedAltitude]; ok {
+			ele := float64(alt)/5 - 500
+			p.Ele = &ele
+		} else if alt, ok = values[fitRecordAltitude]; ok {
+			ele := float64(alt)/5 - 500
+			p.Ele = &ele
+		}
+		a.Points = append(a.Points, p)
+	case fitMesgSession:
+		if sport, ok := values[fitSessionSport]; ok && a.Sport == "" {
+			a.Sport = fitSports[sport]
+		}
+	}
+}
+
+func semicircles(v uint64) float64 {
+	return float64(int32(uint32(v))) * (180.0 / (1 << 31))
+}
+
+func readFull(r *bytes.Reader, buf []byte) (int, error) {
+	if r.Len() < len(buf) {
+		return 0, errors.New("unexpected end of data")
+	}
+	return r.Read(buf)
+}
@@ -0,0 +1,132 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package gpstrack
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+)
+
+// fitBuilder writes the subset of the FIT protocol the decoder reads.
+type fitBuilder struct {
+	buf bytes.Buffer
+}
+
+func (b *fitBuilder) define(local uint8, global uint16, developer bool, fields ...[2]uint8) {
+	hdr := 0x40 | local
+	if developer {
+		hdr |= 0x20
+	}
+	b.buf.WriteByte(hdr)
+	b.buf.Write([]byte{0, 0})
+	_ = binary.Write(&b.buf, binary.LittleEndian, global)
+	b.buf.WriteByte(uint8(len(fields)))
+	for _, f := range fields {
+		b.buf.Write([]byte{f[0], f[1], 0})
+	}
+	if developer {
+		b.buf.Write([]byte{1, 0, 2, 0})
+	}
+}
+
+func (b *fitBuilder) data(hdr uint8, values ...any) {
+	b.buf.WriteByte(hdr)
+	for _, v := range values {
+		_ = binary.Write(&b.buf, binary.LittleEndian, v)
+	}
+}
+
+func (b *fitBuilder) bytes() []byte {
+	body := b.buf.Bytes()
+	header := make([]byte, 14)
+	header[0] = 14
+	header[1] = 0x10
+	binary.LittleEndian.PutUint32(header[4:8], uint32(len(body)))
+	copy(header[8:12], ".FIT")
+	return append(append(header, body...), 0, 0)
+}
+
+func toSemicircles(deg float64) int32 {
+	return int32(math.Round(deg * (1 << 31) / 180))
+}
+
+func toFitAltitude(m float64) uint32 {
+	return uint32((m + 500) * 5)
+}
+
+func buildTestFIT() []byte {
+	start := uint32(time.Date(2024, 5, 1, 6, 0, 0, 0, time.UTC).Sub(fitEpoch).Seconds())
+	b := &fitBuilder{}
+	b.define(0, fitMesgRecord, false, [2]uint8{fitFieldTimestamp, 4}, [2]uint8{fitRecordLat, 4}, [2]uint8{fitRecordLong, 4}, [2]uint8{fitRecordEnhancedAltitude, 4})
+	b.data(0, start, toSemicircles(47), toSemicircles(8), toFitAltitude(400))
+	// a record without a position fix is skipped
+	b.data(0, start+5, int32(0x7FFFFFFF), int32(0x7FFFFFFF), toFitAltitude(401))
+	b.data(0, start+10, toSemicircles(47.001), toSemicircles(8), toFitAltitude(410))
+
+	b.define(2, fitMesgRecord, true, [2]uint8{fitRecordLat, 4}, [2]uint8{fitRecordLong, 4}, [2]uint8{fitRecordAltitude, 2})
+	b.data(0x80|2<<5|uint8((start+20)&0x1F), toSemicircles(47.002), toSemicircles(8), uint16(toFitAltitude(405)), uint16(0xBEEF))
+
+	b.define(1, fitMesgSession, false, [2]uint8{fitSessionSport, 1})
+	b.data(1, uint8(2))
+	return b.bytes()
+}
+
+func TestParseFIT(t *testing.T) {
+	a, err := ParseFIT(buildTestFIT())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Sport != "cycling" || len(a.Points) != 3 {
+		t.Fatalf("unexpected activity %+v", a)
+	}
+	start := time.Date(2024, 5, 1, 6, 0, 0, 0, time.UTC)
+	last := a.Points[2]
+	if math.Abs(last.Lat-47.002) > 1e-6 || math.Abs(last.Lon-8) > 1e-6 || *last.Ele != 405 || !last.Time.Equal(start.Add(20*time.Second)) {
+		t.Errorf("unexpected compressed timestamp record %+v", last)
+	}
+	if *a.Points[1].Ele != 410 || !a.Points[1].Time.Equal(start.Add(10*time.Second)) {
+		t.Errorf("unexpected record %+v", a.Points[1])
+	}
+
+	s := a.stats()
+	if math.Abs(s.DistanceM-222.4) > 0.5 || s.DurationS != 20 || s.ElevationGain != 10 || s.ElevationLoss != 5 {
+		t.Errorf("unexpected stats %+v", s)
+	}
+}
+
+func TestParseFIT_Invalid(t *testing.T) {
+	data := buildTestFIT()
+	cases := map[string][]byte{
+		"short":     data[:8],
+		"signature": append([]byte{14, 0x10, 0, 0, 0, 0, 0, 0, 'G', 'P', 'X', '!'}, make([]byte, 4)...),
+		"truncated": data[:len(data)-10],
+	}
+	for name, in := range cases {
+		if _, err := ParseFIT(in); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+
+	undefined := &fitBuilder{}
+	undefined.data(3, uint8(1))
+	if _, err := ParseFIT(undefined.bytes()); err == nil {
+		t.Error("expected error for data message without definition")
+	}
+}
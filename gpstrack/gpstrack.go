@@ -0,0 +1,293 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package gpstrack
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/logger"
+	"github.com/basenana/plugin/types"
+	"github.com/basenana/plugin/utils"
+	"go.uber.org/zap"
+)
+
+const (
+	pluginName    = "gpstrack"
+	pluginVersion = "1.0"
+
+	formatGPX = "gpx"
+	formatFIT = "fit"
+
+	defaultOutputDir     = "activities"
+	defaultThumbnailSize = 256
+	minThumbnailSize     = 64
+	maxThumbnailSize     = 2048
+)
+
+var PluginSpec = types.PluginSpec{
+	Name:    pluginName,
+	Version: pluginVersion,
+	Type:    types.TypeProcess,
+	Parameters: []types.ParameterSpec{
+		{
+			Name:        "file_path",
+			Required:    true,
+			Description: "GPX or FIT activity file",
+		},
+		{
+			Name:        "output_dir",
+			Required:    false,
+			Default:     defaultOutputDir,
+			Description: "Directory in the working path for the activity documents and thumbnails",
+		},
+		{
+			Name:        "thumbnail",
+			Required:    false,
+			Default:     "true",
+			Description: "Render a PNG route thumbnail next to each document",
+			Options:     []string{"true", "false"},
+		},
+		{
+			Name:        "thumbnail_size",
+			Required:    false,
+			Default:     strconv.Itoa(defaultThumbnailSize),
+			Description: "Thumbnail width and height in pixels",
+		},
+	},
+}
+
+type GPSTrackPlugin struct {
+	logger   *zap.SugaredLogger
+	fileRoot *utils.FileAccess
+}
+
+func NewGPSTrackPlugin(ps types.PluginCall) types.Plugin {
+	return &GPSTrackPlugin{
+		logger:   logger.NewPluginLogger(pluginName, ps.JobID),
+		fileRoot: utils.NewFileAccess(ps.WorkingPath, ps.JobWorkingPath),
+	}
+}
+
+func (p *GPSTrackPlugin) Name() string {
+	return pluginName
+}
+
+func (p *GPSTrackPlugin) Type() types.PluginType {
+	return types.TypeProcess
+}
+
+func (p *GPSTrackPlugin) Version() string {
+	return pluginVersion
+}
+
+func (p *GPSTrackPlugin) Run(ctx context.Context, request *api.Request) (*api.Response, error) {
+	filePath := api.GetStringParameter("file_path", request, "")
+	if filePath == "" {
+		return api.NewFailedResponse("file_path is required"), nil
+	}
+	outputDir := api.GetStringParameter("output_dir", request, defaultOutputDir)
+	withThumbnail := api.GetBoolParameter("thumbnail", request, true)
+	thumbnailSize := defaultThumbnailSize
+	if raw := api.GetStringParameter("thumbnail_size", request, ""); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < minThumbnailSize || n > maxThumbnailSize {
+			return api.NewFailedResponse(fmt.Sprintf("thumbnail_size must be an integer between %d and %d", minThumbnailSize, maxThumbnailSize)), nil
+		}
+		thumbnailSize = n
+	}
+
+	data, err := p.fileRoot.Read(filePath)
+	if err != nil {
+		return api.NewFailedResponse(fmt.Sprintf("read file %s failed: %s", filePath, err)), nil
+	}
+	format := detectFormat(filePath, data)
+	activities, err := parseActivities(format, data)
+	if err != nil {
+		return api.NewFailedResponse(fmt.Sprintf("parse file %s failed: %s", filePath, err)), nil
+	}
+	if len(activities) == 0 {
+		return api.NewFailedResponse(fmt.Sprintf("no track points found in %s", filePath)), nil
+	}
+	if err = p.fileRoot.MkdirAll(outputDir, 0755); err != nil {
+		return api.NewFailedResponse(fmt.Sprintf("create directory %s failed: %s", outputDir, err)), nil
+	}
+
+	fileBase := strings.TrimSuffix(path.Base(filePath), path.Ext(filePath))
+	used := make(map[string]bool)
+	results := make([]map[string]any, 0, len(activities))
+	for i := range activities {
+		a := &activities[i]
+		stats := a.stats()
+		if a.Name == "" {
+			a.Name = defaultName(fileBase, a, i, len(activities))
+		}
+		base := uniqueName(used, utils.SanitizeFilename(a.Name), i)
+
+		var thumbPath, thumbName string
+		if withThumbnail {
+			img, err := a.thumbnail(thumbnailSize)
+			if err != nil {
+				return nil, err
+			}
+			thumbPath = path.Join(outputDir, base+".png")
+			if err = p.fileRoot.Write(thumbPath, img, 0644); err != nil {
+				return api.NewFailedResponse(fmt.Sprintf("write file %s failed: %s", thumbPath, err)), nil
+			}
+			thumbName = path.Base(thumbPath)
+		}
+
+		doc := a.document(stats, thumbName)
+		docPath := path.Join(outputDir, base+".md")
+		if err = p.fileRoot.Write(docPath, []byte(doc.Content), 0644); err != nil {
+			return api.NewFailedResponse(fmt.Sprintf("write file %s failed: %s", docPath, err)), nil
+		}
+
+		result := map[string]any{
+			"file_path": docPath,
+			"name":      a.Name,
+			"sport":     a.Sport,
+			"stats":     utils.MarshalMap(stats),
+			"document":  utils.MarshalMap(doc),
+		}
+		if thumbPath != "" {
+			result["thumbnail_path"] = thumbPath
+		}
+		results = append(results, result)
+	}
+
+	p.logger.Infow("gps track imported", "file", filePath, "format", format, "activities", len(results))
+	return api.NewResponseWithResult(map[string]any{
+		"format":     format,
+		"output_dir": outputDir,
+		"activities": results,
+		"total":      len(results),
+	}), nil
+}
+
+// detectFormat trusts the FIT signature over the file extension.
+func detectFormat(filePath string, data []byte) string {
+	if len(data) >= 12 && string(data[8:12]) == ".FIT" {
+		return formatFIT
+	}
+	if strings.EqualFold(path.Ext(filePath), ".fit") {
+		return formatFIT
+	}
+	return formatGPX
+}
+
+func parseActivities(format string, data []byte) ([]Activity, error) {
+	if format == formatFIT {
+		a, err := ParseFIT(data)
+		if err != nil {
+			return nil, err
+		}
+		if len(a.Points) == 0 {
+			return nil, nil
+		}
+		return []Activity{*a}, nil
+	}
+	return ParseGPX(bytes.NewReader(data))
+}
+
+// defaultName names unnamed activities after the sport and start date, falling back to the file name.
+func defaultName(fileBase string, a *Activity, index, total int) string {
+	var parts []string
+	if a.Sport != "" {
+		parts = append(parts, strings.ReplaceAll(a.Sport, "_", " "))
+	}
+	if start := a.Points[0].Time; !start.IsZero() {
+		parts = append(parts, start.UTC().Format("2006-01-02 15:04"))
+	}
+	if len(parts) == 0 {
+		parts = append(parts, fileBase)
+		if total > 1 {
+			parts = append(parts, strconv.Itoa(index+1))
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// document renders the activity as Markdown with a stats table, Properties carry
+// the name, sport, start time and a one-line summary.
+func (a *Activity) document(s Stats, thumbName string) types.Document {
+	buf := &strings.Builder{}
+	fmt.Fprintf(buf, "# %s\n\n", a.Name)
+	if thumbName != "" {
+		fmt.Fprintf(buf, "![%s](%s)\n\n", a.Name, thumbName)
+	}
+	buf.WriteString("| Metric | Value |\n|--------|-------|\n")
+	row := func(label, value string) {
+		fmt.Fprintf(buf, "| %s | %s |\n", label, value)
+	}
+	if a.Sport != "" {
+		row("Sport", a.Sport)
+	}
+	if s.StartTime != "" {
+		row("Start", s.StartTime)
+	}
+	row("Distance", fmt.Sprintf("%.2f km", s.DistanceM/1000))
+	if s.DurationS > 0 {
+		row("Duration", formatDuration(s.DurationS))
+		row("Moving time", formatDuration(s.MovingTimeS))
+		row("Average speed", fmt.Sprintf("%.1f km/h", s.AvgSpeedKmh))
+		row("Max speed", fmt.Sprintf("%.1f km/h", s.MaxSpeedKmh))
+	}
+	if s.MinElevation != nil {
+		row("Elevation gain", fmt.Sprintf("%.0f m", s.ElevationGain))
+		row("Elevation loss", fmt.Sprintf("%.0f m", s.ElevationLoss))
+		row("Elevation range", fmt.Sprintf("%.0f - %.0f m", *s.MinElevation, *s.MaxElevation))
+	}
+	row("Points", strconv.Itoa(s.Points))
+
+	summary := []string{fmt.Sprintf("%.2f km", s.DistanceM/1000)}
+	if s.DurationS > 0 {
+		summary = append(summary, formatDuration(s.DurationS))
+	}
+	if s.MinElevation != nil {
+		summary = append(summary, fmt.Sprintf("+%.0f m", s.ElevationGain))
+	}
+	props := types.Properties{
+		Title:    a.Name,
+		Abstract: strings.Join(summary, ", "),
+	}
+	if a.Sport != "" {
+		props.Keywords = []string{a.Sport}
+	}
+	if start := a.Points[0].Time; !start.IsZero() {
+		props.PublishAt = start.Unix()
+		props.Year = strconv.Itoa(start.UTC().Year())
+	}
+	return types.Document{Content: buf.String(), Properties: props}
+}
+
+func uniqueName(used map[string]bool, name string, index int) string {
+	if name == "" {
+		name = fmt.Sprintf("activity-%d", index+1)
+	}
+	candidate := name
+	for i := 2; used[strings.ToLower(candidate)]; i++ {
+		candidate = fmt.Sprintf("%s-%d", name, i)
+	}
+	used[strings.ToLower(candidate)] = true
+	return candidate
+}
@@ -0,0 +1,161 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package gpstrack
+
+import (
+	"bytes"
+	"context"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/logger"
+	"github.com/basenana/plugin/types"
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) {
+	logger.SetLogger(zap.NewNop().Sugar())
+	os.Exit(m.Run())
+}
+
+func newGPSTrackPlugin(workdir string) *GPSTrackPlugin {
+	return NewGPSTrackPlugin(types.PluginCall{JobID: "job-1", WorkingPath: workdir}).(*GPSTrackPlugin)
+}
+
+func writeFile(t *testing.T, dir, name string, data []byte) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGPSTrackPlugin_Run_GPX(t *testing.T) {
+	workdir := t.TempDir()
+	writeFile(t, workdir, "lake.gpx", []byte(testGPX))
+	p := newGPSTrackPlugin(workdir)
+
+	resp, err := p.Run(context.Background(), &api.Request{Parameter: map[string]any{"file_path": "lake.gpx", "thumbnail_size": "128"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.IsSucceed {
+		t.Fatalf("Run not succeed: %s", resp.Message)
+	}
+	if resp.Results["format"] != formatGPX || resp.Results["total"] != 2 {
+		t.Errorf("unexpected results %v", resp.Results)
+	}
+
+	activities := resp.Results["activities"].([]map[string]any)
+	run, unnamed := activities[0], activities[1]
+	if run["file_path"] != "activities/Morning_Run.md" || run["thumbnail_path"] != "activities/Morning_Run.png" {
+		t.Errorf("unexpected paths %v", run)
+	}
+	if unnamed["name"] != "lake 2" || unnamed["file_path"] != "activities/lake_2.md" {
+		t.Errorf("expected unnamed track to be named after the file, got %v", unnamed)
+	}
+
+	doc := run["document"].(map[string]any)
+	props := doc["properties"].(map[string]any)
+	if props["title"] != "Morning Run" || props["abstract"] != "0.22 km, 5m00s, +10 m" || props["year"] != "2024" {
+		t.Errorf("unexpected properties %v", props)
+	}
+	if keywords := props["keywords"].([]any); len(keywords) != 1 || keywords[0] != "running" {
+		t.Errorf("unexpected keywords %v", keywords)
+	}
+	content := doc["content"].(string)
+	for _, want := range []string{"# Morning Run", "![Morning Run](Morning_Run.png)", "| Elevation gain | 10 m |", "| Moving time | 2m00s |"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("document missing %q:\n%s", want, content)
+		}
+	}
+	if stats := run["stats"].(map[string]any); stats["points"] != float64(4) {
+		t.Errorf("unexpected stats %v", stats)
+	}
+
+	data, err := os.ReadFile(filepath.Join(workdir, "activities", "Morning_Run.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := img.Bounds(); b.Dx() != 128 || b.Dy() != 128 {
+		t.Errorf("unexpected thumbnail size %v", b)
+	}
+	if _, err = os.Stat(filepath.Join(workdir, "activities", "Morning_Run.md")); err != nil {
+		t.Errorf("document not written: %s", err)
+	}
+}
+
+func TestGPSTrackPlugin_Run_FIT(t *testing.T) {
+	workdir := t.TempDir()
+	writeFile(t, workdir, "ride.bin", buildTestFIT())
+	p := newGPSTrackPlugin(workdir)
+
+	resp, err := p.Run(context.Background(), &api.Request{Parameter: map[string]any{
+		"file_path": "ride.bin", "output_dir": "log", "thumbnail": "false",
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.IsSucceed {
+		t.Fatalf("Run not succeed: %s", resp.Message)
+	}
+	if resp.Results["format"] != formatFIT || resp.Results["total"] != 1 {
+		t.Errorf("unexpected results %v", resp.Results)
+	}
+	activity := resp.Results["activities"].([]map[string]any)[0]
+	if activity["name"] != "cycling 2024-05-01 06:00" || activity["file_path"] != "log/cycling_2024-05-01_06_00.md" {
+		t.Errorf("unexpected activity %v", activity)
+	}
+	if _, ok := activity["thumbnail_path"]; ok {
+		t.Errorf("thumbnail should be disabled")
+	}
+	props := activity["document"].(map[string]any)["properties"].(map[string]any)
+	if props["publish_at"] != float64(1714543200) {
+		t.Errorf("unexpected publish_at %v", props["publish_at"])
+	}
+}
+
+func TestGPSTrackPlugin_Run_Failures(t *testing.T) {
+	workdir := t.TempDir()
+	writeFile(t, workdir, "empty.gpx", []byte(`<gpx><trk><name>x</name></trk></gpx>`))
+	writeFile(t, workdir, "bad.fit", []byte("garbage"))
+	p := newGPSTrackPlugin(workdir)
+
+	cases := map[string]map[string]any{
+		"file_path is required":      {},
+		"thumbnail_size must be":     {"file_path": "empty.gpx", "thumbnail_size": "10"},
+		"read file missing.gpx":      {"file_path": "missing.gpx"},
+		"no track points found":      {"file_path": "empty.gpx"},
+		"parse file bad.fit failed:": {"file_path": "bad.fit"},
+	}
+	for want, params := range cases {
+		resp, err := p.Run(context.Background(), &api.Request{Parameter: params})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.IsSucceed || !strings.Contains(resp.Message, want) {
+			t.Errorf("expected failure %q, got %+v", want, resp)
+		}
+	}
+}
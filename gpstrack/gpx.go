@@ -0,0 +1,94 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package gpstrack
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+type gpxFile struct {
+	Metadata struct {
+		Name string `xml:"name"`
+	} `xml:"metadata"`
+	Tracks []struct {
+		Name     string `xml:"name"`
+		Type     string `xml:"type"`
+		Segments []struct {
+			Points []gpxPoint `xml:"trkpt"`
+		} `xml:"trkseg"`
+	} `xml:"trk"`
+	Routes []struct {
+		Name   string     `xml:"name"`
+		Type   string     `xml:"type"`
+		Points []gpxPoint `xml:"rtept"`
+	} `xml:"rte"`
+}
+
+type gpxPoint struct {
+	Lat  float64  `xml:"lat,attr"`
+	Lon  float64  `xml:"lon,attr"`
+	Ele  *float64 `xml:"ele"`
+	Time string   `xml:"time"`
+}
+
+// ParseGPX returns one activity per track, routes are used when the file has no tracks.
+func ParseGPX(r io.Reader) ([]Activity, error) {
+	var f gpxFile
+	if err := xml.NewDecoder(r).Decode(&f); err != nil {
+		return nil, fmt.Errorf("decode gpx failed: %s", err)
+	}
+
+	var activities []Activity
+	for _, trk := range f.Tracks {
+		a := Activity{Name: strings.TrimSpace(trk.Name), Sport: strings.TrimSpace(trk.Type)}
+		for _, seg := range trk.Segments {
+			for _, p := range seg.Points {
+				a.Points = append(a.Points, p.point())
+			}
+		}
+		if len(a.Points) > 0 {
+			activities = append(activities, a)
+		}
+	}
+	if len(activities) == 0 {
+		for _, rte := range f.Routes {
+			a := Activity{Name: strings.TrimSpace(rte.Name), Sport: strings.TrimSpace(rte.Type)}
+			for _, p := range rte.Points {
+				a.Points = append(a.Points, p.point())
+			}
+			if len(a.Points) > 0 {
+				activities = append(activities, a)
+			}
+		}
+	}
+	if len(activities) == 1 && activities[0].Name == "" {
+		activities[0].Name = strings.TrimSpace(f.Metadata.Name)
+	}
+	return activities, nil
+}
+
+func (p gpxPoint) point() Point {
+	pt := Point{Lat: p.Lat, Lon: p.Lon, Ele: p.Ele}
+	if t, err := time.Parse(time.RFC3339, strings.TrimSpace(p.Time)); err == nil {
+		pt.Time = t
+	}
+	return pt
+}
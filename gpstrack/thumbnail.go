@@ -0,0 +1,97 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package gpstrack
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+)
+
+var (
+	thumbBackground = color.RGBA{R: 0xF7, G: 0xF7, B: 0xF4, A: 0xFF}
+	thumbRoute      = color.RGBA{R: 0xE4, G: 0x57, B: 0x1F, A: 0xFF}
+	thumbStart      = color.RGBA{R: 0x2E, G: 0x9E, B: 0x44, A: 0xFF}
+	thumbEnd        = color.RGBA{R: 0x22, G: 0x22, B: 0x22, A: 0xFF}
+)
+
+// thumbnail draws the route on a square PNG, projected equirectangularly around
+// the middle latitude and scaled to keep its aspect ratio.
+func (a *Activity) thumbnail(size int) ([]byte, error) {
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.Draw(img, img.Bounds(), image.NewUniform(thumbBackground), image.Point{}, draw.Src)
+
+	if len(a.Points) > 0 {
+		minLat, minLon, maxLat, maxLon := a.Points[0].Lat, a.Points[0].Lon, a.Points[0].Lat, a.Points[0].Lon
+		for _, p := range a.Points {
+			minLat, maxLat = math.Min(minLat, p.Lat), math.Max(maxLat, p.Lat)
+			minLon, maxLon = math.Min(minLon, p.Lon), math.Max(maxLon, p.Lon)
+		}
+		kx := math.Cos((minLat + maxLat) / 2 * math.Pi / 180)
+		spanX, spanY := (maxLon-minLon)*kx, maxLat-minLat
+		pad := float64(size) * 0.08
+		scale := (float64(size) - 2*pad) / math.Max(math.Max(spanX, spanY), 1e-9)
+		offX := (float64(size) - spanX*scale) / 2
+		offY := (float64(size) - spanY*scale) / 2
+		project := func(p Point) (float64, float64) {
+			return offX + (p.Lon-minLon)*kx*scale, offY + (maxLat-p.Lat)*scale
+		}
+
+		width := math.Max(1.5, float64(size)/100)
+		for i := 1; i < len(a.Points); i++ {
+			x0, y0 := project(a.Points[i-1])
+			x1, y1 := project(a.Points[i])
+			line(img, x0, y0, x1, y1, width, thumbRoute)
+		}
+		x, y := project(a.Points[len(a.Points)-1])
+		dot(img, x, y, width*2, thumbEnd)
+		x, y = project(a.Points[0])
+		dot(img, x, y, width*2, thumbStart)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := png.Encode(buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func line(img *image.RGBA, x0, y0, x1, y1, width float64, c color.RGBA) {
+	steps := int(math.Ceil(math.Max(math.Abs(x1-x0), math.Abs(y1-y0))))
+	for s := 0; s <= steps; s++ {
+		t := 0.0
+		if steps > 0 {
+			t = float64(s) / float64(steps)
+		}
+		dot(img, x0+(x1-x0)*t, y0+(y1-y0)*t, width/2, c)
+	}
+}
+
+func dot(img *image.RGBA, cx, cy, r float64, c color.RGBA) {
+	bounds := image.Rect(int(cx-r)-1, int(cy-r)-1, int(cx+r)+2, int(cy+r)+2).Intersect(img.Bounds())
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			dx, dy := float64(x)+0.5-cx, float64(y)+0.5-cy
+			if dx*dx+dy*dy <= r*r {
+				img.SetRGBA(x, y, c)
+			}
+		}
+	}
+}
@@ -0,0 +1,151 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package gpstrack
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	earthRadius = 6371008.8 // meters
+
+	// elevationThreshold filters GPS altitude noise out of the climb totals.
+	elevationThreshold = 2.0
+	// movingSpeed is the slowest speed in m/s that counts as moving.
+	movingSpeed = 0.5
+)
+
+type Point struct {
+	Lat  float64
+	Lon  float64
+	Ele  *float64
+	Time time.Time
+}
+
+// Activity is one track of a GPX file or the whole recording of a FIT file.
+type Activity struct {
+	Name   string
+	Sport  string
+	Points []Point
+}
+
+type Stats struct {
+	StartTime     string     `json:"start_time,omitempty"`
+	EndTime       string     `json:"end_time,omitempty"`
+	Points        int        `json:"points"`
+	DistanceM     float64    `json:"distance_m"`
+	DurationS     float64    `json:"duration_s"`
+	MovingTimeS   float64    `json:"moving_time_s"`
+	AvgSpeedKmh   float64    `json:"avg_speed_kmh"`
+	MaxSpeedKmh   float64    `json:"max_speed_kmh"`
+	ElevationGain float64    `json:"elevation_gain_m"`
+	ElevationLoss float64    `json:"elevation_loss_m"`
+	MinElevation  *float64   `json:"min_elevation_m,omitempty"`
+	MaxElevation  *float64   `json:"max_elevation_m,omitempty"`
+	Bounds        [4]float64 `json:"bounds"` // min lat, min lon, max lat, max lon
+}
+
+func (a *Activity) stats() Stats {
+	s := Stats{Points: len(a.Points)}
+	if len(a.Points) == 0 {
+		return s
+	}
+	first, last := a.Points[0], a.Points[len(a.Points)-1]
+	if !first.Time.IsZero() {
+		s.StartTime = first.Time.UTC().Format(time.RFC3339)
+	}
+	if !last.Time.IsZero() {
+		s.EndTime = last.Time.UTC().Format(time.RFC3339)
+	}
+	if !first.Time.IsZero() && last.Time.After(first.Time) {
+		s.DurationS = last.Time.Sub(first.Time).Seconds()
+	}
+
+	s.Bounds = [4]float64{first.Lat, first.Lon, first.Lat, first.Lon}
+	var anchor *float64
+	for i, p := range a.Points {
+		s.Bounds[0], s.Bounds[1] = math.Min(s.Bounds[0], p.Lat), math.Min(s.Bounds[1], p.Lon)
+		s.Bounds[2], s.Bounds[3] = math.Max(s.Bounds[2], p.Lat), math.Max(s.Bounds[3], p.Lon)
+
+		if p.Ele != nil {
+			ele := *p.Ele
+			if s.MinElevation == nil || ele < *s.MinElevation {
+				s.MinElevation = &ele
+			}
+			if s.MaxElevation == nil || ele > *s.MaxElevation {
+				s.MaxElevation = &ele
+			}
+			switch {
+			case anchor == nil:
+				anchor = &ele
+			case ele-*anchor >= elevationThreshold:
+				s.ElevationGain += ele - *anchor
+				anchor = &ele
+			case *anchor-ele >= elevationThreshold:
+				s.ElevationLoss += *anchor - ele
+				anchor = &ele
+			}
+		}
+
+		if i == 0 {
+			continue
+		}
+		prev := a.Points[i-1]
+		d := haversine(prev, p)
+		s.DistanceM += d
+		if !prev.Time.IsZero() && p.Time.After(prev.Time) {
+			dt := p.Time.Sub(prev.Time).Seconds()
+			if speed := d / dt; speed >= movingSpeed {
+				s.MovingTimeS += dt
+				s.MaxSpeedKmh = math.Max(s.MaxSpeedKmh, speed*3.6)
+			}
+		}
+	}
+	if s.MovingTimeS > 0 {
+		s.AvgSpeedKmh = s.DistanceM / s.MovingTimeS * 3.6
+	}
+
+	s.DistanceM = round(s.DistanceM, 1)
+	s.AvgSpeedKmh = round(s.AvgSpeedKmh, 2)
+	s.MaxSpeedKmh = round(s.MaxSpeedKmh, 2)
+	s.ElevationGain = round(s.ElevationGain, 1)
+	s.ElevationLoss = round(s.ElevationLoss, 1)
+	return s
+}
+
+func haversine(a, b Point) float64 {
+	lat1, lat2 := a.Lat*math.Pi/180, b.Lat*math.Pi/180
+	dLat, dLon := lat2-lat1, (b.Lon-a.Lon)*math.Pi/180
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadius * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+func round(v float64, decimals int) float64 {
+	p := math.Pow(10, float64(decimals))
+	return math.Round(v*p) / p
+}
+
+func formatDuration(seconds float64) string {
+	d := time.Duration(seconds) * time.Second
+	h, m, s := int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60
+	if h > 0 {
+		return fmt.Sprintf("%dh%02dm%02ds", h, m, s)
+	}
+	return fmt.Sprintf("%dm%02ds", m, s)
+}
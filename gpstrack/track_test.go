@@ -0,0 +1,124 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package gpstrack
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testGPX = `<?xml version="1.0" encoding="UTF-8"?>
+<gpx version="1.1" creator="test" xmlns="http://www.topografix.com/GPX/1/1">
+  <metadata><name>Lake loop</name></metadata>
+  <trk>
+    <name>Morning Run</name>
+    <type>running</type>
+    <trkseg>
+      <trkpt lat="47.000" lon="8.000"><ele>400</ele><time>2024-05-01T06:00:00Z</time></trkpt>
+      <trkpt lat="47.001" lon="8.000"><ele>410</ele><time>2024-05-01T06:01:00Z</time></trkpt>
+    </trkseg>
+    <trkseg>
+      <trkpt lat="47.002" lon="8.000"><ele>405</ele><time>2024-05-01T06:02:00Z</time></trkpt>
+      <trkpt lat="47.002" lon="8.000"><ele>405.5</ele><time>2024-05-01T06:05:00Z</time></trkpt>
+    </trkseg>
+  </trk>
+  <trk>
+    <trkseg>
+      <trkpt lat="46.000" lon="7.000"/>
+      <trkpt lat="46.000" lon="7.001"/>
+    </trkseg>
+  </trk>
+  <trk><name>Empty</name></trk>
+</gpx>`
+
+func TestParseGPX(t *testing.T) {
+	activities, err := ParseGPX(strings.NewReader(testGPX))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(activities) != 2 {
+		t.Fatalf("expected 2 activities, got %d", len(activities))
+	}
+	run := activities[0]
+	if run.Name != "Morning Run" || run.Sport != "running" || len(run.Points) != 4 {
+		t.Errorf("unexpected first activity %+v", run)
+	}
+	if run.Points[1].Ele == nil || *run.Points[1].Ele != 410 || !run.Points[1].Time.Equal(time.Date(2024, 5, 1, 6, 1, 0, 0, time.UTC)) {
+		t.Errorf("unexpected point %+v", run.Points[1])
+	}
+	if activities[1].Name != "" || activities[1].Points[0].Ele != nil || !activities[1].Points[0].Time.IsZero() {
+		t.Errorf("unexpected second activity %+v", activities[1])
+	}
+
+	routes, err := ParseGPX(strings.NewReader(`<gpx><metadata><name>Planned</name></metadata><rte><rtept lat="1" lon="2"/></rte></gpx>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(routes) != 1 || routes[0].Name != "Planned" || len(routes[0].Points) != 1 {
+		t.Errorf("expected the route with the metadata name, got %+v", routes)
+	}
+
+	if _, err = ParseGPX(strings.NewReader("not xml")); err == nil {
+		t.Error("expected error for invalid gpx")
+	}
+}
+
+func TestActivityStats(t *testing.T) {
+	activities, err := ParseGPX(strings.NewReader(testGPX))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := activities[0].stats()
+
+	if math.Abs(s.DistanceM-222.4) > 0.5 {
+		t.Errorf("expected about 222.4 m, got %v", s.DistanceM)
+	}
+	if s.DurationS != 300 || s.MovingTimeS != 120 {
+		t.Errorf("expected 300s duration and 120s moving, got %v and %v", s.DurationS, s.MovingTimeS)
+	}
+	// the final 0.5 m rise is below the noise threshold
+	if s.ElevationGain != 10 || s.ElevationLoss != 5 {
+		t.Errorf("expected +10/-5 m, got +%v/-%v", s.ElevationGain, s.ElevationLoss)
+	}
+	if *s.MinElevation != 400 || *s.MaxElevation != 410 {
+		t.Errorf("unexpected elevation range %v - %v", *s.MinElevation, *s.MaxElevation)
+	}
+	if math.Abs(s.AvgSpeedKmh-6.67) > 0.05 || math.Abs(s.MaxSpeedKmh-6.67) > 0.05 {
+		t.Errorf("unexpected speeds avg %v max %v", s.AvgSpeedKmh, s.MaxSpeedKmh)
+	}
+	if s.StartTime != "2024-05-01T06:00:00Z" || s.EndTime != "2024-05-01T06:05:00Z" {
+		t.Errorf("unexpected times %s - %s", s.StartTime, s.EndTime)
+	}
+	if s.Bounds != [4]float64{47, 8, 47.002, 8} {
+		t.Errorf("unexpected bounds %v", s.Bounds)
+	}
+
+	untimed := activities[1].stats()
+	if untimed.DurationS != 0 || untimed.MovingTimeS != 0 || untimed.MinElevation != nil || untimed.DistanceM == 0 {
+		t.Errorf("unexpected stats without time and elevation %+v", untimed)
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	for seconds, want := range map[float64]string{59: "0m59s", 125: "2m05s", 3725: "1h02m05s"} {
+		if got := formatDuration(seconds); got != want {
+			t.Errorf("formatDuration(%v) = %s, want %s", seconds, got, want)
+		}
+	}
+}
@@ -0,0 +1,385 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package invoice
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	FieldVendor   = "vendor"
+	FieldNumber   = "invoice_number"
+	FieldDate     = "date"
+	FieldDueDate  = "due_date"
+	FieldCurrency = "currency"
+	FieldSubtotal = "subtotal"
+	FieldTax      = "tax"
+	FieldTotal    = "total"
+)
+
+type Invoice struct {
+	Vendor        string     `json:"vendor"`
+	InvoiceNumber string     `json:"invoice_number,omitempty"`
+	Date          string     `json:"date,omitempty"`
+	DueDate       string     `json:"due_date,omitempty"`
+	Currency      string     `json:"currency,omitempty"`
+	Subtotal      *float64   `json:"subtotal,omitempty"`
+	Tax           *float64   `json:"tax,omitempty"`
+	Total         *float64   `json:"total,omitempty"`
+	LineItems     []LineItem `json:"line_items"`
+}
+
+type LineItem struct {
+	Description string   `json:"description"`
+	Quantity    *float64 `json:"quantity,omitempty"`
+	UnitPrice   *float64 `json:"unit_price,omitempty"`
+	Amount      float64  `json:"amount"`
+}
+
+// missing returns the fields every invoice is expected to have but this one lacks.
+func (inv *Invoice) missing() []string {
+	fields := make([]string, 0)
+	if inv.Vendor == "" {
+		fields = append(fields, FieldVendor)
+	}
+	if inv.Date == "" {
+		fields = append(fields, FieldDate)
+	}
+	if inv.Total == nil {
+		fields = append(fields, FieldTotal)
+	}
+	return fields
+}
+
+// merge fills the fields of inv that are still empty from other.
+func (inv *Invoice) merge(other *Invoice) {
+	if inv.Vendor == "" {
+		inv.Vendor = other.Vendor
+	}
+	if inv.InvoiceNumber == "" {
+		inv.InvoiceNumber = other.InvoiceNumber
+	}
+	if inv.Date == "" {
+		inv.Date = other.Date
+	}
+	if inv.DueDate == "" {
+		inv.DueDate = other.DueDate
+	}
+	if inv.Currency == "" {
+		inv.Currency = other.Currency
+	}
+	if inv.Subtotal == nil {
+		inv.Subtotal = other.Subtotal
+	}
+	if inv.Tax == nil {
+		inv.Tax = other.Tax
+	}
+	if inv.Total == nil {
+		inv.Total = other.Total
+	}
+	if len(inv.LineItems) == 0 {
+		inv.LineItems = other.LineItems
+	}
+}
+
+// Template holds the extraction rules for one vendor layout. Field patterns use their
+// first capture group; the line item pattern uses the named groups description,
+// quantity, unit_price and amount.
+type Template struct {
+	Name        string            `json:"name"`
+	Match       string            `json:"match,omitempty"`
+	Vendor      string            `json:"vendor,omitempty"`
+	Fields      map[string]string `json:"fields,omitempty"`
+	LineItem    string            `json:"line_item,omitempty"`
+	DateFormats []string          `json:"date_formats,omitempty"`
+}
+
+const genericTemplateName = "generic"
+
+var (
+	genericFields = map[string]string{
+		FieldNumber:   `(?im)\b(?:invoice|receipt|bill)\s*(?:no\.?|number|num|#)\s*[:#]?\s*([A-Z0-9][A-Z0-9\-/]*)`,
+		FieldDate:     `(?im)^\s*(?:invoice\s+|receipt\s+|issue\s+)?date(?:\s+of\s+issue)?\s*[:]?\s*(.+?)\s*$`,
+		FieldDueDate:  `(?im)^\s*(?:payment\s+)?due(?:\s+date)?\s*[:]?\s*(.+?)\s*$`,
+		FieldSubtotal: `(?im)^\s*sub\s*-?total\b[^\d\n]*([\d.,]+)\s*$`,
+		FieldTax:      `(?im)^\s*(?:sales\s+)?(?:tax|vat|gst)\b[^\n]*?([\d.,]+)\s*$`,
+		FieldTotal:    `(?im)^\s*(?:grand\s+)?total\b(?:\s+(?:due|amount))?[^\d\n]*([\d.,]+)\s*$`,
+	}
+	genericLineItems = []string{
+		`^(?P<description>.*?[A-Za-z].*?)\s+(?P<quantity>\d+(?:[.,]\d+)?)\s*(?:x|@|×)?\s+\D{0,3}(?P<unit_price>\d[\d.,]*)\s+\D{0,3}(?P<amount>\d[\d.,]*)$`,
+		`^(?P<description>.*?[A-Za-z].*?)\s{2,}\D{0,3}(?P<amount>\d[\d.,]*\d)$`,
+	}
+	// lines with these words are totals, not items
+	summaryLine = regexp.MustCompile(`(?i)\b(sub\s*-?total|total|tax|vat|gst|balance|amount due|paid|change|discount)\b`)
+
+	defaultDateFormats = []string{
+		"2006-01-02", "2006/01/02", "01/02/2006", "1/2/2006", "02.01.2006", "2.1.2006",
+		"Jan 2, 2006", "January 2, 2006", "2 Jan 2006", "2 January 2006", "Jan 2 2006", "02-Jan-2006",
+	}
+	currencySymbols = [][2]string{{"€", "EUR"}, {"£", "GBP"}, {"¥", "CNY"}, {"₹", "INR"}, {"$", "USD"}}
+	currencyCode    = regexp.MustCompile(`\b(USD|EUR|GBP|CNY|RMB|JPY|CAD|AUD|CHF|INR|HKD|SGD)\b`)
+)
+
+// compiledTemplate is a Template with its patterns compiled, the generic rules fill every gap.
+type compiledTemplate struct {
+	name        string
+	match       *regexp.Regexp
+	vendor      string
+	fields      map[string]*regexp.Regexp
+	lineItems   []*regexp.Regexp
+	dateFormats []string
+}
+
+func compileTemplate(t Template) (*compiledTemplate, error) {
+	ct := &compiledTemplate{
+		name:        t.Name,
+		vendor:      t.Vendor,
+		fields:      make(map[string]*regexp.Regexp),
+		dateFormats: append(append([]string{}, t.DateFormats...), defaultDateFormats...),
+	}
+	if ct.name == "" {
+		return nil, fmt.Errorf("template name is required")
+	}
+	var err error
+	if t.Match != "" {
+		if ct.match, err = regexp.Compile(t.Match); err != nil {
+			return nil, fmt.Errorf("template %s: invalid match: %s", t.Name, err)
+		}
+	}
+
+	for field, pattern := range genericFields {
+		ct.fields[field] = regexp.MustCompile(pattern)
+	}
+	for field, pattern := range t.Fields {
+		if _, ok := genericFields[field]; !ok && field != FieldVendor && field != FieldCurrency {
+			return nil, fmt.Errorf("template %s: unknown field %s", t.Name, field)
+		}
+		if ct.fields[field], err = regexp.Compile(pattern); err != nil {
+			return nil, fmt.Errorf("template %s: invalid %s pattern: %s", t.Name, field, err)
+		}
+	}
+
+	if t.LineItem != "" {
+		re, err := regexp.Compile(t.LineItem)
+		if err != nil {
+			return nil, fmt.Errorf("template %s: invalid line_item pattern: %s", t.Name, err)
+		}
+		if re.SubexpIndex("description") < 0 || re.SubexpIndex("amount") < 0 {
+			return nil, fmt.Errorf("template %s: line_item needs the description and amount groups", t.Name)
+		}
+		ct.lineItems = append(ct.lineItems, re)
+	}
+	for _, pattern := range genericLineItems {
+		ct.lineItems = append(ct.lineItems, regexp.MustCompile(pattern))
+	}
+	return ct, nil
+}
+
+func (ct *compiledTemplate) matches(text string) bool {
+	return ct.match == nil || ct.match.MatchString(text)
+}
+
+// extract applies the template to the document text and returns the invoice
+// with warnings about values that could not be parsed.
+func (ct *compiledTemplate) extract(text string) (*Invoice, []string) {
+	var (
+		inv      = &Invoice{Vendor: ct.vendor, LineItems: []LineItem{}}
+		warnings = make([]string, 0)
+	)
+
+	capture := func(field string) string {
+		re, ok := ct.fields[field]
+		if !ok {
+			return ""
+		}
+		if m := re.FindStringSubmatch(text); len(m) > 1 {
+			return strings.TrimSpace(m[1])
+		}
+		return ""
+	}
+	amount := func(field string) *float64 {
+		raw := capture(field)
+		if raw == "" {
+			return nil
+		}
+		v, err := parseAmount(raw)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("parse %s [%s] failed", field, raw))
+			return nil
+		}
+		return &v
+	}
+	date := func(field string) string {
+		raw := capture(field)
+		if raw == "" {
+			return ""
+		}
+		d, ok := parseDate(raw, ct.dateFormats)
+		if !ok {
+			warnings = append(warnings, fmt.Sprintf("parse %s [%s] failed", field, raw))
+			return ""
+		}
+		return d
+	}
+
+	if inv.Vendor == "" {
+		if inv.Vendor = capture(FieldVendor); inv.Vendor == "" {
+			inv.Vendor = firstLine(text)
+		}
+	}
+	inv.InvoiceNumber = capture(FieldNumber)
+	inv.Date = date(FieldDate)
+	inv.DueDate = date(FieldDueDate)
+	if inv.Currency = strings.ToUpper(capture(FieldCurrency)); inv.Currency == "" {
+		inv.Currency = detectCurrency(text)
+	}
+	inv.Subtotal = amount(FieldSubtotal)
+	inv.Tax = amount(FieldTax)
+	inv.Total = amount(FieldTotal)
+	inv.LineItems = ct.extractLineItems(text)
+	return inv, warnings
+}
+
+func (ct *compiledTemplate) extractLineItems(text string) []LineItem {
+	items := make([]LineItem, 0)
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || summaryLine.MatchString(line) {
+			continue
+		}
+		for _, re := range ct.lineItems {
+			m := re.FindStringSubmatch(line)
+			if m == nil {
+				continue
+			}
+			group := func(name string) string {
+				if i := re.SubexpIndex(name); i >= 0 {
+					return strings.TrimSpace(m[i])
+				}
+				return ""
+			}
+			amountValue, err := parseAmount(group("amount"))
+			if err != nil {
+				continue
+			}
+			item := LineItem{Description: group("description"), Amount: amountValue}
+			if v, err := parseAmount(group("quantity")); err == nil {
+				item.Quantity = &v
+			}
+			if v, err := parseAmount(group("unit_price")); err == nil {
+				item.UnitPrice = &v
+			}
+			items = append(items, item)
+			break
+		}
+	}
+	return items
+}
+
+// validate cross-checks line items, subtotal, tax and total.
+func validate(inv *Invoice) []string {
+	var warnings []string
+	if len(inv.LineItems) > 0 {
+		var sum float64
+		for _, item := range inv.LineItems {
+			sum += item.Amount
+			if item.Quantity != nil && item.UnitPrice != nil && !amountEqual(*item.Quantity**item.UnitPrice, item.Amount) {
+				warnings = append(warnings, fmt.Sprintf("line item %q: quantity x unit price is %.2f, amount is %.2f",
+					item.Description, *item.Quantity**item.UnitPrice, item.Amount))
+			}
+		}
+		expected := inv.Subtotal
+		if expected == nil && inv.Tax == nil {
+			expected = inv.Total
+		}
+		if expected != nil && !amountEqual(sum, *expected) {
+			warnings = append(warnings, fmt.Sprintf("line items sum to %.2f, expected %.2f", sum, *expected))
+		}
+	}
+	if inv.Subtotal != nil && inv.Tax != nil && inv.Total != nil && !amountEqual(*inv.Subtotal+*inv.Tax, *inv.Total) {
+		warnings = append(warnings, fmt.Sprintf("subtotal %.2f plus tax %.2f does not match total %.2f", *inv.Subtotal, *inv.Tax, *inv.Total))
+	}
+	return warnings
+}
+
+func amountEqual(a, b float64) bool {
+	return math.Abs(a-b) < 0.01
+}
+
+// parseAmount accepts 1,234.56 as well as 1.234,56 and strips currency symbols.
+func parseAmount(raw string) (float64, error) {
+	raw = strings.Map(func(r rune) rune {
+		if (r >= '0' && r <= '9') || r == '.' || r == ',' || r == '-' {
+			return r
+		}
+		return -1
+	}, raw)
+	if raw == "" {
+		return 0, fmt.Errorf("no number")
+	}
+
+	lastDot, lastComma := strings.LastIndex(raw, "."), strings.LastIndex(raw, ",")
+	switch {
+	case lastComma > lastDot && len(raw)-lastComma-1 != 3:
+		// thousands groups have three digits, so this comma is the decimal separator
+		raw = strings.ReplaceAll(raw, ".", "")
+		raw = strings.Replace(raw, ",", ".", 1)
+	default:
+		raw = strings.ReplaceAll(raw, ",", "")
+	}
+	return strconv.ParseFloat(raw, 64)
+}
+
+// parseDate returns the date in 2006-01-02 form, trying each layout on the raw value.
+func parseDate(raw string, layouts []string) (string, bool) {
+	raw = strings.TrimSpace(strings.TrimRight(raw, "."))
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, raw); err == nil {
+			return t.Format("2006-01-02"), true
+		}
+	}
+	return "", false
+}
+
+func detectCurrency(text string) string {
+	if m := currencyCode.FindString(text); m != "" {
+		if m == "RMB" {
+			return "CNY"
+		}
+		return m
+	}
+	for _, symbol := range currencySymbols {
+		if strings.Contains(text, symbol[0]) {
+			return symbol[1]
+		}
+	}
+	return ""
+}
+
+func firstLine(text string) string {
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
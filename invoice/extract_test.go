@@ -0,0 +1,173 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package invoice
+
+import (
+	"strings"
+	"testing"
+)
+
+const sampleInvoice = `ACME Supplies Ltd.
+123 Market Street
+
+Invoice No: INV-2024-0042
+Invoice Date: 2024-03-05
+Due Date: 2024-04-04
+
+Description        Qty   Unit Price   Amount
+Paper A4 box       2     $12.50       $25.00
+Ink cartridge      3     $20.00       $60.00
+Delivery                              $15.00
+
+Subtotal: $100.00
+Tax (10%): $10.00
+Total: $110.00
+`
+
+func float(v float64) *float64 {
+	return &v
+}
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want float64
+	}{
+		{"$1,234.56", 1234.56},
+		{"1.234,56 €", 1234.56},
+		{"12,5", 12.5},
+		{"100", 100},
+		{"-3.20", -3.2},
+	}
+	for _, tt := range tests {
+		got, err := parseAmount(tt.raw)
+		if err != nil || !amountEqual(got, tt.want) {
+			t.Errorf("parseAmount(%q) = %v, %v, want %v", tt.raw, got, err, tt.want)
+		}
+	}
+	if _, err := parseAmount("n/a"); err == nil {
+		t.Errorf("parseAmount(n/a) expected error")
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	for raw, want := range map[string]string{
+		"2024-03-05":    "2024-03-05",
+		"03/05/2024":    "2024-03-05",
+		"5.3.2024":      "2024-03-05",
+		"March 5, 2024": "2024-03-05",
+		"5 Mar 2024":    "2024-03-05",
+	} {
+		if got, ok := parseDate(raw, defaultDateFormats); !ok || got != want {
+			t.Errorf("parseDate(%q) = %q, %v, want %q", raw, got, ok, want)
+		}
+	}
+	if _, ok := parseDate("soon", defaultDateFormats); ok {
+		t.Errorf("parseDate(soon) expected failure")
+	}
+}
+
+func TestGenericTemplate_Extract(t *testing.T) {
+	tpl, err := compileTemplate(Template{Name: genericTemplateName})
+	if err != nil {
+		t.Fatal(err)
+	}
+	inv, warnings := tpl.extract(sampleInvoice)
+	if len(warnings) != 0 {
+		t.Errorf("unexpected warnings %v", warnings)
+	}
+	if inv.Vendor != "ACME Supplies Ltd." || inv.InvoiceNumber != "INV-2024-0042" || inv.Currency != "USD" {
+		t.Errorf("unexpected header %+v", inv)
+	}
+	if inv.Date != "2024-03-05" || inv.DueDate != "2024-04-04" {
+		t.Errorf("unexpected dates %s %s", inv.Date, inv.DueDate)
+	}
+	if inv.Subtotal == nil || *inv.Subtotal != 100 || inv.Tax == nil || *inv.Tax != 10 || inv.Total == nil || *inv.Total != 110 {
+		t.Errorf("unexpected totals %v %v %v", inv.Subtotal, inv.Tax, inv.Total)
+	}
+	if len(inv.LineItems) != 3 {
+		t.Fatalf("expected 3 line items, got %+v", inv.LineItems)
+	}
+	first := inv.LineItems[0]
+	if first.Description != "Paper A4 box" || first.Quantity == nil || *first.Quantity != 2 || first.UnitPrice == nil || *first.UnitPrice != 12.5 || first.Amount != 25 {
+		t.Errorf("unexpected first line item %+v", first)
+	}
+	if last := inv.LineItems[2]; last.Description != "Delivery" || last.Quantity != nil || last.Amount != 15 {
+		t.Errorf("unexpected last line item %+v", last)
+	}
+	if warnings = validate(inv); len(warnings) != 0 {
+		t.Errorf("unexpected validation warnings %v", warnings)
+	}
+}
+
+func TestTemplate_Override(t *testing.T) {
+	tpl, err := compileTemplate(Template{
+		Name:        "corner-shop",
+		Match:       `(?i)corner shop`,
+		Vendor:      "Corner Shop",
+		Fields:      map[string]string{FieldTotal: `(?m)^TO PAY\s+(\S+)$`, FieldDate: `(?m)^(\d{2}\.\d{2}\.\d{2})`},
+		LineItem:    `^(?P<amount>\d+,\d{2})\s+(?P<description>.+)$`,
+		DateFormats: []string{"02.01.06"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := "Welcome to the corner shop\n05.03.24 10:22\n2,50 Milk\n1,20 Bread\nTO PAY 3,70\n"
+	if !tpl.matches(text) {
+		t.Fatalf("template should match")
+	}
+	inv, _ := tpl.extract(text)
+	if inv.Vendor != "Corner Shop" || inv.Date != "2024-03-05" || inv.Total == nil || *inv.Total != 3.7 {
+		t.Errorf("unexpected invoice %+v", inv)
+	}
+	if len(inv.LineItems) != 2 || inv.LineItems[0].Description != "Milk" || inv.LineItems[0].Amount != 2.5 {
+		t.Errorf("unexpected line items %+v", inv.LineItems)
+	}
+}
+
+func TestCompileTemplate_Invalid(t *testing.T) {
+	for _, tpl := range []Template{
+		{},
+		{Name: "bad-match", Match: "("},
+		{Name: "bad-field", Fields: map[string]string{"color": "(.*)"}},
+		{Name: "bad-pattern", Fields: map[string]string{FieldTotal: "("}},
+		{Name: "bad-line-item", LineItem: `(?P<description>.+)`},
+	} {
+		if _, err := compileTemplate(tpl); err == nil {
+			t.Errorf("compileTemplate(%+v) expected error", tpl)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	inv := &Invoice{
+		Subtotal: float(100),
+		Tax:      float(5),
+		Total:    float(110),
+		LineItems: []LineItem{
+			{Description: "A", Quantity: float(2), UnitPrice: float(10), Amount: 25},
+			{Description: "B", Amount: 70},
+		},
+	}
+	warnings := validate(inv)
+	if len(warnings) != 3 {
+		t.Fatalf("expected 3 warnings, got %v", warnings)
+	}
+	if !strings.Contains(warnings[0], `"A"`) || !strings.Contains(warnings[1], "95.00") || !strings.Contains(warnings[2], "110.00") {
+		t.Errorf("unexpected warnings %v", warnings)
+	}
+}
@@ -0,0 +1,295 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package invoice
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/basenana/friday/core/providers/openai"
+	fridaytypes "github.com/basenana/friday/core/types"
+	"github.com/basenana/plugin/agentic"
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/docloader"
+	"github.com/basenana/plugin/logger"
+	"github.com/basenana/plugin/types"
+	"github.com/basenana/plugin/utils"
+	"go.uber.org/zap"
+)
+
+const (
+	pluginName    = "invoice"
+	pluginVersion = "1.0"
+
+	llmAssistAuto   = "auto"
+	llmAssistAlways = "always"
+	llmAssistNever  = "never"
+
+	maxLLMInput = 16000
+)
+
+var PluginSpec = types.PluginSpec{
+	Name:    pluginName,
+	Version: pluginVersion,
+	Type:    types.TypeProcess,
+	Dependencies: []types.Dependency{
+		{Kind: types.DependencyCapability, Name: types.CapabilityNetwork, Optional: true},
+	},
+	Parameters: []types.ParameterSpec{
+		{
+			Name:        "file_path",
+			Required:    true,
+			Description: "Invoice or receipt file: pdf, txt, md, html",
+		},
+		{
+			Name:        "templates",
+			Required:    false,
+			Description: "JSON array of extraction templates, the first whose match pattern matches is used",
+		},
+		{
+			Name:        "template_path",
+			Required:    false,
+			Description: "JSON file in the working path holding an array of extraction templates",
+		},
+		{
+			Name:        "llm_assist",
+			Required:    false,
+			Default:     llmAssistAuto,
+			Description: "Use the LLM to fill fields the templates missed: auto (when friday_llm_* config is set), always, never",
+			Options:     []string{llmAssistAuto, llmAssistAlways, llmAssistNever},
+		},
+		{
+			Name:        "output_path",
+			Required:    false,
+			Description: "Write the extracted invoice as JSON to this file",
+		},
+	},
+}
+
+// extractFunc asks the LLM for an invoice from the document text.
+type extractFunc func(ctx context.Context, text string) (*Invoice, error)
+
+type InvoicePlugin struct {
+	logger   *zap.SugaredLogger
+	fileRoot *utils.FileAccess
+	jobID    string
+	config   map[string]string
+	llm      extractFunc
+}
+
+func NewInvoicePlugin(ps types.PluginCall) types.Plugin {
+	config := agentic.PluginLLMConfig(ps.Config, pluginName)
+	return &InvoicePlugin{
+		logger:   logger.NewPluginLogger(pluginName, ps.JobID),
+		fileRoot: utils.NewFileAccess(ps.WorkingPath, ps.JobWorkingPath),
+		jobID:    ps.JobID,
+		config:   config,
+		llm:      llmExtract(config),
+	}
+}
+
+func (p *InvoicePlugin) Name() string {
+	return pluginName
+}
+
+func (p *InvoicePlugin) Type() types.PluginType {
+	return types.TypeProcess
+}
+
+func (p *InvoicePlugin) Version() string {
+	return pluginVersion
+}
+
+func (p *InvoicePlugin) Run(ctx context.Context, request *api.Request) (*api.Response, error) {
+	filePath := api.GetStringParameter("file_path", request, "")
+	if filePath == "" {
+		return api.NewFailedResponse("file_path is required"), nil
+	}
+	assist := api.GetStringParameter("llm_assist", request, llmAssistAuto)
+	if assist != llmAssistAuto && assist != llmAssistAlways && assist != llmAssistNever {
+		return api.NewFailedResponse(fmt.Sprintf("unknown llm_assist: %s", assist)), nil
+	}
+
+	templates, err := p.loadTemplates(request)
+	if err != nil {
+		return api.NewFailedResponse(err.Error()), nil
+	}
+
+	text, err := p.loadText(ctx, filePath)
+	if err != nil {
+		p.logger.Warnw("load invoice file failed", "file", filePath, "error", err)
+		return api.NewFailedResponse(fmt.Sprintf("load file %s failed: %s", filePath, err)), nil
+	}
+	if strings.TrimSpace(text) == "" {
+		return api.NewFailedResponse(fmt.Sprintf("no text found in %s", filePath)), nil
+	}
+
+	var tpl *compiledTemplate
+	for _, t := range templates {
+		if t.matches(text) {
+			tpl = t
+			break
+		}
+	}
+	inv, warnings := tpl.extract(text)
+	source := "template"
+
+	missing := inv.missing()
+	useLLM := assist == llmAssistAlways ||
+		(assist == llmAssistAuto && (len(missing) > 0 || len(inv.LineItems) == 0) && agentic.LLMConfigured(p.config))
+	if useLLM {
+		budget, err := agentic.NewLLMBudget(p.jobID, p.config, request.Store)
+		if err != nil {
+			return api.NewFailedResponse(err.Error()), nil
+		}
+		llmInv, err := p.llm(agentic.WithLLMBudget(ctx, budget), text)
+		if err != nil {
+			p.logger.Warnw("llm invoice extraction failed", "file", filePath, "error", err)
+			warnings = append(warnings, fmt.Sprintf("llm assist failed: %s", err))
+		} else {
+			inv.merge(llmInv)
+			source = "template+llm"
+		}
+		missing = inv.missing()
+	}
+	warnings = append(warnings, validate(inv)...)
+
+	results := map[string]any{
+		"file_path": filePath,
+		"template":  tpl.name,
+		"source":    source,
+		"invoice":   utils.MarshalMap(inv),
+		"missing":   missing,
+		"warnings":  warnings,
+	}
+	if outputPath := api.GetStringParameter("output_path", request, ""); outputPath != "" {
+		data, err := json.MarshalIndent(inv, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		if err = p.fileRoot.Write(outputPath, data, 0644); err != nil {
+			return api.NewFailedResponse(fmt.Sprintf("write file %s failed: %s", outputPath, err)), nil
+		}
+		results["output_path"] = outputPath
+	}
+
+	p.logger.Infow("invoice parsed", "file", filePath, "template", tpl.name, "source", source,
+		"line_items", len(inv.LineItems), "missing", missing, "warnings", len(warnings))
+	return api.NewResponseWithResult(results), nil
+}
+
+// loadTemplates returns the user templates in order followed by the generic one.
+func (p *InvoicePlugin) loadTemplates(request *api.Request) ([]*compiledTemplate, error) {
+	var templates []Template
+	if raw := api.GetStringParameter("templates", request, ""); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &templates); err != nil {
+			return nil, fmt.Errorf("parse templates failed: %s", err)
+		}
+	}
+	if templatePath := api.GetStringParameter("template_path", request, ""); templatePath != "" {
+		data, err := p.fileRoot.Read(templatePath)
+		if err != nil {
+			return nil, fmt.Errorf("read template file failed: %s", err)
+		}
+		var fileTemplates []Template
+		if err = json.Unmarshal(data, &fileTemplates); err != nil {
+			return nil, fmt.Errorf("parse template file failed: %s", err)
+		}
+		templates = append(templates, fileTemplates...)
+	}
+	templates = append(templates, Template{Name: genericTemplateName})
+
+	compiled := make([]*compiledTemplate, 0, len(templates))
+	for _, t := range templates {
+		ct, err := compileTemplate(t)
+		if err != nil {
+			return nil, err
+		}
+		compiled = append(compiled, ct)
+	}
+	return compiled, nil
+}
+
+func (p *InvoicePlugin) loadText(ctx context.Context, filePath string) (string, error) {
+	absPath, err := p.fileRoot.GetAbsPath(filePath)
+	if err != nil {
+		return "", err
+	}
+	parser, err := docloader.NewParser(absPath, nil)
+	if err != nil {
+		return "", err
+	}
+	doc, err := parser.Load(logger.IntoContext(ctx, p.logger))
+	if err != nil {
+		return "", err
+	}
+	return doc.Content, nil
+}
+
+const invoicePrompt = `You extract structured data from invoices and receipts.
+Reply with a single JSON object and nothing else, using this schema:
+{"vendor": string, "invoice_number": string, "date": "YYYY-MM-DD", "due_date": "YYYY-MM-DD",
+ "currency": ISO 4217 code, "subtotal": number, "tax": number, "total": number,
+ "line_items": [{"description": string, "quantity": number, "unit_price": number, "amount": number}]}
+Omit fields that are not present in the document.`
+
+func llmExtract(config map[string]string) extractFunc {
+	return func(ctx context.Context, text string) (*Invoice, error) {
+		llm, err := agentic.NewLLMClient(config)
+		if err != nil {
+			return nil, err
+		}
+		if len(text) > maxLLMInput {
+			text = text[:maxLLMInput]
+		}
+		reply, err := llm.CompletionNonStreaming(ctx, openai.NewSimpleRequest(invoicePrompt, fridaytypes.Message{UserMessage: text}))
+		if err != nil {
+			return nil, err
+		}
+		return parseLLMInvoice(reply)
+	}
+}
+
+// parseLLMInvoice decodes the JSON object in the reply, tolerating markdown fences around it.
+func parseLLMInvoice(reply string) (*Invoice, error) {
+	start, end := strings.Index(reply, "{"), strings.LastIndex(reply, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("no JSON object in reply")
+	}
+	inv := &Invoice{}
+	if err := json.Unmarshal([]byte(reply[start:end+1]), inv); err != nil {
+		return nil, fmt.Errorf("parse reply failed: %s", err)
+	}
+	if inv.Date != "" {
+		if d, ok := parseDate(inv.Date, defaultDateFormats); ok {
+			inv.Date = d
+		} else {
+			inv.Date = ""
+		}
+	}
+	if inv.DueDate != "" {
+		if d, ok := parseDate(inv.DueDate, defaultDateFormats); ok {
+			inv.DueDate = d
+		} else {
+			inv.DueDate = ""
+		}
+	}
+	inv.Currency = strings.ToUpper(inv.Currency)
+	return inv, nil
+}
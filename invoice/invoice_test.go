@@ -0,0 +1,166 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package invoice
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/basenana/plugin/agentic"
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/logger"
+	"github.com/basenana/plugin/types"
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) {
+	logger.SetLogger(zap.NewNop().Sugar())
+	os.Exit(m.Run())
+}
+
+func newInvoicePlugin(t *testing.T, config map[string]string, files map[string]string) (*InvoicePlugin, string) {
+	workdir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(workdir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return NewInvoicePlugin(types.PluginCall{JobID: "test-job", WorkingPath: workdir, Config: config}).(*InvoicePlugin), workdir
+}
+
+var llmConfig = map[string]string{
+	agentic.ConfigHost:   "http://127.0.0.1",
+	agentic.ConfigAPIKey: "key",
+	agentic.ConfigModel:  "model",
+}
+
+func TestInvoicePlugin_Template(t *testing.T) {
+	p, workdir := newInvoicePlugin(t, nil, map[string]string{"invoice.txt": sampleInvoice})
+	p.llm = func(ctx context.Context, text string) (*Invoice, error) {
+		t.Fatalf("llm should not be called without config")
+		return nil, nil
+	}
+
+	resp, err := p.Run(context.Background(), &api.Request{Parameter: map[string]any{
+		"file_path":   "invoice.txt",
+		"output_path": "invoice.json",
+	}})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if !resp.IsSucceed {
+		t.Fatalf("Run not succeed: %s", resp.Message)
+	}
+	if resp.Results["template"] != genericTemplateName || resp.Results["source"] != "template" {
+		t.Errorf("unexpected template/source %v/%v", resp.Results["template"], resp.Results["source"])
+	}
+	invoice := resp.Results["invoice"].(map[string]any)
+	if invoice["vendor"] != "ACME Supplies Ltd." || invoice["total"] != 110.0 {
+		t.Errorf("unexpected invoice %v", invoice)
+	}
+
+	data, err := os.ReadFile(filepath.Join(workdir, "invoice.json"))
+	if err != nil {
+		t.Fatalf("read output failed: %v", err)
+	}
+	var saved Invoice
+	if err = json.Unmarshal(data, &saved); err != nil || len(saved.LineItems) != 3 {
+		t.Errorf("unexpected output file %s", data)
+	}
+}
+
+func TestInvoicePlugin_TemplateSelection(t *testing.T) {
+	p, _ := newInvoicePlugin(t, nil, map[string]string{
+		"receipt.txt":    "CORNER SHOP\nTO PAY 3,70\n",
+		"templates.json": `[{"name": "other", "match": "NEVER"}, {"name": "corner", "match": "CORNER SHOP", "fields": {"total": "TO PAY (\\S+)"}}]`,
+	})
+	resp, err := p.Run(context.Background(), &api.Request{Parameter: map[string]any{
+		"file_path":     "receipt.txt",
+		"template_path": "templates.json",
+		"llm_assist":    "never",
+	}})
+	if err != nil || !resp.IsSucceed {
+		t.Fatalf("Run failed: %v %v", err, resp)
+	}
+	if resp.Results["template"] != "corner" {
+		t.Errorf("expected corner template, got %v", resp.Results["template"])
+	}
+	if invoice := resp.Results["invoice"].(map[string]any); invoice["total"] != 3.7 {
+		t.Errorf("unexpected total %v", invoice["total"])
+	}
+	if missing := resp.Results["missing"].([]string); len(missing) != 1 || missing[0] != FieldDate {
+		t.Errorf("unexpected missing %v", missing)
+	}
+}
+
+func TestInvoicePlugin_LLMAssist(t *testing.T) {
+	p, _ := newInvoicePlugin(t, llmConfig, map[string]string{"receipt.md": "Thanks for shopping\nTotal 9.99\n"})
+	var calls int
+	p.llm = func(ctx context.Context, text string) (*Invoice, error) {
+		calls++
+		return parseLLMInvoice("```json\n" + `{"vendor": "Cafe Blue", "date": "2024-05-01", "total": 1.00, "currency": "eur",
+			"line_items": [{"description": "Latte", "quantity": 1, "unit_price": 9.99, "amount": 9.99}]}` + "\n```")
+	}
+
+	resp, err := p.Run(context.Background(), &api.Request{Parameter: map[string]any{"file_path": "receipt.md"}})
+	if err != nil || !resp.IsSucceed {
+		t.Fatalf("Run failed: %v %v", err, resp)
+	}
+	if calls != 1 || resp.Results["source"] != "template+llm" {
+		t.Errorf("expected llm assist, calls=%d source=%v", calls, resp.Results["source"])
+	}
+	invoice := resp.Results["invoice"].(map[string]any)
+	if invoice["vendor"] != "Thanks for shopping" || invoice["total"] != 9.99 {
+		t.Errorf("template values should win over llm values, got %v", invoice)
+	}
+	if invoice["date"] != "2024-05-01" || invoice["currency"] != "EUR" || len(invoice["line_items"].([]any)) != 1 {
+		t.Errorf("llm should fill missing fields, got %v", invoice)
+	}
+
+	p.llm = func(ctx context.Context, text string) (*Invoice, error) {
+		return nil, errors.New("llm down")
+	}
+	resp, _ = p.Run(context.Background(), &api.Request{Parameter: map[string]any{"file_path": "receipt.md", "llm_assist": "always"}})
+	if !resp.IsSucceed || resp.Results["source"] != "template" || len(resp.Results["warnings"].([]string)) == 0 {
+		t.Errorf("llm failure should only add a warning, got %v", resp.Results)
+	}
+}
+
+func TestInvoicePlugin_InvalidParameters(t *testing.T) {
+	p, _ := newInvoicePlugin(t, nil, map[string]string{"empty.txt": " \n", "invoice.csv": "a,b"})
+	for _, params := range []map[string]any{
+		{},
+		{"file_path": "missing.txt"},
+		{"file_path": "empty.txt"},
+		{"file_path": "invoice.csv"},
+		{"file_path": "empty.txt", "llm_assist": "sometimes"},
+		{"file_path": "empty.txt", "templates": "{"},
+		{"file_path": "empty.txt", "template_path": "missing.json"},
+	} {
+		resp, err := p.Run(context.Background(), &api.Request{Parameter: params})
+		if err != nil {
+			t.Fatalf("Run(%v) returned error: %v", params, err)
+		}
+		if resp.IsSucceed {
+			t.Errorf("Run(%v) succeeded, want failure", params)
+		}
+	}
+}
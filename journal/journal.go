@@ -0,0 +1,382 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package journal
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/logger"
+	"github.com/basenana/plugin/types"
+	"github.com/basenana/plugin/utils"
+	"go.uber.org/zap"
+)
+
+const (
+	pluginName    = "journal"
+	pluginVersion = "1.0"
+
+	periodDaily  = "daily"
+	periodWeekly = "weekly"
+
+	maxFailuresListed = 20
+)
+
+var PluginSpec = types.PluginSpec{
+	Name:    pluginName,
+	Version: pluginVersion,
+	Type:    types.TypeProcess,
+	Dependencies: []types.Dependency{
+		{Kind: types.DependencyCapability, Name: types.CapabilityStore},
+		{Kind: types.DependencyCapability, Name: types.CapabilityFS, Optional: true},
+	},
+	Parameters: []types.ParameterSpec{
+		{
+			Name:        "period",
+			Required:    false,
+			Default:     periodDaily,
+			Description: "Journal period: daily, or weekly for the Monday to Sunday week of date",
+			Options:     []string{periodDaily, periodWeekly},
+		},
+		{
+			Name:        "date",
+			Required:    false,
+			Description: "Day in the period as YYYY-MM-DD (UTC), default yesterday for daily and this week for weekly",
+		},
+		{
+			Name:        "workflow",
+			Required:    false,
+			Description: "Only include calls of this workflow",
+		},
+		{
+			Name:        "file_name",
+			Required:    false,
+			Description: "Journal file name, default journal-<date>.md or journal-<year>-W<week>.md",
+		},
+		{
+			Name:        "parent_uri",
+			Required:    false,
+			Description: "Save the journal as an entry under this NanaFS parent URI",
+		},
+	},
+}
+
+type JournalPlugin struct {
+	logger   *zap.SugaredLogger
+	fileRoot *utils.FileAccess
+	now      func() time.Time
+}
+
+func NewJournalPlugin(ps types.PluginCall) types.Plugin {
+	return &JournalPlugin{
+		logger:   logger.NewPluginLogger(pluginName, ps.JobID),
+		fileRoot: utils.NewFileAccess(ps.WorkingPath, ps.JobWorkingPath),
+		now:      time.Now,
+	}
+}
+
+func (p *JournalPlugin) Name() string {
+	return pluginName
+}
+
+func (p *JournalPlugin) Type() types.PluginType {
+	return types.TypeProcess
+}
+
+func (p *JournalPlugin) Version() string {
+	return pluginVersion
+}
+
+func (p *JournalPlugin) Run(ctx context.Context, request *api.Request) (*api.Response, error) {
+	if request.Store == nil {
+		return api.NewFailedResponse("persistent store is not available"), nil
+	}
+	period := api.GetStringParameter("period", request, periodDaily)
+	if period != periodDaily && period != periodWeekly {
+		return api.NewFailedResponse(fmt.Sprintf("unknown period: %s", period)), nil
+	}
+	start, err := periodStart(period, api.GetStringParameter("date", request, ""), p.now().UTC())
+	if err != nil {
+		return api.NewFailedResponse(err.Error()), nil
+	}
+	days := 1
+	if period == periodWeekly {
+		days = 7
+	}
+	workflow := api.GetStringParameter("workflow", request, "")
+	parentURI := api.GetStringParameter("parent_uri", request, "")
+	if parentURI != "" && request.FS == nil {
+		return api.NewFailedResponse("file system is not available"), nil
+	}
+
+	var records []types.ActivityRecord
+	for i := 0; i < days; i++ {
+		day := start.AddDate(0, 0, i).Format(types.ActivityDayLayout)
+		var activity types.ActivityDay
+		if err = request.Store.Load(ctx, types.ActivitySource, types.ActivityGroup, day, &activity); err != nil {
+			p.logger.Debugw("no activity recorded", "day", day, "err", err)
+			continue
+		}
+		for _, r := range activity.Records {
+			if workflow == "" || r.Workflow == workflow {
+				records = append(records, r)
+			}
+		}
+	}
+
+	j := newJournal(period, start, days, records)
+	fileName := api.GetStringParameter("file_name", request, j.fileName())
+	content := j.markdown(workflow)
+	if err = p.fileRoot.Write(fileName, []byte(content), 0644); err != nil {
+		return api.NewFailedResponse(fmt.Sprintf("write file %s failed: %s", fileName, err)), nil
+	}
+
+	results := map[string]any{
+		"file_path":   fileName,
+		"period":      period,
+		"start":       start.Format(types.ActivityDayLayout),
+		"end":         start.AddDate(0, 0, days-1).Format(types.ActivityDayLayout),
+		"calls":       j.total.Calls,
+		"failed":      j.total.Failed,
+		"items":       j.total.Items,
+		"duration_ms": j.total.DurationMs,
+		"workflows":   statMaps(j.workflows),
+		"plugins":     statMaps(j.plugins),
+	}
+
+	if parentURI != "" {
+		props := types.Properties{
+			Title:     j.title(),
+			Keywords:  []string{"journal"},
+			Abstract:  j.abstract(),
+			PublishAt: start.Unix(),
+		}
+		if err = request.FS.SaveEntry(ctx, parentURI, fileName, props, io.NopCloser(strings.NewReader(content))); err != nil {
+			p.logger.Warnw("save journal entry failed", "parent_uri", parentURI, "error", err)
+			return api.NewFailedResponse(fmt.Sprintf("save journal failed: %s", err)), nil
+		}
+		results["parent_uri"] = parentURI
+	}
+
+	p.logger.Infow("journal written", "file", fileName, "period", period, "start", results["start"], "calls", j.total.Calls)
+	return api.NewResponseWithResult(results), nil
+}
+
+// periodStart returns the first day of the period holding date. Without a date a daily journal
+// covers yesterday, the last complete day, and a weekly one the current week.
+func periodStart(period, date string, now time.Time) (time.Time, error) {
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if date != "" {
+		t, err := time.Parse(types.ActivityDayLayout, date)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid date: %s, expect YYYY-MM-DD", date)
+		}
+		day = t
+	} else if period == periodDaily {
+		day = day.AddDate(0, 0, -1)
+	}
+	if period == periodWeekly {
+		offset := (int(day.Weekday()) + 6) % 7
+		day = day.AddDate(0, 0, -offset)
+	}
+	return day, nil
+}
+
+// stat aggregates the calls of one workflow, plugin or day.
+type stat struct {
+	Name       string `json:"name"`
+	Calls      int    `json:"calls"`
+	Failed     int    `json:"failed"`
+	Items      int    `json:"items"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+func (s *stat) add(r types.ActivityRecord) {
+	s.Calls++
+	if !r.Succeed {
+		s.Failed++
+	}
+	s.Items += r.Items
+	s.DurationMs += r.DurationMs
+}
+
+type journal struct {
+	period    string
+	start     time.Time
+	days      []*stat
+	total     stat
+	workflows []*stat
+	plugins   []*stat
+	failures  []types.ActivityRecord
+}
+
+func newJournal(period string, start time.Time, days int, records []types.ActivityRecord) *journal {
+	j := &journal{period: period, start: start}
+	dayStats := make(map[string]*stat, days)
+	for i := 0; i < days; i++ {
+		s := &stat{Name: start.AddDate(0, 0, i).Format(types.ActivityDayLayout)}
+		j.days = append(j.days, s)
+		dayStats[s.Name] = s
+	}
+	workflows := make(map[string]*stat)
+	plugins := make(map[string]*stat)
+
+	sort.SliceStable(records, func(i, k int) bool { return records[i].StartedAt < records[k].StartedAt })
+	for _, r := range records {
+		j.total.add(r)
+		if s, ok := dayStats[strings.SplitN(r.StartedAt, "T", 2)[0]]; ok {
+			s.add(r)
+		}
+		name := r.Workflow
+		if name == "" {
+			name = "-"
+		}
+		statOf(workflows, name).add(r)
+		statOf(plugins, r.Plugin).add(r)
+		if !r.Succeed {
+			j.failures = append(j.failures, r)
+		}
+	}
+	j.workflows = sortedStats(workflows)
+	j.plugins = sortedStats(plugins)
+	return j
+}
+
+func statOf(stats map[string]*stat, name string) *stat {
+	s, ok := stats[name]
+	if !ok {
+		s = &stat{Name: name}
+		stats[name] = s
+	}
+	return s
+}
+
+// sortedStats orders by calls, the busiest first.
+func sortedStats(stats map[string]*stat) []*stat {
+	result := make([]*stat, 0, len(stats))
+	for _, s := range stats {
+		result = append(result, s)
+	}
+	sort.Slice(result, func(i, k int) bool {
+		if result[i].Calls != result[k].Calls {
+			return result[i].Calls > result[k].Calls
+		}
+		return result[i].Name < result[k].Name
+	})
+	return result
+}
+
+func statMaps(stats []*stat) []map[string]any {
+	result := make([]map[string]any, 0, len(stats))
+	for _, s := range stats {
+		result = append(result, utils.MarshalMap(s))
+	}
+	return result
+}
+
+func (j *journal) label() string {
+	if j.period == periodWeekly {
+		year, week := j.start.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	}
+	return j.start.Format(types.ActivityDayLayout)
+}
+
+func (j *journal) fileName() string {
+	return fmt.Sprintf("journal-%s.md", j.label())
+}
+
+func (j *journal) title() string {
+	if j.period == periodWeekly {
+		end := j.start.AddDate(0, 0, len(j.days)-1)
+		return fmt.Sprintf("Automation Journal %s (%s to %s)", j.label(), j.start.Format(types.ActivityDayLayout), end.Format(types.ActivityDayLayout))
+	}
+	return fmt.Sprintf("Automation Journal %s", j.label())
+}
+
+func (j *journal) abstract() string {
+	if j.total.Calls == 0 {
+		return "No workflow activity recorded."
+	}
+	return fmt.Sprintf("%d calls in %d workflows, %d failed, %d items processed in %s.",
+		j.total.Calls, len(j.workflows), j.total.Failed, j.total.Items, formatDuration(j.total.DurationMs))
+}
+
+func (j *journal) markdown(workflow string) string {
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "# %s\n\n", j.title())
+	if workflow != "" {
+		fmt.Fprintf(buf, "Workflow: %s\n\n", workflow)
+	}
+	buf.WriteString(j.abstract())
+	buf.WriteString("\n")
+	if j.total.Calls == 0 {
+		return buf.String()
+	}
+
+	if j.period == periodWeekly {
+		writeTable(buf, "By Day", "Day", j.days)
+	}
+	writeTable(buf, "By Workflow", "Workflow", j.workflows)
+	writeTable(buf, "By Plugin", "Plugin", j.plugins)
+
+	if len(j.failures) > 0 {
+		buf.WriteString("\n## Failures\n\n")
+		for i, r := range j.failures {
+			if i == maxFailuresListed {
+				fmt.Fprintf(buf, "- and %d more\n", len(j.failures)-maxFailuresListed)
+				break
+			}
+			when := r.StartedAt
+			if t, err := time.Parse(time.RFC3339, r.StartedAt); err == nil {
+				when = t.Format("2006-01-02 15:04")
+			}
+			fmt.Fprintf(buf, "- %s `%s`", when, r.Plugin)
+			if r.Workflow != "" {
+				fmt.Fprintf(buf, " in %s", r.Workflow)
+			}
+			if r.Message != "" {
+				fmt.Fprintf(buf, ": %s", strings.ReplaceAll(r.Message, "\n", " "))
+			}
+			buf.WriteString("\n")
+		}
+	}
+	return buf.String()
+}
+
+func writeTable(buf *bytes.Buffer, heading, column string, stats []*stat) {
+	fmt.Fprintf(buf, "\n## %s\n\n", heading)
+	fmt.Fprintf(buf, "| %s | Calls | Failed | Items | Duration |\n", column)
+	buf.WriteString("|---|---:|---:|---:|---:|\n")
+	for _, s := range stats {
+		fmt.Fprintf(buf, "| %s | %d | %d | %d | %s |\n", s.Name, s.Calls, s.Failed, s.Items, formatDuration(s.DurationMs))
+	}
+}
+
+func formatDuration(ms int64) string {
+	d := time.Duration(ms) * time.Millisecond
+	if d < time.Second {
+		return d.String()
+	}
+	return d.Round(time.Second).String()
+}
@@ -0,0 +1,263 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package journal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/logger"
+	"github.com/basenana/plugin/types"
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) {
+	logger.SetLogger(zap.NewNop().Sugar())
+	os.Exit(m.Run())
+}
+
+type memStore struct {
+	data map[string][]byte
+}
+
+func (m *memStore) Load(ctx context.Context, source, group, key string, data any) error {
+	raw, ok := m.data[source+"/"+group+"/"+key]
+	if !ok {
+		return errors.New("no record")
+	}
+	return json.Unmarshal(raw, data)
+}
+
+func (m *memStore) Save(ctx context.Context, source, group, key string, data any) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	m.data[source+"/"+group+"/"+key] = raw
+	return nil
+}
+
+type savedEntry struct {
+	parentURI, name string
+	props           types.Properties
+	content         string
+}
+
+type mockFS struct {
+	saved []savedEntry
+}
+
+func (m *mockFS) CreateGroupIfNotExists(ctx context.Context, parentURI, group string, properties types.Properties) error {
+	return nil
+}
+
+func (m *mockFS) SaveEntry(ctx context.Context, parentURI, name string, properties types.Properties, reader io.ReadCloser) error {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	m.saved = append(m.saved, savedEntry{parentURI: parentURI, name: name, props: properties, content: string(data)})
+	return nil
+}
+
+func (m *mockFS) UpdateEntry(ctx context.Context, entryURI, content string, properties types.Properties) error {
+	return nil
+}
+
+func (m *mockFS) GetEntryProperties(ctx context.Context, entryURI string) (*types.Properties, error) {
+	return nil, errors.New("entry not found")
+}
+
+func (m *mockFS) Search(ctx context.Context, query string, filter types.SearchFilter) ([]types.Entry, error) {
+	return nil, nil
+}
+
+func newJournalPlugin(t *testing.T, now time.Time) *JournalPlugin {
+	p := NewJournalPlugin(types.PluginCall{JobID: "test-job", WorkingPath: t.TempDir()}).(*JournalPlugin)
+	p.now = func() time.Time { return now }
+	return p
+}
+
+func newActivityStore(t *testing.T, records ...types.ActivityRecord) *memStore {
+	store := &memStore{data: map[string][]byte{}}
+	days := make(map[string]*types.ActivityDay)
+	for _, r := range records {
+		day := r.StartedAt[:10]
+		if days[day] == nil {
+			days[day] = &types.ActivityDay{}
+		}
+		days[day].Records = append(days[day].Records, r)
+	}
+	for day, activity := range days {
+		if err := store.Save(context.Background(), types.ActivitySource, types.ActivityGroup, day, activity); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return store
+}
+
+var testRecords = []types.ActivityRecord{
+	{Workflow: "news", Plugin: "rss", StartedAt: "2024-03-04T08:00:00Z", DurationMs: 12000, Succeed: true, Items: 5},
+	{Workflow: "news", Plugin: "save", StartedAt: "2024-03-04T08:01:00Z", DurationMs: 300, Succeed: true},
+	{Workflow: "papers", Plugin: "arxiv", StartedAt: "2024-03-04T09:00:00Z", DurationMs: 4000, Message: "fetch arxiv failed: 503"},
+	{Workflow: "news", Plugin: "rss", StartedAt: "2024-03-05T08:00:00Z", DurationMs: 8000, Succeed: true, Items: 3},
+	{Workflow: "news", Plugin: "rss", StartedAt: "2024-03-11T08:00:00Z", DurationMs: 8000, Succeed: true, Items: 7},
+}
+
+func TestPeriodStart(t *testing.T) {
+	now := time.Date(2024, 3, 6, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		period, date, want string
+	}{
+		{periodDaily, "", "2024-03-05"},
+		{periodDaily, "2024-03-01", "2024-03-01"},
+		{periodWeekly, "", "2024-03-04"},
+		{periodWeekly, "2024-03-10", "2024-03-04"},
+		{periodWeekly, "2024-03-11", "2024-03-11"},
+	}
+	for _, tt := range tests {
+		got, err := periodStart(tt.period, tt.date, now)
+		if err != nil {
+			t.Fatalf("%s %s: %v", tt.period, tt.date, err)
+		}
+		if got.Format(types.ActivityDayLayout) != tt.want {
+			t.Errorf("%s %q: expected %s, got %s", tt.period, tt.date, tt.want, got.Format(types.ActivityDayLayout))
+		}
+	}
+	if _, err := periodStart(periodDaily, "03/04/2024", now); err == nil {
+		t.Error("expected error for invalid date")
+	}
+}
+
+func TestJournalPlugin_Daily(t *testing.T) {
+	p := newJournalPlugin(t, time.Date(2024, 3, 5, 6, 0, 0, 0, time.UTC))
+	resp, err := p.Run(context.Background(), &api.Request{Parameter: map[string]any{}, Store: newActivityStore(t, testRecords...)})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if !resp.IsSucceed {
+		t.Fatalf("Run not succeed: %s", resp.Message)
+	}
+	if resp.Results["file_path"] != "journal-2024-03-04.md" || resp.Results["calls"] != 3 || resp.Results["failed"] != 1 || resp.Results["items"] != 5 {
+		t.Errorf("unexpected results %v", resp.Results)
+	}
+	workflows := resp.Results["workflows"].([]map[string]any)
+	if len(workflows) != 2 || workflows[0]["name"] != "news" || workflows[0]["calls"] != float64(2) {
+		t.Errorf("unexpected workflows %v", workflows)
+	}
+
+	data, err := p.fileRoot.Read("journal-2024-03-04.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := string(data)
+	for _, want := range []string{
+		"# Automation Journal 2024-03-04",
+		"3 calls in 2 workflows, 1 failed, 5 items processed in 16s.",
+		"| news | 2 | 0 | 5 | 12s |",
+		"| arxiv | 1 | 1 | 0 | 4s |",
+		"- 2024-03-04 09:00 `arxiv` in papers: fetch arxiv failed: 503",
+	} {
+		if !strings.Contains(content, want) {
+			t.Errorf("expected journal to contain %q:\n%s", want, content)
+		}
+	}
+	if strings.Contains(content, "By Day") {
+		t.Error("expected no day table in a daily journal")
+	}
+}
+
+func TestJournalPlugin_WeeklySaved(t *testing.T) {
+	fs := &mockFS{}
+	p := newJournalPlugin(t, time.Date(2024, 3, 12, 6, 0, 0, 0, time.UTC))
+	resp, err := p.Run(context.Background(), &api.Request{
+		Parameter: map[string]any{"period": periodWeekly, "date": "2024-03-06", "workflow": "news", "parent_uri": "/journal"},
+		Store:     newActivityStore(t, testRecords...),
+		FS:        fs,
+	})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if !resp.IsSucceed {
+		t.Fatalf("Run not succeed: %s", resp.Message)
+	}
+	if resp.Results["start"] != "2024-03-04" || resp.Results["end"] != "2024-03-10" || resp.Results["calls"] != 3 {
+		t.Errorf("unexpected results %v", resp.Results)
+	}
+	if len(fs.saved) != 1 {
+		t.Fatalf("expected journal saved, got %d entries", len(fs.saved))
+	}
+	saved := fs.saved[0]
+	if saved.parentURI != "/journal" || saved.name != "journal-2024-W10.md" {
+		t.Errorf("unexpected entry %s/%s", saved.parentURI, saved.name)
+	}
+	if saved.props.Title != "Automation Journal 2024-W10 (2024-03-04 to 2024-03-10)" || saved.props.PublishAt != time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC).Unix() {
+		t.Errorf("unexpected properties %+v", saved.props)
+	}
+	for _, want := range []string{"Workflow: news", "| 2024-03-05 | 1 | 0 | 3 | 8s |", "| 2024-03-10 | 0 | 0 | 0 | 0s |"} {
+		if !strings.Contains(saved.content, want) {
+			t.Errorf("expected journal to contain %q:\n%s", want, saved.content)
+		}
+	}
+	if strings.Contains(saved.content, "arxiv") {
+		t.Error("expected other workflows filtered out")
+	}
+}
+
+func TestJournalPlugin_NoActivity(t *testing.T) {
+	p := newJournalPlugin(t, time.Date(2024, 3, 5, 6, 0, 0, 0, time.UTC))
+	resp, err := p.Run(context.Background(), &api.Request{Parameter: map[string]any{"date": "2024-01-01"}, Store: newActivityStore(t)})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if !resp.IsSucceed || resp.Results["calls"] != 0 {
+		t.Fatalf("unexpected response %+v", resp)
+	}
+	data, _ := p.fileRoot.Read("journal-2024-01-01.md")
+	if !strings.Contains(string(data), "No workflow activity recorded.") {
+		t.Errorf("unexpected journal %s", data)
+	}
+}
+
+func TestJournalPlugin_Errors(t *testing.T) {
+	p := newJournalPlugin(t, time.Now())
+	tests := []struct {
+		name string
+		req  *api.Request
+	}{
+		{"no store", &api.Request{Parameter: map[string]any{}}},
+		{"unknown period", &api.Request{Parameter: map[string]any{"period": "monthly"}, Store: newActivityStore(t)}},
+		{"invalid date", &api.Request{Parameter: map[string]any{"date": "yesterday"}, Store: newActivityStore(t)}},
+		{"no fs", &api.Request{Parameter: map[string]any{"parent_uri": "/journal"}, Store: newActivityStore(t)}},
+	}
+	for _, tt := range tests {
+		resp, err := p.Run(context.Background(), tt.req)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if resp.IsSucceed {
+			t.Errorf("%s: expected failure", tt.name)
+		}
+	}
+}
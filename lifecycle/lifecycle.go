@@ -0,0 +1,374 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package lifecycle
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"time"
+
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/logger"
+	"github.com/basenana/plugin/types"
+	"github.com/basenana/plugin/utils"
+	"go.uber.org/zap"
+)
+
+const (
+	pluginName    = "lifecycle"
+	pluginVersion = "1.0"
+
+	statusPlanned = "planned"
+	statusDone    = "done"
+	statusFailed  = "failed"
+)
+
+var PluginSpec = types.PluginSpec{
+	Name:    pluginName,
+	Version: pluginVersion,
+	Type:    types.TypeProcess,
+	Dependencies: []types.Dependency{
+		{Kind: types.DependencyCapability, Name: types.CapabilityLister},
+		{Kind: types.DependencyCapability, Name: types.CapabilityFS, Optional: true},
+	},
+	Parameters: []types.ParameterSpec{
+		{
+			Name:        "parent_uri",
+			Required:    true,
+			Description: "Group whose entries are evaluated",
+		},
+		{
+			Name:        "policies",
+			Required:    false,
+			Description: "JSON array of policies, each entry gets the action of the first matching policy",
+		},
+		{
+			Name:        "policy_path",
+			Required:    false,
+			Description: "JSON file in the working path holding an array of policies",
+		},
+		{
+			Name:        "recursive",
+			Required:    false,
+			Default:     "false",
+			Description: "Also evaluate entries in sub groups",
+			Options:     []string{"true", "false"},
+		},
+		{
+			Name:        "dry_run",
+			Required:    false,
+			Default:     "false",
+			Description: "Report the planned actions without applying them",
+			Options:     []string{"true", "false"},
+		},
+	},
+}
+
+type LifecyclePlugin struct {
+	logger   *zap.SugaredLogger
+	fileRoot *utils.FileAccess
+	now      func() time.Time
+}
+
+func NewLifecyclePlugin(ps types.PluginCall) types.Plugin {
+	return &LifecyclePlugin{
+		logger:   logger.NewPluginLogger(pluginName, ps.JobID),
+		fileRoot: utils.NewFileAccess(ps.WorkingPath, ps.JobWorkingPath),
+		now:      time.Now,
+	}
+}
+
+func (p *LifecyclePlugin) Name() string {
+	return pluginName
+}
+
+func (p *LifecyclePlugin) Type() types.PluginType {
+	return types.TypeProcess
+}
+
+func (p *LifecyclePlugin) Version() string {
+	return pluginVersion
+}
+
+// candidate is an entry selected by a policy, path is relative to parent_uri.
+type candidate struct {
+	entry types.Entry
+	path  string
+}
+
+type actionResult struct {
+	Policy   string `json:"policy"`
+	Action   string `json:"action"`
+	EntryURI string `json:"entry_uri"`
+	Path     string `json:"path"`
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+}
+
+func (p *LifecyclePlugin) Run(ctx context.Context, request *api.Request) (*api.Response, error) {
+	parentURI := api.GetStringParameter("parent_uri", request, "")
+	if parentURI == "" {
+		return api.NewFailedResponse("parent_uri is required"), nil
+	}
+	policies, err := p.loadPolicies(request)
+	if err != nil {
+		return api.NewFailedResponse(err.Error()), nil
+	}
+	if request.Lister == nil {
+		return api.NewFailedResponse("entry lister is not available"), nil
+	}
+	for _, policy := range policies {
+		if (policy.Action == actionArchive || policy.Action == actionMark) && request.FS == nil {
+			return api.NewFailedResponse(fmt.Sprintf("policy %s: file system is not available", policy.Name)), nil
+		}
+	}
+	recursive := api.GetBoolParameter("recursive", request, false)
+	dryRun := api.GetBoolParameter("dry_run", request, false)
+
+	p.logger.Infow("lifecycle started", "parent_uri", parentURI, "policies", len(policies), "recursive", recursive, "dry_run", dryRun)
+
+	var (
+		now       = p.now()
+		evaluated int
+		selected  = make(map[string][]candidate)
+	)
+	err = p.walk(ctx, request.Lister, parentURI, "", recursive, func(c candidate) {
+		evaluated++
+		for _, policy := range policies {
+			if policy.matches(c.entry, now) {
+				selected[policy.Name] = append(selected[policy.Name], c)
+				return
+			}
+		}
+	})
+	if err != nil {
+		p.logger.Warnw("list entries failed", "parent_uri", parentURI, "error", err)
+		return api.NewFailedResponse("failed to list entries: " + err.Error()), nil
+	}
+
+	var (
+		actions  = make([]map[string]any, 0)
+		archives = make([]map[string]any, 0)
+		matched  int
+		failed   int
+	)
+	for _, policy := range policies {
+		candidates := selected[policy.Name]
+		if len(candidates) == 0 {
+			continue
+		}
+		matched += len(candidates)
+
+		var results []actionResult
+		if dryRun {
+			for _, c := range candidates {
+				results = append(results, newActionResult(policy, c, statusPlanned, nil))
+			}
+		} else if policy.Action == actionArchive {
+			var archive map[string]any
+			results, archive = p.archive(ctx, request, policy, candidates)
+			if archive != nil {
+				archives = append(archives, archive)
+			}
+		} else {
+			for _, c := range candidates {
+				results = append(results, newActionResult(policy, c, "", p.apply(ctx, request, policy, c.entry)))
+			}
+		}
+		for _, r := range results {
+			if r.Status == statusFailed {
+				failed++
+				p.logger.Warnw("lifecycle action failed", "policy", r.Policy, "action", r.Action, "entry_uri", r.EntryURI, "error", r.Error)
+			}
+		}
+		for _, r := range results {
+			actions = append(actions, utils.MarshalMap(r))
+		}
+	}
+
+	p.logger.Infow("lifecycle completed", "parent_uri", parentURI, "evaluated", evaluated, "matched", matched, "failed", failed)
+	return api.NewResponseWithResult(map[string]any{
+		"evaluated": evaluated,
+		"matched":   matched,
+		"failed":    failed,
+		"dry_run":   dryRun,
+		"actions":   actions,
+		"archives":  archives,
+	}), nil
+}
+
+func (p *LifecyclePlugin) loadPolicies(request *api.Request) ([]*compiledPolicy, error) {
+	var policies []Policy
+	if raw := api.GetStringParameter("policies", request, ""); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &policies); err != nil {
+			return nil, fmt.Errorf("parse policies failed: %s", err)
+		}
+	}
+	if policyPath := api.GetStringParameter("policy_path", request, ""); policyPath != "" {
+		data, err := p.fileRoot.Read(policyPath)
+		if err != nil {
+			return nil, fmt.Errorf("read policy file failed: %s", err)
+		}
+		var filePolicies []Policy
+		if err = json.Unmarshal(data, &filePolicies); err != nil {
+			return nil, fmt.Errorf("parse policy file failed: %s", err)
+		}
+		policies = append(policies, filePolicies...)
+	}
+	return compilePolicies(policies)
+}
+
+// walk lists the entries under parentURI in depth first order, groups themselves are never selected.
+func (p *LifecyclePlugin) walk(ctx context.Context, lister api.Lister, parentURI, prefix string, recursive bool, fn func(candidate)) error {
+	entries, err := lister.ListEntries(ctx, parentURI)
+	if err != nil {
+		return err
+	}
+	for _, en := range entries {
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+		entryPath := path.Join(prefix, en.Name)
+		if en.IsGroup {
+			if recursive {
+				if err = p.walk(ctx, lister, en.URI, entryPath, recursive, fn); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		fn(candidate{entry: en, path: entryPath})
+	}
+	return nil
+}
+
+func (p *LifecyclePlugin) apply(ctx context.Context, request *api.Request, policy *compiledPolicy, en types.Entry) error {
+	switch policy.Action {
+	case actionMove:
+		return request.Lister.MoveEntry(ctx, en.URI, policy.TargetURI)
+	case actionDelete:
+		return request.Lister.DeleteEntry(ctx, en.URI)
+	case actionMark:
+		return request.FS.UpdateEntry(ctx, en.URI, "", policy.Properties)
+	}
+	return fmt.Errorf("unknown action: %s", policy.Action)
+}
+
+// archive packs the entries into one zip saved under target_uri, and deletes the
+// originals only once the zip is saved.
+func (p *LifecyclePlugin) archive(ctx context.Context, request *api.Request, policy *compiledPolicy, candidates []candidate) ([]actionResult, map[string]any) {
+	name := policy.ArchiveName
+	if name == "" {
+		name = fmt.Sprintf("%s-%s.zip", policy.Name, p.now().Format("20060102"))
+	}
+
+	results := make([]actionResult, 0, len(candidates))
+	packed, err := p.writeZip(ctx, request.Lister, name, candidates)
+	if err == nil {
+		err = p.saveArchive(ctx, request.FS, policy.TargetURI, name)
+	}
+	_ = p.fileRoot.Remove(name)
+	if err != nil {
+		for _, c := range candidates {
+			results = append(results, newActionResult(policy, c, "", fmt.Errorf("archive %s failed: %s", name, err)))
+		}
+		return results, nil
+	}
+
+	for _, c := range candidates {
+		if !packed[c.entry.URI] {
+			results = append(results, newActionResult(policy, c, "", fmt.Errorf("read entry failed")))
+			continue
+		}
+		results = append(results, newActionResult(policy, c, "", request.Lister.DeleteEntry(ctx, c.entry.URI)))
+	}
+	return results, map[string]any{
+		"policy":    policy.Name,
+		"name":      name,
+		"entry_uri": path.Join(policy.TargetURI, name),
+		"entries":   len(packed),
+	}
+}
+
+// writeZip returns the URIs of the entries that were packed, unreadable entries are skipped.
+func (p *LifecyclePlugin) writeZip(ctx context.Context, lister api.Lister, name string, candidates []candidate) (map[string]bool, error) {
+	f, err := p.fileRoot.Create(name, 0644)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	packed := make(map[string]bool)
+	zw := zip.NewWriter(f)
+	for _, c := range candidates {
+		if err = p.addToZip(ctx, lister, zw, c); err != nil {
+			p.logger.Warnw("read entry for archive failed", "entry_uri", c.entry.URI, "error", err)
+			continue
+		}
+		packed[c.entry.URI] = true
+	}
+	if err = zw.Close(); err != nil {
+		return nil, err
+	}
+	if len(packed) == 0 {
+		return nil, fmt.Errorf("no entry could be read")
+	}
+	return packed, f.Close()
+}
+
+func (p *LifecyclePlugin) addToZip(ctx context.Context, lister api.Lister, zw *zip.Writer, c candidate) error {
+	reader, err := lister.OpenEntry(ctx, c.entry.URI)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	header := &zip.FileHeader{Name: c.path, Method: zip.Deflate}
+	if ts := entryTime(c.entry); !ts.IsZero() {
+		header.Modified = ts
+	}
+	w, err := zw.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, reader)
+	return err
+}
+
+func (p *LifecyclePlugin) saveArchive(ctx context.Context, fs api.NanaFS, targetURI, name string) error {
+	f, err := p.fileRoot.Open(name)
+	if err != nil {
+		return err
+	}
+	return fs.SaveEntry(ctx, targetURI, name, types.Properties{Title: name}, f)
+}
+
+func newActionResult(policy *compiledPolicy, c candidate, status string, err error) actionResult {
+	r := actionResult{Policy: policy.Name, Action: policy.Action, EntryURI: c.entry.URI, Path: c.path, Status: status}
+	if r.Status == "" {
+		r.Status = statusDone
+		if err != nil {
+			r.Status = statusFailed
+			r.Error = err.Error()
+		}
+	}
+	return r
+}
@@ -0,0 +1,310 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package lifecycle
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/logger"
+	"github.com/basenana/plugin/types"
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) {
+	logger.SetLogger(zap.NewNop().Sugar())
+	os.Exit(m.Run())
+}
+
+var testNow = time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+func newLifecyclePlugin(workdir string) *LifecyclePlugin {
+	p := NewLifecyclePlugin(types.PluginCall{JobID: "job-1", WorkingPath: workdir}).(*LifecyclePlugin)
+	p.now = func() time.Time { return testNow }
+	return p
+}
+
+type memEntry struct {
+	entry   types.Entry
+	parent  string
+	content string
+}
+
+// memFS implements both api.NanaFS and api.Lister over an in-memory tree.
+type memFS struct {
+	entries map[string]*memEntry
+	saved   map[string][]byte
+	updated map[string]types.Properties
+	openErr map[string]bool
+}
+
+func newMemFS() *memFS {
+	return &memFS{
+		entries: make(map[string]*memEntry),
+		saved:   make(map[string][]byte),
+		updated: make(map[string]types.Properties),
+		openErr: make(map[string]bool),
+	}
+}
+
+func (m *memFS) add(parent string, en types.Entry, content string) {
+	en.URI = path.Join(parent, en.Name)
+	m.entries[en.URI] = &memEntry{entry: en, parent: parent, content: content}
+}
+
+func (m *memFS) ListEntries(ctx context.Context, parentURI string) ([]types.Entry, error) {
+	var result []types.Entry
+	for _, en := range m.entries {
+		if en.parent == parentURI {
+			result = append(result, en.entry)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result, nil
+}
+
+func (m *memFS) OpenEntry(ctx context.Context, entryURI string) (io.ReadCloser, error) {
+	en, ok := m.entries[entryURI]
+	if !ok || m.openErr[entryURI] {
+		return nil, fmt.Errorf("open %s failed", entryURI)
+	}
+	return io.NopCloser(strings.NewReader(en.content)), nil
+}
+
+func (m *memFS) MoveEntry(ctx context.Context, entryURI, newParentURI string) error {
+	en, ok := m.entries[entryURI]
+	if !ok {
+		return fmt.Errorf("entry %s not found", entryURI)
+	}
+	delete(m.entries, entryURI)
+	m.add(newParentURI, en.entry, en.content)
+	return nil
+}
+
+func (m *memFS) DeleteEntry(ctx context.Context, entryURI string) error {
+	if _, ok := m.entries[entryURI]; !ok {
+		return fmt.Errorf("entry %s not found", entryURI)
+	}
+	delete(m.entries, entryURI)
+	return nil
+}
+
+func (m *memFS) CreateGroupIfNotExists(ctx context.Context, parentURI, group string, properties types.Properties) error {
+	return nil
+}
+
+func (m *memFS) SaveEntry(ctx context.Context, parentURI, name string, properties types.Properties, reader io.ReadCloser) error {
+	defer reader.Close()
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	m.saved[path.Join(parentURI, name)] = data
+	return nil
+}
+
+func (m *memFS) UpdateEntry(ctx context.Context, entryURI, content string, properties types.Properties) error {
+	m.updated[entryURI] = properties
+	return nil
+}
+
+func (m *memFS) GetEntryProperties(ctx context.Context, entryURI string) (*types.Properties, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *memFS) Search(ctx context.Context, query string, filter types.SearchFilter) ([]types.Entry, error) {
+	return nil, nil
+}
+
+func daysAgo(n int) int64 {
+	return testNow.Add(-time.Duration(n) * 24 * time.Hour).Unix()
+}
+
+func boolPtr(b bool) *bool { return &b }
+
+func newTestTree() *memFS {
+	fs := newMemFS()
+	fs.add("/inbox", types.Entry{Name: "old-read.html", ModifiedAt: daysAgo(90), Properties: types.Properties{Unread: boolPtr(false)}}, "old read")
+	fs.add("/inbox", types.Entry{Name: "old-unread.html", ModifiedAt: daysAgo(90), Properties: types.Properties{Unread: boolPtr(true)}}, "old unread")
+	fs.add("/inbox", types.Entry{Name: "news.html", ModifiedAt: daysAgo(5), Properties: types.Properties{Keywords: []string{"News", "tech"}}}, "news")
+	fs.add("/inbox", types.Entry{Name: "fresh.html", ModifiedAt: daysAgo(1)}, "fresh")
+	fs.add("/inbox", types.Entry{Name: "2023", IsGroup: true}, "")
+	fs.add("/inbox/2023", types.Entry{Name: "nested.html", CreatedAt: daysAgo(400)}, "nested")
+	return fs
+}
+
+func runLifecycle(t *testing.T, p *LifecyclePlugin, fs *memFS, params map[string]any) *api.Response {
+	t.Helper()
+	resp, err := p.Run(context.Background(), &api.Request{Parameter: params, FS: fs, Lister: fs})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	return resp
+}
+
+func TestLifecyclePlugin_Actions(t *testing.T) {
+	fs := newTestTree()
+	p := newLifecyclePlugin(t.TempDir())
+
+	resp := runLifecycle(t, p, fs, map[string]any{
+		"parent_uri": "/inbox",
+		"policies": `[
+			{"name": "drop-read", "match": {"older_than": "30d", "unread": false}, "action": "delete"},
+			{"name": "stale", "match": {"older_than": "720h"}, "action": "mark", "properties": {"marked": true}},
+			{"name": "news", "match": {"tags": ["news"]}, "action": "move", "target_uri": "/news"}
+		]`,
+	})
+	if !resp.IsSucceed {
+		t.Fatalf("Run not succeed: %s", resp.Message)
+	}
+	if resp.Results["evaluated"] != 4 || resp.Results["matched"] != 3 || resp.Results["failed"] != 0 {
+		t.Errorf("unexpected results: %v", resp.Results)
+	}
+	if _, ok := fs.entries["/inbox/old-read.html"]; ok {
+		t.Error("old read entry should be deleted")
+	}
+	if props, ok := fs.updated["/inbox/old-unread.html"]; !ok || !boolValue(props.Marked) {
+		t.Errorf("old unread entry should be marked, got %v", fs.updated)
+	}
+	if _, ok := fs.entries["/news/news.html"]; !ok {
+		t.Error("news entry should be moved")
+	}
+	if _, ok := fs.entries["/inbox/2023/nested.html"]; !ok {
+		t.Error("nested entry should be untouched without recursive")
+	}
+}
+
+func TestLifecyclePlugin_DryRun(t *testing.T) {
+	fs := newTestTree()
+	p := newLifecyclePlugin(t.TempDir())
+
+	resp := runLifecycle(t, p, fs, map[string]any{
+		"parent_uri": "/inbox",
+		"recursive":  true,
+		"dry_run":    true,
+		"policies":   `[{"name": "old", "match": {"older_than": "60d"}, "action": "delete"}]`,
+	})
+	if !resp.IsSucceed {
+		t.Fatalf("Run not succeed: %s", resp.Message)
+	}
+	actions := resp.Results["actions"].([]map[string]any)
+	if len(actions) != 3 {
+		t.Fatalf("expected 3 planned actions, got %v", actions)
+	}
+	for _, a := range actions {
+		if a["status"] != statusPlanned {
+			t.Errorf("expected planned status, got %v", a)
+		}
+	}
+	if actions[0]["path"] != "2023/nested.html" {
+		t.Errorf("expected nested path first, got %v", actions[0]["path"])
+	}
+	if len(fs.entries) != 6 {
+		t.Errorf("dry run should not change entries, got %d", len(fs.entries))
+	}
+}
+
+func TestLifecyclePlugin_Archive(t *testing.T) {
+	fs := newTestTree()
+	fs.openErr["/inbox/old-unread.html"] = true
+	workdir := t.TempDir()
+	p := newLifecyclePlugin(workdir)
+
+	resp := runLifecycle(t, p, fs, map[string]any{
+		"parent_uri": "/inbox",
+		"recursive":  "true",
+		"policies":   `[{"name": "yearly", "match": {"older_than": "60d"}, "action": "archive", "target_uri": "/archive"}]`,
+	})
+	if !resp.IsSucceed {
+		t.Fatalf("Run not succeed: %s", resp.Message)
+	}
+	if resp.Results["failed"] != 1 {
+		t.Errorf("expected the unreadable entry to fail, got %v", resp.Results)
+	}
+
+	data, ok := fs.saved["/archive/yearly-20240601.zip"]
+	if !ok {
+		t.Fatalf("archive not saved, got %v", fs.saved)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+	}
+	if strings.Join(names, ",") != "2023/nested.html,old-read.html" {
+		t.Errorf("unexpected archive content: %v", names)
+	}
+
+	if _, ok = fs.entries["/inbox/old-read.html"]; ok {
+		t.Error("archived entry should be deleted")
+	}
+	if _, ok = fs.entries["/inbox/old-unread.html"]; !ok {
+		t.Error("unreadable entry should be kept")
+	}
+	if _, err = os.Stat(path.Join(workdir, "yearly-20240601.zip")); !os.IsNotExist(err) {
+		t.Error("local archive should be removed")
+	}
+}
+
+func TestLifecyclePlugin_Errors(t *testing.T) {
+	fs := newTestTree()
+	p := newLifecyclePlugin(t.TempDir())
+
+	cases := []struct {
+		name    string
+		params  map[string]any
+		request *api.Request
+		msg     string
+	}{
+		{"missing parent", map[string]any{"policies": `[]`}, nil, "parent_uri is required"},
+		{"no policies", map[string]any{"parent_uri": "/inbox"}, nil, "at least one policy is required"},
+		{"bad json", map[string]any{"parent_uri": "/inbox", "policies": `{`}, nil, "parse policies failed"},
+		{"no lister", map[string]any{"parent_uri": "/inbox", "policies": `[{"match": {"unread": true}, "action": "delete"}]`},
+			&api.Request{FS: fs}, "entry lister is not available"},
+		{"no fs for mark", map[string]any{"parent_uri": "/inbox", "policies": `[{"match": {"unread": true}, "action": "mark", "properties": {"marked": true}}]`},
+			&api.Request{Lister: fs}, "file system is not available"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			req := c.request
+			if req == nil {
+				req = &api.Request{FS: fs, Lister: fs}
+			}
+			req.Parameter = c.params
+			resp, err := p.Run(context.Background(), req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.IsSucceed || !strings.Contains(resp.Message, c.msg) {
+				t.Errorf("expected failure %q, got %+v", c.msg, resp)
+			}
+		})
+	}
+}
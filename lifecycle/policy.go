@@ -0,0 +1,170 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package lifecycle
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/basenana/plugin/types"
+)
+
+const (
+	actionArchive = "archive"
+	actionMove    = "move"
+	actionDelete  = "delete"
+	actionMark    = "mark"
+)
+
+// Policy selects entries with Match and applies Action to them.
+type Policy struct {
+	Name        string           `json:"name"`
+	Match       Match            `json:"match"`
+	Action      string           `json:"action"`
+	TargetURI   string           `json:"target_uri,omitempty"`
+	ArchiveName string           `json:"archive_name,omitempty"`
+	Properties  types.Properties `json:"properties,omitempty"`
+}
+
+// Match conditions are combined with AND, empty conditions are ignored.
+type Match struct {
+	OlderThan string   `json:"older_than,omitempty"`
+	Tags      []string `json:"tags,omitempty"`
+	Unread    *bool    `json:"unread,omitempty"`
+	Marked    *bool    `json:"marked,omitempty"`
+	MinSize   int64    `json:"min_size,omitempty"`
+	MaxSize   int64    `json:"max_size,omitempty"`
+}
+
+type compiledPolicy struct {
+	Policy
+	olderThan time.Duration
+}
+
+func compilePolicies(policies []Policy) ([]*compiledPolicy, error) {
+	if len(policies) == 0 {
+		return nil, fmt.Errorf("at least one policy is required")
+	}
+	compiled := make([]*compiledPolicy, 0, len(policies))
+	seen := make(map[string]bool)
+	for i, p := range policies {
+		if p.Name == "" {
+			p.Name = fmt.Sprintf("policy-%d", i+1)
+		}
+		if seen[p.Name] {
+			return nil, fmt.Errorf("duplicate policy name: %s", p.Name)
+		}
+		seen[p.Name] = true
+
+		switch p.Action {
+		case actionArchive, actionMove:
+			if p.TargetURI == "" {
+				return nil, fmt.Errorf("policy %s: target_uri is required for %s", p.Name, p.Action)
+			}
+		case actionDelete, actionMark:
+		default:
+			return nil, fmt.Errorf("policy %s: unknown action: %s", p.Name, p.Action)
+		}
+		if p.Action == actionMark && p.Properties.Unread == nil && p.Properties.Marked == nil && len(p.Properties.Keywords) == 0 {
+			return nil, fmt.Errorf("policy %s: properties are required for mark", p.Name)
+		}
+		if p.Match.MinSize < 0 || p.Match.MaxSize < 0 || (p.Match.MaxSize > 0 && p.Match.MinSize > p.Match.MaxSize) {
+			return nil, fmt.Errorf("policy %s: invalid size range", p.Name)
+		}
+
+		cp := &compiledPolicy{Policy: p}
+		if p.Match.OlderThan != "" {
+			d, err := parseAge(p.Match.OlderThan)
+			if err != nil || d <= 0 {
+				return nil, fmt.Errorf("policy %s: invalid older_than: %s", p.Name, p.Match.OlderThan)
+			}
+			cp.olderThan = d
+		}
+		if p.Match.OlderThan == "" && len(p.Match.Tags) == 0 && p.Match.Unread == nil &&
+			p.Match.Marked == nil && p.Match.MinSize == 0 && p.Match.MaxSize == 0 {
+			return nil, fmt.Errorf("policy %s: match has no conditions", p.Name)
+		}
+		compiled = append(compiled, cp)
+	}
+	return compiled, nil
+}
+
+// parseAge accepts Go durations and a day suffix, such as "720h" or "30d".
+func parseAge(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
+
+func (p *compiledPolicy) matches(en types.Entry, now time.Time) bool {
+	if p.olderThan > 0 {
+		ts := entryTime(en)
+		if ts.IsZero() || now.Sub(ts) < p.olderThan {
+			return false
+		}
+	}
+	if len(p.Match.Tags) > 0 && !hasTags(en.Properties.Keywords, p.Match.Tags) {
+		return false
+	}
+	if p.Match.Unread != nil && boolValue(en.Properties.Unread) != *p.Match.Unread {
+		return false
+	}
+	if p.Match.Marked != nil && boolValue(en.Properties.Marked) != *p.Match.Marked {
+		return false
+	}
+	if p.Match.MinSize > 0 && en.Size < p.Match.MinSize {
+		return false
+	}
+	if p.Match.MaxSize > 0 && en.Size > p.Match.MaxSize {
+		return false
+	}
+	return true
+}
+
+// entryTime is the last modification of an entry, falling back to creation and publish time.
+func entryTime(en types.Entry) time.Time {
+	for _, ts := range []int64{en.ModifiedAt, en.CreatedAt, en.Properties.PublishAt} {
+		if ts > 0 {
+			return time.Unix(ts, 0)
+		}
+	}
+	return time.Time{}
+}
+
+func hasTags(keywords, tags []string) bool {
+	have := make(map[string]bool, len(keywords))
+	for _, k := range keywords {
+		have[strings.ToLower(strings.TrimSpace(k))] = true
+	}
+	for _, t := range tags {
+		if !have[strings.ToLower(strings.TrimSpace(t))] {
+			return false
+		}
+	}
+	return true
+}
+
+func boolValue(b *bool) bool {
+	return b != nil && *b
+}
@@ -0,0 +1,90 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package lifecycle
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/basenana/plugin/types"
+)
+
+func TestCompilePolicies(t *testing.T) {
+	cases := []struct {
+		policies []Policy
+		err      string
+	}{
+		{[]Policy{{Match: Match{Unread: boolPtr(true)}, Action: "shred"}}, "unknown action"},
+		{[]Policy{{Match: Match{Unread: boolPtr(true)}, Action: actionMove}}, "target_uri is required"},
+		{[]Policy{{Match: Match{Unread: boolPtr(true)}, Action: actionMark}}, "properties are required"},
+		{[]Policy{{Action: actionDelete}}, "match has no conditions"},
+		{[]Policy{{Match: Match{OlderThan: "soon"}, Action: actionDelete}}, "invalid older_than"},
+		{[]Policy{{Match: Match{MinSize: 10, MaxSize: 5}, Action: actionDelete}}, "invalid size range"},
+		{[]Policy{{Name: "a", Match: Match{MinSize: 1}, Action: actionDelete}, {Name: "a", Match: Match{MinSize: 1}, Action: actionDelete}}, "duplicate policy name"},
+	}
+	for _, c := range cases {
+		if _, err := compilePolicies(c.policies); err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("compilePolicies(%+v) expected %q, got %v", c.policies, c.err, err)
+		}
+	}
+
+	compiled, err := compilePolicies([]Policy{{Match: Match{OlderThan: "30d"}, Action: actionDelete}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if compiled[0].Name != "policy-1" || compiled[0].olderThan != 30*24*time.Hour {
+		t.Errorf("unexpected compiled policy: %+v", compiled[0])
+	}
+}
+
+func TestPolicyMatches(t *testing.T) {
+	compiled, err := compilePolicies([]Policy{{
+		Match:  Match{OlderThan: "7d", Tags: []string{"news"}, Unread: boolPtr(false), MinSize: 10, MaxSize: 100},
+		Action: actionDelete,
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy := compiled[0]
+	base := types.Entry{Size: 50, ModifiedAt: daysAgo(10), Properties: types.Properties{Keywords: []string{" NEWS "}}}
+	if !policy.matches(base, testNow) {
+		t.Fatal("expected entry to match")
+	}
+
+	for name, modify := range map[string]func(en *types.Entry){
+		"too new":   func(en *types.Entry) { en.ModifiedAt = daysAgo(3) },
+		"no time":   func(en *types.Entry) { en.ModifiedAt = 0 },
+		"no tag":    func(en *types.Entry) { en.Properties.Keywords = []string{"tech"} },
+		"unread":    func(en *types.Entry) { en.Properties.Unread = boolPtr(true) },
+		"too small": func(en *types.Entry) { en.Size = 5 },
+		"too large": func(en *types.Entry) { en.Size = 500 },
+	} {
+		en := base
+		modify(&en)
+		if policy.matches(en, testNow) {
+			t.Errorf("%s: expected entry not to match", name)
+		}
+	}
+
+	en := base
+	en.ModifiedAt = 0
+	en.Properties.PublishAt = daysAgo(30)
+	if !policy.matches(en, testNow) {
+		t.Error("expected publish time to be used when modification time is missing")
+	}
+}
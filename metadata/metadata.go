@@ -53,7 +53,7 @@ type MetadataPlugin struct {
 func NewMetadataPlugin(ps types.PluginCall) types.Plugin {
 	return &MetadataPlugin{
 		logger:   logger.NewPluginLogger(pluginName, ps.JobID),
-		fileRoot: utils.NewFileAccess(ps.WorkingPath),
+		fileRoot: utils.NewFileAccess(ps.WorkingPath, ps.JobWorkingPath),
 	}
 }
 
@@ -19,11 +19,17 @@ package plugin
 import (
 	"context"
 	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
 	"time"
 
+	"code.dny.dev/ssrf"
 	"github.com/basenana/plugin/api"
 	"github.com/basenana/plugin/logger"
 	"github.com/basenana/plugin/types"
+	"github.com/basenana/plugin/utils"
 	"go.uber.org/zap"
 )
 
@@ -35,23 +41,80 @@ type ProcessPlugin interface {
 const (
 	delayPluginName    = "delay"
 	delayPluginVersion = "1.0"
+
+	defaultWaitInterval = time.Second
+	defaultWaitTimeout  = 10 * time.Minute
 )
 
 var DelayProcessPluginSpec = types.PluginSpec{
 	Name:    delayPluginName,
 	Version: delayPluginVersion,
 	Type:    types.TypeProcess,
+	Parameters: []types.ParameterSpec{
+		{
+			Name:        "delay",
+			Required:    false,
+			Description: "Duration to wait (e.g., 5s, 1m30s)",
+		},
+		{
+			Name:        "until",
+			Required:    false,
+			Description: "RFC3339 timestamp to wait until",
+		},
+		{
+			Name:        "wait_for_file",
+			Required:    false,
+			Description: "Wait until the file appears in the working path",
+		},
+		{
+			Name:        "wait_for_url",
+			Required:    false,
+			Description: "Wait until the http or https URL returns HTTP 200",
+		},
+		{
+			Name:        "interval",
+			Required:    false,
+			Default:     "1s",
+			Description: "Polling interval for wait_for_file and wait_for_url",
+		},
+		{
+			Name:        "timeout",
+			Required:    false,
+			Default:     "10m",
+			Description: "Maximum wait time for wait_for_file and wait_for_url",
+		},
+	},
 }
 
+// delayPrivateNet follows the WebPackerEnablePrivateNet env of the web package.
+var delayPrivateNet = os.Getenv("WebPackerEnablePrivateNet") == "true"
+
 type DelayProcessPlugin struct {
-	logger *zap.SugaredLogger
+	logger   *zap.SugaredLogger
+	fileRoot *utils.FileAccess
+	client   *http.Client
 }
 
 var _ ProcessPlugin = &DelayProcessPlugin{}
 
 func NewDelayProcessPlugin(ps types.PluginCall) types.Plugin {
 	return &DelayProcessPlugin{
-		logger: logger.NewPluginLogger(delayPluginName, ps.JobID),
+		logger:   logger.NewPluginLogger(delayPluginName, ps.JobID),
+		fileRoot: utils.NewFileAccess(ps.WorkingPath, ps.JobWorkingPath),
+		client:   newDelayClient(),
+	}
+}
+
+// newDelayClient polls wait_for_url without reaching private addresses, unless the
+// WebPackerEnablePrivateNet env allows them.
+func newDelayClient() *http.Client {
+	dialer := &net.Dialer{Timeout: 30 * time.Second}
+	if !delayPrivateNet {
+		dialer.Control = ssrf.New().Safe
+	}
+	return &http.Client{
+		Timeout:   30 * time.Second,
+		Transport: &http.Transport{DialContext: dialer.DialContext},
 	}
 }
 
@@ -71,8 +134,28 @@ func (d *DelayProcessPlugin) Run(ctx context.Context, request *api.Request) (*ap
 	var (
 		delayDurationStr = api.GetStringParameter("delay", request, "")
 		untilStr         = api.GetStringParameter("until", request, "")
+		waitForFile      = api.GetStringParameter("wait_for_file", request, "")
+		waitForURL       = api.GetStringParameter("wait_for_url", request, "")
 	)
 
+	switch {
+	case waitForFile != "":
+		if err := d.fileRoot.ValidatePath(waitForFile); err != nil {
+			return api.NewFailedResponse(fmt.Sprintf("invalid file path: %s", err)), nil
+		}
+		return d.waitFor(ctx, request, "file", waitForFile, func(ctx context.Context) bool {
+			_, err := d.fileRoot.Stat(waitForFile)
+			return err == nil
+		})
+	case waitForURL != "":
+		if u, err := url.Parse(waitForURL); err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			return api.NewFailedResponse(fmt.Sprintf("invalid url: %s", waitForURL)), nil
+		}
+		return d.waitFor(ctx, request, "url", waitForURL, func(ctx context.Context) bool {
+			return d.urlReady(ctx, waitForURL)
+		})
+	}
+
 	var (
 		now   = time.Now()
 		until time.Time
@@ -116,3 +199,64 @@ func (d *DelayProcessPlugin) Run(ctx context.Context, request *api.Request) (*ap
 
 	return api.NewResponse(), nil
 }
+
+func (d *DelayProcessPlugin) waitFor(ctx context.Context, request *api.Request, kind, target string, ready func(ctx context.Context) bool) (*api.Response, error) {
+	var (
+		intervalStr = api.GetStringParameter("interval", request, "")
+		timeoutStr  = api.GetStringParameter("timeout", request, "")
+		interval    = defaultWaitInterval
+		timeout     = defaultWaitTimeout
+		err         error
+	)
+
+	if intervalStr != "" {
+		interval, err = time.ParseDuration(intervalStr)
+		if err != nil || interval <= 0 {
+			d.logger.Warnw("parse wait interval failed", "interval", intervalStr, "error", err)
+			return nil, fmt.Errorf("parse wait interval [%s] failed: %v", intervalStr, err)
+		}
+	}
+	if timeoutStr != "" {
+		timeout, err = time.ParseDuration(timeoutStr)
+		if err != nil || timeout <= 0 {
+			d.logger.Warnw("parse wait timeout failed", "timeout", timeoutStr, "error", err)
+			return nil, fmt.Errorf("parse wait timeout [%s] failed: %v", timeoutStr, err)
+		}
+	}
+
+	d.logger.Infow("wait started", "kind", kind, "target", target, "interval", interval, "timeout", timeout)
+
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if ready(ctx) {
+			d.logger.Infow("wait completed", "kind", kind, "target", target)
+			return api.NewResponse(), nil
+		}
+		select {
+		case <-ticker.C:
+		case <-deadline.C:
+			d.logger.Warnw("wait timeout", "kind", kind, "target", target, "timeout", timeout)
+			return api.NewFailedResponse(fmt.Sprintf("wait for %s %s timeout after %s", kind, target, timeout)), nil
+		case <-ctx.Done():
+			return api.NewFailedResponse(ctx.Err().Error()), nil
+		}
+	}
+}
+
+func (d *DelayProcessPlugin) urlReady(ctx context.Context, target string) bool {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return false
+	}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		d.logger.Debugw("poll url failed", "url", target, "error", err)
+		return false
+	}
+	defer resp.Body.Close()
+	return resp.StatusCode == http.StatusOK
+}
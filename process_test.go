@@ -18,6 +18,9 @@ package plugin
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -266,3 +269,190 @@ func TestDelayPlugin_ContextCancellation(t *testing.T) {
 		t.Error("expected failure due to context cancellation")
 	}
 }
+
+func TestDelayPlugin_WaitForFile(t *testing.T) {
+	p := newDelayPlugin(t)
+	ctx := context.Background()
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		_ = p.fileRoot.Write("ready.txt", []byte("ok"), 0644)
+	}()
+
+	req := &api.Request{
+		Parameter: map[string]any{
+			"wait_for_file": "ready.txt",
+			"interval":      "10ms",
+			"timeout":       "5s",
+		},
+	}
+
+	resp, err := p.Run(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.IsSucceed {
+		t.Errorf("expected success, got failure: %s", resp.Message)
+	}
+}
+
+func TestDelayPlugin_WaitForFile_Timeout(t *testing.T) {
+	p := newDelayPlugin(t)
+	ctx := context.Background()
+
+	req := &api.Request{
+		Parameter: map[string]any{
+			"wait_for_file": "never.txt",
+			"interval":      "10ms",
+			"timeout":       "50ms",
+		},
+	}
+
+	resp, err := p.Run(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.IsSucceed {
+		t.Error("expected failure on timeout")
+	}
+}
+
+func TestDelayPlugin_WaitForFile_OutsideWorkingPath(t *testing.T) {
+	p := newDelayPlugin(t)
+
+	req := &api.Request{
+		Parameter: map[string]any{
+			"wait_for_file": "../outside.txt",
+		},
+	}
+
+	resp, err := p.Run(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.IsSucceed {
+		t.Error("expected failure for path outside working path")
+	}
+}
+
+func TestDelayPlugin_WaitForURL(t *testing.T) {
+	allowDelayPrivateNet(t)
+	p := newDelayPlugin(t)
+	ctx := context.Background()
+
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&hits, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	req := &api.Request{
+		Parameter: map[string]any{
+			"wait_for_url": server.URL,
+			"interval":     "10ms",
+			"timeout":      "5s",
+		},
+	}
+
+	resp, err := p.Run(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.IsSucceed {
+		t.Errorf("expected success, got failure: %s", resp.Message)
+	}
+	if atomic.LoadInt32(&hits) < 3 {
+		t.Errorf("expected at least 3 polls, got %d", hits)
+	}
+}
+
+func TestDelayPlugin_WaitForURL_Timeout(t *testing.T) {
+	allowDelayPrivateNet(t)
+	p := newDelayPlugin(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	req := &api.Request{
+		Parameter: map[string]any{
+			"wait_for_url": server.URL,
+			"interval":     "10ms",
+			"timeout":      "50ms",
+		},
+	}
+
+	resp, err := p.Run(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.IsSucceed {
+		t.Error("expected failure on timeout")
+	}
+}
+
+func allowDelayPrivateNet(t *testing.T) {
+	orig := delayPrivateNet
+	delayPrivateNet = true
+	t.Cleanup(func() { delayPrivateNet = orig })
+}
+
+func TestDelayPlugin_WaitForURL_Rejected(t *testing.T) {
+	p := newDelayPlugin(t)
+	for _, target := range []string{"file:///etc/passwd", "ftp://example.com/ready", "gopher://example.com/"} {
+		resp, err := p.Run(context.Background(), &api.Request{Parameter: map[string]any{"wait_for_url": target}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.IsSucceed {
+			t.Errorf("expected %s to be rejected", target)
+		}
+	}
+}
+
+func TestDelayPlugin_WaitForURL_PrivateAddress(t *testing.T) {
+	orig := delayPrivateNet
+	delayPrivateNet = false
+	defer func() { delayPrivateNet = orig }()
+	p := newDelayPlugin(t)
+
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	resp, err := p.Run(context.Background(), &api.Request{Parameter: map[string]any{
+		"wait_for_url": server.URL,
+		"interval":     "10ms",
+		"timeout":      "50ms",
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.IsSucceed || atomic.LoadInt32(&hits) != 0 {
+		t.Errorf("expected the loopback URL to be blocked, got success=%v hits=%d", resp.IsSucceed, hits)
+	}
+}
+
+func TestDelayPlugin_WaitInvalidInterval(t *testing.T) {
+	p := newDelayPlugin(t)
+
+	req := &api.Request{
+		Parameter: map[string]any{
+			"wait_for_file": "ready.txt",
+			"interval":      "invalid",
+		},
+	}
+
+	_, err := p.Run(context.Background(), req)
+	if err == nil {
+		t.Error("expected error for invalid interval")
+	}
+}
@@ -0,0 +1,152 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package publish
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/basenana/plugin/types"
+)
+
+const (
+	gitAuthorName  = "NanaFS Publisher"
+	gitAuthorEmail = "publisher@nanafs.local"
+)
+
+var (
+	// gitBranchPattern rejects a leading '-', git would read such a branch as an option.
+	gitBranchPattern = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9._/-]*$`)
+	// gitTransportPattern matches remote helper URLs such as ext::<command>, which run commands.
+	gitTransportPattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9+.-]*::`)
+)
+
+// validateGitBranch accepts the branch names of git check-ref-format --branch made of ASCII
+// letters, digits and ._/- only.
+func validateGitBranch(branch string) error {
+	if !gitBranchPattern.MatchString(branch) || strings.Contains(branch, "..") || strings.Contains(branch, "//") ||
+		strings.Contains(branch, "/.") || strings.HasSuffix(branch, "/") || strings.HasSuffix(branch, ".") ||
+		strings.HasSuffix(branch, ".lock") {
+		return fmt.Errorf("invalid branch [%s]", branch)
+	}
+	return nil
+}
+
+// validateGitRepo rejects repos git would read as an option or hand to a remote helper.
+func validateGitRepo(repo string) error {
+	if strings.HasPrefix(repo, "-") || gitTransportPattern.MatchString(repo) || strings.ContainsAny(repo, "\x00\n") {
+		return fmt.Errorf("invalid repo [%s]", repo)
+	}
+	return nil
+}
+
+type gitPublisher struct {
+	runner  types.CommandRunner
+	workdir string
+	repo    string
+	branch  string
+	message string
+}
+
+func (g *gitPublisher) Publish(ctx context.Context, docs []document) (map[string]any, error) {
+	workdir, err := os.MkdirTemp(g.workdir, ".publish-git-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(workdir)
+
+	if _, err = g.git(ctx, workdir, "init", "-q"); err != nil {
+		return nil, err
+	}
+	if _, err = g.git(ctx, workdir, "remote", "add", "--", "origin", g.repo); err != nil {
+		return nil, err
+	}
+	// a missing branch is created from scratch instead of failing the publish
+	if _, err = g.git(ctx, workdir, "fetch", "-q", "--depth", "1", "--", "origin", g.branch); err == nil {
+		_, err = g.git(ctx, workdir, "checkout", "-q", "-B", g.branch, "FETCH_HEAD")
+	} else {
+		_, err = g.git(ctx, workdir, "checkout", "-q", "-b", g.branch)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, doc := range docs {
+		target := filepath.Join(workdir, filepath.FromSlash(doc.Path))
+		if err = os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return nil, err
+		}
+		if err = os.WriteFile(target, doc.Data, 0644); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err = g.git(ctx, workdir, "add", "-A"); err != nil {
+		return nil, err
+	}
+	status, err := g.git(ctx, workdir, "status", "--porcelain")
+	if err != nil {
+		return nil, err
+	}
+	if status == "" {
+		commit, _ := g.git(ctx, workdir, "rev-parse", "HEAD")
+		return map[string]any{"commit": commit, "changed": false}, nil
+	}
+
+	if _, err = g.git(ctx, workdir, "commit", "-q", "-m", g.message); err != nil {
+		return nil, err
+	}
+	if _, err = g.git(ctx, workdir, "push", "-q", "--", "origin", "HEAD:refs/heads/"+g.branch); err != nil {
+		return nil, err
+	}
+	commit, err := g.git(ctx, workdir, "rev-parse", "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{"commit": commit, "changed": true}, nil
+}
+
+func (g *gitPublisher) git(ctx context.Context, dir string, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	err := g.runner.Run(ctx, types.Command{
+		Name: "git",
+		Args: args,
+		Dir:  dir,
+		Env: []string{
+			"GIT_TERMINAL_PROMPT=0",
+			"GIT_AUTHOR_NAME=" + gitAuthorName,
+			"GIT_AUTHOR_EMAIL=" + gitAuthorEmail,
+			"GIT_COMMITTER_NAME=" + gitAuthorName,
+			"GIT_COMMITTER_EMAIL=" + gitAuthorEmail,
+		},
+		Stdout:  &stdout,
+		Stderr:  &stderr,
+		Network: true,
+	})
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s failed: %s", args[0], msg)
+		}
+		return "", fmt.Errorf("git %s failed: %w", args[0], err)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
@@ -0,0 +1,346 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package publish
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/logger"
+	"github.com/basenana/plugin/sandbox"
+	"github.com/basenana/plugin/types"
+	"github.com/basenana/plugin/utils"
+	"go.uber.org/zap"
+)
+
+const (
+	pluginName    = "publish"
+	pluginVersion = "1.0"
+
+	targetGit    = "git"
+	targetWebDAV = "webdav"
+	targetS3     = "s3"
+
+	ConfigWebDAVUsername = "publish_webdav_username"
+	ConfigWebDAVPassword = "publish_webdav_password"
+	ConfigS3AccessKey    = "publish_s3_access_key"
+	ConfigS3SecretKey    = "publish_s3_secret_key"
+)
+
+var PluginSpec = types.PluginSpec{
+	Name:    pluginName,
+	Version: pluginVersion,
+	Type:    types.TypeProcess,
+	Dependencies: []types.Dependency{
+		{Kind: types.DependencyCapability, Name: types.CapabilityNetwork},
+		{Kind: types.DependencyBinary, Name: "git", Optional: true},
+	},
+	Parameters: []types.ParameterSpec{
+		{
+			Name:        "target",
+			Required:    true,
+			Description: "Publish target: git, webdav, s3",
+			Options:     []string{targetGit, targetWebDAV, targetS3},
+		},
+		{
+			Name:        "files",
+			Required:    true,
+			Description: "Files in the working path to publish, as a JSON array or comma-separated list",
+		},
+		{
+			Name:        "dest_dir",
+			Required:    false,
+			Description: "Directory at the target the files are published into",
+		},
+		{
+			Name:        "front_matter",
+			Required:    false,
+			Description: "JSON object added as YAML front matter to markdown files without one",
+		},
+		{
+			Name:        "repo",
+			Required:    false,
+			Description: "Git repository URL or path (git target)",
+		},
+		{
+			Name:        "branch",
+			Required:    false,
+			Default:     "main",
+			Description: "Git branch to push to (git target)",
+		},
+		{
+			Name:        "commit_message",
+			Required:    false,
+			Description: "Git commit message (git target)",
+		},
+		{
+			Name:        "url",
+			Required:    false,
+			Description: "WebDAV folder URL (webdav target)",
+		},
+		{
+			Name:        "endpoint",
+			Required:    false,
+			Description: "S3 endpoint URL, e.g. https://s3.us-east-1.amazonaws.com (s3 target)",
+		},
+		{
+			Name:        "bucket",
+			Required:    false,
+			Description: "S3 bucket (s3 target)",
+		},
+		{
+			Name:        "region",
+			Required:    false,
+			Default:     "us-east-1",
+			Description: "S3 region (s3 target)",
+		},
+	},
+}
+
+// document is one file ready to be published at its destination path.
+type document struct {
+	Path        string
+	Data        []byte
+	ContentType string
+}
+
+type publisher interface {
+	Publish(ctx context.Context, docs []document) (map[string]any, error)
+}
+
+type PublishPlugin struct {
+	logger   *zap.SugaredLogger
+	fileRoot *utils.FileAccess
+	config   map[string]string
+	jobID    string
+	runner   types.CommandRunner
+}
+
+func NewPublishPlugin(ps types.PluginCall) types.Plugin {
+	return &PublishPlugin{
+		logger:   logger.NewPluginLogger(pluginName, ps.JobID),
+		fileRoot: utils.NewFileAccess(ps.WorkingPath, ps.JobWorkingPath),
+		config:   ps.Config,
+		jobID:    ps.JobID,
+		runner:   sandbox.ForCall(ps),
+	}
+}
+
+func (p *PublishPlugin) Name() string {
+	return pluginName
+}
+
+func (p *PublishPlugin) Type() types.PluginType {
+	return types.TypeProcess
+}
+
+func (p *PublishPlugin) Version() string {
+	return pluginVersion
+}
+
+func (p *PublishPlugin) Run(ctx context.Context, request *api.Request) (*api.Response, error) {
+	target := api.GetStringParameter("target", request, "")
+	if target == "" {
+		return api.NewFailedResponse("target is required"), nil
+	}
+
+	files, err := parseFiles(api.GetStringParameter("files", request, ""))
+	if err != nil {
+		return api.NewFailedResponse(err.Error()), nil
+	}
+	if len(files) == 0 {
+		return api.NewFailedResponse("files is required"), nil
+	}
+
+	var frontMatter map[string]any
+	if raw := api.GetStringParameter("front_matter", request, ""); raw != "" {
+		if err = json.Unmarshal([]byte(raw), &frontMatter); err != nil {
+			return api.NewFailedResponse(fmt.Sprintf("parse front_matter failed: %s", err)), nil
+		}
+	}
+
+	pub, err := p.newPublisher(target, request)
+	if err != nil {
+		return api.NewFailedResponse(err.Error()), nil
+	}
+
+	destDir := strings.Trim(api.GetStringParameter("dest_dir", request, ""), "/")
+	if err = p.validateDestPath(destDir); destDir != "" && err != nil {
+		return api.NewFailedResponse(fmt.Sprintf("invalid dest_dir: %s", err)), nil
+	}
+	docs := make([]document, 0, len(files))
+	for _, file := range files {
+		data, err := p.fileRoot.Read(file)
+		if err != nil {
+			p.logger.Warnw("read publish file failed", "file", file, "error", err)
+			return api.NewFailedResponse(fmt.Sprintf("read file %s failed: %s", file, err)), nil
+		}
+		ext := strings.ToLower(filepath.Ext(file))
+		if len(frontMatter) > 0 && (ext == ".md" || ext == ".markdown") {
+			data = addFrontMatter(data, frontMatter)
+		}
+		docPath := path.Join(destDir, filepath.Base(file))
+		if err = p.validateDestPath(docPath); err != nil {
+			return api.NewFailedResponse(fmt.Sprintf("invalid destination of %s: %s", file, err)), nil
+		}
+		docs = append(docs, document{
+			Path:        docPath,
+			Data:        data,
+			ContentType: contentType(ext),
+		})
+	}
+
+	p.logger.Infow("publish started", "target", target, "files", len(docs))
+	results, err := pub.Publish(ctx, docs)
+	if err != nil {
+		p.logger.Warnw("publish failed", "target", target, "error", err)
+		return api.NewFailedResponse(fmt.Sprintf("publish to %s failed: %s", target, err)), nil
+	}
+
+	published := make([]string, 0, len(docs))
+	for _, doc := range docs {
+		published = append(published, doc.Path)
+	}
+	results["target"] = target
+	results["published"] = published
+	p.logger.Infow("publish completed", "target", target, "files", len(docs))
+	return api.NewResponseWithResult(results), nil
+}
+
+// validateDestPath keeps a path at the target inside its root, the git target writes it into a
+// clone on the host, and out of the .git directory of the clone.
+func (p *PublishPlugin) validateDestPath(destPath string) error {
+	for _, part := range strings.Split(destPath, "/") {
+		if part == ".." || strings.EqualFold(part, ".git") {
+			return fmt.Errorf("%s: .. and .git are not allowed", destPath)
+		}
+	}
+	return p.fileRoot.ValidatePath(destPath)
+}
+
+func (p *PublishPlugin) newPublisher(target string, request *api.Request) (publisher, error) {
+	switch target {
+	case targetGit:
+		repo := api.GetStringParameter("repo", request, "")
+		if repo == "" {
+			return nil, fmt.Errorf("repo is required for git target")
+		}
+		if err := validateGitRepo(repo); err != nil {
+			return nil, err
+		}
+		branch := api.GetStringParameter("branch", request, "main")
+		if err := validateGitBranch(branch); err != nil {
+			return nil, err
+		}
+		return &gitPublisher{
+			runner:  p.runner,
+			workdir: p.fileRoot.Workdir(),
+			repo:    repo,
+			branch:  branch,
+			message: api.GetStringParameter("commit_message", request, fmt.Sprintf("Publish from job %s", p.jobID)),
+		}, nil
+	case targetWebDAV:
+		folder := api.GetStringParameter("url", request, "")
+		if folder == "" {
+			return nil, fmt.Errorf("url is required for webdav target")
+		}
+		return newWebDAVPublisher(folder, p.config[ConfigWebDAVUsername], p.config[ConfigWebDAVPassword])
+	case targetS3:
+		endpoint := api.GetStringParameter("endpoint", request, "")
+		bucket := api.GetStringParameter("bucket", request, "")
+		if endpoint == "" || bucket == "" {
+			return nil, fmt.Errorf("endpoint and bucket are required for s3 target")
+		}
+		accessKey, secretKey := p.config[ConfigS3AccessKey], p.config[ConfigS3SecretKey]
+		if accessKey == "" || secretKey == "" {
+			return nil, fmt.Errorf("%s and %s are required in config for s3 target", ConfigS3AccessKey, ConfigS3SecretKey)
+		}
+		return newS3Publisher(endpoint, bucket, api.GetStringParameter("region", request, "us-east-1"), accessKey, secretKey)
+	default:
+		return nil, fmt.Errorf("unknown target: %s", target)
+	}
+}
+
+// parseFiles accepts a JSON array or a comma/newline separated list.
+func parseFiles(raw string) ([]string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, nil
+	}
+	var files []string
+	if strings.HasPrefix(raw, "[") {
+		if err := json.Unmarshal([]byte(raw), &files); err != nil {
+			return nil, fmt.Errorf("parse files failed: %s", err)
+		}
+	} else {
+		files = strings.FieldsFunc(raw, func(r rune) bool { return r == ',' || r == '\n' })
+	}
+
+	result := make([]string, 0, len(files))
+	for _, f := range files {
+		if f = strings.TrimSpace(f); f != "" {
+			result = append(result, f)
+		}
+	}
+	return result, nil
+}
+
+// addFrontMatter prepends YAML front matter unless the document already has one.
+func addFrontMatter(data []byte, frontMatter map[string]any) []byte {
+	if bytes.HasPrefix(bytes.TrimPrefix(data, []byte("\ufeff")), []byte("---\n")) {
+		return data
+	}
+
+	keys := make([]string, 0, len(frontMatter))
+	for k := range frontMatter {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	buf := &bytes.Buffer{}
+	buf.WriteString("---\n")
+	for _, k := range keys {
+		// JSON scalars and arrays are valid YAML flow values
+		value, err := json.Marshal(frontMatter[k])
+		if err != nil {
+			continue
+		}
+		buf.WriteString(fmt.Sprintf("%s: %s\n", k, value))
+	}
+	buf.WriteString("---\n\n")
+	buf.Write(data)
+	return buf.Bytes()
+}
+
+func contentType(ext string) string {
+	switch ext {
+	case ".md", ".markdown":
+		return "text/markdown; charset=utf-8"
+	}
+	if t := mime.TypeByExtension(ext); t != "" {
+		return t
+	}
+	return "application/octet-stream"
+}
@@ -0,0 +1,354 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package publish
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/logger"
+	"github.com/basenana/plugin/types"
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) {
+	logger.SetLogger(zap.NewNop().Sugar())
+	os.Exit(m.Run())
+}
+
+func newPublishPlugin(t *testing.T, config map[string]string) (*PublishPlugin, string) {
+	workdir := t.TempDir()
+	files := map[string]string{
+		"post.md":    "# Hello\n\nbody\n",
+		"page.html":  "<html><body>page</body></html>",
+		"draft.md":   "---\ntitle: kept\n---\n\ndraft\n",
+		"notes/a.md": "nested",
+	}
+	for name, content := range files {
+		full := filepath.Join(workdir, name)
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return NewPublishPlugin(types.PluginCall{JobID: "job-1", WorkingPath: workdir, Config: config}).(*PublishPlugin), workdir
+}
+
+type recordedRequest struct {
+	Method string
+	Path   string
+	Auth   string
+	Body   string
+}
+
+func newRecorder(status func(r *http.Request) int) (*httptest.Server, func() []recordedRequest) {
+	var (
+		mux      sync.Mutex
+		requests []recordedRequest
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mux.Lock()
+		requests = append(requests, recordedRequest{Method: r.Method, Path: r.URL.EscapedPath(), Auth: r.Header.Get("Authorization"), Body: string(body)})
+		mux.Unlock()
+		w.WriteHeader(status(r))
+	}))
+	return srv, func() []recordedRequest {
+		mux.Lock()
+		defer mux.Unlock()
+		return append([]recordedRequest(nil), requests...)
+	}
+}
+
+func TestParseFiles(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    []string
+		wantErr bool
+	}{
+		{raw: "", want: nil},
+		{raw: `["a.md", "b.html"]`, want: []string{"a.md", "b.html"}},
+		{raw: "a.md, b.md\nc.md", want: []string{"a.md", "b.md", "c.md"}},
+		{raw: `["a.md"`, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseFiles(tt.raw)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("parseFiles(%q) error = %v, wantErr %v", tt.raw, err, tt.wantErr)
+		}
+		if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("parseFiles(%q) = %v, want %v", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestAddFrontMatter(t *testing.T) {
+	got := string(addFrontMatter([]byte("body\n"), map[string]any{"title": "Hello", "tags": []string{"a", "b"}, "draft": false}))
+	want := "---\ndraft: false\ntags: [\"a\",\"b\"]\ntitle: \"Hello\"\n---\n\nbody\n"
+	if got != want {
+		t.Errorf("addFrontMatter() = %q, want %q", got, want)
+	}
+
+	existing := "---\ntitle: kept\n---\nbody"
+	if got = string(addFrontMatter([]byte(existing), map[string]any{"title": "x"})); got != existing {
+		t.Errorf("addFrontMatter() overwrote existing front matter: %q", got)
+	}
+}
+
+func TestPublishPlugin_InvalidParameters(t *testing.T) {
+	p, _ := newPublishPlugin(t, nil)
+	tests := []map[string]any{
+		{"files": "post.md"},
+		{"target": "webdav"},
+		{"target": "ftp", "files": "post.md"},
+		{"target": "webdav", "files": "post.md"},
+		{"target": "s3", "files": "post.md", "endpoint": "http://127.0.0.1", "bucket": "site"},
+		{"target": "git", "files": "post.md"},
+		{"target": "git", "files": "post.md", "repo": "ext::sh -c touch% /tmp/pwned"},
+		{"target": "git", "files": "post.md", "repo": "--upload-pack=touch /tmp/pwned"},
+		{"target": "git", "files": "post.md", "repo": "https://example.com/site.git", "branch": "-b"},
+		{"target": "webdav", "files": "missing.md", "url": "http://127.0.0.1/dav"},
+		{"target": "webdav", "files": "post.md", "url": "http://127.0.0.1/dav", "front_matter": "{"},
+	}
+	for _, params := range tests {
+		resp, err := p.Run(context.Background(), &api.Request{Parameter: params})
+		if err != nil {
+			t.Fatalf("Run(%v) returned error: %v", params, err)
+		}
+		if resp.IsSucceed {
+			t.Errorf("Run(%v) succeeded, want failure", params)
+		}
+	}
+}
+
+func TestPublishPlugin_WebDAV(t *testing.T) {
+	srv, requests := newRecorder(func(r *http.Request) int {
+		if r.Method == "MKCOL" && r.URL.Path == "/dav/blog/" {
+			return http.StatusMethodNotAllowed
+		}
+		return http.StatusCreated
+	})
+	defer srv.Close()
+
+	p, _ := newPublishPlugin(t, map[string]string{ConfigWebDAVUsername: "alice", ConfigWebDAVPassword: "secret"})
+	resp, err := p.Run(context.Background(), &api.Request{Parameter: map[string]any{
+		"target":       "webdav",
+		"files":        `["post.md", "notes/a.md"]`,
+		"dest_dir":     "blog/2024",
+		"url":          srv.URL + "/dav",
+		"front_matter": `{"title": "Hello"}`,
+	}})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if !resp.IsSucceed {
+		t.Fatalf("Run not succeed: %s", resp.Message)
+	}
+
+	var got []string
+	for _, r := range requests() {
+		got = append(got, r.Method+" "+r.Path)
+		if !strings.HasPrefix(r.Auth, "Basic ") {
+			t.Errorf("%s %s missing basic auth", r.Method, r.Path)
+		}
+		if r.Path == "/dav/blog/2024/post.md" && !strings.HasPrefix(r.Body, "---\ntitle: \"Hello\"\n---\n") {
+			t.Errorf("post.md body missing front matter: %q", r.Body)
+		}
+	}
+	want := []string{"MKCOL /dav/blog/", "MKCOL /dav/blog/2024/", "PUT /dav/blog/2024/post.md", "PUT /dav/blog/2024/a.md"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("requests = %v, want %v", got, want)
+	}
+
+	published := resp.Results["published"].([]string)
+	if len(published) != 2 || published[0] != "blog/2024/post.md" {
+		t.Errorf("published = %v", published)
+	}
+}
+
+func TestPublishPlugin_WebDAVFailure(t *testing.T) {
+	srv, _ := newRecorder(func(r *http.Request) int { return http.StatusForbidden })
+	defer srv.Close()
+
+	p, _ := newPublishPlugin(t, nil)
+	resp, err := p.Run(context.Background(), &api.Request{Parameter: map[string]any{
+		"target": "webdav",
+		"files":  "post.md",
+		"url":    srv.URL,
+	}})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if resp.IsSucceed || !strings.Contains(resp.Message, "403") {
+		t.Errorf("expected 403 failure, got succeed=%v message=%q", resp.IsSucceed, resp.Message)
+	}
+}
+
+func TestPublishPlugin_S3(t *testing.T) {
+	srv, requests := newRecorder(func(r *http.Request) int { return http.StatusOK })
+	defer srv.Close()
+
+	p, _ := newPublishPlugin(t, map[string]string{ConfigS3AccessKey: "AKID", ConfigS3SecretKey: "SECRET"})
+	resp, err := p.Run(context.Background(), &api.Request{Parameter: map[string]any{
+		"target":   "s3",
+		"files":    "page.html,post.md",
+		"dest_dir": "site",
+		"endpoint": srv.URL,
+		"bucket":   "my-bucket",
+		"region":   "eu-west-1",
+	}})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if !resp.IsSucceed {
+		t.Fatalf("Run not succeed: %s", resp.Message)
+	}
+
+	reqs := requests()
+	if len(reqs) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(reqs))
+	}
+	if reqs[0].Method != http.MethodPut || reqs[0].Path != "/my-bucket/site/page.html" {
+		t.Errorf("unexpected request %s %s", reqs[0].Method, reqs[0].Path)
+	}
+	if !strings.HasPrefix(reqs[0].Auth, "AWS4-HMAC-SHA256 Credential=AKID/") || !strings.Contains(reqs[0].Auth, "/eu-west-1/s3/aws4_request") {
+		t.Errorf("unexpected authorization header %q", reqs[0].Auth)
+	}
+}
+
+func TestPublishPlugin_Git(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	remote := filepath.Join(t.TempDir(), "site.git")
+	if out, err := exec.Command("git", "init", "-q", "--bare", remote).CombinedOutput(); err != nil {
+		t.Fatalf("init bare repo failed: %v %s", err, out)
+	}
+
+	p, _ := newPublishPlugin(t, nil)
+	run := func() *api.Response {
+		resp, err := p.Run(context.Background(), &api.Request{Parameter: map[string]any{
+			"target":         "git",
+			"files":          "post.md,draft.md",
+			"dest_dir":       "content/posts",
+			"repo":           remote,
+			"branch":         "pages",
+			"commit_message": "publish posts",
+			"front_matter":   `{"title": "Hello"}`,
+		}})
+		if err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		if !resp.IsSucceed {
+			t.Fatalf("Run not succeed: %s", resp.Message)
+		}
+		return resp
+	}
+
+	resp := run()
+	commit, _ := resp.Results["commit"].(string)
+	if commit == "" || resp.Results["changed"] != true {
+		t.Fatalf("unexpected results %v", resp.Results)
+	}
+
+	out, err := exec.Command("git", "--git-dir", remote, "show", "pages:content/posts/post.md").CombinedOutput()
+	if err != nil {
+		t.Fatalf("read published file failed: %v %s", err, out)
+	}
+	if !strings.HasPrefix(string(out), "---\ntitle: \"Hello\"\n---\n\n# Hello") {
+		t.Errorf("unexpected published content %q", out)
+	}
+	out, _ = exec.Command("git", "--git-dir", remote, "log", "-1", "--format=%s", "pages").CombinedOutput()
+	if strings.TrimSpace(string(out)) != "publish posts" {
+		t.Errorf("unexpected commit message %q", out)
+	}
+
+	resp = run()
+	if resp.Results["changed"] != false || resp.Results["commit"] != commit {
+		t.Errorf("republishing unchanged files should not commit, got %v", resp.Results)
+	}
+}
+
+func TestValidateGitBranch(t *testing.T) {
+	for _, branch := range []string{"main", "pages", "release/v1.2", "feature_x-1"} {
+		if err := validateGitBranch(branch); err != nil {
+			t.Errorf("validateGitBranch(%q) = %v", branch, err)
+		}
+	}
+	for _, branch := range []string{"", "-b", "--upload-pack=x", "a..b", "a//b", "a/", "a/.b", "a.lock", "a b", "a:b", "a@{1}", "a~1"} {
+		if err := validateGitBranch(branch); err == nil {
+			t.Errorf("validateGitBranch(%q) should fail", branch)
+		}
+	}
+}
+
+func TestPublishPlugin_GitOptionBranch(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	remote := filepath.Join(t.TempDir(), "site.git")
+	if out, err := exec.Command("git", "init", "-q", "--bare", remote).CombinedOutput(); err != nil {
+		t.Fatalf("init bare repo failed: %v %s", err, out)
+	}
+	marker := filepath.Join(t.TempDir(), "pwned")
+
+	p, _ := newPublishPlugin(t, nil)
+	resp, err := p.Run(context.Background(), &api.Request{Parameter: map[string]any{
+		"target": "git",
+		"files":  "post.md",
+		"repo":   remote,
+		"branch": "--upload-pack=touch " + marker + ";git-upload-pack",
+	}})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if resp.IsSucceed || !strings.Contains(resp.Message, "invalid branch") {
+		t.Errorf("expected an option-like branch to be refused, got %+v", resp)
+	}
+	if _, err = os.Stat(marker); !os.IsNotExist(err) {
+		t.Errorf("branch was run as a command, stat err: %v", err)
+	}
+}
+
+func TestPublishPlugin_InvalidDestDir(t *testing.T) {
+	p, _ := newPublishPlugin(t, nil)
+	for _, destDir := range []string{"../../x", "blog/../../x", ".git/hooks", "site/.GIT", "/.."} {
+		resp, err := p.Run(context.Background(), &api.Request{Parameter: map[string]any{
+			"target":   "git",
+			"files":    "post.md",
+			"repo":     filepath.Join(t.TempDir(), "site.git"),
+			"dest_dir": destDir,
+		}})
+		if err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		if resp.IsSucceed || !strings.Contains(resp.Message, "invalid dest_dir") {
+			t.Errorf("dest_dir %q: expected it to be refused, got %+v", destDir, resp)
+		}
+	}
+}
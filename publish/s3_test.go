@@ -0,0 +1,48 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package publish
+
+import (
+	"testing"
+	"time"
+)
+
+// Example "GET Object" from the AWS Signature Version 4 documentation.
+func TestSignV4(t *testing.T) {
+	headers := map[string]string{
+		"host":                 "examplebucket.s3.amazonaws.com",
+		"range":                "bytes=0-9",
+		"x-amz-content-sha256": "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+		"x-amz-date":           "20130524T000000Z",
+	}
+	signature, signedHeaders := signV4("GET", "/test.txt", "", headers,
+		"e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+		time.Date(2013, 5, 24, 0, 0, 0, 0, time.UTC), "us-east-1", "wJalrXUtnFEMI/K7MDENG/bPxRfiCYEXAMPLEKEY")
+
+	if signedHeaders != "host;range;x-amz-content-sha256;x-amz-date" {
+		t.Errorf("signedHeaders = %s", signedHeaders)
+	}
+	if want := "f0e8bdb87c964420e857bd35b5d6ed310bd44f0170aba48dd91039c6036bdb41"; signature != want {
+		t.Errorf("signature = %s, want %s", signature, want)
+	}
+}
+
+func TestS3EscapePath(t *testing.T) {
+	if got := s3EscapePath("/bucket/site/my post (1).md"); got != "/bucket/site/my%20post%20%281%29.md" {
+		t.Errorf("s3EscapePath() = %s", got)
+	}
+}
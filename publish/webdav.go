@@ -0,0 +1,114 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package publish
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+type webdavPublisher struct {
+	base     *url.URL
+	username string
+	password string
+	client   *http.Client
+}
+
+func newWebDAVPublisher(folder, username, password string) (*webdavPublisher, error) {
+	base, err := url.Parse(folder)
+	if err != nil || base.Host == "" {
+		return nil, fmt.Errorf("invalid webdav url: %s", folder)
+	}
+	return &webdavPublisher{
+		base:     base,
+		username: username,
+		password: password,
+		client:   &http.Client{Timeout: 60 * time.Second},
+	}, nil
+}
+
+func (w *webdavPublisher) Publish(ctx context.Context, docs []document) (map[string]any, error) {
+	created := make(map[string]bool)
+	urls := make([]string, 0, len(docs))
+	for _, doc := range docs {
+		if err := w.mkcolAll(ctx, path.Dir(doc.Path), created); err != nil {
+			return nil, err
+		}
+		target := w.resolve(doc.Path)
+		if err := w.do(ctx, http.MethodPut, target, doc.Data, doc.ContentType, http.StatusOK, http.StatusCreated, http.StatusNoContent); err != nil {
+			return nil, fmt.Errorf("put %s failed: %w", doc.Path, err)
+		}
+		urls = append(urls, target)
+	}
+	return map[string]any{"urls": urls}, nil
+}
+
+// mkcolAll creates the collections of dir one level at a time, existing ones answer 405.
+func (w *webdavPublisher) mkcolAll(ctx context.Context, dir string, created map[string]bool) error {
+	if dir == "." || dir == "" {
+		return nil
+	}
+	current := ""
+	for _, part := range strings.Split(dir, "/") {
+		current = path.Join(current, part)
+		if created[current] {
+			continue
+		}
+		err := w.do(ctx, "MKCOL", w.resolve(current)+"/", nil, "", http.StatusCreated, http.StatusMethodNotAllowed)
+		if err != nil {
+			return fmt.Errorf("create collection %s failed: %w", current, err)
+		}
+		created[current] = true
+	}
+	return nil
+}
+
+func (w *webdavPublisher) resolve(p string) string {
+	u := *w.base
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + p
+	return u.String()
+}
+
+func (w *webdavPublisher) do(ctx context.Context, method, target string, body []byte, contentType string, expected ...int) error {
+	req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if w.username != "" {
+		req.SetBasicAuth(w.username, w.password)
+	}
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	for _, code := range expected {
+		if resp.StatusCode == code {
+			return nil
+		}
+	}
+	return fmt.Errorf("unexpected status %s", resp.Status)
+}
@@ -18,22 +18,46 @@ package plugin
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/basenana/plugin/agentic"
 	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/approval"
 	"github.com/basenana/plugin/archive"
+	"github.com/basenana/plugin/arxiv"
+	"github.com/basenana/plugin/chart"
 	"github.com/basenana/plugin/checksum"
+	"github.com/basenana/plugin/classify"
 	"github.com/basenana/plugin/docloader"
 	"github.com/basenana/plugin/fileop"
 	"github.com/basenana/plugin/filewrite"
 	"github.com/basenana/plugin/fs"
+	"github.com/basenana/plugin/gpstrack"
+	"github.com/basenana/plugin/invoice"
+	"github.com/basenana/plugin/journal"
+	"github.com/basenana/plugin/lifecycle"
 	"github.com/basenana/plugin/logger"
 	"github.com/basenana/plugin/metadata"
+	"github.com/basenana/plugin/publish"
+	"github.com/basenana/plugin/repowatch"
 	"github.com/basenana/plugin/rss"
+	"github.com/basenana/plugin/sandbox"
+	"github.com/basenana/plugin/snapshot"
+	"github.com/basenana/plugin/tagger"
 	"github.com/basenana/plugin/text"
+	"github.com/basenana/plugin/translation"
 	"github.com/basenana/plugin/types"
+	"github.com/basenana/plugin/utils"
+	"github.com/basenana/plugin/vars"
+	"github.com/basenana/plugin/vcard"
 	"github.com/basenana/plugin/web"
 	"go.uber.org/zap"
 )
@@ -44,31 +68,72 @@ var (
 
 type Factory func(ps types.PluginCall) types.Plugin
 
+// WorkdirRetention controls what happens to a per-call working directory after the call returns.
+type WorkdirRetention string
+
+const (
+	RetainNever     WorkdirRetention = "never"
+	RetainOnFailure WorkdirRetention = "on_failure"
+	RetainAlways    WorkdirRetention = "always"
+
+	callWorkdirName = ".calls"
+)
+
+type Option func(m *manager)
+
+// WithCallWorkdir makes the manager run every call in an isolated subdirectory of the job working path.
+func WithCallWorkdir(retention WorkdirRetention) Option {
+	return func(m *manager) {
+		m.callWorkdir = true
+		m.retention = retention
+	}
+}
+
 type Manager interface {
+	Init() error
 	ListPlugins() []types.PluginSpec
 	GetPlugin(name string) (*types.PluginSpec, error)
+	Describe(name string) (*PluginDoc, error)
 	Register(spec types.PluginSpec, factory Factory)
 	Call(ctx context.Context, ps types.PluginCall, req *api.Request) (resp *api.Response, err error)
 }
 
 type manager struct {
-	plugins map[string]*pluginInfo
-	mux     sync.RWMutex
-	logger  *zap.SugaredLogger
+	plugins      map[string]*pluginInfo
+	mux          sync.RWMutex
+	logger       *zap.SugaredLogger
+	callWorkdir  bool
+	retention    WorkdirRetention
+	capabilities map[string]struct{}
+	order        []string
+	activityLog  bool
+	activityMux  sync.Mutex
+	sandbox      *sandbox.Config
 }
 
 type pluginInfo struct {
-	factory Factory
-	spec    types.PluginSpec
-	disable bool
-	buildIn bool
+	factory       Factory
+	spec          types.PluginSpec
+	disable       bool
+	disableReason error
+	buildIn       bool
 }
 
+// ListPlugins returns plugins in dependency order once Init has been called.
 func (m *manager) ListPlugins() []types.PluginSpec {
 	var infos = make([]*pluginInfo, 0, len(m.plugins))
 	m.mux.Lock()
-	for _, p := range m.plugins {
-		infos = append(infos, p)
+	listed := make(map[string]bool, len(m.order))
+	for _, name := range m.order {
+		if p, ok := m.plugins[name]; ok {
+			infos = append(infos, p)
+			listed[name] = true
+		}
+	}
+	for name, p := range m.plugins {
+		if !listed[name] {
+			infos = append(infos, p)
+		}
 	}
 	m.mux.Unlock()
 
@@ -107,6 +172,22 @@ func (m *manager) Register(spec types.PluginSpec, factory Factory) {
 }
 
 func (m *manager) Call(ctx context.Context, ps types.PluginCall, req *api.Request) (resp *api.Response, err error) {
+	if m.activityLog && req != nil && req.Store != nil {
+		started := time.Now()
+		defer func() {
+			m.recordActivity(ctx, req.Store, ps, started, resp, err)
+		}()
+	}
+	if m.callWorkdir && ps.WorkingPath != "" {
+		ps, err = m.prepareCallWorkdir(ps)
+		if err != nil {
+			return nil, err
+		}
+		defer func() {
+			m.cleanupCallWorkdir(ps, resp, err)
+		}()
+	}
+
 	var plugin types.Plugin
 	plugin, err = m.BuildPlugin(ps)
 	if err != nil {
@@ -132,35 +213,190 @@ func (m *manager) BuildPlugin(ps types.PluginCall) (types.Plugin, error) {
 		m.logger.Warnw("build plugin failed", "plugin", ps.PluginName)
 		return nil, ErrNotFound
 	}
+	disable, reason := p.disable, p.disableReason
 	m.mux.RUnlock()
+	if disable {
+		return nil, fmt.Errorf("%w: %s", ErrPluginDisabled, reason)
+	}
 	if ps.Params == nil {
 		ps.Params = map[string]string{}
 	}
 	if ps.Config == nil {
 		ps.Config = map[string]string{}
 	}
+	ps.Runner = m.commandRunner(p.spec, ps)
 	return p.factory(ps), nil
 }
 
-func New() Manager {
+func (m *manager) prepareCallWorkdir(ps types.PluginCall) (types.PluginCall, error) {
+	// the call id names the workdir removed after the call, it must not reach outside .calls
+	ps.CallID = utils.SanitizeFilename(ps.CallID)
+	if ps.CallID == "" {
+		buf := make([]byte, 8)
+		if _, err := rand.Read(buf); err != nil {
+			return ps, fmt.Errorf("generate call id failed: %w", err)
+		}
+		ps.CallID = hex.EncodeToString(buf)
+	}
+
+	callDir := filepath.Join(ps.WorkingPath, callWorkdirName, fmt.Sprintf("%s-%s", ps.PluginName, ps.CallID))
+	if !isCallWorkdir(ps.WorkingPath, callDir) {
+		return ps, fmt.Errorf("invalid call workdir %s", callDir)
+	}
+	if err := os.MkdirAll(callDir, 0755); err != nil {
+		m.logger.Warnw("create call workdir failed", "plugin", ps.PluginName, "dir", callDir, "error", err)
+		return ps, fmt.Errorf("create call workdir failed: %w", err)
+	}
+
+	ps.JobWorkingPath = ps.WorkingPath
+	ps.WorkingPath = callDir
+	return ps, nil
+}
+
+func (m *manager) cleanupCallWorkdir(ps types.PluginCall, resp *api.Response, err error) {
+	failed := err != nil || resp == nil || !resp.IsSucceed
+	if !failed {
+		m.promoteOutputs(ps, resp.Results)
+	}
+	switch {
+	case m.retention == RetainAlways:
+		return
+	case m.retention == RetainOnFailure && failed:
+		m.logger.Infow("retain call workdir of failed call", "plugin", ps.PluginName, "dir", ps.WorkingPath)
+		return
+	}
+
+	if !isCallWorkdir(ps.JobWorkingPath, ps.WorkingPath) {
+		m.logger.Warnw("skip cleanup of a directory outside the call workdirs", "plugin", ps.PluginName, "dir", ps.WorkingPath)
+		return
+	}
+	if rmErr := os.RemoveAll(ps.WorkingPath); rmErr != nil {
+		m.logger.Warnw("cleanup call workdir failed", "plugin", ps.PluginName, "dir", ps.WorkingPath, "error", rmErr)
+	}
+}
+
+// promoteOutputs moves the files a successful call returns under path keys into the job
+// working path, so later steps still find them once the call workdir is removed.
+func (m *manager) promoteOutputs(ps types.PluginCall, results map[string]any) {
+	for key, value := range results {
+		results[key] = m.promoteValue(ps, key, value)
+	}
+}
+
+func (m *manager) promoteValue(ps types.PluginCall, key string, value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		m.promoteOutputs(ps, v)
+	case []map[string]any:
+		for _, item := range v {
+			m.promoteOutputs(ps, item)
+		}
+	case []any:
+		for i := range v {
+			v[i] = m.promoteValue(ps, key, v[i])
+		}
+	case []string:
+		if isOutputKey(key) {
+			for i := range v {
+				v[i] = m.promoteFile(ps, v[i])
+			}
+		}
+	case string:
+		if isOutputKey(key) {
+			return m.promoteFile(ps, v)
+		}
+	}
+	return value
+}
+
+func isOutputKey(key string) bool {
+	return key == "path" || key == "files" || strings.HasSuffix(key, "_path") || strings.HasSuffix(key, "_paths")
+}
+
+// promoteFile moves an output of the call workdir to the same place in the job working path,
+// absolute paths are rewritten to the new location.
+func (m *manager) promoteFile(ps types.PluginCall, output string) string {
+	rel := output
+	if filepath.IsAbs(output) {
+		var err error
+		if rel, err = filepath.Rel(ps.WorkingPath, output); err != nil {
+			return output
+		}
+	}
+	if rel == "." || !filepath.IsLocal(rel) {
+		return output
+	}
+	src := filepath.Join(ps.WorkingPath, rel)
+	if _, err := os.Lstat(src); err != nil {
+		return output
+	}
+
+	dst := filepath.Join(ps.JobWorkingPath, rel)
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		m.logger.Warnw("promote call output failed", "plugin", ps.PluginName, "path", output, "error", err)
+		return output
+	}
+	if err := os.Rename(src, dst); err != nil {
+		m.logger.Warnw("promote call output failed", "plugin", ps.PluginName, "path", output, "error", err)
+		return output
+	}
+	if filepath.IsAbs(output) {
+		return dst
+	}
+	return output
+}
+
+// isCallWorkdir reports whether dir is a directory directly under <workingPath>/.calls.
+func isCallWorkdir(workingPath, dir string) bool {
+	return workingPath != "" && filepath.Dir(filepath.Clean(dir)) == filepath.Join(workingPath, callWorkdirName)
+}
+
+func New(opts ...Option) Manager {
 	m := &manager{
-		plugins: map[string]*pluginInfo{},
-		logger:  logger.NewLogger("registry"),
+		plugins:   map[string]*pluginInfo{},
+		logger:    logger.NewLogger("registry"),
+		retention: RetainNever,
+	}
+	for _, opt := range opts {
+		opt(m)
 	}
 
+	m.Register(approval.PluginSpec, approval.NewApprovalPlugin)
 	m.Register(archive.PluginSpec, archive.NewArchivePlugin)
+	m.Register(arxiv.PluginSpec, arxiv.NewArxivPlugin)
 	m.Register(agentic.PluginSpec, agentic.NewReactPlugin)
 	m.Register(agentic.ResearchPluginSpec, agentic.NewResearchPlugin)
 	m.Register(agentic.SummaryPluginSpec, agentic.NewSummaryPlugin)
+	m.Register(agentic.ExtractPluginSpec, agentic.NewExtractPlugin)
+	m.Register(agentic.CategorizePluginSpec, agentic.NewCategorizePlugin)
+	m.Register(agentic.EnrichPluginSpec, agentic.NewEnrichPlugin)
+	m.Register(agentic.RAGPluginSpec, agentic.NewRAGPlugin)
+	m.Register(chart.PluginSpec, chart.NewChartPlugin)
 	m.Register(checksum.PluginSpec, checksum.NewChecksumPlugin)
+	m.Register(classify.PluginSpec, classify.NewClassifyPlugin)
 	m.Register(docloader.PluginSpec, docloader.NewDocLoader)
 	m.Register(fileop.PluginSpec, fileop.NewFileOpPlugin)
 	m.Register(filewrite.PluginSpec, filewrite.NewFileWritePlugin)
 	m.Register(fs.SavePluginSpec, fs.NewSaver)
+	m.Register(fs.SearchPluginSpec, fs.NewSearcher)
+	m.Register(fs.ReadPluginSpec, fs.NewReader)
+	m.Register(fs.ListPluginSpec, fs.NewLister)
+	m.Register(fs.DuplicatePluginSpec, fs.NewDuplicateFinder)
 	m.Register(fs.UpdatePluginSpec, fs.NewUpdater)
+	m.Register(gpstrack.PluginSpec, gpstrack.NewGPSTrackPlugin)
+	m.Register(invoice.PluginSpec, invoice.NewInvoicePlugin)
+	m.Register(journal.PluginSpec, journal.NewJournalPlugin)
+	m.Register(lifecycle.PluginSpec, lifecycle.NewLifecyclePlugin)
 	m.Register(metadata.PluginSpec, metadata.NewMetadataPlugin)
+	m.Register(publish.PluginSpec, publish.NewPublishPlugin)
+	m.Register(repowatch.PluginSpec, repowatch.NewRepoWatchPlugin)
 	m.Register(rss.RssSourcePluginSpec, rss.NewRssPlugin)
+	m.Register(snapshot.PluginSpec, snapshot.NewSnapshotPlugin)
+	m.Register(tagger.PluginSpec, tagger.NewTaggerPlugin)
 	m.Register(text.PluginSpec, text.NewTextPlugin)
+	m.Register(translation.PluginSpec, translation.NewTranslationMemoryPlugin)
+	m.Register(vars.PluginSpec, vars.NewVarsPlugin)
+	m.Register(vcard.PluginSpec, vcard.NewVCardPlugin)
 	m.Register(web.WebpackPluginSpec, web.NewWebpackPlugin)
 
 	return m
@@ -0,0 +1,206 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package plugin
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/types"
+)
+
+type workdirRecorder struct {
+	ps      types.PluginCall
+	succeed bool
+}
+
+func (w *workdirRecorder) Name() string           { return "workdir-recorder" }
+func (w *workdirRecorder) Type() types.PluginType { return types.TypeProcess }
+func (w *workdirRecorder) Version() string        { return "1.0" }
+
+func (w *workdirRecorder) Run(ctx context.Context, request *api.Request) (*api.Response, error) {
+	if err := os.WriteFile(filepath.Join(w.ps.WorkingPath, "out.txt"), []byte("ok"), 0644); err != nil {
+		return nil, err
+	}
+	if !w.succeed {
+		return api.NewFailedResponse("failed"), nil
+	}
+	return api.NewResponse(), nil
+}
+
+func newWorkdirManager(t *testing.T, succeed bool, opts ...Option) (Manager, *[]types.PluginCall) {
+	calls := make([]types.PluginCall, 0)
+	m := New(opts...)
+	m.Register(types.PluginSpec{Name: "workdir-recorder", Version: "1.0", Type: types.TypeProcess}, func(ps types.PluginCall) types.Plugin {
+		calls = append(calls, ps)
+		return &workdirRecorder{ps: ps, succeed: succeed}
+	})
+	return m, &calls
+}
+
+func TestManager_Call_SharedWorkdirByDefault(t *testing.T) {
+	workdir := t.TempDir()
+	m, calls := newWorkdirManager(t, true)
+
+	_, err := m.Call(context.Background(), types.PluginCall{PluginName: "workdir-recorder", WorkingPath: workdir}, &api.Request{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if (*calls)[0].WorkingPath != workdir {
+		t.Errorf("expected working path %s, got %s", workdir, (*calls)[0].WorkingPath)
+	}
+	if _, err := os.Stat(filepath.Join(workdir, "out.txt")); err != nil {
+		t.Errorf("expected output in job working path: %v", err)
+	}
+}
+
+func TestManager_Call_IsolatedWorkdir(t *testing.T) {
+	workdir := t.TempDir()
+	m, calls := newWorkdirManager(t, true, WithCallWorkdir(RetainAlways))
+
+	for i := 0; i < 2; i++ {
+		_, err := m.Call(context.Background(), types.PluginCall{PluginName: "workdir-recorder", WorkingPath: workdir}, &api.Request{})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	first, second := (*calls)[0], (*calls)[1]
+	if first.WorkingPath == second.WorkingPath {
+		t.Errorf("expected distinct call workdirs, got %s twice", first.WorkingPath)
+	}
+	for _, call := range []types.PluginCall{first, second} {
+		if call.JobWorkingPath != workdir {
+			t.Errorf("expected job working path %s, got %s", workdir, call.JobWorkingPath)
+		}
+		if call.CallID == "" {
+			t.Error("expected call id to be set")
+		}
+		if !strings.HasPrefix(call.WorkingPath, filepath.Join(workdir, callWorkdirName)) {
+			t.Errorf("expected call workdir under job working path, got %s", call.WorkingPath)
+		}
+		if _, err := os.Stat(filepath.Join(call.WorkingPath, "out.txt")); err != nil {
+			t.Errorf("expected retained output: %v", err)
+		}
+	}
+}
+
+func TestManager_Call_CleanupWorkdir(t *testing.T) {
+	workdir := t.TempDir()
+	m, calls := newWorkdirManager(t, true, WithCallWorkdir(RetainNever))
+
+	_, err := m.Call(context.Background(), types.PluginCall{PluginName: "workdir-recorder", WorkingPath: workdir}, &api.Request{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat((*calls)[0].WorkingPath); !os.IsNotExist(err) {
+		t.Errorf("expected call workdir to be removed, stat err: %v", err)
+	}
+}
+
+func TestManager_Call_RetainOnFailure(t *testing.T) {
+	workdir := t.TempDir()
+
+	m, calls := newWorkdirManager(t, false, WithCallWorkdir(RetainOnFailure))
+	if _, err := m.Call(context.Background(), types.PluginCall{PluginName: "workdir-recorder", WorkingPath: workdir}, &api.Request{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat((*calls)[0].WorkingPath); err != nil {
+		t.Errorf("expected failed call workdir to be retained: %v", err)
+	}
+
+	m, calls = newWorkdirManager(t, true, WithCallWorkdir(RetainOnFailure))
+	if _, err := m.Call(context.Background(), types.PluginCall{PluginName: "workdir-recorder", WorkingPath: workdir}, &api.Request{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat((*calls)[0].WorkingPath); !os.IsNotExist(err) {
+		t.Errorf("expected succeeded call workdir to be removed, stat err: %v", err)
+	}
+}
+
+func TestManager_Call_SanitizesCallID(t *testing.T) {
+	workdir := t.TempDir()
+	victim := filepath.Join(workdir, "victim")
+	if err := os.Mkdir(victim, 0755); err != nil {
+		t.Fatal(err)
+	}
+	m, calls := newWorkdirManager(t, true, WithCallWorkdir(RetainNever))
+
+	_, err := m.Call(context.Background(), types.PluginCall{PluginName: "workdir-recorder", WorkingPath: workdir, CallID: "x/../../../victim"}, &api.Request{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	call := (*calls)[0]
+	if filepath.Dir(call.WorkingPath) != filepath.Join(workdir, callWorkdirName) || strings.Contains(call.CallID, "/") {
+		t.Errorf("expected call workdir directly under %s, got %s", callWorkdirName, call.WorkingPath)
+	}
+	if _, err := os.Stat(victim); err != nil {
+		t.Errorf("expected directory outside the call workdirs to be kept: %v", err)
+	}
+}
+
+func TestIsCallWorkdir(t *testing.T) {
+	for dir, want := range map[string]bool{
+		"/jobs/1/.calls/web-abc":      true,
+		"/jobs/1/.calls/web-abc/../x": true,
+		"/jobs/1/.calls":              false,
+		"/jobs/1/.calls/../victim":    false,
+		"/jobs/1/.calls/a/b":          false,
+		"/jobs/1":                     false,
+	} {
+		if got := isCallWorkdir("/jobs/1", dir); got != want {
+			t.Errorf("isCallWorkdir(%s) = %v, want %v", dir, got, want)
+		}
+	}
+	if isCallWorkdir("", ".calls/web-abc") {
+		t.Error("expected no call workdir without a job working path")
+	}
+}
+
+func TestManager_Call_TwoStepsWithCallWorkdir(t *testing.T) {
+	workdir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(workdir, "input.txt"), []byte("job input"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m := New(WithCallWorkdir(RetainNever))
+	ctx := context.Background()
+
+	resp, err := m.Call(ctx, types.PluginCall{JobID: "job-1", PluginName: "filewrite", WorkingPath: workdir},
+		&api.Request{Parameter: map[string]any{"content": "step output", "dest_path": "out/report.txt"}})
+	if err != nil || !resp.IsSucceed {
+		t.Fatalf("write step failed: %v %v", err, resp)
+	}
+	written, _ := resp.Results["file_path"].(string)
+	if written != filepath.Join(workdir, "out", "report.txt") {
+		t.Errorf("expected output promoted to the job working path, got %s", written)
+	}
+	if entries, _ := os.ReadDir(filepath.Join(workdir, callWorkdirName)); len(entries) != 0 {
+		t.Errorf("expected the call workdir to be removed, got %d entries", len(entries))
+	}
+
+	for _, filePath := range []string{written, "out/report.txt", "input.txt"} {
+		resp, err = m.Call(ctx, types.PluginCall{JobID: "job-1", PluginName: "checksum", WorkingPath: workdir},
+			&api.Request{Parameter: map[string]any{"file_path": filePath}})
+		if err != nil || !resp.IsSucceed {
+			t.Errorf("expected the next step to read %s: %v %v", filePath, err, resp)
+		}
+	}
+}
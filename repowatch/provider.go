@@ -0,0 +1,328 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package repowatch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	kindRelease = "release"
+	kindTag     = "tag"
+	kindIssue   = "issue"
+
+	perPage = 30
+)
+
+// Item is a release, tag or issue of a watched repository.
+type Item struct {
+	Kind        string    `json:"kind"`
+	Repo        string    `json:"repo"`
+	Title       string    `json:"title"`
+	URL         string    `json:"url"`
+	Tag         string    `json:"tag,omitempty"`
+	Number      int       `json:"number,omitempty"`
+	State       string    `json:"state,omitempty"`
+	Author      string    `json:"author,omitempty"`
+	Labels      []string  `json:"labels,omitempty"`
+	Prerelease  bool      `json:"prerelease,omitempty"`
+	PublishedAt time.Time `json:"published_at"`
+	Body        string    `json:"-"`
+	Assets      []Asset   `json:"assets,omitempty"`
+	FilePath    string    `json:"file_path,omitempty"`
+}
+
+type Asset struct {
+	Name     string `json:"name"`
+	URL      string `json:"url"`
+	Size     int64  `json:"size,omitempty"`
+	FilePath string `json:"file_path,omitempty"`
+}
+
+// key identifies the item in the dedup records.
+func (i Item) key() string {
+	if i.Kind == kindIssue {
+		return fmt.Sprintf("%s/%s/%d", i.Repo, i.Kind, i.Number)
+	}
+	return i.Repo + "/" + i.Kind + "/" + i.Tag
+}
+
+type provider interface {
+	releases(ctx context.Context, repo Repo) ([]Item, error)
+	tags(ctx context.Context, repo Repo) ([]Item, error)
+	issues(ctx context.Context, repo Repo, state string) ([]Item, error)
+}
+
+type apiClient struct {
+	cli     *http.Client
+	headers map[string]string
+}
+
+func (c *apiClient) getJSON(ctx context.Context, rawURL string, data any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return err
+	}
+	for k, v := range c.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := c.cli.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("request %s failed: status code is %d: %s", rawURL, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return json.NewDecoder(resp.Body).Decode(data)
+}
+
+type gitHub struct {
+	apiClient
+	apiURL string
+}
+
+func newGitHub(cli *http.Client, apiURL, token string) *gitHub {
+	headers := map[string]string{"Accept": "application/vnd.github+json", "X-GitHub-Api-Version": "2022-11-28"}
+	if token != "" {
+		headers["Authorization"] = "Bearer " + token
+	}
+	return &gitHub{apiClient: apiClient{cli: cli, headers: headers}, apiURL: strings.TrimRight(apiURL, "/")}
+}
+
+func (g *gitHub) releases(ctx context.Context, repo Repo) ([]Item, error) {
+	var releases []struct {
+		TagName     string    `json:"tag_name"`
+		Name        string    `json:"name"`
+		Body        string    `json:"body"`
+		HTMLURL     string    `json:"html_url"`
+		Draft       bool      `json:"draft"`
+		Prerelease  bool      `json:"prerelease"`
+		PublishedAt time.Time `json:"published_at"`
+		CreatedAt   time.Time `json:"created_at"`
+		Author      struct {
+			Login string `json:"login"`
+		} `json:"author"`
+		Assets []struct {
+			Name               string `json:"name"`
+			Size               int64  `json:"size"`
+			BrowserDownloadURL string `json:"browser_download_url"`
+		} `json:"assets"`
+	}
+	if err := g.getJSON(ctx, fmt.Sprintf("%s/repos/%s/releases?per_page=%d", g.apiURL, repo.Path, perPage), &releases); err != nil {
+		return nil, err
+	}
+	items := make([]Item, 0, len(releases))
+	for _, r := range releases {
+		if r.Draft {
+			continue
+		}
+		item := Item{
+			Kind: kindRelease, Repo: repo.String(), Title: firstNonEmpty(r.Name, r.TagName), URL: r.HTMLURL,
+			Tag: r.TagName, Author: r.Author.Login, Prerelease: r.Prerelease, Body: r.Body, PublishedAt: r.PublishedAt,
+		}
+		if item.PublishedAt.IsZero() {
+			item.PublishedAt = r.CreatedAt
+		}
+		for _, a := range r.Assets {
+			item.Assets = append(item.Assets, Asset{Name: a.Name, URL: a.BrowserDownloadURL, Size: a.Size})
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func (g *gitHub) tags(ctx context.Context, repo Repo) ([]Item, error) {
+	var tags []struct {
+		Name string `json:"name"`
+	}
+	if err := g.getJSON(ctx, fmt.Sprintf("%s/repos/%s/tags?per_page=%d", g.apiURL, repo.Path, perPage), &tags); err != nil {
+		return nil, err
+	}
+	items := make([]Item, 0, len(tags))
+	for _, t := range tags {
+		items = append(items, Item{Kind: kindTag, Repo: repo.String(), Title: t.Name, Tag: t.Name, URL: repo.htmlURL("/tree/" + url.PathEscape(t.Name))})
+	}
+	return items, nil
+}
+
+func (g *gitHub) issues(ctx context.Context, repo Repo, state string) ([]Item, error) {
+	var issues []struct {
+		Number    int       `json:"number"`
+		Title     string    `json:"title"`
+		Body      string    `json:"body"`
+		HTMLURL   string    `json:"html_url"`
+		State     string    `json:"state"`
+		CreatedAt time.Time `json:"created_at"`
+		User      struct {
+			Login string `json:"login"`
+		} `json:"user"`
+		Labels []struct {
+			Name string `json:"name"`
+		} `json:"labels"`
+		PullRequest json.RawMessage `json:"pull_request"`
+	}
+	rawURL := fmt.Sprintf("%s/repos/%s/issues?state=%s&sort=created&direction=desc&per_page=%d", g.apiURL, repo.Path, state, perPage)
+	if err := g.getJSON(ctx, rawURL, &issues); err != nil {
+		return nil, err
+	}
+	items := make([]Item, 0, len(issues))
+	for _, i := range issues {
+		// the issues API also lists pull requests
+		if len(i.PullRequest) > 0 {
+			continue
+		}
+		item := Item{
+			Kind: kindIssue, Repo: repo.String(), Title: i.Title, URL: i.HTMLURL, Number: i.Number,
+			State: i.State, Author: i.User.Login, Body: i.Body, PublishedAt: i.CreatedAt,
+		}
+		for _, l := range i.Labels {
+			item.Labels = append(item.Labels, l.Name)
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+type gitLab struct {
+	apiClient
+}
+
+func newGitLab(cli *http.Client, token string) *gitLab {
+	headers := map[string]string{}
+	if token != "" {
+		headers["PRIVATE-TOKEN"] = token
+	}
+	return &gitLab{apiClient: apiClient{cli: cli, headers: headers}}
+}
+
+func (g *gitLab) projectURL(repo Repo) string {
+	return repo.WebURL + "/api/v4/projects/" + url.PathEscape(repo.Path)
+}
+
+func (g *gitLab) releases(ctx context.Context, repo Repo) ([]Item, error) {
+	var releases []struct {
+		TagName         string    `json:"tag_name"`
+		Name            string    `json:"name"`
+		Description     string    `json:"description"`
+		ReleasedAt      time.Time `json:"released_at"`
+		UpcomingRelease bool      `json:"upcoming_release"`
+		Author          struct {
+			Username string `json:"username"`
+		} `json:"author"`
+		Links struct {
+			Self string `json:"self"`
+		} `json:"_links"`
+		Assets struct {
+			Links []struct {
+				Name           string `json:"name"`
+				URL            string `json:"url"`
+				DirectAssetURL string `json:"direct_asset_url"`
+			} `json:"links"`
+		} `json:"assets"`
+	}
+	if err := g.getJSON(ctx, fmt.Sprintf("%s/releases?per_page=%d", g.projectURL(repo), perPage), &releases); err != nil {
+		return nil, err
+	}
+	items := make([]Item, 0, len(releases))
+	for _, r := range releases {
+		item := Item{
+			Kind: kindRelease, Repo: repo.String(), Title: firstNonEmpty(r.Name, r.TagName), Tag: r.TagName,
+			URL:    firstNonEmpty(r.Links.Self, repo.htmlURL("/-/releases/"+url.PathEscape(r.TagName))),
+			Author: r.Author.Username, Prerelease: r.UpcomingRelease, Body: r.Description, PublishedAt: r.ReleasedAt,
+		}
+		for _, l := range r.Assets.Links {
+			item.Assets = append(item.Assets, Asset{Name: l.Name, URL: firstNonEmpty(l.DirectAssetURL, l.URL)})
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func (g *gitLab) tags(ctx context.Context, repo Repo) ([]Item, error) {
+	var tags []struct {
+		Name    string `json:"name"`
+		Message string `json:"message"`
+		Commit  struct {
+			CreatedAt time.Time `json:"created_at"`
+		} `json:"commit"`
+	}
+	if err := g.getJSON(ctx, fmt.Sprintf("%s/repository/tags?per_page=%d", g.projectURL(repo), perPage), &tags); err != nil {
+		return nil, err
+	}
+	items := make([]Item, 0, len(tags))
+	for _, t := range tags {
+		items = append(items, Item{
+			Kind: kindTag, Repo: repo.String(), Title: t.Name, Tag: t.Name, Body: t.Message,
+			URL: repo.htmlURL("/-/tags/" + url.PathEscape(t.Name)), PublishedAt: t.Commit.CreatedAt,
+		})
+	}
+	return items, nil
+}
+
+func (g *gitLab) issues(ctx context.Context, repo Repo, state string) ([]Item, error) {
+	switch state {
+	case "open":
+		state = "opened"
+	case "all":
+		state = ""
+	}
+	var issues []struct {
+		IID         int       `json:"iid"`
+		Title       string    `json:"title"`
+		Description string    `json:"description"`
+		WebURL      string    `json:"web_url"`
+		State       string    `json:"state"`
+		Labels      []string  `json:"labels"`
+		CreatedAt   time.Time `json:"created_at"`
+		Author      struct {
+			Username string `json:"username"`
+		} `json:"author"`
+	}
+	rawURL := fmt.Sprintf("%s/issues?order_by=created_at&sort=desc&per_page=%d", g.projectURL(repo), perPage)
+	if state != "" {
+		rawURL += "&state=" + state
+	}
+	if err := g.getJSON(ctx, rawURL, &issues); err != nil {
+		return nil, err
+	}
+	items := make([]Item, 0, len(issues))
+	for _, i := range issues {
+		items = append(items, Item{
+			Kind: kindIssue, Repo: repo.String(), Title: i.Title, URL: i.WebURL, Number: i.IID, State: i.State,
+			Author: i.Author.Username, Labels: i.Labels, Body: i.Description, PublishedAt: i.CreatedAt,
+		})
+	}
+	return items, nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,86 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package repowatch
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGitHub_IssuesSkipPullRequests(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("state") != "closed" {
+			t.Errorf("unexpected state %s", r.URL.Query().Get("state"))
+		}
+		writeJSON(w, []map[string]any{
+			{"number": 3, "title": "Fix typo", "pull_request": map[string]any{"url": "x"}},
+			{"number": 2, "title": "Broken link", "labels": []map[string]any{{"name": "docs"}}, "user": map[string]any{"login": "bob"}},
+		})
+	}))
+	defer srv.Close()
+
+	items, err := newGitHub(srv.Client(), srv.URL, "").issues(context.Background(), Repo{providerGitHub, "o/r", "https://github.com"}, "closed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].Number != 2 || items[0].Author != "bob" || items[0].Labels[0] != "docs" {
+		t.Errorf("unexpected issues %+v", items)
+	}
+	if items[0].key() != "github:o/r/issue/2" {
+		t.Errorf("unexpected key %s", items[0].key())
+	}
+}
+
+func TestGitLab_ReleasesAndTags(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.EscapedPath() {
+		case "/api/v4/projects/group%2Fproject/releases":
+			writeJSON(w, []map[string]any{{
+				"tag_name": "v2.0", "description": "notes", "released_at": "2024-05-01T00:00:00Z",
+				"assets": map[string]any{"links": []map[string]any{
+					{"name": "bin", "url": "https://example.com/bin", "direct_asset_url": "https://example.com/direct/bin"},
+				}},
+			}})
+		case "/api/v4/projects/group%2Fproject/repository/tags":
+			writeJSON(w, []map[string]any{{"name": "v2.0", "message": "tag message"}})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	repo := Repo{providerGitLab, "group/project", srv.URL}
+	gl := newGitLab(srv.Client(), "")
+	releases, err := gl.releases(context.Background(), repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(releases) != 1 || releases[0].Title != "v2.0" || releases[0].URL != srv.URL+"/group/project/-/releases/v2.0" ||
+		releases[0].Assets[0].URL != "https://example.com/direct/bin" {
+		t.Errorf("unexpected releases %+v", releases)
+	}
+
+	tags, err := gl.tags(context.Background(), repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tags) != 1 || tags[0].Body != "tag message" || tags[0].URL != srv.URL+"/group/project/-/tags/v2.0" {
+		t.Errorf("unexpected tags %+v", tags)
+	}
+}
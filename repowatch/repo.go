@@ -0,0 +1,138 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package repowatch
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+const (
+	providerGitHub = "github"
+	providerGitLab = "gitlab"
+
+	defaultGitHubAPI = "https://api.github.com"
+	gitHubHost       = "github.com"
+	gitLabHost       = "gitlab.com"
+)
+
+// Repo is one watched repository. WebURL is the site root, Path the owner/name or GitLab project path.
+type Repo struct {
+	Provider string `json:"provider"`
+	Path     string `json:"path"`
+	WebURL   string `json:"web_url"`
+}
+
+// String names the repository, self-hosted GitLab instances are prefixed with their host.
+func (r Repo) String() string {
+	if r.Provider == providerGitLab && r.WebURL != "https://"+gitLabHost {
+		u, _ := url.Parse(r.WebURL)
+		return r.Provider + ":" + u.Host + "/" + r.Path
+	}
+	return r.Provider + ":" + r.Path
+}
+
+func (r Repo) htmlURL(suffix string) string {
+	return r.WebURL + "/" + r.Path + suffix
+}
+
+// parseRepo accepts "github:owner/name", "gitlab:group/project", a bare "owner/name" for GitHub,
+// or a repository URL. URLs on hosts other than github.com are treated as GitLab instances.
+func parseRepo(spec string) (Repo, error) {
+	spec = strings.TrimSpace(spec)
+	var repo Repo
+	switch {
+	case strings.HasPrefix(spec, "github:"):
+		repo = Repo{Provider: providerGitHub, Path: spec[len("github:"):], WebURL: "https://" + gitHubHost}
+	case strings.HasPrefix(spec, "gitlab:"):
+		repo = Repo{Provider: providerGitLab, Path: spec[len("gitlab:"):], WebURL: "https://" + gitLabHost}
+	case strings.HasPrefix(spec, "http://") || strings.HasPrefix(spec, "https://"):
+		u, err := url.Parse(spec)
+		if err != nil || u.Host == "" {
+			return Repo{}, fmt.Errorf("invalid repository url %s", spec)
+		}
+		repo = Repo{Provider: providerGitLab, Path: u.Path, WebURL: u.Scheme + "://" + u.Host}
+		if strings.EqualFold(u.Hostname(), gitHubHost) || strings.EqualFold(u.Hostname(), "www."+gitHubHost) {
+			repo.Provider, repo.WebURL = providerGitHub, "https://"+gitHubHost
+		}
+		if i := strings.Index(repo.Path, "/-/"); i >= 0 {
+			repo.Path = repo.Path[:i]
+		}
+	default:
+		repo = Repo{Provider: providerGitHub, Path: spec, WebURL: "https://" + gitHubHost}
+	}
+
+	repo.Path = strings.TrimSuffix(strings.Trim(repo.Path, "/"), ".git")
+	segments := strings.Split(repo.Path, "/")
+	for _, s := range segments {
+		if s == "" {
+			return Repo{}, fmt.Errorf("invalid repository %s", spec)
+		}
+	}
+	switch {
+	case repo.Provider == providerGitHub && len(segments) < 2:
+		return Repo{}, fmt.Errorf("invalid github repository %s: expect owner/name", spec)
+	case repo.Provider == providerGitHub:
+		// drop trailing page paths such as /releases
+		repo.Path = segments[0] + "/" + segments[1]
+	case len(segments) < 2:
+		return Repo{}, fmt.Errorf("invalid gitlab repository %s: expect group/project", spec)
+	}
+	return repo, nil
+}
+
+// parseRepos reads a JSON array or a comma/newline separated list, duplicates are dropped.
+func parseRepos(raw string) ([]Repo, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, fmt.Errorf("repos is required")
+	}
+	var specs []string
+	if strings.HasPrefix(raw, "[") {
+		if err := json.Unmarshal([]byte(raw), &specs); err != nil {
+			return nil, fmt.Errorf("parse repos failed: %s", err)
+		}
+	} else {
+		specs = strings.FieldsFunc(raw, func(r rune) bool { return r == ',' || r == '\n' })
+	}
+
+	var (
+		repos []Repo
+		seen  = make(map[string]bool)
+	)
+	for _, spec := range specs {
+		if strings.TrimSpace(spec) == "" {
+			continue
+		}
+		repo, err := parseRepo(spec)
+		if err != nil {
+			return nil, err
+		}
+		key := strings.ToLower(repo.WebURL + "/" + repo.Path)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		repos = append(repos, repo)
+	}
+	if len(repos) == 0 {
+		return nil, fmt.Errorf("repos is required")
+	}
+	return repos, nil
+}
@@ -0,0 +1,74 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package repowatch
+
+import (
+	"testing"
+)
+
+func TestParseRepo(t *testing.T) {
+	tests := []struct {
+		spec string
+		want Repo
+		name string
+	}{
+		{"github:basenana/plugin", Repo{providerGitHub, "basenana/plugin", "https://github.com"}, "github:basenana/plugin"},
+		{"basenana/plugin", Repo{providerGitHub, "basenana/plugin", "https://github.com"}, "github:basenana/plugin"},
+		{"https://github.com/basenana/plugin.git", Repo{providerGitHub, "basenana/plugin", "https://github.com"}, "github:basenana/plugin"},
+		{"https://github.com/basenana/plugin/releases", Repo{providerGitHub, "basenana/plugin", "https://github.com"}, "github:basenana/plugin"},
+		{"gitlab:group/sub/project", Repo{providerGitLab, "group/sub/project", "https://gitlab.com"}, "gitlab:group/sub/project"},
+		{"https://gitlab.com/group/project/-/releases", Repo{providerGitLab, "group/project", "https://gitlab.com"}, "gitlab:group/project"},
+		{"https://git.example.com:8443/team/tool", Repo{providerGitLab, "team/tool", "https://git.example.com:8443"}, "gitlab:git.example.com:8443/team/tool"},
+	}
+	for _, tt := range tests {
+		got, err := parseRepo(tt.spec)
+		if err != nil {
+			t.Errorf("parseRepo(%s) failed: %s", tt.spec, err)
+			continue
+		}
+		if got != tt.want || got.String() != tt.name {
+			t.Errorf("parseRepo(%s) = %+v (%s), want %+v (%s)", tt.spec, got, got.String(), tt.want, tt.name)
+		}
+	}
+
+	for _, spec := range []string{"plugin", "github:basenana", "gitlab:project", "https://gitlab.com/", "github:a//b", "http://"} {
+		if _, err := parseRepo(spec); err == nil {
+			t.Errorf("parseRepo(%s) expected error", spec)
+		}
+	}
+}
+
+func TestParseRepos(t *testing.T) {
+	repos, err := parseRepos("basenana/plugin, github:basenana/plugin\ngitlab:group/project")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(repos) != 2 || repos[1].Provider != providerGitLab {
+		t.Errorf("unexpected repos %v", repos)
+	}
+
+	repos, err = parseRepos(`["github:basenana/nanafs", "https://gitlab.com/group/project"]`)
+	if err != nil || len(repos) != 2 {
+		t.Errorf("unexpected repos %v, %v", repos, err)
+	}
+
+	for _, raw := range []string{"", " , ", "[]", "[1]"} {
+		if _, err = parseRepos(raw); err == nil {
+			t.Errorf("parseRepos(%q) expected error", raw)
+		}
+	}
+}
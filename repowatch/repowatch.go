@@ -0,0 +1,554 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package repowatch
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/logger"
+	"github.com/basenana/plugin/types"
+	"github.com/basenana/plugin/utils"
+	"go.uber.org/zap"
+)
+
+const (
+	pluginName    = "repo_watch"
+	pluginVersion = "1.0"
+
+	defaultOutputDir    = "repowatch"
+	defaultMaxItems     = 20
+	defaultMaxAssetSize = 100 // MiB
+	defaultIssueState   = "open"
+)
+
+var PluginSpec = types.PluginSpec{
+	Name:    pluginName,
+	Version: pluginVersion,
+	Type:    types.TypeSource,
+	Dependencies: []types.Dependency{
+		{Kind: types.DependencyCapability, Name: types.CapabilityNetwork},
+		{Kind: types.DependencyCapability, Name: types.CapabilityStore, Optional: true},
+	},
+	Parameters: []types.ParameterSpec{
+		{
+			Name:        "repos",
+			Required:    true,
+			Description: "Repositories to watch, JSON array or comma separated: github:owner/name, gitlab:group/project, owner/name or a repository URL",
+		},
+		{
+			Name:        "watch",
+			Required:    false,
+			Default:     kindRelease,
+			Description: "Comma separated kinds to watch: release, tag, issue",
+		},
+		{
+			Name:        "include_pattern",
+			Required:    false,
+			Description: "Only keep items whose title or tag matches this regular expression",
+		},
+		{
+			Name:        "exclude_pattern",
+			Required:    false,
+			Description: "Drop items whose title or tag matches this regular expression",
+		},
+		{
+			Name:        "labels",
+			Required:    false,
+			Description: "Comma separated issue labels, issues need at least one of them",
+		},
+		{
+			Name:        "issue_state",
+			Required:    false,
+			Default:     defaultIssueState,
+			Description: "Issue state to watch",
+			Options:     []string{"open", "closed", "all"},
+		},
+		{
+			Name:        "include_prereleases",
+			Required:    false,
+			Default:     "false",
+			Description: "Keep pre-releases, drafts are always skipped",
+			Options:     []string{"true", "false"},
+		},
+		{
+			Name:        "download_assets",
+			Required:    false,
+			Default:     "false",
+			Description: "Download release assets next to the release document",
+			Options:     []string{"true", "false"},
+		},
+		{
+			Name:        "asset_pattern",
+			Required:    false,
+			Description: "Only download assets whose name matches this regular expression",
+		},
+		{
+			Name:        "max_asset_size",
+			Required:    false,
+			Default:     strconv.Itoa(defaultMaxAssetSize),
+			Description: "Skip assets larger than this size in MiB",
+		},
+		{
+			Name:        "output_dir",
+			Required:    false,
+			Default:     defaultOutputDir,
+			Description: "Directory in the working path for the item documents and assets",
+		},
+		{
+			Name:        "max_items",
+			Required:    false,
+			Default:     strconv.Itoa(defaultMaxItems),
+			Description: "Maximum new items per repository and run, the oldest are archived first",
+		},
+		{
+			Name:        "github_token",
+			Required:    false,
+			Description: "GitHub token, only sent to the GitHub API",
+		},
+		{
+			Name:        "gitlab_token",
+			Required:    false,
+			Description: "GitLab token, only sent to the GitLab hosts of the watched repositories",
+		},
+		{
+			Name:        "github_api_url",
+			Required:    false,
+			Default:     defaultGitHubAPI,
+			Description: "GitHub API endpoint, for GitHub Enterprise",
+		},
+		{
+			Name:        "state_file",
+			Required:    false,
+			Default:     defaultStateFile,
+			Description: "File in the working path recording seen items when no store is provided",
+		},
+	},
+}
+
+type watchOption struct {
+	Kinds        []string
+	Include      *regexp.Regexp
+	Exclude      *regexp.Regexp
+	Labels       []string
+	IssueState   string
+	Prereleases  bool
+	Assets       bool
+	AssetPattern *regexp.Regexp
+	MaxAssetSize int64
+	OutputDir    string
+	MaxItems     int
+}
+
+type RepoWatchPlugin struct {
+	logger   *zap.SugaredLogger
+	fileRoot *utils.FileAccess
+	cli      *http.Client
+}
+
+func NewRepoWatchPlugin(ps types.PluginCall) types.Plugin {
+	return &RepoWatchPlugin{
+		logger:   logger.NewPluginLogger(pluginName, ps.JobID),
+		fileRoot: utils.NewFileAccess(ps.WorkingPath, ps.JobWorkingPath),
+		cli:      &http.Client{Timeout: time.Minute},
+	}
+}
+
+func (p *RepoWatchPlugin) Name() string {
+	return pluginName
+}
+
+func (p *RepoWatchPlugin) Type() types.PluginType {
+	return types.TypeSource
+}
+
+func (p *RepoWatchPlugin) Version() string {
+	return pluginVersion
+}
+
+func (p *RepoWatchPlugin) Run(ctx context.Context, request *api.Request) (*api.Response, error) {
+	repos, err := parseRepos(api.GetStringParameter("repos", request, ""))
+	if err != nil {
+		return api.NewFailedResponse(err.Error()), nil
+	}
+	opt, err := parseOption(request)
+	if err != nil {
+		return api.NewFailedResponse(err.Error()), nil
+	}
+
+	store := request.Store
+	if store == nil {
+		store = &fileStore{fileRoot: p.fileRoot, path: api.GetStringParameter("state_file", request, defaultStateFile)}
+	}
+	gitLabToken := api.GetStringParameter("gitlab_token", request, "")
+	gh := newGitHub(p.cli, api.GetStringParameter("github_api_url", request, defaultGitHubAPI), api.GetStringParameter("github_token", request, ""))
+	gl := newGitLab(p.cli, gitLabToken)
+
+	var (
+		items     = make([]map[string]any, 0)
+		repoStats = make([]map[string]any, 0, len(repos))
+		failed    int
+	)
+	for _, repo := range repos {
+		var prov provider = gh
+		if repo.Provider == providerGitLab {
+			prov = gl
+		}
+		stat := map[string]any{"repo": repo.String(), "url": repo.htmlURL("")}
+		repoStats = append(repoStats, stat)
+
+		candidates, err := p.collect(ctx, prov, repo, opt, store)
+		if err != nil {
+			p.logger.Warnw("watch repository failed", "repo", repo.String(), "err", err)
+			stat["error"] = err.Error()
+			continue
+		}
+
+		var archived int
+		for _, item := range candidates {
+			doc, err := p.archive(ctx, repo, &item, opt, gitLabToken)
+			if err != nil {
+				p.logger.Warnw("archive item failed, retry next run", "repo", repo.String(), "item", item.key(), "err", err)
+				failed++
+				continue
+			}
+			if err = store.Save(ctx, pluginName, "items", item.key(), item.PublishedAt); err != nil {
+				return nil, fmt.Errorf("save item record failed: %w", err)
+			}
+			result := utils.MarshalMap(item)
+			result["document"] = utils.MarshalMap(doc)
+			items = append(items, result)
+			archived++
+		}
+		stat["new"] = archived
+	}
+
+	p.logger.Infow("repositories watched", "repos", len(repos), "items", len(items), "failed", failed)
+	return api.NewResponseWithResult(map[string]any{
+		"items":  items,
+		"total":  len(items),
+		"failed": failed,
+		"repos":  repoStats,
+	}), nil
+}
+
+// collect lists the unseen items of a repository passing the filters, oldest first and capped at max_items.
+func (p *RepoWatchPlugin) collect(ctx context.Context, prov provider, repo Repo, opt watchOption, store api.PersistentStore) ([]Item, error) {
+	var all []Item
+	for _, kind := range opt.Kinds {
+		var (
+			items []Item
+			err   error
+		)
+		switch kind {
+		case kindRelease:
+			items, err = prov.releases(ctx, repo)
+		case kindTag:
+			items, err = prov.tags(ctx, repo)
+		case kindIssue:
+			items, err = prov.issues(ctx, repo, opt.IssueState)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("list %ss failed: %w", kind, err)
+		}
+		// the APIs list newest first
+		slices.Reverse(items)
+		all = append(all, items...)
+	}
+
+	candidates := make([]Item, 0, len(all))
+	for _, item := range all {
+		if !opt.match(item) {
+			continue
+		}
+		var seen time.Time
+		if err := store.Load(ctx, pluginName, "items", item.key(), &seen); err == nil {
+			continue
+		}
+		candidates = append(candidates, item)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].PublishedAt.Before(candidates[j].PublishedAt)
+	})
+	if len(candidates) > opt.MaxItems {
+		p.logger.Infow("too many new items, keep the oldest", "repo", repo.String(), "limit", opt.MaxItems, "candidates", len(candidates))
+		candidates = candidates[:opt.MaxItems]
+	}
+	return candidates, nil
+}
+
+func (o watchOption) match(item Item) bool {
+	if item.Kind == kindRelease && item.Prerelease && !o.Prereleases {
+		return false
+	}
+	if o.Include != nil && !o.Include.MatchString(item.Title) && !o.Include.MatchString(item.Tag) {
+		return false
+	}
+	if o.Exclude != nil && (o.Exclude.MatchString(item.Title) || (item.Tag != "" && o.Exclude.MatchString(item.Tag))) {
+		return false
+	}
+	if item.Kind == kindIssue && len(o.Labels) > 0 {
+		return slices.ContainsFunc(item.Labels, func(l string) bool {
+			return slices.ContainsFunc(o.Labels, func(want string) bool { return strings.EqualFold(l, want) })
+		})
+	}
+	return true
+}
+
+// archive downloads the wanted assets and writes the item document. Any failure leaves
+// the item unrecorded so the next run retries it.
+func (p *RepoWatchPlugin) archive(ctx context.Context, repo Repo, item *Item, opt watchOption, gitLabToken string) (types.Document, error) {
+	repoDir := path.Join(opt.OutputDir, utils.SanitizeFilename(strings.ReplaceAll(repo.Path, "/", "_")))
+	baseName := item.Kind + "_" + utils.SanitizeFilename(item.Tag)
+	if item.Kind == kindIssue {
+		baseName = item.Kind + "_" + strconv.Itoa(item.Number)
+	}
+	if err := p.fileRoot.MkdirAll(repoDir, 0755); err != nil {
+		return types.Document{}, err
+	}
+
+	if opt.Assets && item.Kind == kindRelease {
+		for i := range item.Assets {
+			asset := &item.Assets[i]
+			if opt.AssetPattern != nil && !opt.AssetPattern.MatchString(asset.Name) {
+				continue
+			}
+			if asset.Size > opt.MaxAssetSize {
+				p.logger.Infow("asset too large, skip", "asset", asset.URL, "size", asset.Size)
+				continue
+			}
+			filePath, err := p.downloadAsset(ctx, repo, *asset, path.Join(repoDir, baseName), opt.MaxAssetSize, gitLabToken)
+			if err != nil {
+				return types.Document{}, fmt.Errorf("download asset %s failed: %w", asset.Name, err)
+			}
+			asset.FilePath = filePath
+		}
+	}
+
+	doc := item.document(repo, repoDir)
+	item.FilePath = path.Join(repoDir, baseName+".md")
+	if err := p.fileRoot.Write(item.FilePath, []byte(doc.Content), 0644); err != nil {
+		return types.Document{}, err
+	}
+	return doc, nil
+}
+
+// downloadAsset saves the asset under dir. Assets over maxSize are skipped and return an empty path.
+func (p *RepoWatchPlugin) downloadAsset(ctx context.Context, repo Repo, asset Asset, dir string, maxSize int64, gitLabToken string) (string, error) {
+	name := path.Base(strings.ReplaceAll(asset.Name, "\\", "/"))
+	if name == "." || name == "/" || name == ".." {
+		return "", fmt.Errorf("invalid asset name %q", asset.Name)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, asset.URL, nil)
+	if err != nil {
+		return "", err
+	}
+	if repo.Provider == providerGitLab && gitLabToken != "" && sameHost(asset.URL, repo.WebURL) {
+		req.Header.Set("PRIVATE-TOKEN", gitLabToken)
+	}
+	resp, err := p.cli.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("status code is %d", resp.StatusCode)
+	}
+	if resp.ContentLength > maxSize {
+		p.logger.Infow("asset too large, skip", "asset", asset.URL, "size", resp.ContentLength)
+		return "", nil
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		return "", err
+	}
+	if int64(len(data)) > maxSize {
+		p.logger.Infow("asset too large, skip", "asset", asset.URL)
+		return "", nil
+	}
+
+	if err = p.fileRoot.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	filePath := path.Join(dir, name)
+	if err = p.fileRoot.Write(filePath, data, 0644); err != nil {
+		return "", err
+	}
+	return filePath, nil
+}
+
+// document renders the item as Markdown with its changelog or description as the body.
+func (i Item) document(repo Repo, repoDir string) types.Document {
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "# %s\n\n", i.Title)
+	fmt.Fprintf(buf, "- Repository: [%s](%s)\n", repo.Path, repo.htmlURL(""))
+	fmt.Fprintf(buf, "- Kind: %s\n", i.Kind)
+	if i.Tag != "" {
+		fmt.Fprintf(buf, "- Tag: %s\n", i.Tag)
+	}
+	if i.Number > 0 {
+		fmt.Fprintf(buf, "- Number: #%d\n", i.Number)
+	}
+	if i.State != "" {
+		fmt.Fprintf(buf, "- State: %s\n", i.State)
+	}
+	if i.Author != "" {
+		fmt.Fprintf(buf, "- Author: %s\n", i.Author)
+	}
+	if len(i.Labels) > 0 {
+		fmt.Fprintf(buf, "- Labels: %s\n", strings.Join(i.Labels, ", "))
+	}
+	if i.Prerelease {
+		buf.WriteString("- Pre-release: yes\n")
+	}
+	if !i.PublishedAt.IsZero() {
+		fmt.Fprintf(buf, "- Published: %s\n", i.PublishedAt.UTC().Format(time.RFC3339))
+	}
+	if i.URL != "" {
+		fmt.Fprintf(buf, "- Link: %s\n", i.URL)
+	}
+	if len(i.Assets) > 0 {
+		buf.WriteString("\n## Assets\n\n")
+		for _, a := range i.Assets {
+			target := a.URL
+			if a.FilePath != "" {
+				target = strings.TrimPrefix(a.FilePath, repoDir+"/")
+			}
+			fmt.Fprintf(buf, "- [%s](%s)\n", a.Name, (&url.URL{Path: target}).String())
+		}
+	}
+	if body := strings.TrimSpace(i.Body); body != "" {
+		fmt.Fprintf(buf, "\n---\n\n%s\n", body)
+	}
+
+	keywords := append([]string{i.Kind}, i.Labels...)
+	props := types.Properties{
+		Title:    i.Title,
+		Author:   i.Author,
+		Source:   repo.String(),
+		URL:      i.URL,
+		SiteName: repo.Path,
+		SiteURL:  repo.htmlURL(""),
+		Keywords: keywords,
+	}
+	if !i.PublishedAt.IsZero() {
+		props.PublishAt = i.PublishedAt.Unix()
+		props.Year = strconv.Itoa(i.PublishedAt.Year())
+	}
+	return types.Document{Content: buf.String(), Properties: props}
+}
+
+func parseOption(request *api.Request) (watchOption, error) {
+	opt := watchOption{
+		IssueState: api.GetStringParameter("issue_state", request, defaultIssueState),
+		OutputDir:  strings.Trim(api.GetStringParameter("output_dir", request, defaultOutputDir), "/"),
+		MaxItems:   defaultMaxItems,
+	}
+	for _, kind := range strings.Split(api.GetStringParameter("watch", request, kindRelease), ",") {
+		kind = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(kind)), "s")
+		switch kind {
+		case "":
+			continue
+		case kindRelease, kindTag, kindIssue:
+			if !slices.Contains(opt.Kinds, kind) {
+				opt.Kinds = append(opt.Kinds, kind)
+			}
+		default:
+			return opt, fmt.Errorf("unknown watch kind: %s", kind)
+		}
+	}
+	if len(opt.Kinds) == 0 {
+		return opt, fmt.Errorf("watch is empty")
+	}
+	if opt.IssueState != "open" && opt.IssueState != "closed" && opt.IssueState != "all" {
+		return opt, fmt.Errorf("unknown issue_state: %s", opt.IssueState)
+	}
+	if opt.OutputDir == "" {
+		opt.OutputDir = defaultOutputDir
+	}
+
+	var err error
+	for name, target := range map[string]**regexp.Regexp{
+		"include_pattern": &opt.Include,
+		"exclude_pattern": &opt.Exclude,
+		"asset_pattern":   &opt.AssetPattern,
+	} {
+		if raw := api.GetStringParameter(name, request, ""); raw != "" {
+			if *target, err = regexp.Compile(raw); err != nil {
+				return opt, fmt.Errorf("parse %s failed: %s", name, err)
+			}
+		}
+	}
+	for _, l := range strings.Split(api.GetStringParameter("labels", request, ""), ",") {
+		if l = strings.TrimSpace(l); l != "" {
+			opt.Labels = append(opt.Labels, l)
+		}
+	}
+	if opt.Prereleases, err = parseBool(request, "include_prereleases"); err != nil {
+		return opt, err
+	}
+	if opt.Assets, err = parseBool(request, "download_assets"); err != nil {
+		return opt, err
+	}
+
+	maxSize := defaultMaxAssetSize
+	if raw := api.GetStringParameter("max_asset_size", request, ""); raw != "" {
+		if maxSize, err = strconv.Atoi(raw); err != nil || maxSize <= 0 {
+			return opt, fmt.Errorf("parse max_asset_size [%s] failed: expect positive integer", raw)
+		}
+	}
+	opt.MaxAssetSize = int64(maxSize) << 20
+	if raw := api.GetStringParameter("max_items", request, ""); raw != "" {
+		if opt.MaxItems, err = strconv.Atoi(raw); err != nil || opt.MaxItems <= 0 {
+			return opt, fmt.Errorf("parse max_items [%s] failed: expect positive integer", raw)
+		}
+	}
+	return opt, nil
+}
+
+func parseBool(request *api.Request, name string) (bool, error) {
+	raw := api.GetStringParameter(name, request, "false")
+	v, err := strconv.ParseBool(raw)
+	if err != nil {
+		return false, fmt.Errorf("parse %s [%s] failed: expect true or false", name, raw)
+	}
+	return v, nil
+}
+
+func sameHost(rawURL, base string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	b, err := url.Parse(base)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, b.Host)
+}
@@ -0,0 +1,247 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package repowatch
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/logger"
+	"github.com/basenana/plugin/types"
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) {
+	logger.SetLogger(zap.NewNop().Sugar())
+	os.Exit(m.Run())
+}
+
+type fakeForge struct {
+	*httptest.Server
+	mu          sync.Mutex
+	auth        map[string]string
+	assetStatus int
+}
+
+// newFakeForge serves the GitHub API under /gh and a GitLab instance for group/project.
+func newFakeForge(t *testing.T) *fakeForge {
+	f := &fakeForge{auth: make(map[string]string), assetStatus: http.StatusOK}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		f.auth[r.URL.Path] = r.Header.Get("Authorization") + r.Header.Get("PRIVATE-TOKEN")
+		assetStatus := f.assetStatus
+		f.mu.Unlock()
+
+		switch r.URL.Path {
+		case "/gh/repos/basenana/plugin/releases":
+			writeJSON(w, []map[string]any{
+				{"tag_name": "v1.2.0-rc1", "name": "", "prerelease": true, "published_at": "2024-03-01T00:00:00Z", "html_url": "https://github.com/basenana/plugin/releases/tag/v1.2.0-rc1"},
+				{"tag_name": "v1.1.0", "name": "Release 1.1", "body": "## Changes\n\n- faster sync", "published_at": "2024-02-01T00:00:00Z",
+					"html_url": "https://github.com/basenana/plugin/releases/tag/v1.1.0", "author": map[string]any{"login": "hyponet"},
+					"assets": []map[string]any{
+						{"name": "plugin_linux.tar.gz", "size": 4, "browser_download_url": f.URL + "/assets/plugin_linux.tar.gz"},
+						{"name": "checksums.txt", "size": 4, "browser_download_url": f.URL + "/assets/checksums.txt"},
+					}},
+				{"tag_name": "v1.2.0", "draft": true},
+				{"tag_name": "v1.0.0", "name": "v1.0.0", "published_at": "2024-01-01T00:00:00Z"},
+			})
+		case "/gh/repos/basenana/missing/releases":
+			http.Error(w, `{"message":"Not Found"}`, http.StatusNotFound)
+		case "/api/v4/projects/group%2Fproject/issues", "/api/v4/projects/group/project/issues":
+			if r.URL.Query().Get("state") != "opened" {
+				t.Errorf("unexpected issue state %s", r.URL.Query().Get("state"))
+			}
+			writeJSON(w, []map[string]any{
+				{"iid": 7, "title": "Crash on start", "description": "stack trace", "state": "opened", "labels": []string{"Bug"},
+					"web_url": f.URL + "/group/project/-/issues/7", "created_at": "2024-04-02T00:00:00Z", "author": map[string]any{"username": "alice"}},
+				{"iid": 6, "title": "Add dark mode", "state": "opened", "labels": []string{"feature"}, "created_at": "2024-04-01T00:00:00Z"},
+			})
+		case "/assets/plugin_linux.tar.gz", "/assets/checksums.txt":
+			w.WriteHeader(assetStatus)
+			_, _ = w.Write([]byte("data"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func runWatch(t *testing.T, workdir string, params map[string]any) *api.Response {
+	t.Helper()
+	p := NewRepoWatchPlugin(types.PluginCall{JobID: "job-1", WorkingPath: workdir}).(*RepoWatchPlugin)
+	resp, err := p.Run(context.Background(), &api.Request{Parameter: params})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestRepoWatchPlugin_Run_GitHubReleases(t *testing.T) {
+	forge := newFakeForge(t)
+	workdir := t.TempDir()
+	params := map[string]any{
+		"repos":           "basenana/plugin",
+		"github_api_url":  forge.URL + "/gh",
+		"github_token":    "gh-secret",
+		"download_assets": "true",
+		"asset_pattern":   `linux`,
+	}
+
+	resp := runWatch(t, workdir, params)
+	if !resp.IsSucceed {
+		t.Fatalf("Run not succeed: %s", resp.Message)
+	}
+	items := resp.Results["items"].([]map[string]any)
+	if resp.Results["total"] != 2 || len(items) != 2 {
+		t.Fatalf("expected draft and pre-release to be skipped, got %v", resp.Results)
+	}
+	if items[0]["tag"] != "v1.0.0" || items[1]["tag"] != "v1.1.0" {
+		t.Errorf("expected oldest first, got %v, %v", items[0]["tag"], items[1]["tag"])
+	}
+
+	release := items[1]
+	if release["file_path"] != "repowatch/basenana_plugin/release_v1_1_0.md" || release["author"] != "hyponet" {
+		t.Errorf("unexpected release %v", release)
+	}
+	assets := release["assets"].([]any)
+	linux, checksums := assets[0].(map[string]any), assets[1].(map[string]any)
+	if linux["file_path"] != "repowatch/basenana_plugin/release_v1_1_0/plugin_linux.tar.gz" || checksums["file_path"] != nil {
+		t.Errorf("expected only the matching asset to be downloaded, got %v", assets)
+	}
+	if data, err := os.ReadFile(filepath.Join(workdir, "repowatch/basenana_plugin/release_v1_1_0/plugin_linux.tar.gz")); err != nil || string(data) != "data" {
+		t.Errorf("unexpected asset content %q, %v", data, err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(workdir, "repowatch/basenana_plugin/release_v1_1_0.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"# Release 1.1", "- Tag: v1.1.0", "[plugin_linux.tar.gz](release_v1_1_0/plugin_linux.tar.gz)", "## Changes\n\n- faster sync"} {
+		if !strings.Contains(string(content), want) {
+			t.Errorf("document missing %q:\n%s", want, content)
+		}
+	}
+	doc := release["document"].(map[string]any)
+	props := doc["properties"].(map[string]any)
+	if props["source"] != "github:basenana/plugin" || props["year"] != "2024" || props["url"] != "https://github.com/basenana/plugin/releases/tag/v1.1.0" {
+		t.Errorf("unexpected properties %v", props)
+	}
+
+	if forge.auth["/gh/repos/basenana/plugin/releases"] != "Bearer gh-secret" {
+		t.Errorf("expected token on api request, got %q", forge.auth["/gh/repos/basenana/plugin/releases"])
+	}
+	if forge.auth["/assets/plugin_linux.tar.gz"] != "" {
+		t.Errorf("token leaked to asset download")
+	}
+
+	resp = runWatch(t, workdir, params)
+	if resp.Results["total"] != 0 {
+		t.Errorf("expected seen releases to be skipped, got %v", resp.Results["items"])
+	}
+
+	params["include_prereleases"] = "true"
+	resp = runWatch(t, workdir, params)
+	if items = resp.Results["items"].([]map[string]any); len(items) != 1 || items[0]["title"] != "v1.2.0-rc1" {
+		t.Errorf("expected the pre-release, got %v", items)
+	}
+}
+
+func TestRepoWatchPlugin_Run_AssetFailureRetried(t *testing.T) {
+	forge := newFakeForge(t)
+	forge.assetStatus = http.StatusBadGateway
+	workdir := t.TempDir()
+	params := map[string]any{
+		"repos":           "basenana/plugin",
+		"github_api_url":  forge.URL + "/gh",
+		"download_assets": "true",
+	}
+
+	resp := runWatch(t, workdir, params)
+	if resp.Results["total"] != 1 || resp.Results["failed"] != 1 {
+		t.Fatalf("expected the release with assets to fail, got %v", resp.Results)
+	}
+
+	forge.mu.Lock()
+	forge.assetStatus = http.StatusOK
+	forge.mu.Unlock()
+	resp = runWatch(t, workdir, params)
+	items := resp.Results["items"].([]map[string]any)
+	if len(items) != 1 || items[0]["tag"] != "v1.1.0" || resp.Results["failed"] != 0 {
+		t.Errorf("expected the failed release to be retried, got %v", resp.Results)
+	}
+}
+
+func TestRepoWatchPlugin_Run_GitLabIssues(t *testing.T) {
+	forge := newFakeForge(t)
+	workdir := t.TempDir()
+
+	resp := runWatch(t, workdir, map[string]any{
+		"repos":          forge.URL + "/group/project, basenana/missing",
+		"github_api_url": forge.URL + "/gh",
+		"gitlab_token":   "gl-secret",
+		"watch":          "issues",
+		"labels":         "bug",
+	})
+	if !resp.IsSucceed {
+		t.Fatalf("Run not succeed: %s", resp.Message)
+	}
+	items := resp.Results["items"].([]map[string]any)
+	if len(items) != 1 || items[0]["number"] != float64(7) || items[0]["file_path"] != "repowatch/group_project/issue_7.md" {
+		t.Fatalf("expected the bug issue only, got %v", items)
+	}
+	doc := items[0]["document"].(map[string]any)
+	if keywords := doc["properties"].(map[string]any)["keywords"].([]any); len(keywords) != 2 || keywords[1] != "Bug" {
+		t.Errorf("unexpected keywords %v", keywords)
+	}
+	if forge.auth["/api/v4/projects/group/project/issues"] != "gl-secret" {
+		t.Errorf("expected gitlab token on api request, got %v", forge.auth)
+	}
+
+	repos := resp.Results["repos"].([]map[string]any)
+	if repos[0]["new"] != 1 || repos[1]["error"] == nil {
+		t.Errorf("expected per repository results, got %v", repos)
+	}
+}
+
+func TestRepoWatchPlugin_Run_InvalidParameters(t *testing.T) {
+	for _, params := range []map[string]any{
+		{},
+		{"repos": "basenana/plugin", "watch": "commits"},
+		{"repos": "basenana/plugin", "issue_state": "opened"},
+		{"repos": "basenana/plugin", "include_pattern": "("},
+		{"repos": "basenana/plugin", "max_items": "0"},
+		{"repos": "basenana/plugin", "download_assets": "yes please"},
+	} {
+		if resp := runWatch(t, t.TempDir(), params); resp.IsSucceed {
+			t.Errorf("expected %v to fail", params)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package repowatch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/utils"
+)
+
+const defaultStateFile = ".repowatch_state.json"
+
+var stateFileMux sync.Mutex
+
+// fileStore keeps seen items in a JSON file under the working path.
+// It is used when the caller does not provide a PersistentStore.
+type fileStore struct {
+	fileRoot *utils.FileAccess
+	path     string
+}
+
+var _ api.PersistentStore = &fileStore{}
+
+func (f *fileStore) Load(ctx context.Context, source, group, key string, data any) error {
+	stateFileMux.Lock()
+	defer stateFileMux.Unlock()
+
+	state, err := f.read()
+	if err != nil {
+		return err
+	}
+	raw, ok := state[source+"/"+group+"/"+key]
+	if !ok {
+		return fmt.Errorf("no record")
+	}
+	return json.Unmarshal(raw, data)
+}
+
+func (f *fileStore) Save(ctx context.Context, source, group, key string, data any) error {
+	stateFileMux.Lock()
+	defer stateFileMux.Unlock()
+
+	state, err := f.read()
+	if err != nil {
+		return err
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	state[source+"/"+group+"/"+key] = raw
+
+	content, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	tmpPath := f.path + ".tmp"
+	if err = f.fileRoot.Write(tmpPath, content, 0644); err != nil {
+		return fmt.Errorf("write repowatch state failed: %w", err)
+	}
+	return f.fileRoot.Rename(tmpPath, f.path)
+}
+
+func (f *fileStore) read() (map[string]json.RawMessage, error) {
+	state := make(map[string]json.RawMessage)
+	content, err := f.fileRoot.Read(f.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return state, nil
+		}
+		return nil, fmt.Errorf("read repowatch state failed: %w", err)
+	}
+	if len(content) == 0 {
+		return state, nil
+	}
+	if err = json.Unmarshal(content, &state); err != nil {
+		return nil, fmt.Errorf("parse repowatch state failed: %w", err)
+	}
+	return state, nil
+}
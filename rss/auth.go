@@ -0,0 +1,121 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package rss
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/web"
+)
+
+const (
+	rssParameterAuthType    = "auth_type"
+	rssParameterUsername    = "username"
+	rssParameterPassword    = "password"
+	rssParameterBearerToken = "bearer_token"
+	rssParameterCookie      = "cookie"
+
+	authTypeNone   = "none"
+	authTypeBasic  = "basic"
+	authTypeBearer = "bearer"
+)
+
+// feedAuth is the credential of one feed. It is sent with the feed request and with
+// article requests to the feed host or its subdomains, never to third-party sites.
+type feedAuth struct {
+	host       string
+	credential web.Credential
+}
+
+// parseFeedAuth returns nil when no authentication is configured. Without auth_type,
+// basic is used when username is set and bearer when bearer_token is set.
+func parseFeedAuth(request *api.Request, feedURL string) (*feedAuth, error) {
+	var (
+		authType = strings.ToLower(api.GetStringParameter(rssParameterAuthType, request, ""))
+		username = api.GetStringParameter(rssParameterUsername, request, "")
+		password = api.GetStringParameter(rssParameterPassword, request, "")
+		token    = api.GetStringParameter(rssParameterBearerToken, request, "")
+		cookie   = api.GetStringParameter(rssParameterCookie, request, "")
+	)
+	if authType == "" {
+		switch {
+		case username != "":
+			authType = authTypeBasic
+		case token != "":
+			authType = authTypeBearer
+		default:
+			authType = authTypeNone
+		}
+	}
+
+	var cred web.Credential
+	switch authType {
+	case authTypeNone:
+	case authTypeBasic:
+		if username == "" {
+			return nil, fmt.Errorf("username is required for basic auth")
+		}
+		cred.BasicAuth = &web.BasicAuth{Username: username, Password: password}
+	case authTypeBearer:
+		if token == "" {
+			return nil, fmt.Errorf("bearer_token is required for bearer auth")
+		}
+		cred.Headers = map[string]string{"Authorization": "Bearer " + token}
+	default:
+		return nil, fmt.Errorf("unknown auth_type: %s", authType)
+	}
+	cred.Cookie = cookie
+
+	if cred.BasicAuth == nil && cred.Headers == nil && cred.Cookie == "" {
+		return nil, nil
+	}
+	u, err := url.Parse(feedURL)
+	if err != nil || u.Hostname() == "" {
+		return nil, fmt.Errorf("feed url %s has no host for authentication", feedURL)
+	}
+	return &feedAuth{host: strings.ToLower(u.Hostname()), credential: cred}, nil
+}
+
+func (a *feedAuth) apply(req *http.Request) {
+	if a == nil {
+		return
+	}
+	for k, v := range a.credential.Headers {
+		req.Header.Set(k, v)
+	}
+	if a.credential.Cookie != "" {
+		req.Header.Set("Cookie", a.credential.Cookie)
+	}
+	if a.credential.BasicAuth != nil {
+		req.SetBasicAuth(a.credential.BasicAuth.Username, a.credential.BasicAuth.Password)
+	}
+}
+
+// option returns the credential for an article link, nil when the link is off the feed host.
+func (a *feedAuth) option(link string) web.Option {
+	if a == nil {
+		return nil
+	}
+	if _, _, ok := (web.CredentialStore{a.host: a.credential}).Match(link); !ok {
+		return nil
+	}
+	return a.credential.Option()
+}
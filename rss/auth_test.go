@@ -0,0 +1,194 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package rss
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/web"
+	"github.com/hyponet/webpage-packer/packer"
+)
+
+func TestParseFeedAuth(t *testing.T) {
+	auth, err := parseFeedAuth(&api.Request{}, "https://example.com/feed")
+	if err != nil || auth != nil {
+		t.Fatalf("expected no auth, got %+v, %v", auth, err)
+	}
+
+	auth, err = parseFeedAuth(&api.Request{Parameter: map[string]any{"username": "u", "password": "p"}}, "https://Example.com/feed")
+	if err != nil || auth.host != "example.com" || auth.credential.BasicAuth == nil || auth.credential.BasicAuth.Username != "u" {
+		t.Errorf("expected inferred basic auth, got %+v, %v", auth, err)
+	}
+	auth, err = parseFeedAuth(&api.Request{Parameter: map[string]any{"bearer_token": "t", "cookie": "session=1"}}, "https://example.com/feed")
+	if err != nil || auth.credential.Headers["Authorization"] != "Bearer t" || auth.credential.Cookie != "session=1" {
+		t.Errorf("expected inferred bearer auth with cookie, got %+v, %v", auth, err)
+	}
+	auth, err = parseFeedAuth(&api.Request{Parameter: map[string]any{"auth_type": "none", "username": "u", "cookie": "a=b"}}, "https://example.com/feed")
+	if err != nil || auth.credential.BasicAuth != nil || auth.credential.Cookie != "a=b" {
+		t.Errorf("auth_type none should only send the cookie, got %+v, %v", auth, err)
+	}
+
+	for _, params := range []map[string]any{
+		{"auth_type": "basic"},
+		{"auth_type": "bearer", "username": "u"},
+		{"auth_type": "digest", "username": "u"},
+	} {
+		if _, err = parseFeedAuth(&api.Request{Parameter: params}, "https://example.com/feed"); err == nil {
+			t.Errorf("parseFeedAuth(%v) expected error", params)
+		}
+	}
+}
+
+func TestFeedAuth_Option(t *testing.T) {
+	auth, err := parseFeedAuth(&api.Request{Parameter: map[string]any{"bearer_token": "t"}}, "https://example.com/feed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for link, want := range map[string]bool{
+		"https://example.com/post":          true,
+		"https://blog.example.com/post":     true,
+		"https://notexample.com/post":       false,
+		"https://cdn.other.org/example.com": false,
+	} {
+		if got := auth.option(link) != nil; got != want {
+			t.Errorf("option(%s) applied = %v, want %v", link, got, want)
+		}
+	}
+	var none *feedAuth
+	if none.option("https://example.com/post") != nil {
+		t.Error("nil auth should not add options")
+	}
+}
+
+func TestRssPlugin_Run_Auth(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != "reader" || pass != "secret" || r.Header.Get("Cookie") != "session=abc" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/rss+xml")
+		_, _ = fmt.Fprintf(w, `<?xml version="1.0"?><rss version="2.0"><channel><title>Private</title><link>%[1]s</link>`+
+			`<item><title>Own</title><link>%[1]s/own</link></item>`+
+			`<item><title>External</title><link>https://other.example.org/post</link></item>`+
+			`</channel></rss>`, server.URL)
+	}))
+	defer server.Close()
+
+	authorized := recordArticleCredentials(t)
+
+	p := newRssPluginWithWorkdir(t.TempDir(), map[string]string{"file_type": "rawhtml"})
+	resp, err := p.Run(context.Background(), &api.Request{
+		Parameter: map[string]any{"feed": server.URL, "username": "reader", "password": "secret", "cookie": "session=abc"},
+		Store:     newMemStore(),
+	})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if !resp.IsSucceed {
+		t.Fatalf("Run not succeed: %s", resp.Message)
+	}
+	if got := authorized.get(server.URL + "/own"); !strings.HasPrefix(got, "Basic ") || !strings.HasSuffix(got, "|session=abc") {
+		t.Errorf("expected credentials for the feed host article, got %q", got)
+	}
+	if got := authorized.get("https://other.example.org/post"); got != "|" {
+		t.Errorf("credentials must not be sent to other hosts, got %q", got)
+	}
+
+	resp, err = p.Run(context.Background(), &api.Request{
+		Parameter: map[string]any{"feed": server.URL},
+		Store:     newMemStore(),
+	})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if resp.IsSucceed || !strings.Contains(resp.Message, "401") {
+		t.Errorf("expected unauthorized feed fetch to fail, got %+v", resp)
+	}
+}
+
+func TestRssPlugin_Run_AuthOffHostArticles(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		_, _ = fmt.Fprintf(w, `<?xml version="1.0"?><rss version="2.0"><channel><title>Private</title><link>%[1]s</link>`, server.URL)
+		for i := 0; i < 8; i++ {
+			_, _ = fmt.Fprintf(w, `<item><title>Own %[2]d</title><link>%[1]s/own/%[2]d</link></item>`+
+				`<item><title>External %[2]d</title><link>https://other.example.org/post/%[2]d</link></item>`, server.URL, i)
+		}
+		_, _ = fmt.Fprint(w, `</channel></rss>`)
+	}))
+	defer server.Close()
+
+	authorized := recordArticleCredentials(t)
+	p := newRssPluginWithWorkdir(t.TempDir(), map[string]string{"file_type": "rawhtml"})
+	resp, err := p.Run(context.Background(), &api.Request{
+		Parameter: map[string]any{"feed": server.URL, "bearer_token": "t1", "concurrency": 4},
+		Store:     newMemStore(),
+	})
+	if err != nil || !resp.IsSucceed {
+		t.Fatalf("Run failed: %v %+v", err, resp)
+	}
+	for i := 0; i < 8; i++ {
+		if got := authorized.get(fmt.Sprintf("%s/own/%d", server.URL, i)); got != "Bearer t1|" {
+			t.Errorf("expected the credential for own article %d, got %q", i, got)
+		}
+		if got := authorized.get(fmt.Sprintf("https://other.example.org/post/%d", i)); got != "|" {
+			t.Errorf("credentials must not be sent to other hosts, article %d got %q", i, got)
+		}
+	}
+}
+
+// articleCredentials records the Authorization and Cookie headers each article is packed with.
+type articleCredentials struct {
+	mu   sync.Mutex
+	seen map[string]string
+}
+
+func (a *articleCredentials) get(link string) string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.seen[link]
+}
+
+func recordArticleCredentials(t *testing.T) *articleCredentials {
+	authorized := &articleCredentials{seen: make(map[string]string)}
+	origin := packFromURL
+	packFromURL = func(ctx context.Context, filename, urlInfo, tgtFileType, outputDir string, clutterFree bool, options ...web.Option) (string, error) {
+		opt := &packer.Option{}
+		for _, o := range options {
+			o(opt)
+		}
+		authorized.mu.Lock()
+		authorized.seen[urlInfo] = opt.Headers["Authorization"] + "|" + opt.Headers["Cookie"]
+		authorized.mu.Unlock()
+		filePath := path.Join(outputDir, filename+"."+tgtFileType)
+		return filePath, os.WriteFile(filePath, []byte("<html></html>"), 0644)
+	}
+	t.Cleanup(func() { packFromURL = origin })
+	return authorized
+}
@@ -0,0 +1,98 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package rss
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/basenana/plugin/api"
+	"github.com/mmcdole/gofeed"
+)
+
+const (
+	rssParameterMaxItems = "max_items"
+	rssParameterSince    = "since"
+
+	feedCursorGroup = "cursors"
+)
+
+// feedCursor is the publish time of the newest archived item, later runs skip older items.
+type feedCursor struct {
+	Since string `json:"since"`
+}
+
+func parseMaxItems(request *api.Request) (int, error) {
+	raw := api.GetStringParameter(rssParameterMaxItems, request, "")
+	if raw == "" {
+		return rssPostMaxCollect, nil
+	}
+	maxItems, err := strconv.Atoi(raw)
+	if err != nil || maxItems <= 0 {
+		return 0, fmt.Errorf("parse max_items [%s] failed: expect positive integer", raw)
+	}
+	return maxItems, nil
+}
+
+func parseSince(request *api.Request) (time.Time, error) {
+	since := api.GetStringParameter(rssParameterSince, request, "")
+	if since == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, since)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parse since [%s] failed: expect RFC3339 time", since)
+	}
+	return t, nil
+}
+
+func (s *rssSource) loadCursor(ctx context.Context) time.Time {
+	var cursor feedCursor
+	if err := s.Store.Load(ctx, RssSourcePluginName, feedCursorGroup, s.FeedUrl, &cursor); err != nil {
+		return time.Time{}
+	}
+	t, err := time.Parse(time.RFC3339, cursor.Since)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+func (s *rssSource) saveCursor(ctx context.Context, since time.Time) error {
+	return s.Store.Save(ctx, RssSourcePluginName, feedCursorGroup, s.FeedUrl, &feedCursor{Since: since.Format(time.RFC3339)})
+}
+
+func itemPublished(item *gofeed.Item) *time.Time {
+	if item.PublishedParsed != nil {
+		return item.PublishedParsed
+	}
+	return item.UpdatedParsed
+}
+
+// oldestFirst sorts items by publish time so a capped run never skips past older items; undated items go last.
+func oldestFirst(items []*gofeed.Item) {
+	sort.SliceStable(items, func(i, j int) bool {
+		pi, pj := itemPublished(items[i]), itemPublished(items[j])
+		if pi == nil || pj == nil {
+			return pi != nil
+		}
+		return pi.Before(*pj)
+	})
+}
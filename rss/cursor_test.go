@@ -0,0 +1,135 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package rss
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/basenana/plugin/api"
+	"github.com/mmcdole/gofeed"
+)
+
+const testCursorFeed = `<?xml version="1.0"?>
+<rss version="2.0"><channel><title>T</title><link>https://example.com/</link>
+<item><title>Post 3</title><link>https://example.com/3</link><pubDate>Wed, 03 Jan 2024 00:00:00 GMT</pubDate></item>
+<item><title>Post 2</title><link>https://example.com/2</link><pubDate>Tue, 02 Jan 2024 00:00:00 GMT</pubDate></item>
+<item><title>Post 1</title><link>https://example.com/1</link><pubDate>Mon, 01 Jan 2024 00:00:00 GMT</pubDate></item>
+</channel></rss>`
+
+func TestParseMaxItems(t *testing.T) {
+	if n, err := parseMaxItems(&api.Request{}); err != nil || n != rssPostMaxCollect {
+		t.Errorf("expected default %d, got %d (%v)", rssPostMaxCollect, n, err)
+	}
+	if n, err := parseMaxItems(&api.Request{Parameter: map[string]any{"max_items": 5}}); err != nil || n != 5 {
+		t.Errorf("expected 5, got %d (%v)", n, err)
+	}
+	for _, v := range []any{"0", "-1", "many"} {
+		if _, err := parseMaxItems(&api.Request{Parameter: map[string]any{"max_items": v}}); err == nil {
+			t.Errorf("expected error for %v", v)
+		}
+	}
+}
+
+func TestParseSince(t *testing.T) {
+	since, err := parseSince(&api.Request{Parameter: map[string]any{"since": "2024-01-02T00:00:00Z"}})
+	if err != nil || !since.Equal(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected since %v (%v)", since, err)
+	}
+	if _, err = parseSince(&api.Request{Parameter: map[string]any{"since": "yesterday"}}); err == nil {
+		t.Errorf("expected error for invalid since")
+	}
+}
+
+func TestOldestFirst(t *testing.T) {
+	t1 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	t2 := t1.Add(time.Hour)
+	items := []*gofeed.Item{{Title: "undated"}, {Title: "b", PublishedParsed: &t2}, {Title: "a", UpdatedParsed: &t1}}
+	oldestFirst(items)
+	if items[0].Title != "a" || items[1].Title != "b" || items[2].Title != "undated" {
+		t.Errorf("unexpected order %s, %s, %s", items[0].Title, items[1].Title, items[2].Title)
+	}
+}
+
+func TestRssPlugin_MaxItemsAndCursor(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		_, _ = w.Write([]byte(testCursorFeed))
+	}))
+	defer server.Close()
+
+	p := newRssPluginWithWorkdir(t.TempDir(), map[string]string{"file_type": "url"})
+	store := newMemStore()
+	run := func() *api.Response {
+		resp, err := p.Run(context.Background(), &api.Request{
+			Parameter: map[string]any{"feed": server.URL, "max_items": 2},
+			Store:     store,
+		})
+		if err != nil || !resp.IsSucceed {
+			t.Fatalf("Run failed: %v %v", err, resp)
+		}
+		return resp
+	}
+
+	resp := run()
+	articles := resp.Results["articles"].([]map[string]interface{})
+	if len(articles) != 2 || articles[0]["title"] != "Post 1" || articles[1]["title"] != "Post 2" {
+		t.Fatalf("expected the two oldest posts, got %v", articles)
+	}
+	if resp.Results["since"] != "2024-01-02T00:00:00Z" {
+		t.Errorf("unexpected cursor %v", resp.Results["since"])
+	}
+
+	// validators are not saved while items are left over, so the next run refetches the feed
+	resp = run()
+	articles = resp.Results["articles"].([]map[string]interface{})
+	if len(articles) != 1 || articles[0]["title"] != "Post 3" {
+		t.Fatalf("expected the remaining post, got %v", articles)
+	}
+	if resp.Results["since"] != "2024-01-03T00:00:00Z" {
+		t.Errorf("unexpected cursor %v", resp.Results["since"])
+	}
+}
+
+func TestRssPlugin_SinceParameter(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		_, _ = w.Write([]byte(testCursorFeed))
+	}))
+	defer server.Close()
+
+	p := newRssPluginWithWorkdir(t.TempDir(), map[string]string{"file_type": "url"})
+	resp, err := p.Run(context.Background(), &api.Request{
+		Parameter: map[string]any{"feed": server.URL, "since": "2024-01-02T00:00:00Z"},
+		Store:     newMemStore(),
+	})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	articles := resp.Results["articles"].([]map[string]interface{})
+	if len(articles) != 2 {
+		t.Errorf("expected posts published since the cursor, got %v", articles)
+	}
+}
@@ -0,0 +1,117 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package rss
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/utils"
+)
+
+const (
+	rssParameterContentDedup = "content_dedup"
+
+	contentGroup = "contents"
+	// shorter feed content is hashed together with the title, summaries alone collide too easily
+	contentHashMinLength = 200
+)
+
+// contentHashes holds the hashes produced in the current run, shared by every feed of the run.
+type contentHashes map[string]struct{}
+
+func parseContentDedup(request *api.Request) (bool, error) {
+	raw := api.GetStringParameter(rssParameterContentDedup, request, "true")
+	enabled, err := strconv.ParseBool(raw)
+	if err != nil {
+		return false, fmt.Errorf("parse content_dedup [%s] failed: expect true or false", raw)
+	}
+	return enabled, nil
+}
+
+// contentHash hashes the visible text of the article lowercased with only letters and digits kept,
+// so markup, whitespace and punctuation changes between cross-posts do not matter.
+// It returns an empty string for items without content.
+func contentHash(title, content string) string {
+	text := normalizeText(utils.ContentTrim("html", content))
+	if text == "" {
+		return ""
+	}
+	if utf8.RuneCountInString(text) < contentHashMinLength {
+		text = normalizeText(title) + "\n" + text
+	}
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
+
+func normalizeText(text string) string {
+	text = html.UnescapeString(text)
+	var buf strings.Builder
+	for _, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			buf.WriteRune(unicode.ToLower(r))
+		}
+	}
+	return buf.String()
+}
+
+// isDuplicate reports whether an article with the same content hash was produced in this run
+// or recorded by an earlier one. A hash is marked as produced only once its article is packed,
+// see markContent, so a failed pack does not hide a cross-post from a later feed.
+func (s *rssSource) isDuplicate(ctx context.Context, hash string) (bool, error) {
+	if hash == "" || s.Contents == nil {
+		return false, nil
+	}
+	if _, ok := s.Contents[hash]; ok {
+		return true, nil
+	}
+	var v = make(map[string]any)
+	err := s.Store.Load(ctx, RssSourcePluginName, contentGroup, hash, &v)
+	if err == nil {
+		return true, nil
+	}
+	if !strings.Contains(err.Error(), "no record") && !strings.Contains(err.Error(), "not found") {
+		return false, err
+	}
+	return false, nil
+}
+
+// markContent marks the hash of a packed article as produced in this run.
+func (s *rssSource) markContent(hash string) {
+	if s.Contents != nil {
+		s.Contents[hash] = struct{}{}
+	}
+}
+
+func (s *rssSource) recordContents(ctx context.Context, hashes map[string]string) error {
+	for hash, link := range hashes {
+		v := map[string]string{"link": link, "time": time.Now().Format(time.RFC3339)}
+		if err := s.Store.Save(ctx, RssSourcePluginName, contentGroup, hash, &v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
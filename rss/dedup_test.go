@@ -0,0 +1,135 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package rss
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/web"
+)
+
+func TestContentHash(t *testing.T) {
+	body := strings.Repeat("Go 1.23 ships range-over-func iterators. ", 10)
+	a := contentHash("Go 1.23 released", "<p>"+body+"</p>")
+	b := contentHash("[Blog] Go 1.23 released!", "<div>  "+strings.ToUpper(body)+"</div>")
+	if a == "" || a != b {
+		t.Errorf("expected cross-posted content to share a hash, got %q and %q", a, b)
+	}
+	if contentHash("Go 1.23 released", "<p>"+body+" More.</p>") == a {
+		t.Errorf("expected different content to change the hash")
+	}
+
+	if contentHash("Weekly update", "<p>Read more</p>") == contentHash("Another update", "<p>Read more</p>") {
+		t.Errorf("expected short content to be hashed with the title")
+	}
+	if contentHash("Title only", "  <p> </p>") != "" {
+		t.Errorf("expected no hash without content")
+	}
+}
+
+func TestRssPlugin_Run_ContentDedup(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		_, _ = fmt.Fprintf(w, `<?xml version="1.0"?>
+<rss version="2.0"><channel><title>%[1]s</title><link>https://example.com/</link>
+<item><title>Shared post</title><link>https://example.com%[1]s/shared</link><description>&lt;p&gt;Same text on every aggregator.&lt;/p&gt;</description></item>
+<item><title>Own post %[1]s</title><link>https://example.com%[1]s/own</link><description>Only on %[1]s</description></item>
+</channel></rss>`, r.URL.Path)
+	}))
+	defer server.Close()
+
+	p := newRssPluginWithWorkdir(t.TempDir(), map[string]string{"file_type": "html"})
+	store := newMemStore()
+	resp, err := p.Run(context.Background(), &api.Request{
+		Parameter: map[string]any{"feeds": server.URL + "/a," + server.URL + "/b"},
+		Store:     store,
+	})
+	if err != nil || !resp.IsSucceed {
+		t.Fatalf("Run failed: %v %v", err, resp)
+	}
+	if articles := resp.Results["articles"].([]map[string]interface{}); len(articles) != 3 {
+		t.Errorf("expected the shared post archived once, got %d articles", len(articles))
+	}
+	groups := resp.Results["feeds"].([]map[string]any)
+	if skipped := groups[1]["metrics"].(map[string]any)["skipped"]; skipped != float64(1) {
+		t.Errorf("expected the cross-post counted as skipped, got %v", skipped)
+	}
+
+	resp, err = p.Run(context.Background(), &api.Request{
+		Parameter: map[string]any{"feed": server.URL + "/c"},
+		Store:     store,
+	})
+	if err != nil || !resp.IsSucceed {
+		t.Fatalf("Run failed: %v %v", err, resp)
+	}
+	if articles := resp.Results["articles"].([]map[string]interface{}); len(articles) != 1 || articles[0]["title"] != "Own post /c" {
+		t.Errorf("expected the recorded content to be skipped in later runs, got %v", articles)
+	}
+
+	resp, err = p.Run(context.Background(), &api.Request{
+		Parameter: map[string]any{"feed": server.URL + "/d", "content_dedup": "false"},
+		Store:     store,
+	})
+	if err != nil || !resp.IsSucceed {
+		t.Fatalf("Run failed: %v %v", err, resp)
+	}
+	if articles := resp.Results["articles"].([]map[string]interface{}); len(articles) != 2 {
+		t.Errorf("expected content dedup to be disabled, got %v", articles)
+	}
+}
+
+func TestRssPlugin_Run_ContentDedupAfterFailedPack(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		_, _ = fmt.Fprintf(w, `<?xml version="1.0"?>
+<rss version="2.0"><channel><title>%[1]s</title><link>https://example.com/</link>
+<item><title>Shared post</title><link>https://example.com%[1]s/shared</link><description>&lt;p&gt;Same text on every aggregator.&lt;/p&gt;</description></item>
+</channel></rss>`, r.URL.Path)
+	}))
+	defer server.Close()
+
+	origin := packFromURL
+	packFromURL = func(ctx context.Context, filename, urlInfo, tgtFileType, outputDir string, clutterFree bool, options ...web.Option) (string, error) {
+		if strings.HasPrefix(urlInfo, "https://example.com/a/") {
+			return "", fmt.Errorf("pack to web failed: status code is 404")
+		}
+		filePath := path.Join(outputDir, filename+"."+tgtFileType)
+		return filePath, os.WriteFile(filePath, []byte("archive"), 0644)
+	}
+	t.Cleanup(func() { packFromURL = origin })
+
+	p := newRssPluginWithWorkdir(t.TempDir(), map[string]string{"file_type": "webarchive"})
+	resp, err := p.Run(context.Background(), &api.Request{
+		Parameter: map[string]any{"feeds": server.URL + "/a," + server.URL + "/b", "retry_backoff": "1ms"},
+		Store:     newMemStore(),
+	})
+	if err != nil || !resp.IsSucceed {
+		t.Fatalf("Run failed: %v %v", err, resp)
+	}
+	articles := resp.Results["articles"].([]map[string]interface{})
+	if len(articles) != 1 || articles[0]["url"] != "https://example.com/b/shared" {
+		t.Errorf("expected the cross-post of the second feed after the first failed, got %v", articles)
+	}
+}
@@ -0,0 +1,95 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package rss
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/basenana/plugin/types"
+	"github.com/basenana/plugin/utils"
+	"github.com/mmcdole/gofeed"
+)
+
+// articleDocument holds the properties fs/save needs for the article, so it can be saved without
+// running docloader on the archived file. content is the article HTML, the abstract is generated from it.
+func articleDocument(feed *gofeed.Feed, item *gofeed.Item, content string) types.Document {
+	props := types.Properties{
+		Title:       item.Title,
+		Author:      itemAuthor(item),
+		URL:         item.Link,
+		SiteName:    feed.Title,
+		SiteURL:     feed.Link,
+		HeaderImage: headerImage(item, content),
+		Keywords:    item.Categories,
+	}
+	if content != "" {
+		props.Abstract = utils.GenerateContentAbstract(content)
+	}
+	if published := itemPublished(item); published != nil {
+		props.PublishAt = published.Unix()
+	}
+	return types.Document{Properties: props}
+}
+
+// headerImage picks the feed image of the item, an image enclosure or media thumbnail,
+// or else the first image of the content, resolved against the item link.
+func headerImage(item *gofeed.Item, content string) string {
+	src := itemImage(item)
+	if src == "" && content != "" {
+		if doc, err := goquery.NewDocumentFromReader(strings.NewReader(content)); err == nil {
+			doc.Find("img").EachWithBreak(func(_ int, img *goquery.Selection) bool {
+				src = strings.TrimSpace(img.AttrOr("src", ""))
+				if strings.HasPrefix(src, "data:") {
+					src = ""
+				}
+				return src == ""
+			})
+		}
+	}
+	if src == "" {
+		return ""
+	}
+	ref, err := url.Parse(src)
+	if err != nil {
+		return ""
+	}
+	if base, err := url.Parse(item.Link); err == nil {
+		ref = base.ResolveReference(ref)
+	}
+	return ref.String()
+}
+
+func itemImage(item *gofeed.Item) string {
+	if item.Image != nil && item.Image.URL != "" {
+		return item.Image.URL
+	}
+	for _, enc := range item.Enclosures {
+		if enc != nil && enc.URL != "" && strings.HasPrefix(enc.Type, "image/") {
+			return enc.URL
+		}
+	}
+	for _, name := range []string{"thumbnail", "content"} {
+		for _, e := range item.Extensions["media"][name] {
+			if u := e.Attrs["url"]; u != "" && (name == "thumbnail" || e.Attrs["medium"] == "image") {
+				return u
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,156 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package rss
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/basenana/plugin/api"
+	"github.com/mmcdole/gofeed"
+	ext "github.com/mmcdole/gofeed/extensions"
+)
+
+func TestHeaderImage(t *testing.T) {
+	tests := []struct {
+		name    string
+		item    *gofeed.Item
+		content string
+		want    string
+	}{
+		{
+			name: "item image",
+			item: &gofeed.Item{Image: &gofeed.Image{URL: "https://cdn.example.com/cover.png"}},
+			want: "https://cdn.example.com/cover.png",
+		},
+		{
+			name: "image enclosure",
+			item: &gofeed.Item{Enclosures: []*gofeed.Enclosure{
+				{URL: "https://cdn.example.com/episode.mp3", Type: "audio/mpeg"},
+				{URL: "https://cdn.example.com/episode.jpg", Type: "image/jpeg"},
+			}},
+			want: "https://cdn.example.com/episode.jpg",
+		},
+		{
+			name: "media thumbnail",
+			item: &gofeed.Item{Extensions: ext.Extensions{"media": {"thumbnail": {{Attrs: map[string]string{"url": "https://cdn.example.com/thumb.jpg"}}}}}},
+			want: "https://cdn.example.com/thumb.jpg",
+		},
+		{
+			name:    "inline content image skipped",
+			item:    &gofeed.Item{Link: "https://example.com/posts/1"},
+			content: `<p>intro</p><img src="data:image/gif;base64,AAAA"><img src="/img/a.png">`,
+			want:    "https://example.com/img/a.png",
+		},
+		{
+			name:    "relative content image",
+			item:    &gofeed.Item{Link: "https://example.com/posts/1"},
+			content: `<p>intro</p><img src="../img/a.png"><img src="/img/b.png">`,
+			want:    "https://example.com/img/a.png",
+		},
+		{
+			name: "none",
+			item: &gofeed.Item{},
+		},
+	}
+	for _, tt := range tests {
+		if got := headerImage(tt.item, tt.content); got != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestArticleDocument(t *testing.T) {
+	published := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+	feed := &gofeed.Feed{Title: "Example Blog", Link: "https://example.com/"}
+	item := &gofeed.Item{
+		Title:           "First Post",
+		Link:            "https://example.com/posts/1",
+		Author:          &gofeed.Person{Name: "Alice"},
+		Categories:      []string{"go", "testing"},
+		PublishedParsed: &published,
+	}
+	content := "<p>" + strings.Repeat("The quick brown fox jumps over the lazy dog. ", 5) + "</p>"
+
+	doc := articleDocument(feed, item, content)
+	props := doc.Properties
+	if props.Title != "First Post" || props.Author != "Alice" || props.URL != item.Link {
+		t.Errorf("unexpected properties %+v", props)
+	}
+	if props.SiteName != "Example Blog" || props.SiteURL != "https://example.com/" {
+		t.Errorf("unexpected site %q %q", props.SiteName, props.SiteURL)
+	}
+	if props.PublishAt != published.Unix() {
+		t.Errorf("expected publish_at %d, got %d", published.Unix(), props.PublishAt)
+	}
+	if !strings.HasPrefix(props.Abstract, "The quick brown fox") {
+		t.Errorf("unexpected abstract %q", props.Abstract)
+	}
+	if len(props.Keywords) != 2 {
+		t.Errorf("expected categories as keywords, got %v", props.Keywords)
+	}
+	if doc.Content != "" {
+		t.Error("expected no document content")
+	}
+}
+
+func TestRssPlugin_ArticleDocument(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		_, _ = fmt.Fprint(w, `<?xml version="1.0"?><rss version="2.0"><channel><title>Example Blog</title><link>https://example.com/</link>
+<item><title>First Post</title><link>https://example.com/1</link><author>alice@example.com (Alice)</author><pubDate>Tue, 02 Jan 2024 15:04:05 GMT</pubDate>
+<description>&lt;p&gt;Hello world, this is the first post.&lt;/p&gt;&lt;img src="/cover.png"&gt;</description></item>
+</channel></rss>`)
+	}))
+	defer server.Close()
+
+	p := newRssPluginWithWorkdir(t.TempDir(), map[string]string{"file_type": "url"})
+	resp, err := p.Run(context.Background(), &api.Request{
+		Parameter: map[string]any{"feed": server.URL},
+		Store:     newMemStore(),
+	})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	articles := resp.Results["articles"].([]map[string]interface{})
+	if len(articles) != 1 {
+		t.Fatalf("expected 1 article, got %d", len(articles))
+	}
+	doc, ok := articles[0]["document"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected document in article, got %v", articles[0])
+	}
+	props := doc["properties"].(map[string]interface{})
+	want := map[string]interface{}{
+		"title":        "First Post",
+		"url":          "https://example.com/1",
+		"site_name":    "Example Blog",
+		"header_image": "https://example.com/cover.png",
+		"abstract":     "Hello world, this is the first post.",
+		"publish_at":   float64(time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC).Unix()),
+	}
+	for k, v := range want {
+		if props[k] != v {
+			t.Errorf("property %s: expected %v, got %v", k, v, props[k])
+		}
+	}
+}
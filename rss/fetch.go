@@ -0,0 +1,96 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package rss
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+
+	"github.com/basenana/plugin/web"
+	"github.com/mmcdole/gofeed"
+)
+
+const feedCacheGroup = "feeds"
+
+// feedCache holds the validators of the last successful fetch, used for conditional GET.
+type feedCache struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+}
+
+// fetchFeed downloads and parses one page of the feed, it returns a nil feed when the server answers 304.
+// The returned link points to the page holding older items when the feed is paged.
+func fetchFeed(ctx context.Context, fp *gofeed.Parser, source rssSource, pageURL string, cache feedCache) (*gofeed.Feed, feedCache, string, error) {
+	if err := web.WaitFetch(ctx, pageURL); err != nil {
+		return nil, cache, "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+	if err != nil {
+		return nil, cache, "", err
+	}
+	req.Header.Set("User-Agent", fp.UserAgent)
+	source.Auth.apply(req)
+	if cache.ETag != "" {
+		req.Header.Set("If-None-Match", cache.ETag)
+	}
+	if cache.LastModified != "" {
+		req.Header.Set("If-Modified-Since", cache.LastModified)
+	}
+
+	resp, err := source.httpClient().Do(req)
+	if err != nil {
+		return nil, cache, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, cache, "", nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, cache, "", gofeed.HTTPError{StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, cache, "", err
+	}
+	feed, err := fp.Parse(bytes.NewReader(data))
+	if err != nil {
+		return nil, cache, "", err
+	}
+	return feed, feedCache{
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	}, archiveLink(data, pageURL), nil
+}
+
+func (s *rssSource) loadFeedCache(ctx context.Context) feedCache {
+	var cache feedCache
+	if err := s.Store.Load(ctx, RssSourcePluginName, feedCacheGroup, s.FeedUrl, &cache); err != nil {
+		return feedCache{}
+	}
+	return cache
+}
+
+func (s *rssSource) saveFeedCache(ctx context.Context, cache feedCache) error {
+	if cache.ETag == "" && cache.LastModified == "" {
+		return nil
+	}
+	return s.Store.Save(ctx, RssSourcePluginName, feedCacheGroup, s.FeedUrl, &cache)
+}
@@ -0,0 +1,87 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package rss
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/basenana/plugin/api"
+)
+
+func TestRssPlugin_ConditionalGet(t *testing.T) {
+	var full, notModified int32
+	const etag = `"v1"`
+	const lastModified = "Mon, 01 Jan 2024 00:00:00 GMT"
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == etag && r.Header.Get("If-Modified-Since") == lastModified {
+			atomic.AddInt32(&notModified, 1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		atomic.AddInt32(&full, 1)
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Last-Modified", lastModified)
+		w.Header().Set("Content-Type", "application/rss+xml")
+		_, _ = fmt.Fprintf(w, testRssFeedTpl, "")
+	}))
+	defer server.Close()
+
+	store := newMemStore()
+	workDir := t.TempDir()
+	for i := 0; i < 2; i++ {
+		p := newRssPluginWithWorkdir(workDir, map[string]string{"file_type": "url"})
+		resp, err := p.Run(context.Background(), &api.Request{
+			Parameter: map[string]any{"feed": server.URL + "/feed.xml"},
+			Store:     store,
+		})
+		if err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		if !resp.IsSucceed {
+			t.Fatalf("Run not succeed: %s", resp.Message)
+		}
+	}
+
+	if full != 1 || notModified != 1 {
+		t.Errorf("expected 1 full fetch and 1 not-modified, got %d and %d", full, notModified)
+	}
+}
+
+func TestRssPlugin_FetchHTTPError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	p := newRssPluginWithWorkdir(t.TempDir(), map[string]string{"file_type": "url"})
+	resp, err := p.Run(context.Background(), &api.Request{
+		Parameter: map[string]any{"feed": server.URL + "/feed.xml"},
+		Store:     newMemStore(),
+	})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if resp.IsSucceed {
+		t.Error("expected failure for http error")
+	}
+}
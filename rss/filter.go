@@ -0,0 +1,110 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package rss
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/basenana/plugin/api"
+	"github.com/mmcdole/gofeed"
+)
+
+const (
+	rssParameterIncludePattern = "include_pattern"
+	rssParameterExcludePattern = "exclude_pattern"
+	rssParameterCategories     = "categories"
+	rssParameterPublishedAfter = "published_after"
+)
+
+type itemFilter struct {
+	include        *regexp.Regexp
+	exclude        *regexp.Regexp
+	categories     map[string]struct{}
+	publishedAfter time.Time
+}
+
+func parseItemFilter(request *api.Request, now time.Time) (*itemFilter, error) {
+	var (
+		f   = &itemFilter{}
+		err error
+	)
+
+	if pattern := api.GetStringParameter(rssParameterIncludePattern, request, ""); pattern != "" {
+		if f.include, err = regexp.Compile("(?i)" + pattern); err != nil {
+			return nil, fmt.Errorf("parse include_pattern failed: %s", err)
+		}
+	}
+	if pattern := api.GetStringParameter(rssParameterExcludePattern, request, ""); pattern != "" {
+		if f.exclude, err = regexp.Compile("(?i)" + pattern); err != nil {
+			return nil, fmt.Errorf("parse exclude_pattern failed: %s", err)
+		}
+	}
+
+	for _, c := range strings.Split(api.GetStringParameter(rssParameterCategories, request, ""), ",") {
+		if c = strings.ToLower(strings.TrimSpace(c)); c != "" {
+			if f.categories == nil {
+				f.categories = make(map[string]struct{})
+			}
+			f.categories[c] = struct{}{}
+		}
+	}
+
+	if after := api.GetStringParameter(rssParameterPublishedAfter, request, ""); after != "" {
+		if t, err := time.Parse(time.RFC3339, after); err == nil {
+			f.publishedAfter = t
+		} else if d, err := time.ParseDuration(after); err == nil {
+			f.publishedAfter = now.Add(-d)
+		} else {
+			return nil, fmt.Errorf("parse published_after [%s] failed: expect RFC3339 time or duration", after)
+		}
+	}
+	return f, nil
+}
+
+// match reports whether the item should be collected; items without a date pass the date check.
+func (f *itemFilter) match(item *gofeed.Item) bool {
+	if f == nil {
+		return true
+	}
+	if f.include != nil && !f.include.MatchString(item.Title) {
+		return false
+	}
+	if f.exclude != nil && f.exclude.MatchString(item.Title) {
+		return false
+	}
+	if len(f.categories) > 0 {
+		found := false
+		for _, c := range item.Categories {
+			if _, ok := f.categories[strings.ToLower(strings.TrimSpace(c))]; ok {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if !f.publishedAfter.IsZero() {
+		if published := itemPublished(item); published != nil && published.Before(f.publishedAfter) {
+			return false
+		}
+	}
+	return true
+}
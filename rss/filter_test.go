@@ -0,0 +1,102 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package rss
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/basenana/plugin/api"
+	"github.com/mmcdole/gofeed"
+)
+
+func TestItemFilter_Match(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	old := now.Add(-10 * 24 * time.Hour)
+	recent := now.Add(-time.Hour)
+
+	goItem := &gofeed.Item{Title: "Go 1.22 Released", Categories: []string{"Golang", "Release"}, PublishedParsed: &recent}
+	rustItem := &gofeed.Item{Title: "Rust news", Categories: []string{"rust"}, PublishedParsed: &old}
+	sponsoredItem := &gofeed.Item{Title: "Sponsored: Go hosting", Categories: []string{"golang"}}
+
+	tests := []struct {
+		name   string
+		params map[string]any
+		want   []bool
+	}{
+		{"no filter", map[string]any{}, []bool{true, true, true}},
+		{"include", map[string]any{"include_pattern": `\bgo\b`}, []bool{true, false, true}},
+		{"exclude", map[string]any{"include_pattern": "go", "exclude_pattern": "^sponsored"}, []bool{true, false, false}},
+		{"categories", map[string]any{"categories": "golang, python"}, []bool{true, false, true}},
+		{"published after duration", map[string]any{"published_after": "72h"}, []bool{true, false, true}},
+		{"published after time", map[string]any{"published_after": "2024-05-01T00:00:00Z"}, []bool{true, true, true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := parseItemFilter(&api.Request{Parameter: tt.params}, now)
+			if err != nil {
+				t.Fatalf("parse filter failed: %v", err)
+			}
+			for i, item := range []*gofeed.Item{goItem, rustItem, sponsoredItem} {
+				if got := f.match(item); got != tt.want[i] {
+					t.Errorf("match(%q) = %v, want %v", item.Title, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseItemFilter_Invalid(t *testing.T) {
+	for _, params := range []map[string]any{
+		{"include_pattern": "("},
+		{"exclude_pattern": "[a-"},
+		{"published_after": "yesterday"},
+	} {
+		if _, err := parseItemFilter(&api.Request{Parameter: params}, time.Now()); err == nil {
+			t.Errorf("expected error for %v", params)
+		}
+	}
+}
+
+func TestRssPlugin_Run_WithFilter(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		_, _ = w.Write([]byte(`<?xml version="1.0"?>
+<rss version="2.0"><channel><title>T</title><link>https://example.com/</link>
+<item><title>Kubernetes tips</title><link>https://example.com/1</link></item>
+<item><title>Cooking recipes</title><link>https://example.com/2</link></item>
+</channel></rss>`))
+	}))
+	defer server.Close()
+
+	p := newRssPluginWithWorkdir(t.TempDir(), map[string]string{"file_type": "url"})
+	resp, err := p.Run(context.Background(), &api.Request{
+		Parameter: map[string]any{"feed": server.URL, "include_pattern": "kubernetes"},
+		Store:     newMemStore(),
+	})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	articles := resp.Results["articles"].([]map[string]interface{})
+	if len(articles) != 1 || articles[0]["title"] != "Kubernetes tips" {
+		t.Errorf("expected only the matching article, got %v", articles)
+	}
+}
@@ -0,0 +1,112 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package rss
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/logger"
+	"github.com/basenana/plugin/utils"
+	"github.com/basenana/plugin/web"
+	"github.com/mmcdole/gofeed"
+)
+
+const (
+	rssParameterFullContent          = "full_content"
+	rssParameterFullContentMinLength = "full_content_min_length"
+
+	defaultFullContentMinLength = 500
+)
+
+// readFromFile is replaced in tests together with packFromURL.
+var readFromFile = web.ReadFromFile
+
+// fullContentOption controls fetching the article page for items whose feed content is only a summary.
+type fullContentOption struct {
+	Enabled   bool
+	MinLength int
+}
+
+func parseFullContent(request *api.Request) (fullContentOption, error) {
+	opt := fullContentOption{MinLength: defaultFullContentMinLength}
+	if raw := api.GetStringParameter(rssParameterFullContent, request, ""); raw != "" {
+		enabled, err := strconv.ParseBool(raw)
+		if err != nil {
+			return opt, fmt.Errorf("parse full_content [%s] failed: expect true or false", raw)
+		}
+		opt.Enabled = enabled
+	}
+	if raw := api.GetStringParameter(rssParameterFullContentMinLength, request, ""); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			return opt, fmt.Errorf("parse full_content_min_length [%s] failed: expect positive integer", raw)
+		}
+		opt.MinLength = n
+	}
+	return opt, nil
+}
+
+// contentLength counts the characters of the visible text of an HTML fragment.
+func contentLength(content string) int {
+	return utf8.RuneCountInString(strings.TrimSpace(utils.ContentTrim("html", content)))
+}
+
+// fullContent fetches the item page and returns its readable content when the feed content
+// is shorter than the threshold. The feed content is kept when fetching fails or yields less.
+// fetched reports whether the page was downloaded.
+func (r *RssSourcePlugin) fullContent(ctx context.Context, source rssSource, item *gofeed.Item) (content string, retries int, fetched bool) {
+	current := contentLength(item.Content)
+	if !source.FullContent.Enabled || current >= source.FullContent.MinLength || item.Link == "" {
+		return item.Content, 0, false
+	}
+
+	sum := sha256.Sum256([]byte(item.Link))
+	tmpName := ".rss_full_" + hex.EncodeToString(sum[:8])
+	var filePath string
+	retries, err := source.Retry.do(ctx, func() (packErr error) {
+		filePath, packErr = source.pack(logger.IntoContext(ctx, r.logger), tmpName, item.Link, "html", r.fileRoot.Workdir(), true)
+		return packErr
+	})
+	if err != nil {
+		r.logger.Warnw("fetch full content failed, keep feed content", "link", item.Link, "retries", retries, "err", err)
+		return item.Content, retries, false
+	}
+	defer func() {
+		if err := r.fileRoot.Remove(tmpName + ".html"); err != nil {
+			r.logger.Debugw("remove full content file failed", "file", filePath, "err", err)
+		}
+	}()
+
+	page, err := readFromFile(logger.IntoContext(ctx, r.logger), filePath)
+	if err != nil {
+		r.logger.Warnw("read full content failed, keep feed content", "link", item.Link, "err", err)
+		return item.Content, retries, true
+	}
+	if contentLength(page) <= current {
+		r.logger.Infow("full content not longer than feed content, keep feed content", "link", item.Link)
+		return item.Content, retries, true
+	}
+	r.logger.Infow("use full content for summary-only item", "link", item.Link, "feedLength", current)
+	return page, retries, true
+}
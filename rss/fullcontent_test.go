@@ -0,0 +1,123 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package rss
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/web"
+)
+
+func TestParseFullContent(t *testing.T) {
+	opt, err := parseFullContent(&api.Request{})
+	if err != nil || opt.Enabled || opt.MinLength != defaultFullContentMinLength {
+		t.Fatalf("unexpected default option %+v, err %v", opt, err)
+	}
+	opt, err = parseFullContent(&api.Request{Parameter: map[string]any{"full_content": "true", "full_content_min_length": "200"}})
+	if err != nil || !opt.Enabled || opt.MinLength != 200 {
+		t.Fatalf("unexpected option %+v, err %v", opt, err)
+	}
+	for _, params := range []map[string]any{
+		{"full_content": "maybe"},
+		{"full_content_min_length": "0"},
+		{"full_content_min_length": "long"},
+	} {
+		if _, err = parseFullContent(&api.Request{Parameter: params}); err == nil {
+			t.Errorf("parseFullContent(%v) expected error", params)
+		}
+	}
+}
+
+func TestContentLength(t *testing.T) {
+	if got := contentLength("<p>Hello <b>world</b></p>\n\n<p>again</p>"); got != len("Hello world again") {
+		t.Errorf("contentLength() = %d", got)
+	}
+	if got := contentLength("<p>你好</p>"); got != 2 {
+		t.Errorf("contentLength() should count characters, got %d", got)
+	}
+}
+
+func TestRssPlugin_Run_FullContentFallback(t *testing.T) {
+	longBody := strings.Repeat("The full article keeps going with plenty of detail. ", 20)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		_, _ = fmt.Fprintf(w, `<?xml version="1.0"?><rss version="2.0"><channel><title>T</title><link>https://example.com/</link>`+
+			`<item><title>Short</title><link>https://example.com/short</link><description>Just a teaser.</description></item>`+
+			`<item><title>Long</title><link>https://example.com/long</link><description>%s</description></item>`+
+			`<item><title>Broken</title><link>https://example.com/broken</link><description>Teaser only.</description></item>`+
+			`</channel></rss>`, longBody)
+	}))
+	defer server.Close()
+
+	var fetched []string
+	origin := packFromURL
+	packFromURL = func(ctx context.Context, filename, urlInfo, tgtFileType, outputDir string, clutterFree bool, options ...web.Option) (string, error) {
+		fetched = append(fetched, urlInfo)
+		if strings.HasSuffix(urlInfo, "/broken") {
+			return "", fmt.Errorf("pack to web failed: status code is 404")
+		}
+		filePath := path.Join(outputDir, filename+"."+tgtFileType)
+		page := fmt.Sprintf("<html><head><title>Short</title></head><body><nav>menu</nav><article><h1>Short</h1><p>%s</p></article></body></html>", longBody)
+		return filePath, os.WriteFile(filePath, []byte(page), 0644)
+	}
+	t.Cleanup(func() { packFromURL = origin })
+
+	workdir := t.TempDir()
+	p := newRssPluginWithWorkdir(workdir, map[string]string{"file_type": "html", rssParameterConcurrency: "1"})
+	resp, err := p.Run(context.Background(), &api.Request{
+		Parameter: map[string]any{"feed": server.URL, "full_content": "true"},
+		Store:     newMemStore(),
+	})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if !resp.IsSucceed {
+		t.Fatalf("Run not succeed: %s", resp.Message)
+	}
+	if len(fetched) != 2 || fetched[0] != "https://example.com/short" || fetched[1] != "https://example.com/broken" {
+		t.Errorf("only summary-only items should be fetched, got %v", fetched)
+	}
+
+	articles := resp.Results["articles"].([]map[string]interface{})
+	if len(articles) != 3 {
+		t.Fatalf("expected 3 articles, got %d", len(articles))
+	}
+	short, _ := os.ReadFile(filepath.Join(workdir, articles[0]["file_path"].(string)))
+	if !strings.Contains(string(short), "The full article keeps going") || strings.Contains(string(short), "Just a teaser.") {
+		t.Errorf("expected full content for the short item, got %s", short)
+	}
+	broken, _ := os.ReadFile(filepath.Join(workdir, articles[2]["file_path"].(string)))
+	if !strings.Contains(string(broken), "Teaser only.") {
+		t.Errorf("expected feed content when the fetch fails, got %s", broken)
+	}
+
+	entries, _ := os.ReadDir(workdir)
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), ".rss_full_") {
+			t.Errorf("temporary full content file %s not removed", e.Name())
+		}
+	}
+}
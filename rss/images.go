@@ -0,0 +1,234 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package rss
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/web"
+)
+
+const (
+	rssParameterDownloadImages = "download_images"
+	rssParameterImagesDir      = "images_dir"
+
+	defaultImagesDir    = "images"
+	maxImageSize        = 10 << 20
+	maxImagesPerArticle = 50
+)
+
+var imageExtensions = map[string]string{
+	"image/jpeg":    ".jpg",
+	"image/png":     ".png",
+	"image/gif":     ".gif",
+	"image/webp":    ".webp",
+	"image/svg+xml": ".svg",
+	"image/avif":    ".avif",
+	"image/bmp":     ".bmp",
+}
+
+// imageOption controls saving the images of html, rawhtml and markdown articles into the working path.
+type imageOption struct {
+	Enabled bool
+	Dir     string
+}
+
+func parseImageOption(request *api.Request) (imageOption, error) {
+	opt := imageOption{
+		Enabled: api.GetBoolParameter(rssParameterDownloadImages, request, false),
+		Dir:     defaultImagesDir,
+	}
+	if raw := api.GetStringParameter(rssParameterImagesDir, request, ""); raw != "" {
+		dir := path.Clean(raw)
+		if path.IsAbs(dir) || dir == "." || dir == ".." || strings.HasPrefix(dir, "../") {
+			return opt, fmt.Errorf("images_dir [%s] must be a relative directory inside the working path", raw)
+		}
+		opt.Dir = dir
+	}
+	return opt, nil
+}
+
+// imageSrc decides the src of a downloaded image from its index and local path.
+type imageSrc func(index int, filePath string) string
+
+// relativeImageSrc points the src at the local copy, relative to the article in the working path root.
+func relativeImageSrc(_ int, filePath string) string {
+	return (&url.URL{Path: filePath}).String()
+}
+
+// placeholderImageSrc marks the image for localizeMarkdownImages, the HTML to Markdown
+// conversion would resolve a relative path against the article URL.
+func placeholderImageSrc(index int, _ string) string {
+	return fmt.Sprintf("rss-image:%d", index)
+}
+
+// localizeImages downloads the images referenced by the article HTML into images_dir/<articleBase>/
+// and returns the HTML with their src rewritten together with the local paths. document keeps the
+// whole page instead of the body fragment. Images that fail to download keep their original src.
+func (r *RssSourcePlugin) localizeImages(ctx context.Context, source rssSource, link, articleBase, content string, document bool, srcOf imageSrc) (string, []string) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(content))
+	if err != nil {
+		r.logger.Warnw("parse article html for images failed", "link", link, "err", err)
+		return content, nil
+	}
+	base, _ := url.Parse(link)
+
+	var (
+		paths  []string
+		index  = make(map[string]int)
+		failed = make(map[string]bool)
+	)
+	doc.Find("img").Each(func(_ int, img *goquery.Selection) {
+		src := strings.TrimSpace(img.AttrOr("src", ""))
+		if src == "" || strings.HasPrefix(src, "data:") {
+			src = strings.TrimSpace(img.AttrOr("data-src", ""))
+		}
+		if src == "" || strings.HasPrefix(src, "data:") {
+			return
+		}
+		ref, err := url.Parse(src)
+		if err != nil {
+			return
+		}
+		if base != nil {
+			ref = base.ResolveReference(ref)
+		}
+		if ref.Scheme != "http" && ref.Scheme != "https" {
+			return
+		}
+		abs := ref.String()
+
+		i, ok := index[abs]
+		if !ok {
+			if failed[abs] || len(paths) >= maxImagesPerArticle {
+				return
+			}
+			filePath, err := r.saveImage(ctx, source, articleBase, abs)
+			if err != nil {
+				r.logger.Warnw("download article image failed, keep remote src", "link", link, "image", abs, "err", err)
+				failed[abs] = true
+				return
+			}
+			i = len(paths)
+			index[abs] = i
+			paths = append(paths, filePath)
+		}
+		img.SetAttr("src", srcOf(i, paths[i]))
+		img.RemoveAttr("srcset")
+		img.RemoveAttr("data-src")
+	})
+	if len(paths) == 0 {
+		return content, nil
+	}
+
+	var out string
+	if document {
+		out, err = doc.Html()
+	} else {
+		out, err = doc.Find("body").Html()
+	}
+	if err != nil {
+		r.logger.Warnw("render article html with local images failed", "link", link, "err", err)
+		return content, nil
+	}
+	return out, paths
+}
+
+// localizePackedImages rewrites the images of a page packed as raw HTML.
+func (r *RssSourcePlugin) localizePackedImages(ctx context.Context, source rssSource, link, articleBase, fileName string) error {
+	data, err := r.fileRoot.Read(fileName)
+	if err != nil {
+		return fmt.Errorf("read packed html failed: %s", err)
+	}
+	content, paths := r.localizeImages(ctx, source, link, articleBase, string(data), true, relativeImageSrc)
+	if len(paths) == 0 {
+		return nil
+	}
+	if err = r.fileRoot.Write(fileName, []byte(content), 0655); err != nil {
+		return fmt.Errorf("write packed html failed: %s", err)
+	}
+	return nil
+}
+
+func (r *RssSourcePlugin) saveImage(ctx context.Context, source rssSource, articleBase, link string) (string, error) {
+	if err := web.WaitFetch(ctx, link); err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+	if err != nil {
+		return "", err
+	}
+	if source.Auth.option(link) != nil {
+		source.Auth.apply(req)
+	}
+	resp, err := source.httpClient().Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", fmt.Errorf("status code is %d", resp.StatusCode)
+	}
+	mediaType := strings.ToLower(strings.TrimSpace(strings.Split(resp.Header.Get("Content-Type"), ";")[0]))
+	if !strings.HasPrefix(mediaType, "image/") {
+		return "", fmt.Errorf("unexpected content type %q", mediaType)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxImageSize+1))
+	if err != nil {
+		return "", err
+	}
+	if len(data) > maxImageSize {
+		return "", fmt.Errorf("image larger than %d bytes", maxImageSize)
+	}
+
+	ext, ok := imageExtensions[mediaType]
+	if !ok {
+		if ext = strings.ToLower(path.Ext(resp.Request.URL.Path)); len(ext) < 2 || len(ext) > 6 {
+			ext = ".img"
+		}
+	}
+	sum := sha256.Sum256([]byte(link))
+	dir := path.Join(source.Images.Dir, articleBase)
+	filePath := path.Join(dir, hex.EncodeToString(sum[:8])+ext)
+	if err = r.fileRoot.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	if err = r.fileRoot.Write(filePath, data, 0644); err != nil {
+		return "", err
+	}
+	return filePath, nil
+}
+
+// localizeMarkdownImages replaces the image placeholders left in the converted Markdown with the local paths.
+func localizeMarkdownImages(markdown string, paths []string) string {
+	for i, filePath := range paths {
+		placeholder, src := placeholderImageSrc(i, filePath), relativeImageSrc(i, filePath)
+		markdown = strings.ReplaceAll(markdown, "]("+placeholder+")", "]("+src+")")
+		markdown = strings.ReplaceAll(markdown, "]("+placeholder+" ", "]("+src+" ")
+	}
+	return markdown
+}
@@ -0,0 +1,191 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package rss
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/web"
+)
+
+func TestParseImageOption(t *testing.T) {
+	opt, err := parseImageOption(&api.Request{})
+	if err != nil || opt.Enabled || opt.Dir != defaultImagesDir {
+		t.Fatalf("unexpected default option %+v, %v", opt, err)
+	}
+	opt, err = parseImageOption(&api.Request{Parameter: map[string]any{"download_images": "true", "images_dir": "assets/img/"}})
+	if err != nil || !opt.Enabled || opt.Dir != "assets/img" {
+		t.Errorf("unexpected option %+v, %v", opt, err)
+	}
+	for _, dir := range []string{"/tmp/images", "..", "../images", "."} {
+		if _, err = parseImageOption(&api.Request{Parameter: map[string]any{"images_dir": dir}}); err == nil {
+			t.Errorf("images_dir %s expected error", dir)
+		}
+	}
+}
+
+const pngPixel = "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00\x00\x01\x00\x00\x00\x01\x08\x06\x00\x00\x00\x1f\x15\xc4\x89"
+
+func newImageFeedServer(t *testing.T, downloads *int32) *httptest.Server {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/feed":
+			content := `<p>Intro</p><img src="/img/a.png" srcset="/img/a@2x.png 2x" alt="A">` +
+				`<img src="` + server.URL + `/img/a.png"><img data-src="b.jpg" src="data:image/gif;base64,R0lGOD">` +
+				`<img src="/img/missing.png"><img src="/page.html">`
+			w.Header().Set("Content-Type", "application/rss+xml")
+			_, _ = fmt.Fprintf(w, `<?xml version="1.0"?><rss version="2.0"><channel><title>Images</title><link>%[1]s</link>`+
+				`<item><title>Pictures</title><link>%[1]s/posts/pictures</link><description><![CDATA[%[2]s]]></description></item>`+
+				`</channel></rss>`, server.URL, content)
+		case "/img/a.png":
+			atomic.AddInt32(downloads, 1)
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = fmt.Fprint(w, pngPixel)
+		case "/posts/b.jpg":
+			atomic.AddInt32(downloads, 1)
+			w.Header().Set("Content-Type", "image/jpeg; charset=binary")
+			_, _ = fmt.Fprint(w, "jpeg")
+		case "/page.html":
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = fmt.Fprint(w, "<html></html>")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestRssPlugin_Run_DownloadImagesHtml(t *testing.T) {
+	var downloads int32
+	server := newImageFeedServer(t, &downloads)
+	workdir := t.TempDir()
+	p := newRssPluginWithWorkdir(workdir, map[string]string{"file_type": "html"})
+
+	resp, err := p.Run(context.Background(), &api.Request{
+		Parameter: map[string]any{"feed": server.URL + "/feed", "download_images": "true"},
+		Store:     newMemStore(),
+	})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if !resp.IsSucceed {
+		t.Fatalf("Run not succeed: %s", resp.Message)
+	}
+	if downloads != 2 {
+		t.Errorf("expected the duplicated image to be downloaded once, got %d downloads", downloads)
+	}
+
+	data, err := os.ReadFile(filepath.Join(workdir, "Pictures.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	html := string(data)
+	entries, err := os.ReadDir(filepath.Join(workdir, "images", "Pictures"))
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("expected 2 saved images, got %v, %v", entries, err)
+	}
+	for _, e := range entries {
+		local := "images/Pictures/" + e.Name()
+		if !strings.Contains(html, `src="`+local+`"`) {
+			t.Errorf("expected src %s in html:\n%s", local, html)
+		}
+		if !strings.HasSuffix(e.Name(), ".png") && !strings.HasSuffix(e.Name(), ".jpg") {
+			t.Errorf("unexpected image extension %s", e.Name())
+		}
+	}
+	if strings.Contains(html, "srcset") || strings.Contains(html, "data-src") {
+		t.Errorf("srcset and data-src should be dropped for local images:\n%s", html)
+	}
+	for _, kept := range []string{`src="/img/missing.png"`, `src="/page.html"`} {
+		if !strings.Contains(html, kept) {
+			t.Errorf("expected failed image to keep %s:\n%s", kept, html)
+		}
+	}
+}
+
+func TestRssPlugin_Run_DownloadImagesMarkdown(t *testing.T) {
+	var downloads int32
+	server := newImageFeedServer(t, &downloads)
+	workdir := t.TempDir()
+	p := newRssPluginWithWorkdir(workdir, map[string]string{"file_type": "markdown"})
+
+	resp, err := p.Run(context.Background(), &api.Request{
+		Parameter: map[string]any{"feed": server.URL + "/feed", "download_images": "true", "images_dir": "assets"},
+		Store:     newMemStore(),
+	})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if !resp.IsSucceed {
+		t.Fatalf("Run not succeed: %s", resp.Message)
+	}
+
+	data, err := os.ReadFile(filepath.Join(workdir, "Pictures.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	markdown := string(data)
+	if strings.Contains(markdown, "rss-image:") || strings.Count(markdown, "](assets/Pictures/") != 3 {
+		t.Errorf("expected 3 local image links:\n%s", markdown)
+	}
+	if !strings.Contains(markdown, "]("+server.URL+"/img/missing.png)") {
+		t.Errorf("expected the failed image resolved against the article url:\n%s", markdown)
+	}
+}
+
+func TestRssPlugin_Run_DownloadImagesRawHtml(t *testing.T) {
+	var downloads int32
+	server := newImageFeedServer(t, &downloads)
+	origin := packFromURL
+	packFromURL = func(ctx context.Context, filename, urlInfo, tgtFileType, outputDir string, clutterFree bool, options ...web.Option) (string, error) {
+		filePath := filepath.Join(outputDir, filename+"."+tgtFileType)
+		page := `<!DOCTYPE html><html><head><title>x</title></head><body><img src="../img/a.png"></body></html>`
+		return filePath, os.WriteFile(filePath, []byte(page), 0644)
+	}
+	t.Cleanup(func() { packFromURL = origin })
+
+	workdir := t.TempDir()
+	p := newRssPluginWithWorkdir(workdir, map[string]string{"file_type": "rawhtml"})
+	resp, err := p.Run(context.Background(), &api.Request{
+		Parameter: map[string]any{"feed": server.URL + "/feed", "download_images": "true"},
+		Store:     newMemStore(),
+	})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if !resp.IsSucceed {
+		t.Fatalf("Run not succeed: %s", resp.Message)
+	}
+	data, err := os.ReadFile(filepath.Join(workdir, "Pictures.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `<title>x</title>`) || !strings.Contains(string(data), `src="images/Pictures/`) {
+		t.Errorf("expected the whole page with a local image:\n%s", data)
+	}
+}
@@ -0,0 +1,103 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package rss
+
+import (
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/mmcdole/gofeed"
+	"github.com/mmcdole/gofeed/json"
+)
+
+const jsonFeedTitleMaxLength = 80
+
+// jsonFeedTranslator fills the gaps left by the default JSON Feed translator:
+// items may omit title and url, and content_text is plain text rather than HTML.
+type jsonFeedTranslator struct {
+	gofeed.DefaultJSONTranslator
+}
+
+func (t *jsonFeedTranslator) Translate(feed interface{}) (*gofeed.Feed, error) {
+	jf, ok := feed.(*json.Feed)
+	if !ok {
+		return nil, fmt.Errorf("feed did not match expected type of *json.Feed")
+	}
+
+	result, err := t.DefaultJSONTranslator.Translate(jf)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, item := range result.Items {
+		if i >= len(jf.Items) {
+			break
+		}
+		jsonItem := jf.Items[i]
+
+		if item.Link == "" {
+			switch {
+			case jsonItem.ExternalURL != "":
+				item.Link = jsonItem.ExternalURL
+			case strings.HasPrefix(jsonItem.ID, "http://"), strings.HasPrefix(jsonItem.ID, "https://"):
+				item.Link = jsonItem.ID
+			}
+		}
+
+		if jsonItem.ContentHTML == "" && jsonItem.ContentText != "" {
+			item.Content = plainTextToHTML(jsonItem.ContentText)
+		}
+
+		if item.Title == "" {
+			item.Title = jsonFeedItemTitle(jsonItem, item.Link)
+		}
+	}
+	return result, nil
+}
+
+func jsonFeedItemTitle(item *json.Item, link string) string {
+	for _, candidate := range []string{item.Summary, item.ContentText} {
+		candidate = strings.Join(strings.Fields(candidate), " ")
+		if candidate == "" {
+			continue
+		}
+		runes := []rune(candidate)
+		if len(runes) > jsonFeedTitleMaxLength {
+			return strings.TrimSpace(string(runes[:jsonFeedTitleMaxLength])) + "..."
+		}
+		return candidate
+	}
+	if link != "" {
+		return link
+	}
+	return item.ID
+}
+
+func plainTextToHTML(text string) string {
+	buf := &strings.Builder{}
+	for _, para := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n\n") {
+		para = strings.TrimSpace(para)
+		if para == "" {
+			continue
+		}
+		buf.WriteString("<p>")
+		buf.WriteString(strings.ReplaceAll(html.EscapeString(para), "\n", "<br/>"))
+		buf.WriteString("</p>\n")
+	}
+	return buf.String()
+}
@@ -0,0 +1,112 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package rss
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/basenana/plugin/api"
+)
+
+const testJSONFeed = `{
+  "version": "https://jsonfeed.org/version/1.1",
+  "title": "Example Blog",
+  "home_page_url": "https://blog.example.com/",
+  "feed_url": "https://blog.example.com/feed.json",
+  "authors": [{"name": "Alice"}],
+  "language": "en",
+  "items": [
+    {
+      "id": "1",
+      "url": "https://blog.example.com/posts/first",
+      "title": "First Post",
+      "content_html": "<p>Hello <b>world</b></p>",
+      "date_published": "2024-01-02T10:00:00Z"
+    },
+    {
+      "id": "https://blog.example.com/notes/2",
+      "content_text": "A short note without a title.\n\nSecond paragraph & more.",
+      "date_published": "2024-01-03T10:00:00Z"
+    }
+  ]
+}`
+
+func TestRssPlugin_JSONFeed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/feed+json")
+		_, _ = w.Write([]byte(testJSONFeed))
+	}))
+	defer server.Close()
+
+	workDir := t.TempDir()
+	p := newRssPluginWithWorkdir(workDir, map[string]string{"file_type": "html"})
+
+	resp, err := p.Run(context.Background(), &api.Request{
+		Parameter: map[string]any{"feed": server.URL + "/feed.json"},
+		Store:     newMemStore(),
+	})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if !resp.IsSucceed {
+		t.Fatalf("Run not succeed: %s", resp.Message)
+	}
+
+	articles := resp.Results["articles"].([]map[string]interface{})
+	if len(articles) != 2 {
+		t.Fatalf("expected 2 articles, got %d", len(articles))
+	}
+
+	first := articles[0]
+	if first["title"] != "First Post" || first["url"] != "https://blog.example.com/posts/first" {
+		t.Errorf("unexpected first article: %v", first)
+	}
+	if first["site_name"] != "Example Blog" || first["site_url"] != "https://blog.example.com/" {
+		t.Errorf("unexpected site info: %v", first)
+	}
+	if first["updated_at"] != "2024-01-02T10:00:00Z" {
+		t.Errorf("unexpected updated_at: %v", first["updated_at"])
+	}
+
+	second := articles[1]
+	if second["url"] != "https://blog.example.com/notes/2" {
+		t.Errorf("expected id to be used as url, got %v", second["url"])
+	}
+	if second["title"] != "A short note without a title. Second paragraph & more." {
+		t.Errorf("expected title derived from content_text, got %v", second["title"])
+	}
+
+	data, err := p.fileRoot.Read(second["file_path"].(string))
+	if err != nil {
+		t.Fatalf("read archived file failed: %v", err)
+	}
+	if !strings.Contains(string(data), "<p>Second paragraph &amp; more.</p>") {
+		t.Errorf("expected content_text to be converted to html, got %s", data)
+	}
+}
+
+func TestPlainTextToHTML(t *testing.T) {
+	got := plainTextToHTML("line one\nline two\n\n<script>")
+	want := "<p>line one<br/>line two</p>\n<p>&lt;script&gt;</p>\n"
+	if got != want {
+		t.Errorf("plainTextToHTML() = %q, want %q", got, want)
+	}
+}
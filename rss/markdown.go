@@ -0,0 +1,73 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package rss
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	htmltomarkdown "github.com/JohannesKaufmann/html-to-markdown/v2"
+	"github.com/JohannesKaufmann/html-to-markdown/v2/converter"
+	"github.com/mmcdole/gofeed"
+)
+
+// markdownContent converts the item HTML to Markdown with a YAML front matter for note tools.
+func markdownContent(item *gofeed.Item, content string) (string, error) {
+	body, err := htmltomarkdown.ConvertString(content, converter.WithDomain(item.Link))
+	if err != nil {
+		return "", err
+	}
+
+	buf := &bytes.Buffer{}
+	buf.WriteString("---\n")
+	writeFrontMatter(buf, "title", item.Title)
+	writeFrontMatter(buf, "author", itemAuthor(item))
+	writeFrontMatter(buf, "url", item.Link)
+	if published := itemPublished(item); published != nil {
+		writeFrontMatter(buf, "published", published.Format(time.RFC3339))
+	}
+	buf.WriteString("---\n\n")
+	buf.WriteString(strings.TrimSpace(body))
+	buf.WriteString("\n")
+	return buf.String(), nil
+}
+
+func writeFrontMatter(buf *bytes.Buffer, key, value string) {
+	if value == "" {
+		return
+	}
+	// a JSON string is a valid YAML double-quoted scalar
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	buf.WriteString(fmt.Sprintf("%s: ", key))
+	_ = enc.Encode(value)
+}
+
+func itemAuthor(item *gofeed.Item) string {
+	if item.Author != nil && item.Author.Name != "" {
+		return item.Author.Name
+	}
+	for _, author := range item.Authors {
+		if author != nil && author.Name != "" {
+			return author.Name
+		}
+	}
+	return ""
+}
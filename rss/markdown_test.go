@@ -0,0 +1,107 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package rss
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/basenana/plugin/api"
+	"github.com/mmcdole/gofeed"
+)
+
+func TestMarkdownContent(t *testing.T) {
+	published := time.Date(2024, 3, 1, 8, 30, 0, 0, time.UTC)
+	item := &gofeed.Item{
+		Title:           `Go "generics" <explained>`,
+		Link:            "https://example.com/posts/generics",
+		Authors:         []*gofeed.Person{{Name: "Jane Doe"}},
+		PublishedParsed: &published,
+	}
+	md, err := markdownContent(item, `<h2>Intro</h2><p>Read <a href="/docs">the docs</a> and <strong>enjoy</strong>.</p>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "---\n" +
+		"title: \"Go \\\"generics\\\" <explained>\"\n" +
+		"author: \"Jane Doe\"\n" +
+		"url: \"https://example.com/posts/generics\"\n" +
+		"published: \"2024-03-01T08:30:00Z\"\n" +
+		"---\n\n"
+	if !strings.HasPrefix(md, want) {
+		t.Errorf("unexpected front matter:\n%s", md)
+	}
+	for _, part := range []string{"## Intro", "[the docs](https://example.com/docs)", "**enjoy**"} {
+		if !strings.Contains(md, part) {
+			t.Errorf("expected %q in markdown:\n%s", part, md)
+		}
+	}
+
+	md, err = markdownContent(&gofeed.Item{Title: "Untitled", Link: "https://example.com/a"}, "<p>text</p>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(md, "author:") || strings.Contains(md, "published:") {
+		t.Errorf("empty fields should be left out:\n%s", md)
+	}
+}
+
+func TestRssPlugin_Run_Markdown(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		_, _ = w.Write([]byte(`<?xml version="1.0"?><rss version="2.0"><channel><title>T</title><link>https://example.com/</link>` +
+			`<item><title>Hello World</title><link>https://example.com/hello</link><author>jane@example.com (Jane)</author>` +
+			`<pubDate>Fri, 01 Mar 2024 08:30:00 GMT</pubDate><description><![CDATA[<p>Some <em>body</em> text.</p>]]></description></item>` +
+			`</channel></rss>`))
+	}))
+	defer server.Close()
+
+	workdir := t.TempDir()
+	p := newRssPluginWithWorkdir(workdir, map[string]string{"file_type": "markdown"})
+	resp, err := p.Run(context.Background(), &api.Request{
+		Parameter: map[string]any{"feed": server.URL},
+		Store:     newMemStore(),
+	})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if !resp.IsSucceed {
+		t.Fatalf("Run not succeed: %s", resp.Message)
+	}
+
+	articles := resp.Results["articles"].([]map[string]interface{})
+	if len(articles) != 1 || articles[0]["file_path"] != "Hello_World.md" {
+		t.Fatalf("unexpected articles %v", articles)
+	}
+	data, err := os.ReadFile(filepath.Join(workdir, "Hello_World.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	md := string(data)
+	for _, part := range []string{"title: \"Hello World\"", "url: \"https://example.com/hello\"", "published: \"2024-03-01T08:30:00Z\"", "Some *body* text."} {
+		if !strings.Contains(md, part) {
+			t.Errorf("expected %q in markdown:\n%s", part, md)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package rss
+
+// syncMetrics describes the health of one feed sync. Seen counts the feed items, each of which
+// ends up filtered, skipped as already archived, deferred past max_items, packed or failed.
+type syncMetrics struct {
+	Seen        int           `json:"seen"`
+	Filtered    int           `json:"filtered"`
+	Skipped     int           `json:"skipped"`
+	Deferred    int           `json:"deferred"`
+	Fetched     int           `json:"fetched"`
+	Packed      int           `json:"packed"`
+	Failed      int           `json:"failed"`
+	Retried     int           `json:"retried"`
+	Pages       int           `json:"backfill_pages,omitempty"`
+	NotModified bool          `json:"not_modified,omitempty"`
+	Failures    []itemFailure `json:"failures,omitempty"`
+}
+
+// itemFailure is an item left for the next run.
+type itemFailure struct {
+	Feed   string `json:"feed"`
+	Title  string `json:"title"`
+	URL    string `json:"url"`
+	Reason string `json:"reason"`
+}
+
+func (m *syncMetrics) fail(feed, title, link string, err error) {
+	m.Failed++
+	m.Failures = append(m.Failures, itemFailure{Feed: feed, Title: title, URL: link, Reason: err.Error()})
+}
+
+func (m *syncMetrics) add(o syncMetrics) {
+	m.Seen += o.Seen
+	m.Filtered += o.Filtered
+	m.Skipped += o.Skipped
+	m.Deferred += o.Deferred
+	m.Fetched += o.Fetched
+	m.Packed += o.Packed
+	m.Failed += o.Failed
+	m.Retried += o.Retried
+	m.Pages += o.Pages
+	m.Failures = append(m.Failures, o.Failures...)
+}
@@ -0,0 +1,107 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package rss
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/web"
+)
+
+func TestRssPlugin_Run_Metrics(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		_, _ = fmt.Fprint(w, `<?xml version="1.0"?><rss version="2.0"><channel><title>T</title><link>https://example.com/</link>`+
+			`<item><title>Sponsored</title><link>https://example.com/ad</link><pubDate>Mon, 01 Jan 2024 00:00:00 GMT</pubDate></item>`+
+			`<item><title>Gone</title><link>https://example.com/gone</link><pubDate>Tue, 02 Jan 2024 00:00:00 GMT</pubDate></item>`+
+			`<item><title>First</title><link>https://example.com/first</link><pubDate>Wed, 03 Jan 2024 00:00:00 GMT</pubDate></item>`+
+			`<item><title>Second</title><link>https://example.com/second</link><pubDate>Thu, 04 Jan 2024 00:00:00 GMT</pubDate></item>`+
+			`<item><title>Third</title><link>https://example.com/third</link><pubDate>Fri, 05 Jan 2024 00:00:00 GMT</pubDate></item>`+
+			`</channel></rss>`)
+	}))
+	defer server.Close()
+
+	origin := packFromURL
+	packFromURL = func(ctx context.Context, filename, urlInfo, tgtFileType, outputDir string, clutterFree bool, options ...web.Option) (string, error) {
+		if strings.HasSuffix(urlInfo, "/gone") {
+			return "", fmt.Errorf("pack to web failed: status code is 404")
+		}
+		filePath := path.Join(outputDir, filename+"."+tgtFileType)
+		return filePath, os.WriteFile(filePath, []byte("archive"), 0644)
+	}
+	t.Cleanup(func() { packFromURL = origin })
+
+	p := newRssPluginWithWorkdir(t.TempDir(), map[string]string{"file_type": "webarchive"})
+	store := newMemStore()
+	run := func() syncMetrics {
+		t.Helper()
+		resp, err := p.Run(context.Background(), &api.Request{
+			Parameter: map[string]any{"feed": server.URL, "exclude_pattern": "Sponsored", "max_items": "3", "max_retries": "0"},
+			Store:     store,
+		})
+		if err != nil || !resp.IsSucceed {
+			t.Fatalf("Run failed: %v %v", err, resp)
+		}
+		metrics, ok := resp.Results["metrics"].(map[string]any)
+		if !ok {
+			t.Fatalf("expected metrics in results, got %v", resp.Results)
+		}
+		var m syncMetrics
+		m.Seen, m.Filtered, m.Skipped = int(metrics["seen"].(float64)), int(metrics["filtered"].(float64)), int(metrics["skipped"].(float64))
+		m.Deferred, m.Fetched, m.Packed = int(metrics["deferred"].(float64)), int(metrics["fetched"].(float64)), int(metrics["packed"].(float64))
+		m.Failed = int(metrics["failed"].(float64))
+		for _, f := range metrics["failures"].([]any) {
+			failure := f.(map[string]any)
+			m.Failures = append(m.Failures, itemFailure{Feed: failure["feed"].(string), Title: failure["title"].(string),
+				URL: failure["url"].(string), Reason: failure["reason"].(string)})
+		}
+		return m
+	}
+
+	m := run()
+	if m.Seen != 5 || m.Filtered != 1 || m.Skipped != 0 || m.Deferred != 1 || m.Fetched != 2 || m.Packed != 2 || m.Failed != 1 {
+		t.Errorf("unexpected first run metrics %+v", m)
+	}
+	if len(m.Failures) != 1 || m.Failures[0].URL != "https://example.com/gone" || m.Failures[0].Feed != server.URL ||
+		!strings.Contains(m.Failures[0].Reason, "404") {
+		t.Errorf("unexpected failures %+v", m.Failures)
+	}
+
+	m = run()
+	if m.Seen != 5 || m.Filtered != 1 || m.Skipped != 2 || m.Deferred != 0 || m.Packed != 1 || m.Failed != 1 {
+		t.Errorf("unexpected second run metrics %+v", m)
+	}
+}
+
+func TestSyncMetrics_Add(t *testing.T) {
+	var total syncMetrics
+	total.add(syncMetrics{Seen: 3, Packed: 2, Retried: 1})
+	b := syncMetrics{Seen: 2, Filtered: 1}
+	b.fail("feed-b", "Gone", "https://example.com/gone", fmt.Errorf("status code is 404"))
+	total.add(b)
+	if total.Seen != 5 || total.Packed != 2 || total.Filtered != 1 || total.Failed != 1 || total.Retried != 1 || len(total.Failures) != 1 {
+		t.Errorf("unexpected total %+v", total)
+	}
+}
@@ -0,0 +1,128 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package rss
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/utils"
+	"github.com/mmcdole/gofeed"
+)
+
+const (
+	rssParameterNameTemplate = "name_template"
+
+	maxSlugLength = 80
+)
+
+// nameData is the data name_template is executed with.
+type nameData struct {
+	Title       string
+	Slug        string
+	PublishedAt time.Time
+	Author      string
+	Host        string
+	ID          string
+}
+
+func parseNameTemplate(request *api.Request) (*template.Template, error) {
+	raw := api.GetStringParameter(rssParameterNameTemplate, request, "")
+	if raw == "" {
+		return nil, nil
+	}
+	tpl, err := template.New(rssParameterNameTemplate).Option("missingkey=error").Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("parse name_template failed: %s", err)
+	}
+	// catch unknown fields before any article is fetched
+	if err = tpl.Execute(io.Discard, nameData{}); err != nil {
+		return nil, fmt.Errorf("parse name_template failed: %s", err)
+	}
+	return tpl, nil
+}
+
+// articleName returns the file name of the item without extension: the sanitized title,
+// or the sanitized output of name_template when it is set.
+func (s *rssSource) articleName(item *gofeed.Item, now time.Time) (string, error) {
+	if s.NameTemplate == nil {
+		return utils.SanitizeFilename(item.Title), nil
+	}
+
+	data := nameData{
+		Title:       item.Title,
+		Slug:        slugify(item.Title),
+		PublishedAt: now,
+		Author:      itemAuthor(item),
+		ID:          shortHash(utils.CanonicalURL(item.Link)),
+	}
+	if published := itemPublished(item); published != nil {
+		data.PublishedAt = *published
+	}
+	if u, err := url.Parse(item.Link); err == nil {
+		data.Host = u.Hostname()
+	}
+
+	var buf strings.Builder
+	if err := s.NameTemplate.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("execute name_template failed: %s", err)
+	}
+	name := utils.SanitizeFilename(buf.String())
+	if name == "" {
+		return utils.SanitizeFilename(item.Title), nil
+	}
+	return name, nil
+}
+
+// slugify lowercases the title and joins its runs of letters and digits with hyphens.
+func slugify(title string) string {
+	var (
+		buf strings.Builder
+		n   int
+		sep bool
+	)
+	for _, r := range strings.ToLower(title) {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			sep = buf.Len() > 0
+			continue
+		}
+		if n >= maxSlugLength {
+			break
+		}
+		if sep {
+			buf.WriteByte('-')
+			sep = false
+			n++
+		}
+		buf.WriteRune(r)
+		n++
+	}
+	return buf.String()
+}
+
+func shortHash(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:4])
+}
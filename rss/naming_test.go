@@ -0,0 +1,116 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package rss
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/basenana/plugin/api"
+	"github.com/mmcdole/gofeed"
+)
+
+func TestSlugify(t *testing.T) {
+	tests := map[string]string{
+		"Hello, World!":          "hello-world",
+		"  Go 1.22 -- Released ": "go-1-22-released",
+		"你好 世界":                  "你好-世界",
+		"!!!":                    "",
+	}
+	for title, want := range tests {
+		if got := slugify(title); got != want {
+			t.Errorf("slugify(%q): expected %q, got %q", title, want, got)
+		}
+	}
+}
+
+func TestParseNameTemplate(t *testing.T) {
+	tpl, err := parseNameTemplate(&api.Request{Parameter: map[string]any{}})
+	if err != nil || tpl != nil {
+		t.Errorf("expected no template by default, got %v, %v", tpl, err)
+	}
+	if _, err = parseNameTemplate(&api.Request{Parameter: map[string]any{rssParameterNameTemplate: "{{.Slug"}}); err == nil {
+		t.Error("expected error for invalid template")
+	}
+	if _, err = parseNameTemplate(&api.Request{Parameter: map[string]any{rssParameterNameTemplate: "{{.Missing}}"}}); err == nil {
+		t.Error("expected error for unknown field")
+	}
+}
+
+func TestRssSource_ArticleName(t *testing.T) {
+	published := time.Date(2024, 3, 5, 10, 0, 0, 0, time.UTC)
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	item := &gofeed.Item{
+		Title:           "Hello: World?",
+		Link:            "https://blog.example.com/posts/hello",
+		PublishedParsed: &published,
+		Author:          &gofeed.Person{Name: "Alice"},
+	}
+
+	tests := []struct {
+		template string
+		item     *gofeed.Item
+		want     string
+	}{
+		{"", item, "Hello__World_"},
+		{`{{.PublishedAt.Format "2006-01-02"}}-{{.Slug}}`, item, "2024-03-05-hello-world"},
+		{`{{.Host}}/{{.Author}} {{.Slug}}`, item, "blog_example_com_Alice_hello-world"},
+		{`{{.PublishedAt.Format "2006-01-02"}}-{{.Slug}}`, &gofeed.Item{Title: "Undated"}, "2024-06-01-undated"},
+		{`{{if .Author}}{{.Author}}{{end}}`, &gofeed.Item{Title: "Fallback"}, "Fallback"},
+	}
+	for _, tt := range tests {
+		tpl, err := parseNameTemplate(&api.Request{Parameter: map[string]any{rssParameterNameTemplate: tt.template}})
+		if err != nil {
+			t.Fatalf("parse %q failed: %v", tt.template, err)
+		}
+		source := rssSource{NameTemplate: tpl}
+		got, err := source.articleName(tt.item, now)
+		if err != nil {
+			t.Fatalf("name %q failed: %v", tt.template, err)
+		}
+		if got != tt.want {
+			t.Errorf("template %q: expected %q, got %q", tt.template, tt.want, got)
+		}
+	}
+}
+
+func TestRssPlugin_NameTemplate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		_, _ = fmt.Fprint(w, `<?xml version="1.0"?><rss version="2.0"><channel><title>T</title><link>https://example.com/</link>
+<item><title>First Post</title><link>https://example.com/1</link><pubDate>Tue, 02 Jan 2024 15:04:05 GMT</pubDate><description>body</description></item>
+</channel></rss>`)
+	}))
+	defer server.Close()
+
+	p := newRssPluginWithWorkdir(t.TempDir(), map[string]string{"file_type": "html"})
+	resp, err := p.Run(context.Background(), &api.Request{
+		Parameter: map[string]any{"feed": server.URL, rssParameterNameTemplate: `{{.PublishedAt.Format "2006-01-02"}}-{{.Slug}}`},
+		Store:     newMemStore(),
+	})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	articles := resp.Results["articles"].([]map[string]interface{})
+	if len(articles) != 1 || articles[0]["file_path"] != "2024-01-02-first-post.html" {
+		t.Errorf("unexpected articles %v", articles)
+	}
+}
@@ -0,0 +1,192 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package rss
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/utils"
+)
+
+const (
+	rssParameterOPMLPath = "opml_path"
+	rssParameterFeeds    = "feeds"
+)
+
+type opmlOutline struct {
+	Text     string        `xml:"text,attr"`
+	Title    string        `xml:"title,attr"`
+	XMLURL   string        `xml:"xmlUrl,attr"`
+	Outlines []opmlOutline `xml:"outline"`
+}
+
+type opmlDocument struct {
+	Body struct {
+		Outlines []opmlOutline `xml:"outline"`
+	} `xml:"body"`
+}
+
+// parseOPML returns the feed URLs of all outlines, nested category outlines included.
+func parseOPML(data []byte) ([]string, error) {
+	var doc opmlDocument
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parse opml failed: %s", err)
+	}
+
+	var (
+		feeds []string
+		walk  func(outlines []opmlOutline)
+	)
+	walk = func(outlines []opmlOutline) {
+		for _, o := range outlines {
+			if feed := strings.TrimSpace(o.XMLURL); feed != "" {
+				feeds = append(feeds, feed)
+			}
+			walk(o.Outlines)
+		}
+	}
+	walk(doc.Body.Outlines)
+	return feeds, nil
+}
+
+// parseFeedsParameter accepts a JSON array or a comma/newline separated list.
+func parseFeedsParameter(raw string) ([]string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, nil
+	}
+	if strings.HasPrefix(raw, "[") {
+		var feeds []string
+		if err := json.Unmarshal([]byte(raw), &feeds); err != nil {
+			return nil, fmt.Errorf("parse feeds failed: %s", err)
+		}
+		return feeds, nil
+	}
+	return strings.FieldsFunc(raw, func(r rune) bool { return r == ',' || r == '\n' }), nil
+}
+
+// feedList returns the feeds of a multi-feed run, it is empty when neither opml_path nor feeds is set.
+func (r *RssSourcePlugin) feedList(request *api.Request) ([]string, error) {
+	var candidates []string
+	if opmlPath := api.GetStringParameter(rssParameterOPMLPath, request, ""); opmlPath != "" {
+		data, err := r.fileRoot.Read(opmlPath)
+		if err != nil {
+			return nil, fmt.Errorf("read opml file failed: %s", err)
+		}
+		feeds, err := parseOPML(data)
+		if err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, feeds...)
+	}
+	feeds, err := parseFeedsParameter(api.GetStringParameter(rssParameterFeeds, request, ""))
+	if err != nil {
+		return nil, err
+	}
+	candidates = append(candidates, feeds...)
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+	if feed := api.GetStringParameter(rssParameterFeed, request, ""); feed != "" {
+		candidates = append([]string{feed}, candidates...)
+	}
+
+	var (
+		result = make([]string, 0, len(candidates))
+		seen   = make(map[string]struct{}, len(candidates))
+	)
+	for _, feed := range candidates {
+		feed = strings.TrimSpace(feed)
+		if feed == "" {
+			continue
+		}
+		key := utils.CanonicalURL(feed)
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		result = append(result, feed)
+	}
+	return result, nil
+}
+
+// runFeeds syncs every feed in turn, a failing feed is reported in its group without stopping the others.
+func (r *RssSourcePlugin) runFeeds(ctx context.Context, request *api.Request, feeds []string) (*api.Response, error) {
+	var (
+		groups   = make([]map[string]any, 0, len(feeds))
+		articles = make([]map[string]interface{}, 0)
+		failed   int
+
+		metrics  syncMetrics
+		contents = contentHashes{} // shared so an article cross-posted to several feeds is archived once
+	)
+	for _, feed := range feeds {
+		group := map[string]any{"feed": feed}
+		groups = append(groups, group)
+
+		source, err := r.newRssSource(request, feed)
+		if err != nil {
+			r.logger.Warnw("get rss source failed", "feed", feed, "err", err)
+			group["error"] = err.Error()
+			failed++
+			continue
+		}
+		if source.Contents != nil {
+			source.Contents = contents
+		}
+		r.logger.Infow("syncing rss", "feed", source.FeedUrl, "fileType", source.FileType)
+
+		result, err := r.syncRssSource(ctx, source)
+		if err != nil {
+			r.logger.Warnw("sync rss failed", "source", source.FeedUrl, "err", err)
+			group["error"] = fmt.Sprintf("sync rss failed: %s", err)
+			failed++
+			continue
+		}
+
+		articleMaps := make([]map[string]interface{}, len(result.Articles))
+		for i := range result.Articles {
+			articleMaps[i] = utils.MarshalMap(result.Articles[i])
+		}
+		group["articles"] = articleMaps
+		group["retried"] = result.Metrics.Retried
+		group["failed"] = result.Metrics.Failed
+		group["metrics"] = utils.MarshalMap(result.Metrics)
+		if !result.Since.IsZero() {
+			group["since"] = result.Since.Format(time.RFC3339)
+		}
+		articles = append(articles, articleMaps...)
+		metrics.add(result.Metrics)
+	}
+
+	if failed == len(feeds) {
+		return api.NewFailedResponse(fmt.Sprintf("sync rss failed: all %d feeds failed", failed)), nil
+	}
+	return api.NewResponseWithResult(map[string]any{
+		"feeds":    groups,
+		"articles": articles,
+		"retried":  metrics.Retried,
+		"failed":   metrics.Failed,
+		"metrics":  utils.MarshalMap(metrics),
+	}), nil
+}
@@ -0,0 +1,159 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package rss
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/basenana/plugin/api"
+)
+
+const testOPML = `<?xml version="1.0" encoding="UTF-8"?>
+<opml version="2.0">
+  <head><title>Subscriptions</title></head>
+  <body>
+    <outline text="Tech" title="Tech">
+      <outline type="rss" text="Blog A" xmlUrl="%s/a.xml" htmlUrl="https://a.example.com/"/>
+      <outline type="rss" text="Broken" xmlUrl="%s/broken.xml"/>
+    </outline>
+    <outline type="rss" text="Blog B" xmlUrl="%s/b.xml"/>
+  </body>
+</opml>`
+
+func TestParseOPML(t *testing.T) {
+	feeds, err := parseOPML([]byte(fmt.Sprintf(testOPML, "https://x", "https://x", "https://x")))
+	if err != nil {
+		t.Fatalf("parseOPML failed: %v", err)
+	}
+	want := []string{"https://x/a.xml", "https://x/broken.xml", "https://x/b.xml"}
+	if !reflect.DeepEqual(feeds, want) {
+		t.Errorf("feeds = %v, want %v", feeds, want)
+	}
+
+	if _, err = parseOPML([]byte("<opml><body>")); err == nil {
+		t.Errorf("expected error for malformed opml")
+	}
+}
+
+func TestParseFeedsParameter(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want []string
+	}{
+		{"", nil},
+		{`["https://a/feed", "https://b/feed"]`, []string{"https://a/feed", "https://b/feed"}},
+		{"https://a/feed,https://b/feed\nhttps://c/feed", []string{"https://a/feed", "https://b/feed", "https://c/feed"}},
+	}
+	for _, tt := range tests {
+		got, err := parseFeedsParameter(tt.raw)
+		if err != nil {
+			t.Fatalf("parseFeedsParameter(%q) failed: %v", tt.raw, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseFeedsParameter(%q) = %v, want %v", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestRssPlugin_FeedList_Dedup(t *testing.T) {
+	p := newRssPluginWithWorkdir(t.TempDir(), map[string]string{"file_type": "url"})
+	feeds, err := p.feedList(&api.Request{Parameter: map[string]any{
+		"feed":  "https://a.example.com/feed",
+		"feeds": []any{"https://A.example.com/feed#top", "https://b.example.com/feed"},
+	}})
+	if err != nil {
+		t.Fatalf("feedList failed: %v", err)
+	}
+	if len(feeds) != 2 {
+		t.Errorf("expected duplicate feeds to be dropped, got %v", feeds)
+	}
+}
+
+func TestRssPlugin_Run_OPML(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/broken.xml" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/rss+xml")
+		_, _ = fmt.Fprintf(w, `<?xml version="1.0"?>
+<rss version="2.0"><channel><title>%[1]s</title><link>https://example.com/</link>
+<item><title>Post from %[1]s</title><link>https://example.com%[1]s/1</link></item>
+</channel></rss>`, r.URL.Path)
+	}))
+	defer server.Close()
+
+	workdir := t.TempDir()
+	opml := fmt.Sprintf(testOPML, server.URL, server.URL, server.URL)
+	if err := os.WriteFile(filepath.Join(workdir, "subs.opml"), []byte(opml), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	p := newRssPluginWithWorkdir(workdir, map[string]string{"file_type": "url"})
+	resp, err := p.Run(context.Background(), &api.Request{
+		Parameter: map[string]any{"opml_path": "subs.opml"},
+		Store:     newMemStore(),
+	})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if !resp.IsSucceed {
+		t.Fatalf("expected success when only some feeds fail: %s", resp.Message)
+	}
+
+	groups := resp.Results["feeds"].([]map[string]any)
+	if len(groups) != 3 {
+		t.Fatalf("expected 3 feed groups, got %d", len(groups))
+	}
+	if groups[1]["error"] == nil {
+		t.Errorf("expected the broken feed to report an error, got %v", groups[1])
+	}
+	for _, i := range []int{0, 2} {
+		if groups[i]["error"] != nil || len(groups[i]["articles"].([]map[string]interface{})) != 1 {
+			t.Errorf("expected one article for feed %v", groups[i])
+		}
+	}
+	if articles := resp.Results["articles"].([]map[string]interface{}); len(articles) != 2 {
+		t.Errorf("expected 2 articles in total, got %d", len(articles))
+	}
+}
+
+func TestRssPlugin_Run_AllFeedsFailed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	p := newRssPluginWithWorkdir(t.TempDir(), map[string]string{"file_type": "url"})
+	resp, err := p.Run(context.Background(), &api.Request{
+		Parameter: map[string]any{"feeds": server.URL + "/a," + server.URL + "/b"},
+		Store:     newMemStore(),
+	})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if resp.IsSucceed {
+		t.Errorf("expected failure when every feed fails")
+	}
+}
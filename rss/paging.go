@@ -0,0 +1,214 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package rss
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/basenana/plugin/api"
+	"github.com/mmcdole/gofeed"
+)
+
+const (
+	rssParameterBackfillPages = "backfill_pages"
+
+	backfillGroup    = "backfills"
+	maxBackfillPages = 100
+)
+
+// feedBackfill marks a feed whose history has been archived.
+type feedBackfill struct {
+	Pages int `json:"pages"`
+}
+
+func parseBackfillPages(request *api.Request) (int, error) {
+	raw := api.GetStringParameter(rssParameterBackfillPages, request, "")
+	if raw == "" {
+		return 0, nil
+	}
+	pages, err := strconv.Atoi(raw)
+	if err != nil || pages < 0 || pages > maxBackfillPages {
+		return 0, fmt.Errorf("parse backfill_pages [%s] failed: expect integer between 0 and %d", raw, maxBackfillPages)
+	}
+	return pages, nil
+}
+
+// archiveLink returns the feed level link to older items: rel="prev-archive" of an archived feed,
+// else rel="next" of a paged feed (RFC 5005), or next_url of a JSON Feed.
+func archiveLink(data []byte, pageURL string) string {
+	var links map[string]string
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		var jf struct {
+			NextURL string `json:"next_url"`
+		}
+		if err := json.Unmarshal(trimmed, &jf); err == nil && jf.NextURL != "" {
+			links = map[string]string{"next": jf.NextURL}
+		}
+	} else {
+		links = xmlFeedLinks(data)
+	}
+
+	link := links["prev-archive"]
+	if link == "" {
+		link = links["next"]
+	}
+	if link == "" {
+		return ""
+	}
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return ""
+	}
+	ref, err := url.Parse(strings.TrimSpace(link))
+	if err != nil {
+		return ""
+	}
+	return base.ResolveReference(ref).String()
+}
+
+// xmlFeedLinks collects the href of the <link rel="..."> elements outside of entries and items,
+// RSS feeds carry them as atom:link.
+func xmlFeedLinks(data []byte) map[string]string {
+	links := make(map[string]string)
+	d := xml.NewDecoder(bytes.NewReader(data))
+	d.Strict = false
+	d.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) { return input, nil }
+
+	inItem := 0
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return links
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch strings.ToLower(t.Name.Local) {
+			case "entry", "item":
+				inItem++
+			case "link":
+				if inItem > 0 {
+					continue
+				}
+				var rel, href string
+				for _, attr := range t.Attr {
+					switch attr.Name.Local {
+					case "rel":
+						rel = strings.ToLower(attr.Value)
+					case "href":
+						href = attr.Value
+					}
+				}
+				if rel != "" && href != "" && links[rel] == "" {
+					links[rel] = href
+				}
+			}
+		case xml.EndElement:
+			switch strings.ToLower(t.Name.Local) {
+			case "entry", "item":
+				inItem--
+			}
+		}
+	}
+}
+
+// needsBackfill reports whether the feed is synced for the first time, feeds archived before
+// backfilling was enabled already have a cursor or cached validators.
+func (s *rssSource) needsBackfill(ctx context.Context) bool {
+	if s.BackfillPages == 0 {
+		return false
+	}
+	var marker feedBackfill
+	if err := s.Store.Load(ctx, RssSourcePluginName, backfillGroup, s.FeedUrl, &marker); err == nil {
+		return false
+	}
+	if !s.loadCursor(ctx).IsZero() {
+		return false
+	}
+	cache := s.loadFeedCache(ctx)
+	return cache.ETag == "" && cache.LastModified == ""
+}
+
+func (s *rssSource) saveBackfill(ctx context.Context, pages int) error {
+	return s.Store.Save(ctx, RssSourcePluginName, backfillGroup, s.FeedUrl, &feedBackfill{Pages: pages})
+}
+
+// backfill follows the archive links from the first page up to BackfillPages pages on the feed host,
+// appending the items not seen on newer pages to the feed. complete is false when a page failed,
+// so the next run backfills again.
+func (r *RssSourcePlugin) backfill(ctx context.Context, fp *gofeed.Parser, source rssSource, feed *gofeed.Feed, prev string) (pages int, complete bool) {
+	feedURL, err := url.Parse(source.FeedUrl)
+	if err != nil {
+		return 0, false
+	}
+	seen := map[string]struct{}{source.FeedUrl: {}}
+	items := make(map[string]struct{}, len(feed.Items))
+	for _, item := range feed.Items {
+		items[itemKey(item)] = struct{}{}
+	}
+
+	for pages < source.BackfillPages && prev != "" {
+		if _, ok := seen[prev]; ok {
+			break
+		}
+		seen[prev] = struct{}{}
+		if u, err := url.Parse(prev); err != nil || u.Host != feedURL.Host {
+			r.logger.Warnw("archive page not on feed host, stop backfilling", "feed", source.FeedUrl, "page", prev)
+			break
+		}
+
+		var page *gofeed.Feed
+		pageURL := prev
+		_, err = source.Retry.do(ctx, func() (fetchErr error) {
+			page, _, prev, fetchErr = fetchFeed(ctx, fp, source, pageURL, feedCache{})
+			return fetchErr
+		})
+		if err != nil {
+			r.logger.Warnw("fetch archive page failed", "feed", source.FeedUrl, "page", pageURL, "err", err)
+			return pages, false
+		}
+		pages++
+		for _, item := range page.Items {
+			if key := itemKey(item); key != "" {
+				if _, ok := items[key]; ok {
+					continue
+				}
+				items[key] = struct{}{}
+			}
+			feed.Items = append(feed.Items, item)
+		}
+		r.logger.Infow("backfill archive page", "feed", source.FeedUrl, "page", pageURL, "items", len(page.Items))
+	}
+	return pages, true
+}
+
+func itemKey(item *gofeed.Item) string {
+	switch {
+	case item.GUID != "":
+		return "guid:" + item.GUID
+	case item.Link != "":
+		return "link:" + item.Link
+	}
+	return ""
+}
@@ -0,0 +1,179 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package rss
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/basenana/plugin/api"
+)
+
+func TestParseBackfillPages(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"0", 0, false},
+		{"10", 10, false},
+		{"-1", 0, true},
+		{"101", 0, true},
+		{"abc", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseBackfillPages(&api.Request{Parameter: map[string]any{rssParameterBackfillPages: tt.value}})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("backfill_pages %q: unexpected error %v", tt.value, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("backfill_pages %q: expected %d, got %d", tt.value, tt.want, got)
+		}
+	}
+}
+
+func TestArchiveLink(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{
+			name: "atom prev-archive preferred",
+			data: `<feed xmlns="http://www.w3.org/2005/Atom"><link rel="next" href="/page/2"/><link rel="prev-archive" href="/archive/2023"/><entry><link rel="alternate" href="/post"/></entry></feed>`,
+			want: "https://example.com/archive/2023",
+		},
+		{
+			name: "rss atom:link next",
+			data: `<rss xmlns:atom="http://www.w3.org/2005/Atom"><channel><atom:link rel="self" href="https://example.com/feed"/><atom:link rel="next" href="https://example.com/feed?page=2"/><item><link>https://example.com/post</link></item></channel></rss>`,
+			want: "https://example.com/feed?page=2",
+		},
+		{
+			name: "links of items ignored",
+			data: `<feed xmlns="http://www.w3.org/2005/Atom"><entry><link rel="next" href="/other"/></entry></feed>`,
+			want: "",
+		},
+		{
+			name: "json feed next_url",
+			data: `{"version": "https://jsonfeed.org/version/1.1", "next_url": "feed.json?page=2", "items": []}`,
+			want: "https://example.com/blog/feed.json?page=2",
+		},
+	}
+	for _, tt := range tests {
+		if got := archiveLink([]byte(tt.data), "https://example.com/blog/feed.xml"); got != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.want, got)
+		}
+	}
+}
+
+const testPagedFeedTpl = `<?xml version="1.0" encoding="UTF-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <title>Example</title>
+  <link rel="alternate" href="https://example.com/"/>
+  %s
+  <entry><id>post-%d</id><title>Post %d</title><link href="https://example.com/posts/%d"/><updated>2024-01-%02dT00:00:00Z</updated><summary>body</summary></entry>
+  <entry><id>post-%d</id><title>Post %d</title><link href="https://example.com/posts/%d"/><updated>2024-01-%02dT00:00:00Z</updated><summary>body</summary></entry>
+</feed>`
+
+func TestRssPlugin_Backfill(t *testing.T) {
+	var archived int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/atom+xml")
+		switch r.URL.Query().Get("page") {
+		case "":
+			_, _ = fmt.Fprintf(w, testPagedFeedTpl, `<link rel="prev-archive" href="?page=2"/>`, 6, 6, 6, 6, 5, 5, 5, 5)
+		case "2":
+			atomic.AddInt32(&archived, 1)
+			// post 5 is on both pages
+			_, _ = fmt.Fprintf(w, testPagedFeedTpl, `<link rel="prev-archive" href="?page=3"/>`, 5, 5, 5, 5, 4, 4, 4, 4)
+		case "3":
+			atomic.AddInt32(&archived, 1)
+			_, _ = fmt.Fprintf(w, testPagedFeedTpl, `<link rel="prev-archive" href="?page=4"/>`, 3, 3, 3, 3, 2, 2, 2, 2)
+		default:
+			t.Errorf("unexpected page %s", r.URL.RawQuery)
+		}
+	}))
+	defer server.Close()
+
+	store := newMemStore()
+	workDir := t.TempDir()
+	run := func() *api.Response {
+		p := newRssPluginWithWorkdir(workDir, map[string]string{"file_type": "url"})
+		resp, err := p.Run(context.Background(), &api.Request{
+			Parameter: map[string]any{"feed": server.URL + "/feed.xml", rssParameterBackfillPages: "2"},
+			Store:     store,
+		})
+		if err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		if !resp.IsSucceed {
+			t.Fatalf("Run not succeed: %s", resp.Message)
+		}
+		return resp
+	}
+
+	resp := run()
+	articles := resp.Results["articles"].([]map[string]interface{})
+	if len(articles) != 5 {
+		t.Fatalf("expected 5 articles from 3 pages, got %d", len(articles))
+	}
+	metrics := resp.Results["metrics"].(map[string]interface{})
+	if metrics["backfill_pages"] != float64(2) || metrics["seen"] != float64(5) {
+		t.Errorf("unexpected metrics %v", metrics)
+	}
+
+	resp = run()
+	if articles := resp.Results["articles"].([]map[string]interface{}); len(articles) != 0 {
+		t.Errorf("expected no articles on the second run, got %d", len(articles))
+	}
+	if archived != 2 {
+		t.Errorf("expected archive pages fetched only on the first sync, got %d fetches", archived)
+	}
+}
+
+func TestRssPlugin_BackfillDisabled(t *testing.T) {
+	var archived int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/atom+xml")
+		if r.URL.Query().Get("page") != "" {
+			atomic.AddInt32(&archived, 1)
+		}
+		_, _ = fmt.Fprintf(w, testPagedFeedTpl, `<link rel="prev-archive" href="?page=2"/>`, 6, 6, 6, 6, 5, 5, 5, 5)
+	}))
+	defer server.Close()
+
+	p := newRssPluginWithWorkdir(t.TempDir(), map[string]string{"file_type": "url"})
+	resp, err := p.Run(context.Background(), &api.Request{
+		Parameter: map[string]any{"feed": server.URL + "/feed.xml"},
+		Store:     newMemStore(),
+	})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if articles := resp.Results["articles"].([]map[string]interface{}); len(articles) != 2 {
+		t.Errorf("expected 2 articles, got %d", len(articles))
+	}
+	if archived != 0 {
+		t.Errorf("expected no archive page fetched, got %d", archived)
+	}
+}
@@ -0,0 +1,119 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package rss
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/basenana/plugin/logger"
+	"github.com/basenana/plugin/web"
+)
+
+const rssParameterProxyURL = "proxy_url"
+
+// parseProxy validates the proxy_url config. The web packer dials on its own, so with a
+// proxy pages are downloaded here instead, which rules out the webarchive file type.
+func parseProxy(raw, fileType string) (*url.URL, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("parse proxy_url [%s] failed: expect scheme://host:port", raw)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, fmt.Errorf("unsupported proxy_url scheme %s: expect http, https or socks5", u.Scheme)
+	}
+	if fileType == archiveFileTypeWebArchive {
+		return nil, fmt.Errorf("proxy_url does not support file_type webarchive, use rawhtml, html or markdown")
+	}
+	return u, nil
+}
+
+func (s *rssSource) httpClient() *http.Client {
+	cli := &http.Client{Timeout: time.Duration(s.Timeout) * time.Second}
+	if s.Proxy != nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.Proxy = http.ProxyURL(s.Proxy)
+		cli.Transport = transport
+	}
+	return cli
+}
+
+// pack downloads an article page to outputDir/filename.packType, through the proxy when one is set.
+func (s *rssSource) pack(ctx context.Context, filename, link, packType, outputDir string, clutterFree bool) (string, error) {
+	if s.Proxy == nil {
+		return packFromURL(ctx, filename, link, packType, outputDir, clutterFree, s.articleOptions(link)...)
+	}
+	if packType != "html" {
+		return "", fmt.Errorf("unsupported file type %s with proxy", packType)
+	}
+	if err := web.WaitFetch(ctx, link); err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+	if err != nil {
+		return "", err
+	}
+	for k, v := range s.Headers {
+		req.Header.Set(k, v)
+	}
+	if s.Auth.option(link) != nil {
+		s.Auth.apply(req)
+	}
+	resp, err := s.httpClient().Do(req)
+	if err != nil {
+		return "", fmt.Errorf("pack to web failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", fmt.Errorf("pack to web failed: status code is %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("pack to web failed: %w", err)
+	}
+
+	filePath := path.Join(outputDir, filename+"."+packType)
+	if err = os.WriteFile(filePath, data, 0655); err != nil {
+		return "", err
+	}
+	if clutterFree {
+		content, err := readFromFile(ctx, filePath)
+		if err != nil {
+			return "", err
+		}
+		if strings.TrimSpace(content) != "" {
+			if err = os.WriteFile(filePath, []byte(content), 0655); err != nil {
+				return "", err
+			}
+		}
+	}
+	logger.FromContext(ctx).Infow("packed url through proxy", "link", link, "file", filePath)
+	return filePath, nil
+}
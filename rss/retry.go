@@ -0,0 +1,125 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package rss
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/web"
+)
+
+const (
+	rssParameterMaxRetries   = "max_retries"
+	rssParameterRetryBackoff = "retry_backoff"
+
+	defaultMaxRetries   = 2
+	defaultRetryBackoff = time.Second
+	maxRetryBackoff     = 30 * time.Second
+)
+
+// packFromURL is replaced in tests to simulate flaky pages.
+var packFromURL = web.PackFromURL
+
+var serverErrorStatus = regexp.MustCompile(`status code is 5\d\d`)
+
+type retryPolicy struct {
+	MaxRetries int
+	Backoff    time.Duration
+}
+
+func parseRetryPolicy(request *api.Request) (retryPolicy, error) {
+	policy := retryPolicy{MaxRetries: defaultMaxRetries, Backoff: defaultRetryBackoff}
+	if raw := api.GetStringParameter(rssParameterMaxRetries, request, ""); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			return policy, fmt.Errorf("parse max_retries [%s] failed: expect non-negative integer", raw)
+		}
+		policy.MaxRetries = n
+	}
+	if raw := api.GetStringParameter(rssParameterRetryBackoff, request, ""); raw != "" {
+		d, err := time.ParseDuration(raw)
+		if err != nil || d <= 0 {
+			return policy, fmt.Errorf("parse retry_backoff [%s] failed: expect positive duration", raw)
+		}
+		policy.Backoff = d
+	}
+	return policy, nil
+}
+
+// do calls fn until it succeeds, fails with a permanent error or runs out of retries,
+// and returns the number of retries made.
+func (p retryPolicy) do(ctx context.Context, fn func() error) (int, error) {
+	var retries int
+	for {
+		err := fn()
+		if err == nil || retries >= p.MaxRetries || !isTransient(err) {
+			return retries, err
+		}
+
+		timer := time.NewTimer(p.delay(retries))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return retries, err
+		case <-timer.C:
+		}
+		retries++
+	}
+}
+
+// delay doubles the backoff for every retry, the upper half is randomized so parallel retries spread out.
+func (p retryPolicy) delay(retry int) time.Duration {
+	d := p.Backoff << retry
+	if d <= 0 || d > maxRetryBackoff {
+		d = maxRetryBackoff
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// isTransient reports whether a fetch error is worth retrying: server errors, timeouts and dropped connections.
+func isTransient(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	msg := strings.ToLower(err.Error())
+	if serverErrorStatus.MatchString(msg) {
+		return true
+	}
+	for _, s := range []string{"timeout", "connection reset", "connection refused", "eof", "temporarily unavailable"} {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,193 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package rss
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/web"
+)
+
+func TestParseRetryPolicy(t *testing.T) {
+	policy, err := parseRetryPolicy(&api.Request{})
+	if err != nil || policy.MaxRetries != defaultMaxRetries || policy.Backoff != defaultRetryBackoff {
+		t.Fatalf("unexpected default policy %+v, err %v", policy, err)
+	}
+
+	policy, err = parseRetryPolicy(&api.Request{Parameter: map[string]any{"max_retries": "0", "retry_backoff": "200ms"}})
+	if err != nil || policy.MaxRetries != 0 || policy.Backoff != 200*time.Millisecond {
+		t.Fatalf("unexpected policy %+v, err %v", policy, err)
+	}
+
+	for _, params := range []map[string]any{
+		{"max_retries": "-1"},
+		{"max_retries": "many"},
+		{"retry_backoff": "0s"},
+		{"retry_backoff": "soon"},
+	} {
+		if _, err = parseRetryPolicy(&api.Request{Parameter: params}); err == nil {
+			t.Errorf("parseRetryPolicy(%v) expected error", params)
+		}
+	}
+}
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{fmt.Errorf("pack to web failed: do request with url x error: status code is 503 unavailable"), true},
+		{fmt.Errorf("pack to web failed: do request with url x error: status code is 404 not found"), false},
+		{fmt.Errorf("wrap: %w", context.DeadlineExceeded), true},
+		{context.Canceled, false},
+		{errors.New("read tcp: connection reset by peer"), true},
+		{errors.New("Client.Timeout exceeded while awaiting headers"), true},
+		{errors.New("parse html file failed: title not found"), false},
+	}
+	for _, tt := range tests {
+		if got := isTransient(tt.err); got != tt.want {
+			t.Errorf("isTransient(%q) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestRetryPolicy_Delay(t *testing.T) {
+	p := retryPolicy{Backoff: 100 * time.Millisecond}
+	for retry, base := range []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond} {
+		for i := 0; i < 20; i++ {
+			if d := p.delay(retry); d < base/2 || d > base {
+				t.Fatalf("delay(%d) = %s, want within [%s, %s]", retry, d, base/2, base)
+			}
+		}
+	}
+	if d := p.delay(20); d > maxRetryBackoff {
+		t.Errorf("delay should be capped at %s, got %s", maxRetryBackoff, d)
+	}
+}
+
+func TestRetryPolicy_Do(t *testing.T) {
+	p := retryPolicy{MaxRetries: 3, Backoff: time.Millisecond}
+	transient := errors.New("status code is 502")
+
+	calls := 0
+	retries, err := p.do(context.Background(), func() error {
+		calls++
+		if calls < 3 {
+			return transient
+		}
+		return nil
+	})
+	if err != nil || retries != 2 || calls != 3 {
+		t.Errorf("expected success after 2 retries, got retries=%d calls=%d err=%v", retries, calls, err)
+	}
+
+	calls = 0
+	retries, err = p.do(context.Background(), func() error {
+		calls++
+		return transient
+	})
+	if err == nil || retries != 3 || calls != 4 {
+		t.Errorf("expected failure after 3 retries, got retries=%d calls=%d err=%v", retries, calls, err)
+	}
+
+	calls = 0
+	retries, err = p.do(context.Background(), func() error {
+		calls++
+		return errors.New("status code is 404")
+	})
+	if err == nil || retries != 0 || calls != 1 {
+		t.Errorf("permanent error should not be retried, got retries=%d calls=%d", retries, calls)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	calls = 0
+	if _, err = (retryPolicy{MaxRetries: 3, Backoff: time.Hour}).do(ctx, func() error {
+		calls++
+		return transient
+	}); err == nil || calls != 1 {
+		t.Errorf("canceled context should stop retrying, calls=%d err=%v", calls, err)
+	}
+}
+
+func TestRssPlugin_Run_RetryPackFailures(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		_, _ = fmt.Fprint(w, `<?xml version="1.0"?><rss version="2.0"><channel><title>T</title><link>https://example.com/</link>`+
+			`<item><title>Flaky</title><link>https://example.com/flaky</link></item>`+
+			`<item><title>Gone</title><link>https://example.com/gone</link></item>`+
+			`<item><title>Stable</title><link>https://example.com/stable</link></item>`+
+			`</channel></rss>`)
+	}))
+	defer server.Close()
+
+	var (
+		mux   sync.Mutex
+		calls = map[string]int{}
+	)
+	origin := packFromURL
+	packFromURL = func(ctx context.Context, filename, urlInfo, tgtFileType, outputDir string, clutterFree bool, options ...web.Option) (string, error) {
+		mux.Lock()
+		calls[urlInfo]++
+		n := calls[urlInfo]
+		mux.Unlock()
+
+		switch {
+		case strings.HasSuffix(urlInfo, "/gone"):
+			return "", fmt.Errorf("pack to web failed: status code is 404")
+		case strings.HasSuffix(urlInfo, "/flaky") && n == 1:
+			return "", fmt.Errorf("pack to web failed: status code is 503")
+		}
+		filePath := path.Join(outputDir, filename+"."+tgtFileType)
+		return filePath, os.WriteFile(filePath, []byte("archive"), 0644)
+	}
+	t.Cleanup(func() { packFromURL = origin })
+
+	p := newRssPluginWithWorkdir(t.TempDir(), map[string]string{"file_type": "webarchive"})
+	resp, err := p.Run(context.Background(), &api.Request{
+		Parameter: map[string]any{"feed": server.URL, "retry_backoff": "1ms"},
+		Store:     newMemStore(),
+	})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if !resp.IsSucceed {
+		t.Fatalf("Run not succeed: %s", resp.Message)
+	}
+
+	articles := resp.Results["articles"].([]map[string]interface{})
+	if len(articles) != 2 {
+		t.Fatalf("expected 2 articles, got %d", len(articles))
+	}
+	if resp.Results["retried"] != 1 || resp.Results["failed"] != 1 {
+		t.Errorf("expected retried=1 failed=1, got retried=%v failed=%v", resp.Results["retried"], resp.Results["failed"])
+	}
+	if calls["https://example.com/flaky"] != 2 || calls["https://example.com/gone"] != 1 || calls["https://example.com/stable"] != 1 {
+		t.Errorf("unexpected fetch attempts %v", calls)
+	}
+}
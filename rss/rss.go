@@ -20,12 +20,16 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"maps"
 	"net/url"
 	"path"
 	"strconv"
 	"strings"
+	"sync"
+	"text/template"
 	"time"
 
+	"github.com/basenana/plugin/agentic"
 	"github.com/basenana/plugin/api"
 	"github.com/basenana/plugin/logger"
 	"github.com/basenana/plugin/types"
@@ -45,26 +49,31 @@ const (
 	archiveFileTypeHtml       = "html"
 	archiveFileTypeRawHtml    = "rawhtml"
 	archiveFileTypeWebArchive = "webarchive"
+	archiveFileTypeMarkdown   = "markdown"
 
 	rssParameterFeed        = "feed"
 	rssParameterFileType    = "file_type"
 	rssParameterTimeout     = "timeout"
 	rssParameterClutterFree = "clutter_free"
+	rssParameterStateFile   = "state_file"
+	rssParameterConcurrency = "concurrency"
 
-	rssPostMaxCollect = 50
+	rssPostMaxCollect  = 50
+	defaultConcurrency = 4
 )
 
 var RssSourcePluginSpec = types.PluginSpec{
-	Name:    RssSourcePluginName,
-	Version: RssSourcePluginVersion,
-	Type:    types.TypeSource,
+	Name:         RssSourcePluginName,
+	Version:      RssSourcePluginVersion,
+	Type:         types.TypeSource,
+	Dependencies: []types.Dependency{{Kind: types.DependencyCapability, Name: types.CapabilityNetwork}},
 	InitParameters: []types.ParameterSpec{
 		{
 			Name:        "file_type",
 			Required:    false,
 			Default:     "webarchive",
-			Description: "Archive format: url, html, rawhtml, webarchive",
-			Options:     []string{"url", "html", "rawhtml", "webarchive"},
+			Description: "Archive format: url, html, rawhtml, webarchive, markdown",
+			Options:     []string{"url", "html", "rawhtml", "webarchive", "markdown"},
 		},
 		{
 			Name:        "timeout",
@@ -79,23 +88,186 @@ var RssSourcePluginSpec = types.PluginSpec{
 			Description: "Enable clutter-free mode",
 			Options:     []string{"true", "false"},
 		},
+		{
+			Name:        "state_file",
+			Required:    false,
+			Default:     defaultStateFile,
+			Description: "Dedup state file in the working path, used when no persistent store is provided",
+		},
+		{
+			Name:        "concurrency",
+			Required:    false,
+			Default:     strconv.Itoa(defaultConcurrency),
+			Description: "Maximum number of articles fetched and packed at the same time",
+		},
+		{
+			Name:        rssParameterProxyURL,
+			Required:    false,
+			Description: "http, https or socks5 proxy for feed and article requests, not supported with webarchive",
+		},
 	},
-	Parameters: []types.ParameterSpec{
+	Parameters: append([]types.ParameterSpec{
 		{
 			Name:        "feed",
-			Required:    true,
-			Description: "RSS/Atom feed URL",
+			Required:    false,
+			Description: "RSS/Atom/JSON feed URL, required unless opml_path or feeds is set",
 		},
-	},
+		{
+			Name:        "opml_path",
+			Required:    false,
+			Description: "OPML file in the working path, every outline with an xmlUrl is synced",
+		},
+		{
+			Name:        "feeds",
+			Required:    false,
+			Description: "Feed URLs to sync in one run, as a JSON array or comma-separated list",
+		},
+		{
+			Name:        "include_pattern",
+			Required:    false,
+			Description: "Only collect items whose title matches this regex (case-insensitive)",
+		},
+		{
+			Name:        "exclude_pattern",
+			Required:    false,
+			Description: "Skip items whose title matches this regex (case-insensitive)",
+		},
+		{
+			Name:        "categories",
+			Required:    false,
+			Description: "Comma-separated categories, items must have at least one",
+		},
+		{
+			Name:        "published_after",
+			Required:    false,
+			Description: "Only collect items published after this RFC3339 time or within this duration (e.g. 72h)",
+		},
+		{
+			Name:        "score_keywords",
+			Required:    false,
+			Description: "JSON object of keyword to weight, added when the title or description contains the keyword",
+		},
+		{
+			Name:        "source_weight",
+			Required:    false,
+			Default:     "1",
+			Description: "Multiplier applied to the score of every item in this feed",
+		},
+		{
+			Name:        "recency_window",
+			Required:    false,
+			Default:     "168h",
+			Description: "Items published within this window get a recency bonus from 1 down to 0",
+		},
+		{
+			Name:        "relevance_topic",
+			Required:    false,
+			Description: "Ask the LLM to rate each item's relevance to this topic (0-1), requires friday_llm_* config",
+		},
+		{
+			Name:        "min_score",
+			Required:    false,
+			Description: "Skip items scoring below this value; scored items are collected highest first",
+		},
+		{
+			Name:        "full_content",
+			Required:    false,
+			Default:     "false",
+			Description: "For the html and markdown file types, fetch the article page when the feed content is shorter than full_content_min_length",
+			Options:     []string{"true", "false"},
+		},
+		{
+			Name:        "full_content_min_length",
+			Required:    false,
+			Default:     strconv.Itoa(defaultFullContentMinLength),
+			Description: "Feed content with fewer text characters is treated as a summary",
+		},
+		{
+			Name:        "auth_type",
+			Required:    false,
+			Description: "Feed authentication, inferred from username or bearer_token when empty",
+			Options:     []string{authTypeNone, authTypeBasic, authTypeBearer},
+		},
+		{
+			Name:        "username",
+			Required:    false,
+			Description: "Basic auth username",
+		},
+		{
+			Name:        "password",
+			Required:    false,
+			Description: "Basic auth password",
+		},
+		{
+			Name:        "bearer_token",
+			Required:    false,
+			Description: "Bearer token sent as the Authorization header",
+		},
+		{
+			Name:        "cookie",
+			Required:    false,
+			Description: "Cookie header sent with the feed and article requests",
+		},
+		{
+			Name:        rssParameterDownloadImages,
+			Required:    false,
+			Default:     "false",
+			Description: "For the html, rawhtml and markdown file types, save article images into images_dir and point the src at the local copies",
+			Options:     []string{"true", "false"},
+		},
+		{
+			Name:        rssParameterImagesDir,
+			Required:    false,
+			Default:     defaultImagesDir,
+			Description: "Directory in the working path for downloaded images, one subdirectory per article",
+		},
+		{
+			Name:        rssParameterContentDedup,
+			Required:    false,
+			Default:     "true",
+			Description: "Skip articles whose normalized content matches one already archived, e.g. cross-posted to several feeds",
+			Options:     []string{"true", "false"},
+		},
+		{
+			Name:        rssParameterNameTemplate,
+			Required:    false,
+			Description: "Go template for article file names, e.g. {{.PublishedAt.Format \"2006-01-02\"}}-{{.Slug}}; fields: Title, Slug, PublishedAt, Author, Host, ID",
+		},
+		{
+			Name:        rssParameterBackfillPages,
+			Required:    false,
+			Default:     "0",
+			Description: "On the first sync of a paged feed, follow up to this many prev-archive/next links (RFC 5005) to backfill its history",
+		},
+		{
+			Name:        "max_retries",
+			Required:    false,
+			Default:     strconv.Itoa(defaultMaxRetries),
+			Description: "Retries per article on server errors and timeouts, with exponential backoff and jitter",
+		},
+		{
+			Name:        "retry_backoff",
+			Required:    false,
+			Default:     defaultRetryBackoff.String(),
+			Description: "Initial retry delay, doubled on every retry up to 30s",
+		},
+	}, web.FetchPolicyParameters...),
 }
 
 type RssSourcePlugin struct {
 	logger      *zap.SugaredLogger
 	fileRoot    *utils.FileAccess
+	stateRoot   *utils.FileAccess
 	fileType    string
 	timeout     int
 	clutterFree bool
 	headers     map[string]string
+	stateFile   string
+	concurrency int
+	proxyURL    string
+	relevance   relevanceFunc
+	jobID       string
+	config      map[string]string
 }
 
 func NewRssPlugin(ps types.PluginCall) types.Plugin {
@@ -118,6 +290,21 @@ func NewRssPlugin(ps types.PluginCall) types.Plugin {
 		clutterFree = v == "true" || v == "1"
 	}
 
+	stateFile := ps.Params[rssParameterStateFile]
+	if stateFile == "" {
+		stateFile = defaultStateFile
+	}
+	// the state file outlives the call, it is kept in the job working path
+	stateRoot := ps.JobWorkingPath
+	if stateRoot == "" {
+		stateRoot = ps.WorkingPath
+	}
+
+	concurrency := defaultConcurrency
+	if c, err := strconv.Atoi(ps.Params[rssParameterConcurrency]); err == nil && c > 0 {
+		concurrency = c
+	}
+
 	headers := make(map[string]string)
 	for k, v := range ps.Params {
 		if strings.HasPrefix(k, "header_") || strings.HasPrefix(k, "HEADER_") {
@@ -127,22 +314,31 @@ func NewRssPlugin(ps types.PluginCall) types.Plugin {
 
 	return &RssSourcePlugin{
 		logger:      logger.NewPluginLogger(RssSourcePluginName, ps.JobID),
-		fileRoot:    utils.NewFileAccess(ps.WorkingPath),
+		fileRoot:    utils.NewFileAccess(ps.WorkingPath, ps.JobWorkingPath),
+		stateRoot:   utils.NewFileAccess(stateRoot),
 		fileType:    fileType,
 		timeout:     timeout,
 		clutterFree: clutterFree,
 		headers:     headers,
+		stateFile:   stateFile,
+		concurrency: concurrency,
+		proxyURL:    ps.Params[rssParameterProxyURL],
+		relevance:   llmRelevance(agentic.PluginLLMConfig(ps.Config, RssSourcePluginName)),
+		jobID:       ps.JobID,
+		config:      ps.Config,
 	}
 }
 
 type Article struct {
-	FilePath  string `json:"file_path"`
-	Size      int64  `json:"size"`
-	Title     string `json:"title"`
-	URL       string `json:"url"`
-	SiteURL   string `json:"site_url"`
-	SiteName  string `json:"site_name"`
-	UpdatedAt string `json:"updated_at"`
+	FilePath  string         `json:"file_path"`
+	Size      int64          `json:"size"`
+	Title     string         `json:"title"`
+	URL       string         `json:"url"`
+	SiteURL   string         `json:"site_url"`
+	SiteName  string         `json:"site_name"`
+	UpdatedAt string         `json:"updated_at"`
+	Score     float64        `json:"score,omitempty"`
+	Document  types.Document `json:"document"`
 }
 
 func (r *RssSourcePlugin) Name() string {
@@ -158,6 +354,30 @@ func (r *RssSourcePlugin) Version() string {
 }
 
 func (r *RssSourcePlugin) Run(ctx context.Context, request *api.Request) (*api.Response, error) {
+	feeds, err := r.feedList(request)
+	if err != nil {
+		r.logger.Errorw("get rss feed list failed", "err", err)
+		return nil, err
+	}
+	fetchPolicy, err := web.ParseFetchPolicy(request)
+	if err != nil {
+		r.logger.Errorw("parse fetch policy failed", "err", err)
+		return nil, err
+	}
+	// shared by all feeds so requests to one host are throttled together
+	ctx = web.WithFetchPolicy(ctx, fetchPolicy)
+	if api.GetStringParameter(rssParameterRelevanceTopic, request, "") != "" {
+		budget, err := agentic.NewLLMBudget(r.jobID, r.config, request.Store)
+		if err != nil {
+			r.logger.Errorw("parse llm budget failed", "err", err)
+			return nil, err
+		}
+		ctx = agentic.WithLLMBudget(ctx, budget)
+	}
+	if len(feeds) > 0 {
+		return r.runFeeds(ctx, request, feeds)
+	}
+
 	source, err := r.rssSources(request)
 	if err != nil {
 		r.logger.Errorw("get rss source failed", "err", err)
@@ -165,23 +385,36 @@ func (r *RssSourcePlugin) Run(ctx context.Context, request *api.Request) (*api.R
 	}
 	r.logger.Infow("syncing rss", "feed", source.FeedUrl, "fileType", source.FileType)
 
-	articles, err := r.syncRssSource(ctx, source)
+	result, err := r.syncRssSource(ctx, source)
 	if err != nil {
 		r.logger.Warnw("sync rss failed", "source", source.FeedUrl, "err", err)
 		return api.NewFailedResponse(fmt.Sprintf("sync rss failed: %s", err)), nil
 	}
 
-	articleMaps := make([]map[string]interface{}, len(articles))
-	for i := range articles {
-		articleMaps[i] = utils.MarshalMap(articles[i])
+	articleMaps := make([]map[string]interface{}, len(result.Articles))
+	for i := range result.Articles {
+		articleMaps[i] = utils.MarshalMap(result.Articles[i])
 	}
 
-	resp := api.NewResponseWithResult(map[string]any{"articles": articleMaps})
+	results := map[string]any{
+		"articles": articleMaps,
+		"retried":  result.Metrics.Retried,
+		"failed":   result.Metrics.Failed,
+		"metrics":  utils.MarshalMap(result.Metrics),
+	}
+	if !result.Since.IsZero() {
+		results["since"] = result.Since.Format(time.RFC3339)
+	}
+	resp := api.NewResponseWithResult(results)
 	return resp, nil
 }
 
-func (r *RssSourcePlugin) rssSources(request *api.Request) (src rssSource, err error) {
-	src.FeedUrl = api.GetStringParameter(rssParameterFeed, request, "")
+func (r *RssSourcePlugin) rssSources(request *api.Request) (rssSource, error) {
+	return r.newRssSource(request, api.GetStringParameter(rssParameterFeed, request, ""))
+}
+
+func (r *RssSourcePlugin) newRssSource(request *api.Request, feedURL string) (src rssSource, err error) {
+	src.FeedUrl = feedURL
 	if src.FeedUrl == "" {
 		err = fmt.Errorf("feed url is empty")
 		return
@@ -193,15 +426,79 @@ func (r *RssSourcePlugin) rssSources(request *api.Request) (src rssSource, err e
 		return
 	}
 
+	src.Filter, err = parseItemFilter(request, time.Now())
+	if err != nil {
+		return
+	}
+	src.Scorer, err = parseItemScorer(request, r.relevance)
+	if err != nil {
+		return
+	}
+	src.MaxItems, err = parseMaxItems(request)
+	if err != nil {
+		return
+	}
+	src.Since, err = parseSince(request)
+	if err != nil {
+		return
+	}
+	src.Retry, err = parseRetryPolicy(request)
+	if err != nil {
+		return
+	}
+	src.FullContent, err = parseFullContent(request)
+	if err != nil {
+		return
+	}
+	src.Auth, err = parseFeedAuth(request, src.FeedUrl)
+	if err != nil {
+		return
+	}
+	src.Images, err = parseImageOption(request)
+	if err != nil {
+		return
+	}
+	src.NameTemplate, err = parseNameTemplate(request)
+	if err != nil {
+		return
+	}
+	src.BackfillPages, err = parseBackfillPages(request)
+	if err != nil {
+		return
+	}
+	dedup, err := parseContentDedup(request)
+	if err != nil {
+		return
+	}
+	if dedup {
+		src.Contents = contentHashes{}
+	}
+
 	src.FileType = r.fileType
+	src.Proxy, err = parseProxy(r.proxyURL, src.FileType)
+	if err != nil {
+		return
+	}
 	src.Timeout = r.timeout
+	src.Concurrency = r.concurrency
 	src.ClutterFree = r.clutterFree
 	src.Headers = r.headers
 	src.Store = request.Store
+	if src.Store == nil {
+		src.Store = newFileStore(r.stateRoot, r.stateFile)
+	}
 	return
 }
 
-func (r *RssSourcePlugin) syncRssSource(ctx context.Context, source rssSource) ([]Article, error) {
+// syncResult is the outcome of syncing one feed. Metrics.Retried counts articles that needed
+// more than one fetch attempt, Metrics.Failed those left for the next run.
+type syncResult struct {
+	Articles []Article
+	Since    time.Time
+	Metrics  syncMetrics
+}
+
+func (r *RssSourcePlugin) syncRssSource(ctx context.Context, source rssSource) (*syncResult, error) {
 	var nowTime = time.Now()
 	siteURL, err := parseSiteURL(source.FeedUrl)
 	if err != nil {
@@ -209,21 +506,51 @@ func (r *RssSourcePlugin) syncRssSource(ctx context.Context, source rssSource) (
 		return nil, err
 	}
 
+	since := source.Since
+	if since.IsZero() {
+		since = source.loadCursor(ctx)
+	}
+
 	fp := gofeed.NewParser()
-	feed, err := fp.ParseURLWithContext(source.FeedUrl, ctx)
+	fp.JSONTranslator = &jsonFeedTranslator{}
+	feed, cache, prev, err := fetchFeed(ctx, fp, source, source.FeedUrl, source.loadFeedCache(ctx))
 	if err != nil {
 		return nil, err
 	}
+	if feed == nil {
+		r.logger.Infow("feed not modified, skip", "feed", source.FeedUrl)
+		return &syncResult{Articles: []Article{}, Since: since, Metrics: syncMetrics{NotModified: true}}, nil
+	}
 
 	var (
-		articles = make([]Article, 0)
-		links    []string
+		result  = &syncResult{Articles: make([]Article, 0)}
+		metrics = &result.Metrics
+		links   []string
+		guids   []string
+		hashes  = make(map[*gofeed.Item]string)
+		records = make(map[string]string)
+		// hashes of this feed's candidates, the run-wide Contents only holds packed articles
+		selectedHashes = make(map[string]struct{})
+		newest         = since
+
+		backfilled, backfillComplete bool
 	)
 
-	for i, item := range feed.Items {
-		if i > rssPostMaxCollect {
-			r.logger.Infow("soo many post need to collect, skip", "collectLimit", rssPostMaxCollect)
-			break
+	if prev != "" && source.needsBackfill(ctx) {
+		backfilled = true
+		metrics.Pages, backfillComplete = r.backfill(ctx, fp, source, feed, prev)
+	}
+	metrics.Seen = len(feed.Items)
+	candidates := make([]*gofeed.Item, 0, len(feed.Items))
+	for _, item := range feed.Items {
+		if !source.Filter.match(item) {
+			r.logger.Debugw("rss post filtered", "title", item.Title)
+			metrics.Filtered++
+			continue
+		}
+		if published := itemPublished(item); published != nil && published.Before(since) {
+			metrics.Skipped++
+			continue
 		}
 
 		item.Link = absoluteURL(siteURL, item.Link)
@@ -231,55 +558,93 @@ func (r *RssSourcePlugin) syncRssSource(ctx context.Context, source rssSource) (
 			item.Content = item.Description
 		}
 
-		if isNew, err := source.isNew(ctx, item.Link); err != nil || !isNew {
+		if isNew, err := source.isNew(ctx, item.Link, item.GUID); err != nil || !isNew {
 			if err != nil {
 				r.logger.Errorw("check if feed is new", "feed", source.FeedUrl, "err", err)
+				metrics.fail(source.FeedUrl, item.Title, item.Link, fmt.Errorf("check archived records failed: %w", err))
+			} else {
+				metrics.Skipped++
 			}
 			continue
 		}
-
-		r.logger.Infow("parse rss post", "link", item.Link)
-
-		fileName := utils.SanitizeFilename(item.Title)
-		switch source.FileType {
-		case archiveFileTypeUrl:
-			fileName += ".url"
-			buf := bytes.Buffer{}
-			buf.WriteString("[InternetShortcut]")
-			buf.WriteString("\n")
-			buf.WriteString(fmt.Sprintf("URL=%s", item.Link))
-
-			err = r.fileRoot.Write(fileName, buf.Bytes(), 0655)
+		hash := contentHash(item.Title, item.Content)
+		if _, selected := selectedHashes[hash]; selected && source.Contents != nil {
+			r.logger.Debugw("rss post content already selected", "title", item.Title, "link", item.Link)
+			metrics.Skipped++
+			continue
+		}
+		if dup, err := source.isDuplicate(ctx, hash); err != nil || dup {
 			if err != nil {
-				return nil, fmt.Errorf("pack to url file failed: %s", err)
+				r.logger.Errorw("check if content is new", "feed", source.FeedUrl, "err", err)
+				metrics.fail(source.FeedUrl, item.Title, item.Link, fmt.Errorf("check archived records failed: %w", err))
+			} else {
+				r.logger.Debugw("rss post content already archived", "title", item.Title, "link", item.Link)
+				metrics.Skipped++
 			}
+			continue
+		}
+		if hash != "" {
+			hashes[item] = hash
+			selectedHashes[hash] = struct{}{}
+		}
+		candidates = append(candidates, item)
+	}
 
-		case archiveFileTypeHtml:
-			fileName += ".html"
-			htmlContent := readableHtmlContent(item.Link, item.Title, item.Content)
-			err = r.fileRoot.Write(fileName, []byte(htmlContent), 0655)
-			if err != nil {
-				return nil, fmt.Errorf("pack to html file failed: %s", err)
-			}
+	scores := make(map[*gofeed.Item]float64)
+	if source.Scorer != nil {
+		ranked := len(candidates)
+		candidates, scores = source.Scorer.rank(ctx, candidates, nowTime)
+		metrics.Filtered += ranked - len(candidates)
+	}
 
-		case archiveFileTypeRawHtml:
-			filePath, err := web.PackFromURL(logger.IntoContext(ctx, r.logger), fileName, item.Link, "html", r.fileRoot.Workdir(), source.ClutterFree, source.toOption())
-			if err != nil {
-				r.logger.Warnw("pack to raw html file failed", "link", item.Link, "err", err)
-				continue
-			}
-			fileName = path.Base(filePath)
+	truncated := len(candidates) > source.MaxItems
+	if truncated {
+		r.logger.Infow("soo many post need to collect, skip", "collectLimit", source.MaxItems, "candidates", len(candidates))
+		if source.Scorer == nil {
+			oldestFirst(candidates)
+		}
+		metrics.Deferred = len(candidates) - source.MaxItems
+		candidates = candidates[:source.MaxItems]
+	}
 
-		case archiveFileTypeWebArchive:
-			filePath, err := web.PackFromURL(logger.IntoContext(ctx, r.logger), fileName, item.Link, "webarchive", r.fileRoot.Workdir(), source.ClutterFree, source.toOption())
-			if err != nil {
-				r.logger.Warnw("pack to webarchive failed", "link", item.Link, "err", err)
-				continue
-			}
-			fileName = path.Base(filePath)
+	var (
+		outcomes = make([]packOutcome, len(candidates))
+		packErrs = make([]error, len(candidates))
+		wg       sync.WaitGroup
+		workers  = make(chan struct{}, max(source.Concurrency, 1))
+	)
+	for i, item := range candidates {
+		wg.Add(1)
+		workers <- struct{}{}
+		go func(i int, item *gofeed.Item) {
+			defer func() {
+				<-workers
+				wg.Done()
+			}()
+			outcomes[i], packErrs[i] = r.packItem(ctx, source, item)
+		}(i, item)
+	}
+	wg.Wait()
 
-		default:
-			return nil, fmt.Errorf("unknown rss archive file type %s", source.FileType)
+	for i, item := range candidates {
+		if packErrs[i] != nil {
+			return nil, packErrs[i]
+		}
+		outcome := outcomes[i]
+		if outcome.Retries > 0 {
+			metrics.Retried++
+		}
+		if outcome.Fetched {
+			metrics.Fetched++
+		}
+		if outcome.Failure != nil {
+			metrics.fail(source.FeedUrl, item.Title, item.Link, outcome.Failure)
+			continue
+		}
+		fileName := outcome.FileName
+		articleContent := outcome.Content
+		if articleContent == "" {
+			articleContent = item.Content
 		}
 
 		fInfo, err := r.fileRoot.Stat(fileName)
@@ -299,8 +664,19 @@ func (r *RssSourcePlugin) syncRssSource(ctx context.Context, source rssSource) (
 			updatedAt = &nowTime
 		}
 
+		if published := itemPublished(item); published != nil && published.After(newest) {
+			newest = *published
+		}
 		links = append(links, item.Link)
-		articles = append(articles, Article{
+		if item.GUID != "" {
+			guids = append(guids, item.GUID)
+		}
+		if hash, ok := hashes[item]; ok {
+			records[hash] = item.Link
+			source.markContent(hash)
+		}
+		metrics.Packed++
+		result.Articles = append(result.Articles, Article{
 			FilePath:  fileName,
 			Size:      fInfo.Size(),
 			Title:     item.Title,
@@ -308,16 +684,143 @@ func (r *RssSourcePlugin) syncRssSource(ctx context.Context, source rssSource) (
 			SiteURL:   feed.Link,
 			SiteName:  feed.Title,
 			UpdatedAt: updatedAt.Format(time.RFC3339),
+			Score:     scores[item],
+			Document:  articleDocument(feed, item, articleContent),
 		})
 	}
 
 	if err = source.record(ctx, links...); err != nil {
 		r.logger.Warnw("record links failed", "err", err)
 	}
+	if err = source.recordGUIDs(ctx, guids...); err != nil {
+		r.logger.Warnw("record guids failed", "err", err)
+	}
+	if err = source.recordContents(ctx, records); err != nil {
+		r.logger.Warnw("record content hashes failed", "err", err)
+	}
+	// keep refetching the full feed until every item has been archived
+	if metrics.Failed == 0 && !truncated {
+		if err = source.saveFeedCache(ctx, cache); err != nil {
+			r.logger.Warnw("save feed cache failed", "err", err)
+		}
+		if backfilled && backfillComplete {
+			if err = source.saveBackfill(ctx, metrics.Pages); err != nil {
+				r.logger.Warnw("save feed backfill failed", "err", err)
+			}
+		}
+	}
+	// ranked items left over by max_items may be older than the newest archived one
+	if metrics.Failed == 0 && (!truncated || source.Scorer == nil) && newest.After(since) {
+		if err = source.saveCursor(ctx, newest); err != nil {
+			r.logger.Warnw("save feed cursor failed", "err", err)
+		}
+		since = newest
+	}
+
+	result.Since = since
+
+	r.logger.Infow("sync rss finish", "entries", len(result.Articles), "seen", metrics.Seen, "filtered", metrics.Filtered,
+		"skipped", metrics.Skipped, "deferred", metrics.Deferred, "retried", metrics.Retried, "failed", metrics.Failed)
+	return result, nil
+}
+
+// packOutcome is the result of archiving one item. Content is the article HTML when it was
+// taken from the feed or the full content page rather than archived from the link. Fetched is set when the article page was
+// downloaded, Failure when fetching it still failed after retrying, so the item is left for the next run.
+type packOutcome struct {
+	FileName string
+	Content  string
+	Retries  int
+	Fetched  bool
+	Failure  error
+}
+
+// packItem archives one item, see packOutcome.
+func (r *RssSourcePlugin) packItem(ctx context.Context, source rssSource, item *gofeed.Item) (outcome packOutcome, err error) {
+	r.logger.Infow("parse rss post", "link", item.Link)
+
+	fileName, err := source.articleName(item, time.Now())
+	if err != nil {
+		outcome.Failure = err
+		return outcome, nil
+	}
+	baseName := fileName
+	switch source.FileType {
+	case archiveFileTypeUrl:
+		fileName += ".url"
+		buf := bytes.Buffer{}
+		buf.WriteString("[InternetShortcut]")
+		buf.WriteString("\n")
+		buf.WriteString(fmt.Sprintf("URL=%s", item.Link))
+
+		err = r.fileRoot.Write(fileName, buf.Bytes(), 0655)
+		if err != nil {
+			return outcome, fmt.Errorf("pack to url file failed: %s", err)
+		}
+
+	case archiveFileTypeHtml:
+		fileName += ".html"
+		var content string
+		content, outcome.Retries, outcome.Fetched = r.fullContent(ctx, source, item)
+		outcome.Content = content
+		if source.Images.Enabled {
+			content, _ = r.localizeImages(ctx, source, item.Link, baseName, content, false, relativeImageSrc)
+		}
+		htmlContent := readableHtmlContent(item.Link, item.Title, content)
+		err = r.fileRoot.Write(fileName, []byte(htmlContent), 0655)
+		if err != nil {
+			return outcome, fmt.Errorf("pack to html file failed: %s", err)
+		}
+
+	case archiveFileTypeMarkdown:
+		fileName += ".md"
+		var (
+			content, markdown string
+			images            []string
+		)
+		content, outcome.Retries, outcome.Fetched = r.fullContent(ctx, source, item)
+		outcome.Content = content
+		if source.Images.Enabled {
+			content, images = r.localizeImages(ctx, source, item.Link, baseName, content, false, placeholderImageSrc)
+		}
+		markdown, err = markdownContent(item, content)
+		if err != nil {
+			return outcome, fmt.Errorf("convert to markdown failed: %s", err)
+		}
+		markdown = localizeMarkdownImages(markdown, images)
+		err = r.fileRoot.Write(fileName, []byte(markdown), 0655)
+		if err != nil {
+			return outcome, fmt.Errorf("pack to markdown file failed: %s", err)
+		}
 
-	r.logger.Infow("sync rss finish", "entries", len(articles))
+	case archiveFileTypeRawHtml, archiveFileTypeWebArchive:
+		packType := "webarchive"
+		if source.FileType == archiveFileTypeRawHtml {
+			packType = "html"
+		}
+		var filePath string
+		outcome.Retries, err = source.Retry.do(ctx, func() (packErr error) {
+			filePath, packErr = source.pack(logger.IntoContext(ctx, r.logger), fileName, item.Link, packType, r.fileRoot.Workdir(), source.ClutterFree)
+			return packErr
+		})
+		if err != nil {
+			r.logger.Warnw("pack rss post failed", "link", item.Link, "fileType", source.FileType, "retries", outcome.Retries, "err", err)
+			outcome.Failure = err
+			return outcome, nil
+		}
+		outcome.Fetched = true
+		fileName = path.Base(filePath)
+		if source.Images.Enabled && packType == "html" {
+			if err = r.localizePackedImages(ctx, source, item.Link, baseName, fileName); err != nil {
+				return outcome, err
+			}
+		}
 
-	return articles, nil
+	default:
+		return outcome, fmt.Errorf("unknown rss archive file type %s", source.FileType)
+	}
+	outcome.FileName = fileName
+	return outcome, nil
 }
 
 func parseSiteURL(feed string) (string, error) {
@@ -337,18 +840,49 @@ func absoluteURL(sitURL, link string) string {
 }
 
 type rssSource struct {
-	FeedUrl     string
-	FileType    string
-	ClutterFree bool
-	Timeout     int
-	Headers     map[string]string
+	FeedUrl       string
+	FileType      string
+	ClutterFree   bool
+	Timeout       int
+	Concurrency   int
+	Headers       map[string]string
+	Filter        *itemFilter
+	Scorer        *itemScorer
+	MaxItems      int
+	Since         time.Time
+	Retry         retryPolicy
+	FullContent   fullContentOption
+	Auth          *feedAuth
+	Proxy         *url.URL
+	Images        imageOption
+	Contents      contentHashes
+	BackfillPages int
+	NameTemplate  *template.Template
 
 	Store api.PersistentStore
 }
 
-func (s *rssSource) isNew(ctx context.Context, linkStr string) (bool, error) {
+func (s *rssSource) isNew(ctx context.Context, linkStr, guid string) (bool, error) {
+	key := utils.CanonicalURL(linkStr)
+	isNew, err := s.isNewKey(ctx, key)
+	if err != nil || !isNew {
+		return isNew, err
+	}
+	if key != linkStr {
+		// records created before url normalization are keyed by the raw link
+		if isNew, err = s.isNewKey(ctx, linkStr); err != nil || !isNew {
+			return isNew, err
+		}
+	}
+	if guid != "" {
+		return s.isNewKey(ctx, guidKey(guid))
+	}
+	return true, nil
+}
+
+func (s *rssSource) isNewKey(ctx context.Context, key string) (bool, error) {
 	var v = make(map[string]any)
-	err := s.Store.Load(ctx, RssSourcePluginName, "articles", linkStr, &v)
+	err := s.Store.Load(ctx, RssSourcePluginName, "articles", key, &v)
 	if err == nil {
 		return false, nil
 	}
@@ -367,7 +901,7 @@ func (s *rssSource) isNew(ctx context.Context, linkStr string) (bool, error) {
 func (s *rssSource) record(ctx context.Context, linkList ...string) error {
 	for _, linkStr := range linkList {
 		v := map[string]string{"link": linkStr, "time": time.Now().Format(time.RFC3339)}
-		err := s.Store.Save(ctx, RssSourcePluginName, "articles", linkStr, &v)
+		err := s.Store.Save(ctx, RssSourcePluginName, "articles", utils.CanonicalURL(linkStr), &v)
 		if err != nil {
 			return err
 		}
@@ -375,10 +909,35 @@ func (s *rssSource) record(ctx context.Context, linkList ...string) error {
 	return nil
 }
 
+func (s *rssSource) recordGUIDs(ctx context.Context, guids ...string) error {
+	for _, guid := range guids {
+		v := map[string]string{"guid": guid, "time": time.Now().Format(time.RFC3339)}
+		err := s.Store.Save(ctx, RssSourcePluginName, "articles", guidKey(guid), &v)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func guidKey(guid string) string {
+	return "guid:" + guid
+}
+
+// articleOptions adds the feed credential for links on the feed host.
+func (s *rssSource) articleOptions(link string) []web.Option {
+	options := []web.Option{s.toOption()}
+	if opt := s.Auth.option(link); opt != nil {
+		options = append(options, opt)
+	}
+	return options
+}
+
 func (s *rssSource) toOption() web.Option {
 	return func(option *packer.Option) {
 		option.Timeout = s.Timeout
 		option.ClutterFree = s.ClutterFree
-		option.Headers = s.Headers
+		// the credential of feed host articles is added to the headers, each article gets its own copy
+		option.Headers = maps.Clone(s.Headers)
 	}
 }
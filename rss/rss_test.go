@@ -17,7 +17,14 @@
 package rss
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/basenana/plugin/api"
@@ -207,6 +214,54 @@ func TestNewRssPlugin_CustomTimeout(t *testing.T) {
 	}
 }
 
+func TestNewRssPlugin_Concurrency(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected int
+	}{
+		{"", defaultConcurrency},
+		{"8", 8},
+		{"0", defaultConcurrency},
+		{"many", defaultConcurrency},
+	}
+	for _, tt := range tests {
+		p := newRssPluginWithWorkdir(testWorkDir, map[string]string{rssParameterConcurrency: tt.value})
+		if p.concurrency != tt.expected {
+			t.Errorf("concurrency %q: expected %d, got %d", tt.value, tt.expected, p.concurrency)
+		}
+	}
+}
+
+func TestRssPlugin_Run_ConcurrentPacking(t *testing.T) {
+	var items strings.Builder
+	for i := 0; i < 20; i++ {
+		fmt.Fprintf(&items, "<item><title>Post %02d</title><link>https://example.com/%d</link><description>body %d</description></item>", i, i, i)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		_, _ = fmt.Fprintf(w, `<?xml version="1.0"?><rss version="2.0"><channel><title>T</title><link>https://example.com/</link>%s</channel></rss>`, items.String())
+	}))
+	defer server.Close()
+
+	p := newRssPluginWithWorkdir(t.TempDir(), map[string]string{"file_type": "html", rssParameterConcurrency: "8"})
+	resp, err := p.Run(context.Background(), &api.Request{
+		Parameter: map[string]any{"feed": server.URL},
+		Store:     newMemStore(),
+	})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	articles := resp.Results["articles"].([]map[string]interface{})
+	if len(articles) != 20 {
+		t.Fatalf("expected 20 articles, got %d", len(articles))
+	}
+	for i, a := range articles {
+		if a["title"] != fmt.Sprintf("Post %02d", i) {
+			t.Errorf("expected feed order to be kept, article %d is %v", i, a["title"])
+		}
+	}
+}
+
 func TestNewRssPlugin_DefaultClutterFree(t *testing.T) {
 	p := newRssPluginWithWorkdir(testWorkDir, map[string]string{})
 
@@ -319,3 +374,77 @@ func TestParseSiteURL_InvalidURL(t *testing.T) {
 		t.Log("parseSiteURL handles invalid-looking URLs gracefully")
 	}
 }
+
+type memStore struct {
+	mux  sync.Mutex
+	data map[string][]byte
+}
+
+func newMemStore() *memStore {
+	return &memStore{data: map[string][]byte{}}
+}
+
+func (m *memStore) Load(ctx context.Context, source, group, key string, data any) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	raw, ok := m.data[source+"/"+group+"/"+key]
+	if !ok {
+		return fmt.Errorf("no record")
+	}
+	return json.Unmarshal(raw, data)
+}
+
+func (m *memStore) Save(ctx context.Context, source, group, key string, data any) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	m.data[source+"/"+group+"/"+key] = raw
+	return nil
+}
+
+func TestRssSource_DedupWithNormalizedURL(t *testing.T) {
+	ctx := context.Background()
+	source := rssSource{Store: newMemStore()}
+
+	if err := source.record(ctx, "https://Example.com/post/1?utm_source=rss#top"); err != nil {
+		t.Fatalf("record failed: %v", err)
+	}
+
+	isNew, err := source.isNew(ctx, "https://example.com/post/1", "")
+	if err != nil {
+		t.Fatalf("isNew failed: %v", err)
+	}
+	if isNew {
+		t.Error("expected normalized link to be recognized as seen")
+	}
+
+	isNew, err = source.isNew(ctx, "https://example.com/post/2", "")
+	if err != nil {
+		t.Fatalf("isNew failed: %v", err)
+	}
+	if !isNew {
+		t.Error("expected different link to be new")
+	}
+}
+
+func TestRssSource_DedupLegacyRawKey(t *testing.T) {
+	ctx := context.Background()
+	store := newMemStore()
+	source := rssSource{Store: store}
+
+	legacy := "https://Example.com/post/1"
+	if err := store.Save(ctx, RssSourcePluginName, "articles", legacy, map[string]string{"link": legacy}); err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+
+	isNew, err := source.isNew(ctx, legacy, "")
+	if err != nil {
+		t.Fatalf("isNew failed: %v", err)
+	}
+	if isNew {
+		t.Error("expected link recorded under raw key to be recognized as seen")
+	}
+}
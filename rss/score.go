@@ -0,0 +1,204 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package rss
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/basenana/friday/core/providers/openai"
+	fridaytypes "github.com/basenana/friday/core/types"
+	"github.com/basenana/plugin/agentic"
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/utils"
+	"github.com/mmcdole/gofeed"
+)
+
+const (
+	rssParameterScoreKeywords  = "score_keywords"
+	rssParameterSourceWeight   = "source_weight"
+	rssParameterRecencyWindow  = "recency_window"
+	rssParameterRelevanceTopic = "relevance_topic"
+	rssParameterMinScore       = "min_score"
+
+	defaultRecencyWindow = 7 * 24 * time.Hour
+
+	relevancePrompt = `You rate how relevant an article is to a topic.
+Reply with a single integer from 0 (unrelated) to 10 (highly relevant) and nothing else.`
+)
+
+var relevanceNumberRegex = regexp.MustCompile(`\d+(\.\d+)?`)
+
+// relevanceFunc returns the relevance of an article to the topic in the range [0, 1].
+type relevanceFunc func(ctx context.Context, topic, title, summary string) (float64, error)
+
+type itemScorer struct {
+	keywords      map[string]float64
+	sourceWeight  float64
+	recencyWindow time.Duration
+	topic         string
+	minScore      float64
+	hasMinScore   bool
+	relevance     relevanceFunc
+}
+
+// parseItemScorer returns nil when no scoring parameter is set, so feeds keep their original order.
+func parseItemScorer(request *api.Request, relevance relevanceFunc) (*itemScorer, error) {
+	var (
+		s = &itemScorer{
+			sourceWeight:  1,
+			recencyWindow: defaultRecencyWindow,
+			relevance:     relevance,
+		}
+		enabled bool
+	)
+
+	if raw := api.GetStringParameter(rssParameterScoreKeywords, request, ""); raw != "" {
+		keywords := make(map[string]float64)
+		if err := json.Unmarshal([]byte(raw), &keywords); err != nil {
+			return nil, fmt.Errorf("parse score_keywords failed: %s", err)
+		}
+		s.keywords = make(map[string]float64, len(keywords))
+		for k, w := range keywords {
+			if k = strings.ToLower(strings.TrimSpace(k)); k != "" {
+				s.keywords[k] = w
+			}
+		}
+		enabled = true
+	}
+	if raw := api.GetStringParameter(rssParameterSourceWeight, request, ""); raw != "" {
+		w, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("parse source_weight [%s] failed: %s", raw, err)
+		}
+		s.sourceWeight = w
+		enabled = true
+	}
+	if raw := api.GetStringParameter(rssParameterRecencyWindow, request, ""); raw != "" {
+		d, err := time.ParseDuration(raw)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("parse recency_window [%s] failed: expect positive duration", raw)
+		}
+		s.recencyWindow = d
+		enabled = true
+	}
+	if topic := api.GetStringParameter(rssParameterRelevanceTopic, request, ""); topic != "" {
+		s.topic = topic
+		enabled = true
+	}
+	if raw := api.GetStringParameter(rssParameterMinScore, request, ""); raw != "" {
+		m, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("parse min_score [%s] failed: %s", raw, err)
+		}
+		s.minScore = m
+		s.hasMinScore = true
+		enabled = true
+	}
+
+	if !enabled {
+		return nil, nil
+	}
+	return s, nil
+}
+
+// score is source_weight * (matched keyword weights + recency + relevance).
+func (s *itemScorer) score(ctx context.Context, item *gofeed.Item, now time.Time) float64 {
+	var total float64
+
+	text := strings.ToLower(item.Title + "\n" + item.Description)
+	for k, w := range s.keywords {
+		if strings.Contains(text, k) {
+			total += w
+		}
+	}
+
+	if published := itemPublished(item); published != nil {
+		recency := 1 - float64(now.Sub(*published))/float64(s.recencyWindow)
+		total += clamp01(recency)
+	}
+
+	if s.topic != "" && s.relevance != nil {
+		summary := utils.GenerateContentAbstract(item.Content)
+		if relevance, err := s.relevance(ctx, s.topic, item.Title, summary); err == nil {
+			total += clamp01(relevance)
+		}
+	}
+
+	return s.sourceWeight * total
+}
+
+// rank drops items below min_score and sorts the rest by score, highest first.
+func (s *itemScorer) rank(ctx context.Context, items []*gofeed.Item, now time.Time) ([]*gofeed.Item, map[*gofeed.Item]float64) {
+	scores := make(map[*gofeed.Item]float64, len(items))
+	ranked := make([]*gofeed.Item, 0, len(items))
+	for _, item := range items {
+		score := s.score(ctx, item, now)
+		if s.hasMinScore && score < s.minScore {
+			continue
+		}
+		scores[item] = score
+		ranked = append(ranked, item)
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return scores[ranked[i]] > scores[ranked[j]]
+	})
+	return ranked, scores
+}
+
+func llmRelevance(config map[string]string) relevanceFunc {
+	return func(ctx context.Context, topic, title, summary string) (float64, error) {
+		llm, err := agentic.NewLLMClient(config)
+		if err != nil {
+			return 0, err
+		}
+		message := fmt.Sprintf("Topic: %s\n\nTitle: %s\n\nSummary: %s", topic, title, summary)
+		reply, err := llm.CompletionNonStreaming(ctx, openai.NewSimpleRequest(relevancePrompt, fridaytypes.Message{UserMessage: message}))
+		if err != nil {
+			return 0, err
+		}
+		return parseRelevance(reply)
+	}
+}
+
+func parseRelevance(reply string) (float64, error) {
+	num := relevanceNumberRegex.FindString(reply)
+	if num == "" {
+		return 0, fmt.Errorf("no relevance score in reply: %s", reply)
+	}
+	score, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return 0, err
+	}
+	return clamp01(score / 10), nil
+}
+
+func clamp01(v float64) float64 {
+	if v < 0 {
+		return 0
+	}
+	if v > 1 {
+		return 1
+	}
+	return v
+}
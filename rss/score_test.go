@@ -0,0 +1,172 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package rss
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/basenana/plugin/api"
+	"github.com/mmcdole/gofeed"
+)
+
+func TestParseItemScorer_Disabled(t *testing.T) {
+	s, err := parseItemScorer(&api.Request{Parameter: map[string]any{}}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s != nil {
+		t.Errorf("expected no scorer without scoring parameters")
+	}
+}
+
+func TestParseItemScorer_Invalid(t *testing.T) {
+	for _, params := range []map[string]any{
+		{"score_keywords": "go"},
+		{"source_weight": "heavy"},
+		{"recency_window": "-1h"},
+		{"min_score": "high"},
+	} {
+		if _, err := parseItemScorer(&api.Request{Parameter: params}, nil); err == nil {
+			t.Errorf("expected error for %v", params)
+		}
+	}
+}
+
+func TestItemScorer_Score(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	halfDay := now.Add(-12 * time.Hour)
+
+	s, err := parseItemScorer(&api.Request{Parameter: map[string]any{
+		"score_keywords":  `{"Kubernetes": 2, "release": 0.5}`,
+		"source_weight":   "2",
+		"recency_window":  "24h",
+		"relevance_topic": "cloud native",
+	}}, func(ctx context.Context, topic, title, summary string) (float64, error) {
+		if topic != "cloud native" {
+			t.Errorf("unexpected topic %q", topic)
+		}
+		return 0.25, nil
+	})
+	if err != nil {
+		t.Fatalf("parse scorer failed: %v", err)
+	}
+
+	item := &gofeed.Item{Title: "Kubernetes 1.30", Description: "New release notes", PublishedParsed: &halfDay}
+	// 2 * (2 + 0.5 + 0.5 recency + 0.25 relevance)
+	if got := s.score(context.Background(), item, now); math.Abs(got-6.5) > 1e-9 {
+		t.Errorf("score = %v, want 6.5", got)
+	}
+}
+
+func TestItemScorer_Rank(t *testing.T) {
+	now := time.Now()
+	s, err := parseItemScorer(&api.Request{Parameter: map[string]any{
+		"score_keywords": `{"go": 1, "generics": 2}`,
+		"min_score":      "1",
+	}}, nil)
+	if err != nil {
+		t.Fatalf("parse scorer failed: %v", err)
+	}
+
+	items := []*gofeed.Item{
+		{Title: "Go news"},
+		{Title: "Cooking"},
+		{Title: "Go generics deep dive"},
+	}
+	ranked, scores := s.rank(context.Background(), items, now)
+	if len(ranked) != 2 {
+		t.Fatalf("expected 2 items above min_score, got %d", len(ranked))
+	}
+	if ranked[0].Title != "Go generics deep dive" || scores[ranked[0]] != 3 {
+		t.Errorf("expected highest score first, got %q (%v)", ranked[0].Title, scores[ranked[0]])
+	}
+}
+
+func TestParseRelevance(t *testing.T) {
+	tests := []struct {
+		reply string
+		want  float64
+	}{
+		{"7", 0.7},
+		{"Score: 10", 1},
+		{"15", 1},
+	}
+	for _, tt := range tests {
+		got, err := parseRelevance(tt.reply)
+		if err != nil {
+			t.Fatalf("parseRelevance(%q) failed: %v", tt.reply, err)
+		}
+		if math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("parseRelevance(%q) = %v, want %v", tt.reply, got, tt.want)
+		}
+	}
+	if _, err := parseRelevance("not relevant"); err == nil {
+		t.Errorf("expected error without a number")
+	}
+}
+
+func TestRssPlugin_Run_WithScoring(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		_, _ = w.Write([]byte(`<?xml version="1.0"?>
+<rss version="2.0"><channel><title>T</title><link>https://example.com/</link>
+<item><title>Cooking recipes</title><link>https://example.com/1</link></item>
+<item><title>Kubernetes tips</title><link>https://example.com/2</link></item>
+<item><title>Kubernetes operators</title><link>https://example.com/3</link></item>
+</channel></rss>`))
+	}))
+	defer server.Close()
+
+	p := newRssPluginWithWorkdir(t.TempDir(), map[string]string{"file_type": "url"})
+	p.relevance = func(ctx context.Context, topic, title, summary string) (float64, error) {
+		if strings.Contains(title, "operators") {
+			return 1, nil
+		}
+		return 0, nil
+	}
+	store := newMemStore()
+	resp, err := p.Run(context.Background(), &api.Request{
+		Parameter: map[string]any{
+			"feed":            server.URL,
+			"score_keywords":  `{"kubernetes": 1}`,
+			"relevance_topic": "platform engineering",
+			"min_score":       "1",
+		},
+		Store: store,
+	})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	articles := resp.Results["articles"].([]map[string]interface{})
+	if len(articles) != 2 {
+		t.Fatalf("expected 2 articles above min_score, got %v", articles)
+	}
+	if articles[0]["title"] != "Kubernetes operators" || articles[0]["score"] != float64(2) {
+		t.Errorf("expected highest scored article first, got %v", articles[0])
+	}
+
+	source := &rssSource{Store: store}
+	if isNew, _ := source.isNew(context.Background(), "https://example.com/1", ""); !isNew {
+		t.Errorf("items below min_score should not be recorded")
+	}
+}
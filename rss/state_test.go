@@ -0,0 +1,103 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package rss
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/utils"
+)
+
+const testRssFeedTpl = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0">
+<channel>
+  <title>Example</title>
+  <link>https://example.com/</link>
+  <item>
+    <title>Post One</title>
+    <link>https://example.com/posts/1%s</link>
+    <guid>post-1</guid>
+    <description>First post</description>
+  </item>
+</channel>
+</rss>`
+
+func TestFileStore_LoadSave(t *testing.T) {
+	ctx := context.Background()
+	fileRoot := utils.NewFileAccess(t.TempDir())
+	store := newFileStore(fileRoot, defaultStateFile)
+
+	var v map[string]string
+	if err := store.Load(ctx, "rss", "articles", "missing", &v); err == nil {
+		t.Fatal("expected error for missing record")
+	}
+
+	if err := store.Save(ctx, "rss", "articles", "k1", map[string]string{"link": "a"}); err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+
+	reopened := newFileStore(fileRoot, defaultStateFile)
+	if err := reopened.Load(ctx, "rss", "articles", "k1", &v); err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	if v["link"] != "a" {
+		t.Errorf("expected link a, got %v", v)
+	}
+}
+
+func TestRssPlugin_DedupAcrossRuns(t *testing.T) {
+	var suffix atomic.Value
+	suffix.Store("")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		_, _ = fmt.Fprintf(w, testRssFeedTpl, suffix.Load().(string))
+	}))
+	defer server.Close()
+
+	workDir := t.TempDir()
+	run := func() int {
+		p := newRssPluginWithWorkdir(workDir, map[string]string{"file_type": "url"})
+		resp, err := p.Run(context.Background(), &api.Request{
+			Parameter: map[string]any{"feed": server.URL + "/feed.xml"},
+		})
+		if err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		if !resp.IsSucceed {
+			t.Fatalf("Run not succeed: %s", resp.Message)
+		}
+		return len(resp.Results["articles"].([]map[string]interface{}))
+	}
+
+	if n := run(); n != 1 {
+		t.Fatalf("expected 1 article on first run, got %d", n)
+	}
+	if n := run(); n != 0 {
+		t.Errorf("expected no articles on second run, got %d", n)
+	}
+
+	suffix.Store("?ref=changed")
+	if n := run(); n != 0 {
+		t.Errorf("expected item with known guid to be skipped, got %d", n)
+	}
+}
@@ -0,0 +1,41 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package plugin
+
+import (
+	"github.com/basenana/plugin/sandbox"
+	"github.com/basenana/plugin/types"
+)
+
+// WithSandbox applies the config to every binary run by plugins: an allowlist on top of the
+// binaries each plugin declares, time, CPU and memory limits, and workspace-only mounts.
+// Without it, plugins may still only run the binaries they declare.
+func WithSandbox(config sandbox.Config) Option {
+	return func(m *manager) {
+		m.sandbox = &config
+	}
+}
+
+func (m *manager) commandRunner(spec types.PluginSpec, ps types.PluginCall) types.CommandRunner {
+	var binaries []string
+	for _, dep := range spec.Dependencies {
+		if dep.Kind == types.DependencyBinary {
+			binaries = append(binaries, dep.Name)
+		}
+	}
+	return sandbox.New(m.sandbox, ps.WorkingPath, binaries)
+}
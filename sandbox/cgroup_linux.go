@@ -0,0 +1,106 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package sandbox
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+)
+
+const (
+	cpuPeriod = 100000
+
+	// cgroupLimitsEnforced reports that CgroupRoot applies the CPU and memory limits.
+	cgroupLimitsEnforced = true
+)
+
+var cgroupSeq atomic.Int64
+
+// cgroup is a cgroup v2 group holding a single command, the process is started inside it.
+type cgroup struct {
+	dir string
+	fd  *os.File
+}
+
+func newCgroup(config Config, name string) (*cgroup, error) {
+	dir := filepath.Join(config.CgroupRoot, fmt.Sprintf("%s-%d-%d", name, os.Getpid(), cgroupSeq.Add(1)))
+	if err := os.Mkdir(dir, 0755); err != nil {
+		return nil, err
+	}
+	cg := &cgroup{dir: dir}
+
+	limits := map[string]string{}
+	if config.MemoryBytes > 0 {
+		limits["memory.max"] = strconv.FormatInt(config.MemoryBytes, 10)
+		limits["memory.swap.max"] = "0"
+	}
+	if config.CPUs > 0 {
+		limits["cpu.max"] = fmt.Sprintf("%d %d", int64(config.CPUs*cpuPeriod), cpuPeriod)
+	}
+	if config.Pids > 0 {
+		limits["pids.max"] = strconv.Itoa(config.Pids)
+	}
+	for file, value := range limits {
+		// memory.swap.max is missing when swap accounting is off
+		if err := os.WriteFile(filepath.Join(dir, file), []byte(value), 0644); err != nil && file != "memory.swap.max" {
+			cg.remove()
+			return nil, fmt.Errorf("set %s failed: %w", file, err)
+		}
+	}
+
+	fd, err := os.Open(dir)
+	if err != nil {
+		cg.remove()
+		return nil, err
+	}
+	cg.fd = fd
+	return cg, nil
+}
+
+func (cg *cgroup) apply(c *exec.Cmd) {
+	c.SysProcAttr = &syscall.SysProcAttr{UseCgroupFD: true, CgroupFD: int(cg.fd.Fd())}
+}
+
+func (cg *cgroup) oomKilled() bool {
+	f, err := os.Open(filepath.Join(cg.dir, "memory.events"))
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == "oom_kill" && fields[1] != "0" {
+			return true
+		}
+	}
+	return false
+}
+
+func (cg *cgroup) remove() {
+	if cg.fd != nil {
+		_ = cg.fd.Close()
+	}
+	_ = os.Remove(cg.dir)
+}
@@ -0,0 +1,37 @@
+//go:build !linux
+
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package sandbox
+
+import "os/exec"
+
+// cgroupLimitsEnforced is false, the CPU and memory limits need cgroup v2.
+const cgroupLimitsEnforced = false
+
+type cgroup struct{}
+
+// newCgroup returns no group, limits are not enforced without cgroup v2.
+func newCgroup(config Config, name string) (*cgroup, error) {
+	return nil, nil
+}
+
+func (cg *cgroup) apply(c *exec.Cmd) {}
+
+func (cg *cgroup) oomKilled() bool { return false }
+
+func (cg *cgroup) remove() {}
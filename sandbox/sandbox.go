@@ -0,0 +1,239 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package sandbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/basenana/plugin/types"
+)
+
+var ErrNotAllowed = errors.New("binary not allowed")
+
+// defaultPath is the PATH of commands when the host environment is not inherited.
+const defaultPath = "/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"
+
+// systemDirs are mounted read-only into the bubblewrap sandbox when present.
+var systemDirs = []string{
+	"/usr", "/bin", "/sbin", "/lib", "/lib32", "/lib64", "/opt",
+	"/etc/alternatives", "/etc/ssl", "/etc/pki", "/etc/ca-certificates", "/etc/fonts",
+	"/etc/resolv.conf", "/etc/hosts", "/etc/nsswitch.conf", "/etc/passwd", "/etc/group", "/etc/localtime",
+}
+
+// Config is the host-wide policy of binaries run by plugins. Zero values disable a limit.
+type Config struct {
+	// Allowed narrows the binaries plugins may run, empty allows every declared binary.
+	Allowed []string
+	// Timeout bounds the wall time of every command.
+	Timeout time.Duration
+	// CgroupRoot is a cgroup v2 directory the host may create groups in, e.g. /sys/fs/cgroup/nanafs.
+	// MemoryBytes, CPUs and Pids are only enforced when it is set.
+	CgroupRoot  string
+	MemoryBytes int64
+	CPUs        float64
+	Pids        int
+	// Bubblewrap is the bwrap binary. When set, commands run in new namespaces that only
+	// see the call working path, read-only system directories and ReadOnlyPaths.
+	Bubblewrap    string
+	ReadOnlyPaths []string
+	// PassEnv names the host environment variables commands receive.
+	PassEnv []string
+}
+
+func (c Config) hasCgroupLimits() bool {
+	return c.CgroupRoot != "" && (c.MemoryBytes > 0 || c.CPUs > 0 || c.Pids > 0)
+}
+
+// Runner runs the binaries of one plugin call inside its working path.
+type Runner struct {
+	config   *Config
+	workdir  string
+	binaries map[string]struct{}
+}
+
+var _ types.CommandRunner = &Runner{}
+
+// New returns the runner of a call. Only the binaries the plugin declares and config allows
+// can be run. A nil config applies no limits and passes the host environment through.
+func New(config *Config, workdir string, binaries []string) *Runner {
+	r := &Runner{config: config, workdir: workdir, binaries: make(map[string]struct{})}
+	for _, b := range binaries {
+		if config != nil && len(config.Allowed) > 0 && !contains(config.Allowed, b) {
+			continue
+		}
+		r.binaries[b] = struct{}{}
+	}
+	return r
+}
+
+// ForCall returns the runner the manager set for the call. Plugins built without a manager,
+// e.g. in tests, get a runner without limits confined to their working path.
+func ForCall(ps types.PluginCall) types.CommandRunner {
+	if ps.Runner != nil {
+		return ps.Runner
+	}
+	return &Runner{workdir: ps.WorkingPath}
+}
+
+// Limited reports whether the config bounds the wall time, CPU and memory of every command.
+func (r *Runner) Limited() bool {
+	return r.config != nil && r.config.Timeout > 0 && r.config.CPUs > 0 && r.config.MemoryBytes > 0 &&
+		r.config.CgroupRoot != "" && cgroupLimitsEnforced
+}
+
+// Isolated reports whether commands run under bubblewrap, seeing only the working path and the
+// read-only system directories, without network unless they ask for it.
+func (r *Runner) Isolated() bool {
+	return r.config != nil && r.config.Bubblewrap != ""
+}
+
+func (r *Runner) Run(ctx context.Context, cmd types.Command) error {
+	if cmd.Name == "" || strings.ContainsRune(cmd.Name, filepath.Separator) {
+		return fmt.Errorf("invalid binary name [%s], expect a name looked up in PATH", cmd.Name)
+	}
+	if r.binaries != nil {
+		if _, ok := r.binaries[cmd.Name]; !ok {
+			return fmt.Errorf("%w: %s", ErrNotAllowed, cmd.Name)
+		}
+	}
+	binary, err := exec.LookPath(cmd.Name)
+	if err != nil {
+		return fmt.Errorf("%s not found: %w", cmd.Name, err)
+	}
+	if binary, err = filepath.Abs(binary); err != nil {
+		return err
+	}
+	dir, err := r.resolveDir(cmd.Dir)
+	if err != nil {
+		return err
+	}
+
+	timeout := cmd.Timeout
+	if r.config != nil && r.config.Timeout > 0 && (timeout <= 0 || timeout > r.config.Timeout) {
+		timeout = r.config.Timeout
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	name, args := binary, cmd.Args
+	if r.config != nil && r.config.Bubblewrap != "" {
+		name, args = r.config.Bubblewrap, r.bubblewrapArgs(dir, binary, cmd)
+	}
+	c := exec.CommandContext(ctx, name, args...)
+	c.Dir = dir
+	c.Env = r.environ(cmd.Env)
+	c.Stdin, c.Stdout, c.Stderr = cmd.Stdin, cmd.Stdout, cmd.Stderr
+	c.WaitDelay = time.Second
+
+	var cg *cgroup
+	if r.config != nil && r.config.hasCgroupLimits() {
+		if cg, err = newCgroup(*r.config, cmd.Name); err != nil {
+			return fmt.Errorf("create cgroup for %s failed: %w", cmd.Name, err)
+		}
+		if cg != nil {
+			defer cg.remove()
+			cg.apply(c)
+		}
+	}
+
+	err = c.Run()
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return fmt.Errorf("%s timed out after %s", cmd.Name, timeout)
+	case cg != nil && cg.oomKilled():
+		return fmt.Errorf("%s killed: memory limit of %d bytes exceeded", cmd.Name, r.config.MemoryBytes)
+	}
+	return fmt.Errorf("%s failed: %w", cmd.Name, err)
+}
+
+// resolveDir resolves the command directory against the working path and keeps it inside.
+func (r *Runner) resolveDir(dir string) (string, error) {
+	if r.workdir == "" {
+		return dir, nil
+	}
+	workdir, err := filepath.Abs(r.workdir)
+	if err != nil {
+		return "", err
+	}
+	if dir == "" {
+		return workdir, nil
+	}
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(workdir, dir)
+	}
+	dir = filepath.Clean(dir)
+	if rel, err := filepath.Rel(workdir, dir); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("command dir %s is outside the working path", dir)
+	}
+	return dir, nil
+}
+
+func (r *Runner) environ(extra []string) []string {
+	if r.config == nil {
+		return append(os.Environ(), extra...)
+	}
+	env := []string{"PATH=" + defaultPath, "LANG=C.UTF-8"}
+	if r.workdir != "" {
+		env = append(env, "HOME="+r.workdir, "TMPDIR="+r.workdir)
+	}
+	for _, key := range r.config.PassEnv {
+		if v, ok := os.LookupEnv(key); ok {
+			env = append(env, key+"="+v)
+		}
+	}
+	return append(env, extra...)
+}
+
+func (r *Runner) bubblewrapArgs(dir, binary string, cmd types.Command) []string {
+	args := []string{"--die-with-parent", "--new-session", "--unshare-all"}
+	if cmd.Network {
+		args = append(args, "--share-net")
+	}
+	readOnly := append(append([]string{}, systemDirs...), r.config.ReadOnlyPaths...)
+	readOnly = append(readOnly, filepath.Dir(binary))
+	for _, p := range readOnly {
+		args = append(args, "--ro-bind-try", p, p)
+	}
+	args = append(args, "--proc", "/proc", "--dev", "/dev", "--tmpfs", "/tmp")
+	if r.workdir != "" {
+		workdir, _ := filepath.Abs(r.workdir)
+		args = append(args, "--bind", workdir, workdir)
+	}
+	args = append(args, "--chdir", dir, "--", binary)
+	return append(args, cmd.Args...)
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,173 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package sandbox
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/basenana/plugin/types"
+)
+
+func requireBinary(t *testing.T, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		if _, err := exec.LookPath(name); err != nil {
+			t.Skipf("%s not installed", name)
+		}
+	}
+}
+
+func TestRunner_Allowlist(t *testing.T) {
+	requireBinary(t, "sh")
+	workdir := t.TempDir()
+
+	r := New(nil, workdir, []string{"sh"})
+	if err := r.Run(context.Background(), types.Command{Name: "ls"}); !errors.Is(err, ErrNotAllowed) {
+		t.Errorf("undeclared binary should not run, got %v", err)
+	}
+	if err := r.Run(context.Background(), types.Command{Name: "/bin/sh"}); err == nil {
+		t.Error("binary paths should be rejected")
+	}
+	if err := r.Run(context.Background(), types.Command{Name: "sh", Args: []string{"-c", "true"}}); err != nil {
+		t.Errorf("declared binary should run, got %v", err)
+	}
+
+	r = New(&Config{Allowed: []string{"git"}}, workdir, []string{"sh", "git"})
+	if err := r.Run(context.Background(), types.Command{Name: "sh", Args: []string{"-c", "true"}}); !errors.Is(err, ErrNotAllowed) {
+		t.Errorf("binary outside the host allowlist should not run, got %v", err)
+	}
+}
+
+func TestRunner_Dir(t *testing.T) {
+	requireBinary(t, "sh")
+	workdir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(workdir, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	r := New(nil, workdir, []string{"sh"})
+
+	var out bytes.Buffer
+	if err := r.Run(context.Background(), types.Command{Name: "sh", Args: []string{"-c", "pwd"}, Dir: "sub", Stdout: &out}); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	got, _ := filepath.EvalSymlinks(strings.TrimSpace(out.String()))
+	want, _ := filepath.EvalSymlinks(filepath.Join(workdir, "sub"))
+	if got != want {
+		t.Errorf("expected dir %s, got %s", want, got)
+	}
+
+	for _, dir := range []string{"..", "../other", t.TempDir()} {
+		if err := r.Run(context.Background(), types.Command{Name: "sh", Args: []string{"-c", "true"}, Dir: dir}); err == nil {
+			t.Errorf("dir %s outside the working path should be rejected", dir)
+		}
+	}
+}
+
+func TestRunner_Timeout(t *testing.T) {
+	requireBinary(t, "sleep")
+	r := New(&Config{Timeout: 100 * time.Millisecond}, t.TempDir(), []string{"sleep"})
+
+	started := time.Now()
+	err := r.Run(context.Background(), types.Command{Name: "sleep", Args: []string{"5"}, Timeout: time.Minute})
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("expected timeout, got %v", err)
+	}
+	if time.Since(started) > 3*time.Second {
+		t.Errorf("command not stopped at the limit")
+	}
+}
+
+func TestRunner_Environ(t *testing.T) {
+	requireBinary(t, "sh")
+	t.Setenv("SANDBOX_TEST_SECRET", "secret")
+	t.Setenv("SANDBOX_TEST_PASSED", "passed")
+	workdir := t.TempDir()
+	script := `echo "$SANDBOX_TEST_SECRET|$SANDBOX_TEST_PASSED|$EXTRA|$HOME"`
+
+	var out bytes.Buffer
+	r := New(&Config{PassEnv: []string{"SANDBOX_TEST_PASSED"}}, workdir, []string{"sh"})
+	if err := r.Run(context.Background(), types.Command{Name: "sh", Args: []string{"-c", script}, Env: []string{"EXTRA=1"}, Stdout: &out}); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	if got, want := strings.TrimSpace(out.String()), "|passed|1|"+workdir; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+
+	out.Reset()
+	r = New(nil, workdir, []string{"sh"})
+	if err := r.Run(context.Background(), types.Command{Name: "sh", Args: []string{"-c", script}, Stdout: &out}); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	if !strings.HasPrefix(out.String(), "secret|passed||") {
+		t.Errorf("runner without config should inherit the environment, got %q", out.String())
+	}
+}
+
+func TestForCall(t *testing.T) {
+	runner := New(nil, "", nil)
+	if ForCall(types.PluginCall{Runner: runner}) != runner {
+		t.Error("expected the runner set by the manager")
+	}
+	if r, ok := ForCall(types.PluginCall{WorkingPath: "/tmp/job"}).(*Runner); !ok || r.workdir != "/tmp/job" || r.binaries != nil {
+		t.Errorf("unexpected fallback runner %+v", r)
+	}
+}
+
+func TestRunner_Limited(t *testing.T) {
+	limits := Config{Timeout: time.Minute, CgroupRoot: "/sys/fs/cgroup/test", CPUs: 1, MemoryBytes: 1 << 30}
+	if got := New(&limits, "", nil).Limited(); got != cgroupLimitsEnforced {
+		t.Errorf("expected limited %v with timeout, CPU and memory limits", cgroupLimitsEnforced)
+	}
+	noCgroup := limits
+	noCgroup.CgroupRoot = ""
+	for _, r := range []*Runner{New(nil, "", nil), New(&Config{Timeout: time.Minute}, "", nil), New(&noCgroup, "", nil)} {
+		if r.Limited() {
+			t.Errorf("expected %+v not to be limited", r.config)
+		}
+	}
+}
+
+func TestRunner_Isolated(t *testing.T) {
+	if New(nil, "", nil).Isolated() || New(&Config{Timeout: time.Minute}, "", nil).Isolated() {
+		t.Error("expected runners without bubblewrap not to be isolated")
+	}
+	if !New(&Config{Bubblewrap: "bwrap"}, "", nil).Isolated() {
+		t.Error("expected a bubblewrap runner to be isolated")
+	}
+}
+
+func TestRunner_BubblewrapArgs(t *testing.T) {
+	r := New(&Config{Bubblewrap: "bwrap", ReadOnlyPaths: []string{"/data/models"}}, "/jobs/1", []string{"yt-dlp"})
+	args := strings.Join(r.bubblewrapArgs("/jobs/1/out", "/usr/bin/yt-dlp", types.Command{Args: []string{"-x", "url"}, Network: true}), " ")
+	for _, want := range []string{"--unshare-all --share-net", "--ro-bind-try /data/models /data/models", "--bind /jobs/1 /jobs/1", "--chdir /jobs/1/out -- /usr/bin/yt-dlp -x url"} {
+		if !strings.Contains(args, want) {
+			t.Errorf("expected %q in %s", want, args)
+		}
+	}
+	args = strings.Join(r.bubblewrapArgs("/jobs/1", "/usr/bin/yt-dlp", types.Command{}), " ")
+	if strings.Contains(args, "--share-net") {
+		t.Errorf("network should be unshared by default: %s", args)
+	}
+}
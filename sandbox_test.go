@@ -0,0 +1,61 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package plugin
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/sandbox"
+	"github.com/basenana/plugin/types"
+)
+
+func TestManager_Call_CommandRunner(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not installed")
+	}
+	var calls []types.PluginCall
+	m := New(WithSandbox(sandbox.Config{Timeout: time.Second}))
+	m.Register(types.PluginSpec{
+		Name:         "shell",
+		Version:      "1.0",
+		Type:         types.TypeProcess,
+		Dependencies: []types.Dependency{{Kind: types.DependencyBinary, Name: "sh"}},
+	}, func(ps types.PluginCall) types.Plugin {
+		calls = append(calls, ps)
+		return &workdirRecorder{ps: ps, succeed: true}
+	})
+
+	workdir := t.TempDir()
+	if _, err := m.Call(context.Background(), types.PluginCall{PluginName: "shell", WorkingPath: workdir}, &api.Request{}); err != nil {
+		t.Fatalf("call failed: %v", err)
+	}
+	runner := calls[0].Runner
+	if runner == nil {
+		t.Fatal("expected the manager to set a command runner")
+	}
+	if err := runner.Run(context.Background(), types.Command{Name: "sh", Args: []string{"-c", "true"}}); err != nil {
+		t.Errorf("declared binary should run, got %v", err)
+	}
+	if err := runner.Run(context.Background(), types.Command{Name: "ls"}); !errors.Is(err, sandbox.ErrNotAllowed) {
+		t.Errorf("undeclared binary should not run, got %v", err)
+	}
+}
@@ -0,0 +1,321 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package snapshot
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Manifest describes one snapshot: the hash of its archive and every file it holds.
+type Manifest struct {
+	Name      string      `json:"name"`
+	CreatedAt string      `json:"created_at"`
+	Archive   string      `json:"archive"`
+	SHA256    string      `json:"sha256"`
+	Size      int64       `json:"size"`
+	Files     []FileEntry `json:"files"`
+}
+
+// FileEntry is a file or directory of the snapshot, Path is slash separated and relative to the working path.
+type FileEntry struct {
+	Path   string      `json:"path"`
+	Dir    bool        `json:"dir,omitempty"`
+	Size   int64       `json:"size,omitempty"`
+	Mode   fs.FileMode `json:"mode"`
+	SHA256 string      `json:"sha256,omitempty"`
+}
+
+// skipFunc reports whether a path relative to the working path is left out of snapshots.
+type skipFunc func(rel string) bool
+
+// writeArchive packs the files under root into a gzip compressed tar at archivePath and returns
+// the manifest without name and time. Anything but regular files and directories is skipped.
+func writeArchive(root, archivePath string, skip skipFunc) (*Manifest, []string, error) {
+	tmpPath := archivePath + ".tmp"
+	f, err := os.Create(tmpPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer os.Remove(tmpPath)
+	defer f.Close()
+
+	archiveHash := sha256.New()
+	gw := gzip.NewWriter(io.MultiWriter(f, archiveHash))
+	tw := tar.NewWriter(gw)
+
+	var (
+		manifest = &Manifest{Files: make([]FileEntry, 0)}
+		skipped  []string
+	)
+	err = filepath.WalkDir(root, func(absPath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, absPath)
+		if err != nil || rel == "." {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if skip(rel) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() && !info.IsDir() {
+			skipped = append(skipped, rel)
+			return nil
+		}
+		hdr, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		hdr.Name = rel
+		entry := FileEntry{Path: rel, Mode: info.Mode().Perm()}
+		if info.IsDir() {
+			hdr.Name += "/"
+			entry.Dir = true
+			manifest.Files = append(manifest.Files, entry)
+			return tw.WriteHeader(hdr)
+		}
+
+		if err = tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		src, err := os.Open(absPath)
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		fileHash := sha256.New()
+		n, err := io.Copy(io.MultiWriter(tw, fileHash), src)
+		if err != nil {
+			return fmt.Errorf("pack %s failed: %w", rel, err)
+		}
+		entry.Size = n
+		entry.SHA256 = hex.EncodeToString(fileHash.Sum(nil))
+		manifest.Size += n
+		manifest.Files = append(manifest.Files, entry)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if err = tw.Close(); err != nil {
+		return nil, nil, err
+	}
+	if err = gw.Close(); err != nil {
+		return nil, nil, err
+	}
+	if err = f.Close(); err != nil {
+		return nil, nil, err
+	}
+	if err = os.Rename(tmpPath, archivePath); err != nil {
+		return nil, nil, err
+	}
+	manifest.SHA256 = hex.EncodeToString(archiveHash.Sum(nil))
+	return manifest, skipped, nil
+}
+
+// extractArchive verifies the archive against the manifest and unpacks it into staging,
+// checking the hash of every file.
+func extractArchive(archivePath, staging string, manifest *Manifest) error {
+	sum, err := fileSHA256(archivePath)
+	if err != nil {
+		return err
+	}
+	if sum != manifest.SHA256 {
+		return fmt.Errorf("archive hash mismatch: expect %s, got %s", manifest.SHA256, sum)
+	}
+
+	entries := make(map[string]FileEntry, len(manifest.Files))
+	for _, en := range manifest.Files {
+		entries[en.Path] = en
+	}
+
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gr, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer gr.Close()
+	tr := tar.NewReader(gr)
+
+	extracted := make(map[string]bool, len(entries))
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		rel := strings.TrimSuffix(hdr.Name, "/")
+		en, ok := entries[rel]
+		if !ok || !validRelPath(rel) {
+			return fmt.Errorf("unexpected file %s in archive", hdr.Name)
+		}
+		target := filepath.Join(staging, filepath.FromSlash(rel))
+		if en.Dir {
+			if err = os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+			extracted[rel] = true
+			continue
+		}
+
+		if err = os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, en.Mode|0200)
+		if err != nil {
+			return err
+		}
+		fileHash := sha256.New()
+		_, err = io.Copy(io.MultiWriter(out, fileHash), tr)
+		closeErr := out.Close()
+		if err != nil {
+			return err
+		}
+		if closeErr != nil {
+			return closeErr
+		}
+		if sum := hex.EncodeToString(fileHash.Sum(nil)); sum != en.SHA256 {
+			return fmt.Errorf("file %s hash mismatch: expect %s, got %s", rel, en.SHA256, sum)
+		}
+		extracted[rel] = true
+	}
+
+	for rel := range entries {
+		if !extracted[rel] {
+			return fmt.Errorf("file %s missing from archive", rel)
+		}
+	}
+	return nil
+}
+
+// applyStaging moves the extracted files from staging into root. With clean, files and
+// directories under root that are not in the manifest are removed first.
+func applyStaging(root, staging string, manifest *Manifest, skip skipFunc, clean bool) (removed []string, err error) {
+	entries := make(map[string]FileEntry, len(manifest.Files))
+	for _, en := range manifest.Files {
+		entries[en.Path] = en
+	}
+
+	if clean {
+		err = filepath.WalkDir(root, func(absPath string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(root, absPath)
+			if err != nil || rel == "." {
+				return err
+			}
+			rel = filepath.ToSlash(rel)
+			if skip(rel) {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if en, ok := entries[rel]; ok && en.Dir == d.IsDir() {
+				return nil
+			}
+			removed = append(removed, rel)
+			if err = os.RemoveAll(absPath); err != nil {
+				return err
+			}
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		})
+		if err != nil {
+			return removed, err
+		}
+	}
+
+	files := append([]FileEntry(nil), manifest.Files...)
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	for _, en := range files {
+		target := filepath.Join(root, filepath.FromSlash(en.Path))
+		if info, err := os.Lstat(target); err == nil && info.IsDir() != en.Dir {
+			if err = os.RemoveAll(target); err != nil {
+				return removed, err
+			}
+		}
+		if en.Dir {
+			if err = os.MkdirAll(target, 0755); err != nil {
+				return removed, err
+			}
+			if err = os.Chmod(target, en.Mode|0700); err != nil {
+				return removed, err
+			}
+			continue
+		}
+		if err = os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return removed, err
+		}
+		if err = os.Rename(filepath.Join(staging, filepath.FromSlash(en.Path)), target); err != nil {
+			return removed, err
+		}
+		if err = os.Chmod(target, en.Mode); err != nil {
+			return removed, err
+		}
+	}
+	return removed, nil
+}
+
+func fileSHA256(filePath string) (string, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err = io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func validRelPath(rel string) bool {
+	if rel == "" || path.IsAbs(rel) || strings.Contains(rel, "\\") {
+		return false
+	}
+	return path.Clean(rel) == rel && rel != ".." && !strings.HasPrefix(rel, "../")
+}
@@ -0,0 +1,372 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package snapshot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/logger"
+	"github.com/basenana/plugin/types"
+	"github.com/basenana/plugin/utils"
+	"go.uber.org/zap"
+)
+
+const (
+	pluginName    = "snapshot"
+	pluginVersion = "1.0"
+
+	actionCreate  = "create"
+	actionRestore = "restore"
+	actionList    = "list"
+
+	defaultSnapshotDir = ".snapshots"
+	latestSnapshot     = "latest"
+	nameTimeLayout     = "20060102T150405Z"
+
+	archiveExt  = ".tar.gz"
+	manifestExt = ".json"
+	stagingDir  = ".restore"
+
+	// per-call working directories of the registry, they belong to single calls
+	callWorkdirName = ".calls"
+)
+
+var snapshotName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+var PluginSpec = types.PluginSpec{
+	Name:    pluginName,
+	Version: pluginVersion,
+	Type:    types.TypeProcess,
+	Parameters: []types.ParameterSpec{
+		{
+			Name:        "action",
+			Required:    false,
+			Default:     actionCreate,
+			Description: "Action: create, restore or list",
+			Options:     []string{actionCreate, actionRestore, actionList},
+		},
+		{
+			Name:        "name",
+			Required:    false,
+			Description: "Snapshot name; create defaults to the UTC time, restore accepts latest",
+		},
+		{
+			Name:        "snapshot_dir",
+			Required:    false,
+			Default:     defaultSnapshotDir,
+			Description: "Directory in the job working path holding the snapshots, never snapshotted itself",
+		},
+		{
+			Name:        "exclude",
+			Required:    false,
+			Description: "Comma-separated glob patterns of paths left out, matched against the relative path and the base name",
+		},
+		{
+			Name:        "keep",
+			Required:    false,
+			Default:     "0",
+			Description: "After create, keep only this many newest snapshots; 0 keeps all",
+		},
+		{
+			Name:        "clean",
+			Required:    false,
+			Default:     "true",
+			Description: "On restore, remove files that are not in the snapshot",
+			Options:     []string{"true", "false"},
+		},
+	},
+}
+
+type SnapshotPlugin struct {
+	logger *zap.SugaredLogger
+	root   string
+}
+
+// NewSnapshotPlugin works on the job working path, so a call running in an isolated
+// directory still snapshots the whole job.
+func NewSnapshotPlugin(ps types.PluginCall) types.Plugin {
+	root := ps.JobWorkingPath
+	if root == "" {
+		root = ps.WorkingPath
+	}
+	return &SnapshotPlugin{
+		logger: logger.NewPluginLogger(pluginName, ps.JobID),
+		root:   utils.NewFileAccess(root).Workdir(),
+	}
+}
+
+func (p *SnapshotPlugin) Name() string {
+	return pluginName
+}
+
+func (p *SnapshotPlugin) Type() types.PluginType {
+	return types.TypeProcess
+}
+
+func (p *SnapshotPlugin) Version() string {
+	return pluginVersion
+}
+
+func (p *SnapshotPlugin) Run(ctx context.Context, request *api.Request) (*api.Response, error) {
+	action := api.GetStringParameter("action", request, actionCreate)
+	snapshotDir := path.Clean(filepath.ToSlash(api.GetStringParameter("snapshot_dir", request, defaultSnapshotDir)))
+	if err := utils.NewFileAccess(p.root).ValidatePath(snapshotDir); err != nil || snapshotDir == "." {
+		return api.NewFailedResponse(fmt.Sprintf("invalid snapshot_dir: %s", snapshotDir)), nil
+	}
+	skip := p.skipFunc(snapshotDir, api.GetStringParameter("exclude", request, ""))
+	dir := filepath.Join(p.root, filepath.FromSlash(snapshotDir))
+
+	p.logger.Infow("snapshot plugin started", "action", action, "root", p.root, "snapshot_dir", snapshotDir)
+	switch action {
+	case actionCreate:
+		return p.create(request, dir, skip)
+	case actionRestore:
+		return p.restore(request, dir, skip)
+	case actionList:
+		manifests, err := listManifests(dir)
+		if err != nil {
+			return api.NewFailedResponse(fmt.Sprintf("list snapshots failed: %s", err)), nil
+		}
+		snapshots := make([]map[string]any, 0, len(manifests))
+		for _, m := range manifests {
+			snapshots = append(snapshots, summary(m))
+		}
+		return api.NewResponseWithResult(map[string]any{"snapshots": snapshots, "total": len(snapshots)}), nil
+	default:
+		return api.NewFailedResponse(fmt.Sprintf("unknown action: %s", action)), nil
+	}
+}
+
+func (p *SnapshotPlugin) create(request *api.Request, dir string, skip skipFunc) (*api.Response, error) {
+	now := time.Now().UTC()
+	name := api.GetStringParameter("name", request, now.Format(nameTimeLayout))
+	if !snapshotName.MatchString(name) || name == latestSnapshot {
+		return api.NewFailedResponse(fmt.Sprintf("invalid snapshot name: %s", name)), nil
+	}
+	keep, err := strconv.Atoi(api.GetStringParameter("keep", request, "0"))
+	if err != nil || keep < 0 {
+		return api.NewFailedResponse(fmt.Sprintf("invalid keep: %s", api.GetStringParameter("keep", request, ""))), nil
+	}
+
+	manifestPath := filepath.Join(dir, name+manifestExt)
+	if _, err = os.Stat(manifestPath); err == nil {
+		return api.NewFailedResponse(fmt.Sprintf("snapshot %s already exists", name)), nil
+	}
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("create snapshot dir failed: %w", err)
+	}
+
+	manifest, skipped, err := writeArchive(p.root, filepath.Join(dir, name+archiveExt), skip)
+	if err != nil {
+		p.logger.Warnw("write snapshot archive failed", "name", name, "err", err)
+		return api.NewFailedResponse(fmt.Sprintf("create snapshot %s failed: %s", name, err)), nil
+	}
+	manifest.Name = name
+	manifest.CreatedAt = now.Format(time.RFC3339Nano)
+	manifest.Archive = name + archiveExt
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err = os.WriteFile(manifestPath, data, 0644); err != nil {
+		return nil, fmt.Errorf("write snapshot manifest failed: %w", err)
+	}
+	if len(skipped) > 0 {
+		p.logger.Warnw("skip files that are not regular", "name", name, "files", skipped)
+	}
+
+	results := summary(manifest)
+	results["skipped"] = skipped
+	if keep > 0 {
+		pruned, err := prune(dir, keep)
+		if err != nil {
+			p.logger.Warnw("prune snapshots failed", "err", err)
+		}
+		results["pruned"] = pruned
+	}
+	p.logger.Infow("snapshot created", "name", name, "files", len(manifest.Files), "size", manifest.Size)
+	return api.NewResponseWithResult(results), nil
+}
+
+func (p *SnapshotPlugin) restore(request *api.Request, dir string, skip skipFunc) (*api.Response, error) {
+	name := api.GetStringParameter("name", request, "")
+	if name == "" {
+		return api.NewFailedResponse("name is required"), nil
+	}
+	manifest, err := loadManifest(dir, name)
+	if err != nil {
+		return api.NewFailedResponse(err.Error()), nil
+	}
+
+	staging := filepath.Join(dir, stagingDir)
+	if err = os.RemoveAll(staging); err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(staging)
+	if err = extractArchive(filepath.Join(dir, manifest.Archive), staging, manifest); err != nil {
+		p.logger.Warnw("verify snapshot failed", "name", manifest.Name, "err", err)
+		return api.NewFailedResponse(fmt.Sprintf("restore snapshot %s failed: %s", manifest.Name, err)), nil
+	}
+
+	removed, err := applyStaging(p.root, staging, manifest, skip, api.GetBoolParameter("clean", request, true))
+	if err != nil {
+		p.logger.Errorw("apply snapshot failed", "name", manifest.Name, "err", err)
+		return api.NewFailedResponse(fmt.Sprintf("restore snapshot %s failed: %s", manifest.Name, err)), nil
+	}
+	if removed == nil {
+		removed = []string{}
+	}
+
+	results := summary(manifest)
+	results["removed"] = removed
+	p.logger.Infow("snapshot restored", "name", manifest.Name, "files", len(manifest.Files), "removed", len(removed))
+	return api.NewResponseWithResult(results), nil
+}
+
+// skipFunc leaves out the snapshot directory, per-call working directories and excluded paths.
+func (p *SnapshotPlugin) skipFunc(snapshotDir, exclude string) skipFunc {
+	var patterns []string
+	for _, pattern := range strings.Split(exclude, ",") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			patterns = append(patterns, pattern)
+		}
+	}
+	return func(rel string) bool {
+		for _, dir := range []string{snapshotDir, callWorkdirName} {
+			if rel == dir || strings.HasPrefix(rel, dir+"/") {
+				return true
+			}
+		}
+		for _, pattern := range patterns {
+			if ok, _ := path.Match(pattern, rel); ok {
+				return true
+			}
+			if ok, _ := path.Match(pattern, path.Base(rel)); ok {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func summary(m *Manifest) map[string]any {
+	var files int
+	for _, en := range m.Files {
+		if !en.Dir {
+			files++
+		}
+	}
+	return map[string]any{
+		"name":       m.Name,
+		"created_at": m.CreatedAt,
+		"archive":    m.Archive,
+		"sha256":     m.SHA256,
+		"files":      files,
+		"size":       m.Size,
+	}
+}
+
+func loadManifest(dir, name string) (*Manifest, error) {
+	if name == latestSnapshot {
+		manifests, err := listManifests(dir)
+		if err != nil {
+			return nil, fmt.Errorf("list snapshots failed: %s", err)
+		}
+		if len(manifests) == 0 {
+			return nil, fmt.Errorf("no snapshot found")
+		}
+		return manifests[0], nil
+	}
+	if !snapshotName.MatchString(name) {
+		return nil, fmt.Errorf("invalid snapshot name: %s", name)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, name+manifestExt))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("snapshot %s not found", name)
+		}
+		return nil, err
+	}
+	manifest := &Manifest{}
+	if err = json.Unmarshal(data, manifest); err != nil {
+		return nil, fmt.Errorf("parse snapshot %s manifest failed: %s", name, err)
+	}
+	if manifest.Archive == "" || manifest.Archive != filepath.Base(manifest.Archive) {
+		return nil, fmt.Errorf("invalid archive of snapshot %s: %s", name, manifest.Archive)
+	}
+	return manifest, nil
+}
+
+// listManifests returns the snapshots newest first.
+func listManifests(dir string) ([]*Manifest, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var manifests []*Manifest
+	for _, en := range entries {
+		name, ok := strings.CutSuffix(en.Name(), manifestExt)
+		if !ok || en.IsDir() {
+			continue
+		}
+		m, err := loadManifest(dir, name)
+		if err != nil {
+			return nil, err
+		}
+		manifests = append(manifests, m)
+	}
+	sort.SliceStable(manifests, func(i, j int) bool {
+		ti, _ := time.Parse(time.RFC3339Nano, manifests[i].CreatedAt)
+		tj, _ := time.Parse(time.RFC3339Nano, manifests[j].CreatedAt)
+		return ti.After(tj)
+	})
+	return manifests, nil
+}
+
+// prune removes all but the newest keep snapshots and returns the removed names.
+func prune(dir string, keep int) ([]string, error) {
+	manifests, err := listManifests(dir)
+	if err != nil {
+		return nil, err
+	}
+	pruned := make([]string, 0)
+	for i := keep; i < len(manifests); i++ {
+		m := manifests[i]
+		if err = os.Remove(filepath.Join(dir, m.Archive)); err != nil && !os.IsNotExist(err) {
+			return pruned, err
+		}
+		if err = os.Remove(filepath.Join(dir, m.Name+manifestExt)); err != nil {
+			return pruned, err
+		}
+		pruned = append(pruned, m.Name)
+	}
+	return pruned, nil
+}
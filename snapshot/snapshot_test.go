@@ -0,0 +1,264 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package snapshot
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/logger"
+	"github.com/basenana/plugin/types"
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) {
+	logger.SetLogger(zap.NewNop().Sugar())
+	os.Exit(m.Run())
+}
+
+func newSnapshotPlugin(t *testing.T, workdir string) *SnapshotPlugin {
+	return NewSnapshotPlugin(types.PluginCall{
+		JobID:       "test-job",
+		Workflow:    "test-workflow",
+		Namespace:   "test-namespace",
+		WorkingPath: workdir,
+		Params:      map[string]string{},
+	}).(*SnapshotPlugin)
+}
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	for name, content := range files {
+		target := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(target, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func readFile(t *testing.T, root, name string) string {
+	data, err := os.ReadFile(filepath.Join(root, name))
+	if err != nil {
+		t.Fatalf("read %s failed: %v", name, err)
+	}
+	return string(data)
+}
+
+func run(t *testing.T, p *SnapshotPlugin, params map[string]any) *api.Response {
+	resp, err := p.Run(context.Background(), &api.Request{Parameter: params})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	return resp
+}
+
+func TestSnapshotPlugin_CreateRestore(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"step1.txt":         "step one",
+		"data/report.md":    "# report",
+		"data/raw/rows.csv": "a,b\n1,2",
+		"cache/tmp.bin":     "scratch",
+	})
+	if err := os.MkdirAll(filepath.Join(root, "empty"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	p := newSnapshotPlugin(t, root)
+
+	resp := run(t, p, map[string]any{"name": "checkpoint-1", "exclude": "cache"})
+	if !resp.IsSucceed {
+		t.Fatalf("create failed: %s", resp.Message)
+	}
+	if resp.Results["files"] != 3 || resp.Results["name"] != "checkpoint-1" {
+		t.Errorf("unexpected create results %v", resp.Results)
+	}
+	for _, name := range []string{"checkpoint-1.tar.gz", "checkpoint-1.json"} {
+		if _, err := os.Stat(filepath.Join(root, defaultSnapshotDir, name)); err != nil {
+			t.Errorf("expected %s: %v", name, err)
+		}
+	}
+
+	writeFiles(t, root, map[string]string{
+		"step1.txt":      "changed",
+		"step2.txt":      "step two",
+		"data/report.md": "# report v2",
+	})
+	if err := os.RemoveAll(filepath.Join(root, "data", "raw")); err != nil {
+		t.Fatal(err)
+	}
+
+	resp = run(t, p, map[string]any{"action": "restore", "name": "latest", "exclude": "cache"})
+	if !resp.IsSucceed {
+		t.Fatalf("restore failed: %s", resp.Message)
+	}
+	if got := readFile(t, root, "step1.txt"); got != "step one" {
+		t.Errorf("expected step1.txt restored, got %q", got)
+	}
+	if got := readFile(t, root, "data/report.md"); got != "# report" {
+		t.Errorf("expected report restored, got %q", got)
+	}
+	if got := readFile(t, root, "data/raw/rows.csv"); got != "a,b\n1,2" {
+		t.Errorf("expected rows restored, got %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(root, "step2.txt")); !os.IsNotExist(err) {
+		t.Error("expected step2.txt removed by clean restore")
+	}
+	if _, err := os.Stat(filepath.Join(root, "empty")); err != nil {
+		t.Errorf("expected empty dir restored: %v", err)
+	}
+	if got := readFile(t, root, "cache/tmp.bin"); got != "scratch" {
+		t.Errorf("expected excluded file untouched, got %q", got)
+	}
+	removed := resp.Results["removed"].([]string)
+	if len(removed) != 1 || removed[0] != "step2.txt" {
+		t.Errorf("unexpected removed %v", removed)
+	}
+	if _, err := os.Stat(filepath.Join(root, defaultSnapshotDir, stagingDir)); !os.IsNotExist(err) {
+		t.Error("expected staging dir removed")
+	}
+}
+
+func TestSnapshotPlugin_RestoreWithoutClean(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{"a.txt": "a"})
+	p := newSnapshotPlugin(t, root)
+	if resp := run(t, p, map[string]any{"name": "s1"}); !resp.IsSucceed {
+		t.Fatalf("create failed: %s", resp.Message)
+	}
+	writeFiles(t, root, map[string]string{"a.txt": "changed", "b.txt": "b"})
+
+	resp := run(t, p, map[string]any{"action": "restore", "name": "s1", "clean": "false"})
+	if !resp.IsSucceed {
+		t.Fatalf("restore failed: %s", resp.Message)
+	}
+	if got := readFile(t, root, "a.txt"); got != "a" {
+		t.Errorf("expected a.txt restored, got %q", got)
+	}
+	if got := readFile(t, root, "b.txt"); got != "b" {
+		t.Errorf("expected b.txt kept, got %q", got)
+	}
+}
+
+func TestSnapshotPlugin_CorruptArchive(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{"a.txt": "a"})
+	p := newSnapshotPlugin(t, root)
+	if resp := run(t, p, map[string]any{"name": "s1"}); !resp.IsSucceed {
+		t.Fatalf("create failed: %s", resp.Message)
+	}
+	archive := filepath.Join(root, defaultSnapshotDir, "s1.tar.gz")
+	f, err := os.OpenFile(archive, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = f.WriteString("garbage")
+	_ = f.Close()
+	writeFiles(t, root, map[string]string{"a.txt": "changed"})
+
+	resp := run(t, p, map[string]any{"action": "restore", "name": "s1"})
+	if resp.IsSucceed {
+		t.Fatal("expected restore of a corrupt archive to fail")
+	}
+	if got := readFile(t, root, "a.txt"); got != "changed" {
+		t.Errorf("expected working path untouched, got %q", got)
+	}
+}
+
+func TestSnapshotPlugin_ListAndKeep(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{"a.txt": "a"})
+	p := newSnapshotPlugin(t, root)
+	for _, name := range []string{"s1", "s2", "s3"} {
+		if resp := run(t, p, map[string]any{"name": name, "keep": "2"}); !resp.IsSucceed {
+			t.Fatalf("create %s failed: %s", name, resp.Message)
+		}
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	resp := run(t, p, map[string]any{"action": "list"})
+	if !resp.IsSucceed {
+		t.Fatalf("list failed: %s", resp.Message)
+	}
+	snapshots := resp.Results["snapshots"].([]map[string]any)
+	if len(snapshots) != 2 || snapshots[0]["name"] != "s3" || snapshots[1]["name"] != "s2" {
+		t.Errorf("expected s3 and s2 kept newest first, got %v", snapshots)
+	}
+	if _, err := os.Stat(filepath.Join(root, defaultSnapshotDir, "s1.tar.gz")); !os.IsNotExist(err) {
+		t.Error("expected s1 archive pruned")
+	}
+	// snapshots never include older snapshots
+	if resp = run(t, p, map[string]any{"name": "s4"}); resp.Results["files"] != 1 {
+		t.Errorf("expected only a.txt in s4, got %v", resp.Results["files"])
+	}
+}
+
+func TestSnapshotPlugin_Errors(t *testing.T) {
+	root := t.TempDir()
+	p := newSnapshotPlugin(t, root)
+	tests := []struct {
+		name   string
+		params map[string]any
+	}{
+		{"unknown action", map[string]any{"action": "diff"}},
+		{"invalid name", map[string]any{"name": "../escape"}},
+		{"reserved name", map[string]any{"name": "latest"}},
+		{"invalid keep", map[string]any{"keep": "-1"}},
+		{"snapshot dir outside", map[string]any{"snapshot_dir": "../snapshots"}},
+		{"restore without name", map[string]any{"action": "restore"}},
+		{"restore missing", map[string]any{"action": "restore", "name": "nope"}},
+		{"restore latest without snapshots", map[string]any{"action": "restore", "name": "latest"}},
+	}
+	for _, tt := range tests {
+		if resp := run(t, p, tt.params); resp.IsSucceed {
+			t.Errorf("%s: expected failure", tt.name)
+		}
+	}
+
+	if resp := run(t, p, map[string]any{"name": "dup"}); !resp.IsSucceed {
+		t.Fatalf("create failed: %s", resp.Message)
+	}
+	if resp := run(t, p, map[string]any{"name": "dup"}); resp.IsSucceed {
+		t.Error("expected failure for existing snapshot")
+	}
+}
+
+func TestNewSnapshotPlugin_JobWorkingPath(t *testing.T) {
+	root := t.TempDir()
+	callDir := filepath.Join(root, callWorkdirName, "snapshot-1")
+	writeFiles(t, root, map[string]string{"job.txt": "job", ".calls/other-1/tmp.txt": "tmp"})
+	if err := os.MkdirAll(callDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	p := NewSnapshotPlugin(types.PluginCall{JobID: "test-job", WorkingPath: callDir, JobWorkingPath: root}).(*SnapshotPlugin)
+	resp := run(t, p, map[string]any{"name": "job"})
+	if !resp.IsSucceed {
+		t.Fatalf("create failed: %s", resp.Message)
+	}
+	if resp.Results["files"] != 1 {
+		t.Errorf("expected only job.txt, got %v", resp.Results["files"])
+	}
+	if _, err := os.Stat(filepath.Join(root, defaultSnapshotDir, "job.json")); err != nil {
+		t.Errorf("expected snapshot in the job working path: %v", err)
+	}
+}
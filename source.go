@@ -47,7 +47,7 @@ var _ SourcePlugin = &ThreeBodyPlugin{}
 func NewThreeBodyPlugin(ps types.PluginCall) types.Plugin {
 	return &ThreeBodyPlugin{
 		logger:   logger.NewPluginLogger(the3BodyPluginName, ps.JobID),
-		fileRoot: utils.NewFileAccess(ps.WorkingPath),
+		fileRoot: utils.NewFileAccess(ps.WorkingPath, ps.JobWorkingPath),
 	}
 }
 
@@ -0,0 +1,276 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package tagger
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/docloader"
+	"github.com/basenana/plugin/logger"
+	"github.com/basenana/plugin/types"
+	"github.com/basenana/plugin/utils"
+	"go.uber.org/zap"
+)
+
+const (
+	pluginName    = "tagger"
+	pluginVersion = "1.0"
+
+	defaultMaxKeywords = 10
+)
+
+var PluginSpec = types.PluginSpec{
+	Name:    pluginName,
+	Version: pluginVersion,
+	Type:    types.TypeProcess,
+	Dependencies: []types.Dependency{
+		{Kind: types.DependencyCapability, Name: types.CapabilityFS, Optional: true},
+	},
+	Parameters: []types.ParameterSpec{
+		{
+			Name:        "file_path",
+			Required:    true,
+			Description: "Document to tag: pdf, txt, md, html, epub, or any UTF-8 text file",
+		},
+		{
+			Name:        "taxonomy",
+			Required:    false,
+			Description: "JSON array of {\"keyword\", \"aliases\", \"parents\", \"case_sensitive\"}, or an object of keyword to aliases",
+		},
+		{
+			Name:        "taxonomy_path",
+			Required:    false,
+			Description: "JSON taxonomy file in the working path, added to taxonomy",
+		},
+		{
+			Name:        "stemming",
+			Required:    false,
+			Default:     "true",
+			Description: "Match English plural, -ing and -ed forms of the keywords",
+			Options:     []string{"true", "false"},
+		},
+		{
+			Name:        "min_count",
+			Required:    false,
+			Default:     "1",
+			Description: "Occurrences a keyword needs to be tagged",
+		},
+		{
+			Name:        "max_keywords",
+			Required:    false,
+			Default:     strconv.Itoa(defaultMaxKeywords),
+			Description: "Most frequent keywords kept, 0 keeps all",
+		},
+		{
+			Name:        "include_parents",
+			Required:    false,
+			Default:     "true",
+			Description: "Also tag the parent keywords of the matched terms",
+			Options:     []string{"true", "false"},
+		},
+		{
+			Name:        "entry_uri",
+			Required:    false,
+			Description: "Add the keywords to this NanaFS entry",
+		},
+	},
+}
+
+type TaggerPlugin struct {
+	logger   *zap.SugaredLogger
+	fileRoot *utils.FileAccess
+}
+
+func NewTaggerPlugin(ps types.PluginCall) types.Plugin {
+	return &TaggerPlugin{
+		logger:   logger.NewPluginLogger(pluginName, ps.JobID),
+		fileRoot: utils.NewFileAccess(ps.WorkingPath, ps.JobWorkingPath),
+	}
+}
+
+func (p *TaggerPlugin) Name() string {
+	return pluginName
+}
+
+func (p *TaggerPlugin) Type() types.PluginType {
+	return types.TypeProcess
+}
+
+func (p *TaggerPlugin) Version() string {
+	return pluginVersion
+}
+
+func (p *TaggerPlugin) Run(ctx context.Context, request *api.Request) (*api.Response, error) {
+	filePath := api.GetStringParameter("file_path", request, "")
+	if filePath == "" {
+		return api.NewFailedResponse("file_path is required"), nil
+	}
+	terms, err := p.loadTaxonomy(request)
+	if err != nil {
+		return api.NewFailedResponse(err.Error()), nil
+	}
+	matcher, err := NewMatcher(terms, api.GetBoolParameter("stemming", request, true))
+	if err != nil {
+		return api.NewFailedResponse(err.Error()), nil
+	}
+	minCount, err := intParameter(request, "min_count", 1, 1)
+	if err != nil {
+		return api.NewFailedResponse(err.Error()), nil
+	}
+	maxKeywords, err := intParameter(request, "max_keywords", defaultMaxKeywords, 0)
+	if err != nil {
+		return api.NewFailedResponse(err.Error()), nil
+	}
+	entryURI := api.GetStringParameter("entry_uri", request, "")
+	if entryURI != "" && request.FS == nil {
+		return api.NewFailedResponse("file system is not available"), nil
+	}
+
+	text, err := p.loadText(ctx, filePath)
+	if err != nil {
+		p.logger.Warnw("load file failed", "file", filePath, "error", err)
+		return api.NewFailedResponse(fmt.Sprintf("load file %s failed: %s", filePath, err)), nil
+	}
+
+	var matches []Match
+	for _, m := range matcher.Find(text) {
+		if m.Count < minCount {
+			continue
+		}
+		if maxKeywords > 0 && len(matches) >= maxKeywords {
+			break
+		}
+		matches = append(matches, m)
+	}
+	keywords := make([]string, 0, len(matches))
+	for _, m := range matches {
+		keywords = mergeKeywords(keywords, []string{m.Keyword})
+	}
+	if api.GetBoolParameter("include_parents", request, true) {
+		for _, m := range matches {
+			keywords = mergeKeywords(keywords, m.Parents)
+		}
+	}
+
+	matchMaps := make([]map[string]any, 0, len(matches))
+	for _, m := range matches {
+		matchMaps = append(matchMaps, utils.MarshalMap(m))
+	}
+	results := map[string]any{
+		"file_path": filePath,
+		"keywords":  keywords,
+		"matches":   matchMaps,
+	}
+
+	if entryURI != "" {
+		props, err := request.FS.GetEntryProperties(ctx, entryURI)
+		if err != nil {
+			return api.NewFailedResponse(fmt.Sprintf("get entry %s properties failed: %s", entryURI, err)), nil
+		}
+		if props == nil {
+			props = &types.Properties{}
+		}
+		props.Keywords = mergeKeywords(props.Keywords, keywords)
+		if err = request.FS.UpdateEntry(ctx, entryURI, "", *props); err != nil {
+			p.logger.Warnw("update entry failed", "entry_uri", entryURI, "error", err)
+			return api.NewFailedResponse(fmt.Sprintf("update entry %s failed: %s", entryURI, err)), nil
+		}
+		results["entry_uri"] = entryURI
+		results["entry_keywords"] = props.Keywords
+	}
+
+	p.logger.Infow("file tagged", "file", filePath, "terms", len(terms), "keywords", len(keywords))
+	return api.NewResponseWithResult(results), nil
+}
+
+// loadTaxonomy returns the inline taxonomy followed by the one in taxonomy_path.
+func (p *TaggerPlugin) loadTaxonomy(request *api.Request) ([]Term, error) {
+	var terms []Term
+	if raw := api.GetStringParameter("taxonomy", request, ""); raw != "" {
+		inline, err := parseTaxonomy([]byte(raw))
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, inline...)
+	}
+	if taxonomyPath := api.GetStringParameter("taxonomy_path", request, ""); taxonomyPath != "" {
+		data, err := p.fileRoot.Read(taxonomyPath)
+		if err != nil {
+			return nil, fmt.Errorf("read taxonomy file failed: %s", err)
+		}
+		fileTerms, err := parseTaxonomy(data)
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, fileTerms...)
+	}
+	if len(terms) == 0 {
+		return nil, fmt.Errorf("taxonomy or taxonomy_path is required")
+	}
+	return terms, nil
+}
+
+// loadText uses docloader for document formats and reads other files as UTF-8 text.
+func (p *TaggerPlugin) loadText(ctx context.Context, filePath string) (string, error) {
+	absPath, err := p.fileRoot.GetAbsPath(filePath)
+	if err != nil {
+		return "", err
+	}
+	if parser, err := docloader.NewParser(absPath, nil); err == nil {
+		doc, err := parser.Load(logger.IntoContext(ctx, p.logger))
+		if err != nil {
+			return "", err
+		}
+		return doc.Content, nil
+	}
+
+	data, err := p.fileRoot.Read(filePath)
+	if err != nil {
+		return "", err
+	}
+	if !utf8.Valid(data) {
+		return "", fmt.Errorf("unsupported binary file")
+	}
+	return string(data), nil
+}
+
+func intParameter(request *api.Request, name string, defaultVal, minVal int) (int, error) {
+	raw := api.GetStringParameter(name, request, "")
+	if raw == "" {
+		return defaultVal, nil
+	}
+	v, err := strconv.Atoi(raw)
+	if err != nil || v < minVal {
+		return 0, fmt.Errorf("parse %s [%s] failed: expect integer of at least %d", name, raw, minVal)
+	}
+	return v, nil
+}
+
+// mergeKeywords appends the new keywords missing from the list, ignoring case.
+func mergeKeywords(keywords, add []string) []string {
+	for _, k := range add {
+		if k = strings.TrimSpace(k); k != "" && !contains(keywords, k) {
+			keywords = append(keywords, k)
+		}
+	}
+	return keywords
+}
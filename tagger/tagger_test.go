@@ -0,0 +1,148 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package tagger
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/logger"
+	"github.com/basenana/plugin/types"
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) {
+	logger.SetLogger(zap.NewNop().Sugar())
+	os.Exit(m.Run())
+}
+
+type mockFS struct {
+	props   map[string]*types.Properties
+	updated map[string]types.Properties
+}
+
+func (m *mockFS) CreateGroupIfNotExists(ctx context.Context, parentURI, group string, properties types.Properties) error {
+	return nil
+}
+
+func (m *mockFS) SaveEntry(ctx context.Context, parentURI, name string, properties types.Properties, reader io.ReadCloser) error {
+	return nil
+}
+
+func (m *mockFS) UpdateEntry(ctx context.Context, entryURI, content string, properties types.Properties) error {
+	m.updated[entryURI] = properties
+	return nil
+}
+
+func (m *mockFS) GetEntryProperties(ctx context.Context, entryURI string) (*types.Properties, error) {
+	props, ok := m.props[entryURI]
+	if !ok {
+		return nil, errors.New("entry not found")
+	}
+	return props, nil
+}
+
+func (m *mockFS) Search(ctx context.Context, query string, filter types.SearchFilter) ([]types.Entry, error) {
+	return nil, nil
+}
+
+func newTaggerPlugin(t *testing.T, files map[string]string) *TaggerPlugin {
+	workdir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(workdir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return NewTaggerPlugin(types.PluginCall{JobID: "test-job", WorkingPath: workdir}).(*TaggerPlugin)
+}
+
+const testTaxonomy = `[
+  {"keyword": "Kubernetes", "aliases": ["k8s"], "parents": ["Cloud Native"]},
+  {"keyword": "Observability", "aliases": ["tracing", "metrics"], "parents": ["Cloud Native"]},
+  {"keyword": "PostgreSQL", "aliases": ["postgres"]}
+]`
+
+func TestTaggerPlugin_Run(t *testing.T) {
+	p := newTaggerPlugin(t, map[string]string{
+		"post.md":       "# Running k8s\n\nWe run Kubernetes clusters and collect metrics.\nTracing helps too. Postgres is mentioned once.",
+		"taxonomy.json": `{"Go": ["golang"]}`,
+	})
+	fs := &mockFS{
+		props:   map[string]*types.Properties{"/blog/post.md": {Title: "post", Keywords: []string{"blog", "kubernetes"}}},
+		updated: map[string]types.Properties{},
+	}
+
+	resp, err := p.Run(context.Background(), &api.Request{
+		Parameter: map[string]any{"file_path": "post.md", "taxonomy": testTaxonomy, "taxonomy_path": "taxonomy.json",
+			"min_count": "2", "entry_uri": "/blog/post.md"},
+		FS: fs,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.IsSucceed {
+		t.Fatalf("Run not succeed: %s", resp.Message)
+	}
+	keywords := resp.Results["keywords"].([]string)
+	if strings.Join(keywords, ",") != "Kubernetes,Observability,Cloud Native" {
+		t.Errorf("unexpected keywords %v", keywords)
+	}
+	matches := resp.Results["matches"].([]map[string]any)
+	if len(matches) != 2 || matches[0]["count"] != float64(2) {
+		t.Errorf("unexpected matches %v", matches)
+	}
+	if got := fs.updated["/blog/post.md"].Keywords; strings.Join(got, ",") != "blog,kubernetes,Observability,Cloud Native" {
+		t.Errorf("unexpected entry keywords %v", got)
+	}
+
+	resp, err = p.Run(context.Background(), &api.Request{
+		Parameter: map[string]any{"file_path": "post.md", "taxonomy": testTaxonomy, "max_keywords": "1", "include_parents": "false"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keywords = resp.Results["keywords"].([]string); strings.Join(keywords, ",") != "Kubernetes" {
+		t.Errorf("unexpected limited keywords %v", keywords)
+	}
+}
+
+func TestTaggerPlugin_Run_InvalidParameters(t *testing.T) {
+	p := newTaggerPlugin(t, map[string]string{"post.md": "text"})
+	for _, params := range []map[string]any{
+		{"taxonomy": testTaxonomy},
+		{"file_path": "post.md"},
+		{"file_path": "post.md", "taxonomy": "[oops"},
+		{"file_path": "post.md", "taxonomy_path": "missing.json"},
+		{"file_path": "post.md", "taxonomy": testTaxonomy, "min_count": "0"},
+		{"file_path": "post.md", "taxonomy": testTaxonomy, "entry_uri": "/blog/post.md"},
+		{"file_path": "missing.md", "taxonomy": testTaxonomy},
+	} {
+		resp, err := p.Run(context.Background(), &api.Request{Parameter: params})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.IsSucceed {
+			t.Errorf("expected %v to fail", params)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package tagger
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Term is one taxonomy keyword. It matches its own name and every alias; Parents are broader
+// keywords added along with it. Case sensitive terms match the exact spelling without stemming,
+// for acronyms such as IT that would otherwise match common words.
+type Term struct {
+	Keyword       string   `json:"keyword"`
+	Aliases       []string `json:"aliases,omitempty"`
+	Parents       []string `json:"parents,omitempty"`
+	CaseSensitive bool     `json:"case_sensitive,omitempty"`
+}
+
+// parseTaxonomy reads a JSON array of terms, or an object mapping each keyword to its aliases.
+func parseTaxonomy(data []byte) ([]Term, error) {
+	data = []byte(strings.TrimSpace(string(data)))
+	var terms []Term
+	if len(data) > 0 && data[0] == '{' {
+		var aliases map[string][]string
+		if err := json.Unmarshal(data, &aliases); err != nil {
+			return nil, fmt.Errorf("parse taxonomy failed: %s", err)
+		}
+		for keyword, list := range aliases {
+			terms = append(terms, Term{Keyword: keyword, Aliases: list})
+		}
+		sort.Slice(terms, func(i, j int) bool { return terms[i].Keyword < terms[j].Keyword })
+		return terms, nil
+	}
+	if err := json.Unmarshal(data, &terms); err != nil {
+		return nil, fmt.Errorf("parse taxonomy failed: %s", err)
+	}
+	return terms, nil
+}
+
+type pattern struct {
+	term   int
+	alias  string
+	tokens []token
+	exact  bool
+}
+
+// Matcher finds taxonomy terms in text.
+type Matcher struct {
+	terms    []Term
+	dict     *dictionary
+	stemming bool
+	// patterns by the key of their first token, longest first
+	patterns map[string][]pattern
+}
+
+func NewMatcher(terms []Term, stemming bool) (*Matcher, error) {
+	m := &Matcher{terms: terms, dict: newDictionary(), stemming: stemming, patterns: make(map[string][]pattern)}
+	for i, t := range terms {
+		if strings.TrimSpace(t.Keyword) == "" {
+			return nil, fmt.Errorf("taxonomy term %d has no keyword", i)
+		}
+		for _, alias := range t.names() {
+			m.dict.add(alias)
+		}
+	}
+	for i, t := range terms {
+		for _, alias := range t.names() {
+			tokens := tokenize(alias, m.dict, stemming && !t.CaseSensitive)
+			if len(tokens) == 0 {
+				continue
+			}
+			p := pattern{term: i, alias: alias, tokens: tokens, exact: t.CaseSensitive}
+			key := p.key(tokens[0])
+			m.patterns[key] = append(m.patterns[key], p)
+		}
+	}
+	for key := range m.patterns {
+		sort.SliceStable(m.patterns[key], func(i, j int) bool {
+			return len(m.patterns[key][i].tokens) > len(m.patterns[key][j].tokens)
+		})
+	}
+	return m, nil
+}
+
+func (t Term) names() []string {
+	return append([]string{t.Keyword}, t.Aliases...)
+}
+
+// key indexes exact patterns by the raw token and the others by the normalized one,
+// lowercased so both kinds share one lookup per text token.
+func (p pattern) key(t token) string {
+	if p.exact {
+		return strings.ToLower(t.Raw)
+	}
+	return t.Norm
+}
+
+func (p pattern) matchAt(tokens []token, i int) bool {
+	if i+len(p.tokens) > len(tokens) {
+		return false
+	}
+	for k, want := range p.tokens {
+		got := tokens[i+k]
+		if p.exact && got.Raw != want.Raw || !p.exact && got.Norm != want.Norm {
+			return false
+		}
+	}
+	return true
+}
+
+// Match is a taxonomy keyword found in the text.
+type Match struct {
+	Keyword string   `json:"keyword"`
+	Count   int      `json:"count"`
+	Aliases []string `json:"aliases"`
+	Parents []string `json:"parents,omitempty"`
+	first   int
+}
+
+// Find returns the matched keywords, most frequent first. At each position the longest
+// alias wins and the scan continues after it, so "machine learning" is not also "learning".
+func (m *Matcher) Find(text string) []Match {
+	tokens := tokenize(text, m.dict, m.stemming)
+	found := make(map[int]*Match)
+	for i := 0; i < len(tokens); {
+		candidates := m.patterns[tokens[i].Norm]
+		if lower := strings.ToLower(tokens[i].Raw); lower != tokens[i].Norm {
+			candidates = append(append([]pattern{}, candidates...), m.patterns[lower]...)
+			sort.SliceStable(candidates, func(a, b int) bool { return len(candidates[a].tokens) > len(candidates[b].tokens) })
+		}
+		matched := 0
+		for _, p := range candidates {
+			if !p.matchAt(tokens, i) {
+				continue
+			}
+			match, ok := found[p.term]
+			if !ok {
+				t := m.terms[p.term]
+				match = &Match{Keyword: t.Keyword, Parents: t.Parents, first: i}
+				found[p.term] = match
+			}
+			match.Count++
+			if !contains(match.Aliases, p.alias) {
+				match.Aliases = append(match.Aliases, p.alias)
+			}
+			matched = len(p.tokens)
+			break
+		}
+		i += max(matched, 1)
+	}
+
+	matches := make([]Match, 0, len(found))
+	for _, match := range found {
+		matches = append(matches, *match)
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Count != matches[j].Count {
+			return matches[i].Count > matches[j].Count
+		}
+		return matches[i].first < matches[j].first
+	})
+	return matches
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
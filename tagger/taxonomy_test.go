@@ -0,0 +1,87 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package tagger
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseTaxonomy(t *testing.T) {
+	terms, err := parseTaxonomy([]byte(`{"Kubernetes": ["k8s", "kube"], "Go": ["golang"]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(terms) != 2 || terms[0].Keyword != "Go" || terms[1].Aliases[0] != "k8s" {
+		t.Errorf("unexpected terms %+v", terms)
+	}
+
+	terms, err = parseTaxonomy([]byte(`[{"keyword": "IT", "case_sensitive": true, "parents": ["Business"]}]`))
+	if err != nil || !terms[0].CaseSensitive || terms[0].Parents[0] != "Business" {
+		t.Errorf("unexpected terms %+v, %v", terms, err)
+	}
+
+	if _, err = parseTaxonomy([]byte(`[{"keyword": 1}]`)); err == nil {
+		t.Errorf("expected parse error")
+	}
+}
+
+func TestMatcher_Find(t *testing.T) {
+	m, err := NewMatcher([]Term{
+		{Keyword: "Machine Learning", Aliases: []string{"ML", "机器学习"}, Parents: []string{"AI"}},
+		{Keyword: "Learning"},
+		{Keyword: "Kubernetes", Aliases: []string{"k8s", "K8s集群"}},
+		{Keyword: "Information Technology", Aliases: []string{"IT"}, CaseSensitive: true},
+		{Keyword: "Database", Aliases: []string{"databases"}},
+	}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	text := `Machine learning pipelines run on Kubernetes. It is what IT asked for.
+We keep learning. Databases and a database; 机器学习平台运行在K8s集群上，ml models too.`
+	matches := m.Find(text)
+	got := make(map[string]Match)
+	var order []string
+	for _, match := range matches {
+		got[match.Keyword] = match
+		order = append(order, match.Keyword)
+	}
+
+	if ml := got["Machine Learning"]; ml.Count != 3 || strings.Join(ml.Aliases, ",") != "Machine Learning,机器学习,ML" || ml.Parents[0] != "AI" {
+		t.Errorf("unexpected machine learning match %+v", ml)
+	}
+	if l := got["Learning"]; l.Count != 1 {
+		t.Errorf("expected the longer alias to win over learning, got %+v", l)
+	}
+	if k := got["Kubernetes"]; k.Count != 2 {
+		t.Errorf("unexpected kubernetes match %+v", k)
+	}
+	if it := got["Information Technology"]; it.Count != 1 {
+		t.Errorf("expected case sensitive IT to skip It, got %+v", it)
+	}
+	if db := got["Database"]; db.Count != 2 {
+		t.Errorf("expected stemmed database matches, got %+v", db)
+	}
+	if order[0] != "Machine Learning" || order[len(order)-1] != "Learning" {
+		t.Errorf("expected most frequent first, got %v", order)
+	}
+
+	if _, err = NewMatcher([]Term{{Keyword: " "}}, true); err == nil {
+		t.Errorf("expected empty keyword error")
+	}
+}
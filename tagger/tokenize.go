@@ -0,0 +1,181 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package tagger
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// token is one word of the text. Norm is lowercased and, for ASCII words, stemmed.
+type token struct {
+	Raw  string
+	Norm string
+}
+
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+func isWordRune(r rune) bool {
+	return (unicode.IsLetter(r) || unicode.IsDigit(r)) && !isCJK(r)
+}
+
+// dictionary holds the CJK words of the taxonomy. CJK text has no spaces, so runs of CJK
+// characters are segmented by forward maximum matching against it; other characters
+// become single character tokens.
+type dictionary struct {
+	words  map[string]bool
+	maxLen int
+}
+
+func newDictionary() *dictionary {
+	return &dictionary{words: make(map[string]bool)}
+}
+
+func (d *dictionary) add(term string) {
+	for _, run := range cjkRuns(term) {
+		d.words[run] = true
+		d.maxLen = max(d.maxLen, utf8.RuneCountInString(run))
+	}
+}
+
+func (d *dictionary) segment(run []rune) []string {
+	var words []string
+	for i := 0; i < len(run); {
+		n := 1
+		for l := min(d.maxLen, len(run)-i); l > 1; l-- {
+			if d.words[string(run[i:i+l])] {
+				n = l
+				break
+			}
+		}
+		words = append(words, string(run[i:i+n]))
+		i += n
+	}
+	return words
+}
+
+func cjkRuns(text string) []string {
+	var (
+		runs    []string
+		current []rune
+	)
+	for _, r := range text {
+		if isCJK(r) {
+			current = append(current, r)
+			continue
+		}
+		if len(current) > 0 {
+			runs = append(runs, string(current))
+			current = nil
+		}
+	}
+	if len(current) > 0 {
+		runs = append(runs, string(current))
+	}
+	return runs
+}
+
+// tokenize splits text into words. Latin words keep a trailing + or # (C++, C#) and inner
+// dots between letters or digits (node.js); hyphens and other punctuation split words.
+func tokenize(text string, dict *dictionary, stemming bool) []token {
+	var (
+		tokens []token
+		runes  = []rune(text)
+	)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case isCJK(r):
+			j := i
+			for j < len(runes) && isCJK(runes[j]) {
+				j++
+			}
+			for _, w := range dict.segment(runes[i:j]) {
+				tokens = append(tokens, token{Raw: w, Norm: w})
+			}
+			i = j
+		case isWordRune(r):
+			j := i
+			for j < len(runes) {
+				if isWordRune(runes[j]) {
+					j++
+					continue
+				}
+				if runes[j] == '.' && j+1 < len(runes) && isWordRune(runes[j+1]) {
+					j++
+					continue
+				}
+				break
+			}
+			for j < len(runes) && (runes[j] == '+' || runes[j] == '#') {
+				j++
+			}
+			raw := string(runes[i:j])
+			norm := strings.ToLower(raw)
+			if stemming {
+				norm = stem(norm)
+			}
+			tokens = append(tokens, token{Raw: raw, Norm: norm})
+			i = j
+		default:
+			i++
+		}
+	}
+	return tokens
+}
+
+// stem is a light English suffix stripper for plurals and -ing/-ed forms, applied alike to
+// the text and the taxonomy so both reduce to the same stem. Non-ASCII words are kept.
+func stem(word string) string {
+	if len(word) <= 3 {
+		return word
+	}
+	for _, r := range word {
+		if r < 'a' || r > 'z' {
+			return word
+		}
+	}
+
+	switch {
+	case strings.HasSuffix(word, "sses"):
+		word = word[:len(word)-2]
+	case strings.HasSuffix(word, "ies") && len(word) > 4:
+		word = word[:len(word)-3] + "y"
+	case strings.HasSuffix(word, "s") && !strings.HasSuffix(word, "ss") && !strings.HasSuffix(word, "us") && !strings.HasSuffix(word, "is"):
+		word = word[:len(word)-1]
+	}
+
+	for _, suffix := range []string{"ing", "ed"} {
+		base := strings.TrimSuffix(word, suffix)
+		if base == word || len(base) < 3 || !strings.ContainsAny(base, "aeiouy") {
+			continue
+		}
+		word = base
+		if n := len(word); word[n-1] == word[n-2] && !strings.ContainsRune("aeioulsz", rune(word[n-1])) {
+			word = word[:n-1]
+		}
+		break
+	}
+
+	if len(word) > 3 && strings.HasSuffix(word, "e") {
+		word = word[:len(word)-1]
+	}
+	return word
+}
@@ -0,0 +1,81 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package tagger
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStem(t *testing.T) {
+	tests := map[string]string{
+		"tags":       "tag",
+		"tagging":    "tag",
+		"tagged":     "tag",
+		"libraries":  "library",
+		"library":    "library",
+		"classes":    "class",
+		"class":      "class",
+		"analysis":   "analysis",
+		"computing":  "comput",
+		"compute":    "comput",
+		"kubernetes": "kubernet",
+		"string":     "string",
+		"thing":      "thing",
+		"go":         "go",
+		"café":       "café",
+	}
+	for word, want := range tests {
+		if got := stem(word); got != want {
+			t.Errorf("stem(%s) = %s, want %s", word, got, want)
+		}
+	}
+}
+
+func norms(tokens []token) string {
+	var s []string
+	for _, t := range tokens {
+		s = append(s, t.Norm)
+	}
+	return strings.Join(s, "|")
+}
+
+func TestTokenize(t *testing.T) {
+	dict := newDictionary()
+	got := tokenize("Deploying C++ and C# services with Node.js, e-mail alerts.", dict, true)
+	if want := "deploy|c++|and|c#|servic|with|node.js|e|mail|alert"; norms(got) != want {
+		t.Errorf("tokenize = %s, want %s", norms(got), want)
+	}
+	if got[1].Raw != "C++" {
+		t.Errorf("expected raw spelling kept, got %s", got[1].Raw)
+	}
+	if got := tokenize("Tagging", dict, false); norms(got) != "tagging" {
+		t.Errorf("expected no stemming, got %s", norms(got))
+	}
+}
+
+func TestTokenize_CJK(t *testing.T) {
+	dict := newDictionary()
+	dict.add("机器学习")
+	dict.add("学习")
+	dict.add("K8s集群")
+
+	got := tokenize("我们用机器学习管理K8s集群。学习很重要", dict, true)
+	if want := "我|们|用|机器学习|管|理|k8s|集群|学习|很|重|要"; norms(got) != want {
+		t.Errorf("tokenize = %s, want %s", norms(got), want)
+	}
+}
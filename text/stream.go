@@ -0,0 +1,293 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package text
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/basenana/plugin/api"
+)
+
+const (
+	streamBufferSize      = 64 * 1024
+	defaultLinesPerFile   = 100000
+	streamCheckCancelLine = 4096
+)
+
+// lineMatcher reports whether a line, without its line ending, is selected by grep.
+type lineMatcher func(line string) bool
+
+func newLineMatcher(request *api.Request) (lineMatcher, error) {
+	pattern := api.GetStringParameter("pattern", request, "")
+	if pattern == "" {
+		return nil, fmt.Errorf("pattern is required for grep action")
+	}
+	if !api.GetBoolParameter("use_regex", request, false) {
+		return func(line string) bool { return strings.Contains(line, pattern) }, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regex pattern: %w", err)
+	}
+	return re.MatchString, nil
+}
+
+func actionGrep(content string, request *api.Request) (any, error) {
+	match, err := newLineMatcher(request)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]string, 0)
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if match(line) {
+			result = append(result, line)
+		}
+	}
+	return result, nil
+}
+
+// lineReader yields the lines of a file one at a time, keeping their line endings.
+type lineReader struct {
+	ctx  context.Context
+	r    *bufio.Reader
+	read int
+}
+
+func (l *lineReader) next() (string, error) {
+	l.read++
+	if l.read%streamCheckCancelLine == 0 {
+		if err := l.ctx.Err(); err != nil {
+			return "", err
+		}
+	}
+	line, err := l.r.ReadString('\n')
+	if err == io.EOF && line != "" {
+		return line, nil
+	}
+	return line, err
+}
+
+func trimLineEnding(line string) string {
+	return strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+}
+
+// streamFile processes file_path line by line with constant memory and writes to output_path.
+func (p *TextPlugin) streamFile(ctx context.Context, action string, request *api.Request) (any, error) {
+	switch action {
+	case "search", "grep", "replace", "split":
+	default:
+		return nil, fmt.Errorf("action %s does not support file_path", action)
+	}
+	outputPath := api.GetStringParameter("output_path", request, "")
+	if outputPath == "" && action != "search" {
+		return nil, fmt.Errorf("output_path is required for %s action with file_path", action)
+	}
+
+	filePath := api.GetStringParameter("file_path", request, "")
+	in, err := p.fileRoot.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("open file %s failed: %w", filePath, err)
+	}
+	defer in.Close()
+	lines := &lineReader{ctx: ctx, r: bufio.NewReaderSize(in, streamBufferSize)}
+
+	switch action {
+	case "search":
+		return streamSearch(lines, request)
+	case "grep":
+		match, err := newLineMatcher(request)
+		if err != nil {
+			return nil, err
+		}
+		matched := 0
+		total, err := p.writeLines(outputPath, lines, func(w *bufio.Writer, line string) error {
+			if !match(trimLineEnding(line)) {
+				return nil
+			}
+			matched++
+			_, err := w.WriteString(line)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"output_path": outputPath, "lines": total, "matched": matched}, nil
+	case "replace":
+		pattern := api.GetStringParameter("pattern", request, "")
+		replacement := api.GetStringParameter("replacement", request, "")
+		if pattern == "" || replacement == "" {
+			return nil, fmt.Errorf("pattern and replacement are required for replace action")
+		}
+		if strings.Contains(pattern, "\n") {
+			return nil, fmt.Errorf("pattern can not span lines in stream mode")
+		}
+		count := -1
+		fmt.Sscanf(api.GetStringParameter("count", request, "-1"), "%d", &count)
+		replaced := 0
+		total, err := p.writeLines(outputPath, lines, func(w *bufio.Writer, line string) error {
+			n := strings.Count(line, pattern)
+			if count >= 0 && replaced+n > count {
+				n = count - replaced
+			}
+			if n > 0 {
+				line = strings.Replace(line, pattern, replacement, n)
+				replaced += n
+			}
+			_, err := w.WriteString(line)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"output_path": outputPath, "lines": total, "replaced": replaced}, nil
+	default:
+		return p.streamSplit(outputPath, lines, request)
+	}
+}
+
+func streamSearch(lines *lineReader, request *api.Request) (any, error) {
+	pattern := api.GetStringParameter("pattern", request, "")
+	if pattern == "" {
+		return nil, fmt.Errorf("pattern is required for search action")
+	}
+	if strings.Contains(pattern, "\n") {
+		return nil, fmt.Errorf("pattern can not span lines in stream mode")
+	}
+	for {
+		line, err := lines.next()
+		if err == io.EOF {
+			return false, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if strings.Contains(line, pattern) {
+			return true, nil
+		}
+	}
+}
+
+// writeLines feeds every line to handle and atomically replaces output_path with what it writes,
+// so output_path may be the input file. It returns the number of lines read.
+func (p *TextPlugin) writeLines(outputPath string, lines *lineReader, handle func(w *bufio.Writer, line string) error) (int, error) {
+	tmpPath := outputPath + ".tmp"
+	out, err := p.fileRoot.Create(tmpPath, 0644)
+	if err != nil {
+		return 0, fmt.Errorf("create output %s failed: %w", outputPath, err)
+	}
+	defer func() {
+		out.Close()
+		_ = p.fileRoot.Remove(tmpPath)
+	}()
+
+	w := bufio.NewWriterSize(out, streamBufferSize)
+	total := 0
+	for {
+		line, err := lines.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+		total++
+		if err = handle(w, line); err != nil {
+			return 0, fmt.Errorf("write output %s failed: %w", outputPath, err)
+		}
+	}
+	if err = w.Flush(); err != nil {
+		return 0, fmt.Errorf("write output %s failed: %w", outputPath, err)
+	}
+	if err = out.Close(); err != nil {
+		return 0, fmt.Errorf("write output %s failed: %w", outputPath, err)
+	}
+	if err = p.fileRoot.Rename(tmpPath, outputPath); err != nil {
+		return 0, fmt.Errorf("write output %s failed: %w", outputPath, err)
+	}
+	return total, nil
+}
+
+// streamSplit writes every lines_per_file lines to <output_path base>_001<ext>, _002 and so on.
+func (p *TextPlugin) streamSplit(outputPath string, lines *lineReader, request *api.Request) (any, error) {
+	perFile := defaultLinesPerFile
+	if raw := api.GetStringParameter("lines_per_file", request, ""); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid lines_per_file [%s]: expect positive integer", raw)
+		}
+		perFile = n
+	}
+
+	var (
+		ext   = filepath.Ext(outputPath)
+		base  = strings.TrimSuffix(outputPath, ext)
+		files = make([]string, 0)
+		out   *os.File
+		w     *bufio.Writer
+		total int
+	)
+	closeChunk := func() error {
+		if out == nil {
+			return nil
+		}
+		defer func() { out = nil }()
+		if err := w.Flush(); err != nil {
+			out.Close()
+			return err
+		}
+		return out.Close()
+	}
+	defer closeChunk()
+
+	for {
+		line, err := lines.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if total%perFile == 0 {
+			if err = closeChunk(); err != nil {
+				return nil, fmt.Errorf("write output %s failed: %w", files[len(files)-1], err)
+			}
+			name := fmt.Sprintf("%s_%03d%s", base, len(files)+1, ext)
+			if out, err = p.fileRoot.Create(name, 0644); err != nil {
+				return nil, fmt.Errorf("create output %s failed: %w", name, err)
+			}
+			w = bufio.NewWriterSize(out, streamBufferSize)
+			files = append(files, name)
+		}
+		if _, err = w.WriteString(line); err != nil {
+			return nil, fmt.Errorf("write output %s failed: %w", files[len(files)-1], err)
+		}
+		total++
+	}
+	if err := closeChunk(); err != nil {
+		return nil, fmt.Errorf("write output %s failed: %w", files[len(files)-1], err)
+	}
+	return map[string]any{"files": files, "lines": total}, nil
+}
@@ -0,0 +1,167 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package text
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/basenana/plugin/api"
+)
+
+const streamLog = "INFO start\r\nERROR disk full\nINFO retry\nWARN disk slow\nERROR disk gone"
+
+func (tc *testContext) run(t *testing.T, params map[string]any) *api.Response {
+	t.Helper()
+	resp, err := tc.newPlugin().Run(context.Background(), &api.Request{Parameter: params})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func (tc *testContext) readFile(t *testing.T, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(tc.workdir, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestTextPlugin_Stream_Grep(t *testing.T) {
+	tc := newTestContext(t)
+	if err := tc.fa.Write("app.log", []byte(streamLog), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	resp := tc.run(t, map[string]any{"action": "grep", "file_path": "app.log", "output_path": "errors.log", "pattern": "ERROR"})
+	if !resp.IsSucceed {
+		t.Fatalf("expected success, got failure: %s", resp.Message)
+	}
+	want := map[string]any{"output_path": "errors.log", "lines": 5, "matched": 2}
+	if !reflect.DeepEqual(resp.Results["result"], want) {
+		t.Errorf("unexpected result %v", resp.Results["result"])
+	}
+	if got := tc.readFile(t, "errors.log"); got != "ERROR disk full\nERROR disk gone" {
+		t.Errorf("unexpected output %q", got)
+	}
+
+	resp = tc.run(t, map[string]any{"action": "grep", "file_path": "app.log", "output_path": "disk.log", "pattern": "^(INFO|WARN) .*t$", "use_regex": "true"})
+	if !resp.IsSucceed {
+		t.Fatalf("expected success, got failure: %s", resp.Message)
+	}
+	if got := tc.readFile(t, "disk.log"); got != "INFO start\r\n" {
+		t.Errorf("regex should match without the line ending, got %q", got)
+	}
+}
+
+func TestTextPlugin_Grep_Content(t *testing.T) {
+	tc := newTestContext(t)
+	resp := tc.run(t, map[string]any{"action": "grep", "content": streamLog, "pattern": "disk"})
+	want := []string{"ERROR disk full", "WARN disk slow", "ERROR disk gone"}
+	if !reflect.DeepEqual(resp.Results["result"], want) {
+		t.Errorf("unexpected result %v", resp.Results["result"])
+	}
+}
+
+func TestTextPlugin_Stream_ReplaceInPlace(t *testing.T) {
+	tc := newTestContext(t)
+	long := strings.Repeat("disk ", streamBufferSize/2)
+	if err := tc.fa.Write("app.log", []byte(streamLog+"\n"+long+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	resp := tc.run(t, map[string]any{"action": "replace", "file_path": "app.log", "output_path": "app.log",
+		"pattern": "disk", "replacement": "volume", "count": "2"})
+	if !resp.IsSucceed {
+		t.Fatalf("expected success, got failure: %s", resp.Message)
+	}
+	want := map[string]any{"output_path": "app.log", "lines": 6, "replaced": 2}
+	if !reflect.DeepEqual(resp.Results["result"], want) {
+		t.Errorf("unexpected result %v", resp.Results["result"])
+	}
+	expected := "INFO start\r\nERROR volume full\nINFO retry\nWARN volume slow\nERROR disk gone\n" + long + "\n"
+	if got := tc.readFile(t, "app.log"); got != expected {
+		t.Errorf("unexpected output %q", got[:100])
+	}
+	if tc.fa.Exists("app.log.tmp") {
+		t.Errorf("temporary output should be removed")
+	}
+}
+
+func TestTextPlugin_Stream_SplitAndSearch(t *testing.T) {
+	tc := newTestContext(t)
+	if err := tc.fa.Write("app.log", []byte(streamLog), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	resp := tc.run(t, map[string]any{"action": "split", "file_path": "app.log", "output_path": "parts/app.log", "lines_per_file": "2"})
+	if resp.IsSucceed {
+		t.Errorf("expected failure for a missing output directory")
+	}
+	if err := tc.fa.MkdirAll("parts", 0755); err != nil {
+		t.Fatal(err)
+	}
+	resp = tc.run(t, map[string]any{"action": "split", "file_path": "app.log", "output_path": "parts/app.log", "lines_per_file": "2"})
+	if !resp.IsSucceed {
+		t.Fatalf("expected success, got failure: %s", resp.Message)
+	}
+	files := []string{"parts/app_001.log", "parts/app_002.log", "parts/app_003.log"}
+	want := map[string]any{"files": files, "lines": 5}
+	if !reflect.DeepEqual(resp.Results["result"], want) {
+		t.Errorf("unexpected result %v", resp.Results["result"])
+	}
+	if got := tc.readFile(t, files[1]); got != "INFO retry\nWARN disk slow\n" {
+		t.Errorf("unexpected chunk %q", got)
+	}
+
+	resp = tc.run(t, map[string]any{"action": "search", "file_path": "app.log", "pattern": "retry"})
+	if resp.Results["result"] != true {
+		t.Errorf("expected true, got %v", resp.Results["result"])
+	}
+	resp = tc.run(t, map[string]any{"action": "search", "file_path": "app.log", "pattern": "panic"})
+	if resp.Results["result"] != false {
+		t.Errorf("expected false, got %v", resp.Results["result"])
+	}
+}
+
+func TestTextPlugin_Stream_Invalid(t *testing.T) {
+	tc := newTestContext(t)
+	if err := tc.fa.Write("app.log", []byte(streamLog), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, params := range []map[string]any{
+		{"action": "grep", "file_path": "app.log", "pattern": "ERROR"},
+		{"action": "regex", "file_path": "app.log", "output_path": "out.log", "pattern": "E+"},
+		{"action": "grep", "file_path": "app.log", "content": "x", "output_path": "out.log", "pattern": "E"},
+		{"action": "grep", "file_path": "missing.log", "output_path": "out.log", "pattern": "E"},
+		{"action": "grep", "file_path": "../app.log", "output_path": "out.log", "pattern": "E"},
+		{"action": "split", "file_path": "app.log", "output_path": "out.log", "lines_per_file": "0"},
+	} {
+		if resp := tc.run(t, params); resp.IsSucceed {
+			t.Errorf("expected failure for %v", params)
+		}
+	}
+	if tc.fa.Exists("out.log") {
+		t.Errorf("failed runs should not write output")
+	}
+}
@@ -25,6 +25,7 @@ import (
 	"github.com/basenana/plugin/api"
 	"github.com/basenana/plugin/logger"
 	"github.com/basenana/plugin/types"
+	"github.com/basenana/plugin/utils"
 	"go.uber.org/zap"
 )
 
@@ -41,13 +42,23 @@ var PluginSpec = types.PluginSpec{
 		{
 			Name:        "action",
 			Required:    true,
-			Description: "Action: search, replace, regex, split, join",
-			Options:     []string{"search", "replace", "regex", "split", "join"},
+			Description: "Action: search, replace, regex, grep, split, join",
+			Options:     []string{"search", "replace", "regex", "grep", "split", "join"},
 		},
 		{
 			Name:        "content",
 			Required:    false,
-			Description: "Input text (not required for join)",
+			Description: "Input text (not required for join or with file_path)",
+		},
+		{
+			Name:        "file_path",
+			Required:    false,
+			Description: "Input file streamed line by line instead of content, for search, grep, replace and split",
+		},
+		{
+			Name:        "output_path",
+			Required:    false,
+			Description: "Output file of grep and replace with file_path, or the name pattern of the split files",
 		},
 		{
 			Name:        "result_key",
@@ -59,12 +70,14 @@ var PluginSpec = types.PluginSpec{
 }
 
 type TextPlugin struct {
-	logger *zap.SugaredLogger
+	logger   *zap.SugaredLogger
+	fileRoot *utils.FileAccess
 }
 
 func NewTextPlugin(ps types.PluginCall) types.Plugin {
 	return &TextPlugin{
-		logger: logger.NewPluginLogger(pluginName, ps.JobID),
+		logger:   logger.NewPluginLogger(pluginName, ps.JobID),
+		fileRoot: utils.NewFileAccess(ps.WorkingPath, ps.JobWorkingPath),
 	}
 }
 
@@ -83,12 +96,17 @@ func (p *TextPlugin) Version() string {
 func (p *TextPlugin) Run(ctx context.Context, request *api.Request) (*api.Response, error) {
 	action := api.GetStringParameter("action", request, "")
 	content := api.GetStringParameter("content", request, "")
+	filePath := api.GetStringParameter("file_path", request, "")
 
 	if action == "" {
 		return api.NewFailedResponse("action is required"), nil
 	}
 
-	if content == "" && action != "join" {
+	if content != "" && filePath != "" {
+		return api.NewFailedResponse("content and file_path are exclusive"), nil
+	}
+
+	if content == "" && filePath == "" && action != "join" {
 		return api.NewFailedResponse("content is required"), nil
 	}
 
@@ -98,16 +116,20 @@ func (p *TextPlugin) Run(ctx context.Context, request *api.Request) (*api.Respon
 	var result any
 	var err error
 
-	switch action {
-	case "search":
+	switch {
+	case filePath != "":
+		result, err = p.streamFile(ctx, action, request)
+	case action == "search":
 		result, err = actionSearch(content, request)
-	case "replace":
+	case action == "replace":
 		result, err = actionReplace(content, request)
-	case "regex":
+	case action == "regex":
 		result, err = actionRegex(content, request)
-	case "split":
+	case action == "grep":
+		result, err = actionGrep(content, request)
+	case action == "split":
 		result, err = actionSplit(content, request)
-	case "join":
+	case action == "join":
 		result, err = actionJoin(request)
 	default:
 		return api.NewFailedResponse(fmt.Sprintf("unknown action: %s", action)), nil
@@ -0,0 +1,159 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package translation
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// glossaryTerm is the approved translation of a term, variants are known
+// unwanted translations that are rewritten to the approved one.
+type glossaryTerm struct {
+	Translation string   `json:"translation"`
+	Variants    []string `json:"variants,omitempty"`
+}
+
+// glossary holds the terms of one language pair, keyed by the source term.
+type glossary struct {
+	Terms map[string]glossaryTerm `json:"terms"`
+}
+
+// memoryEntry is one translated segment kept in the translation memory.
+type memoryEntry struct {
+	Source      string `json:"source"`
+	Translation string `json:"translation"`
+	UpdatedAt   int64  `json:"updated_at"`
+}
+
+type termMatch struct {
+	Term        string `json:"term"`
+	Translation string `json:"translation"`
+}
+
+type violation struct {
+	Segment     int    `json:"segment"`
+	Term        string `json:"term"`
+	Translation string `json:"translation"`
+}
+
+// sortedTerms returns the source terms longest first, so "machine learning"
+// is matched before "learning".
+func (g *glossary) sortedTerms() []string {
+	terms := make([]string, 0, len(g.Terms))
+	for term := range g.Terms {
+		terms = append(terms, term)
+	}
+	sort.Slice(terms, func(i, j int) bool {
+		if len(terms[i]) != len(terms[j]) {
+			return len(terms[i]) > len(terms[j])
+		}
+		return terms[i] < terms[j]
+	})
+	return terms
+}
+
+// match returns the glossary terms used in text.
+func (g *glossary) match(text string) []termMatch {
+	var matches []termMatch
+	for _, term := range g.sortedTerms() {
+		if termPattern(term).MatchString(text) {
+			matches = append(matches, termMatch{Term: term, Translation: g.Terms[term].Translation})
+		}
+	}
+	return matches
+}
+
+// enforce rewrites unwanted variants of the matched terms to their approved translation.
+func (g *glossary) enforce(translated string, matches []termMatch) (string, int) {
+	var replaced int
+	for _, m := range matches {
+		for _, variant := range g.Terms[m.Term].Variants {
+			pattern := termPattern(variant)
+			replaced += len(pattern.FindAllStringIndex(translated, -1))
+			translated = pattern.ReplaceAllLiteralString(translated, m.Translation)
+		}
+	}
+	return translated, replaced
+}
+
+// termPattern matches a term case-insensitively, on word boundaries where the
+// term starts or ends with a letter or digit of a space separated script.
+func termPattern(term string) *regexp.Regexp {
+	pattern := regexp.QuoteMeta(term)
+	if first, _ := utf8.DecodeRuneInString(term); isWordRune(first) {
+		pattern = `\b` + pattern
+	}
+	if last, _ := utf8.DecodeLastRuneInString(term); isWordRune(last) {
+		pattern += `\b`
+	}
+	return regexp.MustCompile("(?i)" + pattern)
+}
+
+func isWordRune(r rune) bool {
+	return r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_')
+}
+
+// instructions renders the matched terms as a prompt snippet for the translating LLM.
+func instructions(sourceLang, targetLang string, matches []termMatch) string {
+	if len(matches) == 0 {
+		return ""
+	}
+	buf := &strings.Builder{}
+	fmt.Fprintf(buf, "Translate the following terms from %s to %s exactly as listed:\n", sourceLang, targetLang)
+	for _, m := range matches {
+		fmt.Fprintf(buf, "- %s => %s\n", m.Term, m.Translation)
+	}
+	return buf.String()
+}
+
+// splitSegments splits a document into paragraphs separated by blank lines.
+func splitSegments(text string) []string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	var (
+		segments []string
+		current  []string
+	)
+	flush := func() {
+		if len(current) > 0 {
+			segments = append(segments, strings.Join(current, "\n"))
+			current = nil
+		}
+	}
+	for _, line := range strings.Split(text, "\n") {
+		if strings.TrimSpace(line) == "" {
+			flush()
+			continue
+		}
+		current = append(current, strings.TrimRight(line, " \t"))
+	}
+	flush()
+	return segments
+}
+
+// segmentKey identifies a segment in the translation memory regardless of whitespace and case.
+func segmentKey(segment string) string {
+	normalized := strings.ToLower(strings.Join(strings.Fields(segment), " "))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
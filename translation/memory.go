@@ -0,0 +1,375 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package translation
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/logger"
+	"github.com/basenana/plugin/types"
+	"github.com/basenana/plugin/utils"
+	"go.uber.org/zap"
+)
+
+const (
+	pluginName    = "translation_memory"
+	pluginVersion = "1.0"
+
+	actionAddTerm    = "add_term"
+	actionRemoveTerm = "remove_term"
+	actionListTerms  = "list_terms"
+	actionPrepare    = "prepare"
+	actionApply      = "apply"
+)
+
+var PluginSpec = types.PluginSpec{
+	Name:         pluginName,
+	Version:      pluginVersion,
+	Type:         types.TypeProcess,
+	Dependencies: []types.Dependency{{Kind: types.DependencyCapability, Name: types.CapabilityStore}},
+	Parameters: []types.ParameterSpec{
+		{
+			Name:        "action",
+			Required:    true,
+			Description: "Action: add_term, remove_term, list_terms, prepare, apply",
+			Options:     []string{actionAddTerm, actionRemoveTerm, actionListTerms, actionPrepare, actionApply},
+		},
+		{
+			Name:        "source_lang",
+			Required:    true,
+			Description: "Source language code, e.g. en",
+		},
+		{
+			Name:        "target_lang",
+			Required:    true,
+			Description: "Target language code, e.g. zh",
+		},
+		{
+			Name:        "term",
+			Required:    false,
+			Description: "Source term (add_term, remove_term)",
+		},
+		{
+			Name:        "translation",
+			Required:    false,
+			Description: "Approved translation of the term (add_term)",
+		},
+		{
+			Name:        "variants",
+			Required:    false,
+			Description: "Comma-separated unwanted translations rewritten to the approved one (add_term)",
+		},
+		{
+			Name:        "file_path",
+			Required:    false,
+			Description: "Source document in the working path (prepare, apply)",
+		},
+		{
+			Name:        "translated_path",
+			Required:    false,
+			Description: "Translated document in the working path (apply)",
+		},
+		{
+			Name:        "output_path",
+			Required:    false,
+			Description: "Where the enforced translation is written, defaults to translated_path (apply)",
+		},
+		{
+			Name:        "title",
+			Required:    false,
+			Description: "Translated title, returned as entry properties in target_lang (apply)",
+		},
+		{
+			Name:        "abstract",
+			Required:    false,
+			Description: "Translated abstract, returned as entry properties in target_lang (apply)",
+		},
+	},
+}
+
+type TranslationMemoryPlugin struct {
+	logger    *zap.SugaredLogger
+	fileRoot  *utils.FileAccess
+	namespace string
+	now       func() time.Time
+}
+
+func NewTranslationMemoryPlugin(ps types.PluginCall) types.Plugin {
+	return &TranslationMemoryPlugin{
+		logger:    logger.NewPluginLogger(pluginName, ps.JobID),
+		fileRoot:  utils.NewFileAccess(ps.WorkingPath, ps.JobWorkingPath),
+		namespace: ps.Namespace,
+		now:       time.Now,
+	}
+}
+
+func (p *TranslationMemoryPlugin) Name() string {
+	return pluginName
+}
+
+func (p *TranslationMemoryPlugin) Type() types.PluginType {
+	return types.TypeProcess
+}
+
+func (p *TranslationMemoryPlugin) Version() string {
+	return pluginVersion
+}
+
+func (p *TranslationMemoryPlugin) Run(ctx context.Context, request *api.Request) (*api.Response, error) {
+	action := api.GetStringParameter("action", request, "")
+	sourceLang := strings.ToLower(api.GetStringParameter("source_lang", request, ""))
+	targetLang := strings.ToLower(api.GetStringParameter("target_lang", request, ""))
+
+	if action == "" {
+		return api.NewFailedResponse("action is required"), nil
+	}
+	if sourceLang == "" || targetLang == "" {
+		return api.NewFailedResponse("source_lang and target_lang are required"), nil
+	}
+	if request.Store == nil {
+		return api.NewFailedResponse("persistent store is required"), nil
+	}
+
+	p.logger.Infow("translation memory started", "action", action, "source_lang", sourceLang, "target_lang", targetLang)
+
+	pair := sourceLang + ":" + targetLang
+	switch action {
+	case actionAddTerm:
+		return p.addTerm(ctx, request, pair)
+	case actionRemoveTerm:
+		return p.removeTerm(ctx, request, pair)
+	case actionListTerms:
+		g := p.loadGlossary(ctx, request.Store, pair)
+		return api.NewResponseWithResult(map[string]any{"terms": g.Terms, "total": len(g.Terms)}), nil
+	case actionPrepare:
+		return p.prepare(ctx, request, pair, sourceLang, targetLang)
+	case actionApply:
+		return p.apply(ctx, request, pair, sourceLang, targetLang)
+	default:
+		return api.NewFailedResponse(fmt.Sprintf("unknown action: %s", action)), nil
+	}
+}
+
+func (p *TranslationMemoryPlugin) addTerm(ctx context.Context, request *api.Request, pair string) (*api.Response, error) {
+	term := strings.TrimSpace(api.GetStringParameter("term", request, ""))
+	translation := strings.TrimSpace(api.GetStringParameter("translation", request, ""))
+	if term == "" || translation == "" {
+		return api.NewFailedResponse("term and translation are required for add_term action"), nil
+	}
+
+	entry := glossaryTerm{Translation: translation}
+	for _, v := range strings.Split(api.GetStringParameter("variants", request, ""), ",") {
+		if v = strings.TrimSpace(v); v != "" && !strings.EqualFold(v, translation) {
+			entry.Variants = append(entry.Variants, v)
+		}
+	}
+
+	g := p.loadGlossary(ctx, request.Store, pair)
+	g.Terms[term] = entry
+	if err := p.saveGlossary(ctx, request.Store, pair, g); err != nil {
+		return api.NewFailedResponse(err.Error()), nil
+	}
+	return api.NewResponseWithResult(map[string]any{"term": term, "translation": translation, "variants": entry.Variants}), nil
+}
+
+func (p *TranslationMemoryPlugin) removeTerm(ctx context.Context, request *api.Request, pair string) (*api.Response, error) {
+	term := strings.TrimSpace(api.GetStringParameter("term", request, ""))
+	if term == "" {
+		return api.NewFailedResponse("term is required for remove_term action"), nil
+	}
+
+	g := p.loadGlossary(ctx, request.Store, pair)
+	_, found := g.Terms[term]
+	if found {
+		delete(g.Terms, term)
+		if err := p.saveGlossary(ctx, request.Store, pair, g); err != nil {
+			return api.NewFailedResponse(err.Error()), nil
+		}
+	}
+	return api.NewResponseWithResult(map[string]any{"term": term, "found": found}), nil
+}
+
+// prepare runs before the LLM call: segments found in the memory are returned with their
+// translation, and the glossary terms of the document are rendered as prompt instructions.
+func (p *TranslationMemoryPlugin) prepare(ctx context.Context, request *api.Request, pair, sourceLang, targetLang string) (*api.Response, error) {
+	filePath := api.GetStringParameter("file_path", request, "")
+	if filePath == "" {
+		return api.NewFailedResponse("file_path is required for prepare action"), nil
+	}
+	data, err := p.fileRoot.Read(filePath)
+	if err != nil {
+		return api.NewFailedResponse(fmt.Sprintf("read file %s failed: %s", filePath, err)), nil
+	}
+
+	var (
+		g        = p.loadGlossary(ctx, request.Store, pair)
+		segments = splitSegments(string(data))
+		results  = make([]map[string]any, 0, len(segments))
+		hits     int
+	)
+	for i, segment := range segments {
+		item := map[string]any{"index": i, "source": segment}
+		if entry, ok := p.loadMemory(ctx, request.Store, pair, segment); ok {
+			item["translation"] = entry.Translation
+			hits++
+		}
+		if matches := g.match(segment); len(matches) > 0 {
+			item["terms"] = matches
+		}
+		results = append(results, item)
+	}
+
+	docTerms := g.match(string(data))
+	p.logger.Infow("translation memory prepared", "segments", len(segments), "hits", hits, "terms", len(docTerms))
+	return api.NewResponseWithResult(map[string]any{
+		"file_path":    filePath,
+		"segments":     results,
+		"hits":         hits,
+		"misses":       len(segments) - hits,
+		"terms":        docTerms,
+		"instructions": instructions(sourceLang, targetLang, docTerms),
+	}), nil
+}
+
+// apply runs after the LLM call: unwanted term variants are rewritten, segments missing an
+// approved term are reported, and the remaining segment pairs are added to the memory.
+func (p *TranslationMemoryPlugin) apply(ctx context.Context, request *api.Request, pair, sourceLang, targetLang string) (*api.Response, error) {
+	filePath := api.GetStringParameter("file_path", request, "")
+	translatedPath := api.GetStringParameter("translated_path", request, "")
+	if filePath == "" || translatedPath == "" {
+		return api.NewFailedResponse("file_path and translated_path are required for apply action"), nil
+	}
+	outputPath := api.GetStringParameter("output_path", request, translatedPath)
+
+	source, err := p.fileRoot.Read(filePath)
+	if err != nil {
+		return api.NewFailedResponse(fmt.Sprintf("read file %s failed: %s", filePath, err)), nil
+	}
+	translated, err := p.fileRoot.Read(translatedPath)
+	if err != nil {
+		return api.NewFailedResponse(fmt.Sprintf("read file %s failed: %s", translatedPath, err)), nil
+	}
+
+	var (
+		g                  = p.loadGlossary(ctx, request.Store, pair)
+		output, replaced   = g.enforce(string(translated), g.match(string(source)))
+		sourceSegments     = splitSegments(string(source))
+		translatedSegments = splitSegments(output)
+		aligned            = len(sourceSegments) == len(translatedSegments)
+		violations         = make([]violation, 0)
+		stored             int
+	)
+
+	if aligned {
+		for i, segment := range sourceSegments {
+			var missing bool
+			target := strings.ToLower(translatedSegments[i])
+			for _, m := range g.match(segment) {
+				if !strings.Contains(target, strings.ToLower(m.Translation)) {
+					violations = append(violations, violation{Segment: i, Term: m.Term, Translation: m.Translation})
+					missing = true
+				}
+			}
+			// only translations following the glossary are remembered
+			if missing {
+				continue
+			}
+			if err = p.saveMemory(ctx, request.Store, pair, segment, translatedSegments[i]); err != nil {
+				p.logger.Warnw("save translation memory failed", "segment", i, "error", err)
+				continue
+			}
+			stored++
+		}
+	} else {
+		p.logger.Warnw("segments not aligned, skip translation memory update",
+			"source_segments", len(sourceSegments), "translated_segments", len(translatedSegments))
+	}
+
+	if replaced > 0 || outputPath != translatedPath {
+		if err = p.fileRoot.Write(outputPath, []byte(output), 0644); err != nil {
+			return api.NewFailedResponse(fmt.Sprintf("write file %s failed: %s", outputPath, err)), nil
+		}
+	}
+
+	results := map[string]any{
+		"output_path": outputPath,
+		"replaced":    replaced,
+		"violations":  violations,
+		"stored":      stored,
+		"aligned":     aligned,
+	}
+	if props, ok := translatedProperties(request, g, string(source), sourceLang, targetLang); ok {
+		results["properties"] = utils.MarshalMap(props)
+	}
+
+	p.logger.Infow("translation memory applied", "replaced", replaced, "violations", len(violations), "stored", stored, "aligned", aligned)
+	return api.NewResponseWithResult(results), nil
+}
+
+// translatedProperties enforces the glossary on the translated title and abstract and returns
+// them as entry properties for the update plugin.
+func translatedProperties(request *api.Request, g *glossary, source, sourceLang, targetLang string) (types.Properties, bool) {
+	var (
+		matches = g.match(source)
+		t       types.TranslatedProperties
+	)
+	t.Title, _ = g.enforce(strings.TrimSpace(api.GetStringParameter("title", request, "")), matches)
+	t.Abstract, _ = g.enforce(strings.TrimSpace(api.GetStringParameter("abstract", request, "")), matches)
+	if t.IsEmpty() {
+		return types.Properties{}, false
+	}
+	props := types.Properties{Language: sourceLang}
+	props.SetTranslation(targetLang, t)
+	return props, true
+}
+
+func (p *TranslationMemoryPlugin) loadGlossary(ctx context.Context, store api.PersistentStore, pair string) *glossary {
+	g := &glossary{}
+	if err := store.Load(ctx, pluginName, p.namespace+"/glossary", pair, g); err != nil || g.Terms == nil {
+		g.Terms = make(map[string]glossaryTerm)
+	}
+	return g
+}
+
+func (p *TranslationMemoryPlugin) saveGlossary(ctx context.Context, store api.PersistentStore, pair string, g *glossary) error {
+	if err := store.Save(ctx, pluginName, p.namespace+"/glossary", pair, g); err != nil {
+		p.logger.Warnw("save glossary failed", "pair", pair, "error", err)
+		return fmt.Errorf("save glossary failed: %s", err)
+	}
+	return nil
+}
+
+func (p *TranslationMemoryPlugin) loadMemory(ctx context.Context, store api.PersistentStore, pair, segment string) (*memoryEntry, bool) {
+	entry := &memoryEntry{}
+	if err := store.Load(ctx, pluginName, p.memoryGroup(pair), segmentKey(segment), entry); err != nil || entry.Translation == "" {
+		return nil, false
+	}
+	return entry, true
+}
+
+func (p *TranslationMemoryPlugin) saveMemory(ctx context.Context, store api.PersistentStore, pair, segment, translation string) error {
+	entry := &memoryEntry{Source: segment, Translation: translation, UpdatedAt: p.now().Unix()}
+	return store.Save(ctx, pluginName, p.memoryGroup(pair), segmentKey(segment), entry)
+}
+
+func (p *TranslationMemoryPlugin) memoryGroup(pair string) string {
+	return fmt.Sprintf("%s/memory/%s", p.namespace, pair)
+}
@@ -0,0 +1,287 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package translation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/logger"
+	"github.com/basenana/plugin/types"
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) {
+	logger.SetLogger(zap.NewNop().Sugar())
+	os.Exit(m.Run())
+}
+
+type memStore struct {
+	mux  sync.Mutex
+	data map[string][]byte
+}
+
+func newMemStore() *memStore {
+	return &memStore{data: map[string][]byte{}}
+}
+
+func (m *memStore) Load(ctx context.Context, source, group, key string, data any) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	raw, ok := m.data[source+"/"+group+"/"+key]
+	if !ok {
+		return fmt.Errorf("no record")
+	}
+	return json.Unmarshal(raw, data)
+}
+
+func (m *memStore) Save(ctx context.Context, source, group, key string, data any) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	m.data[source+"/"+group+"/"+key] = raw
+	return nil
+}
+
+func newPlugin(t *testing.T, namespace string) (*TranslationMemoryPlugin, string) {
+	workdir := t.TempDir()
+	return NewTranslationMemoryPlugin(types.PluginCall{
+		JobID:       "test-job",
+		Namespace:   namespace,
+		WorkingPath: workdir,
+	}).(*TranslationMemoryPlugin), workdir
+}
+
+func run(t *testing.T, p *TranslationMemoryPlugin, store api.PersistentStore, params map[string]any) *api.Response {
+	t.Helper()
+	params["source_lang"] = "en"
+	params["target_lang"] = "zh"
+	resp, err := p.Run(context.Background(), &api.Request{Parameter: params, Store: store})
+	if err != nil {
+		t.Fatalf("Run(%v) failed: %v", params, err)
+	}
+	return resp
+}
+
+func writeFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTermPattern(t *testing.T) {
+	tests := []struct {
+		term, text string
+		want       bool
+	}{
+		{"cache", "The Cache is warm", true},
+		{"cache", "cached values", false},
+		{"C++", "written in c++ today", true},
+		{"缓存", "这是缓存层", true},
+		{"node.js", "nodexjs", false},
+	}
+	for _, tt := range tests {
+		if got := termPattern(tt.term).MatchString(tt.text); got != tt.want {
+			t.Errorf("termPattern(%q).MatchString(%q) = %v, want %v", tt.term, tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestSplitSegments(t *testing.T) {
+	got := splitSegments("# Title\r\n\r\nfirst line\nsecond line  \n\n\n  \nlast")
+	want := []string{"# Title", "first line\nsecond line", "last"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("splitSegments() = %q, want %q", got, want)
+	}
+	if segmentKey("Hello   World") != segmentKey("hello world") {
+		t.Errorf("segmentKey should ignore case and whitespace")
+	}
+}
+
+func TestGlossary_MatchLongestFirst(t *testing.T) {
+	g := &glossary{Terms: map[string]glossaryTerm{
+		"learning":         {Translation: "学习"},
+		"machine learning": {Translation: "机器学习"},
+	}}
+	matches := g.match("Machine learning is fun")
+	if len(matches) != 2 || matches[0].Term != "machine learning" {
+		t.Errorf("unexpected matches %v", matches)
+	}
+	if instructions("en", "zh", nil) != "" {
+		t.Errorf("instructions without terms should be empty")
+	}
+	if got := instructions("en", "zh", matches); !strings.Contains(got, "- machine learning => 机器学习\n") {
+		t.Errorf("unexpected instructions %q", got)
+	}
+}
+
+func TestTranslationMemory_Terms(t *testing.T) {
+	p, _ := newPlugin(t, "ns")
+	store := newMemStore()
+
+	resp := run(t, p, store, map[string]any{"action": "add_term", "term": "pod", "translation": "Pod", "variants": "豆荚, 容器组,pod"})
+	if !resp.IsSucceed {
+		t.Fatalf("add_term failed: %s", resp.Message)
+	}
+	if variants := resp.Results["variants"].([]string); len(variants) != 2 {
+		t.Errorf("the approved translation should not be a variant, got %v", variants)
+	}
+	run(t, p, store, map[string]any{"action": "add_term", "term": "node", "translation": "节点"})
+
+	resp = run(t, p, store, map[string]any{"action": "list_terms"})
+	if resp.Results["total"] != 2 {
+		t.Errorf("expected 2 terms, got %v", resp.Results["total"])
+	}
+
+	resp = run(t, p, store, map[string]any{"action": "remove_term", "term": "node"})
+	if resp.Results["found"] != true {
+		t.Errorf("expected node to be removed")
+	}
+	resp = run(t, p, store, map[string]any{"action": "remove_term", "term": "node"})
+	if resp.Results["found"] != false {
+		t.Errorf("expected node to be gone")
+	}
+
+	other, _ := newPlugin(t, "other-ns")
+	if resp = run(t, other, store, map[string]any{"action": "list_terms"}); resp.Results["total"] != 0 {
+		t.Errorf("glossary should be scoped to the namespace, got %v", resp.Results["total"])
+	}
+}
+
+func TestTranslationMemory_PrepareAndApply(t *testing.T) {
+	p, workdir := newPlugin(t, "ns")
+	store := newMemStore()
+	run(t, p, store, map[string]any{"action": "add_term", "term": "pod", "translation": "Pod", "variants": "豆荚"})
+	run(t, p, store, map[string]any{"action": "add_term", "term": "node", "translation": "节点"})
+
+	writeFile(t, workdir, "doc.md", "Every pod runs on a node.\n\nThe pod restarts.\n\nDone.")
+	writeFile(t, workdir, "doc.zh.md", "每个豆荚运行在一个节点上。\n\n该豆荚会重启。\n\n完成。")
+
+	resp := run(t, p, store, map[string]any{"action": "prepare", "file_path": "doc.md"})
+	if !resp.IsSucceed {
+		t.Fatalf("prepare failed: %s", resp.Message)
+	}
+	if resp.Results["hits"] != 0 || resp.Results["misses"] != 3 {
+		t.Errorf("expected no memory hits, got %v/%v", resp.Results["hits"], resp.Results["misses"])
+	}
+	if instr := resp.Results["instructions"].(string); !strings.Contains(instr, "- pod => Pod") || !strings.Contains(instr, "- node => 节点") {
+		t.Errorf("unexpected instructions %q", instr)
+	}
+
+	resp = run(t, p, store, map[string]any{"action": "apply", "file_path": "doc.md", "translated_path": "doc.zh.md", "output_path": "out.md"})
+	if !resp.IsSucceed {
+		t.Fatalf("apply failed: %s", resp.Message)
+	}
+	if resp.Results["replaced"] != 2 || resp.Results["stored"] != 3 || resp.Results["aligned"] != true {
+		t.Errorf("unexpected apply results %v", resp.Results)
+	}
+	out, _ := os.ReadFile(filepath.Join(workdir, "out.md"))
+	if strings.Contains(string(out), "豆荚") || !strings.Contains(string(out), "每个Pod运行在一个节点上。") {
+		t.Errorf("variants not enforced: %q", out)
+	}
+
+	resp = run(t, p, store, map[string]any{"action": "prepare", "file_path": "doc.md"})
+	segments := resp.Results["segments"].([]map[string]any)
+	if resp.Results["hits"] != 3 || segments[1]["translation"] != "该Pod会重启。" {
+		t.Errorf("expected memory hits after apply, got %v", segments)
+	}
+}
+
+func TestTranslationMemory_ApplyViolations(t *testing.T) {
+	p, workdir := newPlugin(t, "ns")
+	store := newMemStore()
+	run(t, p, store, map[string]any{"action": "add_term", "term": "node", "translation": "节点"})
+
+	writeFile(t, workdir, "doc.md", "Drain the node.\n\nThen wait.")
+	writeFile(t, workdir, "doc.zh.md", "排空这台机器。\n\n然后等待。")
+
+	resp := run(t, p, store, map[string]any{"action": "apply", "file_path": "doc.md", "translated_path": "doc.zh.md"})
+	violations := resp.Results["violations"].([]violation)
+	if len(violations) != 1 || violations[0].Segment != 0 || violations[0].Term != "node" {
+		t.Errorf("unexpected violations %v", violations)
+	}
+	if resp.Results["stored"] != 1 {
+		t.Errorf("only the segment following the glossary should be stored, got %v", resp.Results["stored"])
+	}
+
+	writeFile(t, workdir, "doc.zh.md", "排空节点。然后等待。")
+	resp = run(t, p, store, map[string]any{"action": "apply", "file_path": "doc.md", "translated_path": "doc.zh.md"})
+	if resp.Results["aligned"] != false || resp.Results["stored"] != 0 {
+		t.Errorf("misaligned documents should not update the memory, got %v", resp.Results)
+	}
+}
+
+func TestTranslationMemory_ApplyProperties(t *testing.T) {
+	p, workdir := newPlugin(t, "ns")
+	store := newMemStore()
+	run(t, p, store, map[string]any{"action": "add_term", "term": "pod", "translation": "Pod", "variants": "豆荚"})
+
+	writeFile(t, workdir, "doc.md", "Every pod restarts.")
+	writeFile(t, workdir, "doc.zh.md", "每个Pod都会重启。")
+
+	resp := run(t, p, store, map[string]any{"action": "apply", "file_path": "doc.md", "translated_path": "doc.zh.md"})
+	if _, ok := resp.Results["properties"]; ok {
+		t.Errorf("expected no properties without title or abstract, got %v", resp.Results["properties"])
+	}
+
+	resp = run(t, p, store, map[string]any{"action": "apply", "file_path": "doc.md", "translated_path": "doc.zh.md",
+		"title": "豆荚重启指南", "abstract": "说明豆荚如何重启。"})
+	props := resp.Results["properties"].(map[string]any)
+	if props["language"] != "en" {
+		t.Errorf("expected source language, got %v", props["language"])
+	}
+	zh := props["translations"].(map[string]any)["zh"].(map[string]any)
+	if zh["title"] != "Pod重启指南" || zh["abstract"] != "说明Pod如何重启。" {
+		t.Errorf("glossary not enforced on properties: %v", zh)
+	}
+}
+
+func TestTranslationMemory_InvalidParameters(t *testing.T) {
+	p, _ := newPlugin(t, "ns")
+	for _, params := range []map[string]any{
+		{"action": "add_term", "term": "pod"},
+		{"action": "remove_term"},
+		{"action": "prepare"},
+		{"action": "prepare", "file_path": "missing.md"},
+		{"action": "apply", "file_path": "doc.md"},
+		{"action": "unknown"},
+	} {
+		if resp := run(t, p, newMemStore(), params); resp.IsSucceed {
+			t.Errorf("Run(%v) succeeded, want failure", params)
+		}
+	}
+
+	resp, _ := p.Run(context.Background(), &api.Request{Parameter: map[string]any{"action": "list_terms", "source_lang": "en", "target_lang": "zh"}})
+	if resp.IsSucceed {
+		t.Errorf("Run without store succeeded, want failure")
+	}
+	resp, _ = p.Run(context.Background(), &api.Request{Parameter: map[string]any{"action": "list_terms"}, Store: newMemStore()})
+	if resp.IsSucceed {
+		t.Errorf("Run without languages succeeded, want failure")
+	}
+}
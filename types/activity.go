@@ -0,0 +1,24 @@
+package types
+
+const (
+	ActivitySource    = "activity"
+	ActivityGroup     = "calls"
+	ActivityDayLayout = "2006-01-02"
+)
+
+// ActivityRecord is one plugin call recorded by a manager created with WithActivityLog.
+type ActivityRecord struct {
+	Workflow   string `json:"workflow,omitempty"`
+	JobID      string `json:"job_id,omitempty"`
+	Plugin     string `json:"plugin"`
+	StartedAt  string `json:"started_at"`
+	DurationMs int64  `json:"duration_ms"`
+	Succeed    bool   `json:"succeed"`
+	Message    string `json:"message,omitempty"`
+	Items      int    `json:"items,omitempty"`
+}
+
+// ActivityDay holds the records of one UTC day, stored under the day formatted with ActivityDayLayout.
+type ActivityDay struct {
+	Records []ActivityRecord `json:"records"`
+}
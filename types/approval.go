@@ -0,0 +1,22 @@
+package types
+
+type ApprovalStatus string
+
+const (
+	ApprovalPending  ApprovalStatus = "pending"
+	ApprovalApproved ApprovalStatus = "approved"
+	ApprovalRejected ApprovalStatus = "rejected"
+)
+
+type Approval struct {
+	ID        string         `json:"id"`
+	Title     string         `json:"title"`
+	Message   string         `json:"message,omitempty"`
+	Workflow  string         `json:"workflow,omitempty"`
+	JobID     string         `json:"job_id,omitempty"`
+	Status    ApprovalStatus `json:"status"`
+	DecidedBy string         `json:"decided_by,omitempty"`
+	Comment   string         `json:"comment,omitempty"`
+	CreatedAt string         `json:"created_at"`
+	DecidedAt string         `json:"decided_at,omitempty"`
+}
@@ -0,0 +1,42 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package types
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// Command is an external binary invocation. Name is looked up in PATH and must be
+// allowed by the runner, Dir defaults to the call working path.
+type Command struct {
+	Name    string
+	Args    []string
+	Dir     string
+	Env     []string // KEY=VALUE pairs added to the environment
+	Stdin   io.Reader
+	Stdout  io.Writer
+	Stderr  io.Writer
+	Timeout time.Duration // shortened to the runner limit when longer
+	Network bool          // keep network access when the runner isolates the process
+}
+
+// CommandRunner runs binaries on behalf of plugins under the limits configured by the host.
+type CommandRunner interface {
+	Run(ctx context.Context, cmd Command) error
+}
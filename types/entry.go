@@ -29,5 +29,45 @@ type Properties struct {
 	PublishAt int64 `json:"publish_at,omitempty"`
 
 	// Agentic
-	Summarize string `json:"summarize,omitempty"` // summarize status
+	Summarize string   `json:"summarize,omitempty"` // summarize status
+	Sources   []Source `json:"sources,omitempty"`   // web pages the content was researched from
+
+	// multilingual, Title/Abstract/Keywords above stay in Language
+	Language     string                          `json:"language,omitempty"`
+	Translations map[string]TranslatedProperties `json:"translations,omitempty"`
+}
+
+// TranslatedProperties holds the title, abstract and keywords of an entry in another language.
+type TranslatedProperties struct {
+	Title    string   `json:"title,omitempty"`
+	Abstract string   `json:"abstract,omitempty"`
+	Keywords []string `json:"keywords,omitempty"`
+}
+
+// Source is a web page a document was researched from, with the claims of the document it supports.
+type Source struct {
+	URL        string   `json:"url"`
+	Title      string   `json:"title,omitempty"`
+	AccessedAt int64    `json:"accessed_at,omitempty"` // Unix time the page was read, 0 when only seen in search results
+	Claims     []string `json:"claims,omitempty"`
+}
+
+type Entry struct {
+	URI        string     `json:"uri"`
+	Name       string     `json:"name"`
+	Size       int64      `json:"size,omitempty"`
+	IsGroup    bool       `json:"is_group,omitempty"`
+	CreatedAt  int64      `json:"created_at,omitempty"`
+	ModifiedAt int64      `json:"modified_at,omitempty"`
+	Properties Properties `json:"properties"`
+}
+
+// SearchFilter narrows down NanaFS search results, empty fields are ignored.
+type SearchFilter struct {
+	ParentURI string   `json:"parent_uri,omitempty"`
+	Keywords  []string `json:"keywords,omitempty"`
+	URL       string   `json:"url,omitempty"`
+	Unread    *bool    `json:"unread,omitempty"`
+	Marked    *bool    `json:"marked,omitempty"`
+	Limit     int      `json:"limit,omitempty"`
 }
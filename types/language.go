@@ -0,0 +1,112 @@
+package types
+
+import (
+	"sort"
+	"strings"
+)
+
+// NormalizeLanguage turns a language tag like "zh_CN" into its map key form "zh-cn".
+func NormalizeLanguage(lang string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(lang)), "_", "-")
+}
+
+func (t TranslatedProperties) IsEmpty() bool {
+	return t.Title == "" && t.Abstract == "" && len(t.Keywords) == 0
+}
+
+// merge fills t with the non-empty fields of other, which win on conflict.
+func (t TranslatedProperties) merge(other TranslatedProperties) TranslatedProperties {
+	if other.Title != "" {
+		t.Title = other.Title
+	}
+	if other.Abstract != "" {
+		t.Abstract = other.Abstract
+	}
+	if len(other.Keywords) > 0 {
+		t.Keywords = other.Keywords
+	}
+	return t
+}
+
+// SetTranslation merges t into the values of lang. Values in the primary Language update Title, Abstract and Keywords.
+func (p *Properties) SetTranslation(lang string, t TranslatedProperties) {
+	lang = NormalizeLanguage(lang)
+	if lang == "" || t.IsEmpty() {
+		return
+	}
+	if lang == NormalizeLanguage(p.Language) {
+		primary := TranslatedProperties{Title: p.Title, Abstract: p.Abstract, Keywords: p.Keywords}.merge(t)
+		p.Title, p.Abstract, p.Keywords = primary.Title, primary.Abstract, primary.Keywords
+		return
+	}
+	if p.Translations == nil {
+		p.Translations = make(map[string]TranslatedProperties)
+	}
+	p.Translations[lang] = p.Translations[lang].merge(t)
+}
+
+// MergeTranslations adds the translations of other that p does not set itself.
+func (p *Properties) MergeTranslations(other map[string]TranslatedProperties) {
+	for lang, t := range other {
+		lang = NormalizeLanguage(lang)
+		if lang == "" || lang == NormalizeLanguage(p.Language) {
+			continue
+		}
+		if p.Translations == nil {
+			p.Translations = make(map[string]TranslatedProperties)
+		}
+		p.Translations[lang] = t.merge(p.Translations[lang])
+	}
+}
+
+// NormalizeTranslations normalizes the language keys, drops empty values and moves the
+// values of the primary Language into Title, Abstract and Keywords.
+func (p *Properties) NormalizeTranslations() {
+	p.Language = NormalizeLanguage(p.Language)
+	translations := p.Translations
+	p.Translations = nil
+	for _, lang := range sortedLanguages(translations) {
+		p.SetTranslation(lang, translations[lang])
+	}
+}
+
+// PromoteTranslation makes the first translated language with a title the primary one when
+// neither Title nor Language is set, so readers that only know Title still get one.
+func (p *Properties) PromoteTranslation() {
+	if p.Title != "" || p.Language != "" {
+		return
+	}
+	for _, lang := range sortedLanguages(p.Translations) {
+		if t := p.Translations[lang]; t.Title != "" {
+			delete(p.Translations, lang)
+			if len(p.Translations) == 0 {
+				p.Translations = nil
+			}
+			p.Language = lang
+			p.SetTranslation(lang, t)
+			return
+		}
+	}
+}
+
+func sortedLanguages(translations map[string]TranslatedProperties) []string {
+	langs := make([]string, 0, len(translations))
+	for lang := range translations {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// Localized returns a copy whose Title, Abstract and Keywords are in lang, falling back to the
+// primary values for fields the translation does not set.
+func (p Properties) Localized(lang string) Properties {
+	lang = NormalizeLanguage(lang)
+	t, ok := p.Translations[lang]
+	if !ok {
+		return p
+	}
+	primary := TranslatedProperties{Title: p.Title, Abstract: p.Abstract, Keywords: p.Keywords}.merge(t)
+	p.Title, p.Abstract, p.Keywords, p.Language = primary.Title, primary.Abstract, primary.Keywords, lang
+	return p
+}
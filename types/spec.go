@@ -38,23 +38,56 @@ type ParameterSpec struct {
 	Options     []string `json:"options,omitempty"`
 }
 
+type DependencyKind string
+
+const (
+	DependencyPlugin     DependencyKind = "plugin"     // another registered plugin
+	DependencyCapability DependencyKind = "capability" // a host capability, see Capability*
+	DependencyBinary     DependencyKind = "binary"     // an executable looked up in PATH
+)
+
+const (
+	CapabilityFS      = "fs"      // Request.FS is provided
+	CapabilityStore   = "store"   // Request.Store is provided
+	CapabilityNetwork = "network" // outbound network access
+	CapabilityLister  = "lister"  // Request.Lister is provided
+)
+
+// Dependency describes something a plugin needs before it can be called
+type Dependency struct {
+	Kind     DependencyKind `json:"kind"`
+	Name     string         `json:"name"`
+	Optional bool           `json:"optional,omitempty"` // missing optional dependencies are only reported
+}
+
 // PluginSpec is Plugin Config File to load a Plugin
 type PluginSpec struct {
 	Name           string          `json:"name"`
 	Version        string          `json:"version"`
 	Type           PluginType      `json:"type"`
-	RequiredConfig []string        `json:"required_config"` // Config keys required by this plugin
-	InitParameters []ParameterSpec `json:"init_parameters"` // Parameters for plugin initialization
-	Parameters     []ParameterSpec `json:"parameters"`      // Parameters for plugin execution
+	RequiredConfig []string        `json:"required_config"`        // Config keys required by this plugin
+	Dependencies   []Dependency    `json:"dependencies,omitempty"` // Plugins and host capabilities required by this plugin
+	InitParameters []ParameterSpec `json:"init_parameters"`        // Parameters for plugin initialization
+	Parameters     []ParameterSpec `json:"parameters"`             // Parameters for plugin execution
+	Example        *PluginExample  `json:"example,omitempty"`      // Sample call shown by Manager.Describe
+}
+
+// PluginExample is a typical call of a plugin, Request holds its parameters and Results the results of a successful run
+type PluginExample struct {
+	Request map[string]any `json:"request"`
+	Results map[string]any `json:"results,omitempty"`
 }
 
 type PluginCall struct {
-	JobID       string            `json:"job_id"`
-	Workflow    string            `json:"workflow"`
-	Namespace   string            `json:"namespace"`
-	WorkingPath string            `json:"working_path"`
-	PluginName  string            `json:"plugin_name"`
-	Version     string            `json:"version"`
-	Params      map[string]string `json:"params"`
-	Config      map[string]string `json:"config"` // LLM and other configuration
+	JobID          string            `json:"job_id"`
+	CallID         string            `json:"call_id,omitempty"`
+	Workflow       string            `json:"workflow"`
+	Namespace      string            `json:"namespace"`
+	WorkingPath    string            `json:"working_path"`
+	JobWorkingPath string            `json:"job_working_path,omitempty"` // Set when the call runs in an isolated subdirectory
+	PluginName     string            `json:"plugin_name"`
+	Version        string            `json:"version"`
+	Params         map[string]string `json:"params"`
+	Config         map[string]string `json:"config"` // LLM and other configuration
+	Runner         CommandRunner     `json:"-"`      // Set by the manager for plugins running binaries
 }
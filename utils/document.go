@@ -18,12 +18,19 @@ package utils
 
 import (
 	"bytes"
+	"io"
+	"net/url"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/PuerkitoBio/goquery"
+	"github.com/go-shiori/go-readability"
 )
 
+const boilerplateSelector = "script, style, noscript, iframe, nav, header, footer, aside, form, " +
+	"[role=navigation], [role=banner], [role=contentinfo], [role=complementary]"
+
 var repeatSpace = regexp.MustCompile(`\s+`)
 var htmlCharFilterRegexp = regexp.MustCompile(`</?[!\w:]+((\s+[\w-]+(\s*=\s*(?:\\*".*?"|'.*?'|[^'">\s]+))?)+\s*|\s*)/?>`)
 
@@ -40,13 +47,24 @@ func ContentTrim(contentType, content string) string {
 	return content
 }
 
+// GenerateContentAbstract summarizes the main content found by ExtractArticle, content readability
+// can not extract is summarized after removing the boilerplate.
 func GenerateContentAbstract(content string) string {
+	if article, err := ExtractArticle(strings.NewReader(content), nil); err == nil && article.Content != "" {
+		if abs := contentAbstract(article.Content); abs != "" {
+			return abs
+		}
+	}
+	return contentAbstract(content)
+}
+
+func contentAbstract(content string) string {
 	query, err := goquery.NewDocumentFromReader(bytes.NewReader([]byte(content)))
 	if err != nil {
 		return ""
 	}
 
-	query.Find("script, style, noscript, iframe, nav, header, footer, aside").Remove()
+	removeBoilerplate(query)
 
 	contents := make([]string, 0)
 	query.Find("p, article, section, li, td, th").EachWithBreak(func(i int, selection *goquery.Selection) bool {
@@ -72,6 +90,40 @@ func GenerateContentAbstract(content string) string {
 	return trimDocumentContent(bodyContent, 400)
 }
 
+// Article is the main content of a web page with the metadata found around it.
+type Article struct {
+	Title         string
+	Byline        string
+	SiteName      string
+	Language      string
+	Excerpt       string
+	PublishedTime *time.Time
+	// Content is the HTML of the main content, without navigation, headers, footers and ads.
+	Content string
+}
+
+// ExtractArticle finds the main content of an HTML page with readability. pageURL resolves the
+// relative links of the content, it may be nil when the page has no URL.
+func ExtractArticle(r io.Reader, pageURL *url.URL) (Article, error) {
+	article, err := readability.FromReader(r, pageURL)
+	if err != nil {
+		return Article{}, err
+	}
+	return Article{
+		Title:         article.Title,
+		Byline:        article.Byline,
+		SiteName:      article.SiteName,
+		Language:      article.Language,
+		Excerpt:       article.Excerpt,
+		PublishedTime: article.PublishedTime,
+		Content:       article.Content,
+	}, nil
+}
+
+func removeBoilerplate(query *goquery.Document) {
+	query.Find(boilerplateSelector).Remove()
+}
+
 func trimDocumentContent(str string, m int) string {
 	str = ContentTrim("html", str)
 	runes := []rune(str)
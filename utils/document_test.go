@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"net/url"
 	"strings"
 	"testing"
 )
@@ -132,6 +133,18 @@ func TestGenerateContentAbstract(t *testing.T) {
 			input:  `<p>中文测试内容中文测试内容中文测试内容中文测试内容中文测试内容中文测试内容中文测试内容中文测试内容</p>`,
 			maxLen: 400,
 		},
+		{
+			name: "summarizes the main content",
+			input: `<html><body>
+<div class="menu"><ul><li>Home page of the site</li><li>Archive of old posts</li><li>Subscribe to the newsletter</li></ul></div>
+<div class="post"><h1>Release notes</h1>
+<p>The new release brings faster builds, a rewritten scheduler and many fixes that users asked for over the last year of development.</p>
+<p>Upgrading is a drop-in replacement for most projects, the migration guide lists the few breaking changes in detail with examples.</p>
+<p>Thanks to everyone who tested the release candidates and reported problems, the final release would not be possible without you.</p>
+</div></body></html>`,
+			contains:    []string{"The new release brings faster builds"},
+			notContains: []string{"Archive of old posts"},
+		},
 		{
 			name:     "falls back to body text",
 			input:    `<html><body><div>Direct body content here</div></body></html>`,
@@ -161,3 +174,40 @@ func TestGenerateContentAbstract(t *testing.T) {
 		})
 	}
 }
+
+func TestExtractArticle(t *testing.T) {
+	page := `<html lang="en"><head><title>Post Title - Example Blog</title>
+<meta property="og:site_name" content="Example Blog"></head><body>
+<nav><a href="/">Home</a> <a href="/about">About</a> <a href="/contact">Contact</a></nav>
+<div class="sidebar"><p>Related links</p></div>
+<article><h1>Post Title</h1>
+<p>The article body is here and it is long enough to be picked as the main content of the page.</p>
+<p>A second paragraph continues the story, with <a href="/posts/next">a relative link</a> to the next post.</p>
+</article>
+<footer>Copyright notice</footer>
+</body></html>`
+	pageURL, _ := url.Parse("https://blog.example.com/posts/first")
+
+	article, err := ExtractArticle(strings.NewReader(page), pageURL)
+	if err != nil {
+		t.Fatalf("extract failed: %v", err)
+	}
+	for _, s := range []string{"The article body is here", "A second paragraph", `href="https://blog.example.com/posts/next"`} {
+		if !strings.Contains(article.Content, s) {
+			t.Errorf("expected content to contain %q, got %q", s, article.Content)
+		}
+	}
+	for _, s := range []string{"Contact", "Related links", "Copyright notice"} {
+		if strings.Contains(article.Content, s) {
+			t.Errorf("expected content NOT to contain %q, got %q", s, article.Content)
+		}
+	}
+	if article.SiteName != "Example Blog" || article.Language != "en" || !strings.Contains(article.Title, "Post Title") {
+		t.Errorf("unexpected metadata %+v", article)
+	}
+
+	article, err = ExtractArticle(strings.NewReader(page), nil)
+	if err != nil || !strings.Contains(article.Content, `href="/posts/next"`) {
+		t.Errorf("expected relative links to be kept without a page URL, got %v %q", err, article.Content)
+	}
+}
@@ -10,15 +10,22 @@ import (
 
 type FileAccess struct {
 	workdir string
+	jobdir  string
 }
 
-func NewFileAccess(workdir string) *FileAccess {
+// NewFileAccess roots file access at workdir. A call running in an isolated directory passes
+// the job working path as jobdir, its existing files stay reachable while new files go to workdir.
+func NewFileAccess(workdir string, jobdir ...string) *FileAccess {
 	if workdir == "" {
 		workdir = os.TempDir()
 	}
-	return &FileAccess{
+	fa := &FileAccess{
 		workdir: filepath.Clean(workdir),
 	}
+	if len(jobdir) > 0 && jobdir[0] != "" {
+		fa.jobdir = filepath.Clean(jobdir[0])
+	}
+	return fa
 }
 
 func (fa *FileAccess) ValidatePath(path string) error {
@@ -70,13 +77,36 @@ func (fa *FileAccess) GetAbsPath(path string) (string, error) {
 	// If it's an absolute path, check if it's within workdir
 	if filepath.IsAbs(path) {
 		// Check if the absolute path is within workdir
-		if !strings.HasPrefix(path, fa.workdir) {
+		if !strings.HasPrefix(path, fa.workdir) && (fa.jobdir == "" || !strings.HasPrefix(path, fa.jobdir)) {
 			return "", fmt.Errorf("path is outside workdir: %s", path)
 		}
 		return path, nil
 	}
 
-	return filepath.Join(fa.workdir, path), nil
+	absPath := filepath.Join(fa.workdir, path)
+	if fa.jobdir != "" {
+		// files of the job working path are used unless the call created its own
+		if _, err := os.Lstat(absPath); os.IsNotExist(err) {
+			if jobPath := filepath.Join(fa.jobdir, path); exists(jobPath) {
+				return jobPath, nil
+			}
+		}
+	}
+	return absPath, nil
+}
+
+// RelPath returns absPath relative to the workdir, or to the job working path for its files.
+func (fa *FileAccess) RelPath(absPath string) (string, error) {
+	rel, err := filepath.Rel(fa.workdir, absPath)
+	if err != nil || fa.jobdir == "" || filepath.IsLocal(rel) {
+		return rel, err
+	}
+	return filepath.Rel(fa.jobdir, absPath)
+}
+
+func exists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
 }
 
 func (fa *FileAccess) Read(path string) ([]byte, error) {
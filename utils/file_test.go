@@ -402,3 +402,36 @@ func TestWorkdir(t *testing.T) {
 		t.Errorf("expected workdir %s, got %s", filepath.Clean(dir), fa.Workdir())
 	}
 }
+
+func TestGetAbsPath_JobDir(t *testing.T) {
+	jobdir := t.TempDir()
+	workdir := filepath.Join(jobdir, ".calls", "step-1")
+	if err := os.MkdirAll(workdir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(jobdir, "input.txt"), []byte("input"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	fa := NewFileAccess(workdir, jobdir)
+
+	data, err := fa.Read("input.txt")
+	if err != nil || string(data) != "input" {
+		t.Errorf("expected job input to be readable, got %q %v", data, err)
+	}
+	if _, err = fa.Read(filepath.Join(jobdir, "input.txt")); err != nil {
+		t.Errorf("expected absolute job path to be readable: %v", err)
+	}
+
+	if err = fa.Write("output.txt", []byte("output"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(filepath.Join(workdir, "output.txt")); err != nil {
+		t.Errorf("expected new file in the call workdir: %v", err)
+	}
+	if rel, err := fa.RelPath(filepath.Join(jobdir, "input.txt")); err != nil || rel != "input.txt" {
+		t.Errorf("expected job input relative to the job working path, got %s %v", rel, err)
+	}
+	if _, err = fa.GetAbsPath(filepath.Join(filepath.Dir(jobdir), "other")); err == nil {
+		t.Error("expected path outside the job working path to be rejected")
+	}
+}
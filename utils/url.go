@@ -0,0 +1,141 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package utils
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+var (
+	trackingParamPrefixes = []string{"utm_", "mtm_", "pk_"}
+	trackingParams        = map[string]struct{}{
+		"fbclid":  {},
+		"gclid":   {},
+		"dclid":   {},
+		"msclkid": {},
+		"yclid":   {},
+		"igshid":  {},
+		"mc_cid":  {},
+		"mc_eid":  {},
+		"_hsenc":  {},
+		"_hsmi":   {},
+		"ref_src": {},
+		"spm":     {},
+	}
+	defaultPorts = map[string]string{
+		"http":  "80",
+		"https": "443",
+	}
+)
+
+// NormalizeURL returns the canonical form of a URL so that different spellings
+// of the same resource share one identity: scheme and host are lowercased,
+// default ports, fragments and tracking parameters are removed, and the
+// remaining query parameters are sorted.
+func NormalizeURL(rawURL string) (string, error) {
+	rawURL = strings.TrimSpace(rawURL)
+	if rawURL == "" {
+		return "", fmt.Errorf("url is empty")
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return "", fmt.Errorf("url %s is not absolute", rawURL)
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	host := strings.ToLower(u.Hostname())
+	if port := u.Port(); port != "" && port != defaultPorts[u.Scheme] {
+		host = net.JoinHostPort(host, port)
+	} else if strings.Contains(host, ":") {
+		// Hostname drops the brackets of an IPv6 literal
+		host = "[" + host + "]"
+	}
+	u.Host = host
+	u.Fragment = ""
+	u.RawFragment = ""
+
+	if u.Path == "" {
+		u.Path = "/"
+	}
+
+	query := u.Query()
+	for key := range query {
+		if isTrackingParam(key) {
+			query.Del(key)
+		}
+	}
+	u.RawQuery = encodeSortedQuery(query)
+
+	return u.String(), nil
+}
+
+// CanonicalURL is like NormalizeURL but falls back to the trimmed input when
+// the URL cannot be normalized.
+func CanonicalURL(rawURL string) string {
+	normalized, err := NormalizeURL(rawURL)
+	if err != nil {
+		return strings.TrimSpace(rawURL)
+	}
+	return normalized
+}
+
+func isTrackingParam(key string) bool {
+	key = strings.ToLower(key)
+	if _, ok := trackingParams[key]; ok {
+		return true
+	}
+	for _, prefix := range trackingParamPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func encodeSortedQuery(query url.Values) string {
+	if len(query) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var buf strings.Builder
+	for _, k := range keys {
+		values := query[k]
+		sort.Strings(values)
+		for _, v := range values {
+			if buf.Len() > 0 {
+				buf.WriteByte('&')
+			}
+			buf.WriteString(url.QueryEscape(k))
+			buf.WriteByte('=')
+			buf.WriteString(url.QueryEscape(v))
+		}
+	}
+	return buf.String()
+}
@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"testing"
+)
+
+func TestNormalizeURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"lowercase host and scheme", "HTTPS://Example.COM/Path", "https://example.com/Path"},
+		{"strip default http port", "http://example.com:80/a", "http://example.com/a"},
+		{"strip default https port", "https://example.com:443/a", "https://example.com/a"},
+		{"keep custom port", "https://example.com:8443/a", "https://example.com:8443/a"},
+		{"remove fragment", "https://example.com/a#section", "https://example.com/a"},
+		{"empty path becomes root", "https://example.com", "https://example.com/"},
+		{"sort query params", "https://example.com/a?b=2&a=1", "https://example.com/a?a=1&b=2"},
+		{"remove utm params", "https://example.com/a?utm_source=rss&utm_medium=feed&id=3", "https://example.com/a?id=3"},
+		{"remove click ids", "https://example.com/a?fbclid=xyz&gclid=abc", "https://example.com/a"},
+		{"trim spaces", "  https://example.com/a  ", "https://example.com/a"},
+		{"keep ipv6 brackets", "http://[2001:DB8::1]/a", "http://[2001:db8::1]/a"},
+		{"strip default port of ipv6 host", "https://[2001:db8::1]:443/a", "https://[2001:db8::1]/a"},
+		{"keep custom port of ipv6 host", "https://[::1]:8443/a", "https://[::1]:8443/a"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := NormalizeURL(tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("NormalizeURL(%q) = %q, expected %q", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNormalizeURL_SameIdentity(t *testing.T) {
+	a, _ := NormalizeURL("https://Example.com:443/post?id=1&utm_source=twitter#comments")
+	b, _ := NormalizeURL("https://example.com/post?id=1")
+	if a != b {
+		t.Errorf("expected same identity, got %q and %q", a, b)
+	}
+}
+
+func TestNormalizeURL_Invalid(t *testing.T) {
+	for _, input := range []string{"", "   ", "/relative/path", "not-a-url"} {
+		if _, err := NormalizeURL(input); err == nil {
+			t.Errorf("expected error for %q", input)
+		}
+	}
+}
+
+func TestCanonicalURL_Fallback(t *testing.T) {
+	if got := CanonicalURL(" /relative "); got != "/relative" {
+		t.Errorf("expected fallback to trimmed input, got %q", got)
+	}
+	if got := CanonicalURL("https://EXAMPLE.com/a#x"); got != "https://example.com/a" {
+		t.Errorf("expected normalized url, got %q", got)
+	}
+}
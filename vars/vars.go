@@ -0,0 +1,277 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package vars
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/logger"
+	"github.com/basenana/plugin/types"
+	"go.uber.org/zap"
+)
+
+const (
+	pluginName    = "vars"
+	pluginVersion = "1.0"
+
+	ScopeWorkflow = "workflow"
+	ScopeGlobal   = "global"
+
+	TypeString = "string"
+	TypeNumber = "number"
+	TypeBool   = "bool"
+	TypeJSON   = "json"
+)
+
+var PluginSpec = types.PluginSpec{
+	Name:         pluginName,
+	Version:      pluginVersion,
+	Type:         types.TypeProcess,
+	Dependencies: []types.Dependency{{Kind: types.DependencyCapability, Name: types.CapabilityStore}},
+	Parameters: []types.ParameterSpec{
+		{
+			Name:        "action",
+			Required:    true,
+			Description: "Action: get, set, delete",
+			Options:     []string{"get", "set", "delete"},
+		},
+		{
+			Name:        "name",
+			Required:    true,
+			Description: "Variable name",
+		},
+		{
+			Name:        "value",
+			Required:    false,
+			Description: "Value to set (required for set)",
+		},
+		{
+			Name:        "type",
+			Required:    false,
+			Default:     TypeString,
+			Description: "Value type for set, and for the default of get",
+			Options:     []string{TypeString, TypeNumber, TypeBool, TypeJSON},
+		},
+		{
+			Name:        "scope",
+			Required:    false,
+			Default:     ScopeWorkflow,
+			Description: "Visible to the current workflow only, or to every workflow in the namespace",
+			Options:     []string{ScopeWorkflow, ScopeGlobal},
+		},
+		{
+			Name:        "ttl",
+			Required:    false,
+			Description: "Expire the variable after this duration (e.g. 30m), never expires when empty",
+		},
+		{
+			Name:        "default",
+			Required:    false,
+			Description: "Value returned by get when the variable is missing or expired",
+		},
+	},
+}
+
+// variable is the record kept in the persistent store.
+type variable struct {
+	Type      string `json:"type"`
+	Value     any    `json:"value"`
+	ExpireAt  int64  `json:"expire_at,omitempty"`
+	UpdatedAt int64  `json:"updated_at"`
+	Deleted   bool   `json:"deleted,omitempty"`
+}
+
+func (v *variable) alive(now time.Time) bool {
+	return !v.Deleted && (v.ExpireAt == 0 || now.Unix() < v.ExpireAt)
+}
+
+type VarsPlugin struct {
+	logger    *zap.SugaredLogger
+	namespace string
+	workflow  string
+	now       func() time.Time
+}
+
+func NewVarsPlugin(ps types.PluginCall) types.Plugin {
+	return &VarsPlugin{
+		logger:    logger.NewPluginLogger(pluginName, ps.JobID),
+		namespace: ps.Namespace,
+		workflow:  ps.Workflow,
+		now:       time.Now,
+	}
+}
+
+func (p *VarsPlugin) Name() string {
+	return pluginName
+}
+
+func (p *VarsPlugin) Type() types.PluginType {
+	return types.TypeProcess
+}
+
+func (p *VarsPlugin) Version() string {
+	return pluginVersion
+}
+
+func (p *VarsPlugin) Run(ctx context.Context, request *api.Request) (*api.Response, error) {
+	action := api.GetStringParameter("action", request, "")
+	name := api.GetStringParameter("name", request, "")
+	scope := api.GetStringParameter("scope", request, ScopeWorkflow)
+
+	if action == "" {
+		return api.NewFailedResponse("action is required"), nil
+	}
+	if name == "" {
+		return api.NewFailedResponse("name is required"), nil
+	}
+	if request.Store == nil {
+		return api.NewFailedResponse("persistent store is required"), nil
+	}
+
+	group, err := p.group(scope)
+	if err != nil {
+		return api.NewFailedResponse(err.Error()), nil
+	}
+
+	p.logger.Infow("vars started", "action", action, "name", name, "scope", scope)
+
+	switch action {
+	case "get":
+		return p.get(ctx, request, group, name)
+	case "set":
+		return p.set(ctx, request, group, name)
+	case "delete":
+		return p.delete(ctx, request, group, name)
+	default:
+		return api.NewFailedResponse(fmt.Sprintf("unknown action: %s", action)), nil
+	}
+}
+
+func (p *VarsPlugin) get(ctx context.Context, request *api.Request, group, name string) (*api.Response, error) {
+	v, found := p.load(ctx, request.Store, group, name)
+	if found {
+		results := map[string]any{"name": name, "type": v.Type, "value": v.Value, "found": true}
+		if v.ExpireAt > 0 {
+			results["expire_at"] = time.Unix(v.ExpireAt, 0).UTC().Format(time.RFC3339)
+		}
+		return api.NewResponseWithResult(results), nil
+	}
+
+	results := map[string]any{"name": name, "found": false}
+	if defaultVal := api.GetStringParameter("default", request, ""); defaultVal != "" {
+		valType := api.GetStringParameter("type", request, TypeString)
+		value, err := parseValue(valType, defaultVal)
+		if err != nil {
+			return api.NewFailedResponse(fmt.Sprintf("parse default failed: %s", err)), nil
+		}
+		results["type"] = valType
+		results["value"] = value
+	}
+	return api.NewResponseWithResult(results), nil
+}
+
+func (p *VarsPlugin) set(ctx context.Context, request *api.Request, group, name string) (*api.Response, error) {
+	if _, ok := request.Parameter["value"]; !ok {
+		return api.NewFailedResponse("value is required for set action"), nil
+	}
+	valType := api.GetStringParameter("type", request, TypeString)
+	value, err := parseValue(valType, api.GetStringParameter("value", request, ""))
+	if err != nil {
+		return api.NewFailedResponse(fmt.Sprintf("parse value failed: %s", err)), nil
+	}
+
+	now := p.now()
+	v := &variable{Type: valType, Value: value, UpdatedAt: now.Unix()}
+	if ttl := api.GetStringParameter("ttl", request, ""); ttl != "" {
+		d, err := time.ParseDuration(ttl)
+		if err != nil || d <= 0 {
+			return api.NewFailedResponse(fmt.Sprintf("invalid ttl: %s", ttl)), nil
+		}
+		v.ExpireAt = now.Add(d).Unix()
+	}
+
+	if err = request.Store.Save(ctx, pluginName, group, name, v); err != nil {
+		p.logger.Warnw("save variable failed", "name", name, "error", err)
+		return api.NewFailedResponse(fmt.Sprintf("save variable failed: %s", err)), nil
+	}
+
+	results := map[string]any{"name": name, "type": v.Type, "value": v.Value}
+	if v.ExpireAt > 0 {
+		results["expire_at"] = time.Unix(v.ExpireAt, 0).UTC().Format(time.RFC3339)
+	}
+	p.logger.Infow("vars set", "name", name, "type", valType)
+	return api.NewResponseWithResult(results), nil
+}
+
+func (p *VarsPlugin) delete(ctx context.Context, request *api.Request, group, name string) (*api.Response, error) {
+	_, found := p.load(ctx, request.Store, group, name)
+	if found {
+		// the store has no delete, a tombstone hides the variable from later gets
+		v := &variable{Deleted: true, UpdatedAt: p.now().Unix()}
+		if err := request.Store.Save(ctx, pluginName, group, name, v); err != nil {
+			p.logger.Warnw("delete variable failed", "name", name, "error", err)
+			return api.NewFailedResponse(fmt.Sprintf("delete variable failed: %s", err)), nil
+		}
+	}
+	return api.NewResponseWithResult(map[string]any{"name": name, "found": found}), nil
+}
+
+func (p *VarsPlugin) load(ctx context.Context, store api.PersistentStore, group, name string) (*variable, bool) {
+	v := &variable{}
+	if err := store.Load(ctx, pluginName, group, name, v); err != nil {
+		return nil, false
+	}
+	if !v.alive(p.now()) {
+		return nil, false
+	}
+	return v, true
+}
+
+func (p *VarsPlugin) group(scope string) (string, error) {
+	switch scope {
+	case ScopeWorkflow:
+		return fmt.Sprintf("%s/%s/%s", p.namespace, ScopeWorkflow, p.workflow), nil
+	case ScopeGlobal:
+		return fmt.Sprintf("%s/%s", p.namespace, ScopeGlobal), nil
+	default:
+		return "", fmt.Errorf("unknown scope: %s", scope)
+	}
+}
+
+func parseValue(valType, raw string) (any, error) {
+	switch valType {
+	case TypeString:
+		return raw, nil
+	case TypeNumber:
+		return strconv.ParseFloat(raw, 64)
+	case TypeBool:
+		return strconv.ParseBool(raw)
+	case TypeJSON:
+		var value any
+		if err := json.Unmarshal([]byte(raw), &value); err != nil {
+			return nil, err
+		}
+		return value, nil
+	default:
+		return nil, fmt.Errorf("unknown type: %s", valType)
+	}
+}
@@ -0,0 +1,197 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package vars
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/logger"
+	"github.com/basenana/plugin/types"
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) {
+	logger.SetLogger(zap.NewNop().Sugar())
+	os.Exit(m.Run())
+}
+
+type memStore struct {
+	mux  sync.Mutex
+	data map[string][]byte
+}
+
+func newMemStore() *memStore {
+	return &memStore{data: map[string][]byte{}}
+}
+
+func (m *memStore) Load(ctx context.Context, source, group, key string, data any) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	raw, ok := m.data[source+"/"+group+"/"+key]
+	if !ok {
+		return fmt.Errorf("no record")
+	}
+	return json.Unmarshal(raw, data)
+}
+
+func (m *memStore) Save(ctx context.Context, source, group, key string, data any) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	m.data[source+"/"+group+"/"+key] = raw
+	return nil
+}
+
+func newVarsPlugin(workflow string) *VarsPlugin {
+	return NewVarsPlugin(types.PluginCall{
+		JobID:     "test-job",
+		Workflow:  workflow,
+		Namespace: "test-namespace",
+	}).(*VarsPlugin)
+}
+
+func runVars(t *testing.T, p *VarsPlugin, store api.PersistentStore, params map[string]any) *api.Response {
+	t.Helper()
+	resp, err := p.Run(context.Background(), &api.Request{Parameter: params, Store: store})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	return resp
+}
+
+func TestVarsPlugin_SetGetTyped(t *testing.T) {
+	p := newVarsPlugin("wf")
+	store := newMemStore()
+
+	tests := []struct {
+		valType string
+		value   any
+		want    any
+	}{
+		{TypeString, "hello", "hello"},
+		{TypeNumber, "42.5", 42.5},
+		{TypeNumber, 3, float64(3)},
+		{TypeBool, "true", true},
+		{TypeJSON, map[string]any{"ids": []any{"a", "b"}}, map[string]any{"ids": []any{"a", "b"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.valType, func(t *testing.T) {
+			resp := runVars(t, p, store, map[string]any{"action": "set", "name": "v", "type": tt.valType, "value": tt.value})
+			if !resp.IsSucceed {
+				t.Fatalf("set failed: %s", resp.Message)
+			}
+			resp = runVars(t, p, store, map[string]any{"action": "get", "name": "v"})
+			if resp.Results["found"] != true || resp.Results["type"] != tt.valType {
+				t.Fatalf("unexpected get result %v", resp.Results)
+			}
+			if !reflect.DeepEqual(resp.Results["value"], tt.want) {
+				t.Errorf("value = %#v, want %#v", resp.Results["value"], tt.want)
+			}
+		})
+	}
+}
+
+func TestVarsPlugin_InvalidValue(t *testing.T) {
+	p := newVarsPlugin("wf")
+	for _, params := range []map[string]any{
+		{"action": "set", "name": "v", "type": TypeNumber, "value": "abc"},
+		{"action": "set", "name": "v", "type": TypeBool, "value": "maybe"},
+		{"action": "set", "name": "v", "type": TypeJSON, "value": "{"},
+		{"action": "set", "name": "v", "type": "date", "value": "2024"},
+		{"action": "set", "name": "v"},
+		{"action": "set", "name": "v", "value": "x", "ttl": "soon"},
+		{"action": "get", "name": "v", "scope": "cluster"},
+		{"action": "list", "name": "v"},
+		{"action": "get"},
+	} {
+		if resp := runVars(t, p, newMemStore(), params); resp.IsSucceed {
+			t.Errorf("expected failure for %v", params)
+		}
+	}
+}
+
+func TestVarsPlugin_RequiresStore(t *testing.T) {
+	resp := runVars(t, newVarsPlugin("wf"), nil, map[string]any{"action": "get", "name": "v"})
+	if resp.IsSucceed {
+		t.Errorf("expected failure without persistent store")
+	}
+}
+
+func TestVarsPlugin_Scopes(t *testing.T) {
+	store := newMemStore()
+	wf1, wf2 := newVarsPlugin("wf1"), newVarsPlugin("wf2")
+
+	runVars(t, wf1, store, map[string]any{"action": "set", "name": "local", "value": "1"})
+	runVars(t, wf1, store, map[string]any{"action": "set", "name": "shared", "value": "2", "scope": ScopeGlobal})
+
+	if resp := runVars(t, wf2, store, map[string]any{"action": "get", "name": "local"}); resp.Results["found"] != false {
+		t.Errorf("workflow variable should not be visible to other workflows")
+	}
+	if resp := runVars(t, wf2, store, map[string]any{"action": "get", "name": "shared", "scope": ScopeGlobal}); resp.Results["value"] != "2" {
+		t.Errorf("global variable should be visible to other workflows, got %v", resp.Results)
+	}
+}
+
+func TestVarsPlugin_TTL(t *testing.T) {
+	store := newMemStore()
+	p := newVarsPlugin("wf")
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	p.now = func() time.Time { return now }
+
+	resp := runVars(t, p, store, map[string]any{"action": "set", "name": "token", "value": "abc", "ttl": "10m"})
+	if resp.Results["expire_at"] != "2024-01-01T00:10:00Z" {
+		t.Errorf("unexpected expire_at %v", resp.Results["expire_at"])
+	}
+
+	now = now.Add(5 * time.Minute)
+	if resp = runVars(t, p, store, map[string]any{"action": "get", "name": "token"}); resp.Results["found"] != true {
+		t.Errorf("variable should be alive before ttl")
+	}
+
+	now = now.Add(10 * time.Minute)
+	resp = runVars(t, p, store, map[string]any{"action": "get", "name": "token", "default": "none"})
+	if resp.Results["found"] != false || resp.Results["value"] != "none" {
+		t.Errorf("expired variable should fall back to default, got %v", resp.Results)
+	}
+}
+
+func TestVarsPlugin_Delete(t *testing.T) {
+	store := newMemStore()
+	p := newVarsPlugin("wf")
+
+	runVars(t, p, store, map[string]any{"action": "set", "name": "v", "value": "x"})
+	if resp := runVars(t, p, store, map[string]any{"action": "delete", "name": "v"}); resp.Results["found"] != true {
+		t.Errorf("expected existing variable to be deleted, got %v", resp.Results)
+	}
+	if resp := runVars(t, p, store, map[string]any{"action": "get", "name": "v"}); resp.Results["found"] != false {
+		t.Errorf("deleted variable should not be found")
+	}
+	if resp := runVars(t, p, store, map[string]any{"action": "delete", "name": "v"}); resp.Results["found"] != false {
+		t.Errorf("deleting a missing variable should report found=false")
+	}
+}
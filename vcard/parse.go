@@ -0,0 +1,392 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package vcard
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime/quotedprintable"
+	"net/http"
+	"strings"
+)
+
+// Contact is one vCard with normalized fields.
+type Contact struct {
+	UID        string   `json:"uid,omitempty"`
+	Name       string   `json:"name"`
+	GivenName  string   `json:"given_name,omitempty"`
+	FamilyName string   `json:"family_name,omitempty"`
+	Emails     []string `json:"emails,omitempty"`
+	Phones     []string `json:"phones,omitempty"`
+	Org        string   `json:"org,omitempty"`
+	Title      string   `json:"title,omitempty"`
+	Birthday   string   `json:"birthday,omitempty"`
+	Addresses  []string `json:"addresses,omitempty"`
+	URLs       []string `json:"urls,omitempty"`
+	Categories []string `json:"categories,omitempty"`
+	Note       string   `json:"note,omitempty"`
+	PhotoURL   string   `json:"photo_url,omitempty"`
+
+	photo     []byte
+	photoType string
+}
+
+// property is one content line, "item1.TEL;TYPE=cell:+1 555" has group item1,
+// name TEL, params {TYPE: [cell]} and value "+1 555".
+type property struct {
+	name   string
+	params map[string][]string
+	value  string
+}
+
+// Parse reads every card of a VCF export, versions 2.1, 3.0 and 4.0.
+func Parse(r io.Reader) ([]Contact, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		contacts []Contact
+		current  []property
+		inCard   bool
+	)
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		prop, err := parseLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", i+1, err)
+		}
+		switch {
+		case prop.name == "BEGIN" && strings.EqualFold(prop.value, "VCARD"):
+			inCard, current = true, nil
+		case prop.name == "END" && strings.EqualFold(prop.value, "VCARD"):
+			if inCard {
+				contacts = append(contacts, buildContact(current))
+			}
+			inCard = false
+		case inCard:
+			current = append(current, prop)
+		}
+	}
+	if len(contacts) == 0 {
+		return nil, fmt.Errorf("no vCard found")
+	}
+	return contacts, nil
+}
+
+// unfold joins continuation lines, both RFC folding (leading space or tab) and
+// quoted-printable soft line breaks (trailing "=").
+func unfold(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	var lines []string
+	softBreak := false
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(lines) == 0 {
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+		switch {
+		case softBreak:
+			lines[len(lines)-1] += line
+		case len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")):
+			lines[len(lines)-1] += line[1:]
+		default:
+			lines = append(lines, line)
+		}
+		last := lines[len(lines)-1]
+		softBreak = strings.HasSuffix(last, "=") && strings.Contains(strings.ToUpper(last), "QUOTED-PRINTABLE")
+		if softBreak {
+			lines[len(lines)-1] = strings.TrimSuffix(last, "=")
+		}
+	}
+	return lines, scanner.Err()
+}
+
+func parseLine(line string) (property, error) {
+	colon := valueStart(line)
+	if colon < 0 {
+		return property{}, fmt.Errorf("missing ':' in %q", line)
+	}
+	head, value := line[:colon], line[colon+1:]
+
+	parts := strings.Split(head, ";")
+	name := strings.ToUpper(parts[0])
+	if dot := strings.LastIndex(name, "."); dot >= 0 {
+		name = name[dot+1:]
+	}
+	prop := property{name: name, params: make(map[string][]string), value: value}
+	for _, param := range parts[1:] {
+		key, val, ok := strings.Cut(param, "=")
+		if !ok {
+			// vCard 2.1 allows bare types such as "TEL;CELL;VOICE"
+			key, val = "TYPE", param
+		}
+		key = strings.ToUpper(key)
+		for _, v := range strings.Split(val, ",") {
+			prop.params[key] = append(prop.params[key], strings.Trim(v, `"`))
+		}
+	}
+
+	if prop.hasParam("ENCODING", "QUOTED-PRINTABLE") {
+		decoded, err := io.ReadAll(quotedprintable.NewReader(strings.NewReader(prop.value)))
+		if err != nil {
+			return property{}, fmt.Errorf("decode quoted-printable failed: %s", err)
+		}
+		prop.value = string(decoded)
+	}
+	return prop, nil
+}
+
+// valueStart finds the colon separating the name and params from the value,
+// skipping colons inside quoted param values.
+func valueStart(line string) int {
+	quoted := false
+	for i, c := range line {
+		switch c {
+		case '"':
+			quoted = !quoted
+		case ':':
+			if !quoted {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func (p property) hasParam(key, value string) bool {
+	for _, v := range p.params[key] {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func buildContact(props []property) Contact {
+	c := Contact{}
+	for _, p := range props {
+		switch p.name {
+		case "UID":
+			c.UID = unescape(p.value)
+		case "FN":
+			c.Name = strings.TrimSpace(unescape(p.value))
+		case "N":
+			fields := splitStructured(p.value)
+			if len(fields) > 0 {
+				c.FamilyName = fields[0]
+			}
+			if len(fields) > 1 {
+				c.GivenName = fields[1]
+			}
+		case "EMAIL":
+			if email := normalizeEmail(unescape(p.value)); email != "" {
+				c.Emails = appendUnique(c.Emails, email)
+			}
+		case "TEL":
+			if phone := normalizePhone(unescape(p.value)); phone != "" {
+				c.Phones = appendUnique(c.Phones, phone)
+			}
+		case "ORG":
+			c.Org = joinNonEmpty(splitStructured(p.value), ", ")
+		case "TITLE":
+			c.Title = unescape(p.value)
+		case "BDAY":
+			c.Birthday = unescape(p.value)
+		case "ADR":
+			if adr := joinNonEmpty(splitStructured(p.value), ", "); adr != "" {
+				c.Addresses = append(c.Addresses, adr)
+			}
+		case "URL":
+			c.URLs = appendUnique(c.URLs, unescape(p.value))
+		case "CATEGORIES":
+			for _, category := range splitList(p.value) {
+				c.Categories = appendUnique(c.Categories, category)
+			}
+		case "NOTE":
+			c.Note = unescape(p.value)
+		case "PHOTO":
+			c.setPhoto(p)
+		}
+	}
+	if c.Name == "" {
+		c.Name = joinNonEmpty([]string{c.GivenName, c.FamilyName}, " ")
+	}
+	if c.Name == "" {
+		c.Name = c.Org
+	}
+	if c.Name == "" && len(c.Emails) > 0 {
+		c.Name = c.Emails[0]
+	}
+	return c
+}
+
+// setPhoto keeps inline photos, base64 in 2.1/3.0 or a data URI in 4.0, and
+// records the URL of linked ones.
+func (c *Contact) setPhoto(p property) {
+	value := strings.TrimSpace(p.value)
+	var data string
+	switch {
+	case p.hasParam("ENCODING", "b") || p.hasParam("ENCODING", "BASE64"):
+		data = value
+	case strings.HasPrefix(value, "data:"):
+		meta, payload, ok := strings.Cut(value, ",")
+		if !ok || !strings.HasSuffix(meta, ";base64") {
+			return
+		}
+		data = payload
+	case strings.HasPrefix(value, "http://") || strings.HasPrefix(value, "https://"):
+		c.PhotoURL = value
+		return
+	default:
+		return
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(data), ""))
+	if err != nil || len(decoded) == 0 {
+		return
+	}
+	c.photo = decoded
+	c.photoType = http.DetectContentType(decoded)
+}
+
+// photoExt returns the file extension of the inline photo, empty when there is none.
+func (c *Contact) photoExt() string {
+	if len(c.photo) == 0 {
+		return ""
+	}
+	switch c.photoType {
+	case "image/png":
+		return ".png"
+	case "image/gif":
+		return ".gif"
+	case "image/webp":
+		return ".webp"
+	case "image/bmp":
+		return ".bmp"
+	}
+	return ".jpg"
+}
+
+func normalizeEmail(s string) string {
+	s = strings.TrimSpace(s)
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "mailto:"), "MAILTO:")
+	return strings.ToLower(s)
+}
+
+// normalizePhone keeps digits, a leading "+" and extensions, dropping formatting.
+func normalizePhone(s string) string {
+	s = strings.TrimSpace(s)
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "tel:"), "TEL:")
+	number, ext, _ := strings.Cut(strings.ToLower(s), ";ext=")
+	if i := strings.Index(number, "ext"); i >= 0 && ext == "" {
+		number, ext = number[:i], strings.TrimLeft(number[i+3:], ". ")
+	}
+	var buf bytes.Buffer
+	for _, r := range number {
+		if r >= '0' && r <= '9' || r == '+' && buf.Len() == 0 {
+			buf.WriteRune(r)
+		}
+	}
+	if buf.Len() == 0 {
+		return ""
+	}
+	if ext = strings.TrimSpace(ext); ext != "" {
+		buf.WriteString(";ext=" + ext)
+	}
+	return buf.String()
+}
+
+// splitStructured splits a structured value such as N or ADR on unescaped ";".
+func splitStructured(value string) []string {
+	return splitEscaped(value, ';')
+}
+
+// splitList splits a list value such as CATEGORIES on unescaped ",".
+func splitList(value string) []string {
+	var items []string
+	for _, item := range splitEscaped(value, ',') {
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func splitEscaped(value string, sep byte) []string {
+	var (
+		fields []string
+		start  int
+	)
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case '\\':
+			i++
+		case sep:
+			fields = append(fields, strings.TrimSpace(unescape(value[start:i])))
+			start = i + 1
+		}
+	}
+	return append(fields, strings.TrimSpace(unescape(value[start:])))
+}
+
+func unescape(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var buf strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+			switch s[i] {
+			case 'n', 'N':
+				buf.WriteByte('\n')
+			default:
+				buf.WriteByte(s[i])
+			}
+			continue
+		}
+		buf.WriteByte(s[i])
+	}
+	return buf.String()
+}
+
+func joinNonEmpty(parts []string, sep string) string {
+	var kept []string
+	for _, p := range parts {
+		if p = strings.TrimSpace(p); p != "" {
+			kept = append(kept, p)
+		}
+	}
+	return strings.Join(kept, sep)
+}
+
+func appendUnique(list []string, v string) []string {
+	for _, existing := range list {
+		if existing == v {
+			return list
+		}
+	}
+	return append(list, v)
+}
@@ -0,0 +1,130 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package vcard
+
+import (
+	"encoding/base64"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// a 1x1 PNG
+const pngPixel = "iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAYAAAAfFcSJAAAADUlEQVR42mNk+M9QDwADhgGAWjR9awAAAABJRU5ErkJggg=="
+
+func TestParse_Versions(t *testing.T) {
+	vcf := strings.Join([]string{
+		"BEGIN:VCARD",
+		"VERSION:3.0",
+		"FN:Jane Doe",
+		"N:Doe;Jane;;;",
+		"ORG:Example\\, Inc.;Research",
+		"TITLE:Engineer",
+		"item1.EMAIL;TYPE=INTERNET,WORK:Jane.Doe@Example.com",
+		"EMAIL;TYPE=HOME:mailto:jane@home.example",
+		"TEL;TYPE=CELL:+1 (555) 010-9999",
+		"TEL;TYPE=WORK:555.010.1234 ext. 42",
+		"ADR;TYPE=WORK:;;1 Main St;Springfield;;12345;USA",
+		"NOTE:Met at the conference\\nlikes Go",
+		"CATEGORIES:friends,work",
+		"PHOTO;ENCODING=b;TYPE=PNG:" + pngPixel[:40],
+		" " + pngPixel[40:],
+		"END:VCARD",
+		"BEGIN:VCARD",
+		"VERSION:2.1",
+		"N;CHARSET=UTF-8;ENCODING=QUOTED-PRINTABLE:M=C3=BCller;J=C3=B6rg",
+		"TEL;CELL;VOICE:0151 2345678",
+		"NOTE;ENCODING=QUOTED-PRINTABLE:first line=0D=0A=",
+		"second line",
+		"END:VCARD",
+		"BEGIN:VCARD",
+		"VERSION:4.0",
+		"ORG:Acme",
+		"EMAIL:sales@acme.example",
+		"TEL;VALUE=uri:tel:+44-20-7946-0000",
+		"PHOTO:data:image/png;base64," + pngPixel,
+		"END:VCARD",
+		"BEGIN:VCARD",
+		"VERSION:4.0",
+		"EMAIL:solo@example.com",
+		"PHOTO;MEDIATYPE=image/jpeg:https://example.com/solo.jpg",
+		"END:VCARD",
+	}, "\r\n")
+
+	contacts, err := Parse(strings.NewReader(vcf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(contacts) != 4 {
+		t.Fatalf("expected 4 contacts, got %d", len(contacts))
+	}
+
+	jane := contacts[0]
+	if jane.Name != "Jane Doe" || jane.GivenName != "Jane" || jane.FamilyName != "Doe" {
+		t.Errorf("unexpected name %+v", jane)
+	}
+	if jane.Org != "Example, Inc., Research" || jane.Title != "Engineer" {
+		t.Errorf("unexpected org %q title %q", jane.Org, jane.Title)
+	}
+	if !reflect.DeepEqual(jane.Emails, []string{"jane.doe@example.com", "jane@home.example"}) {
+		t.Errorf("unexpected emails %v", jane.Emails)
+	}
+	if !reflect.DeepEqual(jane.Phones, []string{"+15550109999", "5550101234;ext=42"}) {
+		t.Errorf("unexpected phones %v", jane.Phones)
+	}
+	if !reflect.DeepEqual(jane.Addresses, []string{"1 Main St, Springfield, 12345, USA"}) {
+		t.Errorf("unexpected addresses %v", jane.Addresses)
+	}
+	if jane.Note != "Met at the conference\nlikes Go" || !reflect.DeepEqual(jane.Categories, []string{"friends", "work"}) {
+		t.Errorf("unexpected note %q categories %v", jane.Note, jane.Categories)
+	}
+	if jane.photoExt() != ".png" {
+		t.Errorf("expected folded base64 photo, got %q", jane.photoType)
+	}
+
+	jorg := contacts[1]
+	if jorg.Name != "Jörg Müller" || jorg.Note != "first line\r\nsecond line" {
+		t.Errorf("unexpected quoted-printable decoding %+v", jorg)
+	}
+	if !reflect.DeepEqual(jorg.Phones, []string{"01512345678"}) {
+		t.Errorf("unexpected phones %v", jorg.Phones)
+	}
+
+	acme := contacts[2]
+	if acme.Name != "Acme" || !reflect.DeepEqual(acme.Phones, []string{"+442079460000"}) || acme.photoExt() != ".png" {
+		t.Errorf("unexpected 4.0 contact %+v", acme)
+	}
+
+	solo := contacts[3]
+	if solo.Name != "solo@example.com" || solo.PhotoURL != "https://example.com/solo.jpg" || solo.photoExt() != "" {
+		t.Errorf("unexpected contact %+v", solo)
+	}
+
+	raw, _ := base64.StdEncoding.DecodeString(pngPixel)
+	if !reflect.DeepEqual(acme.photo, raw) {
+		t.Error("photo bytes differ")
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	if _, err := Parse(strings.NewReader("hello world")); err == nil {
+		t.Error("expected error for invalid line")
+	}
+	if _, err := Parse(strings.NewReader("BEGIN:VCALENDAR\nEND:VCALENDAR\n")); err == nil || !strings.Contains(err.Error(), "no vCard found") {
+		t.Errorf("expected no vCard error, got %v", err)
+	}
+}
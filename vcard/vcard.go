@@ -0,0 +1,219 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package vcard
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/logger"
+	"github.com/basenana/plugin/types"
+	"github.com/basenana/plugin/utils"
+	"go.uber.org/zap"
+)
+
+const (
+	pluginName    = "vcard"
+	pluginVersion = "1.0"
+
+	defaultOutputDir = "contacts"
+)
+
+var PluginSpec = types.PluginSpec{
+	Name:    pluginName,
+	Version: pluginVersion,
+	Type:    types.TypeProcess,
+	Parameters: []types.ParameterSpec{
+		{
+			Name:        "file_path",
+			Required:    true,
+			Description: "VCF address book export",
+		},
+		{
+			Name:        "output_dir",
+			Required:    false,
+			Default:     defaultOutputDir,
+			Description: "Directory in the working path for the contact documents and photos",
+		},
+		{
+			Name:        "extract_photos",
+			Required:    false,
+			Default:     "true",
+			Description: "Write embedded contact photos next to the documents",
+			Options:     []string{"true", "false"},
+		},
+	},
+}
+
+type VCardPlugin struct {
+	logger   *zap.SugaredLogger
+	fileRoot *utils.FileAccess
+}
+
+func NewVCardPlugin(ps types.PluginCall) types.Plugin {
+	return &VCardPlugin{
+		logger:   logger.NewPluginLogger(pluginName, ps.JobID),
+		fileRoot: utils.NewFileAccess(ps.WorkingPath, ps.JobWorkingPath),
+	}
+}
+
+func (p *VCardPlugin) Name() string {
+	return pluginName
+}
+
+func (p *VCardPlugin) Type() types.PluginType {
+	return types.TypeProcess
+}
+
+func (p *VCardPlugin) Version() string {
+	return pluginVersion
+}
+
+func (p *VCardPlugin) Run(ctx context.Context, request *api.Request) (*api.Response, error) {
+	filePath := api.GetStringParameter("file_path", request, "")
+	if filePath == "" {
+		return api.NewFailedResponse("file_path is required"), nil
+	}
+	outputDir := api.GetStringParameter("output_dir", request, defaultOutputDir)
+	extractPhotos := api.GetBoolParameter("extract_photos", request, true)
+
+	data, err := p.fileRoot.Read(filePath)
+	if err != nil {
+		return api.NewFailedResponse(fmt.Sprintf("read file %s failed: %s", filePath, err)), nil
+	}
+	contacts, err := Parse(bytes.NewReader(data))
+	if err != nil {
+		return api.NewFailedResponse(fmt.Sprintf("parse file %s failed: %s", filePath, err)), nil
+	}
+	if err = p.fileRoot.MkdirAll(outputDir, 0755); err != nil {
+		return api.NewFailedResponse(fmt.Sprintf("create directory %s failed: %s", outputDir, err)), nil
+	}
+
+	var (
+		results  = make([]map[string]any, 0, len(contacts))
+		warnings = make([]string, 0)
+		used     = make(map[string]bool)
+		photos   int
+	)
+	for i, c := range contacts {
+		if c.Name == "" && len(c.Phones) == 0 {
+			warnings = append(warnings, fmt.Sprintf("card %d has no name, email or phone, skipped", i+1))
+			continue
+		}
+		base := uniqueName(used, contactFileName(c, i))
+
+		var photoPath, photoName string
+		if extractPhotos && len(c.photo) > 0 {
+			photoPath = path.Join(outputDir, base+c.photoExt())
+			if err = p.fileRoot.Write(photoPath, c.photo, 0644); err != nil {
+				return api.NewFailedResponse(fmt.Sprintf("write file %s failed: %s", photoPath, err)), nil
+			}
+			photoName = path.Base(photoPath)
+			photos++
+		}
+
+		doc := c.document(photoName)
+		docPath := path.Join(outputDir, base+".md")
+		if err = p.fileRoot.Write(docPath, []byte(doc.Content), 0644); err != nil {
+			return api.NewFailedResponse(fmt.Sprintf("write file %s failed: %s", docPath, err)), nil
+		}
+
+		result := map[string]any{
+			"file_path": docPath,
+			"contact":   utils.MarshalMap(c),
+			"document":  utils.MarshalMap(doc),
+		}
+		if photoPath != "" {
+			result["photo_path"] = photoPath
+		}
+		results = append(results, result)
+	}
+
+	p.logger.Infow("vcard imported", "file", filePath, "cards", len(contacts), "contacts", len(results),
+		"photos", photos, "warnings", len(warnings))
+	return api.NewResponseWithResult(map[string]any{
+		"output_dir": outputDir,
+		"contacts":   results,
+		"total":      len(results),
+		"photos":     photos,
+		"warnings":   warnings,
+	}), nil
+}
+
+// document renders the contact as Markdown, Properties carry the normalized name,
+// organization, categories and a searchable summary of emails and phones.
+func (c *Contact) document(photoName string) types.Document {
+	buf := &strings.Builder{}
+	fmt.Fprintf(buf, "# %s\n\n", c.Name)
+	if photoName != "" {
+		fmt.Fprintf(buf, "![%s](%s)\n\n", c.Name, photoName)
+	}
+	field := func(label string, values ...string) {
+		if v := joinNonEmpty(values, ", "); v != "" {
+			fmt.Fprintf(buf, "- **%s**: %s\n", label, v)
+		}
+	}
+	field("Organization", c.Org)
+	field("Title", c.Title)
+	field("Email", c.Emails...)
+	field("Phone", c.Phones...)
+	field("Birthday", c.Birthday)
+	for _, adr := range c.Addresses {
+		field("Address", adr)
+	}
+	field("URL", c.URLs...)
+	field("Categories", c.Categories...)
+	if c.Note != "" {
+		fmt.Fprintf(buf, "\n%s\n", c.Note)
+	}
+
+	props := types.Properties{
+		Title:    c.Name,
+		Source:   c.Org,
+		Abstract: joinNonEmpty(append(append([]string{}, c.Emails...), c.Phones...), ", "),
+		Notes:    c.Note,
+		Keywords: c.Categories,
+	}
+	if len(c.URLs) > 0 {
+		props.URL = c.URLs[0]
+	}
+	return types.Document{Content: buf.String(), Properties: props}
+}
+
+func contactFileName(c Contact, index int) string {
+	name := c.Name
+	if name == "" {
+		name = c.Phones[0]
+	}
+	if name = utils.SanitizeFilename(name); name == "" {
+		name = fmt.Sprintf("contact-%d", index+1)
+	}
+	return name
+}
+
+func uniqueName(used map[string]bool, name string) string {
+	candidate := name
+	for i := 2; used[strings.ToLower(candidate)]; i++ {
+		candidate = fmt.Sprintf("%s-%d", name, i)
+	}
+	used[strings.ToLower(candidate)] = true
+	return candidate
+}
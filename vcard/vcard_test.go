@@ -0,0 +1,148 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package vcard
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/logger"
+	"github.com/basenana/plugin/types"
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) {
+	logger.SetLogger(zap.NewNop().Sugar())
+	os.Exit(m.Run())
+}
+
+func newVCardPlugin(workdir string) *VCardPlugin {
+	return NewVCardPlugin(types.PluginCall{JobID: "job-1", WorkingPath: workdir}).(*VCardPlugin)
+}
+
+const testVCF = `BEGIN:VCARD
+VERSION:3.0
+FN:Jane Doe
+EMAIL:jane@example.com
+TEL:+1 555 0100
+ORG:Example
+CATEGORIES:friends
+URL:https://jane.example.com
+PHOTO;ENCODING=b;TYPE=PNG:` + pngPixel + `
+END:VCARD
+BEGIN:VCARD
+VERSION:3.0
+FN:Jane Doe
+EMAIL:other.jane@example.com
+END:VCARD
+BEGIN:VCARD
+VERSION:3.0
+NOTE:nothing useful
+END:VCARD
+`
+
+func TestVCardPlugin_Run(t *testing.T) {
+	workdir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(workdir, "contacts.vcf"), []byte(testVCF), 0644); err != nil {
+		t.Fatal(err)
+	}
+	p := newVCardPlugin(workdir)
+
+	resp, err := p.Run(context.Background(), &api.Request{Parameter: map[string]any{"file_path": "contacts.vcf", "output_dir": "people"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.IsSucceed {
+		t.Fatalf("Run not succeed: %s", resp.Message)
+	}
+	if resp.Results["total"] != 2 || resp.Results["photos"] != 1 {
+		t.Errorf("unexpected results %v", resp.Results)
+	}
+	if warnings := resp.Results["warnings"].([]string); len(warnings) != 1 || !strings.Contains(warnings[0], "card 3") {
+		t.Errorf("expected the empty card to be skipped, got %v", warnings)
+	}
+
+	contacts := resp.Results["contacts"].([]map[string]any)
+	first, second := contacts[0], contacts[1]
+	if first["file_path"] != "people/Jane_Doe.md" || first["photo_path"] != "people/Jane_Doe.png" || second["file_path"] != "people/Jane_Doe-2.md" {
+		t.Errorf("unexpected paths %v, %v", first, second)
+	}
+	props := first["document"].(map[string]any)["properties"].(map[string]any)
+	if props["title"] != "Jane Doe" || props["source"] != "Example" || props["abstract"] != "jane@example.com, +15550100" || props["url"] != "https://jane.example.com" {
+		t.Errorf("unexpected properties %v", props)
+	}
+	if emails := first["contact"].(map[string]any)["emails"].([]any); len(emails) != 1 || emails[0] != "jane@example.com" {
+		t.Errorf("unexpected contact %v", first["contact"])
+	}
+
+	doc, err := os.ReadFile(filepath.Join(workdir, "people", "Jane_Doe.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, part := range []string{"# Jane Doe", "![Jane Doe](Jane_Doe.png)", "- **Email**: jane@example.com", "- **Phone**: +15550100"} {
+		if !strings.Contains(string(doc), part) {
+			t.Errorf("expected %q in document:\n%s", part, doc)
+		}
+	}
+	if _, err = os.Stat(filepath.Join(workdir, "people", "Jane_Doe.png")); err != nil {
+		t.Errorf("photo not written: %v", err)
+	}
+}
+
+func TestVCardPlugin_Run_NoPhotos(t *testing.T) {
+	workdir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(workdir, "contacts.vcf"), []byte(testVCF), 0644); err != nil {
+		t.Fatal(err)
+	}
+	p := newVCardPlugin(workdir)
+
+	resp, err := p.Run(context.Background(), &api.Request{Parameter: map[string]any{"file_path": "contacts.vcf", "extract_photos": false}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.IsSucceed || resp.Results["photos"] != 0 {
+		t.Fatalf("unexpected response %+v", resp)
+	}
+	if _, err = os.Stat(filepath.Join(workdir, "contacts", "Jane_Doe.png")); !os.IsNotExist(err) {
+		t.Error("photo should not be written")
+	}
+}
+
+func TestVCardPlugin_Run_Errors(t *testing.T) {
+	workdir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(workdir, "bad.vcf"), []byte("not a card"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	p := newVCardPlugin(workdir)
+	for params, msg := range map[string]string{
+		"":            "file_path is required",
+		"missing.vcf": "read file missing.vcf failed",
+		"bad.vcf":     "parse file bad.vcf failed",
+	} {
+		resp, err := p.Run(context.Background(), &api.Request{Parameter: map[string]any{"file_path": params}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.IsSucceed || !strings.Contains(resp.Message, msg) {
+			t.Errorf("file_path %q: expected %q, got %+v", params, msg, resp)
+		}
+	}
+}
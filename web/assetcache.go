@@ -0,0 +1,133 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package web
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const webpackConfigAssetCacheDir = "webpack_asset_cache_dir"
+
+// AssetCache keeps downloaded assets with their validators in a directory shared by all captures.
+// A cached asset is revalidated with If-None-Match / If-Modified-Since and reused on 304 Not Modified.
+type AssetCache struct {
+	dir string
+}
+
+// cachedAsset is stored as one file: its JSON header on the first line, followed by the data.
+type cachedAsset struct {
+	URL          string `json:"url"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+	ContentType  string `json:"content_type,omitempty"`
+
+	Data []byte `json:"-"`
+}
+
+func NewAssetCache(dir string) (*AssetCache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("create asset cache dir failed: %w", err)
+	}
+	return &AssetCache{dir: dir}, nil
+}
+
+func (c *AssetCache) path(rawURL string) string {
+	sum := sha256.Sum256([]byte(rawURL))
+	key := hex.EncodeToString(sum[:])
+	return filepath.Join(c.dir, key[:2], key)
+}
+
+// load returns the cached asset of rawURL, or nil when there is none.
+func (c *AssetCache) load(rawURL string) *cachedAsset {
+	f, err := os.Open(c.path(rawURL))
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	reader := bufio.NewReader(f)
+	header, err := reader.ReadBytes('\n')
+	if err != nil {
+		return nil
+	}
+	asset := &cachedAsset{}
+	if err = json.Unmarshal(header, asset); err != nil || asset.URL != rawURL {
+		return nil
+	}
+	if asset.Data, err = io.ReadAll(reader); err != nil {
+		return nil
+	}
+	return asset
+}
+
+// store caches an asset the server sent validators for, unless it forbids storing it.
+func (c *AssetCache) store(rawURL string, header http.Header, data []byte) (bool, error) {
+	asset := cachedAsset{
+		URL:          rawURL,
+		ETag:         header.Get("ETag"),
+		LastModified: header.Get("Last-Modified"),
+		ContentType:  header.Get("Content-Type"),
+	}
+	if asset.ETag == "" && asset.LastModified == "" {
+		return false, nil
+	}
+	if strings.Contains(strings.ToLower(header.Get("Cache-Control")), "no-store") {
+		return false, nil
+	}
+	meta, err := json.Marshal(asset)
+	if err != nil {
+		return false, err
+	}
+
+	filePath := c.path(rawURL)
+	if err = os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return false, err
+	}
+	// captures running at the same time may store the same asset, the rename keeps each file whole
+	tmp, err := os.CreateTemp(filepath.Dir(filePath), ".asset-*")
+	if err != nil {
+		return false, err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = io.Copy(tmp, io.MultiReader(bytes.NewReader(meta), strings.NewReader("\n"), bytes.NewReader(data)))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, os.Rename(tmp.Name(), filePath)
+}
+
+// setValidators makes the request conditional on the cached version.
+func (a *cachedAsset) setValidators(req *http.Request) {
+	if a.ETag != "" {
+		req.Header.Set("If-None-Match", a.ETag)
+	}
+	if a.LastModified != "" {
+		req.Header.Set("If-Modified-Since", a.LastModified)
+	}
+}
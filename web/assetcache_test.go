@@ -0,0 +1,142 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package web
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/types"
+)
+
+func TestAssetCache_StoreLoad(t *testing.T) {
+	cache, err := NewAssetCache(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := http.Header{}
+	header.Set("ETag", `"v1"`)
+	header.Set("Content-Type", "text/css")
+	if stored, err := cache.store("https://example.com/a.css", header, []byte("a {}\n")); !stored || err != nil {
+		t.Fatalf("store = %v, %v", stored, err)
+	}
+	asset := cache.load("https://example.com/a.css")
+	if asset == nil || asset.ETag != `"v1"` || asset.ContentType != "text/css" || string(asset.Data) != "a {}\n" {
+		t.Errorf("unexpected cached asset %+v", asset)
+	}
+	if cache.load("https://example.com/b.css") != nil {
+		t.Errorf("expected miss")
+	}
+
+	for _, h := range []http.Header{
+		{"Content-Type": {"text/css"}},
+		{"Etag": {`"v1"`}, "Cache-Control": {"private, no-store"}},
+	} {
+		if stored, err := cache.store("https://example.com/c.css", h, []byte("c")); stored || err != nil {
+			t.Errorf("%v: store = %v, %v", h, stored, err)
+		}
+	}
+}
+
+func TestWebpackPlugin_AssetCache(t *testing.T) {
+	var (
+		mu          sync.Mutex
+		full        = map[string]int{}
+		revalidated = map[string]int{}
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/page":
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = w.Write([]byte(`<html><head><title>Cache</title><link rel="stylesheet" href="/style.css"></head>
+<body><img src="/logo.png"><img src="/fresh.png"></body></html>`))
+			return
+		case "/style.css":
+			w.Header().Set("ETag", `"css-1"`)
+			w.Header().Set("Content-Type", "text/css")
+			if r.Header.Get("If-None-Match") == `"css-1"` {
+				mu.Lock()
+				revalidated[r.URL.Path]++
+				mu.Unlock()
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		case "/logo.png":
+			w.Header().Set("Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT")
+			w.Header().Set("Content-Type", "image/png")
+			if r.Header.Get("If-Modified-Since") != "" {
+				mu.Lock()
+				revalidated[r.URL.Path]++
+				mu.Unlock()
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		case "/fresh.png":
+			w.Header().Set("Content-Type", "image/png")
+		}
+		mu.Lock()
+		full[r.URL.Path]++
+		mu.Unlock()
+		_, _ = w.Write([]byte("content of " + r.URL.Path))
+	}))
+	defer server.Close()
+
+	origin := enablePrivateNet
+	enablePrivateNet = true
+	defer func() { enablePrivateNet = origin }()
+
+	cacheDir := t.TempDir()
+	for i, want := range []AssetCacheUse{{Stored: 2}, {Hits: 2}} {
+		p := NewWebpackPlugin(types.PluginCall{
+			WorkingPath: t.TempDir(),
+			Params:      map[string]string{"file_type": "webarchive", "clutter_free": "false"},
+			Config:      map[string]string{webpackConfigAssetCacheDir: cacheDir},
+		}).(*WebpackPlugin)
+		resp, err := p.Run(context.Background(), &api.Request{Parameter: map[string]any{
+			"file_name": "cached",
+			"url":       server.URL + "/page",
+		}})
+		if err != nil || !resp.IsSucceed {
+			t.Fatalf("run %d failed: %v %v", i, err, resp)
+		}
+		if got := resp.Results["asset_cache"]; got != want {
+			t.Errorf("run %d: asset_cache = %v, want %v", i, got, want)
+		}
+		_, resources, err := readArchive(resp.Results["file_path"].(string))
+		if err != nil {
+			t.Fatalf("read archive failed: %v", err)
+		}
+		for _, path := range []string{"/style.css", "/logo.png", "/fresh.png"} {
+			if res, ok := resources[server.URL+path]; !ok || res.Size != len("content of "+path) {
+				t.Errorf("run %d: %s not archived completely: %+v", i, path, res)
+			}
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if full["/style.css"] != 1 || full["/logo.png"] != 1 || full["/fresh.png"] != 2 {
+		t.Errorf("unexpected full downloads %v", full)
+	}
+	if revalidated["/style.css"] != 1 || revalidated["/logo.png"] != 1 {
+		t.Errorf("unexpected revalidations %v", revalidated)
+	}
+}
@@ -0,0 +1,260 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package web
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/hyponet/webpage-packer/packer"
+	"howett.net/plist"
+)
+
+const (
+	AssetCaptured = "captured" // stored inside the archive file
+	AssetInline   = "inline"   // embedded in the page as a data URI
+	AssetExternal = "external" // left as a remote link, html output does not capture assets
+	AssetMissing  = "missing"  // referenced by the page but not in the archive
+
+	dataURIPreview = 64
+)
+
+// Asset is one resource referenced by the packed page.
+type Asset struct {
+	URL      string `json:"url"`
+	Kind     string `json:"kind"` // image, script, stylesheet, icon, media
+	Status   string `json:"status"`
+	Location string `json:"location,omitempty"` // archive file holding the asset, or the data URI prefix
+	MIMEType string `json:"mime_type,omitempty"`
+	Size     int    `json:"size,omitempty"`
+}
+
+// AssetReport audits how complete an archive is.
+type AssetReport struct {
+	URL      string   `json:"url"`
+	FilePath string   `json:"file_path"`
+	Assets   []Asset  `json:"assets"`
+	Missing  []string `json:"missing"`
+	Total    int      `json:"total"`
+	Captured int      `json:"captured"`
+	Inline   int      `json:"inline"`
+	External int      `json:"external"`
+}
+
+type archivedResource struct {
+	MIMEType string
+	Size     int
+}
+
+// BuildAssetReport lists the assets the packed page references and whether the file holds them.
+func BuildAssetReport(filePath, pageURL string) (*AssetReport, error) {
+	page, resources, err := readArchive(filePath)
+	if err != nil {
+		return nil, err
+	}
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return nil, fmt.Errorf("parse url %s failed: %w", pageURL, err)
+	}
+	refs, err := assetRefs(page, base)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &AssetReport{URL: pageURL, FilePath: filePath, Assets: make([]Asset, 0, len(refs)), Missing: make([]string, 0)}
+	for _, ref := range refs {
+		asset := Asset{URL: ref.url, Kind: ref.kind}
+		switch res, ok := resources[ref.url]; {
+		case strings.HasPrefix(ref.url, "data:"):
+			asset.Status = AssetInline
+			asset.Location = ref.url
+			if len(asset.Location) > dataURIPreview {
+				asset.Location = asset.Location[:dataURIPreview] + "..."
+			}
+			asset.URL = ""
+			if mediaType, payload, found := strings.Cut(strings.TrimPrefix(ref.url, "data:"), ","); found {
+				asset.MIMEType = strings.Split(mediaType, ";")[0]
+				asset.Size = len(payload)
+			}
+			report.Inline++
+		case resources == nil:
+			asset.Status = AssetExternal
+			report.External++
+		case ok:
+			asset.Status = AssetCaptured
+			asset.Location = filePath
+			asset.MIMEType, asset.Size = res.MIMEType, res.Size
+			report.Captured++
+		default:
+			asset.Status = AssetMissing
+			report.Missing = append(report.Missing, ref.url)
+		}
+		report.Assets = append(report.Assets, asset)
+	}
+	report.Total = len(report.Assets)
+	return report, nil
+}
+
+// readArchive returns the main page and the resources stored in the file by URL. Resources are nil for html files.
+func readArchive(filePath string) (string, map[string]archivedResource, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", nil, err
+	}
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".webarchive":
+		archive := &packer.WebArchive{}
+		if _, err = plist.Unmarshal(data, archive); err != nil {
+			return "", nil, fmt.Errorf("load webarchive failed: %w", err)
+		}
+		resources := make(map[string]archivedResource)
+		for _, res := range append([]packer.WebResourceItem{archive.WebMainResource}, archive.WebSubresources...) {
+			resources[res.WebResourceURL] = archivedResource{MIMEType: res.WebResourceMIMEType, Size: len(res.WebResourceData)}
+		}
+		return string(archive.WebMainResource.WebResourceData), resources, nil
+	case ".mhtml", ".mht":
+		return readMHTMLResources(bytes.NewReader(data))
+	default:
+		return string(data), nil, nil
+	}
+}
+
+func readMHTMLResources(r io.Reader) (string, map[string]archivedResource, error) {
+	msg, err := mail.ReadMessage(r)
+	if err != nil {
+		return "", nil, fmt.Errorf("parse mhtml failed: %w", err)
+	}
+	_, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil {
+		return "", nil, fmt.Errorf("parse mhtml failed: %w", err)
+	}
+
+	var (
+		page      string
+		resources = make(map[string]archivedResource)
+		mr        = multipart.NewReader(msg.Body, params["boundary"])
+	)
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", nil, fmt.Errorf("parse mhtml failed: %w", err)
+		}
+		var body io.Reader = part
+		if strings.EqualFold(part.Header.Get("Content-Transfer-Encoding"), "base64") {
+			body = base64.NewDecoder(base64.StdEncoding, part)
+		}
+		data, err := io.ReadAll(body)
+		if err != nil {
+			return "", nil, fmt.Errorf("read mhtml part failed: %w", err)
+		}
+		mediaType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		if page == "" && mediaType == "text/html" {
+			page = string(data)
+		}
+		resources[part.Header.Get("Content-Location")] = archivedResource{MIMEType: mediaType, Size: len(data)}
+	}
+	return page, resources, nil
+}
+
+type assetRef struct {
+	url  string
+	kind string
+}
+
+// assetRefs resolves the assets referenced by the page, in document order without duplicates.
+func assetRefs(page string, base *url.URL) ([]assetRef, error) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(page))
+	if err != nil {
+		return nil, fmt.Errorf("parse page failed: %w", err)
+	}
+
+	var (
+		refs []assetRef
+		seen = map[string]bool{}
+	)
+	add := func(raw, kind string) {
+		raw = strings.TrimSpace(raw)
+		if raw == "" || strings.HasPrefix(raw, "#") || strings.HasPrefix(raw, "javascript:") {
+			return
+		}
+		if !strings.HasPrefix(raw, "data:") {
+			u, err := url.Parse(raw)
+			if err != nil {
+				return
+			}
+			u = base.ResolveReference(u)
+			u.Fragment = ""
+			raw = u.String()
+		}
+		if seen[raw] {
+			return
+		}
+		seen[raw] = true
+		refs = append(refs, assetRef{url: raw, kind: kind})
+	}
+
+	doc.Find("img[src], script[src], link[href], video, audio, source[src]").Each(func(_ int, s *goquery.Selection) {
+		switch goquery.NodeName(s) {
+		case "img":
+			add(s.AttrOr("src", ""), "image")
+		case "script":
+			add(s.AttrOr("src", ""), "script")
+		case "link":
+			for _, rel := range strings.Fields(strings.ToLower(s.AttrOr("rel", ""))) {
+				switch rel {
+				case "stylesheet":
+					add(s.AttrOr("href", ""), "stylesheet")
+					return
+				case "icon", "apple-touch-icon":
+					add(s.AttrOr("href", ""), "icon")
+					return
+				}
+			}
+		case "source":
+			if s.ParentFiltered("video, audio").Length() > 0 {
+				add(s.AttrOr("src", ""), "media")
+			}
+		default:
+			add(s.AttrOr("poster", ""), "image")
+			add(s.AttrOr("src", ""), "media")
+		}
+	})
+	return refs, nil
+}
+
+// writeAssetReport saves the report as JSON next to the packed file.
+func writeAssetReport(report *AssetReport, reportPath string) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(reportPath, data, 0644)
+}
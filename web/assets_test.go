@@ -0,0 +1,155 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package web
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/types"
+	"github.com/hyponet/webpage-packer/packer"
+	"howett.net/plist"
+)
+
+const assetPage = `<html><head><link rel="stylesheet" href="/style.css"><link rel="icon" href="https://cdn.example.com/favicon.ico"></head>
+<body><img src="/a.png"><img src="/a.png#top"><img src="data:image/gif;base64,R0lGODlhAQABAAAAACw="><script src="app.js"></script></body></html>`
+
+func TestBuildAssetReport_WebArchive(t *testing.T) {
+	archive := &packer.WebArchive{
+		WebMainResource: packer.WebResourceItem{WebResourceURL: "https://example.com/post/", WebResourceMIMEType: "text/html", WebResourceData: []byte(assetPage)},
+		WebSubresources: []packer.WebResourceItem{
+			{WebResourceURL: "https://example.com/a.png", WebResourceMIMEType: "image/png", WebResourceData: []byte("png-data")},
+			{WebResourceURL: "https://example.com/style.css", WebResourceMIMEType: "text/css", WebResourceData: []byte("body{}")},
+		},
+	}
+	data, err := plist.Marshal(archive, plist.BinaryFormat)
+	if err != nil {
+		t.Fatalf("marshal webarchive failed: %v", err)
+	}
+	filePath := filepath.Join(t.TempDir(), "post.webarchive")
+	if err = os.WriteFile(filePath, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := BuildAssetReport(filePath, "https://example.com/post/")
+	if err != nil {
+		t.Fatalf("build report failed: %v", err)
+	}
+	if report.Total != 5 || report.Captured != 2 || report.Inline != 1 || report.External != 0 {
+		t.Errorf("unexpected summary %+v", report)
+	}
+	want := []string{"https://cdn.example.com/favicon.ico", "https://example.com/post/app.js"}
+	if len(report.Missing) != len(want) || report.Missing[0] != want[0] || report.Missing[1] != want[1] {
+		t.Errorf("unexpected missing %v", report.Missing)
+	}
+	for _, asset := range report.Assets {
+		if asset.URL == "https://example.com/a.png" && (asset.Status != AssetCaptured || asset.Size != 8 || asset.Location != filePath) {
+			t.Errorf("unexpected image asset %+v", asset)
+		}
+		if asset.Status == AssetInline && asset.MIMEType != "image/gif" {
+			t.Errorf("unexpected inline asset %+v", asset)
+		}
+	}
+}
+
+func TestBuildAssetReport_MHTMLAndHTML(t *testing.T) {
+	archive := &packer.WebArchive{
+		WebMainResource: packer.WebResourceItem{WebResourceURL: "https://example.com/post/", WebResourceMIMEType: "text/html", WebResourceData: []byte(assetPage)},
+		WebSubresources: []packer.WebResourceItem{
+			{WebResourceURL: "https://example.com/a.png", WebResourceMIMEType: "image/png", WebResourceData: []byte("png-data")},
+		},
+	}
+	buf := &bytes.Buffer{}
+	if err := writeMHTML(buf, archive, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	mhtmlPath := filepath.Join(dir, "post.mhtml")
+	htmlPath := filepath.Join(dir, "post.html")
+	if err := os.WriteFile(mhtmlPath, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(htmlPath, []byte(assetPage), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := BuildAssetReport(mhtmlPath, "https://example.com/post/")
+	if err != nil {
+		t.Fatalf("build mhtml report failed: %v", err)
+	}
+	if report.Captured != 1 || len(report.Missing) != 3 {
+		t.Errorf("unexpected mhtml summary %+v", report)
+	}
+
+	report, err = BuildAssetReport(htmlPath, "https://example.com/post/")
+	if err != nil {
+		t.Fatalf("build html report failed: %v", err)
+	}
+	if report.External != 4 || report.Inline != 1 || len(report.Missing) != 0 {
+		t.Errorf("unexpected html summary %+v", report)
+	}
+}
+
+func TestWebpackPlugin_AssetReport(t *testing.T) {
+	defer func(orig bool) { enablePrivateNet = orig }(enablePrivateNet)
+	enablePrivateNet = true
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/article", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte(`<html><head><title>Article</title></head><body><p>Offline reading</p><img src="http://` + r.Host + `/logo.png"></body></html>`))
+	})
+	mux.HandleFunc("/logo.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		_, _ = w.Write([]byte("\x89PNG\r\n\x1a\nlogo"))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	workdir := t.TempDir()
+	p := NewWebpackPlugin(types.PluginCall{
+		WorkingPath: workdir,
+		Params:      map[string]string{webpackParameterFileType: "webarchive", webpackParameterClutterFree: "false"},
+	}).(*WebpackPlugin)
+
+	resp, err := p.Run(context.Background(), &api.Request{Parameter: map[string]any{
+		webpackParameterFileName:    "article",
+		webpackParameterURL:         server.URL + "/article",
+		webpackParameterAssetReport: "true",
+	}})
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	reportPath := filepath.Join(workdir, "article.assets.json")
+	if resp.Results["asset_report_path"] != reportPath {
+		t.Errorf("unexpected report path %v", resp.Results["asset_report_path"])
+	}
+	if _, err = os.Stat(reportPath); err != nil {
+		t.Errorf("report file not written: %v", err)
+	}
+	summary, _ := resp.Results["assets"].(map[string]any)
+	if summary["total"] != 1 || summary["captured"] != 1 || summary["missing"] != 0 {
+		t.Errorf("unexpected summary %v", summary)
+	}
+}
@@ -0,0 +1,220 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package web
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/utils"
+)
+
+const (
+	webpackParameterURLs         = "urls"
+	webpackParameterFileNames    = "file_names"
+	webpackParameterNameTemplate = "name_template"
+
+	maxBatchURLs = 1000
+)
+
+// BatchOption lists the pages archived by one call and the names of their files.
+type BatchOption struct {
+	URLs  []string
+	Names []string
+}
+
+// batchName is the data name_template is executed with.
+type batchName struct {
+	Index    int    // 1-based position in urls
+	FileName string // the file_name parameter
+	Host     string
+	Slug     string // last path segment of the URL
+	ID       string // short hash of the URL
+}
+
+// parseBatchOption reads urls with file_names or name_template. Without either the pages are
+// named <file_name>_001, <file_name>_002, ... like sitemap pages.
+func parseBatchOption(request *api.Request, filename string) (BatchOption, error) {
+	var opt BatchOption
+	raw := api.GetStringParameter(webpackParameterURLs, request, "")
+	if raw == "" {
+		return opt, nil
+	}
+	if err := json.Unmarshal([]byte(raw), &opt.URLs); err != nil {
+		return opt, fmt.Errorf("parse urls failed: expect a JSON list of URLs: %s", err)
+	}
+	switch {
+	case len(opt.URLs) == 0:
+		return opt, fmt.Errorf("urls is empty")
+	case len(opt.URLs) > maxBatchURLs:
+		return opt, fmt.Errorf("urls has %d entries, at most %d are allowed", len(opt.URLs), maxBatchURLs)
+	}
+	for i, u := range opt.URLs {
+		parsed, err := url.Parse(u)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+			return opt, fmt.Errorf("invalid url %d [%s]", i, u)
+		}
+	}
+
+	namesRaw := api.GetStringParameter(webpackParameterFileNames, request, "")
+	tplRaw := api.GetStringParameter(webpackParameterNameTemplate, request, "")
+	switch {
+	case namesRaw != "" && tplRaw != "":
+		return opt, fmt.Errorf("file_names and name_template are exclusive")
+	case namesRaw != "":
+		if err := json.Unmarshal([]byte(namesRaw), &opt.Names); err != nil {
+			return opt, fmt.Errorf("parse file_names failed: expect a JSON list of names: %s", err)
+		}
+		if len(opt.Names) != len(opt.URLs) {
+			return opt, fmt.Errorf("file_names has %d entries, urls has %d", len(opt.Names), len(opt.URLs))
+		}
+		for i, name := range opt.Names {
+			opt.Names[i] = utils.SanitizeFilename(name)
+			if opt.Names[i] == "" {
+				return opt, fmt.Errorf("file name %d is empty", i)
+			}
+		}
+	case tplRaw != "":
+		tpl, err := template.New(webpackParameterNameTemplate).Option("missingkey=error").Parse(tplRaw)
+		if err == nil {
+			// catch unknown fields before any page is fetched
+			err = tpl.Execute(io.Discard, batchName{})
+		}
+		if err != nil {
+			return opt, fmt.Errorf("parse name_template failed: %s", err)
+		}
+		for i, u := range opt.URLs {
+			name, err := executeBatchName(tpl, i, u, filename)
+			if err != nil {
+				return opt, err
+			}
+			opt.Names = append(opt.Names, name)
+		}
+	default:
+		for i := range opt.URLs {
+			opt.Names = append(opt.Names, fmt.Sprintf("%s_%03d", filename, i+1))
+		}
+	}
+
+	seen := map[string]bool{}
+	for i, name := range opt.Names {
+		if seen[name] {
+			if namesRaw != "" {
+				return opt, fmt.Errorf("file name %s is used more than once", name)
+			}
+			// a template may name two pages alike, keep both files
+			name = fmt.Sprintf("%s_%03d", name, i+1)
+			opt.Names[i] = name
+		}
+		seen[name] = true
+	}
+	return opt, nil
+}
+
+func executeBatchName(tpl *template.Template, i int, rawURL, filename string) (string, error) {
+	data := batchName{Index: i + 1, FileName: filename}
+	if u, err := url.Parse(rawURL); err == nil {
+		data.Host = u.Hostname()
+		data.Slug = strings.TrimSuffix(path.Base(strings.TrimSuffix(u.Path, "/")), path.Ext(u.Path))
+		if data.Slug == "." || data.Slug == "/" {
+			data.Slug = ""
+		}
+	}
+	sum := sha256.Sum256([]byte(utils.CanonicalURL(rawURL)))
+	data.ID = hex.EncodeToString(sum[:4])
+
+	var buf strings.Builder
+	if err := tpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("execute name_template failed: %s", err)
+	}
+	name := utils.SanitizeFilename(buf.String())
+	if name == "" {
+		name = fmt.Sprintf("%s_%03d", filename, i+1)
+	}
+	return name, nil
+}
+
+// captureBatch archives the urls of one call on a bounded pool of workers and writes the index
+// of the pages in the order of urls. A failed page does not fail the others.
+func (w *WebpackPlugin) captureBatch(ctx context.Context, filename string, batch BatchOption, concurrency int, options ...Option) ([]CrawledPage, string, error) {
+	pages, err := w.packPages(ctx, batch.URLs, batch.Names, concurrency, options...)
+	if err != nil {
+		return nil, "", err
+	}
+	indexPath := filepath.Join(w.fileRoot.Workdir(), filename+crawlIndexExtension)
+	data, err := json.MarshalIndent(map[string]any{"urls": batch.URLs, "pages": pages}, "", "  ")
+	if err != nil {
+		return nil, "", err
+	}
+	if err = os.WriteFile(indexPath, data, 0644); err != nil {
+		return nil, "", fmt.Errorf("write batch index failed: %w", err)
+	}
+	return pages, indexPath, nil
+}
+
+// packPages packs urls[i] as names[i] with up to concurrency pages at the same time.
+func (w *WebpackPlugin) packPages(ctx context.Context, urls, names []string, concurrency int, options ...Option) ([]CrawledPage, error) {
+	var (
+		pages = make([]CrawledPage, len(urls))
+		jobs  = make(chan int)
+		wg    sync.WaitGroup
+	)
+	for n := 0; n < min(concurrency, len(urls)); n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				page := CrawledPage{URL: urls[i]}
+				result, err := w.packFromURL(ctx, names[i], page.URL, w.fileType, w.clutterFree, options...)
+				if err != nil {
+					w.logger.Warnw("packing page failed", "url", page.URL, "error", err)
+					page.Error = err.Error()
+				} else {
+					page.FilePath, _ = result["file_path"].(string)
+					page.Size, _ = result["size"].(int64)
+				}
+				pages[i] = page
+			}
+		}()
+	}
+feed:
+	for i := range urls {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return pages, nil
+}
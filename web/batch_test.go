@@ -0,0 +1,128 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package web
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/types"
+)
+
+func TestParseBatchOption(t *testing.T) {
+	urls := []string{"https://example.com/posts/hello.html", "https://blog.test/", "https://example.com/posts/hello"}
+	tests := []struct {
+		params map[string]any
+		want   []string
+	}{
+		{map[string]any{}, []string{"bookmarks_001", "bookmarks_002", "bookmarks_003"}},
+		{map[string]any{webpackParameterFileNames: []string{"a", "b/c", "d"}}, []string{"a", "b_c", "d"}},
+		{map[string]any{webpackParameterNameTemplate: "{{.Host}}-{{.Slug}}"},
+			[]string{"example_com-hello", "blog_test-", "example_com-hello_003"}},
+		{map[string]any{webpackParameterNameTemplate: "{{.FileName}}-{{.Index}}"},
+			[]string{"bookmarks-1", "bookmarks-2", "bookmarks-3"}},
+	}
+	for _, tt := range tests {
+		tt.params[webpackParameterURLs] = urls
+		opt, err := parseBatchOption(&api.Request{Parameter: tt.params}, "bookmarks")
+		if err != nil {
+			t.Errorf("%v: parse failed: %v", tt.params, err)
+			continue
+		}
+		if !reflect.DeepEqual(opt.URLs, urls) || !reflect.DeepEqual(opt.Names, tt.want) {
+			t.Errorf("%v: names = %v, want %v", tt.params, opt.Names, tt.want)
+		}
+	}
+
+	for _, params := range []map[string]any{
+		{webpackParameterURLs: "https://example.com"},
+		{webpackParameterURLs: []string{}},
+		{webpackParameterURLs: []string{"ftp://example.com/file"}},
+		{webpackParameterURLs: urls, webpackParameterFileNames: []string{"a"}},
+		{webpackParameterURLs: urls, webpackParameterFileNames: []string{"a", "b", "a"}},
+		{webpackParameterURLs: urls, webpackParameterNameTemplate: "{{.Title}}"},
+		{webpackParameterURLs: urls, webpackParameterNameTemplate: "{{.Host}}", webpackParameterFileNames: []string{"a", "b", "c"}},
+	} {
+		if _, err := parseBatchOption(&api.Request{Parameter: params}, "bookmarks"); err == nil {
+			t.Errorf("expected error for %v", params)
+		}
+	}
+	if opt, err := parseBatchOption(&api.Request{}, "bookmarks"); err != nil || opt.URLs != nil {
+		t.Errorf("expected no batch, got %+v, %v", opt, err)
+	}
+}
+
+func TestWebpackPlugin_Batch(t *testing.T) {
+	defer func(orig bool) { enablePrivateNet = orig }(enablePrivateNet)
+	enablePrivateNet = true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/gone" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte("<html><head><title>" + r.URL.Path + "</title></head><body><p>" + r.URL.Path + "</p></body></html>"))
+	}))
+	defer server.Close()
+
+	workdir := t.TempDir()
+	p := NewWebpackPlugin(types.PluginCall{
+		WorkingPath: workdir,
+		Params:      map[string]string{webpackParameterFileType: "html", webpackParameterClutterFree: "false"},
+	}).(*WebpackPlugin)
+	resp, err := p.Run(context.Background(), &api.Request{Parameter: map[string]any{
+		webpackParameterFileName:     "bookmarks",
+		webpackParameterURLs:         []string{server.URL + "/one", server.URL + "/gone", server.URL + "/two"},
+		webpackParameterNameTemplate: "{{.Slug}}",
+		webpackParameterConcurrency:  "2",
+	}})
+	if err != nil || !resp.IsSucceed {
+		t.Fatalf("run failed: %v %v", err, resp)
+	}
+	if resp.Results["captured"] != 2 || resp.Results["failed"] != 1 {
+		t.Errorf("unexpected summary %v", resp.Results)
+	}
+	pages := resp.Results["pages"].([]map[string]any)
+	for i, name := range []string{"one", "", "two"} {
+		if name == "" {
+			if pages[i]["error"] == nil {
+				t.Errorf("page %d: expected error, got %v", i, pages[i])
+			}
+			continue
+		}
+		if pages[i]["file_path"] != filepath.Join(workdir, name+".html") {
+			t.Errorf("page %d: unexpected file %v", i, pages[i]["file_path"])
+		}
+	}
+	if _, err = os.Stat(filepath.Join(workdir, "bookmarks.index.json")); err != nil {
+		t.Errorf("expected index file: %v", err)
+	}
+
+	if _, err = p.Run(context.Background(), &api.Request{Parameter: map[string]any{
+		webpackParameterFileName: "bookmarks",
+		webpackParameterURL:      server.URL + "/one",
+		webpackParameterURLs:     []string{server.URL + "/two"},
+	}}); err == nil {
+		t.Errorf("expected url and urls to be exclusive")
+	}
+}
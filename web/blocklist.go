@@ -0,0 +1,307 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/basenana/plugin/api"
+)
+
+const (
+	webpackParameterBlocklist      = "blocklist"
+	webpackParameterBlocklistFile  = "blocklist_file"
+	webpackParameterSkipMediaTypes = "skip_media_types"
+	webpackParameterAllowedTypes   = "allowed_asset_types"
+)
+
+// AssetFilter keeps blocked and unwanted assets out of a capture. Rules follow the EasyList URL
+// syntax (||host^, |, ^, * and @@ exceptions, $options are ignored); a bare domain or a hosts file
+// line blocks the domain and its subdomains. Assets can also be limited by media type.
+type AssetFilter struct {
+	domains      []string
+	block        []*regexp.Regexp
+	allow        []*regexp.Regexp
+	skipTypes    []string
+	allowedTypes []string
+}
+
+// ParseAssetFilter builds a filter from blocklist rules, media type patterns such as video/* to skip,
+// and media type patterns assets are limited to. It returns nil when all are empty.
+func ParseAssetFilter(rules, skipTypes, allowedTypes []string) (*AssetFilter, error) {
+	f := &AssetFilter{}
+	for _, rule := range rules {
+		if err := f.addRule(rule); err != nil {
+			return nil, err
+		}
+	}
+	var err error
+	if f.skipTypes, err = parseMediaTypePatterns(skipTypes); err != nil {
+		return nil, err
+	}
+	if f.allowedTypes, err = parseMediaTypePatterns(allowedTypes); err != nil {
+		return nil, err
+	}
+	if len(f.domains) == 0 && len(f.block) == 0 && len(f.skipTypes) == 0 && len(f.allowedTypes) == 0 {
+		return nil, nil
+	}
+	return f, nil
+}
+
+func parseMediaTypePatterns(raw []string) ([]string, error) {
+	var patterns []string
+	for _, t := range raw {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t == "" {
+			continue
+		}
+		if _, err := path.Match(t, ""); err != nil {
+			return nil, fmt.Errorf("invalid media type pattern [%s]", t)
+		}
+		patterns = append(patterns, t)
+	}
+	return patterns, nil
+}
+
+func (f *AssetFilter) addRule(rule string) error {
+	rule = strings.TrimSpace(rule)
+	switch {
+	case rule == "", strings.HasPrefix(rule, "!"), strings.HasPrefix(rule, "["),
+		strings.HasPrefix(rule, "#"), strings.Contains(rule, "##"), strings.Contains(rule, "#@#"):
+		// comments, headers and element hiding rules
+		return nil
+	}
+	if fields := strings.Fields(rule); len(fields) == 2 && (fields[0] == "0.0.0.0" || fields[0] == "127.0.0.1") {
+		rule = fields[1]
+	}
+	if isDomainRule(rule) {
+		f.domains = append(f.domains, strings.ToLower(strings.TrimPrefix(rule, "*.")))
+		return nil
+	}
+
+	exception := strings.HasPrefix(rule, "@@")
+	rule = strings.TrimPrefix(rule, "@@")
+	if i := strings.LastIndex(rule, "$"); i >= 0 {
+		rule = rule[:i]
+	}
+	if rule == "" {
+		return nil
+	}
+	re, err := regexp.Compile(ruleExpr(rule))
+	if err != nil {
+		return fmt.Errorf("invalid blocklist rule [%s]: %w", rule, err)
+	}
+	if exception {
+		f.allow = append(f.allow, re)
+	} else {
+		f.block = append(f.block, re)
+	}
+	return nil
+}
+
+var domainRule = regexp.MustCompile(`^(\*\.)?[A-Za-z0-9-]+(\.[A-Za-z0-9-]+)+$`)
+
+func isDomainRule(rule string) bool {
+	return domainRule.MatchString(rule)
+}
+
+// ruleExpr translates an EasyList URL pattern to a case-insensitive regular expression.
+func ruleExpr(rule string) string {
+	var b strings.Builder
+	b.WriteString("(?i)")
+	switch {
+	case strings.HasPrefix(rule, "||"):
+		b.WriteString(`^[a-z][a-z0-9+.-]*://([^/?#]*\.)?`)
+		rule = rule[2:]
+	case strings.HasPrefix(rule, "|"):
+		b.WriteString("^")
+		rule = rule[1:]
+	}
+	anchorEnd := strings.HasSuffix(rule, "|")
+	rule = strings.TrimSuffix(rule, "|")
+	for _, r := range rule {
+		switch r {
+		case '*':
+			b.WriteString(".*")
+		case '^':
+			b.WriteString(`([/?&=:]|$)`)
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	if anchorEnd {
+		b.WriteString("$")
+	}
+	return b.String()
+}
+
+// Blocked reports whether the rules block rawURL or its extension names a media type that is left out.
+func (f *AssetFilter) Blocked(rawURL string) bool {
+	if f == nil {
+		return false
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	for _, re := range f.allow {
+		if re.MatchString(rawURL) {
+			return false
+		}
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, domain := range f.domains {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	for _, re := range f.block {
+		if re.MatchString(rawURL) {
+			return true
+		}
+	}
+	if ext := path.Ext(u.Path); ext != "" {
+		if t := mime.TypeByExtension(ext); t != "" && !f.allowsType(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// Allows reports whether an asset served with contentType is kept. Without a Content-Type the
+// media type is guessed from the extension of rawURL, and is application/octet-stream otherwise.
+func (f *AssetFilter) Allows(rawURL, contentType string) bool {
+	if f == nil {
+		return true
+	}
+	if contentType == "" {
+		if u, err := url.Parse(rawURL); err == nil {
+			contentType = mime.TypeByExtension(path.Ext(u.Path))
+		}
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	return f.allowsType(contentType)
+}
+
+// allowsType reports whether the media type, e.g. "video/mp4; codecs=avc1", is neither skipped
+// nor outside the allowed types.
+func (f *AssetFilter) allowsType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return len(f.allowedTypes) == 0
+	}
+	if matchMediaType(f.skipTypes, mediaType) {
+		return false
+	}
+	return len(f.allowedTypes) == 0 || matchMediaType(f.allowedTypes, mediaType)
+}
+
+func matchMediaType(patterns []string, mediaType string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, mediaType); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// filterPage removes the elements loading blocked assets from the page and returns the blocked URLs.
+func (f *AssetFilter) filterPage(page []byte, base *url.URL) ([]byte, []string, error) {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(page))
+	if err != nil {
+		return nil, nil, fmt.Errorf("parse page failed: %w", err)
+	}
+	var blocked []string
+	doc.Find("script[src], link[href], img[src], iframe[src], embed[src], video[src], audio[src], source[src]").Each(func(_ int, s *goquery.Selection) {
+		attr := "src"
+		if goquery.NodeName(s) == "link" {
+			if !loadsAsset(s.AttrOr("rel", "")) {
+				return
+			}
+			attr = "href"
+		}
+		u, err := url.Parse(strings.TrimSpace(s.AttrOr(attr, "")))
+		if err != nil || strings.HasPrefix(u.Scheme, "data") {
+			return
+		}
+		u = base.ResolveReference(u)
+		if f.Blocked(u.String()) {
+			blocked = append(blocked, u.String())
+			s.Remove()
+		}
+	})
+	if len(blocked) == 0 {
+		return page, nil, nil
+	}
+	html, err := doc.Html()
+	if err != nil {
+		return nil, nil, err
+	}
+	return []byte(html), blocked, nil
+}
+
+// loadsAsset reports whether a link with the rel attribute loads a resource, unlike canonical or alternate links.
+func loadsAsset(rel string) bool {
+	for _, r := range strings.Fields(strings.ToLower(rel)) {
+		switch r {
+		case "stylesheet", "icon", "apple-touch-icon", "preload", "modulepreload", "prefetch", "manifest":
+			return true
+		}
+	}
+	return false
+}
+
+// parseAssetFilter reads blocklist, blocklist_file, skip_media_types and allowed_asset_types.
+func (w *WebpackPlugin) parseAssetFilter(request *api.Request) (*AssetFilter, error) {
+	var (
+		rules        = splitRules(api.GetStringParameter(webpackParameterBlocklist, request, ""))
+		ruleFile     = api.GetStringParameter(webpackParameterBlocklistFile, request, "")
+		skipTypes    = splitRules(api.GetStringParameter(webpackParameterSkipMediaTypes, request, ""))
+		allowedTypes = splitRules(api.GetStringParameter(webpackParameterAllowedTypes, request, ""))
+	)
+	if ruleFile != "" {
+		data, err := w.fileRoot.Read(ruleFile)
+		if err != nil {
+			return nil, fmt.Errorf("read blocklist file failed: %w", err)
+		}
+		rules = append(rules, strings.Split(string(data), "\n")...)
+	}
+	return ParseAssetFilter(rules, skipTypes, allowedTypes)
+}
+
+// splitRules accepts a JSON list, or rules separated by new lines or commas.
+func splitRules(raw string) []string {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil
+	}
+	var list []string
+	if strings.HasPrefix(raw, "[") && json.Unmarshal([]byte(raw), &list) == nil {
+		return list
+	}
+	return strings.FieldsFunc(raw, func(r rune) bool { return r == '\n' || r == ',' })
+}
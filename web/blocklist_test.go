@@ -0,0 +1,179 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package web
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/types"
+)
+
+func TestAssetFilter_Blocked(t *testing.T) {
+	filter, err := ParseAssetFilter([]string{
+		"[Adblock Plus 2.0]",
+		"! comment",
+		"example.com##.banner",
+		"0.0.0.0 tracker.test",
+		"ads.test",
+		"||cdn.test/pixel^$image",
+		"/analytics.js",
+		"|http://plain.test/ad*.gif|",
+		"@@||ads.test/allowed.js",
+	}, []string{"video/*"}, nil)
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	tests := map[string]bool{
+		"https://tracker.test/t.js":         true,
+		"https://www.tracker.test/t.js":     true,
+		"https://nottracker.test/t.js":      false,
+		"https://ads.test/banner.png":       true,
+		"https://ads.test/allowed.js":       false,
+		"https://img.cdn.test/pixel?id=1":   true,
+		"https://cdn.test/pixels.png":       false,
+		"https://site.test/js/analytics.js": true,
+		"http://plain.test/ad1.gif":         true,
+		"http://plain.test/ad1.gif?x=1":     false,
+		"https://site.test/movie.mp4":       true,
+		"https://site.test/photo.png":       false,
+		"https://example.com/page":          false,
+	}
+	for u, want := range tests {
+		if got := filter.Blocked(u); got != want {
+			t.Errorf("Blocked(%s) = %v, want %v", u, got, want)
+		}
+	}
+
+	if filter.Allows("https://site.test/clip", "video/mp4; codecs=avc1") || !filter.Allows("https://site.test/a", "image/png") {
+		t.Errorf("unexpected media type match")
+	}
+	if filter, err = ParseAssetFilter([]string{"! only comments"}, nil, nil); filter != nil || err != nil {
+		t.Errorf("expected no filter, got %v, %v", filter, err)
+	}
+	if _, err = ParseAssetFilter(nil, []string{"video/[*"}, nil); err == nil {
+		t.Errorf("expected error for invalid media type pattern")
+	}
+}
+
+func TestSplitRules(t *testing.T) {
+	for raw, want := range map[string]int{
+		"":                           0,
+		"a.test\nb.test":             2,
+		"video/*, audio/*":           2,
+		`["||a.test^", "b.test"]`:    2,
+		"[Adblock Plus 2.0]\na.test": 2,
+	} {
+		if got := splitRules(raw); len(got) != want {
+			t.Errorf("splitRules(%q) = %v, want %d rules", raw, got, want)
+		}
+	}
+}
+
+func TestWebpackPlugin_Blocklist(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/page":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			_, _ = w.Write([]byte(`<html><head><title>Blocklist</title>
+<script src="/js/analytics.js"></script><script src="http://ads.invalid/ad.js"></script><script src="/app.js"></script></head>
+<body><p>content</p><img src="/photo.png"><img src="/banner"><video src="/movie.mp4"></video></body></html>`))
+		case "/app.js":
+			w.Header().Set("Content-Type", "text/javascript")
+			_, _ = w.Write([]byte("console.log('app')"))
+		case "/photo.png":
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = w.Write([]byte("png"))
+		case "/banner":
+			w.Header().Set("Content-Type", "image/gif")
+			_, _ = w.Write([]byte("gif"))
+		case "/js/analytics.js":
+			t.Errorf("blocked script was requested")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	origin := enablePrivateNet
+	enablePrivateNet = true
+	defer func() { enablePrivateNet = origin }()
+
+	workdir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(workdir, "easylist.txt"), []byte("! easylist\n||ads.invalid^\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	p := NewWebpackPlugin(types.PluginCall{
+		WorkingPath: workdir,
+		Params:      map[string]string{"file_type": "webarchive", "clutter_free": "false"},
+	}).(*WebpackPlugin)
+
+	resp, err := p.Run(context.Background(), &api.Request{Parameter: map[string]any{
+		"file_name":        "blocked",
+		"url":              server.URL + "/page",
+		"blocklist":        "/analytics.js",
+		"blocklist_file":   "easylist.txt",
+		"skip_media_types": "video/*,image/gif",
+	}})
+	if err != nil || !resp.IsSucceed {
+		t.Fatalf("Run failed: %v %v", err, resp)
+	}
+
+	blocked := resp.Results["blocked"].([]string)
+	sort.Strings(blocked)
+	want := []string{"http://ads.invalid/ad.js", server.URL + "/banner", server.URL + "/js/analytics.js", server.URL + "/movie.mp4"}
+	sort.Strings(want)
+	if strings.Join(blocked, ",") != strings.Join(want, ",") {
+		t.Errorf("blocked = %v, want %v", blocked, want)
+	}
+
+	page, resources, err := readArchive(resp.Results["file_path"].(string))
+	if err != nil {
+		t.Fatalf("read archive failed: %v", err)
+	}
+	for _, removed := range []string{"analytics.js", "ads.invalid", "movie.mp4"} {
+		if strings.Contains(page, removed) {
+			t.Errorf("expected %s removed from page", removed)
+		}
+	}
+	for _, path := range []string{"/app.js", "/photo.png"} {
+		if _, ok := resources[server.URL+path]; !ok {
+			t.Errorf("expected %s in archive", path)
+		}
+	}
+	if _, ok := resources[server.URL+"/banner"]; ok {
+		t.Errorf("skipped media type should not be archived")
+	}
+}
+
+func TestWebpackPlugin_BlocklistWithBrowser(t *testing.T) {
+	p := NewWebpackPlugin(types.PluginCall{WorkingPath: t.TempDir()}).(*WebpackPlugin)
+	if _, err := p.Run(context.Background(), &api.Request{Parameter: map[string]any{
+		"url":       "https://example.com",
+		"render":    RenderBrowser,
+		"blocklist": "ads.test",
+	}}); err == nil {
+		t.Errorf("expected blocklist to be rejected with render browser")
+	}
+}
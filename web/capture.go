@@ -0,0 +1,599 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package web
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"code.dny.dev/ssrf"
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/logger"
+	"github.com/hyponet/webpage-packer/packer"
+	"golang.org/x/net/html/charset"
+	"howett.net/plist"
+)
+
+const (
+	webpackParameterTimeout      = "timeout"
+	webpackParameterMaxRedirects = "max_redirects"
+	webpackParameterRetries      = "retries"
+	webpackParameterRetryBackoff = "retry_backoff"
+	webpackParameterMaxPageSize  = "max_page_size"
+	webpackParameterMaxAssetSize = "max_asset_size"
+	webpackParameterMaxTotalSize = "max_total_size"
+
+	defaultRetryBackoff = time.Second
+	maxRetryBackoff     = 30 * time.Second
+	maxRetries          = 10
+	resourceConcurrency = 8
+	resourcePage        = "page"
+)
+
+// FetchControl bounds every request of a capture. A capture with controls downloads the page and its
+// assets itself instead of through the packer, so a slow asset costs at most its own attempts.
+type FetchControl struct {
+	Timeout      time.Duration
+	MaxRedirects int
+	Retries      int
+	Backoff      time.Duration
+	Filter       *AssetFilter
+
+	// size limits in bytes, 0 means unlimited
+	MaxPageSize  int64
+	MaxAssetSize int64
+	MaxTotalSize int64
+}
+
+// ResourceAttempts is how often one resource of a capture was requested.
+type ResourceAttempts struct {
+	URL      string `json:"url"`
+	Kind     string `json:"kind"` // page, or the asset kind
+	Attempts int    `json:"attempts"`
+	Error    string `json:"error,omitempty"`
+}
+
+// parseFetchControl reads timeout, max_redirects, retries, retry_backoff and the size limits.
+// It returns nil when none is set.
+func parseFetchControl(request *api.Request) (*FetchControl, error) {
+	var (
+		timeoutRaw   = api.GetStringParameter(webpackParameterTimeout, request, "")
+		redirectsRaw = api.GetStringParameter(webpackParameterMaxRedirects, request, "")
+		retriesRaw   = api.GetStringParameter(webpackParameterRetries, request, "")
+		backoffRaw   = api.GetStringParameter(webpackParameterRetryBackoff, request, "")
+		pageSizeRaw  = api.GetStringParameter(webpackParameterMaxPageSize, request, "")
+		assetSizeRaw = api.GetStringParameter(webpackParameterMaxAssetSize, request, "")
+		totalSizeRaw = api.GetStringParameter(webpackParameterMaxTotalSize, request, "")
+		control      = defaultFetchControl()
+		err          error
+	)
+	if timeoutRaw == "" && redirectsRaw == "" && retriesRaw == "" && backoffRaw == "" &&
+		pageSizeRaw == "" && assetSizeRaw == "" && totalSizeRaw == "" {
+		return nil, nil
+	}
+	if timeoutRaw != "" {
+		if control.Timeout, err = time.ParseDuration(timeoutRaw); err != nil || control.Timeout <= 0 {
+			return nil, fmt.Errorf("invalid timeout [%s]: expect positive duration", timeoutRaw)
+		}
+	}
+	if redirectsRaw != "" {
+		if control.MaxRedirects, err = strconv.Atoi(redirectsRaw); err != nil || control.MaxRedirects < 0 {
+			return nil, fmt.Errorf("invalid max_redirects [%s]: expect non-negative integer", redirectsRaw)
+		}
+	}
+	if retriesRaw != "" {
+		if control.Retries, err = strconv.Atoi(retriesRaw); err != nil || control.Retries < 0 || control.Retries > maxRetries {
+			return nil, fmt.Errorf("invalid retries [%s]: expect 0 to %d", retriesRaw, maxRetries)
+		}
+	}
+	if backoffRaw != "" {
+		if control.Backoff, err = time.ParseDuration(backoffRaw); err != nil || control.Backoff <= 0 {
+			return nil, fmt.Errorf("invalid retry_backoff [%s]: expect positive duration", backoffRaw)
+		}
+	}
+	for _, limit := range []struct {
+		name string
+		raw  string
+		size *int64
+	}{
+		{webpackParameterMaxPageSize, pageSizeRaw, &control.MaxPageSize},
+		{webpackParameterMaxAssetSize, assetSizeRaw, &control.MaxAssetSize},
+		{webpackParameterMaxTotalSize, totalSizeRaw, &control.MaxTotalSize},
+	} {
+		if limit.raw == "" {
+			continue
+		}
+		if *limit.size, err = parseByteSize(limit.raw); err != nil {
+			return nil, fmt.Errorf("invalid %s [%s]: %w", limit.name, limit.raw, err)
+		}
+	}
+	return control, nil
+}
+
+var byteUnits = map[string]int64{"": 1, "B": 1, "KB": 1 << 10, "MB": 1 << 20, "GB": 1 << 30}
+
+// parseByteSize reads a positive size in bytes with an optional KB, MB or GB (binary) unit, e.g. 512KB.
+func parseByteSize(raw string) (int64, error) {
+	raw = strings.ToUpper(strings.TrimSpace(raw))
+	i := strings.IndexFunc(raw, func(r rune) bool { return r < '0' || r > '9' })
+	if i < 0 {
+		i = len(raw)
+	}
+	unit, ok := byteUnits[strings.TrimSpace(raw[i:])]
+	n, err := strconv.ParseInt(raw[:i], 10, 64)
+	if !ok || err != nil || n <= 0 || n > (1<<62)/unit {
+		return 0, errors.New("expect positive size such as 1048576, 512KB or 10MB")
+	}
+	return n * unit, nil
+}
+
+// fetchControlParameters make webpack capture the page itself under a FetchControl.
+var fetchControlParameters = []string{
+	webpackParameterTimeout, webpackParameterMaxRedirects, webpackParameterRetries, webpackParameterRetryBackoff,
+	webpackParameterMaxPageSize, webpackParameterMaxAssetSize, webpackParameterMaxTotalSize,
+	webpackParameterBlocklist, webpackParameterBlocklistFile, webpackParameterSkipMediaTypes, webpackParameterAllowedTypes,
+}
+
+func defaultFetchControl() *FetchControl {
+	return &FetchControl{Timeout: networkFetchTimeout, MaxRedirects: defaultMaxRedirects, Backoff: defaultRetryBackoff}
+}
+
+type fetchControlKey struct{}
+
+func withFetchControl(ctx context.Context, control *FetchControl) context.Context {
+	if control == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, fetchControlKey{}, control)
+}
+
+func fetchControlFromContext(ctx context.Context) *FetchControl {
+	control, _ := ctx.Value(fetchControlKey{}).(*FetchControl)
+	return control
+}
+
+// errSkippedMediaType marks an asset left out by skip_media_types once its Content-Type is known.
+var errSkippedMediaType = errors.New("media type skipped")
+
+// sizeError fails a response larger than its limit, it is not retried.
+type sizeError int64
+
+func (e sizeError) Error() string {
+	return fmt.Sprintf("response exceeds size limit of %d bytes", int64(e))
+}
+
+// errTotalSize leaves out a resource that would grow the capture beyond max_total_size.
+var errTotalSize = errors.New("capture exceeds max_total_size")
+
+type statusError int
+
+func (e statusError) Error() string {
+	return fmt.Sprintf("status code is %d", int(e))
+}
+
+// capture fetches the resources of one page under a FetchControl and records the attempts of each.
+type capture struct {
+	control *FetchControl
+	client  *http.Client
+	headers map[string]string
+	cache   *AssetCache
+	// scoped resolves the credentials, host headers and cookies of each request URL, so they
+	// never reach asset hosts they were not configured for
+	scoped func(ctx context.Context, rawURL string) []Option
+
+	mu       sync.Mutex
+	attempts []ResourceAttempts
+	blocked  []string
+	total    int64
+	cacheUse AssetCacheUse
+}
+
+// AssetCacheUse counts the assets of a capture reused from and added to the asset cache.
+type AssetCacheUse struct {
+	Hits   int `json:"hits"`
+	Stored int `json:"stored"`
+}
+
+// newCapture builds the client of a capture, through the network policy when one is configured.
+// options apply to every request; the options scoped to a host are resolved per request.
+func (w *WebpackPlugin) newCapture(control *FetchControl, pageURL string, options []Option) *capture {
+	opt := packer.Option{URL: pageURL, Headers: make(map[string]string)}
+	for _, option := range options {
+		option(&opt)
+	}
+	headers := map[string]string{
+		"Accept":     "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8",
+		"User-Agent": "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.6 Safari/605.1.15",
+		"Referer":    pageURL,
+	}
+	for k, v := range opt.Headers {
+		headers[k] = v
+	}
+
+	var client *http.Client
+	if w.network != nil {
+		client = w.network.Client(control.Timeout)
+	} else {
+		dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+		if !enablePrivateNet {
+			dialer.Control = ssrf.New().Safe
+		}
+		client = &http.Client{
+			Timeout:   control.Timeout,
+			Transport: &http.Transport{Proxy: http.ProxyFromEnvironment, DialContext: dialer.DialContext, ForceAttemptHTTP2: true},
+		}
+	}
+	checkRedirect := client.CheckRedirect
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) > control.MaxRedirects {
+			return fmt.Errorf("stopped after %d redirects", control.MaxRedirects)
+		}
+		if checkRedirect != nil {
+			return checkRedirect(req, via)
+		}
+		return nil
+	}
+	return &capture{control: control, client: client, headers: headers, cache: w.assetCache, scoped: w.requestOptions}
+}
+
+// fetch requests rawURL until it succeeds, fails permanently or runs out of retries.
+// It returns the body and its media type parameters as sent by the server.
+func (c *capture) fetch(ctx context.Context, rawURL, kind string) ([]byte, string, error) {
+	var (
+		data        []byte
+		contentType string
+		err         error
+		attempt     int
+	)
+	for attempt = 1; ; attempt++ {
+		data, contentType, err = c.fetchOnce(ctx, rawURL, kind)
+		if err == nil {
+			err = c.reserve(int64(len(data)))
+			break
+		}
+		if attempt > c.control.Retries || !isRetryable(err) {
+			break
+		}
+		timer := time.NewTimer(c.delay(attempt - 1))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			err = ctx.Err()
+		case <-timer.C:
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	record := ResourceAttempts{URL: rawURL, Kind: kind, Attempts: attempt}
+	if err != nil {
+		record.Error = err.Error()
+	}
+	c.mu.Lock()
+	c.attempts = append(c.attempts, record)
+	c.mu.Unlock()
+	if err != nil {
+		return nil, "", fmt.Errorf("fetch %s failed after %d attempts: %w", rawURL, attempt, err)
+	}
+	return data, contentType, nil
+}
+
+func (c *capture) fetchOnce(ctx context.Context, rawURL, kind string) ([]byte, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	for k, v := range c.headers {
+		req.Header.Set(k, v)
+	}
+	// assets may be on other hosts than the page
+	scoped := packer.Option{URL: rawURL}
+	for _, option := range c.scoped(ctx, rawURL) {
+		option(&scoped)
+	}
+	for k, v := range scoped.Headers {
+		req.Header.Set(k, v)
+	}
+	var cached *cachedAsset
+	if kind != resourcePage && c.cache != nil {
+		if cached = c.cache.load(rawURL); cached != nil {
+			cached.setValidators(req)
+		}
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	if kind == resourcePage {
+		recordPageResponse(ctx, resp)
+	}
+
+	limit := c.control.MaxPageSize
+	if kind != resourcePage {
+		limit = c.control.MaxAssetSize
+	}
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		if !c.control.Filter.Allows(rawURL, cached.ContentType) {
+			return nil, "", errSkippedMediaType
+		}
+		if limit > 0 && int64(len(cached.Data)) > limit {
+			return nil, "", sizeError(limit)
+		}
+		c.mu.Lock()
+		c.cacheUse.Hits++
+		c.mu.Unlock()
+		return cached.Data, cached.ContentType, nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+		return nil, "", statusError(resp.StatusCode)
+	}
+	contentType := resp.Header.Get("Content-Type")
+	if kind != resourcePage && !c.control.Filter.Allows(rawURL, contentType) {
+		return nil, "", errSkippedMediaType
+	}
+	data, err := readLimited(resp, limit)
+	if err != nil {
+		return nil, "", err
+	}
+	if kind != resourcePage && c.cache != nil {
+		stored, err := c.cache.store(rawURL, resp.Header, data)
+		if err != nil {
+			logger.FromContext(ctx).Debugw("cache asset failed", "url", rawURL, "err", err)
+		}
+		if stored {
+			c.mu.Lock()
+			c.cacheUse.Stored++
+			c.mu.Unlock()
+		}
+	}
+	return data, contentType, nil
+}
+
+// readLimited reads the body, failing with a sizeError when it is larger than a positive limit.
+func readLimited(resp *http.Response, limit int64) ([]byte, error) {
+	if limit <= 0 {
+		return io.ReadAll(resp.Body)
+	}
+	if resp.ContentLength > limit {
+		return nil, sizeError(limit)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, sizeError(limit)
+	}
+	return data, nil
+}
+
+// reserve counts the downloaded bytes against max_total_size.
+func (c *capture) reserve(size int64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.control.MaxTotalSize > 0 && c.total+size > c.control.MaxTotalSize {
+		return errTotalSize
+	}
+	c.total += size
+	return nil
+}
+
+// delay doubles the backoff for every retry, the upper half is randomized so parallel retries spread out.
+func (c *capture) delay(retry int) time.Duration {
+	d := c.control.Backoff << retry
+	if d <= 0 || d > maxRetryBackoff {
+		d = maxRetryBackoff
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// isRetryable reports whether a request may succeed when repeated: server errors, rate limiting,
+// timeouts and dropped connections.
+func isRetryable(err error) bool {
+	var status statusError
+	if errors.As(err, &status) {
+		return status >= 500 || status == http.StatusRequestTimeout || status == http.StatusTooManyRequests
+	}
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// pack saves the page as tgtFileType. Assets of webarchive and mhtml output are fetched in parallel,
+// an asset that still fails after its retries is left out of the archive.
+func (c *capture) pack(ctx context.Context, filename, urlInfo, tgtFileType, outputDir string, clutterFree bool) (string, error) {
+	log := logger.FromContext(ctx)
+	if err := WaitFetch(ctx, urlInfo); err != nil {
+		return "", err
+	}
+	data, contentType, err := c.fetch(ctx, urlInfo, resourcePage)
+	if err != nil {
+		log.Warnw("fetch page failed", "link", urlInfo, "err", err)
+		return "", fmt.Errorf("pack to web failed: %w", err)
+	}
+	if decoded, err := decodePage(data, contentType); err == nil {
+		data = decoded
+	}
+	if c.control.Filter != nil {
+		base, err := url.Parse(urlInfo)
+		if err != nil {
+			return "", err
+		}
+		filtered, blocked, err := c.control.Filter.filterPage(data, base)
+		if err != nil {
+			return "", fmt.Errorf("pack to web failed: %w", err)
+		}
+		data = filtered
+		c.block(blocked...)
+	}
+
+	switch tgtFileType {
+	case "html", "markdown":
+		return writePage(ctx, data, filename, urlInfo, tgtFileType, outputDir, clutterFree)
+	case "webarchive", "mhtml":
+	default:
+		return "", fmt.Errorf("unsupported file type %s", tgtFileType)
+	}
+
+	page := string(data)
+	if clutterFree {
+		page, err = packer.NewHtmlPacker().ReadContent(ctx, packer.Option{
+			URL:         urlInfo,
+			Reader:      io.NopCloser(bytes.NewReader(data)),
+			ClutterFree: true,
+		})
+		if err != nil {
+			return "", fmt.Errorf("pack to web failed: %w", err)
+		}
+	}
+	archive, err := c.archive(ctx, urlInfo, page)
+	if err != nil {
+		return "", err
+	}
+
+	filePath := path.Join(outputDir, filename+"."+tgtFileType)
+	output, err := os.OpenFile(filePath, os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0644)
+	if err != nil {
+		return "", fmt.Errorf("open output file failed: %w", err)
+	}
+	defer output.Close()
+	if tgtFileType == "mhtml" {
+		err = writeMHTML(output, archive, time.Now())
+	} else {
+		err = plist.NewBinaryEncoder(output).Encode(archive)
+	}
+	if err != nil {
+		return "", fmt.Errorf("write %s failed: %w", tgtFileType, err)
+	}
+	return filePath, nil
+}
+
+// archive downloads the images, scripts, stylesheets and icons of the page.
+func (c *capture) archive(ctx context.Context, pageURL, page string) (*packer.WebArchive, error) {
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return nil, err
+	}
+	refs, err := assetRefs(page, base)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		resources = make([]*packer.WebResourceItem, len(refs))
+		sem       = make(chan struct{}, resourceConcurrency)
+		wg        sync.WaitGroup
+	)
+	for i, ref := range refs {
+		if ref.kind == "media" || !(strings.HasPrefix(ref.url, "http://") || strings.HasPrefix(ref.url, "https://")) {
+			continue
+		}
+		if c.control.Filter.Blocked(ref.url) {
+			c.block(ref.url)
+			continue
+		}
+		wg.Add(1)
+		go func(i int, ref assetRef) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			data, contentType, err := c.fetch(ctx, ref.url, ref.kind)
+			if errors.Is(err, errSkippedMediaType) {
+				c.block(ref.url)
+				return
+			}
+			if err != nil {
+				logger.FromContext(ctx).Debugw("skip asset", "url", ref.url, "err", err)
+				return
+			}
+			mediaType, _, _ := mime.ParseMediaType(contentType)
+			resources[i] = &packer.WebResourceItem{WebResourceURL: ref.url, WebResourceMIMEType: mediaType, WebResourceData: data}
+		}(i, ref)
+	}
+	wg.Wait()
+
+	archive := &packer.WebArchive{WebMainResource: packer.WebResourceItem{
+		WebResourceURL:              pageURL,
+		WebResourceMIMEType:         packer.MIMEHTML,
+		WebResourceData:             []byte(page),
+		WebResourceTextEncodingName: "UTF-8",
+	}}
+	for _, res := range resources {
+		if res != nil {
+			archive.WebSubresources = append(archive.WebSubresources, *res)
+		}
+	}
+	return archive, nil
+}
+
+func (c *capture) assetCacheUse() AssetCacheUse {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cacheUse
+}
+
+// resourceAttempts returns the requests made per resource, the page first.
+func (c *capture) resourceAttempts() []ResourceAttempts {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]ResourceAttempts(nil), c.attempts...)
+}
+
+func (c *capture) block(urls ...string) {
+	c.mu.Lock()
+	c.blocked = append(c.blocked, urls...)
+	c.mu.Unlock()
+}
+
+// blockedResources returns the assets left out by the blocklist or skip_media_types.
+func (c *capture) blockedResources() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.blocked...)
+}
+
+func decodePage(data []byte, contentType string) ([]byte, error) {
+	reader, err := charset.NewReader(bytes.NewReader(data), contentType)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(reader)
+}
@@ -0,0 +1,321 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package web
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/types"
+)
+
+func TestParseFetchControl(t *testing.T) {
+	control, err := parseFetchControl(&api.Request{Parameter: map[string]any{"retries": "3"}})
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	want := FetchControl{Timeout: networkFetchTimeout, MaxRedirects: defaultMaxRedirects, Retries: 3, Backoff: defaultRetryBackoff}
+	if *control != want {
+		t.Errorf("control = %+v, want %+v", *control, want)
+	}
+
+	for _, params := range []map[string]any{
+		{"timeout": "10"},
+		{"timeout": "-1s"},
+		{"max_redirects": "-1"},
+		{"retries": "11"},
+		{"retry_backoff": "0s"},
+		{"max_page_size": "10TB"},
+		{"max_total_size": "0"},
+	} {
+		if _, err = parseFetchControl(&api.Request{Parameter: params}); err == nil {
+			t.Errorf("expected error for %v", params)
+		}
+	}
+	if control, err = parseFetchControl(&api.Request{}); control != nil || err != nil {
+		t.Errorf("expected no control, got %v, %v", control, err)
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{statusError(503), true},
+		{statusError(429), true},
+		{statusError(404), false},
+		{fmt.Errorf("wrapped: %w", statusError(500)), true},
+		{context.Canceled, false},
+		{context.DeadlineExceeded, true},
+		{fmt.Errorf("stopped after 2 redirects"), false},
+	}
+	for _, tt := range tests {
+		if got := isRetryable(tt.err); got != tt.want {
+			t.Errorf("isRetryable(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func newCaptureServer() (*httptest.Server, map[string]int, *sync.Mutex) {
+	var (
+		mux   sync.Mutex
+		calls = map[string]int{}
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mux.Lock()
+		calls[r.URL.Path]++
+		n := calls[r.URL.Path]
+		mux.Unlock()
+		switch r.URL.Path {
+		case "/page":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			_, _ = w.Write([]byte(`<html><head><title>Capture</title><link rel="stylesheet" href="/style.css"></head><body>
+<p>content</p><img src="/ok.png"><img src="/flaky.png"><img src="/slow.png"><img src="/missing.png"></body></html>`))
+		case "/style.css":
+			w.Header().Set("Content-Type", "text/css")
+			_, _ = w.Write([]byte("p { color: red; }"))
+		case "/ok.png":
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = w.Write([]byte("ok"))
+		case "/flaky.png":
+			if n == 1 {
+				http.Error(w, "busy", http.StatusServiceUnavailable)
+				return
+			}
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = w.Write([]byte("flaky"))
+		case "/slow.png":
+			select {
+			case <-time.After(2 * time.Second):
+			case <-r.Context().Done():
+			}
+		case "/moved":
+			http.Redirect(w, r, "/page", http.StatusMovedPermanently)
+		case "/loop":
+			http.Redirect(w, r, "/loop", http.StatusFound)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	return server, calls, &mux
+}
+
+func TestWebpackPlugin_FetchControl(t *testing.T) {
+	server, calls, mux := newCaptureServer()
+	defer server.Close()
+
+	origin := enablePrivateNet
+	enablePrivateNet = true
+	defer func() { enablePrivateNet = origin }()
+
+	p := NewWebpackPlugin(types.PluginCall{
+		WorkingPath: t.TempDir(),
+		Params:      map[string]string{"file_type": "webarchive", "clutter_free": "false"},
+	}).(*WebpackPlugin)
+
+	started := time.Now()
+	resp, err := p.Run(context.Background(), &api.Request{Parameter: map[string]any{
+		"file_name":     "capture",
+		"url":           server.URL + "/page",
+		"timeout":       "200ms",
+		"retries":       "1",
+		"retry_backoff": "10ms",
+	}})
+	if err != nil || !resp.IsSucceed {
+		t.Fatalf("Run failed: %v %v", err, resp)
+	}
+	if elapsed := time.Since(started); elapsed > 2*time.Second {
+		t.Errorf("slow asset stalled the capture for %s", elapsed)
+	}
+
+	attempts := map[string]ResourceAttempts{}
+	for _, a := range resp.Results["resources"].([]ResourceAttempts) {
+		attempts[a.URL[len(server.URL):]] = a
+	}
+	expect := map[string]struct {
+		kind     string
+		attempts int
+		failed   bool
+	}{
+		"/page":        {resourcePage, 1, false},
+		"/style.css":   {"stylesheet", 1, false},
+		"/ok.png":      {"image", 1, false},
+		"/flaky.png":   {"image", 2, false},
+		"/slow.png":    {"image", 2, true},
+		"/missing.png": {"image", 1, true},
+	}
+	for path, want := range expect {
+		got, ok := attempts[path]
+		if !ok || got.Kind != want.kind || got.Attempts != want.attempts || (got.Error != "") != want.failed {
+			t.Errorf("%s: got %+v, want %+v", path, got, want)
+		}
+	}
+
+	_, resources, err := readArchive(resp.Results["file_path"].(string))
+	if err != nil {
+		t.Fatalf("read archive failed: %v", err)
+	}
+	for _, path := range []string{"/page", "/style.css", "/ok.png", "/flaky.png"} {
+		if _, ok := resources[server.URL+path]; !ok {
+			t.Errorf("expected %s in archive", path)
+		}
+	}
+	if _, ok := resources[server.URL+"/slow.png"]; ok {
+		t.Errorf("failed asset should not be archived")
+	}
+
+	mux.Lock()
+	if calls["/missing.png"] != 1 {
+		t.Errorf("client errors should not be retried, got %d calls", calls["/missing.png"])
+	}
+	mux.Unlock()
+}
+
+func TestWebpackPlugin_FetchControl_MaxRedirects(t *testing.T) {
+	server, _, _ := newCaptureServer()
+	defer server.Close()
+
+	origin := enablePrivateNet
+	enablePrivateNet = true
+	defer func() { enablePrivateNet = origin }()
+
+	p := NewWebpackPlugin(types.PluginCall{
+		WorkingPath: t.TempDir(),
+		Params:      map[string]string{"file_type": "html", "clutter_free": "false"},
+	}).(*WebpackPlugin)
+
+	resp, err := p.Run(context.Background(), &api.Request{Parameter: map[string]any{
+		"file_name":     "loop",
+		"url":           server.URL + "/loop",
+		"max_redirects": "2",
+	}})
+	if err == nil || resp.IsSucceed {
+		t.Fatalf("expected redirect limit failure, got %v", resp)
+	}
+
+	resp, err = p.Run(context.Background(), &api.Request{Parameter: map[string]any{
+		"file_name": "page",
+		"url":       server.URL + "/page",
+		"render":    RenderBrowser,
+		"retries":   "1",
+	}})
+	if err == nil {
+		t.Errorf("expected fetch controls to be rejected with render browser")
+	}
+}
+
+func TestParseByteSize(t *testing.T) {
+	tests := map[string]int64{"1024": 1024, "512KB": 512 << 10, "10 mb": 10 << 20, "1GB": 1 << 30, "2B": 2}
+	for raw, want := range tests {
+		if got, err := parseByteSize(raw); err != nil || got != want {
+			t.Errorf("parseByteSize(%s) = %d, %v, want %d", raw, got, err, want)
+		}
+	}
+	for _, raw := range []string{"", "0", "-1KB", "1TB", "MB", "1.5MB"} {
+		if _, err := parseByteSize(raw); err == nil {
+			t.Errorf("expected error for %q", raw)
+		}
+	}
+}
+
+func TestWebpackPlugin_SizeLimits(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/page":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			_, _ = w.Write([]byte(`<html><head><title>Limits</title><link rel="stylesheet" href="/style.css"><script src="/app.js"></script></head>
+<body><p>content</p><img src="/small.png"><img src="/huge.png"><img src="/other.png"></body></html>`))
+		case "/style.css":
+			w.Header().Set("Content-Type", "text/css")
+			_, _ = w.Write([]byte("p { color: red; }"))
+		case "/app.js":
+			w.Header().Set("Content-Type", "text/javascript")
+			_, _ = w.Write([]byte("console.log('app')"))
+		case "/small.png", "/other.png":
+			// with the page only one of them fits into max_total_size, whichever is fetched first
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = w.Write(make([]byte, 450))
+		case "/huge.png":
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = w.Write(make([]byte, 4096))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	origin := enablePrivateNet
+	enablePrivateNet = true
+	defer func() { enablePrivateNet = origin }()
+
+	p := NewWebpackPlugin(types.PluginCall{
+		WorkingPath: t.TempDir(),
+		Params:      map[string]string{"file_type": "webarchive", "clutter_free": "false"},
+	}).(*WebpackPlugin)
+
+	resp, err := p.Run(context.Background(), &api.Request{Parameter: map[string]any{
+		"file_name":           "limits",
+		"url":                 server.URL + "/page",
+		"max_asset_size":      "1KB",
+		"max_total_size":      "1KB",
+		"allowed_asset_types": "image/*,text/css",
+	}})
+	if err != nil || !resp.IsSucceed {
+		t.Fatalf("Run failed: %v %v", err, resp)
+	}
+	_, resources, err := readArchive(resp.Results["file_path"].(string))
+	if err != nil {
+		t.Fatalf("read archive failed: %v", err)
+	}
+	if _, ok := resources[server.URL+"/huge.png"]; ok {
+		t.Errorf("asset over max_asset_size should not be archived")
+	}
+	if _, ok := resources[server.URL+"/app.js"]; ok {
+		t.Errorf("asset outside allowed_asset_types should not be archived")
+	}
+	if _, ok := resources[server.URL+"/style.css"]; !ok {
+		t.Errorf("expected stylesheet in archive")
+	}
+	images := 0
+	for _, path := range []string{"/small.png", "/other.png"} {
+		if _, ok := resources[server.URL+path]; ok {
+			images++
+		}
+	}
+	if images != 1 {
+		t.Errorf("expected one of the small images to fit into max_total_size, got %d", images)
+	}
+	if blocked := resp.Results["blocked"].([]string); len(blocked) != 1 || blocked[0] != server.URL+"/app.js" {
+		t.Errorf("blocked = %v, want app.js", blocked)
+	}
+
+	resp, err = p.Run(context.Background(), &api.Request{Parameter: map[string]any{
+		"file_name":     "too_big",
+		"url":           server.URL + "/page",
+		"max_page_size": "100",
+	}})
+	if err == nil || resp.IsSucceed {
+		t.Errorf("expected page over max_page_size to fail, got %v", resp)
+	}
+}
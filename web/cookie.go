@@ -0,0 +1,278 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package web
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"code.dny.dev/ssrf"
+	"github.com/basenana/plugin/api"
+	"github.com/hyponet/webpage-packer/packer"
+)
+
+const (
+	webpackParameterCookies    = "cookies"
+	webpackParameterCookieFile = "cookie_file"
+	webpackParameterLoginURL   = "login_url"
+	webpackParameterLoginForm  = "login_form"
+
+	loginTimeout = 30 * time.Second
+)
+
+// fileCookie is a cookie of a cookie file with the URL it is stored for in the jar.
+type fileCookie struct {
+	url    *url.URL
+	cookie *http.Cookie
+}
+
+// parseNetscapeCookies reads a cookies.txt file as exported by browsers and curl.
+// Expired cookies are dropped.
+func parseNetscapeCookies(data []byte) ([]fileCookie, error) {
+	var (
+		cookies []fileCookie
+		now     = time.Now()
+		scanner = bufio.NewScanner(bytes.NewReader(data))
+		lineNo  int
+	)
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		httpOnly := strings.HasPrefix(line, "#HttpOnly_")
+		if httpOnly {
+			line = strings.TrimPrefix(line, "#HttpOnly_")
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+		if len(fields) == 6 {
+			// cookies with an empty value lose their last field in some exporters
+			fields = append(fields, "")
+		}
+		if len(fields) != 7 {
+			return nil, fmt.Errorf("line %d: expect 7 tab separated fields, got %d", lineNo, len(fields))
+		}
+		expires, err := strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid expiry [%s]", lineNo, fields[4])
+		}
+
+		var (
+			domain = strings.TrimPrefix(fields[0], ".")
+			secure = strings.EqualFold(fields[3], "TRUE")
+			fc     = fileCookie{
+				url:    &url.URL{Scheme: "http", Host: domain, Path: fields[2]},
+				cookie: &http.Cookie{Name: fields[5], Value: fields[6], Path: fields[2], Secure: secure, HttpOnly: httpOnly},
+			}
+		)
+		if secure {
+			fc.url.Scheme = "https"
+		}
+		// without the subdomain flag the cookie stays host-only
+		if strings.EqualFold(fields[1], "TRUE") {
+			fc.cookie.Domain = domain
+		}
+		if expires > 0 {
+			fc.cookie.Expires = time.Unix(expires, 0)
+			if fc.cookie.Expires.Before(now) {
+				continue
+			}
+		}
+		cookies = append(cookies, fc)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return cookies, nil
+}
+
+// loginRequest is the form posted to login_url before fetching.
+type loginRequest struct {
+	URL  string
+	Form url.Values
+}
+
+func parseLoginRequest(request *api.Request) (*loginRequest, error) {
+	var (
+		loginURL  = api.GetStringParameter(webpackParameterLoginURL, request, "")
+		loginForm = api.GetStringParameter(webpackParameterLoginForm, request, "")
+	)
+	if loginURL == "" {
+		if loginForm != "" {
+			return nil, fmt.Errorf("%s requires %s", webpackParameterLoginForm, webpackParameterLoginURL)
+		}
+		return nil, nil
+	}
+	if u, err := url.Parse(loginURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid %s [%s]", webpackParameterLoginURL, loginURL)
+	}
+
+	form := map[string]string{}
+	if loginForm != "" {
+		if err := json.Unmarshal([]byte(loginForm), &form); err != nil {
+			return nil, fmt.Errorf("parse %s failed: expect JSON object of strings: %w", webpackParameterLoginForm, err)
+		}
+	}
+	login := &loginRequest{URL: loginURL, Form: url.Values{}}
+	for k, v := range form {
+		login.Form.Set(k, v)
+	}
+	return login, nil
+}
+
+// cookieJar loads cookies and cookie_file into a jar, raw cookies apply to the host of target only.
+// It returns nil when neither is set and no login needs a jar.
+func (w *WebpackPlugin) cookieJar(request *api.Request, target string, login bool) (http.CookieJar, error) {
+	var (
+		rawCookies = api.GetStringParameter(webpackParameterCookies, request, "")
+		cookieFile = api.GetStringParameter(webpackParameterCookieFile, request, "")
+	)
+	if rawCookies == "" && cookieFile == "" && !login {
+		return nil, nil
+	}
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+	if rawCookies != "" {
+		targetURL, err := url.Parse(target)
+		if err != nil || targetURL.Host == "" {
+			return nil, fmt.Errorf("invalid url [%s] for cookies", target)
+		}
+		cookies, err := http.ParseCookie(rawCookies)
+		if err != nil {
+			return nil, fmt.Errorf("parse cookies failed: %w", err)
+		}
+		jar.SetCookies(&url.URL{Scheme: targetURL.Scheme, Host: targetURL.Host, Path: "/"}, cookies)
+	}
+	if cookieFile != "" {
+		data, err := w.fileRoot.Read(cookieFile)
+		if err != nil {
+			return nil, fmt.Errorf("read cookie file failed: %w", err)
+		}
+		cookies, err := parseNetscapeCookies(data)
+		if err != nil {
+			return nil, fmt.Errorf("parse cookie file %s failed: %w", cookieFile, err)
+		}
+		for _, fc := range cookies {
+			jar.SetCookies(fc.url, []*http.Cookie{fc.cookie})
+		}
+	}
+	return jar, nil
+}
+
+// login posts the form and keeps the session cookies set by the response and its redirects in the jar.
+// The cookies already in the jar are sent with it.
+func (w *WebpackPlugin) login(ctx context.Context, jar http.CookieJar, login *loginRequest) (int, error) {
+	var client *http.Client
+	if w.network != nil {
+		if err := w.network.CheckURL(ctx, login.URL); err != nil {
+			return 0, err
+		}
+		client = w.network.Client(loginTimeout)
+	} else {
+		dialer := &net.Dialer{Timeout: loginTimeout}
+		if !enablePrivateNet {
+			dialer.Control = ssrf.New().Safe
+		}
+		client = &http.Client{
+			Timeout:   loginTimeout,
+			Transport: &http.Transport{Proxy: http.ProxyFromEnvironment, DialContext: dialer.DialContext},
+		}
+	}
+	client.Jar = jar
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, login.URL, strings.NewReader(login.Form.Encode()))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if _, cred, ok := w.credentials.Match(login.URL); ok {
+		opt := packer.Option{Headers: make(map[string]string)}
+		cred.Option()(&opt)
+		for k, v := range opt.Headers {
+			req.Header.Set(k, v)
+		}
+	}
+	for k, v := range overrideHeaders(ctx, login.URL) {
+		req.Header.Set(k, v)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode >= 400 {
+		return resp.StatusCode, fmt.Errorf("status code is %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+type cookieJarKey struct{}
+
+func withCookieJar(ctx context.Context, jar http.CookieJar) context.Context {
+	if jar == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, cookieJarKey{}, jar)
+}
+
+// cookieOption adds the cookies of the run for rawURL to the Cookie header, after any domain credential cookie.
+func cookieOption(ctx context.Context, rawURL string) (Option, bool) {
+	jar, _ := ctx.Value(cookieJarKey{}).(http.CookieJar)
+	if jar == nil {
+		return nil, false
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, false
+	}
+	cookies := jar.Cookies(u)
+	if len(cookies) == 0 {
+		return nil, false
+	}
+	pairs := make([]string, len(cookies))
+	for i, c := range cookies {
+		pairs[i] = c.String()
+	}
+	header := strings.Join(pairs, "; ")
+	return func(option *packer.Option) {
+		if option.Headers == nil {
+			option.Headers = make(map[string]string)
+		}
+		if existing := option.Headers["Cookie"]; existing != "" {
+			option.Headers["Cookie"] = existing + "; " + header
+			return
+		}
+		option.Headers["Cookie"] = header
+	}, true
+}
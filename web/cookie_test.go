@@ -0,0 +1,207 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package web
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/types"
+)
+
+const testCookieFile = `# Netscape HTTP Cookie File
+.example.com	TRUE	/	TRUE	0	sid	abc
+#HttpOnly_www.example.com	FALSE	/app	FALSE	4102444800	token	t1
+example.com	FALSE	/	FALSE	1000	expired	x
+example.com	FALSE	/	FALSE	0	empty
+`
+
+func TestParseNetscapeCookies(t *testing.T) {
+	cookies, err := parseNetscapeCookies([]byte(testCookieFile))
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if len(cookies) != 3 {
+		t.Fatalf("expected 3 cookies, got %d", len(cookies))
+	}
+
+	sid := cookies[0]
+	if sid.url.String() != "https://example.com/" || sid.cookie.Domain != "example.com" || !sid.cookie.Secure {
+		t.Errorf("unexpected domain cookie %s %+v", sid.url, sid.cookie)
+	}
+	token := cookies[1]
+	if token.url.String() != "http://www.example.com/app" || token.cookie.Domain != "" || !token.cookie.HttpOnly || token.cookie.Expires.IsZero() {
+		t.Errorf("unexpected host-only cookie %s %+v", token.url, token.cookie)
+	}
+	if cookies[2].cookie.Name != "empty" || cookies[2].cookie.Value != "" {
+		t.Errorf("unexpected empty cookie %+v", cookies[2].cookie)
+	}
+
+	if _, err = parseNetscapeCookies([]byte("example.com\tFALSE\t/\n")); err == nil {
+		t.Error("expected error for short line")
+	}
+	if _, err = parseNetscapeCookies([]byte("example.com\tFALSE\t/\tFALSE\tsoon\ta\tb\n")); err == nil {
+		t.Error("expected error for invalid expiry")
+	}
+}
+
+func TestParseLoginRequest(t *testing.T) {
+	login, err := parseLoginRequest(&api.Request{Parameter: map[string]any{
+		"login_url":  "https://example.com/login",
+		"login_form": `{"username": "me", "password": "p&ss"}`,
+	}})
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if login.Form.Encode() != "password=p%26ss&username=me" {
+		t.Errorf("unexpected form %s", login.Form.Encode())
+	}
+
+	for _, params := range []map[string]any{
+		{"login_form": `{"username": "me"}`},
+		{"login_url": "ftp://example.com/login"},
+		{"login_url": "https://example.com/login", "login_form": `["me"]`},
+	} {
+		if _, err = parseLoginRequest(&api.Request{Parameter: params}); err == nil {
+			t.Errorf("expected error for %v", params)
+		}
+	}
+	if login, err = parseLoginRequest(&api.Request{Parameter: map[string]any{}}); login != nil || err != nil {
+		t.Errorf("expected no login, got %v, %v", login, err)
+	}
+}
+
+func TestWebpackPlugin_CookiesAndLogin(t *testing.T) {
+	var (
+		mux         sync.Mutex
+		pageCookies []string
+		loginForm   string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			_ = r.ParseForm()
+			mux.Lock()
+			loginForm = r.PostForm.Encode()
+			mux.Unlock()
+			if r.PostForm.Get("password") != "secret" {
+				http.Error(w, "denied", http.StatusForbidden)
+				return
+			}
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "s1", Path: "/"})
+			http.Redirect(w, r, "/home", http.StatusFound)
+		case "/home":
+			_, _ = w.Write([]byte("welcome"))
+		default:
+			mux.Lock()
+			pageCookies = append(pageCookies, r.Header.Get("Cookie"))
+			mux.Unlock()
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = w.Write([]byte("<html><head><title>Members</title></head><body><p>members only</p></body></html>"))
+		}
+	}))
+	defer server.Close()
+
+	origin := enablePrivateNet
+	enablePrivateNet = true
+	defer func() { enablePrivateNet = origin }()
+
+	workdir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(workdir, "cookies.txt"), []byte("127.0.0.1\tFALSE\t/\tFALSE\t0\tprefs\tp1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	p := NewWebpackPlugin(types.PluginCall{
+		WorkingPath: workdir,
+		Params:      map[string]string{"file_type": "html", "clutter_free": "false"},
+		Config:      map[string]string{webpackConfigCredentials: `{"127.0.0.1": {"cookie": "cred=c1"}}`},
+	}).(*WebpackPlugin)
+
+	resp, err := p.Run(context.Background(), &api.Request{Parameter: map[string]any{
+		"file_name":   "members",
+		"url":         server.URL + "/article",
+		"cookies":     "theme=dark",
+		"cookie_file": "cookies.txt",
+		"login_url":   server.URL + "/login",
+		"login_form":  `{"username": "me", "password": "secret"}`,
+	}})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if !resp.IsSucceed || resp.Results["login_status"] != http.StatusOK {
+		t.Fatalf("unexpected response %s %v", resp.Message, resp.Results)
+	}
+
+	mux.Lock()
+	if loginForm != "password=secret&username=me" {
+		t.Errorf("unexpected login form %s", loginForm)
+	}
+	if len(pageCookies) == 0 || !strings.HasPrefix(pageCookies[0], "cred=c1; ") {
+		t.Fatalf("expected credential cookie first, got %v", pageCookies)
+	}
+	for _, want := range []string{"theme=dark", "prefs=p1", "session=s1"} {
+		if !strings.Contains(pageCookies[0], want) {
+			t.Errorf("expected cookie %s in %s", want, pageCookies[0])
+		}
+	}
+	mux.Unlock()
+
+	resp, err = p.Run(context.Background(), &api.Request{Parameter: map[string]any{
+		"file_name":  "members",
+		"url":        server.URL + "/article",
+		"login_url":  server.URL + "/login",
+		"login_form": `{"username": "me", "password": "wrong"}`,
+	}})
+	if err == nil || resp == nil || resp.IsSucceed {
+		t.Errorf("expected failed login, got %v %v", resp, err)
+	}
+}
+
+func TestWebpackPlugin_CookiesNotSentToAssetHosts(t *testing.T) {
+	servers := newAssetHostServers(t)
+	workdir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(workdir, "cookies.txt"), []byte("127.0.0.1\tFALSE\t/\tFALSE\t0\tsid\tf1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	p := NewWebpackPlugin(types.PluginCall{
+		WorkingPath: workdir,
+		Params:      map[string]string{"file_type": "webarchive", "clutter_free": "false"},
+	}).(*WebpackPlugin)
+	resp, err := p.Run(context.Background(), &api.Request{Parameter: map[string]any{
+		"file_name":   "assets",
+		"url":         servers.page.URL + "/page",
+		"cookies":     "session=abc",
+		"cookie_file": "cookies.txt",
+	}})
+	if err != nil || !resp.IsSucceed {
+		t.Fatalf("Run failed: %v %v", err, resp)
+	}
+
+	page, asset := servers.headers(t)
+	if cookie := page.Get("Cookie"); !strings.Contains(cookie, "session=abc") || !strings.Contains(cookie, "sid=f1") {
+		t.Errorf("expected cookies on the page, got %q", cookie)
+	}
+	if cookie := asset.Get("Cookie"); cookie != "" {
+		t.Errorf("cookies sent to the asset host: %q", cookie)
+	}
+}
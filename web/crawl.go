@@ -0,0 +1,243 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/logger"
+	"github.com/hyponet/webpage-packer/packer"
+)
+
+const (
+	webpackParameterDepth          = "depth"
+	webpackParameterSameOriginOnly = "same_origin_only"
+	webpackParameterMaxPages       = "max_pages"
+
+	maxCrawlDepth       = 5
+	defaultCrawlPages   = 20
+	maxCrawlPages       = 1000
+	crawlIndexExtension = ".index.json"
+)
+
+// skippedLinkExtensions are linked files that are not web pages.
+var skippedLinkExtensions = map[string]bool{
+	".pdf": true, ".zip": true, ".gz": true, ".tar": true, ".png": true, ".jpg": true, ".jpeg": true,
+	".gif": true, ".svg": true, ".webp": true, ".mp3": true, ".mp4": true, ".css": true, ".js": true,
+	".xml": true, ".json": true, ".exe": true, ".dmg": true,
+}
+
+// CrawlOption limits how far webpack follows in-page links from the requested URL.
+type CrawlOption struct {
+	Depth          int
+	SameOriginOnly bool
+	MaxPages       int
+}
+
+// CrawledPage is one entry of the crawl index.
+type CrawledPage struct {
+	URL      string `json:"url"`
+	Depth    int    `json:"depth"`
+	FilePath string `json:"file_path,omitempty"`
+	Size     int64  `json:"size,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+func parseCrawlOption(request *api.Request) (CrawlOption, error) {
+	opt := CrawlOption{
+		SameOriginOnly: api.GetBoolParameter(webpackParameterSameOriginOnly, request, true),
+		MaxPages:       defaultCrawlPages,
+	}
+	if raw := api.GetStringParameter(webpackParameterDepth, request, ""); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 || n > maxCrawlDepth {
+			return opt, fmt.Errorf("invalid depth [%s]: expect 0 to %d", raw, maxCrawlDepth)
+		}
+		opt.Depth = n
+	}
+	if raw := api.GetStringParameter(webpackParameterMaxPages, request, ""); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 || n > maxCrawlPages {
+			return opt, fmt.Errorf("invalid max_pages [%s]: expect 1 to %d", raw, maxCrawlPages)
+		}
+		opt.MaxPages = n
+	}
+	return opt, nil
+}
+
+type crawlTarget struct {
+	url   string
+	depth int
+}
+
+// crawl archives the pages linked from the already packed root page breadth first,
+// one file per page, and writes the index of captured URLs next to them.
+// Failed pages are recorded in the index and do not stop the crawl.
+func (w *WebpackPlugin) crawl(ctx context.Context, filename, rootURL string, root map[string]any, crawlOpt CrawlOption, options ...Option) ([]CrawledPage, string, error) {
+	start, err := url.Parse(rootURL)
+	if err != nil {
+		return nil, "", fmt.Errorf("parse url %s failed: %w", rootURL, err)
+	}
+
+	rootPage := CrawledPage{URL: rootURL}
+	rootPage.FilePath, _ = root["file_path"].(string)
+	rootPage.Size, _ = root["size"].(int64)
+
+	var (
+		pages = []CrawledPage{rootPage}
+		seen  = map[string]bool{normalizeCrawlURL(start): true}
+		queue = []crawlTarget{{url: rootURL}}
+	)
+	for len(queue) > 0 && len(pages) < crawlOpt.MaxPages {
+		if err = ctx.Err(); err != nil {
+			return nil, "", err
+		}
+		current := queue[0]
+		queue = queue[1:]
+		if current.depth >= crawlOpt.Depth {
+			continue
+		}
+
+		links, err := w.pageLinks(ctx, current.url, options...)
+		if err != nil {
+			w.logger.Warnw("read page links failed", "url", current.url, "error", err)
+			continue
+		}
+		for _, link := range links {
+			if len(pages) >= crawlOpt.MaxPages {
+				break
+			}
+			if crawlOpt.SameOriginOnly && (link.Scheme != start.Scheme || link.Host != start.Host) {
+				continue
+			}
+			key := normalizeCrawlURL(link)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			page := CrawledPage{URL: link.String(), Depth: current.depth + 1}
+			result, err := w.packFromURL(ctx, fmt.Sprintf("%s_%03d", filename, len(pages)), page.URL, w.fileType, w.clutterFree, options...)
+			if err != nil {
+				w.logger.Warnw("packing linked page failed", "url", page.URL, "error", err)
+				page.Error = err.Error()
+			} else {
+				page.FilePath, _ = result["file_path"].(string)
+				page.Size, _ = result["size"].(int64)
+				queue = append(queue, crawlTarget{url: page.URL, depth: page.Depth})
+			}
+			pages = append(pages, page)
+		}
+	}
+
+	indexPath := filepath.Join(w.fileRoot.Workdir(), filename+crawlIndexExtension)
+	data, err := json.MarshalIndent(map[string]any{"url": rootURL, "depth": crawlOpt.Depth, "pages": pages}, "", "  ")
+	if err != nil {
+		return nil, "", err
+	}
+	if err = os.WriteFile(indexPath, data, 0644); err != nil {
+		return nil, "", fmt.Errorf("write crawl index failed: %w", err)
+	}
+	return pages, indexPath, nil
+}
+
+// pageLinks fetches the page and returns its http(s) links to other web pages, in document order.
+func (w *WebpackPlugin) pageLinks(ctx context.Context, pageURL string, options ...Option) ([]*url.URL, error) {
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return nil, err
+	}
+	content, err := w.fetchRaw(ctx, pageURL, options...)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("parse page failed: %w", err)
+	}
+
+	var links []*url.URL
+	doc.Find("a[href]").Each(func(_ int, s *goquery.Selection) {
+		if rel := strings.ToLower(s.AttrOr("rel", "")); strings.Contains(rel, "nofollow") {
+			return
+		}
+		u, err := url.Parse(strings.TrimSpace(s.AttrOr("href", "")))
+		if err != nil {
+			return
+		}
+		u = base.ResolveReference(u)
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return
+		}
+		if skippedLinkExtensions[strings.ToLower(path.Ext(u.Path))] {
+			return
+		}
+		u.Fragment = ""
+		links = append(links, u)
+	})
+	return links, nil
+}
+
+// fetchRaw reads the unprocessed body of url with the credentials and cookies applied.
+func (w *WebpackPlugin) fetchRaw(ctx context.Context, rawURL string, options ...Option) (string, error) {
+	if err := WaitFetch(ctx, rawURL); err != nil {
+		return "", err
+	}
+	if control := fetchControlFromContext(ctx); control != nil && !usesBrowser(options) {
+		data, _, err := w.newCapture(control, rawURL, options).fetch(ctx, rawURL, resourcePage)
+		return string(data), err
+	}
+	options = append(options, w.requestOptions(ctx, rawURL)...)
+	if w.network != nil {
+		if err := w.network.CheckURL(ctx, rawURL); err != nil {
+			return "", err
+		}
+		if !usesBrowser(options) {
+			data, err := w.network.Fetch(ctx, rawURL, options...)
+			return string(data), err
+		}
+	}
+	opt := packer.Option{
+		URL:              rawURL,
+		Timeout:          60,
+		Headers:          make(map[string]string),
+		EnablePrivateNet: enablePrivateNet,
+	}
+	for _, option := range options {
+		option(&opt)
+	}
+	return packer.NewHtmlPacker().ReadContent(logger.IntoContext(ctx, w.logger), opt)
+}
+
+// normalizeCrawlURL identifies a page regardless of fragment, host case and a trailing slash.
+func normalizeCrawlURL(u *url.URL) string {
+	n := *u
+	n.Fragment = ""
+	n.Host = strings.ToLower(n.Host)
+	n.Path = strings.TrimSuffix(n.Path, "/")
+	return n.String()
+}
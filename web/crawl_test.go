@@ -0,0 +1,181 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/types"
+)
+
+func newCrawlSite(t *testing.T) *httptest.Server {
+	links := map[string]string{
+		"/docs/":  `<a href="/docs/a">A</a><a href="b">B</a><a href="/docs/a#install">A again</a><a href="http://other.example/x">Other</a><a href="/docs/manual.pdf">PDF</a><a href="mailto:x@example.com">Mail</a>`,
+		"/docs/a": `<a href="/docs/c">C</a><a href="/docs/">Home</a>`,
+		"/docs/b": `<a href="/docs/missing">Missing</a>`,
+		"/docs/c": `<p>leaf</p>`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			_, _ = w.Write([]byte("User-agent: *\nDisallow: /docs/b\n"))
+			return
+		}
+		body, ok := links[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = fmt.Fprintf(w, "<html><head><title>%s</title></head><body>%s</body></html>", r.URL.Path, body)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func runCrawl(t *testing.T, server *httptest.Server, params map[string]any) (string, *api.Response) {
+	t.Helper()
+	workdir := t.TempDir()
+	p := NewWebpackPlugin(types.PluginCall{
+		WorkingPath: workdir,
+		Params:      map[string]string{webpackParameterFileType: "html", webpackParameterClutterFree: "false"},
+	}).(*WebpackPlugin)
+	params[webpackParameterFileName] = "docs"
+	params[webpackParameterURL] = server.URL + "/docs/"
+	resp, err := p.Run(context.Background(), &api.Request{Parameter: params})
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	return workdir, resp
+}
+
+func TestWebpackPlugin_Crawl(t *testing.T) {
+	defer func(orig bool) { enablePrivateNet = orig }(enablePrivateNet)
+	enablePrivateNet = true
+	server := newCrawlSite(t)
+
+	workdir, resp := runCrawl(t, server, map[string]any{webpackParameterDepth: "2"})
+	if resp.Results["file_path"] != filepath.Join(workdir, "docs.html") {
+		t.Errorf("unexpected root file %v", resp.Results["file_path"])
+	}
+	if resp.Results["captured"] != 4 || resp.Results["failed"] != 1 {
+		t.Errorf("unexpected crawl summary captured=%v failed=%v", resp.Results["captured"], resp.Results["failed"])
+	}
+
+	indexPath := filepath.Join(workdir, "docs.index.json")
+	if resp.Results["index_path"] != indexPath {
+		t.Errorf("unexpected index path %v", resp.Results["index_path"])
+	}
+	data, err := os.ReadFile(indexPath)
+	if err != nil {
+		t.Fatalf("read index failed: %v", err)
+	}
+	var index struct {
+		Pages []CrawledPage `json:"pages"`
+	}
+	if err = json.Unmarshal(data, &index); err != nil {
+		t.Fatalf("parse index failed: %v", err)
+	}
+	want := []struct {
+		path  string
+		depth int
+		file  string
+	}{
+		{"/docs/", 0, "docs.html"},
+		{"/docs/a", 1, "docs_001.html"},
+		{"/docs/b", 1, "docs_002.html"},
+		{"/docs/c", 2, "docs_003.html"},
+		{"/docs/missing", 2, ""},
+	}
+	if len(index.Pages) != len(want) {
+		t.Fatalf("unexpected pages %+v", index.Pages)
+	}
+	for i, w := range want {
+		page := index.Pages[i]
+		if page.URL != server.URL+w.path || page.Depth != w.depth {
+			t.Errorf("page %d: unexpected %+v", i, page)
+		}
+		if w.file == "" {
+			if page.Error == "" {
+				t.Errorf("page %d: expected error", i)
+			}
+			continue
+		}
+		if page.FilePath != filepath.Join(workdir, w.file) {
+			t.Errorf("page %d: unexpected file %s", i, page.FilePath)
+		}
+		if _, err = os.Stat(page.FilePath); err != nil {
+			t.Errorf("page %d: file not written: %v", i, err)
+		}
+	}
+}
+
+func TestWebpackPlugin_CrawlLimits(t *testing.T) {
+	defer func(orig bool) { enablePrivateNet = orig }(enablePrivateNet)
+	enablePrivateNet = true
+	server := newCrawlSite(t)
+
+	_, resp := runCrawl(t, server, map[string]any{webpackParameterDepth: "2", webpackParameterMaxPages: "2"})
+	if pages, _ := resp.Results["pages"].([]map[string]any); len(pages) != 2 {
+		t.Errorf("expected crawl to stop at max_pages, got %v", resp.Results["pages"])
+	}
+
+	_, resp = runCrawl(t, server, map[string]any{})
+	if _, ok := resp.Results["pages"]; ok {
+		t.Errorf("depth 0 should not crawl")
+	}
+
+	for _, params := range []map[string]any{
+		{webpackParameterDepth: "9"},
+		{webpackParameterDepth: "1", webpackParameterMaxPages: "0"},
+	} {
+		params[webpackParameterFileName] = "docs"
+		params[webpackParameterURL] = server.URL + "/docs/"
+		p := NewWebpackPlugin(types.PluginCall{WorkingPath: t.TempDir()}).(*WebpackPlugin)
+		if _, err := p.Run(context.Background(), &api.Request{Parameter: params}); err == nil {
+			t.Errorf("expected invalid crawl option error for %v", params)
+		}
+	}
+}
+
+func TestWebpackPlugin_CrawlRespectRobots(t *testing.T) {
+	defer func(orig bool) { enablePrivateNet = orig }(enablePrivateNet)
+	enablePrivateNet = true
+	server := newCrawlSite(t)
+
+	_, resp := runCrawl(t, server, map[string]any{webpackParameterDepth: "2", ParameterRespectRobots: "true"})
+	if resp.Results["captured"] != 3 || resp.Results["failed"] != 1 {
+		t.Fatalf("unexpected crawl summary captured=%v failed=%v", resp.Results["captured"], resp.Results["failed"])
+	}
+	pages, _ := resp.Results["pages"].([]map[string]any)
+	for _, page := range pages {
+		if page["url"] == server.URL+"/docs/b" && !strings.Contains(fmt.Sprint(page["error"]), ErrDisallowedByRobots.Error()) {
+			t.Errorf("expected robots error for /docs/b, got %v", page)
+		}
+		if page["url"] == server.URL+"/docs/missing" {
+			t.Errorf("links of a disallowed page should not be followed")
+		}
+	}
+}
@@ -0,0 +1,202 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package web
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/basenana/plugin/api"
+	"github.com/hyponet/webpage-packer/packer"
+)
+
+const (
+	webpackConfigCredentials = "webpack_credentials"
+
+	webpackParameterHeaders     = "headers"
+	webpackParameterBasicAuth   = "basic_auth"
+	webpackParameterBearerToken = "bearer_token"
+)
+
+type BasicAuth struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// Credential is applied to every request made while packing a URL of the matched domain.
+type Credential struct {
+	Headers   map[string]string `json:"headers,omitempty"`
+	Cookie    string            `json:"cookie,omitempty"`
+	BasicAuth *BasicAuth        `json:"basic_auth,omitempty"`
+}
+
+// CredentialStore maps a domain to its credential. A domain also matches its subdomains.
+type CredentialStore map[string]Credential
+
+func ParseCredentialStore(raw string) (CredentialStore, error) {
+	store := CredentialStore{}
+	if strings.TrimSpace(raw) == "" {
+		return store, nil
+	}
+
+	var parsed map[string]Credential
+	if err := json.Unmarshal([]byte(raw), &parsed); err != nil {
+		return nil, fmt.Errorf("parse credentials failed: %w", err)
+	}
+	for domain, cred := range parsed {
+		domain = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(domain)), "*.")
+		domain = strings.Trim(domain, ".")
+		if domain == "" {
+			continue
+		}
+		store[domain] = cred
+	}
+	return store, nil
+}
+
+// Match returns the credential of the most specific domain matching the URL host.
+func (s CredentialStore) Match(rawURL string) (string, Credential, bool) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Hostname() == "" {
+		return "", Credential{}, false
+	}
+	host := strings.ToLower(u.Hostname())
+
+	var (
+		matched string
+		cred    Credential
+	)
+	for domain, c := range s {
+		if host != domain && !strings.HasSuffix(host, "."+domain) {
+			continue
+		}
+		if len(domain) > len(matched) {
+			matched, cred = domain, c
+		}
+	}
+	return matched, cred, matched != ""
+}
+
+func (c Credential) Option() Option {
+	return func(option *packer.Option) {
+		if option.Headers == nil {
+			option.Headers = make(map[string]string)
+		}
+		for k, v := range c.Headers {
+			option.Headers[k] = v
+		}
+		if c.Cookie != "" {
+			option.Headers["Cookie"] = c.Cookie
+		}
+		if c.BasicAuth != nil {
+			token := base64.StdEncoding.EncodeToString([]byte(c.BasicAuth.Username + ":" + c.BasicAuth.Password))
+			option.Headers["Authorization"] = "Basic " + token
+		}
+	}
+}
+
+// parseRequestCredential reads the headers, basic_auth and bearer_token parameters. It returns nil when none is set.
+func parseRequestCredential(request *api.Request) (*Credential, error) {
+	var (
+		rawHeaders = api.GetStringParameter(webpackParameterHeaders, request, "")
+		rawAuth    = api.GetStringParameter(webpackParameterBasicAuth, request, "")
+		token      = api.GetStringParameter(webpackParameterBearerToken, request, "")
+		cred       Credential
+	)
+	if rawHeaders == "" && rawAuth == "" && token == "" {
+		return nil, nil
+	}
+
+	if rawHeaders != "" {
+		if err := json.Unmarshal([]byte(rawHeaders), &cred.Headers); err != nil {
+			return nil, fmt.Errorf("parse %s failed: expect JSON object of strings: %w", webpackParameterHeaders, err)
+		}
+		if err := checkHeaders(cred.Headers); err != nil {
+			return nil, err
+		}
+	}
+	if rawAuth != "" {
+		cred.BasicAuth = &BasicAuth{}
+		if err := json.Unmarshal([]byte(rawAuth), cred.BasicAuth); err != nil {
+			return nil, fmt.Errorf("parse %s failed: expect JSON object with username and password: %w", webpackParameterBasicAuth, err)
+		}
+		if cred.BasicAuth.Username == "" {
+			return nil, fmt.Errorf("%s requires username", webpackParameterBasicAuth)
+		}
+	}
+	if token != "" {
+		if cred.BasicAuth != nil {
+			return nil, fmt.Errorf("%s and %s are exclusive", webpackParameterBasicAuth, webpackParameterBearerToken)
+		}
+		if cred.Headers == nil {
+			cred.Headers = map[string]string{}
+		}
+		cred.Headers["Authorization"] = "Bearer " + token
+	}
+	return &cred, nil
+}
+
+func checkHeaders(headers map[string]string) error {
+	for k, v := range headers {
+		if strings.TrimSpace(k) == "" || strings.ContainsAny(k, "\r\n: ") || strings.ContainsAny(v, "\r\n") {
+			return fmt.Errorf("invalid header [%s]", k)
+		}
+	}
+	return nil
+}
+
+type requestCredentialKey struct{}
+
+// withRequestCredential limits the credential of the request parameters to the host of target and its subdomains;
+// requestOptions resolves it for each request URL, so assets on other hosts do not receive it.
+func withRequestCredential(ctx context.Context, target string, cred *Credential) (context.Context, error) {
+	if cred == nil {
+		return ctx, nil
+	}
+	u, err := url.Parse(target)
+	if err != nil || u.Hostname() == "" {
+		return ctx, fmt.Errorf("invalid url [%s] for credential", target)
+	}
+	return context.WithValue(ctx, requestCredentialKey{}, CredentialStore{strings.ToLower(u.Hostname()): *cred}), nil
+}
+
+// requestOptions are the domain credential, the credential of the request parameters, the header
+// overrides and the cookies applied to a fetch of rawURL, in that order so the request parameters
+// override the config.
+func (w *WebpackPlugin) requestOptions(ctx context.Context, rawURL string) []Option {
+	var options []Option
+	if domain, cred, ok := w.credentials.Match(rawURL); ok {
+		w.logger.Debugw("apply credential for domain", "domain", domain, "url", rawURL)
+		options = append(options, cred.Option())
+	}
+	if store, ok := ctx.Value(requestCredentialKey{}).(CredentialStore); ok {
+		if _, cred, ok := store.Match(rawURL); ok {
+			options = append(options, cred.Option())
+		}
+	}
+	if opt, ok := headerOverrideOption(ctx, rawURL); ok {
+		options = append(options, opt)
+	}
+	if opt, ok := cookieOption(ctx, rawURL); ok {
+		options = append(options, opt)
+	}
+	return options
+}
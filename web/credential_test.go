@@ -0,0 +1,333 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package web
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/types"
+	"github.com/hyponet/webpage-packer/packer"
+)
+
+const testCredentials = `{
+  "example.com": {"cookie": "session=abc"},
+  "*.api.example.com": {"headers": {"X-Token": "t1"}},
+  "private.org": {"basic_auth": {"username": "user", "password": "pass"}}
+}`
+
+func TestCredentialStore_Match(t *testing.T) {
+	store, err := ParseCredentialStore(testCredentials)
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+
+	tests := []struct {
+		url    string
+		domain string
+		found  bool
+	}{
+		{"https://example.com/post", "example.com", true},
+		{"https://www.Example.com/post", "example.com", true},
+		{"https://v1.api.example.com/x", "api.example.com", true},
+		{"https://notexample.com/", "", false},
+		{"https://private.org:8443/a", "private.org", true},
+		{"not a url", "", false},
+	}
+	for _, tt := range tests {
+		domain, _, found := store.Match(tt.url)
+		if found != tt.found || domain != tt.domain {
+			t.Errorf("Match(%q) = %q, %v; want %q, %v", tt.url, domain, found, tt.domain, tt.found)
+		}
+	}
+}
+
+func TestParseCredentialStore_Invalid(t *testing.T) {
+	if _, err := ParseCredentialStore("{invalid"); err == nil {
+		t.Error("expected error for invalid json")
+	}
+	store, err := ParseCredentialStore("")
+	if err != nil || len(store) != 0 {
+		t.Errorf("expected empty store, got %v, %v", store, err)
+	}
+}
+
+func TestCredential_Option(t *testing.T) {
+	cred := Credential{
+		Headers:   map[string]string{"X-Token": "t1"},
+		Cookie:    "session=abc",
+		BasicAuth: &BasicAuth{Username: "user", Password: "pass"},
+	}
+	opt := packer.Option{}
+	cred.Option()(&opt)
+
+	if opt.Headers["X-Token"] != "t1" {
+		t.Errorf("expected custom header, got %v", opt.Headers)
+	}
+	if opt.Headers["Cookie"] != "session=abc" {
+		t.Errorf("expected cookie header, got %v", opt.Headers)
+	}
+	if opt.Headers["Authorization"] != "Basic dXNlcjpwYXNz" {
+		t.Errorf("expected basic auth header, got %v", opt.Headers)
+	}
+}
+
+func TestWebpackPlugin_AppliesDomainCredential(t *testing.T) {
+	var (
+		mux     sync.Mutex
+		cookies []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mux.Lock()
+		cookies = append(cookies, r.Header.Get("Cookie"))
+		mux.Unlock()
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte("<html><head><title>Private</title></head><body><p>members only</p></body></html>"))
+	}))
+	defer server.Close()
+
+	origin := enablePrivateNet
+	enablePrivateNet = true
+	defer func() { enablePrivateNet = origin }()
+
+	p := NewWebpackPlugin(types.PluginCall{
+		WorkingPath: t.TempDir(),
+		Params:      map[string]string{"file_type": "html", "clutter_free": "false"},
+		Config:      map[string]string{webpackConfigCredentials: `{"127.0.0.1": {"cookie": "session=abc"}}`},
+	}).(*WebpackPlugin)
+
+	resp, err := p.Run(context.Background(), &api.Request{Parameter: map[string]any{
+		"file_name": "private",
+		"url":       server.URL + "/page",
+	}})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if !resp.IsSucceed {
+		t.Fatalf("Run not succeed: %s", resp.Message)
+	}
+
+	mux.Lock()
+	defer mux.Unlock()
+	if len(cookies) == 0 || cookies[0] != "session=abc" {
+		t.Errorf("expected credential cookie to be sent, got %v", cookies)
+	}
+}
+
+func TestParseRequestCredential(t *testing.T) {
+	cred, err := parseRequestCredential(&api.Request{Parameter: map[string]any{
+		"headers":      map[string]any{"X-Api-Key": "k1"},
+		"bearer_token": "t1",
+	}})
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if cred.Headers["X-Api-Key"] != "k1" || cred.Headers["Authorization"] != "Bearer t1" {
+		t.Errorf("unexpected headers %v", cred.Headers)
+	}
+
+	cred, err = parseRequestCredential(&api.Request{Parameter: map[string]any{
+		"basic_auth": `{"username": "user", "password": "pass"}`,
+	}})
+	if err != nil || cred.BasicAuth == nil || cred.BasicAuth.Username != "user" {
+		t.Errorf("unexpected basic auth %+v, %v", cred, err)
+	}
+
+	for _, params := range []map[string]any{
+		{"headers": `["X-Api-Key"]`},
+		{"headers": map[string]any{"X-Bad\nKey": "v"}},
+		{"headers": map[string]any{"X-Key": "v\r\nInjected: 1"}},
+		{"basic_auth": `{"password": "pass"}`},
+		{"basic_auth": `{"username": "user"}`, "bearer_token": "t1"},
+	} {
+		if _, err = parseRequestCredential(&api.Request{Parameter: params}); err == nil {
+			t.Errorf("expected error for %v", params)
+		}
+	}
+	if cred, err = parseRequestCredential(&api.Request{}); cred != nil || err != nil {
+		t.Errorf("expected no credential, got %v, %v", cred, err)
+	}
+}
+
+func TestWebpackPlugin_RequestCredential(t *testing.T) {
+	var (
+		mux     sync.Mutex
+		headers []http.Header
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mux.Lock()
+		headers = append(headers, r.Header.Clone())
+		mux.Unlock()
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte("<html><head><title>Private</title></head><body><p>members only</p></body></html>"))
+	}))
+	defer server.Close()
+
+	origin := enablePrivateNet
+	enablePrivateNet = true
+	defer func() { enablePrivateNet = origin }()
+
+	p := NewWebpackPlugin(types.PluginCall{
+		WorkingPath: t.TempDir(),
+		Params:      map[string]string{"file_type": "html", "clutter_free": "false"},
+		Config:      map[string]string{webpackConfigCredentials: `{"127.0.0.1": {"headers": {"Authorization": "Bearer config", "X-Site": "s1"}}}`},
+	}).(*WebpackPlugin)
+
+	resp, err := p.Run(context.Background(), &api.Request{Parameter: map[string]any{
+		"file_name":    "private",
+		"url":          server.URL + "/page",
+		"headers":      map[string]any{"X-Api-Key": "k1"},
+		"bearer_token": "t1",
+	}})
+	if err != nil || !resp.IsSucceed {
+		t.Fatalf("Run failed: %v %v", err, resp)
+	}
+
+	mux.Lock()
+	defer mux.Unlock()
+	if len(headers) == 0 {
+		t.Fatal("no request received")
+	}
+	if got := headers[0].Get("Authorization"); got != "Bearer t1" {
+		t.Errorf("request parameters should override the config credential, got %s", got)
+	}
+	if headers[0].Get("X-Api-Key") != "k1" || headers[0].Get("X-Site") != "s1" {
+		t.Errorf("expected request and config headers, got %v", headers[0])
+	}
+
+	ctx, _ := withRequestCredential(context.Background(), server.URL, &Credential{Headers: map[string]string{"X-Api-Key": "k1"}})
+	if opts := p.requestOptions(ctx, "https://other.example.com/"); len(opts) != 0 {
+		t.Errorf("request credential should not apply to other hosts")
+	}
+}
+
+// assetHostServers serves a page on 127.0.0.1 whose image is on a second server reached as
+// localhost, and records the headers each of them receives.
+type assetHostServers struct {
+	page, asset *httptest.Server
+
+	mu          sync.Mutex
+	pageHeaders http.Header
+	assetHeader http.Header
+}
+
+func newAssetHostServers(t *testing.T) *assetHostServers {
+	s := &assetHostServers{}
+	s.asset = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.assetHeader = r.Header.Clone()
+		s.mu.Unlock()
+		w.Header().Set("Content-Type", "image/png")
+		_, _ = w.Write([]byte("png"))
+	}))
+	assetURL := strings.Replace(s.asset.URL, "127.0.0.1", "localhost", 1) + "/img.png"
+	s.page = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/page" {
+			http.NotFound(w, r)
+			return
+		}
+		s.mu.Lock()
+		s.pageHeaders = r.Header.Clone()
+		s.mu.Unlock()
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(`<html><head><title>Assets</title></head><body><p>content</p><img src="` + assetURL + `"></body></html>`))
+	}))
+	t.Cleanup(func() {
+		s.page.Close()
+		s.asset.Close()
+	})
+
+	origin := enablePrivateNet
+	enablePrivateNet = true
+	t.Cleanup(func() { enablePrivateNet = origin })
+	return s
+}
+
+// headers returns and resets the headers received by the page and the asset server, failing if
+// either was not requested.
+func (s *assetHostServers) headers(t *testing.T) (http.Header, http.Header) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	page, asset := s.pageHeaders, s.assetHeader
+	s.pageHeaders, s.assetHeader = nil, nil
+	if page == nil || asset == nil {
+		t.Fatalf("expected page and asset requests, got page %v asset %v", page, asset)
+	}
+	return page, asset
+}
+
+func TestWebpackPlugin_CredentialNotSentToAssetHosts(t *testing.T) {
+	servers := newAssetHostServers(t)
+	for name, params := range map[string]map[string]any{
+		"packer":        {},
+		"fetch control": {"timeout": "5s"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			p := NewWebpackPlugin(types.PluginCall{
+				WorkingPath: t.TempDir(),
+				Params:      map[string]string{"file_type": "webarchive", "clutter_free": "false"},
+				Config:      map[string]string{webpackConfigCredentials: `{"127.0.0.1": {"cookie": "session=abc", "basic_auth": {"username": "user", "password": "pass"}}}`},
+			}).(*WebpackPlugin)
+			parameter := map[string]any{"file_name": "assets", "url": servers.page.URL + "/page"}
+			for k, v := range params {
+				parameter[k] = v
+			}
+			resp, err := p.Run(context.Background(), &api.Request{Parameter: parameter})
+			if err != nil || !resp.IsSucceed {
+				t.Fatalf("Run failed: %v %v", err, resp)
+			}
+
+			page, asset := servers.headers(t)
+			if page.Get("Cookie") != "session=abc" || page.Get("Authorization") == "" {
+				t.Errorf("expected credential on the page, got %v", page)
+			}
+			if asset.Get("Cookie") != "" || asset.Get("Authorization") != "" {
+				t.Errorf("credential sent to the asset host: %v", asset)
+			}
+		})
+	}
+}
+
+func TestWebpackPlugin_RequestCredentialNotSentToAssetHosts(t *testing.T) {
+	servers := newAssetHostServers(t)
+	p := NewWebpackPlugin(types.PluginCall{
+		WorkingPath: t.TempDir(),
+		Params:      map[string]string{"file_type": "webarchive", "clutter_free": "false"},
+	}).(*WebpackPlugin)
+	resp, err := p.Run(context.Background(), &api.Request{Parameter: map[string]any{
+		"file_name":    "assets",
+		"url":          servers.page.URL + "/page",
+		"headers":      map[string]string{"X-Api-Key": "k1"},
+		"bearer_token": "t1",
+	}})
+	if err != nil || !resp.IsSucceed {
+		t.Fatalf("Run failed: %v %v", err, resp)
+	}
+
+	page, asset := servers.headers(t)
+	if page.Get("X-Api-Key") != "k1" || page.Get("Authorization") != "Bearer t1" {
+		t.Errorf("expected request credential on the page, got %v", page)
+	}
+	if asset.Get("X-Api-Key") != "" || asset.Get("Authorization") != "" {
+		t.Errorf("request credential sent to the asset host: %v", asset)
+	}
+}
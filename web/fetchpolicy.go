@@ -0,0 +1,245 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package web
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"code.dny.dev/ssrf"
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/logger"
+	"github.com/basenana/plugin/types"
+	"golang.org/x/time/rate"
+)
+
+const (
+	ParameterRequestsPerSecond = "requests_per_second"
+	ParameterBurst             = "burst"
+	ParameterRespectRobots     = "respect_robots"
+
+	robotsTimeout = 10 * time.Second
+	maxRobotsSize = 512 * 1024
+)
+
+// FetchPolicyParameters are the request parameters read by ParseFetchPolicy.
+var FetchPolicyParameters = []types.ParameterSpec{
+	{
+		Name:        ParameterRequestsPerSecond,
+		Required:    false,
+		Description: "Maximum page requests per second to one host, unlimited when empty",
+	},
+	{
+		Name:        ParameterBurst,
+		Required:    false,
+		Default:     "1",
+		Description: "Requests to one host allowed at once before requests_per_second applies",
+	},
+	{
+		Name:        ParameterRespectRobots,
+		Required:    false,
+		Default:     "false",
+		Description: "Skip pages disallowed by the robots.txt of their host and honor its Crawl-delay",
+	},
+}
+
+// ErrDisallowedByRobots is returned for a URL the robots.txt of its host does not allow.
+var ErrDisallowedByRobots = errors.New("disallowed by robots.txt")
+
+// FetchPolicy throttles page requests per host and optionally checks robots.txt before them.
+// It travels in the context so every fetch of a run shares it, see WithFetchPolicy.
+type FetchPolicy struct {
+	requestsPerSecond float64
+	burst             int
+	respectRobots     bool
+
+	mu       sync.Mutex
+	limiters map[string]*rate.Limiter
+	robots   map[string]*robotsRules
+	client   *http.Client
+}
+
+// ParseFetchPolicy reads requests_per_second, burst and respect_robots. It returns nil when none is set.
+func ParseFetchPolicy(request *api.Request) (*FetchPolicy, error) {
+	var (
+		rpsRaw    = api.GetStringParameter(ParameterRequestsPerSecond, request, "")
+		burstRaw  = api.GetStringParameter(ParameterBurst, request, "")
+		respect   = api.GetBoolParameter(ParameterRespectRobots, request, false)
+		rps       float64
+		burst     = 1
+		err       error
+		configSet = rpsRaw != "" || burstRaw != "" || respect
+	)
+	if !configSet {
+		return nil, nil
+	}
+	if rpsRaw != "" {
+		if rps, err = strconv.ParseFloat(rpsRaw, 64); err != nil || rps <= 0 {
+			return nil, fmt.Errorf("invalid requests_per_second [%s]: expect positive number", rpsRaw)
+		}
+	}
+	if burstRaw != "" {
+		if burst, err = strconv.Atoi(burstRaw); err != nil || burst <= 0 {
+			return nil, fmt.Errorf("invalid burst [%s]: expect positive integer", burstRaw)
+		}
+	}
+	return NewFetchPolicy(rps, burst, respect), nil
+}
+
+// NewFetchPolicy allows requestsPerSecond requests per host with bursts of burst; zero means unlimited.
+// With respectRobots the Crawl-delay of a host lowers its rate further.
+func NewFetchPolicy(requestsPerSecond float64, burst int, respectRobots bool) *FetchPolicy {
+	dialer := &net.Dialer{Timeout: robotsTimeout}
+	if !enablePrivateNet {
+		dialer.Control = ssrf.New().Safe
+	}
+	return &FetchPolicy{
+		requestsPerSecond: requestsPerSecond,
+		burst:             max(burst, 1),
+		respectRobots:     respectRobots,
+		limiters:          map[string]*rate.Limiter{},
+		robots:            map[string]*robotsRules{},
+		client: &http.Client{
+			Timeout:   robotsTimeout,
+			Transport: &http.Transport{Proxy: http.ProxyFromEnvironment, DialContext: dialer.DialContext},
+		},
+	}
+}
+
+type fetchPolicyKey struct{}
+
+func WithFetchPolicy(ctx context.Context, policy *FetchPolicy) context.Context {
+	if policy == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, fetchPolicyKey{}, policy)
+}
+
+// WaitFetch blocks until the fetch policy in the context lets rawURL be requested.
+// It returns ErrDisallowedByRobots when robots.txt forbids the URL, and nil without a policy.
+func WaitFetch(ctx context.Context, rawURL string) error {
+	policy, _ := ctx.Value(fetchPolicyKey{}).(*FetchPolicy)
+	if policy == nil {
+		return nil
+	}
+	return policy.Wait(ctx, rawURL)
+}
+
+func (p *FetchPolicy) Wait(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return nil
+	}
+	host := strings.ToLower(u.Host)
+
+	var rules *robotsRules
+	if p.respectRobots {
+		rules = p.hostRobots(ctx, u.Scheme, host)
+		if !rules.allowed(robotsPath(u)) {
+			return fmt.Errorf("fetch %s failed: %w", rawURL, ErrDisallowedByRobots)
+		}
+	}
+	if limiter := p.hostLimiter(host, rules); limiter != nil {
+		return limiter.Wait(ctx)
+	}
+	return nil
+}
+
+func robotsPath(u *url.URL) string {
+	p := u.EscapedPath()
+	if p == "" {
+		p = "/"
+	}
+	if u.RawQuery != "" {
+		p += "?" + u.RawQuery
+	}
+	return p
+}
+
+func (p *FetchPolicy) hostLimiter(host string, rules *robotsRules) *rate.Limiter {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if limiter, ok := p.limiters[host]; ok {
+		return limiter
+	}
+	limit := rate.Limit(p.requestsPerSecond)
+	if rules != nil && rules.crawlDelay > 0 {
+		delayLimit := rate.Every(rules.crawlDelay)
+		if limit == 0 || delayLimit < limit {
+			limit = delayLimit
+		}
+	}
+	var limiter *rate.Limiter
+	if limit > 0 {
+		limiter = rate.NewLimiter(limit, p.burst)
+	}
+	p.limiters[host] = limiter
+	return limiter
+}
+
+// hostRobots returns the cached rules of a host, reading its robots.txt on first use.
+// Concurrent first fetches of a host may read it twice, which is harmless.
+func (p *FetchPolicy) hostRobots(ctx context.Context, scheme, host string) *robotsRules {
+	p.mu.Lock()
+	rules, ok := p.robots[host]
+	p.mu.Unlock()
+	if ok {
+		return rules
+	}
+
+	rules = p.readRobots(ctx, scheme, host)
+	p.mu.Lock()
+	p.robots[host] = rules
+	p.mu.Unlock()
+	return rules
+}
+
+// readRobots follows RFC 9309: a missing robots.txt (4xx) allows everything,
+// an unreachable one (5xx or network error) disallows everything.
+func (p *FetchPolicy) readRobots(ctx context.Context, scheme, host string) *robotsRules {
+	log := logger.FromContext(ctx)
+	robotsURL := (&url.URL{Scheme: scheme, Host: host, Path: "/robots.txt"}).String()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, robotsURL, nil)
+	if err != nil {
+		return nil
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		log.Warnw("read robots.txt failed, disallow host", "url", robotsURL, "error", err)
+		return &robotsRules{disallowed: true}
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return parseRobots(io.LimitReader(resp.Body, maxRobotsSize))
+	case resp.StatusCode >= 400 && resp.StatusCode < 500:
+		return nil
+	default:
+		log.Warnw("robots.txt unavailable, disallow host", "url", robotsURL, "status", resp.StatusCode)
+		return &robotsRules{disallowed: true}
+	}
+}
@@ -0,0 +1,122 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package web
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/basenana/plugin/api"
+)
+
+func TestParseFetchPolicy(t *testing.T) {
+	policy, err := ParseFetchPolicy(&api.Request{Parameter: map[string]any{}})
+	if err != nil || policy != nil {
+		t.Fatalf("expect no policy without parameters, got %v, %v", policy, err)
+	}
+	policy, err = ParseFetchPolicy(&api.Request{Parameter: map[string]any{
+		ParameterRequestsPerSecond: "0.5", ParameterBurst: "3", ParameterRespectRobots: "true",
+	}})
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if policy.requestsPerSecond != 0.5 || policy.burst != 3 || !policy.respectRobots {
+		t.Errorf("unexpected policy %+v", policy)
+	}
+	for _, params := range []map[string]any{
+		{ParameterRequestsPerSecond: "0"},
+		{ParameterRequestsPerSecond: "fast"},
+		{ParameterBurst: "-1"},
+	} {
+		if _, err = ParseFetchPolicy(&api.Request{Parameter: params}); err == nil {
+			t.Errorf("expect error for %v", params)
+		}
+	}
+}
+
+func TestWaitFetch_RateLimit(t *testing.T) {
+	ctx := WithFetchPolicy(context.Background(), NewFetchPolicy(20, 2, false))
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		if err := WaitFetch(ctx, "http://a.example/page"); err != nil {
+			t.Fatalf("wait failed: %v", err)
+		}
+	}
+	// burst of 2, then two more at 50ms each
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond {
+		t.Errorf("requests to one host not throttled, took %s", elapsed)
+	}
+
+	start = time.Now()
+	for _, u := range []string{"http://b.example/", "http://c.example/", "http://D.example/"} {
+		if err := WaitFetch(ctx, u); err != nil {
+			t.Fatalf("wait failed: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 40*time.Millisecond {
+		t.Errorf("hosts should be limited separately, took %s", elapsed)
+	}
+
+	if err := WaitFetch(context.Background(), "http://a.example/page"); err != nil {
+		t.Errorf("expect no-op without policy: %v", err)
+	}
+}
+
+func TestWaitFetch_Robots(t *testing.T) {
+	defer func(orig bool) { enablePrivateNet = orig }(enablePrivateNet)
+	enablePrivateNet = true
+
+	var robotsReads atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			robotsReads.Add(1)
+			_, _ = w.Write([]byte("User-agent: *\nDisallow: /private\n"))
+			return
+		}
+	}))
+	defer server.Close()
+
+	ctx := WithFetchPolicy(context.Background(), NewFetchPolicy(0, 1, true))
+	if err := WaitFetch(ctx, server.URL+"/public"); err != nil {
+		t.Errorf("public page should be allowed: %v", err)
+	}
+	if err := WaitFetch(ctx, server.URL+"/private/a"); !errors.Is(err, ErrDisallowedByRobots) {
+		t.Errorf("expect ErrDisallowedByRobots, got %v", err)
+	}
+	if robotsReads.Load() != 1 {
+		t.Errorf("robots.txt should be read once per host, read %d times", robotsReads.Load())
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+	if err := WaitFetch(ctx, failing.URL+"/page"); !errors.Is(err, ErrDisallowedByRobots) {
+		t.Errorf("unavailable robots.txt should disallow the host, got %v", err)
+	}
+
+	missing := httptest.NewServer(http.NotFoundHandler())
+	defer missing.Close()
+	if err := WaitFetch(ctx, missing.URL+"/page"); err != nil {
+		t.Errorf("missing robots.txt should allow the host: %v", err)
+	}
+}
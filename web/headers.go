@@ -0,0 +1,114 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/basenana/plugin/api"
+	"github.com/hyponet/webpage-packer/packer"
+)
+
+const (
+	webpackParameterUserAgent   = "user_agent"
+	webpackParameterHostHeaders = "host_headers"
+)
+
+// HeaderOverrides replace the User-Agent of every request and add headers to the requests of
+// matching hosts. Like the credentials, the headers of a host are resolved for each request, so
+// an asset receives those of its own host rather than those of the page.
+type HeaderOverrides struct {
+	UserAgent string
+	Hosts     CredentialStore
+}
+
+// parseHeaderOverrides reads user_agent and host_headers. It returns nil when neither is set.
+func parseHeaderOverrides(request *api.Request) (*HeaderOverrides, error) {
+	var (
+		userAgent = strings.TrimSpace(api.GetStringParameter(webpackParameterUserAgent, request, ""))
+		rawHosts  = api.GetStringParameter(webpackParameterHostHeaders, request, "")
+	)
+	if userAgent == "" && rawHosts == "" {
+		return nil, nil
+	}
+	if strings.ContainsAny(userAgent, "\r\n") {
+		return nil, fmt.Errorf("invalid %s", webpackParameterUserAgent)
+	}
+	overrides := &HeaderOverrides{UserAgent: userAgent, Hosts: CredentialStore{}}
+	if rawHosts != "" {
+		var hosts map[string]map[string]string
+		if err := json.Unmarshal([]byte(rawHosts), &hosts); err != nil {
+			return nil, fmt.Errorf("parse %s failed: expect JSON object of host to headers: %w", webpackParameterHostHeaders, err)
+		}
+		for host, headers := range hosts {
+			if err := checkHeaders(headers); err != nil {
+				return nil, fmt.Errorf("%s of %s: %w", webpackParameterHostHeaders, host, err)
+			}
+			host = strings.Trim(strings.TrimPrefix(strings.ToLower(strings.TrimSpace(host)), "*."), ".")
+			if host == "" {
+				return nil, fmt.Errorf("%s has an empty host", webpackParameterHostHeaders)
+			}
+			overrides.Hosts[host] = Credential{Headers: headers}
+		}
+	}
+	return overrides, nil
+}
+
+type headerOverridesKey struct{}
+
+func withHeaderOverrides(ctx context.Context, overrides *HeaderOverrides) context.Context {
+	if overrides == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, headerOverridesKey{}, overrides)
+}
+
+// overrideHeaders returns the User-Agent and the headers of the most specific host matching rawURL.
+func overrideHeaders(ctx context.Context, rawURL string) map[string]string {
+	overrides, ok := ctx.Value(headerOverridesKey{}).(*HeaderOverrides)
+	if !ok {
+		return nil
+	}
+	headers := make(map[string]string)
+	if overrides.UserAgent != "" {
+		headers["User-Agent"] = overrides.UserAgent
+	}
+	if _, host, ok := overrides.Hosts.Match(rawURL); ok {
+		for k, v := range host.Headers {
+			headers[k] = v
+		}
+	}
+	return headers
+}
+
+func headerOverrideOption(ctx context.Context, rawURL string) (Option, bool) {
+	headers := overrideHeaders(ctx, rawURL)
+	if len(headers) == 0 {
+		return nil, false
+	}
+	return func(option *packer.Option) {
+		if option.Headers == nil {
+			option.Headers = make(map[string]string)
+		}
+		for k, v := range headers {
+			option.Headers[k] = v
+		}
+	}, true
+}
@@ -0,0 +1,157 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package web
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/types"
+)
+
+func TestParseHeaderOverrides(t *testing.T) {
+	overrides, err := parseHeaderOverrides(&api.Request{Parameter: map[string]any{
+		webpackParameterUserAgent:   "Archiver/1.0",
+		webpackParameterHostHeaders: map[string]any{"*.Example.com": map[string]string{"X-Token": "a"}, "cdn.example.com": map[string]string{"X-Token": "b"}},
+	}})
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	ctx := withHeaderOverrides(context.Background(), overrides)
+	tests := map[string]map[string]string{
+		"https://www.example.com/page":  {"User-Agent": "Archiver/1.0", "X-Token": "a"},
+		"https://cdn.example.com/a.png": {"User-Agent": "Archiver/1.0", "X-Token": "b"},
+		"https://other.test/":           {"User-Agent": "Archiver/1.0"},
+	}
+	for u, want := range tests {
+		got := overrideHeaders(ctx, u)
+		if len(got) != len(want) {
+			t.Errorf("%s: headers = %v, want %v", u, got, want)
+			continue
+		}
+		for k, v := range want {
+			if got[k] != v {
+				t.Errorf("%s: %s = %q, want %q", u, k, got[k], v)
+			}
+		}
+	}
+
+	for _, params := range []map[string]any{
+		{webpackParameterUserAgent: "bad\nagent"},
+		{webpackParameterHostHeaders: "not json"},
+		{webpackParameterHostHeaders: map[string]any{"example.com": map[string]string{"Bad Header": "x"}}},
+		{webpackParameterHostHeaders: map[string]any{"": map[string]string{"X-Token": "x"}}},
+	} {
+		if _, err = parseHeaderOverrides(&api.Request{Parameter: params}); err == nil {
+			t.Errorf("expected error for %v", params)
+		}
+	}
+	if overrides, err = parseHeaderOverrides(&api.Request{}); overrides != nil || err != nil {
+		t.Errorf("expected no overrides, got %v, %v", overrides, err)
+	}
+	if got := overrideHeaders(context.Background(), "https://example.com"); got != nil {
+		t.Errorf("expected no headers without overrides, got %v", got)
+	}
+}
+
+func TestWebpackPlugin_HeaderOverrides(t *testing.T) {
+	var (
+		mu   sync.Mutex
+		seen = map[string]http.Header{}
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		seen[r.URL.Path] = r.Header.Clone()
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/page":
+			w.Header().Set("Content-Type", "text/html")
+			// the image is requested through another host name of the same server
+			_, _ = w.Write([]byte(`<html><head><title>UA</title></head><body><p>content</p><img src="` +
+				strings.Replace(serverURL(r), "127.0.0.1", "localhost", 1) + `/img.png"></body></html>`))
+		case "/img.png":
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = w.Write([]byte("png"))
+		}
+	}))
+	defer server.Close()
+
+	origin := enablePrivateNet
+	enablePrivateNet = true
+	defer func() { enablePrivateNet = origin }()
+
+	p := NewWebpackPlugin(types.PluginCall{
+		WorkingPath: t.TempDir(),
+		Params:      map[string]string{"file_type": "webarchive", "clutter_free": "false"},
+	}).(*WebpackPlugin)
+	resp, err := p.Run(context.Background(), &api.Request{Parameter: map[string]any{
+		"file_name":  "ua",
+		"url":        server.URL + "/page",
+		"timeout":    "5s",
+		"user_agent": "Archiver/1.0",
+		"host_headers": map[string]any{
+			"127.0.0.1": map[string]string{"X-Page": "1"},
+			"localhost": map[string]string{"X-Asset": "1"},
+		},
+	}})
+	if err != nil || !resp.IsSucceed {
+		t.Fatalf("Run failed: %v %v", err, resp)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	page, img := seen["/page"], seen["/img.png"]
+	if page == nil || img == nil {
+		t.Fatalf("expected page and image requests, got %v", seen)
+	}
+	if page.Get("User-Agent") != "Archiver/1.0" || page.Get("X-Page") != "1" || page.Get("X-Asset") != "" {
+		t.Errorf("unexpected page headers %v", page)
+	}
+	if img.Get("User-Agent") != "Archiver/1.0" || img.Get("X-Asset") != "1" {
+		t.Errorf("unexpected image headers %v", img)
+	}
+}
+
+func TestWebpackPlugin_HostHeadersNotSentToAssetHosts(t *testing.T) {
+	servers := newAssetHostServers(t)
+	p := NewWebpackPlugin(types.PluginCall{
+		WorkingPath: t.TempDir(),
+		Params:      map[string]string{"file_type": "webarchive", "clutter_free": "false"},
+	}).(*WebpackPlugin)
+	resp, err := p.Run(context.Background(), &api.Request{Parameter: map[string]any{
+		"file_name":    "assets",
+		"url":          servers.page.URL + "/page",
+		"host_headers": map[string]any{"127.0.0.1": map[string]string{"X-Page": "1"}},
+	}})
+	if err != nil || !resp.IsSucceed {
+		t.Fatalf("Run failed: %v %v", err, resp)
+	}
+
+	page, asset := servers.headers(t)
+	if page.Get("X-Page") != "1" || asset.Get("X-Page") != "" {
+		t.Errorf("expected X-Page on the page only, got page %v asset %v", page, asset)
+	}
+}
+
+func serverURL(r *http.Request) string {
+	return "http://" + r.Host
+}
@@ -0,0 +1,81 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	htmltomarkdown "github.com/JohannesKaufmann/html-to-markdown/v2"
+	"github.com/JohannesKaufmann/html-to-markdown/v2/converter"
+	"github.com/basenana/plugin/utils"
+)
+
+// htmlToMarkdownFile extracts the readable article of a raw html page and writes it as Markdown with a front matter.
+func htmlToMarkdownFile(htmlPath, mdPath, pageURL string, captured time.Time) error {
+	f, err := os.Open(htmlPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	u, err := url.Parse(pageURL)
+	if err != nil {
+		return fmt.Errorf("parse url %s failed: %w", pageURL, err)
+	}
+	article, err := utils.ExtractArticle(f, u)
+	if err != nil {
+		return fmt.Errorf("extract article failed: %w", err)
+	}
+	body, err := htmltomarkdown.ConvertString(article.Content, converter.WithDomain(pageURL))
+	if err != nil {
+		return fmt.Errorf("convert to markdown failed: %w", err)
+	}
+
+	buf := &bytes.Buffer{}
+	buf.WriteString("---\n")
+	writeFrontMatter(buf, "title", article.Title)
+	writeFrontMatter(buf, "author", article.Byline)
+	writeFrontMatter(buf, "site", article.SiteName)
+	writeFrontMatter(buf, "url", pageURL)
+	if article.PublishedTime != nil {
+		writeFrontMatter(buf, "published", article.PublishedTime.Format(time.RFC3339))
+	}
+	writeFrontMatter(buf, "captured", captured.Format(time.RFC3339))
+	writeFrontMatter(buf, "language", article.Language)
+	writeFrontMatter(buf, "excerpt", strings.TrimSpace(article.Excerpt))
+	buf.WriteString("---\n\n")
+	buf.WriteString(strings.TrimSpace(body))
+	buf.WriteString("\n")
+	return os.WriteFile(mdPath, buf.Bytes(), 0644)
+}
+
+func writeFrontMatter(buf *bytes.Buffer, key, value string) {
+	if value == "" {
+		return
+	}
+	// a JSON string is a valid YAML double-quoted scalar
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	buf.WriteString(fmt.Sprintf("%s: ", key))
+	_ = enc.Encode(value)
+}
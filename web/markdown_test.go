@@ -0,0 +1,90 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package web
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/types"
+)
+
+func TestWebpackPlugin_Markdown(t *testing.T) {
+	defer func(orig bool) { enablePrivateNet = orig }(enablePrivateNet)
+	enablePrivateNet = true
+
+	paragraph := strings.Repeat("Readable articles make good notes, and this sentence says so again. ", 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte(`<html lang="en"><head><title>Notes on Reading</title>
+<meta name="author" content="Ada Writer"><meta property="article:published_time" content="2026-03-04T05:06:07Z"></head>
+<body><nav><a href="/home">Home</a><a href="/about">About</a></nav>
+<article><h1>Notes on Reading</h1><p>` + paragraph + `</p><p>See <a href="/more">more</a>.</p><p>` + paragraph + `</p></article>
+<footer>Copyright footer</footer></body></html>`))
+	}))
+	defer server.Close()
+
+	workdir := t.TempDir()
+	p := NewWebpackPlugin(types.PluginCall{
+		WorkingPath: workdir,
+		Params:      map[string]string{webpackParameterFileType: "markdown"},
+	}).(*WebpackPlugin)
+
+	resp, err := p.Run(context.Background(), &api.Request{Parameter: map[string]any{
+		webpackParameterFileName: "notes",
+		webpackParameterURL:      server.URL + "/post",
+	}})
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	filePath := filepath.Join(workdir, "notes.md")
+	if resp.Results["file_path"] != filePath {
+		t.Errorf("unexpected file path %v", resp.Results["file_path"])
+	}
+	if _, err = os.Stat(filepath.Join(workdir, ".notes.html")); !os.IsNotExist(err) {
+		t.Errorf("intermediate html should be removed, got %v", err)
+	}
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatalf("read markdown failed: %v", err)
+	}
+	md := string(data)
+	for _, want := range []string{
+		"---\ntitle: \"Notes on Reading\"\n",
+		"author: \"Ada Writer\"\n",
+		"url: \"" + server.URL + "/post\"\n",
+		"published: \"2026-03-04T05:06:07Z\"\n",
+		"language: \"en\"\n",
+		"captured: \"",
+		"Readable articles make good notes",
+		"[more](" + server.URL + "/more)",
+	} {
+		if !strings.Contains(md, want) {
+			t.Errorf("expected %q in markdown:\n%s", want, md)
+		}
+	}
+	if strings.Contains(md, "Copyright footer") || strings.Contains(md, "<p>") {
+		t.Errorf("expected clutter-free markdown:\n%s", md)
+	}
+}
@@ -0,0 +1,154 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package web
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/hyponet/webpage-packer/packer"
+	"howett.net/plist"
+)
+
+// webarchiveToMHTML converts a packed webarchive into a MHTML file, which Chromium based
+// browsers open natively. The main resource comes first, followed by its subresources.
+func webarchiveToMHTML(webarchivePath, mhtmlPath string) error {
+	f, err := os.Open(webarchivePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	archive := &packer.WebArchive{}
+	if err = plist.NewDecoder(f).Decode(archive); err != nil {
+		return fmt.Errorf("load webarchive failed: %w", err)
+	}
+
+	output, err := os.OpenFile(mhtmlPath, os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("open output file failed: %w", err)
+	}
+	defer output.Close()
+
+	buf := bufio.NewWriter(output)
+	if err = writeMHTML(buf, archive, time.Now()); err != nil {
+		return err
+	}
+	return buf.Flush()
+}
+
+func writeMHTML(w io.Writer, archive *packer.WebArchive, now time.Time) error {
+	main := archive.WebMainResource
+	boundary := "----MultipartBoundary--" + randomToken() + "----"
+
+	header := &strings.Builder{}
+	header.WriteString("From: <Saved by NanaFS>\r\n")
+	fmt.Fprintf(header, "Snapshot-Content-Location: %s\r\n", main.WebResourceURL)
+	if title := htmlTitle(main.WebResourceData); title != "" {
+		fmt.Fprintf(header, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", title))
+	}
+	fmt.Fprintf(header, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	header.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(header, "Content-Type: multipart/related;\r\n\ttype=\"text/html\";\r\n\tboundary=\"%s\"\r\n\r\n", boundary)
+	if _, err := io.WriteString(w, header.String()); err != nil {
+		return err
+	}
+
+	mw := multipart.NewWriter(w)
+	if err := mw.SetBoundary(boundary); err != nil {
+		return err
+	}
+	seen := map[string]bool{}
+	for _, res := range append([]packer.WebResourceItem{main}, archive.WebSubresources...) {
+		if seen[res.WebResourceURL] {
+			continue
+		}
+		seen[res.WebResourceURL] = true
+		if err := writeMHTMLPart(mw, res); err != nil {
+			return err
+		}
+	}
+	return mw.Close()
+}
+
+func writeMHTMLPart(mw *multipart.Writer, res packer.WebResourceItem) error {
+	contentType := res.WebResourceMIMEType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	isText := strings.HasPrefix(contentType, "text/") || strings.HasSuffix(contentType, "javascript") || strings.HasSuffix(contentType, "xml")
+	if isText && res.WebResourceTextEncodingName != "" {
+		contentType += "; charset=" + res.WebResourceTextEncodingName
+	}
+	encoding := "base64"
+	if isText {
+		encoding = "quoted-printable"
+	}
+
+	h := textproto.MIMEHeader{}
+	h.Set("Content-Type", contentType)
+	h.Set("Content-Transfer-Encoding", encoding)
+	h.Set("Content-Location", res.WebResourceURL)
+	part, err := mw.CreatePart(h)
+	if err != nil {
+		return err
+	}
+
+	if isText {
+		qp := quotedprintable.NewWriter(part)
+		if _, err = qp.Write(res.WebResourceData); err != nil {
+			return err
+		}
+		return qp.Close()
+	}
+	encoded := base64.StdEncoding.EncodeToString(res.WebResourceData)
+	for len(encoded) > 76 {
+		if _, err = io.WriteString(part, encoded[:76]+"\r\n"); err != nil {
+			return err
+		}
+		encoded = encoded[76:]
+	}
+	_, err = io.WriteString(part, encoded+"\r\n")
+	return err
+}
+
+func htmlTitle(data []byte) string {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(data))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(doc.Find("title").First().Text())
+}
+
+func randomToken() string {
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,142 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package web
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/logger"
+	"github.com/basenana/plugin/types"
+	"github.com/hyponet/webpage-packer/packer"
+)
+
+func TestWriteMHTML_RoundTrip(t *testing.T) {
+	page := `<html><head><title>Café notes</title></head><body><p>Crème brûlée = ` + strings.Repeat("x", 100) + `</p><img src="a.png"></body></html>`
+	archive := &packer.WebArchive{
+		WebMainResource: packer.WebResourceItem{
+			WebResourceURL:              "https://example.com/notes",
+			WebResourceMIMEType:         "text/html",
+			WebResourceData:             []byte(page),
+			WebResourceTextEncodingName: "utf-8",
+		},
+		WebSubresources: []packer.WebResourceItem{
+			{WebResourceURL: "https://example.com/notes", WebResourceMIMEType: "text/html", WebResourceData: []byte(page)},
+			{WebResourceURL: "https://example.com/a.png", WebResourceMIMEType: "image/png", WebResourceData: bytes.Repeat([]byte{0x89, 0x50}, 100)},
+		},
+	}
+
+	buf := &bytes.Buffer{}
+	if err := writeMHTML(buf, archive, time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)); err != nil {
+		t.Fatalf("write mhtml failed: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"Snapshot-Content-Location: https://example.com/notes\r\n",
+		"Subject: =?utf-8?q?Caf=C3=A9_notes?=\r\n",
+		"Content-Type: multipart/related;\r\n\ttype=\"text/html\";",
+		"Content-Location: https://example.com/a.png",
+		"Content-Transfer-Encoding: base64",
+		"Content-Type: text/html; charset=utf-8",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in mhtml", want)
+		}
+	}
+	if strings.Count(out, "\r\nContent-Location: https://example.com/notes") != 1 {
+		t.Errorf("main resource should be written once")
+	}
+	for _, line := range strings.Split(out, "\r\n") {
+		if len(line) > 76 && !strings.HasPrefix(line, "Content-") {
+			t.Errorf("line longer than 76 characters: %q", line)
+			break
+		}
+	}
+
+	doc, resources, err := readMHTMLResources(strings.NewReader(out))
+	if err != nil {
+		t.Fatalf("read mhtml failed: %v", err)
+	}
+	if doc != page {
+		t.Errorf("main document changed in round trip: %q", doc)
+	}
+	if res := resources["https://example.com/a.png"]; res.MIMEType != "image/png" || res.Size != 200 {
+		t.Errorf("unexpected image resource %+v", res)
+	}
+}
+
+func TestWebpackPlugin_MHTML(t *testing.T) {
+	defer func(orig bool) { enablePrivateNet = orig }(enablePrivateNet)
+	enablePrivateNet = true
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/article", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte(`<html><head><title>Article</title></head><body><h1>Article</h1><p>Offline reading</p><img src="http://` + r.Host + `/logo.png"></body></html>`))
+	})
+	mux.HandleFunc("/logo.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		_, _ = w.Write([]byte("\x89PNG\r\n\x1a\nlogo"))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	workdir := t.TempDir()
+	p := NewWebpackPlugin(types.PluginCall{
+		WorkingPath: workdir,
+		Params:      map[string]string{webpackParameterFileType: "mhtml", webpackParameterClutterFree: "false"},
+	}).(*WebpackPlugin)
+
+	resp, err := p.Run(context.Background(), &api.Request{Parameter: map[string]any{
+		webpackParameterFileName: "article",
+		webpackParameterURL:      server.URL + "/article",
+	}})
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	filePath := filepath.Join(workdir, "article.mhtml")
+	if resp.Results["file_path"] != filePath {
+		t.Errorf("unexpected file path %v", resp.Results["file_path"])
+	}
+	if _, err = os.Stat(filepath.Join(workdir, ".article.webarchive")); !os.IsNotExist(err) {
+		t.Errorf("intermediate webarchive should be removed, got %v", err)
+	}
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatalf("read mhtml failed: %v", err)
+	}
+	if !strings.Contains(string(data), "Content-Location: "+server.URL+"/logo.png") {
+		t.Errorf("expected image resource in mhtml")
+	}
+
+	content, err := ReadFromFile(logger.IntoContext(context.Background(), p.logger), filePath)
+	if err != nil {
+		t.Fatalf("read content failed: %v", err)
+	}
+	if !strings.Contains(content, "Offline reading") {
+		t.Errorf("unexpected content %q", content)
+	}
+}
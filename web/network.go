@@ -0,0 +1,324 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package web
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"os"
+	"path"
+	"slices"
+	"syscall"
+	"time"
+
+	"code.dny.dev/ssrf"
+	"github.com/basenana/plugin/logger"
+	"github.com/hyponet/webpage-packer/packer"
+)
+
+const (
+	webpackConfigNetworkPolicy = "webpack_network_policy"
+	webpackParameterProxyURL   = "proxy_url"
+
+	defaultMaxRedirects = 10
+	networkFetchTimeout = 60 * time.Second
+)
+
+// NetworkPolicy limits where webpack connects, configured per call instead of by the
+// WebPackerEnablePrivateNet env. Deny CIDRs win over allow CIDRs, and allow CIDRs open
+// addresses the built-in SSRF guard blocks, e.g. an intranet wiki.
+type NetworkPolicy struct {
+	AllowCIDRs   []string `json:"allow_cidrs,omitempty"`
+	DenyCIDRs    []string `json:"deny_cidrs,omitempty"`
+	Schemes      []string `json:"schemes,omitempty"`
+	Ports        []uint16 `json:"ports,omitempty"`
+	MaxRedirects *int     `json:"max_redirects,omitempty"`
+
+	proxy *url.URL
+	deny  []netip.Prefix
+	guard *ssrf.Guardian
+}
+
+// ParseNetworkPolicy reads the webpack_network_policy JSON config and the proxy_url parameter.
+// It returns nil when neither is set, leaving fetches to the web packer.
+func ParseNetworkPolicy(raw, proxyURL string) (*NetworkPolicy, error) {
+	if raw == "" && proxyURL == "" {
+		return nil, nil
+	}
+	p := &NetworkPolicy{}
+	if raw != "" {
+		if err := json.Unmarshal([]byte(raw), p); err != nil {
+			return nil, fmt.Errorf("parse %s failed: %w", webpackConfigNetworkPolicy, err)
+		}
+	}
+
+	if len(p.Schemes) == 0 {
+		p.Schemes = []string{"http", "https"}
+	}
+	for _, scheme := range p.Schemes {
+		if scheme != "http" && scheme != "https" {
+			return nil, fmt.Errorf("unsupported scheme %s: expect http or https", scheme)
+		}
+	}
+	if p.MaxRedirects == nil {
+		maxRedirects := defaultMaxRedirects
+		p.MaxRedirects = &maxRedirects
+	} else if *p.MaxRedirects < 0 {
+		return nil, fmt.Errorf("invalid max_redirects %d: expect zero or more", *p.MaxRedirects)
+	}
+
+	var allow4, allow6, deny4, deny6 []netip.Prefix
+	for _, cidr := range p.AllowCIDRs {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid allow cidr %s: %w", cidr, err)
+		}
+		if prefix.Addr().Is4() {
+			allow4 = append(allow4, prefix.Masked())
+		} else {
+			allow6 = append(allow6, prefix.Masked())
+		}
+	}
+	for _, cidr := range p.DenyCIDRs {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid deny cidr %s: %w", cidr, err)
+		}
+		p.deny = append(p.deny, prefix.Masked())
+		if prefix.Addr().Is4() {
+			deny4 = append(deny4, prefix.Masked())
+		} else {
+			deny6 = append(deny6, prefix.Masked())
+		}
+	}
+	opts := []ssrf.Option{
+		ssrf.WithAllowedV4Prefixes(allow4...), ssrf.WithAllowedV6Prefixes(allow6...),
+		ssrf.WithDeniedV4Prefixes(deny4...), ssrf.WithDeniedV6Prefixes(deny6...),
+	}
+	if len(p.Ports) > 0 {
+		opts = append(opts, ssrf.WithPorts(p.Ports...))
+	}
+	p.guard = ssrf.New(opts...)
+
+	if proxyURL != "" {
+		u, err := url.Parse(proxyURL)
+		if err != nil || u.Host == "" {
+			return nil, fmt.Errorf("parse proxy_url [%s] failed: expect scheme://host:port", proxyURL)
+		}
+		switch u.Scheme {
+		case "http", "https", "socks5":
+		default:
+			return nil, fmt.Errorf("unsupported proxy_url scheme %s: expect http, https or socks5", u.Scheme)
+		}
+		p.proxy = u
+	}
+	return p, nil
+}
+
+// checkAddr is the dial check of an ip:port. WebPackerEnablePrivateNet still lifts the
+// built-in guard, but never the deny CIDRs.
+func (p *NetworkPolicy) checkAddr(network, address string) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: could not parse %s: %s", ssrf.ErrInvalidHostPort, address, err)
+	}
+	ip := addrPort.Addr().Unmap()
+	for _, prefix := range p.deny {
+		if prefix.Contains(ip) {
+			return fmt.Errorf("%w: %s is denied by %s", ssrf.ErrProhibitedIP, ip, prefix)
+		}
+	}
+	if enablePrivateNet {
+		return nil
+	}
+	return p.guard.Safe(network, address, nil)
+}
+
+// CheckURL validates the scheme and the resolved addresses of rawURL. The dialer checks every
+// connection again, except through a proxy, where this is the only check.
+func (p *NetworkPolicy) CheckURL(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	return p.checkURL(ctx, u)
+}
+
+func (p *NetworkPolicy) checkURL(ctx context.Context, u *url.URL) error {
+	if !slices.Contains(p.Schemes, u.Scheme) {
+		return fmt.Errorf("scheme %s of %s is not allowed", u.Scheme, u.Redacted())
+	}
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", u.Hostname())
+	if err != nil {
+		return fmt.Errorf("resolve %s failed: %w", u.Hostname(), err)
+	}
+	for _, addr := range addrs {
+		network := "tcp4"
+		if addr.Unmap().Is6() {
+			network = "tcp6"
+		}
+		if err = p.checkAddr(network, net.JoinHostPort(addr.Unmap().String(), port)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Client returns an HTTP client that enforces the policy on every connection and redirect.
+func (p *NetworkPolicy) Client(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	transport := &http.Transport{
+		DialContext:         dialer.DialContext,
+		ForceAttemptHTTP2:   true,
+		TLSHandshakeTimeout: 10 * time.Second,
+	}
+	if p.proxy != nil {
+		// the proxy dials the target, checkURL covers it instead
+		transport.Proxy = http.ProxyURL(p.proxy)
+	} else {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			return p.checkAddr(network, address)
+		}
+	}
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > *p.MaxRedirects {
+				return fmt.Errorf("stopped after %d redirects", *p.MaxRedirects)
+			}
+			return p.checkRequestURL(req.Context(), req.URL)
+		},
+	}
+}
+
+func (p *NetworkPolicy) checkRequestURL(ctx context.Context, u *url.URL) error {
+	if p.proxy != nil {
+		return p.checkURL(ctx, u)
+	}
+	if !slices.Contains(p.Schemes, u.Scheme) {
+		return fmt.Errorf("scheme %s of %s is not allowed", u.Scheme, u.Redacted())
+	}
+	return nil
+}
+
+// Fetch reads the body of rawURL through the policy client with the headers of the options.
+func (p *NetworkPolicy) Fetch(ctx context.Context, rawURL string, options ...Option) ([]byte, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if err = p.checkRequestURL(ctx, u); err != nil {
+		return nil, err
+	}
+	opt := packer.Option{URL: rawURL, Headers: make(map[string]string)}
+	for _, option := range options {
+		option(&opt)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.6 Safari/605.1.15")
+	req.Header.Set("Referer", rawURL)
+	for k, v := range opt.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := p.Client(networkFetchTimeout).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	recordPageResponse(ctx, resp)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("status code is %d", resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// Pack is PackFromURL for the html and markdown file types with the page fetched through the policy.
+// The webarchive and mhtml packers download page resources with their own client and are not supported.
+func (p *NetworkPolicy) Pack(ctx context.Context, filename, urlInfo, tgtFileType, outputDir string, clutterFree bool, options ...Option) (string, error) {
+	log := logger.FromContext(ctx)
+	if err := WaitFetch(ctx, urlInfo); err != nil {
+		return "", err
+	}
+	data, err := p.Fetch(ctx, urlInfo, options...)
+	if err != nil {
+		log.Warnw("fetch page with network policy failed", "link", urlInfo, "err", err)
+		return "", fmt.Errorf("pack to web failed: %w", err)
+	}
+
+	return writePage(ctx, data, filename, urlInfo, tgtFileType, outputDir, clutterFree)
+}
+
+// writePage saves a fetched page as html or markdown, the file types that need no page resources.
+func writePage(ctx context.Context, data []byte, filename, urlInfo, tgtFileType, outputDir string, clutterFree bool) (string, error) {
+	var (
+		log = logger.FromContext(ctx)
+		err error
+	)
+	switch tgtFileType {
+	case "html":
+		content := string(data)
+		if clutterFree {
+			content, err = packer.NewHtmlPacker().ReadContent(ctx, packer.Option{
+				URL:         urlInfo,
+				Reader:      io.NopCloser(bytes.NewReader(data)),
+				ClutterFree: true,
+			})
+			if err != nil {
+				return "", fmt.Errorf("pack to web failed: %w", err)
+			}
+		}
+		filePath := path.Join(outputDir, filename+".html")
+		if err = os.WriteFile(filePath, []byte(content), 0644); err != nil {
+			return "", err
+		}
+		return filePath, nil
+	case "markdown":
+		htmlPath := path.Join(outputDir, "."+filename+".html")
+		if err = os.WriteFile(htmlPath, data, 0644); err != nil {
+			return "", err
+		}
+		defer os.Remove(htmlPath)
+		filePath := path.Join(outputDir, filename+".md")
+		if err = htmlToMarkdownFile(htmlPath, filePath, urlInfo, time.Now()); err != nil {
+			log.Warnw("convert html to markdown failed", "link", urlInfo, "err", err)
+			return "", fmt.Errorf("convert to markdown failed: %w", err)
+		}
+		return filePath, nil
+	default:
+		return "", fmt.Errorf("unsupported file type %s", tgtFileType)
+	}
+}
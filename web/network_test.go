@@ -0,0 +1,224 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package web
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"code.dny.dev/ssrf"
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/types"
+)
+
+func serverPort(t *testing.T, server *httptest.Server) string {
+	t.Helper()
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("parse server url failed: %v", err)
+	}
+	return u.Port()
+}
+
+func newPolicySite(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = fmt.Fprintf(w, "<html><head><title>Page</title></head><body><article><h1>Page</h1><p>cookie=%s</p></article></body></html>", r.Header.Get("Cookie"))
+	})
+	mux.HandleFunc("/hop/", func(w http.ResponseWriter, r *http.Request) {
+		n, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/hop/"))
+		if n <= 0 {
+			http.Redirect(w, r, "/page", http.StatusFound)
+			return
+		}
+		http.Redirect(w, r, fmt.Sprintf("/hop/%d", n-1), http.StatusFound)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestParseNetworkPolicy(t *testing.T) {
+	policy, err := ParseNetworkPolicy("", "")
+	if err != nil || policy != nil {
+		t.Fatalf("expect no policy, got %v, %v", policy, err)
+	}
+	policy, err = ParseNetworkPolicy(`{"allow_cidrs":["10.0.0.0/8"]}`, "")
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if *policy.MaxRedirects != defaultMaxRedirects || len(policy.Schemes) != 2 {
+		t.Errorf("unexpected defaults %+v", policy)
+	}
+	for _, c := range []struct{ raw, proxy string }{
+		{`{"allow_cidrs":["10.0.0.0"]}`, ""},
+		{`{"deny_cidrs":["nope"]}`, ""},
+		{`{"schemes":["ftp"]}`, ""},
+		{`{"max_redirects":-1}`, ""},
+		{`not json`, ""},
+		{"", "ftp://proxy:21"},
+		{"", "proxy"},
+	} {
+		if _, err = ParseNetworkPolicy(c.raw, c.proxy); err == nil {
+			t.Errorf("expect error for %q %q", c.raw, c.proxy)
+		}
+	}
+}
+
+func TestNetworkPolicy_Fetch(t *testing.T) {
+	defer func(orig bool) { enablePrivateNet = orig }(enablePrivateNet)
+	enablePrivateNet = false
+	server := newPolicySite(t)
+	port := serverPort(t, server)
+	ctx := context.Background()
+
+	blocked, _ := ParseNetworkPolicy(`{"ports":[`+port+`]}`, "")
+	if _, err := blocked.Fetch(ctx, server.URL+"/page"); !errors.Is(err, ssrf.ErrProhibitedIP) {
+		t.Errorf("loopback should be blocked by default, got %v", err)
+	}
+
+	allowed, _ := ParseNetworkPolicy(`{"allow_cidrs":["127.0.0.0/8"],"ports":[`+port+`]}`, "")
+	data, err := allowed.Fetch(ctx, server.URL+"/page", Credential{Cookie: "a=1"}.Option())
+	if err != nil {
+		t.Fatalf("fetch with allow cidr failed: %v", err)
+	}
+	if !strings.Contains(string(data), "cookie=a=1") {
+		t.Errorf("option headers not sent: %s", data)
+	}
+	if _, err = allowed.Fetch(ctx, server.URL+"/hop/3"); err != nil {
+		t.Errorf("redirects within the limit should be followed: %v", err)
+	}
+
+	denied, _ := ParseNetworkPolicy(`{"allow_cidrs":["127.0.0.0/8"],"deny_cidrs":["127.0.0.1/32"],"ports":[`+port+`]}`, "")
+	enablePrivateNet = true
+	if _, err = denied.Fetch(ctx, server.URL+"/page"); !errors.Is(err, ssrf.ErrProhibitedIP) {
+		t.Errorf("deny cidr should win over allow cidr and the env, got %v", err)
+	}
+	enablePrivateNet = false
+
+	httpsOnly, _ := ParseNetworkPolicy(`{"allow_cidrs":["127.0.0.0/8"],"ports":[`+port+`],"schemes":["https"]}`, "")
+	if _, err = httpsOnly.Fetch(ctx, server.URL+"/page"); err == nil || !strings.Contains(err.Error(), "scheme http") {
+		t.Errorf("expect scheme error, got %v", err)
+	}
+
+	oneHop, _ := ParseNetworkPolicy(`{"allow_cidrs":["127.0.0.0/8"],"ports":[`+port+`],"max_redirects":1}`, "")
+	if _, err = oneHop.Fetch(ctx, server.URL+"/hop/0"); err != nil {
+		t.Errorf("one redirect should be followed: %v", err)
+	}
+	if _, err = oneHop.Fetch(ctx, server.URL+"/hop/2"); err == nil || !strings.Contains(err.Error(), "redirects") {
+		t.Errorf("expect redirect limit error, got %v", err)
+	}
+}
+
+func TestNetworkPolicy_Proxy(t *testing.T) {
+	defer func(orig bool) { enablePrivateNet = orig }(enablePrivateNet)
+	enablePrivateNet = false
+
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = append(proxied, r.URL.String())
+		_, _ = w.Write([]byte("<html><body>via proxy</body></html>"))
+	}))
+	defer proxy.Close()
+
+	policy, err := ParseNetworkPolicy("", proxy.URL)
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	data, err := policy.Fetch(context.Background(), "http://93.184.216.34/page")
+	if err != nil || string(data) != "<html><body>via proxy</body></html>" {
+		t.Fatalf("fetch through proxy failed: %q, %v", data, err)
+	}
+	if len(proxied) != 1 || proxied[0] != "http://93.184.216.34/page" {
+		t.Errorf("request not sent through proxy: %v", proxied)
+	}
+	// the target is still checked although the proxy dials it
+	if _, err = policy.Fetch(context.Background(), "http://127.0.0.1/page"); !errors.Is(err, ssrf.ErrProhibitedIP) {
+		t.Errorf("private target should be blocked through the proxy, got %v", err)
+	}
+}
+
+func TestWebpackPlugin_NetworkPolicy(t *testing.T) {
+	defer func(orig bool) { enablePrivateNet = orig }(enablePrivateNet)
+	enablePrivateNet = false
+	server := newPolicySite(t)
+	config := map[string]string{webpackConfigNetworkPolicy: `{"allow_cidrs":["127.0.0.0/8"],"ports":[` + serverPort(t, server) + `]}`}
+
+	for _, fileType := range []string{"html", "markdown"} {
+		workdir := t.TempDir()
+		p := NewWebpackPlugin(types.PluginCall{
+			WorkingPath: workdir,
+			Config:      config,
+			Params:      map[string]string{webpackParameterFileType: fileType},
+		}).(*WebpackPlugin)
+		resp, err := p.Run(context.Background(), &api.Request{Parameter: map[string]any{
+			webpackParameterFileName: "page",
+			webpackParameterURL:      server.URL + "/page",
+		}})
+		if err != nil || !resp.IsSucceed {
+			t.Fatalf("%s: run failed: %v %v", fileType, err, resp)
+		}
+		data, err := os.ReadFile(resp.Results["file_path"].(string))
+		if err != nil || !strings.Contains(string(data), "Page") {
+			t.Errorf("%s: unexpected output %q, %v", fileType, data, err)
+		}
+		if matches, _ := filepath.Glob(filepath.Join(workdir, ".*")); len(matches) != 0 {
+			t.Errorf("%s: temporary files left: %v", fileType, matches)
+		}
+	}
+
+	p := NewWebpackPlugin(types.PluginCall{WorkingPath: t.TempDir(), Config: config}).(*WebpackPlugin)
+	if _, err := p.Run(context.Background(), &api.Request{Parameter: map[string]any{
+		webpackParameterFileName: "page",
+		webpackParameterURL:      server.URL + "/page",
+	}}); err == nil {
+		t.Errorf("webarchive should be rejected with a network policy")
+	}
+
+	for _, fileType := range []string{"html", "png", "pdf"} {
+		p = NewWebpackPlugin(types.PluginCall{
+			WorkingPath: t.TempDir(),
+			Config:      map[string]string{webpackConfigNetworkPolicy: `{"deny_cidrs":["10.0.0.0/8"]}`, webpackConfigBrowserURL: server.URL},
+			Params:      map[string]string{webpackParameterFileType: fileType},
+		}).(*WebpackPlugin)
+		if _, err := p.Run(context.Background(), &api.Request{Parameter: map[string]any{
+			webpackParameterFileName: "page",
+			webpackParameterURL:      "https://example.com/page",
+			webpackParameterRender:   RenderBrowser,
+		}}); err == nil || !strings.Contains(err.Error(), webpackConfigNetworkPolicy) {
+			t.Errorf("%s: a network policy should be rejected with render browser, got %v", fileType, err)
+		}
+	}
+
+	p = NewWebpackPlugin(types.PluginCall{WorkingPath: t.TempDir(), Config: map[string]string{webpackConfigNetworkPolicy: "{"}}).(*WebpackPlugin)
+	if _, err := p.Run(context.Background(), &api.Request{Parameter: map[string]any{
+		webpackParameterFileName: "page",
+		webpackParameterURL:      server.URL + "/page",
+	}}); err == nil {
+		t.Errorf("an invalid policy should fail the run")
+	}
+}
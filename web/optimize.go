@@ -0,0 +1,262 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package web
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/hyponet/webpage-packer/packer"
+	"howett.net/plist"
+)
+
+const (
+	webpackParameterDedupAssets  = "dedup_assets"
+	webpackParameterImageQuality = "image_quality"
+)
+
+// ArchiveOptimization shrinks webarchive files after packing.
+type ArchiveOptimization struct {
+	Dedup        bool
+	ImageQuality int // JPEG quality 1-100 images are recompressed with, 0 keeps them as downloaded
+}
+
+// ArchiveSavings is what an optimization removed from a webarchive.
+type ArchiveSavings struct {
+	Deduplicated int   `json:"deduplicated_assets"`
+	Recompressed int   `json:"recompressed_images"`
+	SavedBytes   int64 `json:"saved_bytes"`
+}
+
+// ParseArchiveOptimization reads dedup_assets (default true) and image_quality.
+func ParseArchiveOptimization(dedup, quality string) (ArchiveOptimization, error) {
+	opt := ArchiveOptimization{Dedup: true}
+	if dedup != "" {
+		v, err := strconv.ParseBool(dedup)
+		if err != nil {
+			return opt, fmt.Errorf("invalid %s [%s]: expect true or false", webpackParameterDedupAssets, dedup)
+		}
+		opt.Dedup = v
+	}
+	if quality != "" {
+		q, err := strconv.Atoi(quality)
+		if err != nil || q < 1 || q > 100 {
+			return opt, fmt.Errorf("invalid %s [%s]: expect 1 to 100", webpackParameterImageQuality, quality)
+		}
+		opt.ImageQuality = q
+	}
+	return opt, nil
+}
+
+func (o ArchiveOptimization) enabled() bool {
+	return o.Dedup || o.ImageQuality > 0
+}
+
+// OptimizeWebArchive rewrites the webarchive at filePath with duplicate assets removed and images
+// recompressed. The file is left untouched when nothing can be saved.
+func OptimizeWebArchive(filePath string, opt ArchiveOptimization) (ArchiveSavings, error) {
+	var savings ArchiveSavings
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return savings, err
+	}
+	archive := &packer.WebArchive{}
+	if _, err = plist.Unmarshal(data, archive); err != nil {
+		return savings, fmt.Errorf("load webarchive failed: %w", err)
+	}
+
+	if opt.Dedup {
+		savings.Deduplicated = dedupResources(archive)
+	}
+	if opt.ImageQuality > 0 {
+		for i := range archive.WebSubresources {
+			res := &archive.WebSubresources[i]
+			if smaller, ok := recompressImage(res.WebResourceData, res.WebResourceMIMEType, opt.ImageQuality); ok {
+				res.WebResourceData = smaller
+				savings.Recompressed++
+			}
+		}
+	}
+	if savings.Deduplicated == 0 && savings.Recompressed == 0 {
+		return savings, nil
+	}
+
+	buf := &bytes.Buffer{}
+	if err = plist.NewBinaryEncoder(buf).Encode(archive); err != nil {
+		return savings, fmt.Errorf("encode webarchive failed: %w", err)
+	}
+	if buf.Len() >= len(data) {
+		return ArchiveSavings{}, nil
+	}
+	if err = os.WriteFile(filePath, buf.Bytes(), 0644); err != nil {
+		return savings, fmt.Errorf("write webarchive failed: %w", err)
+	}
+	savings.SavedBytes = int64(len(data) - buf.Len())
+	return savings, nil
+}
+
+// dedupResources drops repeated URLs, and assets whose content equals an earlier asset once the page
+// references the earlier one instead. An asset that may still be referenced from a stylesheet, a script
+// or the page is kept.
+func dedupResources(archive *packer.WebArchive) int {
+	var (
+		seenURL   = map[string]bool{}
+		canonical = map[[32]byte]string{}
+		dups      = map[string]string{}
+		kept      []packer.WebResourceItem
+		removed   int
+	)
+	for _, res := range archive.WebSubresources {
+		if seenURL[res.WebResourceURL] {
+			removed++
+			continue
+		}
+		seenURL[res.WebResourceURL] = true
+		sum := sha256.Sum256(res.WebResourceData)
+		if first, ok := canonical[sum]; ok && len(res.WebResourceData) > 0 {
+			dups[res.WebResourceURL] = first
+		} else {
+			canonical[sum] = res.WebResourceURL
+		}
+		kept = append(kept, res)
+	}
+	if len(dups) == 0 {
+		archive.WebSubresources = kept
+		return removed
+	}
+
+	page, rewritten := rewriteDuplicateRefs(archive.WebMainResource, dups)
+	if len(rewritten) > 0 {
+		archive.WebMainResource.WebResourceData = page
+	}
+	var texts []string
+	texts = append(texts, string(archive.WebMainResource.WebResourceData))
+	for _, res := range kept {
+		if isTextResource(res.WebResourceMIMEType) {
+			texts = append(texts, string(res.WebResourceData))
+		}
+	}
+
+	archive.WebSubresources = kept[:0]
+	for _, res := range kept {
+		if _, dup := dups[res.WebResourceURL]; dup && rewritten[res.WebResourceURL] && !referenced(res.WebResourceURL, texts) {
+			removed++
+			continue
+		}
+		archive.WebSubresources = append(archive.WebSubresources, res)
+	}
+	return removed
+}
+
+// rewriteDuplicateRefs points the page elements loading a duplicate asset to the asset kept instead.
+func rewriteDuplicateRefs(main packer.WebResourceItem, dups map[string]string) ([]byte, map[string]bool) {
+	base, err := url.Parse(main.WebResourceURL)
+	if err != nil {
+		return nil, nil
+	}
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(main.WebResourceData))
+	if err != nil {
+		return nil, nil
+	}
+	rewritten := map[string]bool{}
+	doc.Find("img[src], script[src], link[href], source[src], video[poster]").Each(func(_ int, s *goquery.Selection) {
+		for _, attr := range []string{"src", "href", "poster"} {
+			raw, ok := s.Attr(attr)
+			if !ok {
+				continue
+			}
+			u, err := url.Parse(strings.TrimSpace(raw))
+			if err != nil {
+				continue
+			}
+			resolved := base.ResolveReference(u).String()
+			if first, dup := dups[resolved]; dup {
+				s.SetAttr(attr, first)
+				rewritten[resolved] = true
+			}
+		}
+	})
+	if len(rewritten) == 0 {
+		return nil, nil
+	}
+	html, err := doc.Html()
+	if err != nil {
+		return nil, nil
+	}
+	return []byte(html), rewritten
+}
+
+// referenced reports whether a text may still mention the asset by its file name and query.
+func referenced(rawURL string, texts []string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return true
+	}
+	name := path.Base(u.Path)
+	if name == "/" || name == "." {
+		return true
+	}
+	if u.RawQuery != "" {
+		name += "?" + u.RawQuery
+	}
+	for _, text := range texts {
+		if strings.Contains(text, name) || strings.Contains(text, strings.ReplaceAll(name, "&", "&amp;")) {
+			return true
+		}
+	}
+	return false
+}
+
+func isTextResource(mimeType string) bool {
+	return strings.HasPrefix(mimeType, "text/") || strings.Contains(mimeType, "javascript") ||
+		strings.Contains(mimeType, "json") || strings.Contains(mimeType, "xml")
+}
+
+// recompressImage encodes JPEG images at quality and PNG images at the best compression level,
+// it reports false when the result is not smaller.
+func recompressImage(data []byte, mimeType string, quality int) ([]byte, bool) {
+	if mimeType != "image/jpeg" && mimeType != "image/png" {
+		return nil, false
+	}
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, false
+	}
+	buf := &bytes.Buffer{}
+	switch format {
+	case "jpeg":
+		err = jpeg.Encode(buf, img, &jpeg.Options{Quality: quality})
+	case "png":
+		err = (&png.Encoder{CompressionLevel: png.BestCompression}).Encode(buf, img)
+	default:
+		return nil, false
+	}
+	if err != nil || buf.Len() >= len(data) {
+		return nil, false
+	}
+	return buf.Bytes(), true
+}
@@ -0,0 +1,127 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package web
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hyponet/webpage-packer/packer"
+	"howett.net/plist"
+)
+
+func TestParseArchiveOptimization(t *testing.T) {
+	opt, err := ParseArchiveOptimization("", "")
+	if err != nil || !opt.Dedup || opt.ImageQuality != 0 {
+		t.Errorf("defaults = %+v, %v", opt, err)
+	}
+	opt, err = ParseArchiveOptimization("false", "75")
+	if err != nil || opt.Dedup || opt.ImageQuality != 75 {
+		t.Errorf("parsed = %+v, %v", opt, err)
+	}
+	for _, tt := range [][2]string{{"maybe", ""}, {"", "0"}, {"", "101"}, {"", "high"}} {
+		if _, err = ParseArchiveOptimization(tt[0], tt[1]); err == nil {
+			t.Errorf("expected error for %v", tt)
+		}
+	}
+}
+
+func testJPEG(t *testing.T) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	for x := 0; x < 64; x++ {
+		for y := 0; y < 64; y++ {
+			img.Set(x, y, color.RGBA{R: uint8(x * 4), G: uint8(y * 4), B: uint8((x * y) % 256), A: 255})
+		}
+	}
+	buf := &bytes.Buffer{}
+	if err := jpeg.Encode(buf, img, &jpeg.Options{Quality: 100}); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestOptimizeWebArchive(t *testing.T) {
+	var (
+		logo  = []byte("same logo bytes")
+		photo = testJPEG(t)
+	)
+	archive := &packer.WebArchive{
+		WebMainResource: packer.WebResourceItem{
+			WebResourceURL:      "https://example.com/page",
+			WebResourceMIMEType: packer.MIMEHTML,
+			WebResourceData: []byte(`<html><head><link rel="stylesheet" href="/style.css"></head><body>
+<img src="/logo.png?v=1"><img src="/logo.png?v=2"><img src="/photo.jpg"></body></html>`),
+		},
+		WebSubresources: []packer.WebResourceItem{
+			{WebResourceURL: "https://example.com/style.css", WebResourceMIMEType: "text/css", WebResourceData: []byte(`div { background: url(bg.png); }`)},
+			{WebResourceURL: "https://example.com/logo.png?v=1", WebResourceMIMEType: "image/png", WebResourceData: logo},
+			{WebResourceURL: "https://example.com/logo.png?v=2", WebResourceMIMEType: "image/png", WebResourceData: logo},
+			{WebResourceURL: "https://example.com/bg.png", WebResourceMIMEType: "image/png", WebResourceData: logo},
+			{WebResourceURL: "https://example.com/photo.jpg", WebResourceMIMEType: "image/jpeg", WebResourceData: photo},
+			{WebResourceURL: "https://example.com/photo.jpg", WebResourceMIMEType: "image/jpeg", WebResourceData: photo},
+		},
+	}
+	filePath := filepath.Join(t.TempDir(), "page.webarchive")
+	output, err := os.Create(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = plist.NewBinaryEncoder(output).Encode(archive); err != nil {
+		t.Fatal(err)
+	}
+	_ = output.Close()
+	before, _ := os.Stat(filePath)
+
+	savings, err := OptimizeWebArchive(filePath, ArchiveOptimization{Dedup: true, ImageQuality: 60})
+	if err != nil {
+		t.Fatalf("optimize failed: %v", err)
+	}
+	// the repeated photo.jpg and logo.png?v=2, bg.png is still used by the stylesheet
+	if savings.Deduplicated != 2 || savings.Recompressed != 1 {
+		t.Errorf("savings = %+v", savings)
+	}
+	after, _ := os.Stat(filePath)
+	if savings.SavedBytes != before.Size()-after.Size() || savings.SavedBytes <= 0 {
+		t.Errorf("saved %d bytes, file shrank by %d", savings.SavedBytes, before.Size()-after.Size())
+	}
+
+	page, resources, err := readArchive(filePath)
+	if err != nil {
+		t.Fatalf("read archive failed: %v", err)
+	}
+	if strings.Contains(page, "logo.png?v=2") || !strings.Contains(page, "https://example.com/logo.png?v=1") {
+		t.Errorf("duplicate reference not rewritten: %s", page)
+	}
+	for _, u := range []string{"style.css", "logo.png?v=1", "bg.png", "photo.jpg"} {
+		if _, ok := resources["https://example.com/"+u]; !ok {
+			t.Errorf("expected %s in archive", u)
+		}
+	}
+	if res := resources["https://example.com/photo.jpg"]; res.Size >= len(photo) {
+		t.Errorf("photo not recompressed: %d >= %d", res.Size, len(photo))
+	}
+
+	if savings, err = OptimizeWebArchive(filePath, ArchiveOptimization{Dedup: true}); err != nil || savings != (ArchiveSavings{}) {
+		t.Errorf("second optimization = %+v, %v", savings, err)
+	}
+}
@@ -0,0 +1,78 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package web
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	defaultPDFPageSize = "A4"
+	defaultPDFMargin   = "1cm"
+)
+
+var (
+	pdfPageSizes = []string{"A3", "A4", "A5", "Letter", "Legal", "Tabloid"}
+	pdfMarginRe  = regexp.MustCompile(`^\d+(\.\d+)?(px|in|cm|mm)?$`)
+)
+
+// PDFLayout is the paper of a printed page, Margin is ordered top, right, bottom, left.
+type PDFLayout struct {
+	PageSize string
+	Margin   [4]string
+}
+
+// ParsePDFLayout reads the page size and a CSS-like margin of one to four values, e.g. "1cm" or "10mm 15mm".
+func ParsePDFLayout(pageSize, margin string) (PDFLayout, error) {
+	var layout PDFLayout
+	if pageSize == "" {
+		pageSize = defaultPDFPageSize
+	}
+	for _, size := range pdfPageSizes {
+		if strings.EqualFold(size, pageSize) {
+			layout.PageSize = size
+		}
+	}
+	if layout.PageSize == "" {
+		return layout, fmt.Errorf("invalid pdf page size [%s], expect one of %s", pageSize, strings.Join(pdfPageSizes, ", "))
+	}
+
+	if strings.TrimSpace(margin) == "" {
+		margin = defaultPDFMargin
+	}
+	values := strings.FieldsFunc(margin, func(r rune) bool { return r == ' ' || r == ',' })
+	for _, v := range values {
+		if !pdfMarginRe.MatchString(v) {
+			return layout, fmt.Errorf("invalid pdf margin [%s]", margin)
+		}
+	}
+	switch len(values) {
+	case 1:
+		layout.Margin = [4]string{values[0], values[0], values[0], values[0]}
+	case 2:
+		layout.Margin = [4]string{values[0], values[1], values[0], values[1]}
+	case 3:
+		layout.Margin = [4]string{values[0], values[1], values[2], values[1]}
+	case 4:
+		layout.Margin = [4]string{values[0], values[1], values[2], values[3]}
+	default:
+		return layout, fmt.Errorf("invalid pdf margin [%s], expect one to four values", margin)
+	}
+	return layout, nil
+}
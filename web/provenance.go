@@ -0,0 +1,77 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package web
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// CaptureInfo is the HTTP provenance of a packed page. The response fields are only known when
+// webpack fetches the page itself, with a fetch control or a network policy.
+type CaptureInfo struct {
+	FinalURL    string
+	StatusCode  int
+	ContentType string
+	Duration    time.Duration
+
+	mu sync.Mutex
+}
+
+type captureInfoKey struct{}
+
+func withCaptureInfo(ctx context.Context) (context.Context, *CaptureInfo) {
+	info := &CaptureInfo{}
+	return context.WithValue(ctx, captureInfoKey{}, info), info
+}
+
+// recordPageResponse keeps the final URL, status and content type of the page response.
+func recordPageResponse(ctx context.Context, resp *http.Response) {
+	info, _ := ctx.Value(captureInfoKey{}).(*CaptureInfo)
+	if info == nil {
+		return
+	}
+	info.mu.Lock()
+	defer info.mu.Unlock()
+	info.FinalURL = resp.Request.URL.String()
+	info.StatusCode = resp.StatusCode
+	info.ContentType = resp.Header.Get("Content-Type")
+}
+
+// addResult adds the provenance to the result of a packed page.
+func (i *CaptureInfo) addResult(result map[string]any) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	result["duration_ms"] = i.Duration.Milliseconds()
+	if i.StatusCode == 0 {
+		return
+	}
+	result["final_url"] = i.FinalURL
+	result["status_code"] = i.StatusCode
+	result["content_type"] = i.ContentType
+}
+
+// countAssets returns the resources stored in a webarchive or mhtml file besides the page.
+func countAssets(filePath string) (int, bool) {
+	_, resources, err := readArchive(filePath)
+	if err != nil || resources == nil {
+		return 0, false
+	}
+	return max(len(resources)-1, 0), true
+}
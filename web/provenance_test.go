@@ -0,0 +1,63 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package web
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/types"
+)
+
+func TestWebpackPlugin_CaptureInfo(t *testing.T) {
+	server, _, _ := newCaptureServer()
+	defer server.Close()
+
+	origin := enablePrivateNet
+	enablePrivateNet = true
+	defer func() { enablePrivateNet = origin }()
+
+	p := NewWebpackPlugin(types.PluginCall{
+		WorkingPath: t.TempDir(),
+		Params:      map[string]string{"file_type": "webarchive", "clutter_free": "false"},
+	}).(*WebpackPlugin)
+
+	resp, err := p.Run(context.Background(), &api.Request{Parameter: map[string]any{
+		"file_name": "info",
+		"url":       server.URL + "/moved",
+		"timeout":   "200ms",
+	}})
+	if err != nil || !resp.IsSucceed {
+		t.Fatalf("Run failed: %v %v", err, resp)
+	}
+	results := resp.Results
+	if results["url"] != server.URL+"/moved" || results["final_url"] != server.URL+"/page" {
+		t.Errorf("url = %v, final_url = %v", results["url"], results["final_url"])
+	}
+	if results["status_code"] != http.StatusOK || results["content_type"] != "text/html; charset=utf-8" {
+		t.Errorf("status_code = %v, content_type = %v", results["status_code"], results["content_type"])
+	}
+	if _, ok := results["duration_ms"].(int64); !ok {
+		t.Errorf("expected duration_ms, got %v", results["duration_ms"])
+	}
+	// style.css and ok.png are stored; flaky.png, slow.png and missing.png fail without retries
+	if results["asset_count"] != 2 || results["failed_asset_count"] != 3 {
+		t.Errorf("asset_count = %v, failed_asset_count = %v", results["asset_count"], results["failed_asset_count"])
+	}
+}
@@ -0,0 +1,159 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package web
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/hyponet/webpage-packer/packer"
+)
+
+const (
+	RenderHTTP    = "http"
+	RenderBrowser = "browser"
+
+	webpackConfigBrowserURL   = "webpack_browser_url"
+	webpackConfigBrowserToken = "webpack_browser_token"
+
+	captureTimeout = 120 * time.Second
+)
+
+// BrowserRenderer is a headless Chromium service exposing the browserless /content API.
+// Pages are loaded and their scripts executed there before packing.
+type BrowserRenderer struct {
+	Endpoint string
+	Token    string
+}
+
+// NewBrowserRenderer reads the renderer from the config, falling back to the WebPackerBrowserless* environment.
+func NewBrowserRenderer(config map[string]string) BrowserRenderer {
+	r := BrowserRenderer{Endpoint: config[webpackConfigBrowserURL], Token: config[webpackConfigBrowserToken]}
+	if r.Endpoint == "" {
+		r.Endpoint, r.Token = browserlessURL, browserlessToken
+	}
+	return r
+}
+
+// Option makes the packer fetch the page through the headless browser.
+func (r BrowserRenderer) Option() (Option, error) {
+	if r.Endpoint == "" {
+		return nil, fmt.Errorf("render %s requires %s config or WebPackerBrowserlessURL env", RenderBrowser, webpackConfigBrowserURL)
+	}
+	return func(option *packer.Option) {
+		option.Browserless = &packer.Browserless{
+			Endpoint:    r.Endpoint,
+			Token:       r.Token,
+			StealthMode: true,
+			BlockADS:    true,
+		}
+	}, nil
+}
+
+func usesBrowser(options []Option) bool {
+	opt := packer.Option{Headers: make(map[string]string)}
+	for _, option := range options {
+		option(&opt)
+	}
+	return opt.Browserless != nil
+}
+
+// Screenshot saves a full-page PNG of the URL to filePath. Options contribute their request headers.
+func (r BrowserRenderer) Screenshot(ctx context.Context, urlInfo, filePath string, options ...Option) error {
+	return r.capture(ctx, "screenshot", urlInfo, filePath, map[string]any{"fullPage": true, "type": "png"}, options)
+}
+
+// PDF prints the URL to a paginated PDF at filePath. Options contribute their request headers.
+func (r BrowserRenderer) PDF(ctx context.Context, urlInfo, filePath string, layout PDFLayout, options ...Option) error {
+	return r.capture(ctx, "pdf", urlInfo, filePath, map[string]any{
+		"format":          layout.PageSize,
+		"printBackground": true,
+		"margin": map[string]string{
+			"top":    layout.Margin[0],
+			"right":  layout.Margin[1],
+			"bottom": layout.Margin[2],
+			"left":   layout.Margin[3],
+		},
+	}, options)
+}
+
+// capture calls the browserless API that renders the page into a file, e.g. /screenshot or /pdf.
+func (r BrowserRenderer) capture(ctx context.Context, api, urlInfo, filePath string, captureOptions map[string]any, options []Option) error {
+	if r.Endpoint == "" {
+		return fmt.Errorf("%s requires %s config or WebPackerBrowserlessURL env", api, webpackConfigBrowserURL)
+	}
+	if err := WaitFetch(ctx, urlInfo); err != nil {
+		return err
+	}
+	apiURL, err := url.Parse(r.Endpoint)
+	if err != nil {
+		return fmt.Errorf("parse browser endpoint failed: %w", err)
+	}
+	query := url.Values{}
+	query.Set("blockAds", "true")
+	if r.Token != "" {
+		query.Set("token", r.Token)
+	}
+	apiURL.Path = "/" + api
+	apiURL.RawQuery = query.Encode()
+
+	opt := packer.Option{URL: urlInfo, Headers: make(map[string]string)}
+	for _, option := range options {
+		option(&opt)
+	}
+	body, err := json.Marshal(map[string]any{
+		"url":                 urlInfo,
+		"setExtraHTTPHeaders": opt.Headers,
+		"gotoOptions":         map[string]any{"timeout": captureTimeout.Milliseconds(), "waitUntil": "networkidle2"},
+		"options":             captureOptions,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL.String(), bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("build %s request failed: %w", api, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := (&http.Client{Timeout: captureTimeout}).Do(req)
+	if err != nil {
+		return fmt.Errorf("%s %s failed: %w", api, urlInfo, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s %s failed: status code is %d %s", api, urlInfo, resp.StatusCode, msg)
+	}
+
+	output, err := os.OpenFile(filePath, os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("open output file failed: %w", err)
+	}
+	defer output.Close()
+	if _, err = io.Copy(output, resp.Body); err != nil {
+		return fmt.Errorf("write %s failed: %w", api, err)
+	}
+	return nil
+}
@@ -0,0 +1,301 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/types"
+)
+
+func TestNewBrowserRenderer(t *testing.T) {
+	r := NewBrowserRenderer(map[string]string{
+		webpackConfigBrowserURL:   "http://chrome:3000",
+		webpackConfigBrowserToken: "secret",
+	})
+	if r.Endpoint != "http://chrome:3000" || r.Token != "secret" {
+		t.Errorf("unexpected renderer %+v", r)
+	}
+
+	r = NewBrowserRenderer(nil)
+	if r.Endpoint != browserlessURL || r.Token != browserlessToken {
+		t.Errorf("expected env fallback, got %+v", r)
+	}
+}
+
+func TestBrowserRenderer_OptionRequiresEndpoint(t *testing.T) {
+	if _, err := (BrowserRenderer{}).Option(); err == nil {
+		t.Error("expected error without endpoint")
+	}
+}
+
+func TestWebpackPlugin_RenderBrowser(t *testing.T) {
+	var (
+		gotPath  string
+		gotToken string
+		gotURL   string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotToken = r.URL.Query().Get("token")
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		gotURL, _ = body["url"].(string)
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte(`<html><head><title>App</title></head><body><div id="root"><p>rendered by script</p></div></body></html>`))
+	}))
+	defer server.Close()
+
+	workdir := t.TempDir()
+	p := NewWebpackPlugin(types.PluginCall{
+		WorkingPath: workdir,
+		Params: map[string]string{
+			webpackParameterFileType:    "html",
+			webpackParameterClutterFree: "false",
+		},
+		Config: map[string]string{
+			webpackConfigBrowserURL:   server.URL,
+			webpackConfigBrowserToken: "secret",
+		},
+	})
+
+	resp, err := p.(*WebpackPlugin).Run(context.Background(), &api.Request{Parameter: map[string]any{
+		webpackParameterFileName: "app",
+		webpackParameterURL:      "https://spa.example.com/",
+		webpackParameterRender:   RenderBrowser,
+	}})
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	if !resp.IsSucceed {
+		t.Fatalf("expected success, got %s", resp.Message)
+	}
+	if gotPath != "/content" || gotToken != "secret" || gotURL != "https://spa.example.com/" {
+		t.Errorf("unexpected render request path=%s token=%s url=%s", gotPath, gotToken, gotURL)
+	}
+
+	data, err := os.ReadFile(filepath.Join(workdir, "app.html"))
+	if err != nil {
+		t.Fatalf("read packed file failed: %v", err)
+	}
+	if !strings.Contains(string(data), "rendered by script") {
+		t.Errorf("expected rendered content, got %s", data)
+	}
+}
+
+func TestWebpackPlugin_RenderInvalid(t *testing.T) {
+	p := newWebpackPlugin(t)
+	renders := []string{"chrome"}
+	if browserlessURL == "" {
+		renders = append(renders, RenderBrowser)
+	}
+	for _, render := range renders {
+		_, err := p.Run(context.Background(), &api.Request{Parameter: map[string]any{
+			webpackParameterFileName: "app",
+			webpackParameterURL:      "https://spa.example.com/",
+			webpackParameterRender:   render,
+		}})
+		if err == nil {
+			t.Errorf("render %s: expected error", render)
+		}
+	}
+}
+
+func TestWebpackPlugin_Screenshot(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\nfake")
+	var (
+		gotPath string
+		body    map[string]any
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		w.Header().Set("Content-Type", "image/png")
+		_, _ = w.Write(png)
+	}))
+	defer server.Close()
+
+	workdir := t.TempDir()
+	p := NewWebpackPlugin(types.PluginCall{
+		WorkingPath: workdir,
+		Params:      map[string]string{webpackParameterFileType: "png"},
+		Config: map[string]string{
+			webpackConfigBrowserURL:  server.URL,
+			webpackConfigCredentials: `{"example.com": {"cookie": "session=abc"}}`,
+		},
+	}).(*WebpackPlugin)
+
+	req := &api.Request{Parameter: map[string]any{
+		webpackParameterFileName: "dashboard",
+		webpackParameterURL:      "https://app.example.com/dashboard",
+	}}
+	if _, err := p.Run(context.Background(), req); err == nil {
+		t.Fatal("expected error for png without browser render")
+	}
+
+	req.Parameter[webpackParameterRender] = RenderBrowser
+	if _, err := p.Run(context.Background(), req); err == nil || !strings.Contains(err.Error(), "browser capture") {
+		t.Fatalf("expected credentials to be refused with a browser capture, got %v", err)
+	}
+	if gotPath != "" {
+		t.Errorf("expected no browser request with credentials, got %s", gotPath)
+	}
+
+	req.Parameter[webpackParameterURL] = "https://app.other.test/dashboard"
+	resp, err := p.Run(context.Background(), req)
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	if resp.Results["size"] != int64(len(png)) {
+		t.Errorf("expected size %d, got %v", len(png), resp.Results["size"])
+	}
+	if gotPath != "/screenshot" {
+		t.Errorf("expected /screenshot, got %s", gotPath)
+	}
+	options, _ := body["options"].(map[string]any)
+	if options["fullPage"] != true || options["type"] != "png" {
+		t.Errorf("unexpected screenshot options %v", options)
+	}
+	headers, _ := body["setExtraHTTPHeaders"].(map[string]any)
+	if _, ok := headers["Cookie"]; ok {
+		t.Errorf("expected no credential cookie for another domain, got %v", headers)
+	}
+
+	data, err := os.ReadFile(filepath.Join(workdir, "dashboard.png"))
+	if err != nil {
+		t.Fatalf("read screenshot failed: %v", err)
+	}
+	if string(data) != string(png) {
+		t.Errorf("unexpected screenshot content %q", data)
+	}
+}
+
+func TestWebpackPlugin_BrowserRefusesRequestCredentials(t *testing.T) {
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		_, _ = w.Write([]byte("%PDF-1.4 fake"))
+	}))
+	defer server.Close()
+
+	for _, params := range []map[string]any{
+		{webpackParameterFileType: "pdf", webpackParameterCookies: "session=abc"},
+		{webpackParameterFileType: "png", webpackParameterBearerToken: "secret"},
+		{webpackParameterFileType: "html", webpackParameterHostHeaders: `{"example.com": {"X-Api-Key": "secret"}}`},
+	} {
+		p := NewWebpackPlugin(types.PluginCall{
+			WorkingPath: t.TempDir(),
+			Config:      map[string]string{webpackConfigBrowserURL: server.URL},
+		}).(*WebpackPlugin)
+		params[webpackParameterFileName] = "page"
+		params[webpackParameterURL] = "https://example.com/page"
+		params[webpackParameterRender] = RenderBrowser
+		if _, err := p.Run(context.Background(), &api.Request{Parameter: params}); err == nil || !strings.Contains(err.Error(), "browser capture") {
+			t.Errorf("%v: expected request credentials to be refused with a browser capture, got %v", params, err)
+		}
+	}
+	if calls != 0 {
+		t.Errorf("expected no browser request, got %d", calls)
+	}
+}
+
+func TestParsePDFLayout(t *testing.T) {
+	tests := []struct {
+		size, margin string
+		want         PDFLayout
+		wantErr      bool
+	}{
+		{"", "", PDFLayout{PageSize: "A4", Margin: [4]string{"1cm", "1cm", "1cm", "1cm"}}, false},
+		{"letter", "10mm 15mm", PDFLayout{PageSize: "Letter", Margin: [4]string{"10mm", "15mm", "10mm", "15mm"}}, false},
+		{"A5", "1in,0.5in,2in", PDFLayout{PageSize: "A5", Margin: [4]string{"1in", "0.5in", "2in", "0.5in"}}, false},
+		{"A3", "0 1cm 2cm 3cm", PDFLayout{PageSize: "A3", Margin: [4]string{"0", "1cm", "2cm", "3cm"}}, false},
+		{"B5", "", PDFLayout{}, true},
+		{"A4", "1em", PDFLayout{}, true},
+		{"A4", "1 2 3 4 5", PDFLayout{}, true},
+	}
+	for _, tt := range tests {
+		got, err := ParsePDFLayout(tt.size, tt.margin)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParsePDFLayout(%q, %q) error = %v", tt.size, tt.margin, err)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("ParsePDFLayout(%q, %q) = %+v, want %+v", tt.size, tt.margin, got, tt.want)
+		}
+	}
+}
+
+func TestWebpackPlugin_PDF(t *testing.T) {
+	var (
+		gotPath string
+		body    map[string]any
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		w.Header().Set("Content-Type", "application/pdf")
+		_, _ = w.Write([]byte("%PDF-1.4 fake"))
+	}))
+	defer server.Close()
+
+	workdir := t.TempDir()
+	p := NewWebpackPlugin(types.PluginCall{
+		WorkingPath: workdir,
+		Params: map[string]string{
+			webpackParameterFileType:    "pdf",
+			webpackParameterPDFPageSize: "Letter",
+			webpackParameterPDFMargin:   "10mm 20mm",
+		},
+		Config: map[string]string{webpackConfigBrowserURL: server.URL},
+	}).(*WebpackPlugin)
+
+	resp, err := p.Run(context.Background(), &api.Request{Parameter: map[string]any{
+		webpackParameterFileName: "article",
+		webpackParameterURL:      "https://example.com/article",
+		webpackParameterRender:   RenderBrowser,
+	}})
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	if resp.Results["file_path"] != filepath.Join(workdir, "article.pdf") {
+		t.Errorf("unexpected file path %v", resp.Results["file_path"])
+	}
+	if gotPath != "/pdf" {
+		t.Errorf("expected /pdf, got %s", gotPath)
+	}
+	options, _ := body["options"].(map[string]any)
+	margin, _ := options["margin"].(map[string]any)
+	if options["format"] != "Letter" || margin["top"] != "10mm" || margin["left"] != "20mm" {
+		t.Errorf("unexpected pdf options %v", options)
+	}
+
+	p = NewWebpackPlugin(types.PluginCall{
+		WorkingPath: workdir,
+		Params:      map[string]string{webpackParameterFileType: "pdf", webpackParameterPDFMargin: "wide"},
+	}).(*WebpackPlugin)
+	if p.pdfLayout.Margin[0] != defaultPDFMargin {
+		t.Errorf("expected default margin for invalid config, got %+v", p.pdfLayout)
+	}
+}
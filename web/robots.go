@@ -0,0 +1,164 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package web
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RobotsUserAgent is the product token matched against robots.txt User-agent lines.
+// Groups for other agents are ignored, the "*" group applies when no group names it.
+const RobotsUserAgent = "nanafs"
+
+// robotsRules are the rules of the robots.txt group that applies to RobotsUserAgent.
+// A nil value allows everything.
+type robotsRules struct {
+	rules      []robotsRule
+	crawlDelay time.Duration
+	disallowed bool // robots.txt could not be read, see RFC 9309 section 2.3.1.4
+}
+
+type robotsRule struct {
+	allow   bool
+	pattern string
+}
+
+type robotsGroup struct {
+	agents     []string
+	rules      []robotsRule
+	crawlDelay time.Duration
+}
+
+// parseRobots reads a robots.txt and keeps the groups naming RobotsUserAgent, or the "*" groups when none does.
+func parseRobots(r io.Reader) *robotsRules {
+	var (
+		groups  []*robotsGroup
+		current *robotsGroup
+		inRules bool
+		scanner = bufio.NewScanner(r)
+	)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			if current == nil || inRules {
+				current = &robotsGroup{}
+				groups = append(groups, current)
+				inRules = false
+			}
+			current.agents = append(current.agents, strings.ToLower(value))
+		case "allow", "disallow":
+			if current == nil {
+				continue
+			}
+			inRules = true
+			if value != "" {
+				current.rules = append(current.rules, robotsRule{allow: key == "allow", pattern: value})
+			}
+		case "crawl-delay":
+			if current == nil {
+				continue
+			}
+			inRules = true
+			if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+				current.crawlDelay = time.Duration(seconds * float64(time.Second))
+			}
+		}
+	}
+
+	var named, wildcard []*robotsGroup
+	for _, g := range groups {
+		for _, agent := range g.agents {
+			if agent == RobotsUserAgent {
+				named = append(named, g)
+				break
+			}
+			if agent == "*" {
+				wildcard = append(wildcard, g)
+				break
+			}
+		}
+	}
+	if len(named) == 0 {
+		named = wildcard
+	}
+	rules := &robotsRules{}
+	for _, g := range named {
+		rules.rules = append(rules.rules, g.rules...)
+		rules.crawlDelay = max(rules.crawlDelay, g.crawlDelay)
+	}
+	return rules
+}
+
+// allowed applies the most specific matching rule to the path and query of a URL; allow wins a tie.
+func (r *robotsRules) allowed(path string) bool {
+	if r == nil || path == "/robots.txt" {
+		return true
+	}
+	if r.disallowed {
+		return false
+	}
+	var (
+		best    = -1
+		allowed = true
+	)
+	for _, rule := range r.rules {
+		if !robotsMatch(rule.pattern, path) {
+			continue
+		}
+		if n := len(rule.pattern); n > best || (n == best && rule.allow) {
+			best, allowed = n, rule.allow
+		}
+	}
+	return allowed
+}
+
+// robotsMatch matches a robots.txt path pattern where "*" is any sequence and a trailing "$" anchors the end.
+func robotsMatch(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	rest := path[len(parts[0]):]
+	for i, part := range parts[1:] {
+		if anchored && i == len(parts)-2 {
+			return strings.HasSuffix(rest, part)
+		}
+		idx := strings.Index(rest, part)
+		if idx < 0 {
+			return false
+		}
+		rest = rest[idx+len(part):]
+	}
+	return !anchored || rest == ""
+}
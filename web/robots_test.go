@@ -0,0 +1,104 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package web
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseRobots(t *testing.T) {
+	content := `# comment
+User-agent: googlebot
+Disallow: /
+
+User-agent: *
+Disallow: /private/
+Allow: /private/open
+Crawl-delay: 2
+
+User-agent: other
+User-agent: *
+Disallow: /*.php$
+`
+	rules := parseRobots(strings.NewReader(content))
+	if rules.crawlDelay != 2*time.Second {
+		t.Errorf("unexpected crawl delay %s", rules.crawlDelay)
+	}
+	cases := map[string]bool{
+		"/":                  true,
+		"/docs/a":            true,
+		"/private/":          false,
+		"/private/x":         false,
+		"/private/open":      true,
+		"/private/open/more": true,
+		"/index.php":         false,
+		"/index.php?x=1":     true,
+		"/robots.txt":        true,
+	}
+	for path, want := range cases {
+		if got := rules.allowed(path); got != want {
+			t.Errorf("allowed(%s) = %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestParseRobots_NamedAgent(t *testing.T) {
+	content := `User-agent: *
+Disallow: /
+
+User-agent: NanaFS
+Disallow: /tmp
+`
+	rules := parseRobots(strings.NewReader(content))
+	if !rules.allowed("/docs") || rules.allowed("/tmp/x") {
+		t.Errorf("named group should replace the wildcard group: %+v", rules.rules)
+	}
+}
+
+func TestRobotsRules_Unavailable(t *testing.T) {
+	var missing *robotsRules
+	if !missing.allowed("/any") {
+		t.Errorf("nil rules should allow everything")
+	}
+	unavailable := &robotsRules{disallowed: true}
+	if unavailable.allowed("/any") || !unavailable.allowed("/robots.txt") {
+		t.Errorf("unreachable robots.txt should disallow all pages but itself")
+	}
+}
+
+func TestRobotsMatch(t *testing.T) {
+	cases := []struct {
+		pattern, path string
+		want          bool
+	}{
+		{"/a", "/abc", true},
+		{"/a$", "/a", true},
+		{"/a$", "/ab", false},
+		{"/*/edit", "/page/edit", true},
+		{"/*/edit", "/page/view", false},
+		{"/a*b$", "/axxb", true},
+		{"/a*b$", "/axxbc", false},
+		{"*.gif$", "/img/x.gif", true},
+	}
+	for _, c := range cases {
+		if got := robotsMatch(c.pattern, c.path); got != c.want {
+			t.Errorf("robotsMatch(%s, %s) = %v, want %v", c.pattern, c.path, got, c.want)
+		}
+	}
+}
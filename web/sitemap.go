@@ -0,0 +1,196 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package web
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/basenana/plugin/api"
+)
+
+const (
+	webpackParameterSitemapURL  = "sitemap_url"
+	webpackParameterURLPattern  = "url_pattern"
+	webpackParameterConcurrency = "concurrency"
+
+	defaultSitemapConcurrency = 4
+	maxSitemapConcurrency     = 16
+	maxNestedSitemaps         = 50
+)
+
+// SitemapOption selects the sitemap URLs webpack archives.
+type SitemapOption struct {
+	URL         string
+	Pattern     *regexp.Regexp
+	Concurrency int
+}
+
+func parseSitemapOption(request *api.Request) (SitemapOption, error) {
+	opt := SitemapOption{
+		URL:         api.GetStringParameter(webpackParameterSitemapURL, request, ""),
+		Concurrency: defaultSitemapConcurrency,
+	}
+	if raw := api.GetStringParameter(webpackParameterURLPattern, request, ""); raw != "" {
+		re, err := regexp.Compile(raw)
+		if err != nil {
+			return opt, fmt.Errorf("invalid url_pattern [%s]: %w", raw, err)
+		}
+		opt.Pattern = re
+	}
+	if raw := api.GetStringParameter(webpackParameterConcurrency, request, ""); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 || n > maxSitemapConcurrency {
+			return opt, fmt.Errorf("invalid concurrency [%s]: expect 1 to %d", raw, maxSitemapConcurrency)
+		}
+		opt.Concurrency = n
+	}
+	return opt, nil
+}
+
+type sitemapDocument struct {
+	XMLName  xml.Name
+	URLs     []sitemapLoc `xml:"url"`
+	Sitemaps []sitemapLoc `xml:"sitemap"`
+}
+
+type sitemapLoc struct {
+	Loc string `xml:"loc"`
+}
+
+// sitemapURLs lists the page URLs of a sitemap in order without duplicates, following
+// sitemap index files. Gzip compressed sitemaps are supported.
+func (w *WebpackPlugin) sitemapURLs(ctx context.Context, sitemapURL string) ([]string, error) {
+	var (
+		urls    []string
+		seen    = map[string]bool{}
+		pending = []string{sitemapURL}
+		visited = map[string]bool{}
+	)
+	for len(pending) > 0 {
+		current := pending[0]
+		pending = pending[1:]
+		if visited[current] {
+			continue
+		}
+		if len(visited) >= maxNestedSitemaps {
+			w.logger.Warnw("too many nested sitemaps, rest ignored", "sitemap_url", sitemapURL, "limit", maxNestedSitemaps)
+			break
+		}
+		visited[current] = true
+
+		doc, err := w.fetchSitemap(ctx, current)
+		if err != nil {
+			if current == sitemapURL {
+				return nil, err
+			}
+			w.logger.Warnw("read nested sitemap failed", "sitemap_url", current, "error", err)
+			continue
+		}
+		for _, s := range doc.Sitemaps {
+			if loc := strings.TrimSpace(s.Loc); loc != "" {
+				pending = append(pending, loc)
+			}
+		}
+		for _, u := range doc.URLs {
+			loc := strings.TrimSpace(u.Loc)
+			if loc == "" || seen[loc] {
+				continue
+			}
+			seen[loc] = true
+			urls = append(urls, loc)
+		}
+	}
+	return urls, nil
+}
+
+func (w *WebpackPlugin) fetchSitemap(ctx context.Context, sitemapURL string) (*sitemapDocument, error) {
+	content, err := w.fetchRaw(ctx, sitemapURL)
+	if err != nil {
+		return nil, fmt.Errorf("read sitemap %s failed: %w", sitemapURL, err)
+	}
+	data := []byte(content)
+	if bytes.HasPrefix(data, []byte{0x1f, 0x8b}) {
+		gz, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("decompress sitemap %s failed: %w", sitemapURL, err)
+		}
+		if data, err = io.ReadAll(gz); err != nil {
+			return nil, fmt.Errorf("decompress sitemap %s failed: %w", sitemapURL, err)
+		}
+	}
+	doc := &sitemapDocument{}
+	if err = xml.Unmarshal(data, doc); err != nil {
+		return nil, fmt.Errorf("parse sitemap %s failed: %w", sitemapURL, err)
+	}
+	if doc.XMLName.Local != "urlset" && doc.XMLName.Local != "sitemapindex" {
+		return nil, fmt.Errorf("parse sitemap %s failed: unexpected root element <%s>", sitemapURL, doc.XMLName.Local)
+	}
+	return doc, nil
+}
+
+// captureSitemap archives the selected sitemap URLs as <file_name>_001.<ext>, ... on a bounded
+// pool of workers and writes the index of captured URLs in sitemap order.
+// It returns the index entries, the index path and the number of matched URLs beyond maxPages.
+func (w *WebpackPlugin) captureSitemap(ctx context.Context, filename string, sitemapOpt SitemapOption, maxPages int, options ...Option) ([]CrawledPage, string, int, error) {
+	urls, err := w.sitemapURLs(ctx, sitemapOpt.URL)
+	if err != nil {
+		return nil, "", 0, err
+	}
+
+	var selected []string
+	for _, u := range urls {
+		if sitemapOpt.Pattern == nil || sitemapOpt.Pattern.MatchString(u) {
+			selected = append(selected, u)
+		}
+	}
+	skipped := 0
+	if len(selected) > maxPages {
+		skipped = len(selected) - maxPages
+		selected = selected[:maxPages]
+	}
+	w.logger.Infow("sitemap loaded", "sitemap_url", sitemapOpt.URL, "urls", len(urls), "selected", len(selected), "skipped", skipped)
+
+	names := make([]string, len(selected))
+	for i := range selected {
+		names[i] = fmt.Sprintf("%s_%03d", filename, i+1)
+	}
+	pages, err := w.packPages(ctx, selected, names, sitemapOpt.Concurrency, options...)
+	if err != nil {
+		return nil, "", 0, err
+	}
+
+	indexPath := filepath.Join(w.fileRoot.Workdir(), filename+crawlIndexExtension)
+	data, err := json.MarshalIndent(map[string]any{"sitemap_url": sitemapOpt.URL, "skipped": skipped, "pages": pages}, "", "  ")
+	if err != nil {
+		return nil, "", 0, err
+	}
+	if err = os.WriteFile(indexPath, data, 0644); err != nil {
+		return nil, "", 0, fmt.Errorf("write sitemap index failed: %w", err)
+	}
+	return pages, indexPath, skipped, nil
+}
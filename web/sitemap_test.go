@@ -0,0 +1,176 @@
+/*
+ Copyright 2023 NanaFS Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package web
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/basenana/plugin/api"
+	"github.com/basenana/plugin/types"
+)
+
+func newSitemapSite(t *testing.T, inFlight, peak *int32) *httptest.Server {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/sitemap.xml":
+			w.Header().Set("Content-Type", "application/xml")
+			_, _ = fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?>
+<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <sitemap><loc>%[1]s/docs.xml.gz</loc></sitemap>
+  <sitemap><loc>%[1]s/blog.xml</loc></sitemap>
+  <sitemap><loc>%[1]s/broken.xml</loc></sitemap>
+</sitemapindex>`, server.URL)
+		case "/docs.xml.gz":
+			buf := &bytes.Buffer{}
+			gz := gzip.NewWriter(buf)
+			_, _ = fmt.Fprintf(gz, `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url><loc>%[1]s/docs/intro</loc></url>
+  <url><loc> %[1]s/docs/setup </loc></url>
+  <url><loc>%[1]s/docs/gone</loc></url>
+  <url><loc>%[1]s/docs/api</loc></url>
+  <url><loc>%[1]s/docs/intro</loc></url>
+</urlset>`, server.URL)
+			_ = gz.Close()
+			w.Header().Set("Content-Type", "application/gzip")
+			_, _ = w.Write(buf.Bytes())
+		case "/blog.xml":
+			_, _ = fmt.Fprintf(w, `<urlset><url><loc>%s/blog/post</loc></url></urlset>`, server.URL)
+		case "/docs/intro", "/docs/setup", "/docs/api", "/blog/post":
+			n := atomic.AddInt32(inFlight, 1)
+			defer atomic.AddInt32(inFlight, -1)
+			for {
+				p := atomic.LoadInt32(peak)
+				if n <= p || atomic.CompareAndSwapInt32(peak, p, n) {
+					break
+				}
+			}
+			time.Sleep(20 * time.Millisecond)
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			_, _ = fmt.Fprintf(w, "<html><head><title>%s</title></head><body><p>%s</p></body></html>", r.URL.Path, r.URL.Path)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestWebpackPlugin_Sitemap(t *testing.T) {
+	defer func(orig bool) { enablePrivateNet = orig }(enablePrivateNet)
+	enablePrivateNet = true
+	var inFlight, peak int32
+	server := newSitemapSite(t, &inFlight, &peak)
+
+	workdir := t.TempDir()
+	p := NewWebpackPlugin(types.PluginCall{
+		WorkingPath: workdir,
+		Params:      map[string]string{webpackParameterFileType: "html", webpackParameterClutterFree: "false"},
+	}).(*WebpackPlugin)
+	resp, err := p.Run(context.Background(), &api.Request{Parameter: map[string]any{
+		webpackParameterFileName:    "site",
+		webpackParameterSitemapURL:  server.URL + "/sitemap.xml",
+		webpackParameterURLPattern:  "/docs/",
+		webpackParameterConcurrency: "2",
+	}})
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	if resp.Results["captured"] != 3 || resp.Results["failed"] != 1 || resp.Results["skipped"] != 0 {
+		t.Errorf("unexpected summary %v", resp.Results)
+	}
+	if peak > 2 {
+		t.Errorf("expected at most 2 concurrent captures, got %d", peak)
+	}
+
+	data, err := os.ReadFile(filepath.Join(workdir, "site.index.json"))
+	if err != nil {
+		t.Fatalf("read index failed: %v", err)
+	}
+	var index struct {
+		Pages []CrawledPage `json:"pages"`
+	}
+	if err = json.Unmarshal(data, &index); err != nil {
+		t.Fatalf("parse index failed: %v", err)
+	}
+	want := []string{"/docs/intro", "/docs/setup", "/docs/gone", "/docs/api"}
+	if len(index.Pages) != len(want) {
+		t.Fatalf("unexpected pages %+v", index.Pages)
+	}
+	for i, path := range want {
+		page := index.Pages[i]
+		if page.URL != server.URL+path {
+			t.Errorf("page %d: unexpected url %s", i, page.URL)
+		}
+		if path == "/docs/gone" {
+			if page.Error == "" || page.FilePath != "" {
+				t.Errorf("page %d: expected error, got %+v", i, page)
+			}
+			continue
+		}
+		if page.FilePath != filepath.Join(workdir, fmt.Sprintf("site_%03d.html", i+1)) {
+			t.Errorf("page %d: unexpected file %s", i, page.FilePath)
+		}
+	}
+}
+
+func TestWebpackPlugin_SitemapLimits(t *testing.T) {
+	defer func(orig bool) { enablePrivateNet = orig }(enablePrivateNet)
+	enablePrivateNet = true
+	var inFlight, peak int32
+	server := newSitemapSite(t, &inFlight, &peak)
+
+	p := NewWebpackPlugin(types.PluginCall{
+		WorkingPath: t.TempDir(),
+		Params:      map[string]string{webpackParameterFileType: "html"},
+	}).(*WebpackPlugin)
+	resp, err := p.Run(context.Background(), &api.Request{Parameter: map[string]any{
+		webpackParameterFileName:   "site",
+		webpackParameterSitemapURL: server.URL + "/sitemap.xml",
+		webpackParameterMaxPages:   "2",
+	}})
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	if resp.Results["captured"] != 2 || resp.Results["skipped"] != 3 {
+		t.Errorf("unexpected summary %v", resp.Results)
+	}
+
+	for _, params := range []map[string]any{
+		{webpackParameterSitemapURL: server.URL + "/sitemap.xml", webpackParameterURL: server.URL + "/docs/intro"},
+		{webpackParameterSitemapURL: server.URL + "/sitemap.xml", webpackParameterDepth: "1"},
+		{webpackParameterSitemapURL: server.URL + "/sitemap.xml", webpackParameterURLPattern: "("},
+		{webpackParameterSitemapURL: server.URL + "/sitemap.xml", webpackParameterConcurrency: "99"},
+		{webpackParameterSitemapURL: server.URL + "/docs/intro"},
+	} {
+		params[webpackParameterFileName] = "site"
+		if _, err = p.Run(context.Background(), &api.Request{Parameter: params}); err == nil {
+			t.Errorf("expected error for %v", params)
+		}
+	}
+}
@@ -3,9 +3,11 @@ package web
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"path"
 	"strings"
+	"time"
 
 	htmltomarkdown "github.com/JohannesKaufmann/html-to-markdown/v2"
 	"github.com/PuerkitoBio/goquery"
@@ -33,6 +35,10 @@ func PackFromURL(ctx context.Context, filename, urlInfo, tgtFileType, outputDir
 		return "", fmt.Errorf("url is empty")
 	}
 
+	if err = WaitFetch(ctx, urlInfo); err != nil {
+		return "", err
+	}
+
 	if filename == "" {
 		filename, err = generateValidFilenameUsingTitle(ctx, urlInfo)
 		if err != nil {
@@ -41,6 +47,9 @@ func PackFromURL(ctx context.Context, filename, urlInfo, tgtFileType, outputDir
 	}
 
 	outputFile := filename + "." + tgtFileType
+	if tgtFileType == "markdown" {
+		outputFile = filename + ".md"
+	}
 	log.Infof("packing url %s to %s", urlInfo, outputFile)
 
 	if browserlessURL != "" {
@@ -54,21 +63,31 @@ func PackFromURL(ctx context.Context, filename, urlInfo, tgtFileType, outputDir
 
 	var (
 		filePath = path.Join(outputDir, outputFile)
+		packPath = filePath
 		p        packer.Packer
 		opt      packer.Option
 	)
 	switch tgtFileType {
 	case "webarchive":
 		p = packer.NewWebArchivePacker()
+	case "mhtml":
+		// resources are collected as a webarchive first, then converted
+		p = packer.NewWebArchivePacker()
+		packPath = path.Join(outputDir, "."+filename+".webarchive")
 	case "html":
 		p = packer.NewHtmlPacker()
+	case "markdown":
+		// the raw page is kept for the readability extraction, then converted
+		p = packer.NewHtmlPacker()
+		packPath = path.Join(outputDir, "."+filename+".html")
+		clutterFree = false
 	default:
 		return "", fmt.Errorf("unsupported file type %s", tgtFileType)
 	}
 
 	opt = packer.Option{
 		URL:              urlInfo,
-		FilePath:         filePath,
+		FilePath:         packPath,
 		Timeout:          60,
 		ClutterFree:      clutterFree,
 		Headers:          make(map[string]string),
@@ -86,6 +105,21 @@ func PackFromURL(ctx context.Context, filename, urlInfo, tgtFileType, outputDir
 		return "", fmt.Errorf("pack to web failed: %w", err)
 	}
 
+	switch tgtFileType {
+	case "mhtml":
+		defer os.Remove(packPath)
+		if err = webarchiveToMHTML(packPath, filePath); err != nil {
+			log.Warnw("convert webarchive to mhtml failed", "link", urlInfo, "err", err)
+			return "", fmt.Errorf("convert to mhtml failed: %w", err)
+		}
+	case "markdown":
+		defer os.Remove(packPath)
+		if err = htmlToMarkdownFile(packPath, filePath, urlInfo, time.Now()); err != nil {
+			log.Warnw("convert html to markdown failed", "link", urlInfo, "err", err)
+			return "", fmt.Errorf("convert to markdown failed: %w", err)
+		}
+	}
+
 	return filePath, nil
 }
 
@@ -108,6 +142,26 @@ func ReadFromFile(ctx context.Context, filePath string) (string, error) {
 			log.Warnw("read webarchive failed", "err", err)
 			return "", fmt.Errorf("read webarchive failed: %w", err)
 		}
+	case ".mhtml", ".mht":
+		f, err := os.Open(filePath)
+		if err != nil {
+			return "", fmt.Errorf("open mhtml failed: %w", err)
+		}
+		defer f.Close()
+		doc, _, err := readMHTMLResources(f)
+		if err != nil {
+			log.Warnw("read mhtml file failed", "err", err)
+			return "", err
+		}
+		p := packer.NewHtmlPacker()
+		content, err = p.ReadContent(ctx, packer.Option{
+			Reader:      io.NopCloser(strings.NewReader(doc)),
+			ClutterFree: true,
+		})
+		if err != nil {
+			log.Warnw("read mhtml document failed", "err", err)
+			return "", fmt.Errorf("read mhtml failed: %w", err)
+		}
 	case ".html", ".htm", ".hts":
 		p := packer.NewHtmlPacker()
 		content, err = p.ReadContent(ctx, packer.Option{
@@ -20,7 +20,9 @@ import (
 	"context"
 	"fmt"
 	"path/filepath"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/basenana/plugin/api"
 	"github.com/basenana/plugin/logger"
@@ -37,19 +39,24 @@ const (
 	webpackParameterFileType    = "file_type"
 	webpackParameterURL         = "url"
 	webpackParameterClutterFree = "clutter_free"
+	webpackParameterRender      = "render"
+	webpackParameterPDFPageSize = "pdf_page_size"
+	webpackParameterPDFMargin   = "pdf_margin"
+	webpackParameterAssetReport = "asset_report"
 )
 
 var WebpackPluginSpec = types.PluginSpec{
-	Name:    WebpackPluginName,
-	Version: WebpackPluginVersion,
-	Type:    types.TypeProcess,
+	Name:         WebpackPluginName,
+	Version:      WebpackPluginVersion,
+	Type:         types.TypeProcess,
+	Dependencies: []types.Dependency{{Kind: types.DependencyCapability, Name: types.CapabilityNetwork}},
 	InitParameters: []types.ParameterSpec{
 		{
 			Name:        "file_type",
 			Required:    false,
 			Default:     "webarchive",
-			Description: "Output format: html, webarchive",
-			Options:     []string{"html", "webarchive"},
+			Description: "Output format: html, webarchive, mhtml, markdown (readable article with front matter), png (full-page screenshot), pdf (paginated print); png and pdf require render browser",
+			Options:     []string{"html", "webarchive", "mhtml", "markdown", "png", "pdf"},
 		},
 		{
 			Name:        "clutter_free",
@@ -58,8 +65,38 @@ var WebpackPluginSpec = types.PluginSpec{
 			Description: "Enable clutter-free mode",
 			Options:     []string{"true", "false"},
 		},
+		{
+			Name:        webpackParameterProxyURL,
+			Required:    false,
+			Description: "http, https or socks5 proxy for page requests, supports the html and markdown file types with render http",
+		},
+		{
+			Name:        "pdf_page_size",
+			Required:    false,
+			Default:     defaultPDFPageSize,
+			Description: "Paper size of pdf output",
+			Options:     pdfPageSizes,
+		},
+		{
+			Name:        "pdf_margin",
+			Required:    false,
+			Default:     defaultPDFMargin,
+			Description: "Margins of pdf output, one to four CSS lengths (top right bottom left), e.g. 1cm or 10mm 15mm",
+		},
+		{
+			Name:        webpackParameterDedupAssets,
+			Required:    false,
+			Default:     "true",
+			Description: "Store identical webarchive assets once, references of the page are pointed to the kept copy",
+			Options:     []string{"true", "false"},
+		},
+		{
+			Name:        webpackParameterImageQuality,
+			Required:    false,
+			Description: "Recompress webarchive JPEG images at this quality (1 to 100) and PNG images at the best compression, keeping only smaller results",
+		},
 	},
-	Parameters: []types.ParameterSpec{
+	Parameters: append([]types.ParameterSpec{
 		{
 			Name:        "file_name",
 			Required:    true,
@@ -67,9 +104,180 @@ var WebpackPluginSpec = types.PluginSpec{
 		},
 		{
 			Name:        "url",
-			Required:    true,
-			Description: "URL to pack",
+			Required:    false,
+			Description: "URL to pack, required unless sitemap_url or urls is set",
+		},
+		{
+			Name:        "render",
+			Required:    false,
+			Default:     RenderHTTP,
+			Description: "How the page is fetched: http, browser (headless Chromium, for JS-rendered pages)",
+			Options:     []string{RenderHTTP, RenderBrowser},
+		},
+		{
+			Name:        "asset_report",
+			Required:    false,
+			Default:     "false",
+			Description: "Write <file_name>.assets.json listing the captured and missing assets (html, webarchive, mhtml)",
+			Options:     []string{"true", "false"},
+		},
+		{
+			Name:        "depth",
+			Required:    false,
+			Default:     "0",
+			Description: "Follow in-page links up to this depth (0 to 5) and archive each linked page, 0 archives only the url",
+		},
+		{
+			Name:        "same_origin_only",
+			Required:    false,
+			Default:     "true",
+			Description: "Only follow links with the same scheme and host as the url",
+			Options:     []string{"true", "false"},
+		},
+		{
+			Name:        "max_pages",
+			Required:    false,
+			Default:     "20",
+			Description: "Maximum number of pages archived by a crawl including the url, or from a sitemap (1 to 1000)",
+		},
+		{
+			Name:        "sitemap_url",
+			Required:    false,
+			Description: "Archive the pages listed in this sitemap or sitemap index instead of url",
+		},
+		{
+			Name:        "url_pattern",
+			Required:    false,
+			Description: "Regular expression the sitemap URLs must match to be archived",
+		},
+		{
+			Name:        webpackParameterURLs,
+			Required:    false,
+			Description: "JSON list of URLs archived in one call instead of url, at most 1000",
+		},
+		{
+			Name:        webpackParameterFileNames,
+			Required:    false,
+			Description: "JSON list of file names matching urls, default <file_name>_NNN",
+		},
+		{
+			Name:        webpackParameterNameTemplate,
+			Required:    false,
+			Description: "Go template naming the pages of urls, fields Index, FileName, Host, Slug, ID; exclusive with file_names",
+		},
+		{
+			Name:        "timeout",
+			Required:    false,
+			Description: "Timeout of each page and asset request, e.g. 20s; with any of timeout, max_redirects, retries and retry_backoff the page and its assets are fetched by webpack itself (render http)",
+		},
+		{
+			Name:        "max_redirects",
+			Required:    false,
+			Default:     "10",
+			Description: "Redirects followed per request",
+		},
+		{
+			Name:        "retries",
+			Required:    false,
+			Default:     "0",
+			Description: "Retries per request (0 to 10) on server errors, 408, 429, timeouts and dropped connections",
+		},
+		{
+			Name:        "retry_backoff",
+			Required:    false,
+			Default:     "1s",
+			Description: "Initial retry delay, doubled on every retry up to 30s",
+		},
+		{
+			Name:        "blocklist",
+			Required:    false,
+			Description: "EasyList-style URL rules or domains, one per line or as a JSON list; matching scripts, stylesheets, images and frames are removed from the capture (render http)",
+		},
+		{
+			Name:        "blocklist_file",
+			Required:    false,
+			Description: "Blocklist file in the working directory, e.g. a downloaded EasyList or hosts file",
+		},
+		{
+			Name:        "skip_media_types",
+			Required:    false,
+			Description: "Comma-separated media types left out of the capture, wildcards allowed, e.g. video/*,audio/*",
+		},
+		{
+			Name:        "allowed_asset_types",
+			Required:    false,
+			Description: "Comma-separated media types assets are limited to, e.g. image/*,text/css; other assets are left out",
+		},
+		{
+			Name:        "max_page_size",
+			Required:    false,
+			Description: "Maximum size of the page, e.g. 5MB; a larger page fails the call",
+		},
+		{
+			Name:        "max_asset_size",
+			Required:    false,
+			Description: "Maximum size of each asset, e.g. 2MB; larger assets are left out",
+		},
+		{
+			Name:        "max_total_size",
+			Required:    false,
+			Description: "Maximum bytes downloaded per captured page, assets beyond it are left out",
+		},
+		{
+			Name:        "headers",
+			Required:    false,
+			Description: "JSON object of request headers sent to the host of url or sitemap_url and its subdomains, not to assets on other hosts",
+		},
+		{
+			Name:        "basic_auth",
+			Required:    false,
+			Description: "JSON object with username and password sent as the Authorization: Basic header to the host of url or sitemap_url",
+		},
+		{
+			Name:        "bearer_token",
+			Required:    false,
+			Description: "Token sent as the Authorization: Bearer header to the host of url or sitemap_url, exclusive with basic_auth",
+		},
+		{
+			Name:        webpackParameterUserAgent,
+			Required:    false,
+			Description: "User-Agent of the page and asset requests, replacing the default browser User-Agent",
+		},
+		{
+			Name:        webpackParameterHostHeaders,
+			Required:    false,
+			Description: "JSON object of host to request headers, applied to the requests of the host and its subdomains",
+		},
+		{
+			Name:        "cookies",
+			Required:    false,
+			Description: "Cookie header sent to the host of url or sitemap_url, e.g. session=abc; theme=dark",
+		},
+		{
+			Name:        "cookie_file",
+			Required:    false,
+			Description: "Netscape cookies.txt in the working directory, its cookies apply to their own domains",
 		},
+		{
+			Name:        "login_url",
+			Required:    false,
+			Description: "URL the login_form is posted to before fetching, the session cookies it sets are kept for the run",
+		},
+		{
+			Name:        "login_form",
+			Required:    false,
+			Description: "JSON object of the login form fields, e.g. {\"username\": \"me\", \"password\": \"secret\"}",
+		},
+		{
+			Name:        "concurrency",
+			Required:    false,
+			Default:     "4",
+			Description: "Number of sitemap or urls pages archived at the same time (1 to 16)",
+		},
+	}, FetchPolicyParameters...),
+	Example: &types.PluginExample{
+		Request: map[string]any{"file_name": "article", "url": "https://example.com/article"},
+		Results: map[string]any{"file_path": "article.webarchive", "size": 48213, "title": "article", "url": "https://example.com/article"},
 	},
 }
 
@@ -78,6 +286,13 @@ type WebpackPlugin struct {
 	fileRoot    *utils.FileAccess
 	fileType    string
 	clutterFree bool
+	credentials CredentialStore
+	browser     BrowserRenderer
+	pdfLayout   PDFLayout
+	network     *NetworkPolicy
+	networkErr  error
+	optimize    ArchiveOptimization
+	assetCache  *AssetCache
 }
 
 func NewWebpackPlugin(ps types.PluginCall) types.Plugin {
@@ -91,11 +306,45 @@ func NewWebpackPlugin(ps types.PluginCall) types.Plugin {
 		clutterFree = v == "true" || v == "1"
 	}
 
+	log := logger.NewPluginLogger(WebpackPluginName, ps.JobID)
+	credentials, err := ParseCredentialStore(ps.Config[webpackConfigCredentials])
+	if err != nil {
+		log.Warnw("load webpack credentials failed", "error", err)
+		credentials = CredentialStore{}
+	}
+	pdfLayout, err := ParsePDFLayout(ps.Params[webpackParameterPDFPageSize], ps.Params[webpackParameterPDFMargin])
+	if err != nil {
+		log.Warnw("parse pdf layout failed, use defaults", "error", err)
+		pdfLayout, _ = ParsePDFLayout("", "")
+	}
+
+	optimize, err := ParseArchiveOptimization(ps.Params[webpackParameterDedupAssets], ps.Params[webpackParameterImageQuality])
+	if err != nil {
+		log.Warnw("parse archive optimization failed, use defaults", "error", err)
+		optimize, _ = ParseArchiveOptimization("", "")
+	}
+
+	var assetCache *AssetCache
+	if dir := ps.Config[webpackConfigAssetCacheDir]; dir != "" {
+		if assetCache, err = NewAssetCache(dir); err != nil {
+			log.Warnw("open asset cache failed, assets are not cached", "dir", dir, "error", err)
+		}
+	}
+
+	network, networkErr := ParseNetworkPolicy(ps.Config[webpackConfigNetworkPolicy], ps.Params[webpackParameterProxyURL])
+
 	return &WebpackPlugin{
-		logger:      logger.NewPluginLogger(WebpackPluginName, ps.JobID),
-		fileRoot:    utils.NewFileAccess(ps.WorkingPath),
+		logger:      log,
+		fileRoot:    utils.NewFileAccess(ps.WorkingPath, ps.JobWorkingPath),
 		fileType:    fileType,
 		clutterFree: clutterFree,
+		credentials: credentials,
+		browser:     NewBrowserRenderer(ps.Config),
+		pdfLayout:   pdfLayout,
+		network:     network,
+		networkErr:  networkErr,
+		optimize:    optimize,
+		assetCache:  assetCache,
 	}
 }
 
@@ -115,54 +364,355 @@ func (w *WebpackPlugin) Run(ctx context.Context, request *api.Request) (*api.Res
 	var (
 		filename = api.GetStringParameter(webpackParameterFileName, request, "")
 		urlInfo  = api.GetStringParameter(webpackParameterURL, request, "")
+		render   = api.GetStringParameter(webpackParameterRender, request, RenderHTTP)
 	)
 
 	if filename == "" {
 		return nil, fmt.Errorf("file name is empty")
 	}
 
-	if urlInfo == "" {
+	sitemapOpt, err := parseSitemapOption(request)
+	if err != nil {
+		return nil, err
+	}
+	batch, err := parseBatchOption(request, filename)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case sitemapOpt.URL != "" && urlInfo != "":
+		return nil, fmt.Errorf("url and sitemap_url are exclusive")
+	case batch.URLs != nil && (urlInfo != "" || sitemapOpt.URL != ""):
+		return nil, fmt.Errorf("urls can not be used with url or sitemap_url")
+	case urlInfo == "" && sitemapOpt.URL == "" && batch.URLs == nil:
 		return nil, fmt.Errorf("url is empty")
 	}
 
-	if w.fileType == "" || (w.fileType != "html" && w.fileType != "webarchive") {
+	if w.networkErr != nil {
+		return nil, w.networkErr
+	}
+
+	switch w.fileType {
+	case "html", "webarchive", "mhtml", "markdown":
+	case "png", "pdf":
+		if render != RenderBrowser {
+			return nil, fmt.Errorf("file type %s requires render [%s]", w.fileType, RenderBrowser)
+		}
+	default:
 		return nil, fmt.Errorf("invalid file type [%s]", w.fileType)
 	}
 
-	w.logger.Infow("webpack started", "url", urlInfo, "file_type", w.fileType)
+	crawlOpt, err := parseCrawlOption(request)
+	if err != nil {
+		return nil, err
+	}
+	if sitemapOpt.URL != "" && crawlOpt.Depth > 0 {
+		return nil, fmt.Errorf("depth can not be used with sitemap_url")
+	}
+	if batch.URLs != nil && crawlOpt.Depth > 0 {
+		return nil, fmt.Errorf("depth can not be used with urls")
+	}
+
+	fetchPolicy, err := ParseFetchPolicy(request)
+	if err != nil {
+		return nil, err
+	}
+	ctx = WithFetchPolicy(ctx, fetchPolicy)
+
+	target := urlInfo
+	switch {
+	case sitemapOpt.URL != "":
+		target = sitemapOpt.URL
+	case batch.URLs != nil:
+		target = batch.URLs[0]
+	}
+	requestCred, err := parseRequestCredential(request)
+	if err != nil {
+		return nil, err
+	}
+	if ctx, err = withRequestCredential(ctx, target, requestCred); err != nil {
+		return nil, err
+	}
+
+	overrides, err := parseHeaderOverrides(request)
+	if err != nil {
+		return nil, err
+	}
+	ctx = withHeaderOverrides(ctx, overrides)
+
+	login, err := parseLoginRequest(request)
+	if err != nil {
+		return nil, err
+	}
+	jar, err := w.cookieJar(request, target, login != nil)
+	if err != nil {
+		return nil, err
+	}
+	loginStatus := 0
+	if login != nil {
+		if loginStatus, err = w.login(ctx, jar, login); err != nil {
+			w.logger.Warnw("login failed", "login_url", login.URL, "error", err)
+			return api.NewFailedResponse(fmt.Sprintf("login %s failed: %s", login.URL, err)), err
+		}
+		w.logger.Infow("login succeeded", "login_url", login.URL, "status", loginStatus)
+	}
+	ctx = withCookieJar(ctx, jar)
+
+	control, err := parseFetchControl(request)
+	if err != nil {
+		return nil, err
+	}
+	filter, err := w.parseAssetFilter(request)
+	if err != nil {
+		return nil, err
+	}
+	if filter != nil {
+		if control == nil {
+			control = defaultFetchControl()
+		}
+		control.Filter = filter
+	}
+	if w.assetCache != nil && control == nil && render == RenderHTTP && (w.fileType == "webarchive" || w.fileType == "mhtml") {
+		// only captures made by webpack itself can use the asset cache
+		control = defaultFetchControl()
+	}
+	scopedHeaders := len(w.credentials) > 0 || requestCred != nil || jar != nil || (overrides != nil && len(overrides.Hosts) > 0)
+	if scopedHeaders && control == nil && render == RenderHTTP && (w.fileType == "webarchive" || w.fileType == "mhtml") {
+		// the web packer sends the credentials and host headers of the page to every asset host
+		control = defaultFetchControl()
+	}
+	ctx = withFetchControl(ctx, control)
 
-	result, err := w.packFromURL(ctx, filename, urlInfo, w.fileType, w.clutterFree)
+	var options []Option
+	switch render {
+	case RenderHTTP:
+		if w.network != nil && control == nil && (w.fileType == "webarchive" || w.fileType == "mhtml") {
+			return nil, fmt.Errorf("file type %s is not supported with %s or %s, use html or markdown, or set %s",
+				w.fileType, webpackConfigNetworkPolicy, webpackParameterProxyURL, webpackParameterTimeout)
+		}
+	case RenderBrowser:
+		// the browser follows redirects and loads resources itself, a policy would only check the page URL
+		if w.network != nil {
+			return nil, fmt.Errorf("%s and %s are not supported with render %s", webpackConfigNetworkPolicy, webpackParameterProxyURL, RenderBrowser)
+		}
+		if control != nil {
+			return nil, fmt.Errorf("fetch controls (%s) are not supported with render %s",
+				strings.Join(fetchControlParameters, ", "), RenderBrowser)
+		}
+		opt, err := w.browser.Option()
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, opt)
+	default:
+		return nil, fmt.Errorf("invalid render [%s]", render)
+	}
+
+	if sitemapOpt.URL != "" {
+		w.logger.Infow("webpack sitemap started", "sitemap_url", sitemapOpt.URL, "file_type", w.fileType, "render", render)
+		pages, indexPath, skipped, err := w.captureSitemap(ctx, filename, sitemapOpt, crawlOpt.MaxPages, options...)
+		if err != nil {
+			w.logger.Warnw("sitemap capture failed", "sitemap_url", sitemapOpt.URL, "error", err)
+			return api.NewFailedResponse(fmt.Sprintf("capture sitemap %s failed: %s", sitemapOpt.URL, err)), err
+		}
+		result := map[string]any{"sitemap_url": sitemapOpt.URL, "skipped": skipped}
+		addPagesResult(result, pages, indexPath)
+		if loginStatus > 0 {
+			result["login_status"] = loginStatus
+		}
+		w.logger.Infow("webpack sitemap completed", "index_path", indexPath, "captured", result["captured"], "failed", result["failed"])
+		return api.NewResponseWithResult(result), nil
+	}
+
+	if batch.URLs != nil {
+		w.logger.Infow("webpack batch started", "urls", len(batch.URLs), "file_type", w.fileType, "render", render)
+		pages, indexPath, err := w.captureBatch(ctx, filename, batch, sitemapOpt.Concurrency, options...)
+		if err != nil {
+			w.logger.Warnw("batch capture failed", "error", err)
+			return api.NewFailedResponse(fmt.Sprintf("capture urls failed: %s", err)), err
+		}
+		result := map[string]any{}
+		addPagesResult(result, pages, indexPath)
+		if loginStatus > 0 {
+			result["login_status"] = loginStatus
+		}
+		w.logger.Infow("webpack batch completed", "index_path", indexPath, "captured", result["captured"], "failed", result["failed"])
+		return api.NewResponseWithResult(result), nil
+	}
+
+	w.logger.Infow("webpack started", "url", urlInfo, "file_type", w.fileType, "render", render)
+
+	result, err := w.packFromURL(ctx, filename, urlInfo, w.fileType, w.clutterFree, options...)
 	if err != nil {
 		w.logger.Warnw("packing failed", "url", urlInfo, "error", err)
 		return api.NewFailedResponse(fmt.Sprintf("packing url %s failed: %s", urlInfo, err)), err
 	}
 
+	if api.GetBoolParameter(webpackParameterAssetReport, request, false) && (w.fileType == "html" || w.fileType == "webarchive" || w.fileType == "mhtml") {
+		w.reportAssets(filename, urlInfo, result)
+	}
+
+	if crawlOpt.Depth > 0 {
+		pages, indexPath, err := w.crawl(ctx, filename, urlInfo, result, crawlOpt, options...)
+		if err != nil {
+			w.logger.Warnw("crawl failed", "url", urlInfo, "error", err)
+			return api.NewFailedResponse(fmt.Sprintf("crawl url %s failed: %s", urlInfo, err)), err
+		}
+		addPagesResult(result, pages, indexPath)
+	}
+
+	if loginStatus > 0 {
+		result["login_status"] = loginStatus
+	}
 	w.logger.Infow("webpack completed", "file_path", result["file_path"])
 
 	resp := api.NewResponseWithResult(result)
 	return resp, nil
 }
 
-func (w *WebpackPlugin) packFromURL(ctx context.Context, filename, urlInfo, tgtFileType string, clutterFree bool) (map[string]any, error) {
+// addPagesResult adds the pages of a crawl or sitemap capture and their counts to the result.
+func addPagesResult(result map[string]any, pages []CrawledPage, indexPath string) {
+	captured, pageMaps := 0, make([]map[string]any, len(pages))
+	for i, page := range pages {
+		if page.Error == "" {
+			captured++
+		}
+		pageMaps[i] = utils.MarshalMap(page)
+	}
+	result["pages"] = pageMaps
+	result["index_path"] = indexPath
+	result["captured"] = captured
+	result["failed"] = len(pages) - captured
+}
+
+func (w *WebpackPlugin) packFromURL(ctx context.Context, filename, urlInfo, tgtFileType string, clutterFree bool, options ...Option) (map[string]any, error) {
 	title := strings.TrimSuffix(filename, filepath.Ext(filename))
 
 	if urlInfo == "" {
 		return nil, fmt.Errorf("url is empty")
 	}
 
-	filePath, err := PackFromURL(logger.IntoContext(ctx, w.logger), filename, urlInfo, tgtFileType, w.fileRoot.Workdir(), clutterFree)
+	// the packer and the browser send the options of the page host with every request, a
+	// capture resolves them for the host of each request and a browser capture can not
+	requestOptions := w.requestOptions(ctx, urlInfo)
+	if len(requestOptions) > 0 && (tgtFileType == "png" || tgtFileType == "pdf" || usesBrowser(options)) {
+		return nil, fmt.Errorf("credentials, cookies and host_headers of %s can not be sent with a browser capture, the browser would send them to every host of the page", urlInfo)
+	}
+	scoped := append(slices.Clip(options), requestOptions...)
+
+	var (
+		filePath string
+		attempts []ResourceAttempts
+		blocked  []string
+		cacheUse AssetCacheUse
+		info     *CaptureInfo
+		started  = time.Now()
+		err      error
+	)
+	ctx, info = withCaptureInfo(ctx)
+	if w.network != nil {
+		if err = w.network.CheckURL(ctx, urlInfo); err != nil {
+			return nil, err
+		}
+	}
+	switch tgtFileType {
+	case "png":
+		filePath = filepath.Join(w.fileRoot.Workdir(), filename+".png")
+		err = w.browser.Screenshot(ctx, urlInfo, filePath, scoped...)
+	case "pdf":
+		filePath = filepath.Join(w.fileRoot.Workdir(), filename+".pdf")
+		err = w.browser.PDF(ctx, urlInfo, filePath, w.pdfLayout, scoped...)
+	default:
+		if control := fetchControlFromContext(ctx); control != nil && !usesBrowser(options) {
+			c := w.newCapture(control, urlInfo, options)
+			filePath, err = c.pack(logger.IntoContext(ctx, w.logger), filename, urlInfo, tgtFileType, w.fileRoot.Workdir(), clutterFree)
+			attempts = c.resourceAttempts()
+			blocked = c.blockedResources()
+			if c.cache != nil {
+				cacheUse = c.assetCacheUse()
+			}
+			break
+		}
+		if w.network != nil && !usesBrowser(options) {
+			filePath, err = w.network.Pack(logger.IntoContext(ctx, w.logger), filename, urlInfo, tgtFileType, w.fileRoot.Workdir(), clutterFree, scoped...)
+			break
+		}
+		filePath, err = PackFromURL(logger.IntoContext(ctx, w.logger), filename, urlInfo, tgtFileType, w.fileRoot.Workdir(), clutterFree, scoped...)
+	}
 	if err != nil {
 		return nil, err
 	}
+	var savings *ArchiveSavings
+	if tgtFileType == "webarchive" && w.optimize.enabled() {
+		if s, err := OptimizeWebArchive(filePath, w.optimize); err != nil {
+			w.logger.Warnw("optimize webarchive failed", "file_path", filePath, "error", err)
+		} else {
+			savings = &s
+		}
+	}
+	info.Duration = time.Since(started)
 
 	fInfo, err := w.fileRoot.Stat(filePath)
 	if err != nil {
 		return nil, fmt.Errorf("stat archive file error: %s", err)
 	}
-	return map[string]any{
+	result := map[string]any{
 		"file_path": filePath,
 		"size":      fInfo.Size(),
 		"title":     title,
 		"url":       urlInfo,
-	}, nil
+	}
+	info.addResult(result)
+	if savings != nil {
+		result["deduplicated_assets"] = savings.Deduplicated
+		result["recompressed_images"] = savings.Recompressed
+		result["saved_bytes"] = savings.SavedBytes
+	}
+	if assets, ok := countAssets(filePath); ok {
+		result["asset_count"] = assets
+	}
+	if attempts != nil {
+		result["resources"] = attempts
+		failed := 0
+		for _, a := range attempts {
+			if a.Kind != resourcePage && a.Error != "" {
+				failed++
+			}
+		}
+		result["failed_asset_count"] = failed
+	}
+	if blocked != nil {
+		result["blocked"] = blocked
+	}
+	if w.assetCache != nil && attempts != nil {
+		result["asset_cache"] = cacheUse
+	}
+	return result, nil
+}
+
+// reportAssets adds the asset summary to the result. A failed report does not fail the packing.
+func (w *WebpackPlugin) reportAssets(filename, urlInfo string, result map[string]any) {
+	filePath, _ := result["file_path"].(string)
+	report, err := BuildAssetReport(filePath, urlInfo)
+	if err != nil {
+		w.logger.Warnw("build asset report failed", "file_path", filePath, "error", err)
+		return
+	}
+	reportPath := filepath.Join(w.fileRoot.Workdir(), filename+".assets.json")
+	if err = writeAssetReport(report, reportPath); err != nil {
+		w.logger.Warnw("write asset report failed", "file_path", reportPath, "error", err)
+		return
+	}
+	if len(report.Missing) > 0 {
+		w.logger.Infow("assets missing from archive", "file_path", filePath, "missing", len(report.Missing))
+	}
+	result["asset_report_path"] = reportPath
+	result["assets"] = map[string]any{
+		"total":    report.Total,
+		"captured": report.Captured,
+		"inline":   report.Inline,
+		"external": report.External,
+		"missing":  len(report.Missing),
+	}
+	result["missing_assets"] = report.Missing
 }